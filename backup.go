@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"socket-server/internal/config"
+	"socket-server/internal/websocket"
+)
+
+var (
+	backupOutput string
+	backupFrom   string
+	restoreForce bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Export the persistent server state to a backup archive",
+	Long: `Writes the persistent state (channel settings, metadata and history, groups, bans, channel
+grants, push device tokens, scheduled broadcasts and event schemas) to a versioned .tar.gz
+archive, for disaster recovery or to clone an environment. The state is read from a running
+server with --from, or from the STATE_FILE otherwise.`,
+	Example: `  socket-server backup --from http://localhost:8080 --server-token $HTTP_TOKEN -o backup.tar.gz
+  STATE_FILE=/var/lib/gosocket/state.json socket-server backup -o backup.tar.gz`,
+	Args: cobra.NoArgs,
+	Run:  runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [archive]",
+	Short: "Import a backup archive into the state file",
+	Long: `Writes the state of a backup archive to the STATE_FILE, which the server restores when it
+starts. Stop the server first: a running server overwrites the file with its own state. An
+existing state file is only replaced with --force.`,
+	Example: `  socket-server restore backup.tar.gz --state-file /var/lib/gosocket/state.json`,
+	Args:    cobra.ExactArgs(1),
+	Run:     runRestore,
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "Archive to write, - for stdout (default: gosocket-backup-<time>.tar.gz)")
+	backupCmd.Flags().StringVar(&backupFrom, "from", "", "Admin URL of a running server to back up, e.g. http://localhost:8080 (default: read the state file)")
+	backupCmd.Flags().StringVar(&httpToken, "server-token", "", "API token of the server given with --from (default: HTTP_TOKEN env var)")
+	backupCmd.Flags().StringVar(&stateFile, "state-file", "", "State file to back up (default: STATE_FILE env var)")
+	restoreCmd.Flags().StringVar(&stateFile, "state-file", "", "State file to write (default: STATE_FILE env var)")
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Replace an existing state file")
+
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+}
+
+func runBackup(cmd *cobra.Command, args []string) {
+	cfg := config.New()
+	cfg.LoadFromFlags("", "", httpToken, "", "", "", "", "")
+	applyFlagOverrides(cfg)
+
+	var snapshot *websocket.Snapshot
+	var source string
+	var err error
+	if backupFrom != "" {
+		source = strings.TrimSuffix(backupFrom, "/")
+		snapshot, err = fetchState(source, cfg.HTTPToken)
+	} else if cfg.StateFile != "" {
+		source = cfg.StateFile
+		snapshot, err = websocket.ReadSnapshotFile(cfg.StateFile)
+	} else {
+		err = errors.New("give the server to back up with --from, or its state file with --state-file or STATE_FILE")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	createdAt := time.Now().UTC()
+	output := backupOutput
+	if output == "" {
+		output = "gosocket-backup-" + createdAt.Format("20060102T150405Z") + ".tar.gz"
+	}
+	var manifest websocket.BackupManifest
+	if output == "-" {
+		manifest, err = websocket.WriteBackup(os.Stdout, snapshot, source, createdAt)
+		output = "stdout"
+	} else {
+		manifest, err = writeBackupFile(output, snapshot, source, createdAt)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Backed up the state of %s to %s: %s\n", source, output, formatContents(manifest.Contents))
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	cfg := config.New()
+	applyFlagOverrides(cfg)
+	if cfg.StateFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: give the state file to restore to with --state-file or STATE_FILE")
+		os.Exit(1)
+	}
+	if _, err := os.Stat(cfg.StateFile); err == nil && !restoreForce {
+		fmt.Fprintf(os.Stderr, "Error: %s exists, use --force to replace it\n", cfg.StateFile)
+		os.Exit(1)
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	manifest, snapshot, err := websocket.ReadBackup(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	if err := websocket.WriteSnapshotFile(cfg.StateFile, snapshot); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Restored the state of %s, taken %s, to %s: %s\n",
+		manifest.Source, manifest.TakenAt.Format(time.RFC3339), cfg.StateFile, formatContents(manifest.Contents))
+}
+
+// writeBackupFile writes a backup archive to filename, removing it if the archive is incomplete
+func writeBackupFile(filename string, snapshot *websocket.Snapshot, source string, createdAt time.Time) (websocket.BackupManifest, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return websocket.BackupManifest{}, fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	manifest, err := websocket.WriteBackup(file, snapshot, source, createdAt)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filename)
+	}
+	return manifest, err
+}
+
+// fetchState requests the state of a running server from its /api/state endpoint
+func fetchState(serverURL, token string) (*websocket.Snapshot, error) {
+	req, err := http.NewRequest("GET", serverURL+"/api/state", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("server answered %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the server's state: %w", err)
+	}
+	return websocket.DecodeSnapshot(data)
+}
+
+// formatContents summarizes the entries of a backup, e.g. "3 bans, 12 channels"
+func formatContents(contents map[string]int) string {
+	kinds := make([]string, 0, len(contents))
+	for kind := range contents {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%d %s", contents[kind], strings.ReplaceAll(kind, "_", " ")))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -15,7 +15,7 @@ go build -o bin/socket-server main.go
 
 # Build the CLI binary
 echo "Building CLI binary..."
-go build -o bin/socket cmd/cli/main.go
+go build -o bin/socket ./cmd/cli
 
 # Make binaries executable
 chmod +x bin/socket-server
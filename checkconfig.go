@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"socket-server/internal/config"
+)
+
+var checkConfigCmd = &cobra.Command{
+	Use:   "check-config",
+	Short: "Validate the configuration and rule files without starting the server",
+	Long: `Loads the configuration from the environment and the same flags as the server, then checks
+every setting, channel pattern and rule file (ingest sources, CDN purge rules and event schemas).
+All problems are listed, rule file errors with their line and column, and the exit status is 1
+when any is found, so deployments can lint their configuration before rolling it out.`,
+	Run: runCheckConfig,
+}
+
+func init() {
+	rootCmd.AddCommand(checkConfigCmd)
+}
+
+func runCheckConfig(cmd *cobra.Command, args []string) {
+	cfg := config.New()
+	cfg.LoadFromFlags(port, jwtSecret, httpToken, workingDir, phpBinary, laravelCmd, tempDir, webDir)
+	applyFlagOverrides(cfg)
+
+	problems := cfg.Check()
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d configuration problem(s) found\n", len(problems))
+		os.Exit(1)
+	}
+
+	fmt.Println("configuration OK")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	banDuration string
+	banReason   string
+)
+
+var banCmd = &cobra.Command{
+	Use:   "ban",
+	Short: "Manage user and IP bans",
+	Long:  "Ban user IDs and IP addresses, permanently or for a duration. Banned connections are closed immediately.",
+}
+
+var banAddCmd = &cobra.Command{
+	Use:   "add [user|ip] [value]",
+	Short: "Ban a user ID or IP address",
+	Example: `  socket ban add user 42 --duration 1h --reason "spamming chat"
+  socket ban add ip 203.0.113.7 --duration 7d`,
+	Args: cobra.ExactArgs(2),
+	Run:  addBan,
+}
+
+var banListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the bans in force",
+	Run:   listBans,
+}
+
+var banRemoveCmd = &cobra.Command{
+	Use:   "remove [user|ip] [value]",
+	Short: "Lift the ban of a user ID or IP address",
+	Args:  cobra.ExactArgs(2),
+	Run:   removeBan,
+}
+
+func init() {
+	banAddCmd.Flags().StringVar(&banDuration, "duration", "", "How long the ban lasts, e.g. 30m, 12h or 7d (default: permanent)")
+	banAddCmd.Flags().StringVar(&banReason, "reason", "", "Why the ban was made, shown to the banned client")
+
+	banCmd.AddCommand(banAddCmd, banListCmd, banRemoveCmd)
+	rootCmd.AddCommand(banCmd)
+}
+
+// ban is a ban as returned by /api/bans
+type ban struct {
+	Type      string     `json:"type"`
+	Value     string     `json:"value"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func addBan(cmd *cobra.Command, args []string) {
+	checkToken()
+	banType := checkBanType(args[0])
+
+	payload := map[string]interface{}{"type": banType, "value": args[1], "reason": banReason}
+	if banDuration != "" {
+		duration, err := parseDuration(banDuration)
+		if err != nil {
+			fmt.Printf("Error: invalid duration %q, use e.g. 30m, 12h or 7d\n", banDuration)
+			os.Exit(1)
+		}
+		payload["ttl"] = int(duration.Seconds())
+	}
+
+	body, err := apiRequest("POST", "/api/bans", payload)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var response struct {
+		Ban          ban `json:"ban"`
+		Disconnected int `json:"disconnected"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	until := "permanently"
+	if response.Ban.ExpiresAt != nil {
+		until = "until " + banExpiry(response.Ban)
+	}
+	fmt.Printf("Banned %s %s %s, %d connection(s) closed\n", response.Ban.Type, response.Ban.Value, until, response.Disconnected)
+}
+
+func listBans(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	body, err := apiRequest("GET", "/api/bans", nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var response struct {
+		Bans  []ban `json:"bans"`
+		Total int   `json:"total"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	if response.Bans == nil {
+		response.Bans = []ban{}
+	}
+	response.Total = len(response.Bans)
+
+	printOutput(response, func() {
+		fmt.Printf("Bans (%d):\n", len(response.Bans))
+		fmt.Printf("%-5s %-40s %-20s %-20s %s\n", "Type", "Value", "Created", "Expires", "Reason")
+		fmt.Printf("%s\n", "----------------------------------------------------------------------------------------------------")
+		for _, b := range response.Bans {
+			fmt.Printf("%-5s %-40s %-20s %-20s %s\n", b.Type, b.Value, b.CreatedAt.Local().Format("2006-01-02 15:04:05"), banExpiry(b), b.Reason)
+		}
+	})
+}
+
+func removeBan(cmd *cobra.Command, args []string) {
+	checkToken()
+	banType := checkBanType(args[0])
+
+	if _, err := apiRequest("DELETE", "/api/bans/"+banType+"/"+url.PathEscape(args[1]), nil); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Lifted the ban of %s %s\n", banType, args[1])
+}
+
+// checkBanType exits unless the argument is a ban type
+func checkBanType(banType string) string {
+	if banType != "user" && banType != "ip" {
+		fmt.Printf("Error: ban type must be user or ip, got %q\n", banType)
+		os.Exit(1)
+	}
+	return banType
+}
+
+// parseDuration parses a Go duration such as 90m, or a number of days such as 7d
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < time.Second {
+		return 0, fmt.Errorf("invalid duration")
+	}
+	return duration, nil
+}
+
+// banExpiry shows when a ban expires
+func banExpiry(b ban) string {
+	if b.ExpiresAt == nil {
+		return "permanent"
+	}
+	return b.ExpiresAt.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyLimit  int
+	historySince  string
+	historyFollow bool
+	historyToken  string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [channel]",
+	Short: "Show a channel's recent messages",
+	Long: `Show the messages a channel retains (see CHANNEL_HISTORY_SIZE), to check whether a broadcast
+actually went out. With --follow, join the channel over a WebSocket afterwards and keep printing
+new messages until interrupted.`,
+	Example: `  socket history orders.42 --limit 10
+  socket history orders.42 --since 0f8c2d6e-... --follow --token $JWT`,
+	Args: cobra.ExactArgs(1),
+	Run:  showHistory,
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Number of most recent messages shown (0 shows all the channel retains)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only show messages published after the message with this ID")
+	historyCmd.Flags().BoolVarP(&historyFollow, "follow", "f", false, "Keep printing new messages as they are published")
+	historyCmd.Flags().StringVar(&historyToken, "token", "", "JWT to authenticate the --follow connection with, for channels that require it")
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+// channelMessage is a channel message as returned by the history API and sent over the WebSocket
+type channelMessage struct {
+	ID        string      `json:"id"`
+	Channel   string      `json:"channel"`
+	Event     string      `json:"event"`
+	Data      interface{} `json:"data"`
+	UserID    string      `json:"user_id"`
+	Sequence  uint64      `json:"sequence"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+func showHistory(cmd *cobra.Command, args []string) {
+	checkToken()
+	channelName := args[0]
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(historyLimit))
+	if historySince != "" {
+		query.Set("since", historySince)
+	}
+	body, err := apiRequest("GET", "/api/channels/"+url.PathEscape(channelName)+"/history?"+query.Encode(), nil)
+	if err != nil && !historyFollow {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// A channel nobody joined yet doesn't exist, which is fine when following it
+	var response struct {
+		Messages []channelMessage `json:"messages"`
+		Gap      bool             `json:"gap"`
+	}
+	if err == nil {
+		if err := json.Unmarshal(body, &response); err != nil {
+			fmt.Printf("Error parsing response: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if response.Gap {
+		fmt.Printf("Message %s is no longer retained, messages may have been missed\n", historySince)
+	}
+	var lastSequence uint64
+	for _, message := range response.Messages {
+		printChannelMessage(message)
+		lastSequence = max(lastSequence, message.Sequence)
+	}
+	if len(response.Messages) == 0 && !historyFollow {
+		fmt.Printf("No messages retained for channel %s\n", channelName)
+	}
+
+	if historyFollow {
+		followChannel(channelName, lastSequence)
+	}
+}
+
+// followChannel joins a channel and prints its messages until interrupted, skipping those with a
+// sequence already printed
+func followChannel(channelName string, lastSequence uint64) {
+	conn, err := dialWebSocket(websocketURL())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		conn.Close()
+	}()
+
+	join := func() error {
+		return conn.WriteJSON(map[string]interface{}{"action": "join_channel", "channel": channelName})
+	}
+	if historyToken != "" {
+		err = conn.WriteJSON(map[string]interface{}{"action": "authenticate", "token": historyToken})
+	} else {
+		err = join()
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	emit := func(message channelMessage) {
+		if message.Sequence != 0 && message.Sequence <= lastSequence {
+			return
+		}
+		printChannelMessage(message)
+		lastSequence = max(lastSequence, message.Sequence)
+	}
+
+	for {
+		var frame struct {
+			channelMessage
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&frame); err != nil {
+			select {
+			case <-interrupt:
+			default:
+				fmt.Printf("Connection closed: %v\n", err)
+			}
+			return
+		}
+
+		switch frame.Event {
+		case "authenticated":
+			if err := join(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+		case "joined_channel":
+			fmt.Printf("Following %s, press Ctrl+C to stop\n", channelName)
+		case "error":
+			var serverError struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(frame.Data, &serverError)
+			fmt.Printf("Server error: %s\n", serverError.Error)
+		case "channel_history":
+			var history struct {
+				Channel  string           `json:"channel"`
+				Messages []channelMessage `json:"messages"`
+			}
+			if json.Unmarshal(frame.Data, &history) == nil && history.Channel == channelName {
+				for _, message := range history.Messages {
+					emit(message)
+				}
+			}
+		default:
+			if frame.Channel == channelName {
+				message := frame.channelMessage
+				json.Unmarshal(frame.Data, &message.Data)
+				emit(message)
+			}
+		}
+	}
+}
+
+// printChannelMessage prints a message on one line: time, sequence, event, sender, ID and data
+func printChannelMessage(message channelMessage) {
+	sequence := "-"
+	if message.Sequence != 0 {
+		sequence = "#" + strconv.FormatUint(message.Sequence, 10)
+	}
+	sender := message.UserID
+	if sender == "" {
+		sender = "server"
+	}
+	data, _ := json.Marshal(message.Data)
+	fmt.Printf("%s %-6s %-20s %-8s %s %s\n", message.Timestamp.Local().Format("2006-01-02 15:04:05"), sequence, message.Event, sender, message.ID, data)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var clientCmd = &cobra.Command{
+	Use:   "client [client-id]",
+	Short: "Show a client's details",
+	Long:  "Show a connected client's user, device, channels with their join data, message counters and last activity",
+	Args:  cobra.ExactArgs(1),
+	Run:   showClient,
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user [user-id]",
+	Short: "Show a user's connections",
+	Long:  "Show every connection of a user with its channels, message counters and last activity, or when an offline user was last seen",
+	Args:  cobra.ExactArgs(1),
+	Run:   showUser,
+}
+
+func init() {
+	rootCmd.AddCommand(clientCmd)
+	rootCmd.AddCommand(userCmd)
+}
+
+// clientDetail is a connection as returned by /api/clients/{client} and /api/users/{user_id}
+type clientDetail struct {
+	Client struct {
+		ID              string          `json:"id"`
+		UserID          string          `json:"user_id"`
+		Username        string          `json:"username"`
+		Email           string          `json:"email"`
+		Channels        map[string]bool `json:"channels"`
+		ChannelMetadata map[string]*struct {
+			Data     interface{} `json:"data"`
+			JoinedAt time.Time   `json:"joined_at"`
+		} `json:"channel_metadata"`
+		LastSeen    time.Time `json:"last_seen"`
+		ConnectedAt time.Time `json:"connected_at"`
+		RemoteAddr  string    `json:"remote_addr"`
+		UserAgent   string    `json:"user_agent"`
+		Device      struct {
+			Browser    string `json:"browser"`
+			OS         string `json:"os"`
+			DeviceType string `json:"device_type"`
+		} `json:"device"`
+		Geo *struct {
+			Country string `json:"country"`
+			Region  string `json:"region"`
+			City    string `json:"city"`
+		} `json:"geo"`
+	} `json:"client"`
+	Stats struct {
+		MessagesSent     uint64     `json:"messages_sent"`
+		MessagesReceived uint64     `json:"messages_received"`
+		BytesSent        uint64     `json:"bytes_sent"`
+		BytesReceived    uint64     `json:"bytes_received"`
+		MessagesDropped  uint64     `json:"messages_dropped"`
+		RateLimited      uint64     `json:"rate_limited"`
+		QueueLength      int        `json:"queue_length"`
+		QueueHighWater   int64      `json:"queue_high_water"`
+		RTT              float64    `json:"rtt_ms"`
+		MissedPongs      uint64     `json:"missed_pongs"`
+		LastError        string     `json:"last_error"`
+		LastErrorAt      *time.Time `json:"last_error_at"`
+	} `json:"stats"`
+	Slow bool `json:"slow"`
+}
+
+func showClient(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	body, err := apiRequest("GET", "/api/clients/"+url.PathEscape(args[0]), nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var detail clientDetail
+	if err := json.Unmarshal(body, &detail); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	printClientDetail(detail, "")
+}
+
+func showUser(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	body, err := apiRequest("GET", "/api/users/"+url.PathEscape(args[0]), nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var response struct {
+		UserID      string         `json:"user_id"`
+		Online      bool           `json:"online"`
+		Connections []clientDetail `json:"connections"`
+		LastSeen    *time.Time     `json:"last_seen"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !response.Online {
+		fmt.Printf("User %s is offline\n", response.UserID)
+		if response.LastSeen != nil {
+			fmt.Printf("Last seen: %s\n", formatTime(*response.LastSeen))
+		}
+		return
+	}
+
+	fmt.Printf("User %s: %d connection(s)\n", response.UserID, len(response.Connections))
+	for _, detail := range response.Connections {
+		fmt.Println()
+		printClientDetail(detail, "  ")
+	}
+}
+
+// printClientDetail prints a connection as an aligned list of fields, then its channels
+func printClientDetail(detail clientDetail, indent string) {
+	client, stats := detail.Client, detail.Stats
+	field := func(name, format string, args ...interface{}) {
+		fmt.Printf("%s%-14s %s\n", indent, name+":", fmt.Sprintf(format, args...))
+	}
+
+	field("Client", "%s", client.ID)
+	if client.UserID != "" {
+		user := client.UserID
+		if client.Username != "" {
+			user += " (" + client.Username + ")"
+		}
+		if client.Email != "" {
+			user += " <" + client.Email + ">"
+		}
+		field("User", "%s", user)
+	} else {
+		field("User", "not authenticated")
+	}
+	field("Connected", "%s", formatTime(client.ConnectedAt))
+	field("Last activity", "%s", formatTime(client.LastSeen))
+	field("Address", "%s", client.RemoteAddr)
+	field("Device", "%s", strings.Join(nonEmpty(client.Device.DeviceType, client.Device.OS, client.Device.Browser), ", "))
+	if client.Geo != nil {
+		field("Location", "%s", strings.Join(nonEmpty(client.Geo.City, client.Geo.Region, client.Geo.Country), ", "))
+	}
+	field("Messages", "%d sent, %d received, %d dropped, %d rate limited", stats.MessagesSent, stats.MessagesReceived, stats.MessagesDropped, stats.RateLimited)
+	field("Bytes", "%d sent, %d received", stats.BytesSent, stats.BytesReceived)
+	field("Queue", "%d queued, high water %d", stats.QueueLength, stats.QueueHighWater)
+	field("Latency", "%.1f ms round trip, %d missed pongs", stats.RTT, stats.MissedPongs)
+	if detail.Slow {
+		field("Slow", "yes")
+	}
+	if stats.LastError != "" {
+		lastError := stats.LastError
+		if stats.LastErrorAt != nil {
+			lastError += " (" + formatTime(*stats.LastErrorAt) + ")"
+		}
+		field("Last error", "%s", lastError)
+	}
+
+	channels := make([]string, 0, len(client.Channels))
+	for name := range client.Channels {
+		channels = append(channels, name)
+	}
+	sort.Strings(channels)
+
+	fmt.Printf("%sChannels (%d):\n", indent, len(channels))
+	for _, name := range channels {
+		line := fmt.Sprintf("%s  %-30s", indent, name)
+		if metadata := client.ChannelMetadata[name]; metadata != nil {
+			line += " joined " + formatTime(metadata.JoinedAt)
+			if metadata.Data != nil {
+				if data, err := json.Marshal(metadata.Data); err == nil && string(data) != "{}" {
+					line += " " + string(data)
+				}
+			}
+		}
+		fmt.Println(line)
+	}
+}
+
+// formatTime shows a time with how long ago it was
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Local().Format("2006-01-02 15:04:05"), time.Since(t).Round(time.Second))
+}
+
+// nonEmpty returns the values that aren't empty
+func nonEmpty(values ...string) []string {
+	var kept []string
+	for _, value := range values {
+		if value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsLevel  string
+	logsGrep   string
+	logsLimit  int
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show server logs",
+	Long: `Show the server's recent log entries. With --follow, keep streaming entries as they are
+logged, including client connections and disconnections, until interrupted.`,
+	Run: showLogs,
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Stream new entries as they are logged")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "Least severe level shown: debug, info, warn or error")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only show entries whose message or fields match this regular expression")
+	logsCmd.Flags().IntVar(&logsLimit, "limit", 50, "Number of recent entries shown without --follow (0 shows all the server keeps)")
+
+	rootCmd.AddCommand(logsCmd)
+}
+
+// logEntry is a log entry as returned by /api/logs
+type logEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields"`
+}
+
+func showLogs(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	query := url.Values{}
+	if logsLevel != "" {
+		query.Set("level", logsLevel)
+	}
+	if logsGrep != "" {
+		query.Set("grep", logsGrep)
+	}
+
+	if logsFollow {
+		followLogs(query)
+		return
+	}
+
+	if logsLimit > 0 {
+		query.Set("limit", strconv.Itoa(logsLimit))
+	}
+	body, err := apiRequest("GET", "/api/logs?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var response struct {
+		Logs []logEntry `json:"logs"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	for _, entry := range response.Logs {
+		printLogEntry(entry)
+	}
+}
+
+// followLogs prints entries from the server's log stream until interrupted
+func followLogs(query url.Values) {
+	req, err := createRequest("GET", serverURL+"/api/logs/stream?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Printf("Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		fmt.Printf("Error sending request: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body := make([]byte, 512)
+		n, _ := resp.Body.Read(body)
+		fmt.Printf("Server error (%d): %s\n", resp.StatusCode, strings.TrimSpace(string(body[:n])))
+		os.Exit(1)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		resp.Body.Close()
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue // event names, comments and heartbeats
+		}
+		var entry logEntry
+		if err := json.Unmarshal([]byte(data), &entry); err == nil {
+			printLogEntry(entry)
+		}
+	}
+
+	select {
+	case <-interrupt:
+	default:
+		fmt.Println("Log stream closed by the server")
+	}
+}
+
+// printLogEntry prints an entry like the server's text logs, with client events marked
+func printLogEntry(entry logEntry) {
+	marker := " "
+	if event, _ := entry.Fields["event"].(string); strings.HasPrefix(event, "client_") {
+		marker = "*"
+	}
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var fields strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&fields, " %s=%v", key, entry.Fields[key])
+	}
+
+	fmt.Printf("%s %s %-7s %s%s\n", entry.Time.Local().Format("2006-01-02 15:04:05"), marker, "["+entry.Level+"]", entry.Message, fields.String())
+}
@@ -8,7 +8,11 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/spf13/cobra"
 )
 
@@ -123,6 +127,56 @@ func createRequest(method, url string, body io.Reader) (*http.Request, error) {
 	return req, nil
 }
 
+// apiRequest calls the HTTP API, JSON-encoding payload when it is set, and returns the response
+// body. Responses other than 2xx are returned as errors.
+func apiRequest(method, path string, payload interface{}) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling payload: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := createRequest(method, serverURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// websocketURL returns the WebSocket endpoint of the --server
+func websocketURL() string {
+	return strings.Replace(strings.TrimSuffix(serverURL, "/"), "http", "ws", 1) + "/ws"
+}
+
+// dialWebSocket opens a WebSocket connection with optional TLS verification bypass
+func dialWebSocket(url string) (*websocket.Conn, error) {
+	dialer := *websocket.DefaultDialer
+	if insecureSkipVerify {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", url, err)
+	}
+	return conn, nil
+}
+
 // checkToken validates that the HTTP token is provided
 func checkToken() {
 	if httpToken == "" {
@@ -134,44 +188,7 @@ func checkToken() {
 func sendMessage(cmd *cobra.Command, args []string) {
 	checkToken()
 
-	var payload map[string]interface{}
-
-	if filePath != "" {
-		// Read from file
-		fileData, err := os.ReadFile(filePath)
-		if err != nil {
-			fmt.Printf("Error reading file: %v\n", err)
-			os.Exit(1)
-		}
-
-		err = json.Unmarshal(fileData, &payload)
-		if err != nil {
-			fmt.Printf("Error parsing JSON file: %v\n", err)
-			os.Exit(1)
-		}
-	} else {
-		// Build payload from flags
-		if channel == "" {
-			fmt.Println("Channel is required (use --channel flag)")
-			os.Exit(1)
-		}
-
-		payload = map[string]interface{}{
-			"channel": channel,
-			"event":   event,
-		}
-
-		if data != "" {
-			var jsonData interface{}
-			err := json.Unmarshal([]byte(data), &jsonData)
-			if err != nil {
-				// If not valid JSON, treat as string
-				payload["data"] = data
-			} else {
-				payload["data"] = jsonData
-			}
-		}
-	}
+	payload := buildBroadcastPayload()
 
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -214,118 +231,175 @@ func sendMessage(cmd *cobra.Command, args []string) {
 	}
 }
 
-func listClients(cmd *cobra.Command, args []string) {
-	checkToken()
+// buildBroadcastPayload builds a /api/broadcast request body from --file, or from --channel,
+// --event and --data
+func buildBroadcastPayload() map[string]interface{} {
+	var payload map[string]interface{}
 
-	client := getHTTPClient()
-	req, err := createRequest("GET", serverURL+"/api/clients", nil)
-	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		os.Exit(1)
-	}
+	if filePath != "" {
+		// Read from file
+		fileData, err := os.ReadFile(filePath)
+		if err != nil {
+			fmt.Printf("Error reading file: %v\n", err)
+			os.Exit(1)
+		}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error sending request: %v\n", err)
-		os.Exit(1)
+		err = json.Unmarshal(fileData, &payload)
+		if err != nil {
+			fmt.Printf("Error parsing JSON file: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		// Build payload from flags
+		if channel == "" {
+			fmt.Println("Channel is required (use --channel flag)")
+			os.Exit(1)
+		}
+
+		payload = map[string]interface{}{
+			"channel": channel,
+			"event":   event,
+		}
+
+		if data != "" {
+			var jsonData interface{}
+			err := json.Unmarshal([]byte(data), &jsonData)
+			if err != nil {
+				// If not valid JSON, treat as string
+				payload["data"] = data
+			} else {
+				payload["data"] = jsonData
+			}
+		}
 	}
-	defer resp.Body.Close()
+	return payload
+}
 
-	body, err := io.ReadAll(resp.Body)
+// clientSummary is a connected client as printed by list clients
+type clientSummary struct {
+	ID       string    `json:"id"`
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	Channels []string  `json:"channels"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// channelSummary is a channel as printed by list channels
+type channelSummary struct {
+	Name        string    `json:"name"`
+	IsPrivate   bool      `json:"is_private"`
+	RequireAuth bool      `json:"require_auth"`
+	ReadOnly    bool      `json:"read_only"`
+	Clients     int       `json:"clients"`
+	MaxClients  int       `json:"max_clients"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func listClients(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	body, err := apiRequest("GET", "/api/clients", nil)
 	if err != nil {
-		fmt.Printf("Error reading response: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Server error (%d): %s\n", resp.StatusCode, string(body))
-		os.Exit(1)
+	var response struct {
+		Clients []struct {
+			ID       string          `json:"id"`
+			UserID   string          `json:"user_id"`
+			Username string          `json:"username"`
+			Channels map[string]bool `json:"channels"`
+			LastSeen time.Time       `json:"last_seen"`
+		} `json:"clients"`
 	}
-
-	var response map[string]interface{}
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	if err := json.Unmarshal(body, &response); err != nil {
 		fmt.Printf("Error parsing response: %v\n", err)
 		os.Exit(1)
 	}
 
-	clients := response["clients"].([]interface{})
-	total := response["total"].(float64)
-
-	fmt.Printf("Connected Clients (%d):\n", int(total))
-	fmt.Printf("%-36s %-15s %-20s %-15s %s\n", "ID", "User ID", "Username", "Channels", "Last Seen")
-	fmt.Printf("%s\n", "---------------------------------------------------------------------------")
-
-	for _, clientData := range clients {
-		client := clientData.(map[string]interface{})
-		id := client["id"].(string)
-		userID := ""
-		username := ""
-
-		if client["user_id"] != nil {
-			userID = client["user_id"].(string)
+	result := struct {
+		Clients []clientSummary `json:"clients"`
+		Total   int             `json:"total"`
+	}{Clients: make([]clientSummary, 0, len(response.Clients))}
+	for _, client := range response.Clients {
+		channels := make([]string, 0, len(client.Channels))
+		for name := range client.Channels {
+			channels = append(channels, name)
 		}
-		if client["username"] != nil {
-			username = client["username"].(string)
+		sort.Strings(channels)
+		result.Clients = append(result.Clients, clientSummary{
+			ID:       client.ID,
+			UserID:   client.UserID,
+			Username: client.Username,
+			Channels: channels,
+			LastSeen: client.LastSeen,
+		})
+	}
+	sort.Slice(result.Clients, func(i, j int) bool {
+		return result.Clients[i].ID < result.Clients[j].ID
+	})
+	result.Total = len(result.Clients)
+
+	printOutput(result, func() {
+		fmt.Printf("Connected Clients (%d):\n", result.Total)
+		fmt.Printf("%-36s %-15s %-20s %-15s %s\n", "ID", "User ID", "Username", "Channels", "Last Seen")
+		fmt.Printf("%s\n", "---------------------------------------------------------------------------")
+		for _, client := range result.Clients {
+			fmt.Printf("%-36s %-15s %-20s %-15d %s\n", client.ID, client.UserID, client.Username, len(client.Channels), client.LastSeen.Format(time.RFC3339Nano))
 		}
-
-		channels := client["channels"].(map[string]interface{})
-		channelCount := len(channels)
-
-		lastSeen := client["last_seen"].(string)
-
-		fmt.Printf("%-36s %-15s %-20s %-15d %s\n", id, userID, username, channelCount, lastSeen)
-	}
+	})
 }
 
 func listChannels(cmd *cobra.Command, args []string) {
 	checkToken()
 
-	client := getHTTPClient()
-	req, err := createRequest("GET", serverURL+"/api/channels", nil)
-	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		os.Exit(1)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error sending request: %v\n", err)
-		os.Exit(1)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := apiRequest("GET", "/api/channels", nil)
 	if err != nil {
-		fmt.Printf("Error reading response: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Server error (%d): %s\n", resp.StatusCode, string(body))
-		os.Exit(1)
+	var channels map[string]struct {
+		IsPrivate   bool      `json:"is_private"`
+		RequireAuth bool      `json:"require_auth"`
+		ReadOnly    bool      `json:"read_only"`
+		ClientCount int       `json:"client_count"`
+		MaxClients  int       `json:"max_clients"`
+		CreatedAt   time.Time `json:"created_at"`
 	}
-
-	var channels map[string]interface{}
-	err = json.Unmarshal(body, &channels)
-	if err != nil {
+	if err := json.Unmarshal(body, &channels); err != nil {
 		fmt.Printf("Error parsing response: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Channels (%d):\n", len(channels))
-	fmt.Printf("%-30s %-10s %-12s %-10s %s\n", "Name", "Private", "Auth Required", "Clients", "Created")
-	fmt.Printf("%s\n", "-------------------------------------------------------------------------------")
-
-	for name, channelData := range channels {
-		channel := channelData.(map[string]interface{})
-		isPrivate := channel["is_private"].(bool)
-		requireAuth := channel["require_auth"].(bool)
-		clientCount := int(channel["client_count"].(float64))
-		createdAt := channel["created_at"].(string)
-
-		fmt.Printf("%-30s %-10t %-12t %-10d %s\n", name, isPrivate, requireAuth, clientCount, createdAt)
-	}
+	result := struct {
+		Channels []channelSummary `json:"channels"`
+		Total    int              `json:"total"`
+	}{Channels: make([]channelSummary, 0, len(channels)), Total: len(channels)}
+	for name, channel := range channels {
+		result.Channels = append(result.Channels, channelSummary{
+			Name:        name,
+			IsPrivate:   channel.IsPrivate,
+			RequireAuth: channel.RequireAuth,
+			ReadOnly:    channel.ReadOnly,
+			Clients:     channel.ClientCount,
+			MaxClients:  channel.MaxClients,
+			CreatedAt:   channel.CreatedAt,
+		})
+	}
+	sort.Slice(result.Channels, func(i, j int) bool {
+		return result.Channels[i].Name < result.Channels[j].Name
+	})
+
+	printOutput(result, func() {
+		fmt.Printf("Channels (%d):\n", result.Total)
+		fmt.Printf("%-30s %-10s %-12s %-10s %s\n", "Name", "Private", "Auth Required", "Clients", "Created")
+		fmt.Printf("%s\n", "-------------------------------------------------------------------------------")
+		for _, channel := range result.Channels {
+			fmt.Printf("%-30s %-10t %-12t %-10d %s\n", channel.Name, channel.IsPrivate, channel.RequireAuth, channel.Clients, channel.CreatedAt.Format(time.RFC3339Nano))
+		}
+	})
 }
 
 func kickClient(cmd *cobra.Command, args []string) {
@@ -371,54 +445,31 @@ func kickClient(cmd *cobra.Command, args []string) {
 func checkHealth(cmd *cobra.Command, args []string) {
 	checkToken()
 
-	client := getHTTPClient()
-	req, err := createRequest("GET", serverURL+"/api/health", nil)
-	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		os.Exit(1)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error sending request: %v\n", err)
-		os.Exit(1)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := apiRequest("GET", "/api/health", nil)
 	if err != nil {
-		fmt.Printf("Error reading response: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Server error (%d): %s\n", resp.StatusCode, string(body))
-		os.Exit(1)
+	var result struct {
+		Status   string `json:"status"`
+		Clients  int    `json:"clients"`
+		Channels int    `json:"channels"`
+		NodeID   string `json:"node_id"`
+		Version  string `json:"version"`
 	}
-
-	var response map[string]interface{}
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	if err := json.Unmarshal(body, &result); err != nil {
 		fmt.Printf("Error parsing response: %v\n", err)
 		fmt.Printf("Raw response: %s\n", string(body))
 		os.Exit(1)
 	}
 
-	fmt.Printf("Server Status: %v\n", response["status"])
-
-	if clients, ok := response["clients"].(float64); ok {
-		fmt.Printf("Connected Clients: %.0f\n", clients)
-	} else {
-		fmt.Printf("Connected Clients: %v\n", response["clients"])
-	}
-
-	if channels, ok := response["channels"].(float64); ok {
-		fmt.Printf("Active Channels: %.0f\n", channels)
-	} else {
-		fmt.Printf("Active Channels: %v\n", response["channels"])
-	}
-
-	fmt.Printf("Version: %v\n", response["version"])
+	printOutput(result, func() {
+		fmt.Printf("Server Status: %s\n", result.Status)
+		fmt.Printf("Connected Clients: %d\n", result.Clients)
+		fmt.Printf("Active Channels: %d\n", result.Channels)
+		fmt.Printf("Version: %s\n", result.Version)
+	})
 }
 
 func main() {
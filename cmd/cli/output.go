@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat is how list and health commands print their result: table, json or yaml
+var outputFormat string
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "Output format of the list and health commands: table, json or yaml")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		switch outputFormat {
+		case "table", "json", "yaml":
+			return nil
+		}
+		return fmt.Errorf("invalid --output %q: expected table, json or yaml", outputFormat)
+	}
+}
+
+// printOutput prints a command's result as JSON or YAML, or calls table to print it for people.
+// The JSON and YAML documents have the same fields, named by the json tags of the result, so
+// scripts can rely on them across releases.
+func printOutput(result interface{}, table func()) {
+	switch outputFormat {
+	case "json":
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		// Going through JSON keeps the field names and time formats of the JSON output
+		data, err := json.Marshal(result)
+		var document interface{}
+		if err == nil {
+			err = json.Unmarshal(data, &document)
+		}
+		if err == nil {
+			data, err = yaml.Marshal(document)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(data))
+	default:
+		table()
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleAt   string
+	scheduleIn   string
+	scheduleNote string
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage scheduled broadcasts",
+	Long:  "Defer broadcasts to a later time, list the pending ones and cancel them, e.g. from CI/CD pipelines and cron jobs.",
+}
+
+var scheduleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Schedule a broadcast",
+	Long: `Schedule a broadcast for --at a time or --in a duration. The broadcast is built from the same
+flags as send: --channel, --event and --data, or a --file holding any /api/broadcast request.`,
+	Example: `  socket schedule create --in 2h --channel announcements --event maintenance --data '{"minutes": 30}'
+  socket schedule create --at 2026-01-01T00:00:00Z --file new-year.json --note "new year banner"`,
+	Run: createSchedule,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the pending scheduled broadcasts",
+	Run:   listSchedules,
+}
+
+var scheduleCancelCmd = &cobra.Command{
+	Use:   "cancel [schedule-id]",
+	Short: "Cancel a scheduled broadcast",
+	Args:  cobra.ExactArgs(1),
+	Run:   cancelSchedule,
+}
+
+func init() {
+	scheduleCreateCmd.Flags().StringVar(&scheduleAt, "at", "", "When to send, as an RFC 3339 time such as 2026-01-01T09:00:00Z")
+	scheduleCreateCmd.Flags().StringVar(&scheduleIn, "in", "", "How long from now to send, e.g. 30m, 12h or 7d")
+	scheduleCreateCmd.Flags().StringVar(&scheduleNote, "note", "", "Note shown in the schedule list")
+	scheduleCreateCmd.Flags().StringVar(&filePath, "file", "", "JSON file containing the broadcast request")
+	scheduleCreateCmd.Flags().StringVar(&channel, "channel", "", "Channel to send message to")
+	scheduleCreateCmd.Flags().StringVar(&event, "event", "broadcast", "Event type")
+	scheduleCreateCmd.Flags().StringVar(&data, "data", "", "JSON data to send")
+
+	scheduleCmd.AddCommand(scheduleCreateCmd, scheduleListCmd, scheduleCancelCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+// scheduledBroadcast is a scheduled broadcast as returned by /api/schedules
+type scheduledBroadcast struct {
+	ID        string                 `json:"id"`
+	SendAt    time.Time              `json:"send_at"`
+	Broadcast map[string]interface{} `json:"broadcast"`
+	Note      string                 `json:"note"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+func createSchedule(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	payload := map[string]interface{}{"note": scheduleNote}
+	switch {
+	case scheduleAt != "" && scheduleIn != "":
+		fmt.Println("Error: use either --at or --in")
+		os.Exit(1)
+	case scheduleAt != "":
+		sendAt, err := time.Parse(time.RFC3339, scheduleAt)
+		if err != nil {
+			fmt.Printf("Error: invalid time %q, use e.g. 2026-01-01T09:00:00Z\n", scheduleAt)
+			os.Exit(1)
+		}
+		payload["send_at"] = sendAt
+	case scheduleIn != "":
+		// The delay is sent rather than a time, so the client's clock doesn't matter
+		delay, err := parseDuration(scheduleIn)
+		if err != nil {
+			fmt.Printf("Error: invalid duration %q, use e.g. 30m, 12h or 7d\n", scheduleIn)
+			os.Exit(1)
+		}
+		payload["delay"] = int(delay.Seconds())
+	default:
+		fmt.Println("Error: --at or --in is required")
+		os.Exit(1)
+	}
+	payload["broadcast"] = buildBroadcastPayload()
+
+	body, err := apiRequest("POST", "/api/schedules", payload)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var response struct {
+		Schedule scheduledBroadcast `json:"schedule"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Scheduled broadcast %s for %s\n", response.Schedule.ID, response.Schedule.SendAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+func listSchedules(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	body, err := apiRequest("GET", "/api/schedules", nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var response struct {
+		Schedules []scheduledBroadcast `json:"schedules"`
+		Total     int                  `json:"total"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	if response.Schedules == nil {
+		response.Schedules = []scheduledBroadcast{}
+	}
+	response.Total = len(response.Schedules)
+
+	printOutput(response, func() {
+		fmt.Printf("Scheduled broadcasts (%d):\n", len(response.Schedules))
+		fmt.Printf("%-36s %-20s %-30s %-20s %s\n", "ID", "Send at", "Target", "Event", "Note")
+		fmt.Printf("%s\n", "----------------------------------------------------------------------------------------------------------------------")
+		for _, schedule := range response.Schedules {
+			event, _ := schedule.Broadcast["event"].(string)
+			fmt.Printf("%-36s %-20s %-30s %-20s %s\n", schedule.ID, schedule.SendAt.Local().Format("2006-01-02 15:04:05"), broadcastTarget(schedule.Broadcast), event, schedule.Note)
+		}
+	})
+}
+
+func cancelSchedule(cmd *cobra.Command, args []string) {
+	checkToken()
+
+	if _, err := apiRequest("DELETE", "/api/schedules/"+url.PathEscape(args[0]), nil); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Cancelled scheduled broadcast %s\n", args[0])
+}
+
+// broadcastTarget describes who a broadcast request is for
+func broadcastTarget(broadcast map[string]interface{}) string {
+	for _, key := range []string{"channel", "group", "user_id", "client_id"} {
+		if value, ok := broadcast[key].(string); ok && value != "" {
+			return key + " " + value
+		}
+	}
+	if broadcastType, ok := broadcast["broadcast_type"].(string); ok && broadcastType != "" {
+		return broadcastType
+	}
+	return "global"
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+)
+
+// shellHistoryLimit is how many commands the history file keeps
+const shellHistoryLimit = 500
+
+var shellHistoryFile string
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Interactive protocol shell",
+	Long: `Open an interactive prompt that keeps a WebSocket connection to the server, for exercising
+the protocol by hand: connect, authenticate, join channels and send messages, while server
+messages are printed as they arrive. Type "help" for the list of commands.`,
+	Run: runShell,
+}
+
+func init() {
+	home, _ := os.UserHomeDir()
+	shellCmd.Flags().StringVar(&shellHistoryFile, "history-file", filepath.Join(home, ".socket_history"), "File the command history is kept in across sessions (empty disables it)")
+
+	rootCmd.AddCommand(shellCmd)
+}
+
+// shellHelp lists the shell's commands
+const shellHelp = `Commands:
+  connect [url]                    Open a WebSocket connection (default: the --server's /ws)
+  disconnect                       Close the connection
+  auth <jwt>                       Authenticate the connection
+  join <channel> [data]            Join a channel, with optional JSON join data
+  leave <channel>                  Leave a channel
+  send <channel> <event> [data]    Send a message to a channel over the connection
+  raw <json>                       Send a raw frame, e.g. {"action":"ping"}
+  broadcast <channel> <event> [data]
+                                   Broadcast through the HTTP API (needs --server-token)
+  list clients|channels            List clients or channels through the HTTP API
+  history                          Show the command history
+  !<n>                             Run command <n> of the history again
+  help                             Show this help
+  exit                             Leave the shell`
+
+// shell is an interactive session holding at most one WebSocket connection
+type shell struct {
+	conn     *websocket.Conn
+	clientID string
+	mutex    sync.Mutex // guards conn, clientID and writes to conn
+
+	history []string
+	out     sync.Mutex // keeps server messages from interleaving with command output
+}
+
+func runShell(cmd *cobra.Command, args []string) {
+	sh := &shell{history: loadShellHistory(shellHistoryFile)}
+	defer sh.disconnect()
+
+	fmt.Println("Socket shell. Type \"help\" for commands.")
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		sh.prompt()
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			n, err := strconv.Atoi(line[1:])
+			if err != nil || n < 1 || n > len(sh.history) {
+				sh.printf("No command %s in the history\n", line)
+				continue
+			}
+			line = sh.history[n-1]
+			sh.printf("%s\n", line)
+		}
+
+		sh.addHistory(line)
+		if !sh.run(line) {
+			return
+		}
+	}
+}
+
+// run executes a command line, returning false when the shell should exit
+func (sh *shell) run(line string) bool {
+	parts := splitArgs(line, 2)
+	command, rest := parts[0], ""
+	if len(parts) > 1 {
+		rest = parts[1]
+	}
+
+	var err error
+	switch command {
+	case "help", "?":
+		sh.printf("%s\n", shellHelp)
+	case "exit", "quit":
+		return false
+	case "connect":
+		err = sh.connect(rest)
+	case "disconnect":
+		if !sh.disconnect() {
+			err = fmt.Errorf("not connected")
+		}
+	case "auth":
+		if rest == "" {
+			err = fmt.Errorf("usage: auth <jwt>")
+			break
+		}
+		err = sh.send(map[string]interface{}{"action": "authenticate", "token": rest})
+	case "join":
+		parts := splitArgs(rest, 2)
+		if parts[0] == "" {
+			err = fmt.Errorf("usage: join <channel> [data]")
+			break
+		}
+		message := map[string]interface{}{"action": "join_channel", "channel": parts[0]}
+		if len(parts) > 1 {
+			message["data"] = parseData(parts[1])
+		}
+		err = sh.send(message)
+	case "leave":
+		if rest == "" {
+			err = fmt.Errorf("usage: leave <channel>")
+			break
+		}
+		err = sh.send(map[string]interface{}{"action": "leave_channel", "channel": rest})
+	case "send":
+		parts := splitArgs(rest, 3)
+		if len(parts) < 2 {
+			err = fmt.Errorf("usage: send <channel> <event> [data]")
+			break
+		}
+		message := map[string]interface{}{"action": "send_message", "channel": parts[0], "event": parts[1]}
+		if len(parts) > 2 {
+			message["data"] = parseData(parts[2])
+		}
+		err = sh.send(message)
+	case "raw":
+		var message interface{}
+		if err = json.Unmarshal([]byte(rest), &message); err != nil {
+			err = fmt.Errorf("invalid JSON: %w", err)
+			break
+		}
+		err = sh.send(message)
+	case "broadcast":
+		parts := splitArgs(rest, 3)
+		if len(parts) < 2 {
+			err = fmt.Errorf("usage: broadcast <channel> <event> [data]")
+			break
+		}
+		payload := map[string]interface{}{"channel": parts[0], "event": parts[1]}
+		if len(parts) > 2 {
+			payload["data"] = parseData(parts[2])
+		}
+		err = sh.api("POST", "/api/broadcast", payload)
+	case "list":
+		switch rest {
+		case "clients", "channels":
+			err = sh.api("GET", "/api/"+rest, nil)
+		default:
+			err = fmt.Errorf("usage: list clients|channels")
+		}
+	case "history":
+		for i, entry := range sh.history {
+			sh.printf("%4d  %s\n", i+1, entry)
+		}
+	default:
+		err = fmt.Errorf("unknown command %q, type \"help\" for commands", command)
+	}
+
+	if err != nil {
+		sh.printf("Error: %v\n", err)
+	}
+	return true
+}
+
+// prompt shows the connection's client ID while connected
+func (sh *shell) prompt() {
+	sh.mutex.Lock()
+	prompt := "socket> "
+	if sh.conn != nil {
+		prompt = "socket"
+		if sh.clientID != "" {
+			prompt += "(" + sh.clientID[:min(8, len(sh.clientID))] + ")"
+		}
+		prompt += "> "
+	}
+	sh.mutex.Unlock()
+
+	sh.printf("%s", prompt)
+}
+
+// printf writes to stdout without interleaving with server messages
+func (sh *shell) printf(format string, args ...interface{}) {
+	sh.out.Lock()
+	defer sh.out.Unlock()
+	fmt.Printf(format, args...)
+}
+
+// connect opens the WebSocket connection and starts printing server messages
+func (sh *shell) connect(url string) error {
+	if url == "" {
+		url = websocketURL()
+	}
+
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+	if sh.conn != nil {
+		return fmt.Errorf("already connected, disconnect first")
+	}
+
+	conn, err := dialWebSocket(url)
+	if err != nil {
+		return err
+	}
+
+	sh.conn = conn
+	sh.clientID = ""
+	sh.printf("Connected to %s\n", url)
+	go sh.readLoop(conn)
+	return nil
+}
+
+// disconnect closes the connection, reporting whether there was one
+func (sh *shell) disconnect() bool {
+	sh.mutex.Lock()
+	conn := sh.conn
+	sh.conn = nil
+	sh.mutex.Unlock()
+
+	if conn == nil {
+		return false
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	conn.Close()
+	return true
+}
+
+// readLoop prints server messages until the connection closes
+func (sh *shell) readLoop(conn *websocket.Conn) {
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			sh.mutex.Lock()
+			current := sh.conn == conn
+			if current {
+				sh.conn = nil
+			}
+			sh.mutex.Unlock()
+
+			if current {
+				sh.printf("\nDisconnected: %v\n", err)
+				sh.prompt()
+			}
+			return
+		}
+
+		var message struct {
+			Event string                 `json:"event"`
+			Data  map[string]interface{} `json:"data"`
+		}
+		if json.Unmarshal(payload, &message) == nil && message.Event == "connected" {
+			if clientID, ok := message.Data["client_id"].(string); ok {
+				sh.mutex.Lock()
+				sh.clientID = clientID
+				sh.mutex.Unlock()
+			}
+		}
+
+		sh.printf("\n< %s\n", payload)
+		sh.prompt()
+	}
+}
+
+// send writes a frame to the connection
+func (sh *shell) send(message interface{}) error {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	if sh.conn == nil {
+		return fmt.Errorf("not connected, use connect first")
+	}
+	return sh.conn.WriteJSON(message)
+}
+
+// api calls the HTTP API and prints the response as indented JSON
+func (sh *shell) api(method, path string, payload interface{}) error {
+	if httpToken == "" {
+		return fmt.Errorf("the HTTP API needs a token, restart the shell with --server-token")
+	}
+
+	data, err := apiRequest(method, path, payload)
+	if err != nil {
+		return err
+	}
+
+	var indented bytes.Buffer
+	if json.Indent(&indented, data, "", "  ") != nil {
+		indented.Write(data)
+	}
+	sh.printf("%s\n", strings.TrimSpace(indented.String()))
+	return nil
+}
+
+// addHistory records a command and appends it to the history file
+func (sh *shell) addHistory(line string) {
+	if len(sh.history) > 0 && sh.history[len(sh.history)-1] == line {
+		return
+	}
+	sh.history = append(sh.history, line)
+	if len(sh.history) > shellHistoryLimit {
+		sh.history = sh.history[len(sh.history)-shellHistoryLimit:]
+	}
+	saveShellHistory(shellHistoryFile, sh.history)
+}
+
+// loadShellHistory reads the commands of previous sessions
+func loadShellHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > shellHistoryLimit {
+		history = history[len(history)-shellHistoryLimit:]
+	}
+	return history
+}
+
+// saveShellHistory writes the history file, which only the user can read. auth commands stay
+// out of it, so tokens aren't kept on disk.
+func saveShellHistory(path string, history []string) {
+	if path == "" {
+		return
+	}
+
+	var lines []string
+	for _, line := range history {
+		if !strings.HasPrefix(line, "auth ") {
+			lines = append(lines, line)
+		}
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// splitArgs splits a command line into at most n whitespace-separated arguments; the last one
+// keeps the rest of the line, so JSON data can contain spaces
+func splitArgs(line string, n int) []string {
+	var args []string
+	line = strings.TrimSpace(line)
+	for len(args) < n-1 {
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			break
+		}
+		args = append(args, line[:i])
+		line = strings.TrimSpace(line[i:])
+	}
+	return append(args, line)
+}
+
+// parseData decodes JSON message data, falling back to the raw string like the send command
+func parseData(value string) interface{} {
+	var data interface{}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return value
+	}
+	return data
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+
+	"socket-server/pkg/client"
+)
+
+var (
+	subscribeChannels []string
+	subscribeEvents   []string
+	subscribeToken    string
+)
+
+var subscribeCmd = &cobra.Command{
+	Use:   "subscribe",
+	Short: "Print the events broadcast to channels",
+	Long: `Join channels over a WebSocket and print every event they receive as indented JSON until
+interrupted, to see what Laravel and the API actually broadcast. The connection is
+re-established and the channels joined again if it drops.`,
+	Example: `  socket subscribe --channel orders
+  socket subscribe --channel private-user.42 --token $JWT --events order.created,order.paid`,
+	Run: subscribe,
+}
+
+func init() {
+	subscribeCmd.Flags().StringSliceVarP(&subscribeChannels, "channel", "c", nil, "Channel to join (repeatable, or comma-separated)")
+	subscribeCmd.Flags().StringSliceVar(&subscribeEvents, "events", nil, "Only receive these events of the channels (default: all)")
+	subscribeCmd.Flags().StringVar(&subscribeToken, "token", "", "JWT to authenticate with, for channels that require it")
+	subscribeCmd.MarkFlagRequired("channel")
+
+	rootCmd.AddCommand(subscribeCmd)
+}
+
+func subscribe(cmd *cobra.Command, args []string) {
+	dialer := *websocket.DefaultDialer
+	if insecureSkipVerify {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	channels := make(map[string]bool, len(subscribeChannels))
+	for _, name := range subscribeChannels {
+		channels[name] = true
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c, err := client.New(client.Options{
+		URL:    serverURL,
+		Token:  subscribeToken,
+		Dialer: &dialer,
+		OnDisconnect: func(err error) {
+			if errors.Is(err, client.ErrUnauthorized) {
+				fmt.Fprintln(os.Stderr, "The server rejected the token")
+				stop()
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Connection lost (%v), reconnecting\n", err)
+		},
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	c.On("*", func(message client.Message) {
+		switch {
+		case message.Event == "error":
+			fmt.Fprintf(os.Stderr, "%v\n", message.Err())
+		case channels[message.Channel]:
+			printEvent(message)
+		}
+	})
+
+	if err := c.Connect(ctx); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer c.Close()
+
+	for _, name := range subscribeChannels {
+		if err := c.Join(ctx, name, subscribeEvents...); err != nil {
+			fmt.Printf("Error joining %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Subscribed to %v, press Ctrl+C to stop\n", subscribeChannels)
+	<-ctx.Done()
+}
+
+// printEvent prints a channel event as indented JSON
+func printEvent(message client.Message) {
+	data, err := json.MarshalIndent(message, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting event: %v\n", err)
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"socket-server/internal/config"
+)
+
+var healthcheckTimeout time.Duration
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Probe the local server's readiness endpoint and exit 0 (ready) or 1",
+	Long: `Performs a readiness probe against the server running on this host, for use as a
+Docker HEALTHCHECK. It uses the unauthenticated /readyz endpoint, so neither curl nor the
+API token is needed inside the container.`,
+	Run: runHealthcheck,
+}
+
+func init() {
+	healthcheckCmd.Flags().StringVarP(&port, "port", "p", "", "Port the server listens on (default: 8080 or SOCKET_PORT env var)")
+	healthcheckCmd.Flags().DurationVar(&healthcheckTimeout, "timeout", 3*time.Second, "Probe timeout")
+	rootCmd.AddCommand(healthcheckCmd)
+}
+
+func runHealthcheck(cmd *cobra.Command, args []string) {
+	cfg := config.New()
+	if port != "" {
+		cfg.Port = port
+	}
+
+	client := &http.Client{Timeout: healthcheckTimeout}
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		// The certificate is issued for the public hostname, not the loopback address
+		scheme = "https"
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	resp, err := client.Get(fmt.Sprintf("%s://127.0.0.1:%s/readyz", scheme, cfg.Port))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "unhealthy: readiness probe returned HTTP %d\n", resp.StatusCode)
+		os.Exit(1)
+	}
+
+	fmt.Println("healthy")
+}
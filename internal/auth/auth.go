@@ -36,6 +36,43 @@ func (s *Service) GenerateToken(userID, channel string) (string, error) {
 	return tokenString, nil
 }
 
+// TokenClaims are the identity claims of a connection token, as read by ExtractUserInfo and
+// IsObserver
+type TokenClaims struct {
+	UserID   string
+	Username string
+	Email    string
+	Observer bool
+}
+
+// SignToken signs a token carrying the claims, expiring after ttl (0 never expires). Empty
+// claims are left out.
+func (s *Service) SignToken(claims TokenClaims, ttl time.Duration) (string, error) {
+	mapClaims := jwt.MapClaims{
+		"user_id": claims.UserID,
+		"iat":     time.Now().Unix(),
+	}
+	if claims.Username != "" {
+		mapClaims["username"] = claims.Username
+	}
+	if claims.Email != "" {
+		mapClaims["email"] = claims.Email
+	}
+	if claims.Observer {
+		mapClaims["observer"] = true
+	}
+	if ttl > 0 {
+		mapClaims["exp"] = time.Now().Add(ttl).Unix()
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
+	tokenString, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return tokenString, nil
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func (s *Service) ValidateToken(tokenStr string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
@@ -62,6 +99,13 @@ func (s *Service) ValidateToken(tokenStr string) (jwt.MapClaims, error) {
 	return claims, nil
 }
 
+// IsObserver reports whether the claims grant a read-only observer connection, with an
+// "observer": true claim
+func (s *Service) IsObserver(claims jwt.MapClaims) bool {
+	observer, _ := claims["observer"].(bool)
+	return observer
+}
+
 // ExtractUserInfo extracts user information from JWT claims
 func (s *Service) ExtractUserInfo(claims jwt.MapClaims) (userID, username, email string) {
 	if uid, exists := claims["user_id"]; exists {
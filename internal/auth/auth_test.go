@@ -102,6 +102,46 @@ func TestValidateToken(t *testing.T) {
 	}
 }
 
+func TestSignToken(t *testing.T) {
+	authService := New("test-secret")
+
+	tokenString, err := authService.SignToken(TokenClaims{UserID: "42", Username: "alice", Observer: true}, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	claims, err := authService.ValidateToken(tokenString)
+	if err != nil {
+		t.Fatalf("Failed to validate signed token: %v", err)
+	}
+
+	userID, username, email := authService.ExtractUserInfo(claims)
+	if userID != "42" || username != "alice" || email != "" {
+		t.Errorf("Expected user 42 alice without email, got %q %q %q", userID, username, email)
+	}
+	if _, exists := claims["email"]; exists {
+		t.Error("Expected an empty email to be left out")
+	}
+	if !authService.IsObserver(claims) {
+		t.Error("Expected an observer token")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil || time.Until(exp.Time) < 119*time.Minute || time.Until(exp.Time) > 2*time.Hour {
+		t.Errorf("Expected the token to expire in 2 hours, got %v (%v)", exp, err)
+	}
+
+	tokenString, err = authService.SignToken(TokenClaims{UserID: "42"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	claims, err = authService.ValidateToken(tokenString)
+	if err != nil {
+		t.Fatalf("Failed to validate signed token: %v", err)
+	}
+	if _, exists := claims["exp"]; exists {
+		t.Error("Expected a token without TTL not to expire")
+	}
+}
+
 func TestExtractUserInfo(t *testing.T) {
 	secret := "test-secret"
 	authService := New(secret)
@@ -319,3 +359,84 @@ func TestExtractUserInfoWithDifferentTypes(t *testing.T) {
 		t.Errorf("Expected email '<nil>', got '%s'", email)
 	}
 }
+
+func TestResumeSignerRotation(t *testing.T) {
+	oldSigner := NewResumeSigner([]string{"old-secret"})
+	token, claims, err := oldSigner.Issue("client-1", "42")
+	if err != nil {
+		t.Fatalf("Unexpected error issuing resume token: %v", err)
+	}
+
+	// After rotation, tokens signed with the previous secret still verify
+	rotated := NewResumeSigner([]string{"new-secret", "old-secret"})
+	verified, err := rotated.Verify(token)
+	if err != nil {
+		t.Fatalf("Expected token signed with old secret to verify, got %v", err)
+	}
+	if verified.SessionID != "client-1" || verified.UserID != "42" || verified.Nonce != claims.Nonce {
+		t.Errorf("Unexpected claims: %+v", verified)
+	}
+
+	// Once the old secret is dropped, they don't
+	if _, err := NewResumeSigner([]string{"new-secret"}).Verify(token); err != ErrInvalidResumeToken {
+		t.Errorf("Expected ErrInvalidResumeToken, got %v", err)
+	}
+
+	// Tampering with the payload breaks the signature
+	tampered := "x" + token
+	if _, err := rotated.Verify(tampered); err != ErrInvalidResumeToken {
+		t.Errorf("Expected ErrInvalidResumeToken for tampered token, got %v", err)
+	}
+}
+
+func TestWebhookSignatures(t *testing.T) {
+	body := []byte(`{"type":"invoice.paid"}`)
+	signature := fmt.Sprintf("%x", hmacSHA256("whsec", body))
+
+	if err := VerifyHMACSignature("whsec", "sha256="+signature, body); err != nil {
+		t.Errorf("Expected a valid HMAC signature, got %v", err)
+	}
+	if err := VerifyHMACSignature("other", "sha256="+signature, body); err != ErrInvalidWebhookSignature {
+		t.Errorf("Expected ErrInvalidWebhookSignature for the wrong secret, got %v", err)
+	}
+	if err := VerifyHMACSignature("whsec", signature, body); err != ErrInvalidWebhookSignature {
+		t.Errorf("Expected ErrInvalidWebhookSignature without the sha256= prefix, got %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	stripeSignature := fmt.Sprintf("%x", hmacSHA256("whsec", append([]byte("1700000000."), body...)))
+	header := "t=1700000000,v1=deadbeef,v1=" + stripeSignature
+
+	if err := VerifyStripeSignature("whsec", header, body, now, StripeSignatureTolerance); err != nil {
+		t.Errorf("Expected a valid Stripe signature, got %v", err)
+	}
+	if err := VerifyStripeSignature("whsec", header, body, now.Add(10*time.Minute), StripeSignatureTolerance); err != ErrInvalidWebhookSignature {
+		t.Errorf("Expected ErrInvalidWebhookSignature for a stale timestamp, got %v", err)
+	}
+	if err := VerifyStripeSignature("whsec", header, []byte(`{"type":"tampered"}`), now, StripeSignatureTolerance); err != ErrInvalidWebhookSignature {
+		t.Errorf("Expected ErrInvalidWebhookSignature for a tampered body, got %v", err)
+	}
+}
+
+func TestIsObserver(t *testing.T) {
+	service := New("test-secret")
+
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		expected bool
+	}{
+		{"observer claim", jwt.MapClaims{"user_id": "1", "observer": true}, true},
+		{"observer claim false", jwt.MapClaims{"user_id": "1", "observer": false}, false},
+		{"observer claim as string", jwt.MapClaims{"user_id": "1", "observer": "true"}, false},
+		{"no observer claim", jwt.MapClaims{"user_id": "1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if observer := service.IsObserver(tt.claims); observer != tt.expected {
+				t.Errorf("Expected IsObserver %v, got %v", tt.expected, observer)
+			}
+		})
+	}
+}
@@ -8,4 +8,10 @@ var (
 
 	// ErrInvalidClaims indicates invalid token claims
 	ErrInvalidClaims = errors.New("invalid token claims")
+
+	// ErrInvalidResumeToken indicates a malformed resume token or one with a bad signature
+	ErrInvalidResumeToken = errors.New("invalid resume token")
+
+	// ErrInvalidWebhookSignature indicates a missing, malformed, stale or wrong webhook signature
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
 )
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeSignatureTolerance is how old a Stripe webhook timestamp may be before it is rejected
+const StripeSignatureTolerance = 5 * time.Minute
+
+// VerifyHMACSignature checks a "sha256=<hex>" signature header, as sent by GitHub in
+// X-Hub-Signature-256, against the HMAC-SHA256 of the body
+func VerifyHMACSignature(secret, header string, body []byte) error {
+	signature, found := strings.CutPrefix(header, "sha256=")
+	if !found {
+		return ErrInvalidWebhookSignature
+	}
+	return compareSignature(signature, hmacSHA256(secret, body))
+}
+
+// SignHMACSignature returns the "sha256=<hex>" signature of body checked by VerifyHMACSignature
+func SignHMACSignature(secret string, body []byte) string {
+	return "sha256=" + hex.EncodeToString(hmacSHA256(secret, body))
+}
+
+// VerifyStripeSignature checks a Stripe-Signature header ("t=<unix>,v1=<hex>[,v1=<hex>]").
+// Signatures cover "<timestamp>.<body>", and timestamps older than tolerance are rejected to
+// prevent replays.
+func VerifyStripeSignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidWebhookSignature
+	}
+	if age := now.Sub(time.Unix(signedAt, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidWebhookSignature
+	}
+
+	expected := hmacSHA256(secret, append([]byte(timestamp+"."), body...))
+	for _, signature := range signatures {
+		if compareSignature(signature, expected) == nil {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
+
+func hmacSHA256(secret string, data []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// compareSignature compares a hex signature with the expected MAC in constant time
+func compareSignature(signature string, expected []byte) error {
+	decoded, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, expected) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// FileError is an error in a configuration file, located by line and column when known
+type FileError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *FileError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// pathError is an error in the part of a JSON document at path, a list of object keys and array
+// indexes, so FileError can locate it
+type pathError struct {
+	path []interface{}
+	err  error
+}
+
+func (e *pathError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pathError) Unwrap() error {
+	return e.err
+}
+
+// errorAt attaches the JSON path of the element an error is about
+func errorAt(err error, path ...interface{}) error {
+	return &pathError{path: path, err: err}
+}
+
+// fileError locates an error of a document read from a file: JSON syntax and type errors by
+// their offset, rule errors by the element they are about
+func fileError(filename string, data []byte, err error) error {
+	located := &FileError{File: filename, Err: err}
+
+	offset := int64(-1)
+	var pathErr *pathError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &pathErr):
+		offset = jsonPathOffset(data, pathErr.path)
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset - 1
+	}
+	if offset >= 0 {
+		located.Line, located.Column = lineColumn(data, offset)
+	}
+	return located
+}
+
+// jsonPathOffset returns the offset of the element at path in a JSON document, or of the
+// deepest part of the path found; -1 when not even the first step is found
+func jsonPathOffset(data []byte, path []interface{}) int64 {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	located := int64(-1)
+	for _, step := range path {
+		if _, err := decoder.Token(); err != nil {
+			return located
+		}
+
+		found := false
+		for i := 0; decoder.More(); i++ {
+			offset := skipSeparators(data, decoder.InputOffset())
+			if key, ok := step.(string); ok {
+				token, err := decoder.Token()
+				if err != nil {
+					return located
+				}
+				found = token == key
+			} else {
+				found = step == i
+			}
+			if found {
+				located = offset
+				break
+			}
+
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return located
+			}
+		}
+		if !found {
+			return located
+		}
+	}
+	return located
+}
+
+// skipSeparators advances an offset past whitespace and commas to the start of the next token
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineColumn converts a byte offset to a 1-based line and column, counting columns in characters
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return bytes.Count(before, []byte("\n")) + 1, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// Check validates the configuration without starting the server, returning every problem found
+// rather than the first: the channel patterns and rules of list settings, named after their
+// environment variable, the rule files, located by line and column, and the other settings
+// checked by Validate.
+func (c *Config) Check() []error {
+	var problems []error
+
+	if _, err := ParseRateLimitRules(c.ChannelRateLimits); err != nil {
+		problems = append(problems, fmt.Errorf("CHANNEL_RATE_LIMITS: %w", err))
+	}
+	if _, err := ParseRateLimitRules(c.ChannelBandwidthLimits); err != nil {
+		problems = append(problems, fmt.Errorf("CHANNEL_BANDWIDTH_LIMITS: %w", err))
+	}
+	if _, err := ParseSizeRules(c.ChannelBlobLimits); err != nil {
+		problems = append(problems, fmt.Errorf("CHANNEL_BLOB_LIMITS: %w", err))
+	}
+	if _, err := ParseDuplicatePolicyRules(c.ChannelDuplicatePolicies); err != nil {
+		problems = append(problems, fmt.Errorf("CHANNEL_DUPLICATE_POLICIES: %w", err))
+	}
+	if _, err := ParseTraceSelectors(c.TraceWire); err != nil {
+		problems = append(problems, fmt.Errorf("TRACE_WIRE: %w", err))
+	}
+	for _, setting := range []struct {
+		name     string
+		patterns []string
+		err      error
+	}{
+		{"RELIABLE_CHANNELS", c.ReliableChannels, ErrInvalidChannelPattern},
+		{"CLIENT_EVENTS_CHANNELS", c.ClientEventChannels, ErrInvalidChannelPattern},
+		{"CLUSTER_CHANNELS", c.ClusterChannels, ErrInvalidChannelPattern},
+		{"ALLOWED_ORIGINS", c.AllowedOrigins, ErrInvalidAllowedOrigin},
+	} {
+		for i, pattern := range setting.patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				problems = append(problems, fmt.Errorf("%s: item %d: %w %q", setting.name, i+1, setting.err, pattern))
+			}
+		}
+	}
+
+	if c.IngestSourcesFile != "" {
+		if _, err := LoadIngestSources(c.IngestSourcesFile); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.PurgeRulesFile != "" {
+		if _, err := LoadPurgeRules(c.PurgeRulesFile); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.EventSchemasFile != "" {
+		if _, err := LoadEventSchemas(c.EventSchemasFile); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	// Validate stops at the first problem, which may be one of the list settings reported above
+	if err := c.Validate(); err != nil {
+		reported := false
+		for _, problem := range problems {
+			reported = reported || errors.Is(problem, err) || strings.HasSuffix(problem.Error(), err.Error())
+		}
+		if !reported {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
@@ -1,8 +1,16 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration for the socket server
@@ -16,10 +24,405 @@ type Config struct {
 	TempDir    string
 	WebDir     string
 	Debug      bool
+
+	// AdminPort serves the /api endpoints, /metrics and the admin interface on their own
+	// listener, as a port or host:port such as 127.0.0.1:9090, keeping them off the public
+	// port (empty serves everything on Port)
+	AdminPort string
+
+	// LogLevel is the least severe level logged: debug, info, warn or error. SOCKET_DEBUG=true
+	// defaults it to debug.
+	LogLevel string
+	// LogFormat is text or json (one object per line, with structured fields as keys)
+	LogFormat string
+	// LogOutput is stdout, stderr, syslog or a file path logs are appended to
+	LogOutput string
+
+	// TLSCertFile and TLSKeyFile serve HTTPS and WSS directly with a PEM certificate and key
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSAutocertHosts obtains certificates for these hostnames from Let's Encrypt instead
+	TLSAutocertHosts []string
+	// TLSAutocertCacheDir stores the obtained certificates across restarts
+	TLSAutocertCacheDir string
+	// TLSAutocertEmail is the optional contact address of the ACME account
+	TLSAutocertEmail string
+	// TLSAutocertHTTPAddr answers ACME HTTP-01 challenges and redirects plain HTTP to HTTPS
+	TLSAutocertHTTPAddr string
+
+	// DirectHistorySize is the number of messages retained per direct message channel (0 disables history)
+	DirectHistorySize int
+
+	// ChannelHistorySize is the number of messages retained per regular channel (0 disables history)
+	ChannelHistorySize int
+
+	// ReliableChannels lists channel name patterns that run in ACK mode (sequenced messages and read receipts)
+	ReliableChannels []string
+
+	// ClientEventChannels lists channel name patterns whose members may relay client- events
+	// (whispers) to each other
+	ClientEventChannels []string
+
+	// DispatchStrategy selects how payloads reach Laravel: "exec" (an artisan process per
+	// payload, the default), "worker-pool" or "http-callback"
+	DispatchStrategy string
+	// DispatchWorkers is the number of PHP worker processes of the worker-pool strategy
+	DispatchWorkers int
+	// DispatchWorkerMaxJobs recycles a PHP worker after this many payloads (0 never recycles)
+	DispatchWorkerMaxJobs int
+	// DispatchTimeout bounds how long a worker or the callback may take per payload
+	DispatchTimeout time.Duration
+	// DispatchCallbackURL is the Laravel route receiving payloads with the http-callback strategy
+	DispatchCallbackURL string
+	// DispatchCallbackSecret signs http-callback requests with an HMAC-SHA256 of the body
+	DispatchCallbackSecret string
+	// DispatchCallbackRetries is how many times a callback failing with a network error, 429
+	// or 5xx response is retried
+	DispatchCallbackRetries int
+
+	// DispatchOutboxDir records payloads on disk until they reached Laravel, replaying those
+	// left undelivered on the next start (empty disables)
+	DispatchOutboxDir string
+
+	// DispatchConnectionEvents dispatches client_connected and client_disconnected payloads to
+	// Laravel
+	DispatchConnectionEvents bool
+
+	// DispatchBatchInterval enables deferred batching of client messages sent to Laravel (0 disables)
+	DispatchBatchInterval time.Duration
+	// DispatchBatchSize flushes a batch early once this many messages are pending
+	DispatchBatchSize int
+
+	// ChannelRateLimits caps outbound broadcasts per channel, as "pattern=msgs_per_sec" pairs
+	// (e.g. "telemetry.*=10,ticker.*=5"). Throttled messages are coalesced latest-wins per event.
+	ChannelRateLimits string
+
+	// NodeID identifies this server instance in welcome messages and routing hints
+	NodeID string
+	// IDFormat is the format of generated message and client IDs: "uuid" (the default), or the
+	// time-sortable "ulid" or "ksuid"
+	IDFormat string
+
+	// ConfigBackend enables dynamic configuration from "consul" or "etcd" (empty disables)
+	ConfigBackend string
+	// ConfigBackendAddr is the backend HTTP address (defaults to the backend's local agent)
+	ConfigBackendAddr string
+	// ConfigKey is the key holding the dynamic settings JSON document
+	ConfigKey string
+	// ConfigPollInterval is how often etcd is polled for changes (Consul uses blocking queries)
+	ConfigPollInterval time.Duration
+
+	// ShutdownGrace is how long a draining server spreads out closing connections before exit.
+	// Keep it below the pod's terminationGracePeriodSeconds.
+	ShutdownGrace time.Duration
+
+	// PodName and PodNamespace identify the Kubernetes pod (set via the downward API)
+	PodName      string
+	PodNamespace string
+	// PodLabelsFile is a downward API volume file holding the pod's labels
+	PodLabelsFile string
+	// PodLabels holds the labels loaded from PodLabelsFile at startup
+	PodLabels map[string]string
+
+	// IngestSourcesFile is a JSON file of webhook sources accepted at /api/ingest/{source}
+	IngestSourcesFile string
+	// IngestSources holds the sources loaded from IngestSourcesFile at startup
+	IngestSources map[string]IngestSource
+	// PurgeRulesFile is a JSON file of CDN purge rules triggered by API broadcasts
+	PurgeRulesFile string
+	// PurgeRules holds the rules loaded from PurgeRulesFile at startup
+	PurgeRules []PurgeRule
+	// EventSchemasFile is a JSON object of JSON Schemas by event name, which the data of API
+	// broadcasts of those events must match
+	EventSchemasFile string
+	// EventSchemas holds the schemas loaded from EventSchemasFile at startup
+	EventSchemas map[string]json.RawMessage
+
+	// PushFCMCredentials is a Google service account key file enabling the FCM push fallback
+	PushFCMCredentials string
+	// PushAPNsKeyFile is an APNs .p8 token signing key enabling the APNs push fallback; it
+	// requires PushAPNsKeyID, PushAPNsTeamID and PushAPNsTopic (the app's bundle ID)
+	PushAPNsKeyFile string
+	PushAPNsKeyID   string
+	PushAPNsTeamID  string
+	PushAPNsTopic   string
+	// PushAPNsSandbox sends to the APNs development environment
+	PushAPNsSandbox bool
+
+	// SMTPHost enables the email fallback of critical broadcasts, sending through this server
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPFrom is the sender address of fallback emails
+	SMTPFrom string
+	// EmailTemplateFile is a Go template rendering fallback emails (.html files send HTML email)
+	EmailTemplateFile string
+	// CriticalOfflineThreshold is how long a user must be offline before critical broadcasts
+	// are delivered through the fallback sinks
+	CriticalOfflineThreshold time.Duration
+
+	// BridgeURL is the base URL of a remote server that broadcasts on BridgeChannels are
+	// forwarded to (empty disables bridging)
+	BridgeURL string
+	// BridgeToken is the remote server's API token
+	BridgeToken string
+	// BridgeChannels are the channel patterns whose broadcasts are forwarded
+	BridgeChannels []string
+	// BridgeQueueSize is the number of broadcasts buffered for the remote server
+	BridgeQueueSize int
+	// BridgeMaxRetries is how many times a failed forward is retried before it is given up on
+	BridgeMaxRetries int
+
+	// StandbyOf is the admin API base URL of the primary this node is a warm standby of (empty
+	// runs as a primary). A standby copies the primary's state and refuses clients until promoted.
+	StandbyOf string
+	// StandbyToken is the primary's API token (empty uses HTTPToken)
+	StandbyToken string
+	// StandbySyncInterval is how often a standby copies the primary's state
+	StandbySyncInterval time.Duration
+
+	// ClusterRedisURL is the redis:// or rediss:// URL of the Redis the nodes of a cluster share
+	// bans and rate limits through (empty runs standalone)
+	ClusterRedisURL string
+	// ClusterPrefix namespaces the cluster's Redis keys and channel, so clusters can share a Redis
+	ClusterPrefix string
+	// ClusterChannels are the channel patterns whose broadcasts are relayed to the other nodes
+	ClusterChannels []string
+	// PublicURL is the WebSocket URL clients reach this node at directly, which the other nodes
+	// of the cluster suggest to their clients when they drain or are busy
+	PublicURL string
+	// MaxConnections is the number of connections the node is sized for and accepts (0 for
+	// unlimited)
+	MaxConnections int
+	// RebalanceThreshold is the percentage of MaxConnections from which the node is under
+	// pressure: its peers stop suggesting it, and it suggests them to new clients (0 disables)
+	RebalanceThreshold int
+	// MaxConnectionsPerIP is the most connections accepted from one address (0 for unlimited)
+	MaxConnectionsPerIP int
+	// MaxChannelsPerClient is the most channels a connection may join (0 for unlimited)
+	MaxChannelsPerClient int
+
+	// ModerationLogSize is the number of moderation actions kept per channel (0 disables the log)
+	ModerationLogSize int
+
+	// DefaultLocale is the language of error and system messages for clients that don't select one
+	DefaultLocale string
+	// LocaleCatalog is a JSON file of additional translations, merged over the built-in catalog
+	LocaleCatalog string
+
+	// GeoIPDatabase is the path to a MaxMind GeoIP2/GeoLite2 database (empty disables Geo-IP)
+	GeoIPDatabase string
+	// GeoAllowedCountries, when set, only accepts connections from these ISO country codes
+	GeoAllowedCountries []string
+	// GeoBlockedCountries rejects connections from these ISO country codes
+	GeoBlockedCountries []string
+
+	// HistoryTTL drops retained channel history older than this (0 keeps messages until evicted)
+	HistoryTTL time.Duration
+	// ExpirySweepInterval is how often expired history and channel grants are reclaimed (0 disables)
+	ExpirySweepInterval time.Duration
+
+	// StateFile persists channels, groups, bans and channel grants across restarts (empty disables)
+	StateFile string
+	// StateSnapshotInterval is how often the state file is rewritten (it is also saved on shutdown)
+	StateSnapshotInterval time.Duration
+
+	// AnalyticsFile stores the daily usage rollups served by /api/analytics (empty disables them)
+	AnalyticsFile string
+	// AnalyticsRetentionDays is how many days of rollups are kept
+	AnalyticsRetentionDays int
+
+	// ExportDir is where POST /api/channels/{channel}/exports writes history exports (empty
+	// disables them; downloads are always available)
+	ExportDir string
+
+	// ResumeSecrets signs session resume tokens, newest first (empty disables session resumption)
+	ResumeSecrets []string
+	// ResumeTTL is how long a disconnected session can be resumed
+	ResumeTTL time.Duration
+	// ResumeIPPolicy handles resumes from a different IP: "allow", "log" (the default) or "reject"
+	ResumeIPPolicy string
+
+	// CompressionMinSize is the message size in bytes below which permessage-deflate is skipped
+	CompressionMinSize int
+
+	// ChunkSize splits messages encoding to more bytes than this into chunk frames carrying at
+	// most ChunkSize bytes each, which clients reassemble (0 disables chunking)
+	ChunkSize int
+	// ChunkedMessageMaxSize is the largest message clients may send in chunks (0 refuses them)
+	ChunkedMessageMaxSize int
+
+	// PayloadCompression compresses Laravel payload files: "" (disabled) or "zstd"
+	PayloadCompression string
+	// PayloadCompressionMinSize is the payload size in bytes below which files are left uncompressed
+	PayloadCompressionMinSize int
+
+	// PingInterval is the initial ping interval of a connection. It adapts between
+	// PingMinInterval (flaky connections) and PingMaxInterval (stable ones).
+	PingInterval    time.Duration
+	PingMinInterval time.Duration
+	PingMaxInterval time.Duration
+	// PongTimeout is how long a connection may stay silent past two ping intervals before it is
+	// closed (0 uses the default of 10 seconds)
+	PongTimeout time.Duration
+	// ReadTimeout, when set, is how long a connection may stay silent whatever its ping interval
+	ReadTimeout time.Duration
+	// MaxMessageSize is the largest message accepted from a client in bytes (0 uses the default
+	// of 512KB)
+	MaxMessageSize int
+
+	// ClientBandwidthLimit caps the bytes per second written to each connection (0 disables)
+	ClientBandwidthLimit int64
+	// BandwidthCapAction is what happens to clients over the cap: "throttle" or "disconnect"
+	BandwidthCapAction string
+	// ChannelBandwidthLimits caps the bytes per second fanned out per channel, as
+	// "pattern=bytes_per_sec" pairs. Messages over the cap are dropped.
+	ChannelBandwidthLimits string
+
+	// BlobMaxSize is the largest binary blob clients may relay through a channel (0 disables
+	// blobs)
+	BlobMaxSize int
+	// ChannelBlobLimits overrides BlobMaxSize for channels matching a pattern, as
+	// "pattern=bytes" pairs; 0 refuses blobs on those channels
+	ChannelBlobLimits string
+
+	// S3-compatible bucket clients upload large files to with pre-signed URLs, only the object
+	// reference going through the channel; enabled when UploadBucket is set. UploadEndpoint
+	// defaults to the AWS S3 endpoint of the region.
+	UploadEndpoint  string
+	UploadBucket    string
+	UploadRegion    string
+	UploadAccessKey string
+	UploadSecretKey string
+	// UploadPathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key
+	UploadPathStyle bool
+	// UploadPublicURL is the base URL uploaded objects are read from; without it, channels
+	// receive pre-signed download URLs
+	UploadPublicURL string
+	// UploadURLTTL is how long an upload URL is valid, and an upload may take to be completed
+	UploadURLTTL time.Duration
+	// UploadMaxSize is the largest file clients may upload
+	UploadMaxSize int64
+
+	// ClientMessageRate caps the messages per second each connection may send (0 disables)
+	ClientMessageRate int
+	// ClientMessageBurst is how many messages a connection may send at once above the rate
+	ClientMessageBurst int
+	// ClientRateLimitAction is what happens to clients over the rate: "throttle", which stops
+	// reading from them until they are back under it, or "disconnect"
+	ClientRateLimitAction string
+	// APIRateLimit caps the /api requests per second from each IP address (0 disables)
+	APIRateLimit int
+	// APIRateBurst is how many /api requests an IP address may make at once above the rate
+	APIRateBurst int
+
+	// TraceWire selects the clients whose frames are traced, as "client:<id>", "user:<id>" or
+	// "channel:<pattern>" selectors, or "*" for every client (empty disables tracing)
+	TraceWire []string
+	// TraceWireFile receives traced frames as NDJSON (empty writes them to the log)
+	TraceWireFile string
+	// TraceWireRedact lists JSON keys redacted from traced frames, on top of the token keys
+	// that are always redacted
+	TraceWireRedact []string
+
+	// PresenceGrace delays the leave_channel dispatch of disconnected users; rejoining within it
+	// suppresses both the leave and the rejoin (0 dispatches leaves immediately)
+	PresenceGrace time.Duration
+
+	// AllowedOrigins are the origin patterns browsers may open WebSocket connections from, e.g.
+	// https://*.example.com (empty allows only the server's own origin)
+	AllowedOrigins []string
+	// AllowAllOrigins disables the origin check, for development
+	AllowAllOrigins bool
+
+	// SendQueueSize is the number of outbound messages buffered per connection
+	SendQueueSize int
+	// SlowClientPolicy handles connections whose queue stays full: "disconnect" (the default)
+	// or "drop", which only drops the messages that don't fit
+	SlowClientPolicy string
+	// WriteTimeout is the deadline for writing a single frame; slower connections are closed
+	WriteTimeout time.Duration
+
+	// BroadcastConcurrency caps the fan-outs running at once (0 is unlimited)
+	BroadcastConcurrency int
+	// BroadcastQueueSize is how many broadcasts may wait for a slot before new ones are rejected
+	BroadcastQueueSize int
+
+	// UserChannels subscribes authenticated connections to their personal channel
+	UserChannels bool
+	// UserChannelTemplate names the personal channel; {user_id} is replaced with the user's ID
+	UserChannelTemplate string
+
+	// DuplicateConnectionPolicy handles a user authenticating again from a connected device:
+	// "allow" (the default), "newest-wins", which closes the older connections, or "deny"
+	DuplicateConnectionPolicy string
+	// DuplicateConnectionScope is what counts as a duplicate: "device" (the default), another
+	// connection with the same fingerprint, or "user", any other connection of the user
+	DuplicateConnectionScope string
+	// ChannelDuplicatePolicies applies duplicate policies to channel subscriptions, as
+	// "pattern=policy" pairs (e.g. "game.*=newest-wins,exam.*=deny")
+	ChannelDuplicatePolicies string
+
+	// SocketIO serves the Socket.IO protocol adapter at /socket.io/, for existing Socket.IO clients
+	SocketIO bool
+
+	// Netpoll watches idle native connections with epoll instead of a reader and a ping
+	// goroutine each (Linux only, TLS connections excepted), for hosting very many connections
+	Netpoll bool
+	// NetpollWorkers bounds the messages of netpoll connections handled at once
+	NetpollWorkers int
+
+	// Pusher app emulated at /app/{key} and /apps/{app_id}/events, for Laravel Echo's pusher-js
+	// connector; the adapter is enabled when the key is set
+	PusherAppID     string
+	PusherAppKey    string
+	PusherAppSecret string
+}
+
+// MinChunkSize is the smallest chunk size, so a message isn't split into thousands of frames
+const MinChunkSize = 1024
+
+// Duplicate connection policies
+const (
+	DuplicatePolicyAllow      = "allow"
+	DuplicatePolicyNewestWins = "newest-wins"
+	DuplicatePolicyDeny       = "deny"
+)
+
+// DuplicatePolicyRule applies a duplicate connection policy to channels matching Pattern
+type DuplicatePolicyRule struct {
+	Pattern string
+	Policy  string
+}
+
+// TraceSelector selects the clients traced by --trace-wire. Kind is "client", "user" or
+// "channel", and Pattern may contain * wildcards.
+type TraceSelector struct {
+	Kind    string
+	Pattern string
+}
+
+// RateLimitRule caps the outbound message rate of channels matching Pattern
+type RateLimitRule struct {
+	Pattern   string
+	PerSecond float64
+}
+
+// SizeRule caps the size in bytes of what is sent on channels matching Pattern
+type SizeRule struct {
+	Pattern string
+	Bytes   int
 }
 
 // New creates a new configuration with default values
 func New() *Config {
+	debug := getEnv("SOCKET_DEBUG", "false") == "true"
+	defaultLogLevel := "info"
+	if debug {
+		defaultLogLevel = "debug"
+	}
+
 	return &Config{
 		Port:       getEnv("SOCKET_PORT", "8080"),
 		JWTSecret:  getEnv("JWT_SECRET", "default-secret-key-change-in-production"),
@@ -29,7 +432,185 @@ func New() *Config {
 		LaravelCmd: getEnv("LARAVEL_COMMAND", "socket:handle"),
 		TempDir:    getEnv("SOCKET_TEMP_DIR", filepath.Join(os.TempDir(), "socket-server-payloads")),
 		WebDir:     getEnv("WEB_DIR", "./web"),
-		Debug:      getEnv("SOCKET_DEBUG", "false") == "true",
+		Debug:      debug,
+		AdminPort:  getEnv("ADMIN_PORT", ""),
+
+		LogLevel:  getEnv("LOG_LEVEL", defaultLogLevel),
+		LogFormat: getEnv("LOG_FORMAT", "text"),
+		LogOutput: getEnv("LOG_OUTPUT", "stdout"),
+
+		TLSCertFile:         getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:          getEnv("TLS_KEY_FILE", ""),
+		TLSAutocertHosts:    getEnvList("TLS_AUTOCERT_HOSTS"),
+		TLSAutocertCacheDir: getEnv("TLS_AUTOCERT_CACHE_DIR", "./certs"),
+		TLSAutocertEmail:    getEnv("TLS_AUTOCERT_EMAIL", ""),
+		TLSAutocertHTTPAddr: getEnv("TLS_AUTOCERT_HTTP_ADDR", ":80"),
+
+		DirectHistorySize:   getEnvInt("DM_HISTORY_SIZE", 0),
+		ChannelHistorySize:  getEnvInt("CHANNEL_HISTORY_SIZE", 0),
+		ReliableChannels:    getEnvList("RELIABLE_CHANNELS"),
+		ClientEventChannels: getEnvList("CLIENT_EVENTS_CHANNELS"),
+
+		DispatchStrategy:        getEnv("DISPATCH_STRATEGY", "exec"),
+		DispatchWorkers:         getEnvInt("DISPATCH_WORKERS", 4),
+		DispatchWorkerMaxJobs:   getEnvInt("DISPATCH_WORKER_MAX_JOBS", 1000),
+		DispatchTimeout:         time.Duration(getEnvInt("DISPATCH_TIMEOUT_SECONDS", 30)) * time.Second,
+		DispatchCallbackURL:     getEnv("DISPATCH_CALLBACK_URL", ""),
+		DispatchCallbackSecret:  getEnv("DISPATCH_CALLBACK_SECRET", ""),
+		DispatchCallbackRetries: getEnvInt("DISPATCH_CALLBACK_RETRIES", 2),
+		DispatchOutboxDir:       getEnv("DISPATCH_OUTBOX_DIR", ""),
+
+		DispatchConnectionEvents: getEnv("DISPATCH_CONNECTION_EVENTS", "false") == "true",
+
+		DispatchBatchInterval: time.Duration(getEnvInt("DISPATCH_BATCH_INTERVAL_MS", 0)) * time.Millisecond,
+		DispatchBatchSize:     getEnvInt("DISPATCH_BATCH_SIZE", 50),
+		ChannelRateLimits:     getEnv("CHANNEL_RATE_LIMITS", ""),
+		NodeID:                getEnv("NODE_ID", defaultNodeID()),
+		IDFormat:              getEnv("ID_FORMAT", "uuid"),
+
+		ConfigBackend:      getEnv("CONFIG_BACKEND", ""),
+		ConfigBackendAddr:  getEnv("CONFIG_BACKEND_ADDR", ""),
+		ConfigKey:          getEnv("CONFIG_KEY", "socket-server/config"),
+		ConfigPollInterval: time.Duration(getEnvInt("CONFIG_POLL_INTERVAL_SECONDS", 10)) * time.Second,
+
+		ShutdownGrace: time.Duration(getEnvInt("SHUTDOWN_GRACE_SECONDS", 25)) * time.Second,
+		PodName:       getEnv("POD_NAME", ""),
+		PodNamespace:  getEnv("POD_NAMESPACE", ""),
+		PodLabelsFile: getEnv("POD_LABELS_FILE", ""),
+
+		IngestSourcesFile: getEnv("INGEST_SOURCES_FILE", ""),
+		PurgeRulesFile:    getEnv("PURGE_RULES_FILE", ""),
+		EventSchemasFile:  getEnv("EVENT_SCHEMAS_FILE", ""),
+
+		PushFCMCredentials: getEnv("PUSH_FCM_CREDENTIALS", ""),
+		PushAPNsKeyFile:    getEnv("PUSH_APNS_KEY_FILE", ""),
+		PushAPNsKeyID:      getEnv("PUSH_APNS_KEY_ID", ""),
+		PushAPNsTeamID:     getEnv("PUSH_APNS_TEAM_ID", ""),
+		PushAPNsTopic:      getEnv("PUSH_APNS_TOPIC", ""),
+		PushAPNsSandbox:    getEnv("PUSH_APNS_SANDBOX", "false") == "true",
+
+		SMTPHost:                 getEnv("SMTP_HOST", ""),
+		SMTPPort:                 getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:             getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:             getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                 getEnv("SMTP_FROM", ""),
+		EmailTemplateFile:        getEnv("EMAIL_TEMPLATE_FILE", ""),
+		CriticalOfflineThreshold: time.Duration(getEnvInt("CRITICAL_OFFLINE_THRESHOLD_SECONDS", 300)) * time.Second,
+
+		BridgeURL:        getEnv("BRIDGE_URL", ""),
+		BridgeToken:      getEnv("BRIDGE_TOKEN", ""),
+		BridgeChannels:   getEnvList("BRIDGE_CHANNELS"),
+		BridgeQueueSize:  getEnvInt("BRIDGE_QUEUE_SIZE", 1000),
+		BridgeMaxRetries: getEnvInt("BRIDGE_MAX_RETRIES", 5),
+
+		StandbyOf:           getEnv("STANDBY_OF", ""),
+		StandbyToken:        getEnv("STANDBY_TOKEN", ""),
+		StandbySyncInterval: time.Duration(getEnvInt("STANDBY_SYNC_INTERVAL_SECONDS", 5)) * time.Second,
+
+		ClusterRedisURL: getEnv("CLUSTER_REDIS_URL", ""),
+		ClusterPrefix:   getEnv("CLUSTER_PREFIX", "gosocket"),
+		ClusterChannels: getEnvList("CLUSTER_CHANNELS"),
+
+		PublicURL:          getEnv("PUBLIC_URL", ""),
+		MaxConnections:     getEnvInt("MAX_CONNECTIONS", 0),
+		RebalanceThreshold: getEnvInt("REBALANCE_THRESHOLD", 80),
+
+		MaxConnectionsPerIP:  getEnvInt("MAX_CONNECTIONS_PER_IP", 0),
+		MaxChannelsPerClient: getEnvInt("MAX_CHANNELS_PER_CLIENT", 0),
+
+		ModerationLogSize: getEnvInt("MODERATION_LOG_SIZE", 100),
+
+		DefaultLocale: getEnv("DEFAULT_LOCALE", "en"),
+		LocaleCatalog: getEnv("LOCALE_CATALOG", ""),
+
+		GeoIPDatabase:       getEnv("GEOIP_DATABASE", ""),
+		GeoAllowedCountries: getEnvList("GEOIP_ALLOWED_COUNTRIES"),
+		GeoBlockedCountries: getEnvList("GEOIP_BLOCKED_COUNTRIES"),
+
+		HistoryTTL:          time.Duration(getEnvInt("HISTORY_TTL_SECONDS", 0)) * time.Second,
+		ExpirySweepInterval: time.Duration(getEnvInt("EXPIRY_SWEEP_INTERVAL_SECONDS", 60)) * time.Second,
+
+		StateFile:             getEnv("STATE_FILE", ""),
+		StateSnapshotInterval: time.Duration(getEnvInt("STATE_SNAPSHOT_INTERVAL_SECONDS", 60)) * time.Second,
+
+		AnalyticsFile:          getEnv("ANALYTICS_FILE", ""),
+		AnalyticsRetentionDays: getEnvInt("ANALYTICS_RETENTION_DAYS", 90),
+
+		ExportDir: getEnv("EXPORT_DIR", ""),
+
+		ResumeSecrets:  getEnvList("RESUME_SECRETS"),
+		ResumeTTL:      time.Duration(getEnvInt("RESUME_TTL_SECONDS", 120)) * time.Second,
+		ResumeIPPolicy: getEnv("RESUME_IP_POLICY", "log"),
+
+		CompressionMinSize: getEnvInt("COMPRESSION_MIN_BYTES", 0),
+
+		ChunkSize:             getEnvInt("CHUNK_SIZE", 0),
+		ChunkedMessageMaxSize: getEnvInt("CHUNKED_MESSAGE_MAX_BYTES", 4*1024*1024),
+
+		PayloadCompression:        getEnv("PAYLOAD_COMPRESSION", ""),
+		PayloadCompressionMinSize: getEnvInt("PAYLOAD_COMPRESSION_MIN_BYTES", 1024),
+
+		PingInterval:    time.Duration(getEnvInt("PING_INTERVAL_SECONDS", 30)) * time.Second,
+		PingMinInterval: time.Duration(getEnvInt("PING_MIN_INTERVAL_SECONDS", 10)) * time.Second,
+		PingMaxInterval: time.Duration(getEnvInt("PING_MAX_INTERVAL_SECONDS", 60)) * time.Second,
+		PongTimeout:     time.Duration(getEnvInt("PONG_TIMEOUT_SECONDS", 10)) * time.Second,
+		ReadTimeout:     time.Duration(getEnvInt("READ_TIMEOUT_SECONDS", 0)) * time.Second,
+		MaxMessageSize:  getEnvInt("MAX_MESSAGE_BYTES", 512*1024),
+
+		ClientBandwidthLimit:   int64(getEnvInt("CLIENT_BANDWIDTH_LIMIT", 0)),
+		BandwidthCapAction:     getEnv("BANDWIDTH_CAP_ACTION", "throttle"),
+		ChannelBandwidthLimits: getEnv("CHANNEL_BANDWIDTH_LIMITS", ""),
+
+		BlobMaxSize:       getEnvInt("BLOB_MAX_BYTES", 0),
+		ChannelBlobLimits: getEnv("CHANNEL_BLOB_LIMITS", ""),
+
+		UploadEndpoint:  getEnv("UPLOAD_S3_ENDPOINT", ""),
+		UploadBucket:    getEnv("UPLOAD_S3_BUCKET", ""),
+		UploadRegion:    getEnv("UPLOAD_S3_REGION", "us-east-1"),
+		UploadAccessKey: getEnv("UPLOAD_S3_ACCESS_KEY", ""),
+		UploadSecretKey: getEnv("UPLOAD_S3_SECRET_KEY", ""),
+		UploadPathStyle: getEnv("UPLOAD_S3_PATH_STYLE", "false") == "true",
+		UploadPublicURL: getEnv("UPLOAD_PUBLIC_URL", ""),
+		UploadURLTTL:    time.Duration(getEnvInt("UPLOAD_URL_TTL_SECONDS", 900)) * time.Second,
+		UploadMaxSize:   int64(getEnvInt("UPLOAD_MAX_BYTES", 100*1024*1024)),
+
+		ClientMessageRate:     getEnvInt("CLIENT_MESSAGE_RATE", 0),
+		ClientMessageBurst:    getEnvInt("CLIENT_MESSAGE_BURST", 20),
+		ClientRateLimitAction: getEnv("CLIENT_RATE_LIMIT_ACTION", "throttle"),
+		APIRateLimit:          getEnvInt("API_RATE_LIMIT", 0),
+		APIRateBurst:          getEnvInt("API_RATE_BURST", 20),
+
+		TraceWire:       getEnvList("TRACE_WIRE"),
+		TraceWireFile:   getEnv("TRACE_WIRE_FILE", ""),
+		TraceWireRedact: getEnvList("TRACE_WIRE_REDACT"),
+
+		PresenceGrace: time.Duration(getEnvInt("PRESENCE_GRACE_SECONDS", 0)) * time.Second,
+
+		AllowedOrigins:  getEnvList("ALLOWED_ORIGINS"),
+		AllowAllOrigins: getEnv("ALLOW_ALL_ORIGINS", "false") == "true",
+
+		SendQueueSize:    getEnvInt("SEND_QUEUE_SIZE", 256),
+		SlowClientPolicy: getEnv("SLOW_CLIENT_POLICY", "disconnect"),
+		WriteTimeout:     time.Duration(getEnvInt("WRITE_TIMEOUT_MS", 500)) * time.Millisecond,
+
+		BroadcastConcurrency: getEnvInt("BROADCAST_CONCURRENCY", 16),
+		BroadcastQueueSize:   getEnvInt("BROADCAST_QUEUE_SIZE", 100),
+
+		UserChannels:        getEnv("USER_CHANNELS_ENABLED", "true") == "true",
+		UserChannelTemplate: getEnv("USER_CHANNEL_TEMPLATE", "private-user.{user_id}"),
+
+		DuplicateConnectionPolicy: getEnv("DUPLICATE_CONNECTION_POLICY", DuplicatePolicyAllow),
+		DuplicateConnectionScope:  getEnv("DUPLICATE_CONNECTION_SCOPE", "device"),
+		ChannelDuplicatePolicies:  getEnv("CHANNEL_DUPLICATE_POLICIES", ""),
+
+		SocketIO: getEnv("SOCKETIO_ENABLED", "false") == "true",
+
+		Netpoll:        getEnv("NETPOLL_ENABLED", "false") == "true",
+		NetpollWorkers: getEnvInt("NETPOLL_WORKERS", 1024),
+
+		PusherAppID:     getEnv("PUSHER_APP_ID", ""),
+		PusherAppKey:    getEnv("PUSHER_APP_KEY", ""),
+		PusherAppSecret: getEnv("PUSHER_APP_SECRET", ""),
 	}
 }
 
@@ -61,6 +642,15 @@ func (c *Config) LoadFromFlags(port, jwtSecret, httpToken, workingDir, phpBinary
 	}
 }
 
+// AdminAddr returns the address of the admin listener, or "" when the admin endpoints are
+// served on the public port
+func (c *Config) AdminAddr() string {
+	if c.AdminPort == "" || strings.Contains(c.AdminPort, ":") {
+		return c.AdminPort
+	}
+	return ":" + c.AdminPort
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.Port == "" {
@@ -72,9 +662,339 @@ func (c *Config) Validate() error {
 	if c.HTTPToken == "" {
 		return ErrEmptyHTTPToken
 	}
+	if c.AdminPort != "" {
+		// The public listener binds every interface, so the admin port must differ whatever its host
+		_, adminPort, err := net.SplitHostPort(c.AdminAddr())
+		if number, convErr := strconv.Atoi(adminPort); err != nil || convErr != nil || number < 1 || number > 65535 || adminPort == c.Port {
+			return ErrInvalidAdminPort
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return ErrIncompleteTLSKeyPair
+	}
+	if c.TLSCertFile != "" && len(c.TLSAutocertHosts) > 0 {
+		return ErrConflictingTLSSettings
+	}
+	if c.DirectHistorySize < 0 || c.ChannelHistorySize < 0 {
+		return ErrInvalidHistorySize
+	}
+	switch c.DispatchStrategy {
+	case "", "exec":
+	case "worker-pool":
+		if c.DispatchWorkers <= 0 || c.DispatchWorkerMaxJobs < 0 {
+			return ErrInvalidDispatchWorkers
+		}
+	case "http-callback":
+		if c.DispatchCallbackURL == "" {
+			return ErrMissingDispatchCallbackURL
+		}
+		if u, err := url.Parse(c.DispatchCallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidDispatchCallbackURL
+		}
+		if c.DispatchCallbackRetries < 0 {
+			return ErrInvalidDispatchCallbackURL
+		}
+	default:
+		return ErrInvalidDispatchStrategy
+	}
+	if c.DispatchTimeout < 0 {
+		return ErrInvalidDispatchWorkers
+	}
+	if c.DispatchBatchInterval < 0 || (c.DispatchBatchInterval > 0 && c.DispatchBatchSize <= 0) {
+		return ErrInvalidBatchSettings
+	}
+	if _, err := ParseRateLimitRules(c.ChannelRateLimits); err != nil {
+		return err
+	}
+	if c.ConfigBackend != "" && c.ConfigBackend != "consul" && c.ConfigBackend != "etcd" {
+		return ErrInvalidConfigBackend
+	}
+	if c.IDFormat != "" && c.IDFormat != "uuid" && c.IDFormat != "ulid" && c.IDFormat != "ksuid" {
+		return ErrInvalidIDFormat
+	}
+	if c.ShutdownGrace < 0 {
+		return ErrInvalidShutdownGrace
+	}
+	if c.PushAPNsKeyFile != "" && (c.PushAPNsKeyID == "" || c.PushAPNsTeamID == "" || c.PushAPNsTopic == "") {
+		return ErrIncompleteAPNsSettings
+	}
+	if c.SMTPHost != "" && (c.SMTPFrom == "" || c.SMTPPort <= 0) {
+		return ErrIncompleteSMTPSettings
+	}
+	if c.CriticalOfflineThreshold < 0 {
+		return ErrInvalidCriticalThreshold
+	}
+	if c.AnalyticsFile != "" && c.AnalyticsRetentionDays <= 0 {
+		return ErrInvalidAnalyticsRetention
+	}
+	if err := c.validateBridge(); err != nil {
+		return err
+	}
+	if c.StandbyOf != "" {
+		primary, err := url.Parse(c.StandbyOf)
+		if err != nil || (primary.Scheme != "http" && primary.Scheme != "https") || primary.Host == "" || c.StandbySyncInterval < time.Second {
+			return ErrInvalidStandbySettings
+		}
+	}
+	if c.ClusterRedisURL != "" {
+		if u, err := url.Parse(c.ClusterRedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Hostname() == "" || c.ClusterPrefix == "" {
+			return ErrInvalidClusterSettings
+		}
+	}
+	if c.MaxConnections < 0 || c.RebalanceThreshold < 0 || c.RebalanceThreshold > 100 {
+		return ErrInvalidRebalanceSettings
+	}
+	if c.PublicURL != "" {
+		if u, err := url.Parse(c.PublicURL); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			return ErrInvalidRebalanceSettings
+		}
+	}
+	if c.MaxConnectionsPerIP < 0 || c.MaxChannelsPerClient < 0 {
+		return ErrInvalidConnectionLimits
+	}
+	if c.ModerationLogSize < 0 {
+		return ErrInvalidModerationLogSize
+	}
+	if c.GeoIPDatabase == "" && (len(c.GeoAllowedCountries) > 0 || len(c.GeoBlockedCountries) > 0) {
+		return ErrGeoIPDatabaseRequired
+	}
+	if c.HistoryTTL < 0 || c.ExpirySweepInterval < 0 {
+		return ErrInvalidExpirySettings
+	}
+	if c.StateSnapshotInterval < 0 {
+		return ErrInvalidSnapshotInterval
+	}
+	if c.ResumeTTL < 0 {
+		return ErrInvalidResumeTTL
+	}
+	switch c.ResumeIPPolicy {
+	case "", "allow", "log", "reject":
+	default:
+		return ErrInvalidResumeIPPolicy
+	}
+	if c.CompressionMinSize < 0 {
+		return ErrInvalidCompressionMinSize
+	}
+	if c.ChunkSize < 0 || (c.ChunkSize > 0 && c.ChunkSize < MinChunkSize) || c.ChunkedMessageMaxSize < 0 {
+		return ErrInvalidChunkSettings
+	}
+	if c.PayloadCompression != "" && c.PayloadCompression != "zstd" {
+		return ErrInvalidPayloadCompression
+	}
+	if c.PayloadCompressionMinSize < 0 {
+		return ErrInvalidCompressionMinSize
+	}
+	if c.PingInterval != 0 && (c.PingMinInterval <= 0 || c.PingMinInterval > c.PingInterval || c.PingInterval > c.PingMaxInterval) {
+		return ErrInvalidPingInterval
+	}
+	if c.PongTimeout < 0 || c.ReadTimeout < 0 || c.MaxMessageSize < 0 || (c.ReadTimeout > 0 && c.ReadTimeout <= c.PingMaxInterval) {
+		return ErrInvalidHeartbeatSettings
+	}
+	if c.ClientBandwidthLimit < 0 {
+		return ErrInvalidBandwidthLimit
+	}
+	if c.BandwidthCapAction != "" && c.BandwidthCapAction != "throttle" && c.BandwidthCapAction != "disconnect" {
+		return ErrInvalidBandwidthCapAction
+	}
+	if _, err := ParseRateLimitRules(c.ChannelBandwidthLimits); err != nil {
+		return err
+	}
+	if c.BlobMaxSize < 0 {
+		return ErrInvalidSizeLimit
+	}
+	if c.UploadBucket != "" {
+		if c.UploadRegion == "" || c.UploadAccessKey == "" || c.UploadSecretKey == "" {
+			return ErrIncompleteUploadStorage
+		}
+		if c.UploadURLTTL < time.Second || c.UploadURLTTL > 7*24*time.Hour || c.UploadMaxSize < 1 {
+			return ErrInvalidUploadSettings
+		}
+	}
+	if c.Netpoll && c.NetpollWorkers < 1 {
+		return ErrInvalidNetpollWorkers
+	}
+	if _, err := ParseSizeRules(c.ChannelBlobLimits); err != nil {
+		return err
+	}
+	if c.ClientMessageRate < 0 || c.ClientMessageBurst < 0 || c.APIRateLimit < 0 || c.APIRateBurst < 0 {
+		return ErrInvalidMessageRateLimit
+	}
+	if c.ClientRateLimitAction != "" && c.ClientRateLimitAction != "throttle" && c.ClientRateLimitAction != "disconnect" {
+		return ErrInvalidRateLimitAction
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		return ErrInvalidLogLevel
+	}
+	if c.LogFormat != "" && c.LogFormat != "text" && c.LogFormat != "json" {
+		return ErrInvalidLogFormat
+	}
+	if _, err := ParseTraceSelectors(c.TraceWire); err != nil {
+		return err
+	}
+	if c.PresenceGrace < 0 {
+		return ErrInvalidPresenceGrace
+	}
+	if (c.PusherAppID != "" || c.PusherAppKey != "" || c.PusherAppSecret != "") && (c.PusherAppID == "" || c.PusherAppKey == "" || c.PusherAppSecret == "") {
+		return ErrIncompletePusherApp
+	}
+	for _, pattern := range append(append(append([]string{}, c.ReliableChannels...), c.ClientEventChannels...), c.ClusterChannels...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w %q", ErrInvalidChannelPattern, pattern)
+		}
+	}
+	for _, origin := range c.AllowedOrigins {
+		if _, err := path.Match(origin, ""); err != nil {
+			return ErrInvalidAllowedOrigin
+		}
+	}
+	if c.SendQueueSize < 0 || c.WriteTimeout < 0 {
+		return ErrInvalidSendQueue
+	}
+	if c.SlowClientPolicy != "" && c.SlowClientPolicy != "disconnect" && c.SlowClientPolicy != "drop" {
+		return ErrInvalidSlowClientPolicy
+	}
+	if c.BroadcastConcurrency < 0 || c.BroadcastQueueSize < 0 {
+		return ErrInvalidBroadcastLimits
+	}
+	if c.UserChannels && strings.Count(c.UserChannelTemplate, "{user_id}") != 1 {
+		return ErrInvalidUserChannelTemplate
+	}
+	if !validDuplicatePolicy(c.DuplicateConnectionPolicy) {
+		return ErrInvalidDuplicatePolicy
+	}
+	if c.DuplicateConnectionScope != "" && c.DuplicateConnectionScope != "device" && c.DuplicateConnectionScope != "user" {
+		return ErrInvalidDuplicateScope
+	}
+	if _, err := ParseDuplicatePolicyRules(c.ChannelDuplicatePolicies); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TLSEnabled reports whether the server serves HTTPS and WSS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || len(c.TLSAutocertHosts) > 0
+}
+
+// validateBridge checks the bridge settings when a remote server is configured
+func (c *Config) validateBridge() error {
+	if c.BridgeURL == "" {
+		return nil
+	}
+	remote, err := url.Parse(c.BridgeURL)
+	if err != nil || (remote.Scheme != "http" && remote.Scheme != "https") || remote.Host == "" {
+		return fmt.Errorf("%w: BRIDGE_URL must be an http or https URL", ErrInvalidBridge)
+	}
+	if c.BridgeToken == "" {
+		return fmt.Errorf("%w: BRIDGE_TOKEN is required", ErrInvalidBridge)
+	}
+	if len(c.BridgeChannels) == 0 {
+		return fmt.Errorf("%w: BRIDGE_CHANNELS is required", ErrInvalidBridge)
+	}
+	for _, pattern := range c.BridgeChannels {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: invalid channel pattern %q", ErrInvalidBridge, pattern)
+		}
+	}
+	if c.BridgeQueueSize <= 0 || c.BridgeMaxRetries < 0 {
+		return fmt.Errorf("%w: queue size must be positive and retries cannot be negative", ErrInvalidBridge)
+	}
 	return nil
 }
 
+// ParseRateLimitRules parses "pattern=rate" pairs separated by commas
+func ParseRateLimitRules(value string) ([]RateLimitRule, error) {
+	rules := make([]RateLimitRule, 0)
+	for _, item := range ParseList(value) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRateLimit, item)
+		}
+
+		pattern := strings.TrimSpace(parts[0])
+		rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if pattern == "" || err != nil || rate <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRateLimit, item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRateLimit, item)
+		}
+
+		rules = append(rules, RateLimitRule{Pattern: pattern, PerSecond: rate})
+	}
+	return rules, nil
+}
+
+// ParseSizeRules parses "pattern=bytes" pairs separated by commas
+func ParseSizeRules(value string) ([]SizeRule, error) {
+	rules := make([]SizeRule, 0)
+	for _, item := range ParseList(value) {
+		pattern, size, found := strings.Cut(item, "=")
+		pattern = strings.TrimSpace(pattern)
+		bytes, err := strconv.Atoi(strings.TrimSpace(size))
+		if !found || pattern == "" || err != nil || bytes < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSizeLimit, item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSizeLimit, item)
+		}
+
+		rules = append(rules, SizeRule{Pattern: pattern, Bytes: bytes})
+	}
+	return rules, nil
+}
+
+// ParseDuplicatePolicyRules parses "pattern=policy" pairs separated by commas
+func ParseDuplicatePolicyRules(value string) ([]DuplicatePolicyRule, error) {
+	rules := make([]DuplicatePolicyRule, 0)
+	for _, item := range ParseList(value) {
+		pattern, policy, found := strings.Cut(item, "=")
+		pattern, policy = strings.TrimSpace(pattern), strings.TrimSpace(policy)
+		if !found || pattern == "" || policy == "" || !validDuplicatePolicy(policy) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDuplicatePolicy, item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDuplicatePolicy, item)
+		}
+
+		rules = append(rules, DuplicatePolicyRule{Pattern: pattern, Policy: policy})
+	}
+	return rules, nil
+}
+
+// ParseTraceSelectors parses wire trace selectors; "*" selects every client
+func ParseTraceSelectors(values []string) ([]TraceSelector, error) {
+	selectors := make([]TraceSelector, 0, len(values))
+	for _, value := range values {
+		if value == "*" {
+			selectors = append(selectors, TraceSelector{Kind: "client", Pattern: "*"})
+			continue
+		}
+
+		kind, pattern, _ := strings.Cut(value, ":")
+		if (kind != "client" && kind != "user" && kind != "channel") || pattern == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTraceSelector, value)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTraceSelector, value)
+		}
+		selectors = append(selectors, TraceSelector{Kind: kind, Pattern: pattern})
+	}
+	return selectors, nil
+}
+
+// validDuplicatePolicy reports whether policy is a known duplicate connection policy (empty
+// allows duplicates)
+func validDuplicatePolicy(policy string) bool {
+	switch policy {
+	case "", DuplicatePolicyAllow, DuplicatePolicyNewestWins, DuplicatePolicyDeny:
+		return true
+	}
+	return false
+}
+
 // getEnv gets an environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -82,3 +1002,66 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// defaultNodeID uses the hostname as the node identifier
+func defaultNodeID() string {
+	if podName := os.Getenv("POD_NAME"); podName != "" {
+		return podName
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "socket-server"
+}
+
+// LoadPodLabels reads a Kubernetes downward API labels file, made of key="value" lines
+func LoadPodLabels(filename string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if filename == "" {
+		return labels, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pod labels file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		value, err := strconv.Unquote(parts[1])
+		if err != nil {
+			value = parts[1]
+		}
+		labels[parts[0]] = value
+	}
+	return labels, nil
+}
+
+// getEnvInt gets an integer environment variable with a default value
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList gets a comma-separated environment variable as a list
+func getEnvList(key string) []string {
+	return ParseList(os.Getenv(key))
+}
+
+// ParseList splits a comma-separated value into trimmed, non-empty items
+func ParseList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"socket-server/internal/models"
 )
 
 func TestNew(t *testing.T) {
@@ -46,6 +50,10 @@ func TestNew(t *testing.T) {
 		t.Errorf("Expected default working directory '.', got %s", cfg.WorkingDir)
 	}
 
+	if cfg.UserChannelTemplate != "private-user.{user_id}" {
+		t.Errorf("Expected default user channel template 'private-user.{user_id}', got %s", cfg.UserChannelTemplate)
+	}
+
 	expectedTempDir := filepath.Join(os.TempDir(), "socket-server-payloads")
 	if cfg.TempDir != expectedTempDir {
 		t.Errorf("Expected default temp directory %s, got %s", expectedTempDir, cfg.TempDir)
@@ -134,10 +142,12 @@ func TestLoadFromFlags(t *testing.T) {
 	cfg.LoadFromFlags(
 		"7000",            // port
 		"flag-secret",     // jwtSecret
+		"flag-token",      // httpToken
 		"/flag/path",      // workingDir
 		"/usr/bin/php7.4", // phpBinary
 		"flag:command",    // laravelCmd
 		"/tmp/flag",       // tempDir
+		"/flag/web",       // webDir
 	)
 
 	if cfg.Port != "7000" {
@@ -171,7 +181,7 @@ func TestLoadFromFlagsWithEmptyValues(t *testing.T) {
 	originalPHPBinary := cfg.PHPBinary
 
 	// Test with empty flag values (should keep existing values)
-	cfg.LoadFromFlags("", "", "", "", "", "")
+	cfg.LoadFromFlags("", "", "", "", "", "", "", "")
 
 	if cfg.Port != originalPort {
 		t.Errorf("Expected port to remain %s, got %s", originalPort, cfg.Port)
@@ -193,6 +203,7 @@ func TestValidate(t *testing.T) {
 			config: &Config{
 				Port:       "8080",
 				JWTSecret:  "test-secret",
+				HTTPToken:  "test-token",
 				WorkingDir: "/test/path",
 				PHPBinary:  "php",
 				LaravelCmd: "test:command",
@@ -288,10 +299,12 @@ func TestEnvironmentFallback(t *testing.T) {
 	cfg.LoadFromFlags(
 		"7000",       // port - should override env
 		"",           // jwtSecret - should keep env value
+		"",           // httpToken - should keep env value
 		"/flag/path", // workingDir - should override env
 		"",           // phpBinary - should keep env value
 		"",           // laravelCmd - should keep env value
 		"",           // tempDir - should keep env value
+		"",           // webDir - should keep env value
 	)
 
 	if cfg.Port != "7000" {
@@ -384,3 +397,556 @@ func TestConcurrentConfigAccess(t *testing.T) {
 		<-done
 	}
 }
+
+func TestParseRateLimitRules(t *testing.T) {
+	rules, err := ParseRateLimitRules("telemetry.*=10, ticker.*=0.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+
+	if rules[0].Pattern != "telemetry.*" || rules[0].PerSecond != 10 {
+		t.Errorf("Unexpected first rule: %+v", rules[0])
+	}
+
+	if rules[1].Pattern != "ticker.*" || rules[1].PerSecond != 0.5 {
+		t.Errorf("Unexpected second rule: %+v", rules[1])
+	}
+
+	for _, invalid := range []string{"telemetry.*", "=10", "telemetry.*=abc", "telemetry.*=0", "[bad=10"} {
+		if _, err := ParseRateLimitRules(invalid); !errors.Is(err, ErrInvalidRateLimit) {
+			t.Errorf("Expected ErrInvalidRateLimit for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestParseSizeRules(t *testing.T) {
+	rules, err := ParseSizeRules("photos.*=5242880, chat.*=0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[0] != (SizeRule{Pattern: "photos.*", Bytes: 5242880}) || rules[1] != (SizeRule{Pattern: "chat.*", Bytes: 0}) {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	for _, invalid := range []string{"photos.*", "=10", "photos.*=1.5", "photos.*=-1", "[bad=10"} {
+		if _, err := ParseSizeRules(invalid); !errors.Is(err, ErrInvalidSizeLimit) {
+			t.Errorf("Expected ErrInvalidSizeLimit for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestParseDynamicSettings(t *testing.T) {
+	settings, err := ParseDynamicSettings([]byte(`{
+		"channel_rate_limits": "ticker.*=5",
+		"banned_ips": ["10.0.0.1"],
+		"channel_groups": {"eu": {"pattern": "stores.eu.*"}}
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if settings.ChannelRateLimits == nil || *settings.ChannelRateLimits != "ticker.*=5" {
+		t.Errorf("Unexpected channel rate limits: %v", settings.ChannelRateLimits)
+	}
+
+	if settings.ReliableChannels != nil {
+		t.Errorf("Expected omitted reliable channels to stay nil, got %v", settings.ReliableChannels)
+	}
+
+	if len(settings.BannedIPs) != 1 || settings.ChannelGroups["eu"].Pattern != "stores.eu.*" {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+
+	if _, err := ParseDynamicSettings([]byte(`{"channel_rate_limits": "ticker.*"}`)); !errors.Is(err, ErrInvalidRateLimit) {
+		t.Errorf("Expected ErrInvalidRateLimit, got %v", err)
+	}
+	if _, err := ParseDynamicSettings([]byte(`{"allowed_origins": ["https://[example.com"]}`)); !errors.Is(err, ErrInvalidAllowedOrigin) {
+		t.Errorf("Expected ErrInvalidAllowedOrigin, got %v", err)
+	}
+	if _, err := ParseDynamicSettings([]byte(`{"channel_settings": {"chat": {"max_clients": -1}}}`)); !errors.Is(err, models.ErrInvalidChannelSettings) {
+		t.Errorf("Expected ErrInvalidChannelSettings, got %v", err)
+	}
+}
+
+func TestLoadPodLabels(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "labels")
+	content := "app=\"socket-server\"\npod-template-hash=\"7d9f\"\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write labels file: %v", err)
+	}
+
+	labels, err := LoadPodLabels(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if labels["app"] != "socket-server" || labels["pod-template-hash"] != "7d9f" {
+		t.Errorf("Unexpected labels: %v", labels)
+	}
+
+	if labels, err := LoadPodLabels(""); err != nil || len(labels) != 0 {
+		t.Errorf("Expected no labels without a file, got %v (%v)", labels, err)
+	}
+}
+
+func TestParseIngestSources(t *testing.T) {
+	sources, err := ParseIngestSources([]byte(`{
+		"stripe": {"type": "stripe", "secret": "whsec", "rules": [
+			{"match": {"type": "invoice.paid"}, "channel": "billing.{{data.object.customer}}", "event": "{{type}}", "data": "data.object"}
+		]}
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rule := sources["stripe"].Rules[0]; rule.Channel != "billing.{{data.object.customer}}" || rule.Match["type"] != "invoice.paid" {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	invalid := []string{
+		`not json`,
+		`{"x": {"type": "paypal", "secret": "s"}}`,
+		`{"x": {"type": "custom"}}`,
+		`{"x": {"type": "github", "secret": "s", "rules": [{"channel": "repo"}]}}`,
+	}
+	for _, document := range invalid {
+		if _, err := ParseIngestSources([]byte(document)); !errors.Is(err, ErrInvalidIngestSource) {
+			t.Errorf("Expected ErrInvalidIngestSource for %s, got %v", document, err)
+		}
+	}
+}
+
+func TestValidateBridge(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Port:             "8080",
+			JWTSecret:        "secret",
+			HTTPToken:        "token",
+			BridgeURL:        "https://eu.example.com",
+			BridgeToken:      "remote-token",
+			BridgeChannels:   []string{"news.*"},
+			BridgeQueueSize:  1000,
+			BridgeMaxRetries: 5,
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	invalid := map[string]func(*Config){
+		"relative URL":     func(c *Config) { c.BridgeURL = "eu.example.com" },
+		"no token":         func(c *Config) { c.BridgeToken = "" },
+		"no channels":      func(c *Config) { c.BridgeChannels = nil },
+		"bad pattern":      func(c *Config) { c.BridgeChannels = []string{"news.["} },
+		"empty queue":      func(c *Config) { c.BridgeQueueSize = 0 },
+		"negative retries": func(c *Config) { c.BridgeMaxRetries = -1 },
+	}
+	for name, mutate := range invalid {
+		cfg := valid()
+		mutate(cfg)
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidBridge) {
+			t.Errorf("%s: expected ErrInvalidBridge, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateStandby(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", StandbyOf: "http://primary:9090", StandbySyncInterval: 5 * time.Second}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid standby settings, got %v", err)
+	}
+
+	for _, invalid := range []func(*Config){
+		func(c *Config) { c.StandbyOf = "primary:9090" },
+		func(c *Config) { c.StandbyOf = "ws://primary:9090" },
+		func(c *Config) { c.StandbySyncInterval = 0 },
+	} {
+		cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", StandbyOf: "http://primary:9090", StandbySyncInterval: 5 * time.Second}
+		invalid(cfg)
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidStandbySettings) {
+			t.Errorf("Expected ErrInvalidStandbySettings, got %v", err)
+		}
+	}
+}
+
+func TestParsePurgeRules(t *testing.T) {
+	rules, err := ParsePurgeRules([]byte(`[
+		{"events": ["product.*"], "provider": "cloudflare", "zone_id": "z", "token": "t", "urls": ["https://shop/{{data.slug}}"]},
+		{"events": ["price.changed"], "provider": "fastly", "service_id": "s", "token": "t", "tags": ["product-{{data.id}}"]}
+	]`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[1].ServiceID != "s" {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	invalid := []string{
+		`{}`,
+		`[{"events": ["a"], "provider": "akamai", "token": "t", "urls": ["u"]}]`,
+		`[{"events": ["a"], "provider": "cloudflare", "zone_id": "z", "urls": ["u"]}]`,
+		`[{"events": ["a"], "provider": "cloudflare", "token": "t", "urls": ["u"]}]`,
+		`[{"events": ["a"], "provider": "fastly", "token": "t"}]`,
+		`[{"events": ["a"], "provider": "fastly", "token": "t", "tags": ["k"]}]`,
+		`[{"events": ["a["], "provider": "fastly", "token": "t", "urls": ["u"]}]`,
+	}
+	for _, document := range invalid {
+		if _, err := ParsePurgeRules([]byte(document)); !errors.Is(err, ErrInvalidPurgeRule) {
+			t.Errorf("Expected ErrInvalidPurgeRule for %s, got %v", document, err)
+		}
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", TLSCertFile: "cert.pem"}
+	if err := cfg.Validate(); !errors.Is(err, ErrIncompleteTLSKeyPair) {
+		t.Errorf("Expected ErrIncompleteTLSKeyPair, got %v", err)
+	}
+
+	cfg.TLSKeyFile = "key.pem"
+	if err := cfg.Validate(); err != nil || !cfg.TLSEnabled() {
+		t.Errorf("Expected a valid TLS configuration, got %v", err)
+	}
+
+	cfg.TLSAutocertHosts = []string{"socket.example.com"}
+	if err := cfg.Validate(); !errors.Is(err, ErrConflictingTLSSettings) {
+		t.Errorf("Expected ErrConflictingTLSSettings, got %v", err)
+	}
+}
+
+func TestValidateDispatchStrategy(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", DispatchStrategy: "fork"}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidDispatchStrategy) {
+		t.Errorf("Expected ErrInvalidDispatchStrategy, got %v", err)
+	}
+
+	cfg.DispatchStrategy = "worker-pool"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidDispatchWorkers) {
+		t.Errorf("Expected ErrInvalidDispatchWorkers, got %v", err)
+	}
+	cfg.DispatchWorkers = 4
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid worker pool, got %v", err)
+	}
+
+	cfg.DispatchStrategy = "http-callback"
+	if err := cfg.Validate(); !errors.Is(err, ErrMissingDispatchCallbackURL) {
+		t.Errorf("Expected ErrMissingDispatchCallbackURL, got %v", err)
+	}
+	cfg.DispatchCallbackURL = "app.example.com/socket"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidDispatchCallbackURL) {
+		t.Errorf("Expected ErrInvalidDispatchCallbackURL, got %v", err)
+	}
+	cfg.DispatchCallbackURL = "https://app.example.com/socket"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid callback, got %v", err)
+	}
+}
+
+func TestValidateAnalytics(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", AnalyticsFile: "analytics.json"}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidAnalyticsRetention) {
+		t.Errorf("Expected ErrInvalidAnalyticsRetention, got %v", err)
+	}
+
+	cfg.AnalyticsRetentionDays = 30
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid analytics configuration, got %v", err)
+	}
+}
+
+func TestValidateAllowedOrigins(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", AllowedOrigins: []string{"https://*.example.com", "*"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid origin patterns, got %v", err)
+	}
+
+	cfg.AllowedOrigins = []string{"https://[example.com"}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidAllowedOrigin) {
+		t.Errorf("Expected ErrInvalidAllowedOrigin, got %v", err)
+	}
+}
+
+func TestParseDuplicatePolicyRules(t *testing.T) {
+	rules, err := ParseDuplicatePolicyRules("game.*=newest-wins, exam.*=deny")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(rules) != 2 || rules[0].Policy != DuplicatePolicyNewestWins || rules[1].Pattern != "exam.*" {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	for _, invalid := range []string{"game.*", "=deny", "game.*=kick", "[bad=deny"} {
+		if _, err := ParseDuplicatePolicyRules(invalid); !errors.Is(err, ErrInvalidDuplicatePolicy) {
+			t.Errorf("Expected ErrInvalidDuplicatePolicy for %q, got %v", invalid, err)
+		}
+	}
+
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", DuplicateConnectionScope: "browser"}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidDuplicateScope) {
+		t.Errorf("Expected ErrInvalidDuplicateScope, got %v", err)
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", ClientMessageRate: 10, ClientMessageBurst: 20, ClientRateLimitAction: "disconnect", APIRateLimit: 5}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid rate limits, got %v", err)
+	}
+
+	cfg.ClientRateLimitAction = "drop"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidRateLimitAction) {
+		t.Errorf("Expected ErrInvalidRateLimitAction, got %v", err)
+	}
+
+	cfg.ClientRateLimitAction = "throttle"
+	cfg.APIRateLimit = -1
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidMessageRateLimit) {
+		t.Errorf("Expected ErrInvalidMessageRateLimit, got %v", err)
+	}
+}
+
+func TestParseTraceSelectors(t *testing.T) {
+	selectors, err := ParseTraceSelectors([]string{"*", "user:42", "channel:chat.*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(selectors) != 3 || selectors[0].Pattern != "*" || selectors[1].Kind != "user" || selectors[2].Pattern != "chat.*" {
+		t.Errorf("Unexpected selectors: %+v", selectors)
+	}
+
+	for _, invalid := range []string{"42", "user:", "room:lobby", "channel:[bad"} {
+		if _, err := ParseTraceSelectors([]string{invalid}); !errors.Is(err, ErrInvalidTraceSelector) {
+			t.Errorf("Expected ErrInvalidTraceSelector for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestValidateLogging(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", LogLevel: "warn", LogFormat: "json"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid logging settings, got %v", err)
+	}
+
+	cfg.LogLevel = "verbose"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("Expected ErrInvalidLogLevel, got %v", err)
+	}
+
+	cfg.LogLevel = "debug"
+	cfg.LogFormat = "logfmt"
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidLogFormat) {
+		t.Errorf("Expected ErrInvalidLogFormat, got %v", err)
+	}
+}
+
+func TestValidatePusherApp(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", PusherAppID: "1", PusherAppKey: "app-key", PusherAppSecret: "app-secret"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid Pusher app, got %v", err)
+	}
+
+	cfg.PusherAppSecret = ""
+	if err := cfg.Validate(); !errors.Is(err, ErrIncompletePusherApp) {
+		t.Errorf("Expected ErrIncompletePusherApp, got %v", err)
+	}
+}
+
+func TestParseEventSchemas(t *testing.T) {
+	schemas, err := ParseEventSchemas([]byte(`{"order.shipped": {"type": "object", "required": ["id"]}}`))
+	if err != nil || len(schemas) != 1 || schemas["order.shipped"] == nil {
+		t.Errorf("Unexpected schemas %v (%v)", schemas, err)
+	}
+
+	for _, invalid := range []string{`[]`, `{"order.shipped": true}`, `{"": {}}`} {
+		if _, err := ParseEventSchemas([]byte(invalid)); !errors.Is(err, ErrInvalidEventSchemas) {
+			t.Errorf("Expected ErrInvalidEventSchemas for %s, got %v", invalid, err)
+		}
+	}
+}
+
+func TestLoadPurgeRulesLocatesErrors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "purge.json")
+	document := "[\n  {\"provider\": \"fastly\", \"token\": \"t\", \"events\": [\"a\"], \"urls\": [\"u\"]},\n  {\"provider\": \"fastly\", \"events\": [\"a\"], \"urls\": [\"u\"]}\n]"
+	if err := os.WriteFile(filename, []byte(document), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadPurgeRules(filename)
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) || !errors.Is(err, ErrInvalidPurgeRule) {
+		t.Fatalf("Expected a located ErrInvalidPurgeRule, got %v", err)
+	}
+	if fileErr.Line != 3 || fileErr.Column != 3 {
+		t.Errorf("Expected the error at 3:3, got %d:%d", fileErr.Line, fileErr.Column)
+	}
+
+	if err := os.WriteFile(filename, []byte("[\n  {\"provider\": 1}\n]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPurgeRules(filename); !errors.As(err, &fileErr) || fileErr.Line != 2 || fileErr.Column != 16 {
+		t.Errorf("Expected the type error at 2:16, got %v", err)
+	}
+}
+
+func TestCheckReportsEveryProblem(t *testing.T) {
+	cfg := &Config{
+		Port:              "8080",
+		JWTSecret:         "secret",
+		HTTPToken:         "token",
+		ReliableChannels:  []string{"orders.*", "chat.["},
+		ChannelRateLimits: "ticker.*",
+		IngestSourcesFile: filepath.Join(t.TempDir(), "missing.json"),
+	}
+	problems := cfg.Check()
+	if len(problems) != 3 {
+		t.Fatalf("Expected 3 problems, got %v", problems)
+	}
+	if !errors.Is(problems[0], ErrInvalidRateLimit) || !errors.Is(problems[1], ErrInvalidChannelPattern) || !errors.Is(problems[2], os.ErrNotExist) {
+		t.Errorf("Unexpected problems %v", problems)
+	}
+
+	cfg = &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token"}
+	if problems := cfg.Check(); len(problems) != 0 {
+		t.Errorf("Expected a valid configuration, got %v", problems)
+	}
+}
+
+func TestValidateCluster(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", ClusterRedisURL: "redis://:pass@redis:6379/1", ClusterPrefix: "gosocket"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid cluster settings, got %v", err)
+	}
+
+	for _, invalid := range []string{"http://redis:6379", "redis://", "redis:6379"} {
+		cfg.ClusterRedisURL = invalid
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidClusterSettings) {
+			t.Errorf("Expected ErrInvalidClusterSettings for %q, got %v", invalid, err)
+		}
+	}
+
+	cfg.ClusterRedisURL, cfg.ClusterPrefix = "rediss://redis.example.com", ""
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidClusterSettings) {
+		t.Errorf("Expected ErrInvalidClusterSettings without a prefix, got %v", err)
+	}
+
+	cfg.ClusterPrefix, cfg.ClusterChannels = "gosocket", []string{"orders.*", "chat.["}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidChannelPattern) {
+		t.Errorf("Expected ErrInvalidChannelPattern for an invalid cluster channel, got %v", err)
+	}
+}
+
+func TestValidateRebalance(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", MaxConnections: 10000, RebalanceThreshold: 80, PublicURL: "wss://node-1.example.com/ws"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid rebalance settings, got %v", err)
+	}
+
+	for _, invalid := range []func(*Config){
+		func(c *Config) { c.MaxConnections = -1 },
+		func(c *Config) { c.RebalanceThreshold = 101 },
+		func(c *Config) { c.PublicURL = "https://node-1.example.com/ws" },
+	} {
+		cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", MaxConnections: 10000, RebalanceThreshold: 80}
+		invalid(cfg)
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidRebalanceSettings) {
+			t.Errorf("Expected ErrInvalidRebalanceSettings, got %v", err)
+		}
+	}
+}
+
+func TestValidateConnectionLimits(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", MaxConnections: 10000, MaxConnectionsPerIP: 50, MaxChannelsPerClient: 100}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid connection limits, got %v", err)
+	}
+
+	for _, invalid := range []func(*Config){
+		func(c *Config) { c.MaxConnectionsPerIP = -1 },
+		func(c *Config) { c.MaxChannelsPerClient = -1 },
+	} {
+		cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token"}
+		invalid(cfg)
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidConnectionLimits) {
+			t.Errorf("Expected ErrInvalidConnectionLimits, got %v", err)
+		}
+	}
+}
+
+func TestValidateModerationLogSize(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", ModerationLogSize: 100}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid moderation log size, got %v", err)
+	}
+
+	cfg.ModerationLogSize = -1
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidModerationLogSize) {
+		t.Errorf("Expected ErrInvalidModerationLogSize, got %v", err)
+	}
+}
+
+func TestValidateHeartbeat(t *testing.T) {
+	base := func() *Config {
+		return &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", PingInterval: 30 * time.Second, PingMinInterval: 10 * time.Second, PingMaxInterval: time.Minute, PongTimeout: 10 * time.Second, MaxMessageSize: 512 * 1024}
+	}
+	cfg := base()
+	cfg.ReadTimeout = 2 * time.Minute
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid heartbeat settings, got %v", err)
+	}
+
+	for _, invalid := range []func(*Config){
+		func(c *Config) { c.PongTimeout = -time.Second },
+		func(c *Config) { c.MaxMessageSize = -1 },
+		func(c *Config) { c.ReadTimeout = time.Minute }, // a silent minute between pings would close connections
+	} {
+		cfg := base()
+		invalid(cfg)
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidHeartbeatSettings) {
+			t.Errorf("Expected ErrInvalidHeartbeatSettings, got %v", err)
+		}
+	}
+}
+
+func TestValidateAdminPort(t *testing.T) {
+	for _, adminPort := range []string{"9090", "127.0.0.1:9090", "[::1]:9090"} {
+		cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", AdminPort: adminPort}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Expected admin port %q to be valid, got %v", adminPort, err)
+		}
+	}
+	if addr := (&Config{AdminPort: "9090"}).AdminAddr(); addr != ":9090" {
+		t.Errorf("Expected admin address :9090, got %q", addr)
+	}
+
+	for _, adminPort := range []string{"8080", "127.0.0.1:8080", "admin", "127.0.0.1:", "70000"} {
+		cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", AdminPort: adminPort}
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidAdminPort) {
+			t.Errorf("Expected ErrInvalidAdminPort for %q, got %v", adminPort, err)
+		}
+	}
+}
+
+func TestValidateChunking(t *testing.T) {
+	cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token", ChunkSize: 64 * 1024, ChunkedMessageMaxSize: 4 * 1024 * 1024}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid chunk settings, got %v", err)
+	}
+
+	for _, invalid := range []func(*Config){
+		func(c *Config) { c.ChunkSize = -1 },
+		func(c *Config) { c.ChunkSize = MinChunkSize - 1 },
+		func(c *Config) { c.ChunkedMessageMaxSize = -1 },
+	} {
+		cfg := &Config{Port: "8080", JWTSecret: "secret", HTTPToken: "token"}
+		invalid(cfg)
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidChunkSettings) {
+			t.Errorf("Expected ErrInvalidChunkSettings, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"encoding/json"
+	"path"
+
+	"socket-server/internal/models"
+)
+
+// DynamicSettings is the runtime-adjustable subset of the configuration, read from a key/value
+// backend (Consul or etcd) as a single JSON document. Omitted fields leave the current value
+// unchanged.
+type DynamicSettings struct {
+	ChannelRateLimits *string                           `json:"channel_rate_limits,omitempty"`
+	ReliableChannels  []string                          `json:"reliable_channels,omitempty"`
+	BannedIPs         []string                          `json:"banned_ips,omitempty"`
+	ChannelGroups     map[string]GroupDefinition        `json:"channel_groups,omitempty"`
+	AllowedOrigins    []string                          `json:"allowed_origins,omitempty"`
+	ChannelSettings   map[string]models.ChannelSettings `json:"channel_settings,omitempty"` // by channel name
+}
+
+// GroupDefinition describes a channel group declared in dynamic configuration
+type GroupDefinition struct {
+	Channels []string `json:"channels,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+}
+
+// ParseDynamicSettings decodes and validates a dynamic settings document
+func ParseDynamicSettings(data []byte) (*DynamicSettings, error) {
+	var settings DynamicSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+
+	if settings.ChannelRateLimits != nil {
+		if _, err := ParseRateLimitRules(*settings.ChannelRateLimits); err != nil {
+			return nil, err
+		}
+	}
+	for _, origin := range settings.AllowedOrigins {
+		if _, err := path.Match(origin, ""); err != nil {
+			return nil, ErrInvalidAllowedOrigin
+		}
+	}
+	for _, channelSettings := range settings.ChannelSettings {
+		if channelSettings.MaxClients != nil && *channelSettings.MaxClients < 0 {
+			return nil, models.ErrInvalidChannelSettings
+		}
+	}
+
+	return &settings, nil
+}
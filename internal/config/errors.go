@@ -6,9 +6,190 @@ var (
 	// ErrEmptyPort indicates an empty port configuration
 	ErrEmptyPort = errors.New("port cannot be empty")
 
+	// ErrInvalidAdminPort indicates an admin listener address that isn't a port or host:port,
+	// or that is the public port
+	ErrInvalidAdminPort = errors.New("admin port must be a port or host:port other than the public port")
+
 	// ErrEmptyJWTSecret indicates an empty JWT secret
 	ErrEmptyJWTSecret = errors.New("JWT secret cannot be empty")
 
 	// ErrEmptyHTTPToken indicates an empty HTTP API token
 	ErrEmptyHTTPToken = errors.New("HTTP API token cannot be empty")
+
+	// ErrInvalidHistorySize indicates a negative history size
+	ErrInvalidHistorySize = errors.New("history size cannot be negative")
+
+	// ErrInvalidDispatchStrategy indicates an unknown Laravel dispatch strategy
+	ErrInvalidDispatchStrategy = errors.New("dispatch strategy must be exec, worker-pool or http-callback")
+
+	// ErrInvalidDispatchWorkers indicates a worker pool without workers, or a negative job limit or timeout
+	ErrInvalidDispatchWorkers = errors.New("dispatch workers must be positive and the worker job limit and timeout cannot be negative")
+
+	// ErrMissingDispatchCallbackURL indicates the http-callback strategy without a callback URL
+	ErrMissingDispatchCallbackURL = errors.New("http-callback dispatch requires DISPATCH_CALLBACK_URL")
+
+	// ErrInvalidDispatchCallbackURL indicates a callback URL that isn't absolute http(s), or negative retries
+	ErrInvalidDispatchCallbackURL = errors.New("dispatch callback URL must be an absolute http or https URL and retries cannot be negative")
+
+	// ErrInvalidBatchSettings indicates inconsistent dispatch batching settings
+	ErrInvalidBatchSettings = errors.New("dispatch batch interval cannot be negative and batch size must be positive")
+
+	// ErrInvalidRateLimit indicates a malformed "pattern=rate" channel rate limit
+	ErrInvalidRateLimit = errors.New("invalid channel rate limit, expected pattern=messages_per_second")
+
+	// ErrInvalidSizeLimit indicates a negative size limit or a malformed "pattern=bytes" rule
+	ErrInvalidSizeLimit = errors.New("invalid size limit, expected a non-negative number of bytes or pattern=bytes")
+
+	// ErrInvalidConfigBackend indicates an unsupported dynamic configuration backend
+	ErrInvalidConfigBackend = errors.New("config backend must be consul or etcd")
+
+	// ErrInvalidShutdownGrace indicates a negative shutdown grace period
+	ErrInvalidShutdownGrace = errors.New("shutdown grace period cannot be negative")
+
+	// ErrGeoIPDatabaseRequired indicates country restrictions without a GeoIP database
+	ErrGeoIPDatabaseRequired = errors.New("country restrictions require a GeoIP database")
+
+	// ErrInvalidExpirySettings indicates a negative history TTL or expiry sweep interval
+	ErrInvalidExpirySettings = errors.New("history TTL and expiry sweep interval cannot be negative")
+
+	// ErrInvalidSnapshotInterval indicates a negative state snapshot interval
+	ErrInvalidSnapshotInterval = errors.New("state snapshot interval cannot be negative")
+
+	// ErrInvalidResumeTTL indicates a negative session resume window
+	ErrInvalidResumeTTL = errors.New("resume TTL cannot be negative")
+
+	// ErrInvalidResumeIPPolicy indicates an unknown policy for resumes from a different IP
+	ErrInvalidResumeIPPolicy = errors.New("resume IP policy must be allow, log or reject")
+
+	// ErrInvalidIDFormat indicates an ID format other than uuid, ulid or ksuid
+	ErrInvalidIDFormat = errors.New("ID format must be uuid, ulid or ksuid")
+
+	// ErrInvalidCompressionMinSize indicates a negative compression size threshold
+	ErrInvalidCompressionMinSize = errors.New("compression minimum size cannot be negative")
+
+	// ErrInvalidPayloadCompression indicates an unsupported Laravel payload compression algorithm
+	ErrInvalidPayloadCompression = errors.New("payload compression must be empty or zstd")
+
+	// ErrInvalidPingInterval indicates ping interval bounds that don't satisfy 0 < min <= interval <= max
+	ErrInvalidPingInterval = errors.New("ping intervals must satisfy 0 < min <= interval <= max")
+
+	// ErrInvalidHeartbeatSettings indicates a negative pong timeout, read timeout or maximum
+	// message size, or a read timeout that doesn't outlast the longest ping interval
+	ErrInvalidHeartbeatSettings = errors.New("pong timeout and maximum message size cannot be negative, and the read timeout must exceed the maximum ping interval")
+
+	// ErrInvalidBandwidthLimit indicates a negative client bandwidth cap
+	ErrInvalidBandwidthLimit = errors.New("client bandwidth limit cannot be negative")
+
+	// ErrInvalidBandwidthCapAction indicates an unknown action for clients over their bandwidth cap
+	ErrInvalidBandwidthCapAction = errors.New("bandwidth cap action must be throttle or disconnect")
+
+	// ErrInvalidMessageRateLimit indicates a negative client message or API request rate limit
+	ErrInvalidMessageRateLimit = errors.New("message and API rate limits and bursts cannot be negative")
+
+	// ErrInvalidRateLimitAction indicates an unknown action for clients over their message rate
+	ErrInvalidRateLimitAction = errors.New("client rate limit action must be throttle or disconnect")
+
+	// ErrInvalidTraceSelector indicates a wire trace selector other than client:, user:,
+	// channel: or *
+	ErrInvalidTraceSelector = errors.New("invalid wire trace selector, expected client:<id>, user:<id>, channel:<pattern> or *")
+
+	// ErrInvalidIngestSource indicates an ingest source without a known type, secret or valid rules
+	ErrInvalidIngestSource = errors.New("invalid ingest source")
+
+	// ErrIncompleteTLSKeyPair indicates a TLS certificate without a key or the other way round
+	ErrIncompleteTLSKeyPair = errors.New("TLS certificate and key must be set together")
+
+	// ErrConflictingTLSSettings indicates both certificate files and autocert hostnames
+	ErrConflictingTLSSettings = errors.New("TLS certificate files and autocert hosts are mutually exclusive")
+
+	// ErrInvalidPurgeRule indicates a CDN purge rule with an unknown provider or missing settings
+	ErrInvalidPurgeRule = errors.New("invalid CDN purge rule")
+
+	// ErrInvalidEventSchemas indicates an event schema file that isn't a JSON object of schemas
+	ErrInvalidEventSchemas = errors.New("event schemas must be a JSON object of schemas by event name")
+
+	// ErrIncompleteAPNsSettings indicates an APNs key without its key ID, team ID or topic
+	ErrIncompleteAPNsSettings = errors.New("APNs key requires a key ID, team ID and topic")
+
+	// ErrIncompleteSMTPSettings indicates an SMTP host without a sender address or valid port
+	ErrIncompleteSMTPSettings = errors.New("SMTP host requires a sender address and a positive port")
+
+	// ErrInvalidAnalyticsRetention indicates analytics rollups kept for less than a day
+	ErrInvalidAnalyticsRetention = errors.New("analytics retention must be at least one day")
+
+	// ErrInvalidCriticalThreshold indicates a negative critical offline threshold
+	ErrInvalidCriticalThreshold = errors.New("critical offline threshold cannot be negative")
+
+	// ErrInvalidBridge indicates incomplete or invalid settings for forwarding to a remote server
+	ErrInvalidBridge = errors.New("invalid bridge settings")
+
+	// ErrInvalidStandbySettings indicates a primary that isn't an http or https URL, or a state
+	// sync interval under a second
+	ErrInvalidStandbySettings = errors.New("STANDBY_OF must be an http or https URL and the sync interval at least 1 second")
+
+	// ErrInvalidClusterSettings indicates a cluster Redis URL that isn't redis:// or rediss://, or
+	// an empty cluster prefix
+	ErrInvalidClusterSettings = errors.New("cluster Redis URL must be a redis:// or rediss:// URL with a non-empty CLUSTER_PREFIX")
+
+	// ErrInvalidRebalanceSettings indicates a negative connection limit, a rebalance threshold
+	// outside 0-100 or a public URL that isn't ws:// or wss://
+	ErrInvalidRebalanceSettings = errors.New("MAX_CONNECTIONS cannot be negative, REBALANCE_THRESHOLD must be a percentage up to 100 and PUBLIC_URL a ws:// or wss:// URL")
+
+	// ErrInvalidChunkSettings indicates a chunk size below the minimum or a negative limit for
+	// chunked messages
+	ErrInvalidChunkSettings = errors.New("CHUNK_SIZE must be 0 or at least 1024 and CHUNKED_MESSAGE_MAX_BYTES cannot be negative")
+
+	// ErrInvalidPresenceGrace indicates a negative presence grace period
+	ErrInvalidPresenceGrace = errors.New("presence grace period cannot be negative")
+
+	// ErrInvalidChannelPattern indicates a malformed channel name pattern
+	ErrInvalidChannelPattern = errors.New("invalid channel pattern")
+
+	// ErrInvalidAllowedOrigin indicates a malformed allowed origin pattern
+	ErrInvalidAllowedOrigin = errors.New("invalid allowed origin pattern")
+
+	// ErrInvalidSendQueue indicates a negative send queue size or write timeout
+	ErrInvalidSendQueue = errors.New("send queue size and write timeout cannot be negative")
+
+	// ErrInvalidSlowClientPolicy indicates an unknown policy for clients that can't keep up
+	ErrInvalidSlowClientPolicy = errors.New("slow client policy must be disconnect or drop")
+
+	// ErrInvalidBroadcastLimits indicates a negative broadcast concurrency or queue size
+	ErrInvalidBroadcastLimits = errors.New("broadcast concurrency and queue size cannot be negative")
+
+	// ErrInvalidUserChannelTemplate indicates a user channel template without exactly one {user_id}
+	ErrInvalidUserChannelTemplate = errors.New("user channel template must contain {user_id} exactly once")
+
+	// ErrInvalidDuplicatePolicy indicates an unknown duplicate connection policy or a malformed
+	// channel duplicate policy rule
+	ErrInvalidDuplicatePolicy = errors.New("duplicate connection policy must be allow, newest-wins or deny")
+
+	// ErrInvalidDuplicateScope indicates an unknown duplicate connection scope
+	ErrInvalidDuplicateScope = errors.New("duplicate connection scope must be device or user")
+
+	// ErrInvalidLogLevel indicates an unknown log level
+	ErrInvalidLogLevel = errors.New("log level must be debug, info, warn or error")
+
+	// ErrInvalidLogFormat indicates an unknown log format
+	ErrInvalidLogFormat = errors.New("log format must be text or json")
+
+	// ErrIncompleteUploadStorage indicates an upload bucket without its region or credentials
+	ErrIncompleteUploadStorage = errors.New("UPLOAD_S3_REGION, UPLOAD_S3_ACCESS_KEY and UPLOAD_S3_SECRET_KEY must be set with UPLOAD_S3_BUCKET")
+
+	// ErrInvalidUploadSettings indicates an upload URL lifetime outside 1 second to 7 days, or
+	// a maximum upload size below 1 byte
+	ErrInvalidUploadSettings = errors.New("upload URL TTL must be between 1 second and 7 days, and the maximum upload size at least 1 byte")
+
+	// ErrInvalidNetpollWorkers indicates netpoll enabled without workers to handle messages
+	ErrInvalidNetpollWorkers = errors.New("netpoll workers must be at least 1")
+
+	// ErrInvalidConnectionLimits indicates a negative per-address connection or per-client
+	// channel limit
+	ErrInvalidConnectionLimits = errors.New("MAX_CONNECTIONS_PER_IP and MAX_CHANNELS_PER_CLIENT cannot be negative")
+
+	// ErrInvalidModerationLogSize indicates a negative moderation log size
+	ErrInvalidModerationLogSize = errors.New("moderation log size cannot be negative")
+
+	// ErrIncompletePusherApp indicates a Pusher app without all of its ID, key and secret
+	ErrIncompletePusherApp = errors.New("PUSHER_APP_ID, PUSHER_APP_KEY and PUSHER_APP_SECRET must be set together")
 )
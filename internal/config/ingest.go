@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Ingest source types, which select how webhook signatures are verified
+const (
+	IngestTypeStripe = "stripe" // Stripe-Signature header
+	IngestTypeGitHub = "github" // X-Hub-Signature-256 header
+	IngestTypeCustom = "custom" // X-Signature-256 header, same format as GitHub
+)
+
+// IngestSource is a third-party webhook sender accepted at POST /api/ingest/{source}
+type IngestSource struct {
+	Type   string       `json:"type"`
+	Secret string       `json:"secret"`
+	Rules  []IngestRule `json:"rules"`
+}
+
+// IngestRule maps a webhook to a channel broadcast. Match compares dotted body paths (or
+// "header.<Name>") with expected values; Channel and Event are templates with {{path}}
+// placeholders, and Data selects the part of the body to broadcast (empty for all of it).
+type IngestRule struct {
+	Match   map[string]string `json:"match,omitempty"`
+	Channel string            `json:"channel"`
+	Event   string            `json:"event"`
+	Data    string            `json:"data,omitempty"`
+}
+
+// ParseIngestSources decodes and validates ingest source definitions keyed by source name
+func ParseIngestSources(data []byte) (map[string]IngestSource, error) {
+	var sources map[string]IngestSource
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIngestSource, err)
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		source := sources[name]
+		switch source.Type {
+		case IngestTypeStripe, IngestTypeGitHub, IngestTypeCustom:
+		default:
+			return nil, errorAt(fmt.Errorf("%w: %s has unknown type %q", ErrInvalidIngestSource, name, source.Type), name, "type")
+		}
+		if source.Secret == "" {
+			return nil, errorAt(fmt.Errorf("%w: %s has no secret", ErrInvalidIngestSource, name), name)
+		}
+		for i, rule := range source.Rules {
+			if rule.Channel == "" || rule.Event == "" {
+				return nil, errorAt(fmt.Errorf("%w: %s rule %d needs a channel and an event", ErrInvalidIngestSource, name, i+1), name, "rules", i)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// LoadIngestSources reads ingest source definitions from a JSON file
+func LoadIngestSources(filename string) (map[string]IngestSource, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ingest sources: %w", err)
+	}
+	sources, err := ParseIngestSources(data)
+	if err != nil {
+		return nil, fileError(filename, data, err)
+	}
+	return sources, nil
+}
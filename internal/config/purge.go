@@ -0,0 +1,82 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+)
+
+// CDN providers supported by purge rules
+const (
+	PurgeProviderCloudflare = "cloudflare"
+	PurgeProviderFastly     = "fastly"
+)
+
+// PurgeRule purges CDN caches when a broadcast matches one of Events (and one of Channels,
+// when set). URLs and Tags are templates with {{channel}}, {{event}} and {{data.<path>}}
+// placeholders. Tags are Cloudflare cache tags or Fastly surrogate keys.
+type PurgeRule struct {
+	Events    []string `json:"events"`
+	Channels  []string `json:"channels,omitempty"`
+	Provider  string   `json:"provider"`
+	ZoneID    string   `json:"zone_id,omitempty"`    // Cloudflare zone
+	ServiceID string   `json:"service_id,omitempty"` // Fastly service, required for tags
+	Token     string   `json:"token"`
+	URLs      []string `json:"urls,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	// Endpoint overrides the provider's API base URL, e.g. for an egress proxy
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// ParsePurgeRules decodes and validates a list of CDN purge rules
+func ParsePurgeRules(data []byte) ([]PurgeRule, error) {
+	var rules []PurgeRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPurgeRule, err)
+	}
+
+	for i, rule := range rules {
+		if err := checkPurgeRule(rule); err != nil {
+			return nil, errorAt(fmt.Errorf("%w: rule %d %v", ErrInvalidPurgeRule, i+1, err), i)
+		}
+	}
+	return rules, nil
+}
+
+// checkPurgeRule returns what a purge rule lacks, if anything
+func checkPurgeRule(rule PurgeRule) error {
+	switch {
+	case rule.Provider != PurgeProviderCloudflare && rule.Provider != PurgeProviderFastly:
+		return fmt.Errorf("has unknown provider %q", rule.Provider)
+	case rule.Token == "":
+		return fmt.Errorf("has no token")
+	case len(rule.Events) == 0:
+		return fmt.Errorf("has no events")
+	case len(rule.URLs) == 0 && len(rule.Tags) == 0:
+		return fmt.Errorf("purges neither urls nor tags")
+	case rule.Provider == PurgeProviderCloudflare && rule.ZoneID == "":
+		return fmt.Errorf("needs a Cloudflare zone_id")
+	case rule.Provider == PurgeProviderFastly && len(rule.Tags) > 0 && rule.ServiceID == "":
+		return fmt.Errorf("needs a Fastly service_id to purge tags")
+	}
+	for _, pattern := range append(append([]string{}, rule.Events...), rule.Channels...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("has invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// LoadPurgeRules reads CDN purge rules from a JSON file
+func LoadPurgeRules(filename string) ([]PurgeRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading purge rules: %w", err)
+	}
+	rules, err := ParsePurgeRules(data)
+	if err != nil {
+		return nil, fileError(filename, data, err)
+	}
+	return rules, nil
+}
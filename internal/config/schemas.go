@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"socket-server/internal/models"
+)
+
+// ParseEventSchemas decodes a JSON object of JSON Schemas by event name, checking that each
+// schema only uses the keywords the server enforces
+func ParseEventSchemas(data []byte) (map[string]json.RawMessage, error) {
+	var schemas map[string]json.RawMessage
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEventSchemas, err)
+	}
+
+	events := make([]string, 0, len(schemas))
+	for event := range schemas {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	for _, event := range events {
+		var object map[string]interface{}
+		if event == "" || json.Unmarshal(schemas[event], &object) != nil || object == nil {
+			return nil, errorAt(fmt.Errorf("%w: the schema of event %q is not an object", ErrInvalidEventSchemas, event), event)
+		}
+		if _, err := models.ParseSchema(schemas[event]); err != nil {
+			return nil, errorAt(fmt.Errorf("schema of event %q: %w", event, err), event)
+		}
+	}
+	return schemas, nil
+}
+
+// LoadEventSchemas reads event schemas from a JSON file
+func LoadEventSchemas(filename string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading event schemas: %w", err)
+	}
+	schemas, err := ParseEventSchemas(data)
+	if err != nil {
+		return nil, fileError(filename, data, err)
+	}
+	return schemas, nil
+}
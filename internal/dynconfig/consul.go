@@ -0,0 +1,52 @@
+package dynconfig
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ConsulSource reads the configuration document from the Consul KV store, using blocking
+// queries so changes are picked up as soon as they are written
+type ConsulSource struct {
+	address string
+	key     string
+	client  *http.Client
+}
+
+// Name returns the backend name
+func (c *ConsulSource) Name() string {
+	return "consul"
+}
+
+// Fetch reads the key, blocking until its index moves past version
+func (c *ConsulSource) Fetch(version uint64) ([]byte, uint64, error) {
+	endpoint := fmt.Sprintf("%s/v1/kv/%s?raw=true", c.address, url.PathEscape(c.key))
+	if version > 0 {
+		endpoint += fmt.Sprintf("&index=%d&wait=5m", version)
+	}
+
+	resp, err := c.client.Get(endpoint)
+	if err != nil {
+		return nil, version, fmt.Errorf("error querying consul: %w", err)
+	}
+	defer resp.Body.Close()
+
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, index, ErrKeyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, version, fmt.Errorf("consul returned HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, version, fmt.Errorf("error reading consul response: %w", err)
+	}
+
+	return body, index, nil
+}
@@ -0,0 +1,106 @@
+package dynconfig
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/pkg/logger"
+)
+
+// Source reads the dynamic configuration document from a key/value backend
+type Source interface {
+	// Fetch returns the document, blocking until it changes from the given version when the
+	// backend supports it. It returns the new version alongside the document.
+	Fetch(version uint64) ([]byte, uint64, error)
+	// Name returns a human readable backend name for logging
+	Name() string
+}
+
+// NewSource creates a Source for the given backend ("consul" or "etcd")
+func NewSource(backend, address, key string, pollInterval time.Duration) (Source, error) {
+	client := &http.Client{Timeout: 10 * time.Minute}
+	address = strings.TrimRight(address, "/")
+
+	switch backend {
+	case "consul":
+		if address == "" {
+			address = "http://127.0.0.1:8500"
+		}
+		return &ConsulSource{address: address, key: key, client: client}, nil
+	case "etcd":
+		if address == "" {
+			address = "http://127.0.0.1:2379"
+		}
+		if pollInterval <= 0 {
+			pollInterval = 10 * time.Second
+		}
+		return &EtcdSource{address: address, key: key, pollInterval: pollInterval, client: client}, nil
+	default:
+		return nil, ErrUnknownBackend
+	}
+}
+
+// Watcher keeps the server in sync with a dynamic configuration source
+type Watcher struct {
+	source Source
+	logger *logger.Logger
+}
+
+// NewWatcher creates a new watcher for a source
+func NewWatcher(source Source, logger *logger.Logger) *Watcher {
+	return &Watcher{
+		source: source,
+		logger: logger,
+	}
+}
+
+// Load fetches the current settings once, for use at startup
+func (w *Watcher) Load() (*config.DynamicSettings, uint64, error) {
+	data, version, err := w.source.Fetch(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	settings, err := config.ParseDynamicSettings(data)
+	return settings, version, err
+}
+
+// Watch calls apply every time the settings document changes. It never returns; backend errors
+// are logged and retried with a delay, and invalid documents are skipped.
+func (w *Watcher) Watch(version uint64, apply func(*config.DynamicSettings)) {
+	var last []byte
+
+	for {
+		data, newVersion, err := w.source.Fetch(version)
+		if err != nil {
+			if err != ErrKeyNotFound {
+				w.logger.Error("Dynamic config: failed to read from %s: %v", w.source.Name(), err)
+			}
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		// Versions can move backwards when a key is recreated
+		if newVersion < version {
+			newVersion = 0
+		}
+		version = newVersion
+
+		if bytes.Equal(data, last) {
+			continue
+		}
+
+		settings, err := config.ParseDynamicSettings(data)
+		if err != nil {
+			w.logger.Error("Dynamic config: ignoring invalid document from %s: %v", w.source.Name(), err)
+			last = data
+			continue
+		}
+
+		last = data
+		w.logger.Info("Dynamic config: applying update from %s (version %d)", w.source.Name(), version)
+		apply(settings)
+	}
+}
@@ -0,0 +1,194 @@
+package dynconfig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/pkg/logger"
+)
+
+func TestNewSource(t *testing.T) {
+	if _, err := NewSource("zookeeper", "", "key", 0); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("Expected an unknown backend to be refused, got %v", err)
+	}
+	source, err := NewSource("consul", "", "gosocket", 0)
+	if err != nil || source.(*ConsulSource).address != "http://127.0.0.1:8500" {
+		t.Errorf("Expected the local Consul agent by default, got %+v, %v", source, err)
+	}
+	source, err = NewSource("etcd", "http://etcd:2379/", "gosocket", 0)
+	if err != nil || source.(*EtcdSource).address != "http://etcd:2379" || source.(*EtcdSource).pollInterval != 10*time.Second {
+		t.Errorf("Expected the address without its trailing slash and a 10s poll, got %+v, %v", source, err)
+	}
+}
+
+func TestConsulSourceFetch(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Path != "/v1/kv/gosocket" {
+			w.Header().Set("X-Consul-Index", "3")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Consul-Index", "42")
+		fmt.Fprint(w, `{"banned_ips": ["10.0.0.1"]}`)
+	}))
+	defer server.Close()
+
+	source, _ := NewSource("consul", server.URL, "gosocket", 0)
+	data, index, err := source.Fetch(0)
+	if err != nil || index != 42 || string(data) != `{"banned_ips": ["10.0.0.1"]}` {
+		t.Fatalf("Expected the document at index 42, got %s, %d, %v", data, index, err)
+	}
+	if _, _, err := source.Fetch(index); err != nil {
+		t.Fatalf("Failed to fetch again: %v", err)
+	}
+	if queries[0] != "raw=true" || queries[1] != "raw=true&index=42&wait=5m" {
+		t.Errorf("Expected a plain read, then a blocking query from the last index, got %v", queries)
+	}
+
+	missing, _ := NewSource("consul", server.URL, "missing", 0)
+	if _, index, err := missing.Fetch(0); !errors.Is(err, ErrKeyNotFound) || index != 3 {
+		t.Errorf("Expected a missing key to be reported with its index, got %d, %v", index, err)
+	}
+}
+
+// etcdBackend serves a key through the etcd v3 JSON gateway, at the given mod revisions in turn
+type etcdBackend struct {
+	revisions []int
+	requests  int
+	mutex     sync.Mutex
+}
+
+func (e *etcdBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Key string `json:"key"`
+	}
+	json.NewDecoder(r.Body).Decode(&request)
+	key, _ := base64.StdEncoding.DecodeString(request.Key)
+	if r.URL.Path != "/v3/kv/range" || string(key) != "gosocket" {
+		fmt.Fprint(w, `{"header": {}}`)
+		return
+	}
+
+	e.mutex.Lock()
+	revision := e.revisions[len(e.revisions)-1]
+	if e.requests < len(e.revisions) {
+		revision = e.revisions[e.requests]
+	}
+	e.requests++
+	e.mutex.Unlock()
+
+	value := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"reliable_channels": ["r%d"]}`, revision)))
+	fmt.Fprintf(w, `{"kvs": [{"value": %q, "mod_revision": "%d"}]}`, value, revision)
+}
+
+func TestEtcdSourceFetch(t *testing.T) {
+	backend := &etcdBackend{revisions: []int{7, 7, 7, 9}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	source, _ := NewSource("etcd", server.URL, "gosocket", time.Millisecond)
+	data, revision, err := source.Fetch(0)
+	if err != nil || revision != 7 || string(data) != `{"reliable_channels": ["r7"]}` {
+		t.Fatalf("Expected the document at revision 7, got %s, %d, %v", data, revision, err)
+	}
+	data, revision, err = source.Fetch(revision)
+	if err != nil || revision != 9 || string(data) != `{"reliable_channels": ["r9"]}` {
+		t.Fatalf("Expected polling until revision 9, got %s, %d, %v", data, revision, err)
+	}
+	if backend.requests != 4 {
+		t.Errorf("Expected 4 range requests, got %d", backend.requests)
+	}
+
+	missing, _ := NewSource("etcd", server.URL, "missing", time.Millisecond)
+	if _, _, err := missing.Fetch(0); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected a missing key to be reported, got %v", err)
+	}
+}
+
+// scriptedSource returns its documents in turn, then blocks
+type scriptedSource struct {
+	documents []string
+	versions  []uint64
+	fetched   []uint64
+	mutex     sync.Mutex
+}
+
+func (s *scriptedSource) Name() string {
+	return "scripted"
+}
+
+func (s *scriptedSource) Fetch(version uint64) ([]byte, uint64, error) {
+	s.mutex.Lock()
+	s.fetched = append(s.fetched, version)
+	if len(s.documents) == 0 {
+		s.mutex.Unlock()
+		select {}
+	}
+	document, next := s.documents[0], s.versions[0]
+	s.documents, s.versions = s.documents[1:], s.versions[1:]
+	s.mutex.Unlock()
+	return []byte(document), next, nil
+}
+
+func TestWatcherWatch(t *testing.T) {
+	source := &scriptedSource{
+		documents: []string{
+			`{"reliable_channels": ["a"]}`,
+			`{"reliable_channels": ["a"]}`,
+			`{"channel_rate_limits": "no-rate"}`,
+			`{"reliable_channels": ["b"]}`,
+		},
+		// The key was recreated before the last document, resetting its index
+		versions: []uint64{5, 6, 7, 2},
+	}
+	applied := make(chan []string, 4)
+	go NewWatcher(source, logger.New(false)).Watch(4, func(settings *config.DynamicSettings) {
+		applied <- settings.ReliableChannels
+	})
+
+	for _, expected := range []string{"a", "b"} {
+		select {
+		case channels := <-applied:
+			if len(channels) != 1 || channels[0] != expected {
+				t.Fatalf("Expected reliable channels [%s], got %v", expected, channels)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the document with reliable channels [%s] to be applied", expected)
+		}
+	}
+	select {
+	case channels := <-applied:
+		t.Fatalf("Expected unchanged and invalid documents to be skipped, got %v applied", channels)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	expected := []uint64{4, 5, 6, 7, 0}
+	if fmt.Sprint(source.fetched) != fmt.Sprint(expected) {
+		t.Errorf("Expected fetches from versions %v, restarting after the index went back, got %v", expected, source.fetched)
+	}
+}
+
+func TestWatcherLoad(t *testing.T) {
+	watcher := NewWatcher(&scriptedSource{documents: []string{`{"channel_groups": {"eu": {"pattern": "eu.*"}}}`}, versions: []uint64{3}}, logger.New(false))
+	settings, version, err := watcher.Load()
+	if err != nil || version != 3 || settings.ChannelGroups["eu"].Pattern != "eu.*" {
+		t.Errorf("Expected the parsed settings at version 3, got %+v, %d, %v", settings, version, err)
+	}
+
+	invalid := NewWatcher(&scriptedSource{documents: []string{`{"channel_rate_limits": "no-rate"}`}, versions: []uint64{1}}, logger.New(false))
+	if _, _, err := invalid.Load(); err == nil {
+		t.Error("Expected an invalid document to be refused at startup")
+	}
+}
@@ -0,0 +1,11 @@
+package dynconfig
+
+import "errors"
+
+var (
+	// ErrUnknownBackend indicates an unsupported configuration backend
+	ErrUnknownBackend = errors.New("unknown configuration backend, expected consul or etcd")
+
+	// ErrKeyNotFound indicates the configuration key does not exist in the backend
+	ErrKeyNotFound = errors.New("configuration key not found")
+)
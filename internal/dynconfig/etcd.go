@@ -0,0 +1,81 @@
+package dynconfig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// EtcdSource reads the configuration document from etcd through its v3 JSON gateway,
+// polling for changes to the key's modification revision
+type EtcdSource struct {
+	address      string
+	key          string
+	pollInterval time.Duration
+	client       *http.Client
+}
+
+// Name returns the backend name
+func (e *EtcdSource) Name() string {
+	return "etcd"
+}
+
+// Fetch reads the key, polling until its mod revision differs from version
+func (e *EtcdSource) Fetch(version uint64) ([]byte, uint64, error) {
+	for {
+		value, revision, err := e.get()
+		if err != nil {
+			return nil, version, err
+		}
+		if version == 0 || revision != version {
+			return value, revision, nil
+		}
+		time.Sleep(e.pollInterval)
+	}
+}
+
+// get performs a single range request for the key
+func (e *EtcdSource) get() ([]byte, uint64, error) {
+	request, err := json.Marshal(map[string]string{
+		"key": base64.StdEncoding.EncodeToString([]byte(e.key)),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := e.client.Post(e.address+"/v3/kv/range", "application/json", bytes.NewReader(request))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying etcd: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("etcd returned HTTP %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Kvs []struct {
+			Value       string `json:"value"`
+			ModRevision string `json:"mod_revision"`
+		} `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, 0, fmt.Errorf("error decoding etcd response: %w", err)
+	}
+
+	if len(response.Kvs) == 0 {
+		return nil, 0, ErrKeyNotFound
+	}
+
+	value, err := base64.StdEncoding.DecodeString(response.Kvs[0].Value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error decoding etcd value: %w", err)
+	}
+	revision, _ := strconv.ParseUint(response.Kvs[0].ModRevision, 10, 64)
+
+	return value, revision, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// defaultAnalyticsDays is the number of days returned when no range is requested
+const defaultAnalyticsDays = 7
+
+// SetAnalytics serves the daily usage rollups
+func (h *HTTPHandlers) SetAnalytics(analytics *services.AnalyticsStore) {
+	h.analytics = analytics
+}
+
+// GetAnalytics returns daily usage rollups. Query parameters: from and to (YYYY-MM-DD in UTC,
+// defaulting to the last 7 days) and channel (comma-separated channel patterns).
+func (h *HTTPHandlers) GetAnalytics(w http.ResponseWriter, r *http.Request) {
+	if h.analytics == nil {
+		http.Error(w, "Analytics are not enabled", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	now := h.wsServer.Now().UTC()
+	to := now.Format(models.AnalyticsDateFormat)
+	from := now.AddDate(0, 0, -defaultAnalyticsDays+1).Format(models.AnalyticsDateFormat)
+	for name, value := range map[string]*string{"from": &from, "to": &to} {
+		if param := query.Get(name); param != "" {
+			if _, err := time.Parse(models.AnalyticsDateFormat, param); err != nil {
+				http.Error(w, "Invalid '"+name+"' date, expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*value = param
+		}
+	}
+
+	var patterns []string
+	if channel := query.Get("channel"); channel != "" {
+		patterns = strings.Split(channel, ",")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"days": h.analytics.Query(from, to, patterns),
+	})
+}
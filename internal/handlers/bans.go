@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+)
+
+// GetBans returns the user and IP bans in force
+func (h *HTTPHandlers) GetBans(w http.ResponseWriter, r *http.Request) {
+	bans := h.wsServer.GetBans()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bans":  bans,
+		"total": len(bans),
+	})
+}
+
+// CreateBan bans a user ID or IP address and disconnects its connections. The ban is permanent
+// unless the request body has a "ttl" (seconds).
+func (h *HTTPHandlers) CreateBan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Type   string `json:"type"` // "user" or "ip"
+		Value  string `json:"value"`
+		Reason string `json:"reason"`
+		TTL    int    `json:"ttl"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if payload.TTL < 0 {
+		http.Error(w, "ttl must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	ban := models.Ban{Type: payload.Type, Value: payload.Value, Reason: payload.Reason}
+	if payload.TTL > 0 {
+		expiresAt := h.wsServer.Now().Add(time.Duration(payload.TTL) * time.Second)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	ban, disconnected, err := h.wsServer.AddBan(ban)
+	if err != nil {
+		if err == models.ErrInvalidBan {
+			http.Error(w, "type must be user or ip, with a user ID or a valid IP address as value", http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "success",
+		"ban":          ban,
+		"disconnected": disconnected,
+	})
+}
+
+// DeleteBan lifts the ban of a user ID or IP address
+func (h *HTTPHandlers) DeleteBan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	banType, value := vars["type"], vars["value"]
+
+	if err := h.wsServer.RemoveBan(banType, value); err != nil {
+		if err == models.ErrBanNotFound {
+			http.Error(w, "Ban not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Ban of " + banType + " " + value + " lifted",
+	})
+}
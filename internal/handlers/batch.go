@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"socket-server/internal/services"
+)
+
+// maxBroadcastBatch is the most broadcasts a single POST /api/broadcast request may carry
+const maxBroadcastBatch = 1000
+
+// BatchItemResult is the outcome of one broadcast of a batch. Successful items carry the
+// fields of a single broadcast's response; failed ones the HTTP status and error the single
+// broadcast would have been refused with.
+type BatchItemResult struct {
+	Index   int                    `json:"index"`
+	Status  string                 `json:"status"` // "success" or "error"
+	Code    int                    `json:"code"`
+	Message string                 `json:"message,omitempty"`
+	Type    string                 `json:"type,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"` // push and critical results, schema errors
+}
+
+// isBatch reports whether a broadcast request body is a JSON array of broadcasts
+func isBatch(body []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+}
+
+// broadcastBatch sends each broadcast of an array body in order, as if each was posted on its
+// own, so items are validated and delivered exactly like single broadcasts. A refused item
+// doesn't stop the others: the response reports a status per item.
+func (h *HTTPHandlers) broadcastBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(items) == 0 {
+		http.Error(w, "The batch is empty", http.StatusBadRequest)
+		return
+	}
+	if len(items) > maxBroadcastBatch {
+		http.Error(w, fmt.Sprintf("Too many broadcasts in the batch, the maximum is %d", maxBroadcastBatch), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]BatchItemResult, len(items))
+	failed := 0
+	for i, item := range items {
+		results[i] = h.broadcastBatchItem(r, item)
+		results[i].Index = i
+		if results[i].Status != "success" {
+			failed++
+		}
+	}
+	h.logger.Debug("Broadcast batch of %d messages: %d sent, %d refused", len(items), len(items)-failed, failed)
+
+	status := "success"
+	if failed == len(items) {
+		status = "error"
+	} else if failed > 0 {
+		status = "partial"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"total":     len(items),
+		"succeeded": len(items) - failed,
+		"failed":    failed,
+		"results":   results,
+	})
+}
+
+// broadcastBatchItem runs one broadcast of a batch through the Broadcast handler
+func (h *HTTPHandlers) broadcastBatchItem(r *http.Request, item json.RawMessage) BatchItemResult {
+	if !bytes.HasPrefix(bytes.TrimSpace(item), []byte("{")) {
+		return BatchItemResult{Status: "error", Code: http.StatusBadRequest, Error: "A batch item must be a broadcast object"}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/broadcast", bytes.NewReader(item)).WithContext(r.Context())
+	req.Header.Set("Content-Type", "application/json")
+	if origin := r.Header.Get(services.BridgeHeader); origin != "" {
+		req.Header.Set(services.BridgeHeader, origin)
+	}
+	recorder := httptest.NewRecorder()
+
+	h.Broadcast(recorder, req)
+
+	result := BatchItemResult{Status: "success", Code: recorder.Code}
+	var response map[string]interface{}
+	if json.Unmarshal(recorder.Body.Bytes(), &response) != nil {
+		// Refusals are plain text
+		result.Status = "error"
+		result.Error = strings.TrimSpace(recorder.Body.String())
+		return result
+	}
+	if recorder.Code != http.StatusOK {
+		result.Status = "error"
+	}
+
+	result.Message, _ = response["message"].(string)
+	result.Type, _ = response["type"].(string)
+	result.Error, _ = response["error"].(string)
+	for _, key := range []string{"status", "message", "type", "error"} {
+		delete(response, key)
+	}
+	if len(response) > 0 {
+		result.Details = response
+	}
+	return result
+}
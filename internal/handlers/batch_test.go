@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"socket-server/internal/auth"
+	"socket-server/internal/config"
+	"socket-server/internal/services"
+	"socket-server/internal/websocket"
+	"socket-server/pkg/logger"
+)
+
+func newTestHandlers(t *testing.T) *HTTPHandlers {
+	t.Helper()
+	cfg := config.New()
+	cfg.JWTSecret = "secret"
+	log := logger.New(false)
+	laravelSvc := services.NewLaravelService(t.TempDir(), "/bin/true", "", t.TempDir(), log)
+	return New(websocket.New(cfg, auth.New(cfg.JWTSecret), laravelSvc, log), log)
+}
+
+func TestBroadcastBatch(t *testing.T) {
+	h := newTestHandlers(t)
+	post := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		h.Broadcast(recorder, httptest.NewRequest(http.MethodPost, "/api/broadcast", strings.NewReader(body)))
+		return recorder
+	}
+	type response struct {
+		Status    string
+		Total     int
+		Succeeded int
+		Failed    int
+		Results   []BatchItemResult
+	}
+
+	recorder := post(`[
+		{"broadcast_type": "global", "event": "first"},
+		{"broadcast_type": "group", "group": "missing"},
+		"not a broadcast",
+		{"broadcast_type": "channel", "channel": "news", "event": "last"}
+	]`)
+	var got response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &got); recorder.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected a batch report, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if got.Status != "partial" || got.Total != 4 || got.Succeeded != 2 || got.Failed != 2 || len(got.Results) != 4 {
+		t.Fatalf("Expected 2 of the 4 broadcasts sent, got %+v", got)
+	}
+	expected := []struct {
+		status string
+		code   int
+	}{{"success", http.StatusOK}, {"error", http.StatusNotFound}, {"error", http.StatusBadRequest}, {"success", http.StatusOK}}
+	for i, result := range got.Results {
+		if result.Index != i || result.Status != expected[i].status || result.Code != expected[i].code {
+			t.Errorf("Expected item %d to be %s with %d, got %+v", i, expected[i].status, expected[i].code, result)
+		}
+	}
+	if got.Results[1].Error != "Group not found" || got.Results[3].Message != "Message broadcasted to channel news" {
+		t.Errorf("Expected the single broadcasts' error and message, got %+v", got.Results)
+	}
+
+	got = response{}
+	json.Unmarshal(post(`[{"broadcast_type": "client"}]`).Body.Bytes(), &got)
+	if got.Status != "error" || got.Failed != 1 {
+		t.Errorf("Expected a batch of refused broadcasts reported as an error, got %+v", got)
+	}
+
+	if recorder := post(`[]`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected an empty batch refused, got %d", recorder.Code)
+	}
+	if recorder := post("[" + strings.Repeat(`{},`, maxBroadcastBatch) + "{}]"); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a batch over %d broadcasts refused, got %d", maxBroadcastBatch, recorder.Code)
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+	"socket-server/internal/websocket"
+)
+
+// GetChannelMetadata returns a channel's metadata
+func (h *HTTPHandlers) GetChannelMetadata(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+
+	channel, exists := h.wsServer.GetChannel(channelName)
+	if !exists {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"channel":  channelName,
+		"metadata": channel.GetMetadata(),
+	})
+}
+
+// GetChannelHistory returns a channel's retained messages, optionally only those published after
+// the message given by since, and at most limit of the most recent ones. gap is set when since is
+// no longer retained, so messages may have been missed.
+func (h *HTTPHandlers) GetChannelHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+
+	channel, exists := h.wsServer.GetChannel(channelName)
+	if !exists {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			http.Error(w, "Invalid 'limit': expected a non-negative integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	since := r.URL.Query().Get("since")
+	messages, found := channel.HistorySince(since, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"channel":          channelName,
+		"messages":         messages,
+		"total":            len(messages),
+		"current_sequence": channel.CurrentSequence(),
+		"gap":              since != "" && !found,
+	})
+}
+
+// GetChannelModeration returns the moderation actions recorded in a channel, newest first. The
+// log outlives the channel, so actions remain auditable after its members left.
+func (h *HTTPHandlers) GetChannelModeration(w http.ResponseWriter, r *http.Request) {
+	channelName := mux.Vars(r)["channel"]
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			http.Error(w, "Invalid 'limit': expected a non-negative integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries := h.wsServer.GetModerationLog(channelName, limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"channel": channelName,
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// UpdateChannelMetadata merges the request body into a channel's metadata; keys set to null are
+// removed. Members are notified with a channel_updated event.
+func (h *HTTPHandlers) UpdateChannelMetadata(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+
+	var updates map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		http.Error(w, "Invalid JSON payload: expected an object of metadata keys", http.StatusBadRequest)
+		return
+	}
+	if len(updates) == 0 {
+		http.Error(w, "At least one metadata key is required", http.StatusBadRequest)
+		return
+	}
+
+	metadata, changed := h.wsServer.UpdateChannelMetadata(channelName, updates)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"channel":      channelName,
+		"metadata":     metadata,
+		"changed_keys": changed,
+	})
+}
+
+// DeleteChannelMetadataKey removes a single metadata key from a channel
+func (h *HTTPHandlers) DeleteChannelMetadataKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+	key := vars["key"]
+
+	if _, exists := h.wsServer.GetChannel(channelName); !exists {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	metadata, changed := h.wsServer.UpdateChannelMetadata(channelName, map[string]interface{}{key: nil})
+	if len(changed) == 0 {
+		http.Error(w, "Metadata key not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"channel":  channelName,
+		"metadata": metadata,
+	})
+}
+
+// CreateChannel pre-creates a channel, optionally with the settings UpdateChannel accepts, so
+// its access flags and capacity are in place before anyone joins
+func (h *HTTPHandlers) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name string `json:"name"`
+		models.ChannelSettings
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if payload.Name == "" {
+		http.Error(w, "Channel name is required", http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.wsServer.CreateChannel(payload.Name, payload.ChannelSettings)
+	if err != nil {
+		switch err {
+		case models.ErrChannelExists:
+			http.Error(w, "Channel already exists", http.StatusConflict)
+		case models.ErrInvalidChannelSettings:
+			http.Error(w, "max_clients cannot be negative", http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"channel":  payload.Name,
+		"settings": channel.GetSettings(),
+	})
+}
+
+// DeleteChannel force-closes a channel: its members are unsubscribed with a channel_closed
+// event carrying the optional "reason" of the request body, and the channel is deleted
+func (h *HTTPHandlers) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+
+	payload := struct {
+		Reason string `json:"reason"`
+	}{Reason: "Channel closed by admin"}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	removed, err := h.wsServer.CloseChannel(channelName, payload.Reason)
+	if err == models.ErrChannelNotFound {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":         true,
+		"channel":         channelName,
+		"members_removed": removed,
+	})
+}
+
+// UpdateChannel changes a channel's access flags (is_private, require_auth, read_only) and
+// capacity (max_clients, 0 for unlimited). Members are notified with a channel_updated event.
+func (h *HTTPHandlers) UpdateChannel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+
+	var settings models.ChannelSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changed, err := h.wsServer.UpdateChannelSettings(channelName, settings)
+	if err != nil {
+		switch err {
+		case models.ErrChannelNotFound:
+			http.Error(w, "Channel not found", http.StatusNotFound)
+		case models.ErrInvalidChannelSettings:
+			http.Error(w, "max_clients cannot be negative", http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	channel, _ := h.wsServer.GetChannel(channelName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"channel":  channelName,
+		"changed":  changed,
+		"settings": channel.GetSettings(),
+	})
+}
+
+// JoinUserToChannel subscribes all of a user's connections to a channel. The grant also applies
+// to connections the user makes within the optional "ttl" (seconds) from the request body.
+func (h *HTTPHandlers) JoinUserToChannel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+	userID := vars["user_id"]
+
+	var payload struct {
+		TTL int `json:"ttl"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.TTL < 0 {
+		http.Error(w, "ttl must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	ttl := time.Duration(payload.TTL) * time.Second
+	if ttl == 0 {
+		ttl = websocket.DefaultUserGrantTTL
+	}
+	joined := h.wsServer.GrantUserChannel(userID, channelName, ttl)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":            true,
+		"channel":            channelName,
+		"user_id":            userID,
+		"connections_joined": joined,
+		"expires_at":         h.wsServer.Now().Add(ttl),
+	})
+}
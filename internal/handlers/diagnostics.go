@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetSlowClients reports clients with bad connectivity. Thresholds can be overridden with the
+// queue_depth, rtt_ms and slow_writes query parameters.
+func (h *HTTPHandlers) GetSlowClients(w http.ResponseWriter, r *http.Request) {
+	criteria := h.wsServer.DefaultSlowClientCriteria()
+	query := r.URL.Query()
+
+	for _, param := range []string{"queue_depth", "rtt_ms", "slow_writes"} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid '"+param+"': expected a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		switch param {
+		case "queue_depth":
+			criteria.QueueDepth = n
+		case "rtt_ms":
+			criteria.RTT = time.Duration(n) * time.Millisecond
+		case "slow_writes":
+			criteria.SlowWrites = uint64(n)
+		}
+	}
+
+	clients := h.wsServer.SlowClients(criteria)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"criteria": map[string]interface{}{
+			"queue_depth": criteria.QueueDepth,
+			"rtt_ms":      criteria.RTT.Milliseconds(),
+			"slow_writes": criteria.SlowWrites,
+		},
+		"clients": clients,
+		"total":   len(clients),
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+)
+
+// unsafeFilenameChars are replaced in the channel name part of export filenames
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// SetExportDir enables channel history exports written to a directory on the server
+func (h *HTTPHandlers) SetExportDir(dir string) {
+	h.exportDir = dir
+}
+
+// ExportChannelHistory downloads a channel's retained messages as NDJSON (the default) or CSV,
+// chosen with the format query parameter. since and limit select messages as for
+// GET /api/channels/{channel}/history.
+func (h *HTTPHandlers) ExportChannelHistory(w http.ResponseWriter, r *http.Request) {
+	channelName, format, messages, ok := h.exportedHistory(w, r)
+	if !ok {
+		return
+	}
+
+	filename := exportFilename(channelName, format, h.wsServer.Now())
+	w.Header().Set("Content-Type", models.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := models.ExportHistory(w, format, messages); err != nil {
+		h.logger.Error("Failed to export the history of channel '%s': %v", channelName, err)
+	}
+}
+
+// CreateChannelExport writes a channel's retained messages to a file of the export directory,
+// with the same query parameters as ExportChannelHistory, for audits that must outlive the
+// channel's history
+func (h *HTTPHandlers) CreateChannelExport(w http.ResponseWriter, r *http.Request) {
+	if h.exportDir == "" {
+		http.Error(w, "History exports to files are not enabled", http.StatusNotFound)
+		return
+	}
+	channelName, format, messages, ok := h.exportedHistory(w, r)
+	if !ok {
+		return
+	}
+
+	var buffer bytes.Buffer
+	if err := models.ExportHistory(&buffer, format, messages); err != nil {
+		http.Error(w, "Failed to export the history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	exportedAt := h.wsServer.Now()
+	file := filepath.Join(h.exportDir, exportFilename(channelName, format, exportedAt))
+	tmpFile := file + ".tmp"
+	err := os.WriteFile(tmpFile, buffer.Bytes(), 0600)
+	if err == nil {
+		err = os.Rename(tmpFile, file)
+	}
+	if err != nil {
+		h.logger.Error("Failed to write the history export of channel '%s': %v", channelName, err)
+		http.Error(w, "Failed to write the export file", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("Exported %d messages of channel '%s' to %s", len(messages), channelName, file)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"channel":     channelName,
+		"format":      format,
+		"file":        file,
+		"messages":    len(messages),
+		"bytes":       buffer.Len(),
+		"exported_at": exportedAt,
+	})
+}
+
+// exportedHistory reads the channel, format and selected messages of an export request,
+// answering the request itself when they are invalid
+func (h *HTTPHandlers) exportedHistory(w http.ResponseWriter, r *http.Request) (string, string, []models.Message, bool) {
+	channelName := mux.Vars(r)["channel"]
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = models.ExportFormatNDJSON
+	}
+	if format != models.ExportFormatNDJSON && format != models.ExportFormatCSV {
+		http.Error(w, "Invalid 'format': expected ndjson or csv", http.StatusBadRequest)
+		return "", "", nil, false
+	}
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			http.Error(w, "Invalid 'limit': expected a non-negative integer", http.StatusBadRequest)
+			return "", "", nil, false
+		}
+	}
+
+	channel, exists := h.wsServer.GetChannel(channelName)
+	if !exists {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return "", "", nil, false
+	}
+	messages, _ := channel.HistorySince(query.Get("since"), limit)
+	return channelName, format, messages, true
+}
+
+// exportFilename names an export after the channel and the time it was made
+func exportFilename(channelName, format string, exportedAt time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", unsafeFilenameChars.ReplaceAllString(channelName, "_"), exportedAt.UTC().Format("20060102T150405.000Z"), format)
+}
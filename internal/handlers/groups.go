@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+)
+
+// groupResponse converts a channel group to its API representation
+func (h *HTTPHandlers) groupResponse(group *models.ChannelGroup) map[string]interface{} {
+	resolved, _ := h.wsServer.ResolveGroupChannels(group.Name)
+	return map[string]interface{}{
+		"name":              group.Name,
+		"channels":          group.GetChannels(),
+		"pattern":           group.GetPattern(),
+		"resolved_channels": resolved,
+		"created_at":        group.CreatedAt,
+	}
+}
+
+// GetGroups returns all channel groups
+func (h *HTTPHandlers) GetGroups(w http.ResponseWriter, r *http.Request) {
+	groups := h.wsServer.GetGroups()
+
+	groupResponse := make(map[string]interface{})
+	for name, group := range groups {
+		groupResponse[name] = h.groupResponse(group)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groupResponse)
+}
+
+// GetGroup returns a specific channel group
+func (h *HTTPHandlers) GetGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupName := vars["group"]
+
+	group, exists := h.wsServer.GetGroup(groupName)
+	if !exists {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.groupResponse(group))
+}
+
+// CreateGroup creates a new channel group
+func (h *HTTPHandlers) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name     string   `json:"name"`
+		Channels []string `json:"channels"`
+		Pattern  string   `json:"pattern"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if payload.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(payload.Channels) == 0 && payload.Pattern == "" {
+		http.Error(w, "channels or pattern is required", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.wsServer.CreateGroup(payload.Name, payload.Channels, payload.Pattern)
+	if err != nil {
+		switch err {
+		case models.ErrGroupExists:
+			http.Error(w, "Group already exists", http.StatusConflict)
+		case models.ErrInvalidPattern:
+			http.Error(w, "Invalid pattern", http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h.groupResponse(group))
+}
+
+// UpdateGroupPattern replaces the pattern of a channel group
+func (h *HTTPHandlers) UpdateGroupPattern(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupName := vars["group"]
+
+	group, exists := h.wsServer.GetGroup(groupName)
+	if !exists {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+
+	var payload struct {
+		Pattern string `json:"pattern"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidatePattern(payload.Pattern); err != nil {
+		http.Error(w, "Invalid pattern", http.StatusBadRequest)
+		return
+	}
+
+	group.SetPattern(payload.Pattern)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.groupResponse(group))
+}
+
+// DeleteGroup removes a channel group
+func (h *HTTPHandlers) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupName := vars["group"]
+
+	if err := h.wsServer.DeleteGroup(groupName); err != nil {
+		if err == models.ErrGroupNotFound {
+			http.Error(w, "Group not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Group " + groupName + " deleted",
+	})
+}
+
+// AddGroupChannel adds a channel to a channel group
+func (h *HTTPHandlers) AddGroupChannel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupName := vars["group"]
+	channelName := vars["channel"]
+
+	group, exists := h.wsServer.GetGroup(groupName)
+	if !exists {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+
+	group.AddChannel(channelName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Channel " + channelName + " added to group " + groupName,
+	})
+}
+
+// RemoveGroupChannel removes a channel from a channel group
+func (h *HTTPHandlers) RemoveGroupChannel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupName := vars["group"]
+	channelName := vars["channel"]
+
+	group, exists := h.wsServer.GetGroup(groupName)
+	if !exists {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+
+	group.RemoveChannel(channelName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Channel " + channelName + " removed from group " + groupName,
+	})
+}
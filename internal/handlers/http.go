@@ -1,22 +1,29 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	"socket-server/internal/models"
+	"socket-server/internal/services"
 	"socket-server/internal/websocket"
 	"socket-server/pkg/logger"
 )
 
 // HTTPHandlers contains all HTTP handlers
 type HTTPHandlers struct {
-	wsServer *websocket.Server
-	logger   *logger.Logger
+	wsServer  *websocket.Server
+	ingest    *services.IngestService
+	purge     *services.PurgeService
+	analytics *services.AnalyticsStore
+	exportDir string
+	logger    *logger.Logger
 }
 
 // New creates new HTTP handlers
@@ -44,6 +51,56 @@ func (h *HTTPHandlers) GetClients(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetClient returns a single client along with its connection statistics
+func (h *HTTPHandlers) GetClient(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clientID := vars["client"]
+
+	client, exists := h.wsServer.GetClient(clientID)
+	if !exists {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"client": client,
+		"stats":  client.Stats(),
+		"slow":   client.IsSlow(),
+	})
+}
+
+// GetUser returns a user's connections, each with its connection statistics. Offline users are
+// reported with when they were last seen, if the server remembers it.
+func (h *HTTPHandlers) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	clients := h.wsServer.GetUserClients(userID)
+	connections := make([]map[string]interface{}, 0, len(clients))
+	for _, client := range clients {
+		connections = append(connections, map[string]interface{}{
+			"client": client,
+			"stats":  client.Stats(),
+			"slow":   client.IsSlow(),
+		})
+	}
+
+	response := map[string]interface{}{
+		"user_id":     userID,
+		"online":      len(clients) > 0,
+		"connections": connections,
+		"total":       len(connections),
+	}
+	if len(clients) == 0 {
+		if lastSeen, ok := h.wsServer.UserLastSeen(userID); ok {
+			response["last_seen"] = lastSeen
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // GetChannels returns all channels
 func (h *HTTPHandlers) GetChannels(w http.ResponseWriter, r *http.Request) {
 	channels := h.wsServer.GetChannels()
@@ -52,11 +109,16 @@ func (h *HTTPHandlers) GetChannels(w http.ResponseWriter, r *http.Request) {
 	channelResponse := make(map[string]interface{})
 	for name, channel := range channels {
 		channelResponse[name] = map[string]interface{}{
-			"name":         channel.Name,
-			"is_private":   channel.IsPrivate,
-			"require_auth": channel.RequireAuth,
-			"client_count": channel.GetClientCount(),
-			"created_at":   channel.CreatedAt,
+			"name":          channel.Name,
+			"is_private":    channel.IsPrivate,
+			"require_auth":  channel.RequireAuth,
+			"client_count":  channel.GetClientCount(),
+			"ack_mode":      channel.AckMode,
+			"read_only":     channel.ReadOnly,
+			"max_clients":   channel.MaxClients,
+			"client_events": channel.ClientEvents,
+			"metadata":      channel.GetMetadata(),
+			"created_at":    channel.CreatedAt,
 		}
 	}
 
@@ -91,6 +153,33 @@ func (h *HTTPHandlers) GetChannelClients(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GetChannelReceipts returns the per-user read cursors of an ACK-mode channel
+func (h *HTTPHandlers) GetChannelReceipts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+
+	channel, exists := h.wsServer.GetChannel(channelName)
+	if !exists {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	cursors := channel.GetReadCursors()
+	receipts := make([]models.ReadCursor, 0, len(cursors))
+	for _, cursor := range cursors {
+		receipts = append(receipts, cursor)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"channel":          channelName,
+		"ack_mode":         channel.AckMode,
+		"current_sequence": channel.CurrentSequence(),
+		"receipts":         receipts,
+		"total":            len(receipts),
+	})
+}
+
 // KickClient kicks a specific client
 func (h *HTTPHandlers) KickClient(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -113,10 +202,9 @@ func (h *HTTPHandlers) KickClient(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Broadcast sends a message to a channel
+// Broadcast sends a message to a channel. An array body is a batch of broadcasts, sent in order.
 func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
-	h.logger.Info("🚀 Broadcast request started")
 
 	var payload struct {
 		Channel             string      `json:"channel"`
@@ -126,15 +214,45 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 		ExcludeCurrentUser  bool        `json:"exclude_current_user"`
 		UserID              *string     `json:"user_id"`
 		ClientID            *string     `json:"client_id"`
-		BroadcastType       string      `json:"broadcast_type"` // "channel", "global", "authenticated", "user", "user_except", "client"
+		Group               string      `json:"group"`
+		Priority            string      `json:"priority"` // "normal" (default) or "high"
+		CoalesceKey         string      `json:"coalesce_key"`
+		Template            bool        `json:"template"`         // resolve {{placeholders}} in data per recipient
+		DeviceType          string      `json:"device_type"`      // platform broadcasts: "mobile", "tablet", "desktop" or "bot"
+		OS                  string      `json:"os"`               // platform broadcasts: "ios", "android", "windows", "macos", "linux" or "chromeos"
+		IncludeChildren     bool        `json:"include_children"` // channel broadcasts: also send to child rooms
+		Countries           []string    `json:"countries"`        // geo broadcasts: ISO country codes
+		Region              string      `json:"region"`           // geo broadcasts: optional ISO subdivision code
+		BroadcastType       string      `json:"broadcast_type"`   // "channel", "global", "authenticated", "user", "user_except", "client", "group", "platform", "geo"
+		// user broadcasts: push notification sent to the user's devices when they have no active connection
+		Push *models.PushNotification `json:"push"`
+		// user broadcasts: delivered through the fallback sinks if the user stays offline
+		Critical *models.CriticalDelivery `json:"critical"`
+		// the connection that triggered the broadcast, which doesn't get it back (Laravel's
+		// toOthers): a client ID or Pusher socket ID, under either name
+		ExcludeClientID string `json:"exclude_client_id"`
+		SocketID        string `json:"socket_id"`
+		// bridged broadcasts: the ID and origin node of the forwarded message, kept so copies
+		// reaching this server twice are dropped
+		MessageID  string `json:"message_id"`
+		OriginNode string `json:"origin_node"`
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read the request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if isBatch(body) {
+		h.broadcastBatch(w, r, body)
+		return
 	}
 
 	decodeStart := time.Now()
-	err := json.NewDecoder(r.Body).Decode(&payload)
+	err = json.NewDecoder(bytes.NewReader(body)).Decode(&payload)
 	decodeTime := time.Since(decodeStart)
-	h.logger.Info("⏱️ JSON decode took: %v", decodeTime)
 	if err != nil {
-		h.logger.Error("Failed to decode JSON payload", "error", err.Error())
+		h.logger.Error("Failed to decode JSON payload: %v", err)
 
 		// Provide more specific error messages for common issues
 		errorMsg := "Invalid JSON payload: " + err.Error()
@@ -149,7 +267,9 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 			case "client_id":
 				errorMsg = "Invalid 'client_id' field: expected string, got " + jsonErr.Value + ". Example: \"client_id\": \"abc123\""
 			case "broadcast_type":
-				errorMsg = "Invalid 'broadcast_type' field: expected string, got " + jsonErr.Value + ". Valid values: \"global\", \"authenticated\", \"user\", \"user_except\", \"client\", \"channel\""
+				errorMsg = "Invalid 'broadcast_type' field: expected string, got " + jsonErr.Value + ". Valid values: \"global\", \"authenticated\", \"user\", \"user_except\", \"client\", \"channel\", \"group\", \"platform\", \"geo\""
+			case "group":
+				errorMsg = "Invalid 'group' field: expected string, got " + jsonErr.Value + ". Example: \"group\": \"all-eu-stores\""
 			case "broadcast_to_everyone":
 				errorMsg = "Invalid 'broadcast_to_everyone' field: expected boolean, got " + jsonErr.Value + ". Example: \"broadcast_to_everyone\": true"
 			case "exclude_current_user":
@@ -166,20 +286,41 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 	if payload.Event == "" {
 		payload.Event = "broadcast"
 	}
+	if errs := h.wsServer.ValidateEventData(payload.Event, payload.Data); len(errs) > 0 {
+		writeSchemaErrors(w, payload.Event, errs)
+		return
+	}
+
+	priority := models.PriorityNormal
+	switch payload.Priority {
+	case "", "normal":
+	case "high":
+		priority = models.PriorityHigh
+	default:
+		http.Error(w, "Invalid priority. Must be: normal or high", http.StatusBadRequest)
+		return
+	}
 
-	msgCreateStart := time.Now()
 	message := models.Message{
-		ID:        uuid.New().String(),
-		Channel:   payload.Channel,
-		Event:     payload.Event,
-		Data:      payload.Data,
-		Timestamp: time.Now(),
+		ID:          h.wsServer.NewID(),
+		Channel:     payload.Channel,
+		Event:       payload.Event,
+		Data:        payload.Data,
+		Timestamp:   h.wsServer.Now(),
+		Priority:    priority,
+		CoalesceKey: payload.CoalesceKey,
+		Template:    payload.Template,
+		BridgedFrom: r.Header.Get(services.BridgeHeader),
+	}
+	message.ExcludeClientID = payload.ExcludeClientID
+	if message.ExcludeClientID == "" {
+		message.ExcludeClientID = payload.SocketID
+	}
+	if message.BridgedFrom != "" && payload.MessageID != "" {
+		message.ID, message.Origin = payload.MessageID, payload.OriginNode
 	}
-	msgCreateTime := time.Since(msgCreateStart)
-	h.logger.Info("⏱️ Message creation took: %v", msgCreateTime)
 
 	// Determine broadcast type based on payload
-	typeDetectStart := time.Now()
 	broadcastType := payload.BroadcastType
 	if broadcastType == "" {
 		// Legacy behavior: determine from other fields
@@ -189,26 +330,43 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 			broadcastType = "user_except"
 		} else if payload.UserID != nil && *payload.UserID != "" {
 			broadcastType = "user"
+		} else if payload.Group != "" {
+			broadcastType = "group"
 		} else if payload.Channel != "" {
 			broadcastType = "channel"
 		} else {
 			broadcastType = "global"
 		}
 	}
-	typeDetectTime := time.Since(typeDetectStart)
-	h.logger.Info("⏱️ Broadcast type detection took: %v", typeDetectTime)
+
+	// Limit concurrent fan-outs server-wide; excess requests wait in a bounded queue
+	release, err := h.wsServer.AcquireBroadcastSlot(r.Context())
+	if err != nil {
+		h.logger.Warn("Broadcast rejected: %v", err)
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Too many concurrent broadcasts, retry later", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	// target is logged with the broadcast's timing
+	target := logger.Fields{"broadcast_type": broadcastType, "message_id": message.ID, "event": message.Event}
 
 	broadcastStart := time.Now()
 	var responseMessage string
+	// delivered counts the local connections the message was queued for, and deliveries
+	// details channel broadcasts
+	var delivered int
+	var deliveries []models.ChannelDelivery
+	var pushResult *models.PushResult
+	var criticalResult *models.CriticalResult
 	switch broadcastType {
 	case "global":
-		h.logger.Info("🌍 Starting global broadcast")
-		h.wsServer.BroadcastToAll(message)
+		delivered = h.wsServer.BroadcastToAll(message)
 		responseMessage = "Message broadcasted to all clients"
 
 	case "authenticated":
-		h.logger.Info("🔐 Starting authenticated broadcast")
-		h.wsServer.BroadcastToAuthenticated(message)
+		delivered = h.wsServer.BroadcastToAuthenticated(message)
 		responseMessage = "Message broadcasted to all authenticated clients"
 
 	case "user":
@@ -216,17 +374,33 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "user_id is required for user broadcast", http.StatusBadRequest)
 			return
 		}
-		h.logger.Info("👤 Starting user broadcast to user: %s", *payload.UserID)
-		h.wsServer.BroadcastToUser(*payload.UserID, message)
+		if payload.Critical != nil {
+			if err := payload.Critical.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		target["user_id"] = *payload.UserID
+		recipients := h.wsServer.BroadcastToUser(*payload.UserID, message)
+		delivered = recipients
 		responseMessage = "Message broadcasted to user " + *payload.UserID
+		if recipients == 0 && payload.Push != nil {
+			result := h.wsServer.PushToUser(*payload.UserID, *payload.Push, message)
+			pushResult = &result
+			responseMessage = "User " + *payload.UserID + " has no active connections, push fallback attempted"
+		}
+		if recipients == 0 && payload.Critical != nil {
+			result := h.wsServer.DeliverCritical(*payload.UserID, *payload.Critical, message)
+			criticalResult = &result
+		}
 
 	case "user_except":
 		if payload.UserID == nil || *payload.UserID == "" {
 			http.Error(w, "user_id is required for user_except broadcast", http.StatusBadRequest)
 			return
 		}
-		h.logger.Info("👥 Starting user_except broadcast excluding user: %s", *payload.UserID)
-		h.wsServer.BroadcastToUsersExcept(*payload.UserID, message)
+		target["user_id"] = *payload.UserID
+		delivered = h.wsServer.BroadcastToUsersExcept(*payload.UserID, message)
 		responseMessage = "Message broadcasted to all authenticated clients except user " + *payload.UserID
 
 	case "client":
@@ -234,7 +408,7 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "client_id is required for client broadcast", http.StatusBadRequest)
 			return
 		}
-		h.logger.Info("🖥️ Starting client broadcast to client: %s", *payload.ClientID)
+		target["client_id"] = *payload.ClientID
 		err := h.wsServer.BroadcastToClient(*payload.ClientID, message)
 		if err != nil {
 			if err == models.ErrClientNotFound {
@@ -244,6 +418,7 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
+		delivered = 1
 		responseMessage = "Message sent to client " + *payload.ClientID
 
 	case "channel":
@@ -251,29 +426,87 @@ func (h *HTTPHandlers) Broadcast(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "channel is required for channel broadcast", http.StatusBadRequest)
 			return
 		}
-		h.logger.Info("📺 Starting channel broadcast to channel: %s", payload.Channel)
-		h.wsServer.BroadcastToChannel(payload.Channel, message)
+		target["channel"] = payload.Channel
+		if payload.IncludeChildren {
+			target["include_children"] = true
+			deliveries = h.wsServer.BroadcastToChannelTree(payload.Channel, message)
+			responseMessage = fmt.Sprintf("Message broadcasted to channel %s and %d child channels", payload.Channel, len(deliveries)-1)
+			break
+		}
+		deliveries = []models.ChannelDelivery{h.wsServer.BroadcastToChannel(payload.Channel, message)}
 		responseMessage = "Message broadcasted to channel " + payload.Channel
 
+	case "group":
+		if payload.Group == "" {
+			http.Error(w, "group is required for group broadcast", http.StatusBadRequest)
+			return
+		}
+		target["group"] = payload.Group
+		deliveries, err = h.wsServer.BroadcastToGroup(payload.Group, message)
+		if err != nil {
+			if err == models.ErrGroupNotFound {
+				http.Error(w, "Group not found", http.StatusNotFound)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		responseMessage = fmt.Sprintf("Message broadcasted to %d channels in group %s", len(deliveries), payload.Group)
+
+	case "platform":
+		if payload.DeviceType == "" && payload.OS == "" {
+			http.Error(w, "device_type or os is required for platform broadcast", http.StatusBadRequest)
+			return
+		}
+		target["device_type"], target["os"] = payload.DeviceType, payload.OS
+		delivered = h.wsServer.BroadcastToPlatform(payload.DeviceType, payload.OS, message)
+		responseMessage = fmt.Sprintf("Message broadcasted to %d clients on the requested platform", delivered)
+
+	case "geo":
+		if len(payload.Countries) == 0 {
+			http.Error(w, "countries is required for geo broadcast", http.StatusBadRequest)
+			return
+		}
+		target["countries"], target["region"] = payload.Countries, payload.Region
+		delivered = h.wsServer.BroadcastToGeo(payload.Countries, payload.Region, message)
+		responseMessage = fmt.Sprintf("Message broadcasted to %d clients in the requested location", delivered)
+
 	default:
-		http.Error(w, "Invalid broadcast_type. Must be: global, authenticated, user, user_except, client, or channel", http.StatusBadRequest)
+		http.Error(w, "Invalid broadcast_type. Must be: global, authenticated, user, user_except, client, channel, group, platform, or geo", http.StatusBadRequest)
 		return
 	}
 	broadcastTime := time.Since(broadcastStart)
-	h.logger.Info("⏱️ Broadcast operation took: %v", broadcastTime)
+
+	h.purge.Trigger(message)
 
 	responseStart := time.Now()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": responseMessage,
 		"type":    broadcastType,
-	})
+	}
+	for _, delivery := range deliveries {
+		delivered += delivery.Delivered
+	}
+	response["delivered"] = delivered
+	if deliveries != nil {
+		response["channels"] = deliveries
+	}
+	if pushResult != nil {
+		response["push"] = pushResult
+	}
+	if criticalResult != nil {
+		response["critical"] = criticalResult
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 	responseTime := time.Since(responseStart)
-	h.logger.Info("⏱️ Response generation took: %v", responseTime)
 
-	totalTime := time.Since(startTime)
-	h.logger.Info("🏁 Total broadcast request took: %v", totalTime)
+	target["decode_ms"] = logger.Millis(decodeTime)
+	target["broadcast_ms"] = logger.Millis(broadcastTime)
+	target["response_ms"] = logger.Millis(responseTime)
+	target["total_ms"] = logger.Millis(time.Since(startTime))
+	h.logger.With(target).Debug("Broadcast request timing")
 }
 
 // Health returns server health status
@@ -281,23 +514,86 @@ func (h *HTTPHandlers) Health(w http.ResponseWriter, r *http.Request) {
 	clients := h.wsServer.GetClients()
 	channels := h.wsServer.GetChannels()
 
+	status := "healthy"
+	if h.wsServer.IsDraining() {
+		status = "draining"
+	} else if h.wsServer.IsStandby() {
+		status = "standby"
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":   "healthy",
-		"clients":  len(clients),
-		"channels": len(channels),
-		"version":  "1.0.0",
+		"status":    status,
+		"clients":   len(clients),
+		"channels":  len(channels),
+		"node_id":   h.wsServer.NodeID(),
+		"pod":       h.wsServer.PodInfo(),
+		"heartbeat": h.wsServer.HeartbeatSettings(),
+		"version":   "1.0.0",
 	})
 }
 
-// GetLogs returns recent server logs
-func (h *HTTPHandlers) GetLogs(w http.ResponseWriter, r *http.Request) {
-	// Get recent logs from logger
-	logs := h.logger.GetRecentLogs()
+// Drain stops the server from accepting new connections and gradually closes existing ones.
+// With ?wait=true the request blocks until every connection is closed, which suits a
+// Kubernetes preStop hook.
+func (h *HTTPHandlers) Drain(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("wait") == "true" {
+		h.wsServer.Drain()
+	} else {
+		go h.wsServer.Drain()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Server is draining",
+		"node_id": h.wsServer.NodeID(),
+	})
+}
+
+// Live answers Kubernetes liveness probes
+func (h *HTTPHandlers) Live(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// Ready answers Kubernetes readiness probes, failing once the server starts draining so the
+// pod is removed from service endpoints, and while it is a standby so it isn't added to them
+func (h *HTTPHandlers) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.wsServer.IsDraining() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	if h.wsServer.IsStandby() {
+		http.Error(w, "standby", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// Route tells load balancers and clients whether this node already holds a user's connections,
+// so reconnects can prefer the node with the user's state
+func (h *HTTPHandlers) Route(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	connections := len(h.wsServer.GetUserClients(userID))
+	preferredNode := ""
+	if connections > 0 {
+		preferredNode = h.wsServer.NodeID()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"logs":  logs,
-		"total": len(logs),
+		"user_id":        userID,
+		"node_id":        h.wsServer.NodeID(),
+		"connected":      connections > 0,
+		"connections":    connections,
+		"preferred_node": preferredNode,
 	})
 }
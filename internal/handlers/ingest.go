@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/auth"
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// maxIngestBodySize caps the size of accepted webhook bodies
+const maxIngestBodySize = 1 << 20
+
+// SetIngestService enables webhook ingestion with the configured sources
+func (h *HTTPHandlers) SetIngestService(ingest *services.IngestService) {
+	h.ingest = ingest
+}
+
+// Ingest receives a third-party webhook and broadcasts it to the channels its source's rules
+// map it to. Requests are authenticated by the source's webhook signature, not the API token.
+func (h *HTTPHandlers) Ingest(w http.ResponseWriter, r *http.Request) {
+	source := mux.Vars(r)["source"]
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBodySize))
+	if err != nil {
+		http.Error(w, "Webhook body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	messages, err := h.ingest.Transform(source, r.Header, body)
+	switch {
+	case errors.Is(err, models.ErrUnknownIngestSource):
+		http.Error(w, "Unknown ingest source", http.StatusNotFound)
+		return
+	case errors.Is(err, auth.ErrInvalidWebhookSignature):
+		h.logger.Warn("Rejected webhook for ingest source %s from %s: invalid signature", source, r.RemoteAddr)
+		http.Error(w, "Invalid webhook signature", http.StatusUnauthorized)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(messages) > 0 {
+		release, err := h.wsServer.AcquireBroadcastSlot(r.Context())
+		if err != nil {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too many concurrent broadcasts, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+	}
+
+	channels := make([]string, 0, len(messages))
+	for _, message := range messages {
+		h.wsServer.BroadcastToChannel(message.Channel, message)
+		h.purge.Trigger(message)
+		channels = append(channels, message.Channel)
+	}
+	h.logger.Info("Ingested webhook from %s into %d broadcasts", source, len(messages))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"source":   source,
+		"channels": channels,
+	})
+}
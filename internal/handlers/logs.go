@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"socket-server/pkg/logger"
+)
+
+// logStreamHeartbeat is how often an idle log stream sends a comment, so proxies keep it open
+const logStreamHeartbeat = 15 * time.Second
+
+// GetLogs returns recent server logs, optionally filtered by minimum level, a since timestamp
+// (RFC 3339 or Unix seconds), a grep pattern and a limit on the number of most recent entries
+func (h *HTTPHandlers) GetLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseLogFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logs := h.logger.QueryLogs(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"logs":  logs,
+		"total": len(logs),
+	})
+}
+
+// StreamLogs streams log entries as they are logged, as server-sent "log" events, until the
+// client goes away. It takes the same level and grep filters as GetLogs. Client connections,
+// authentications and disconnections are entries with an "event" field.
+func (h *HTTPHandlers) StreamLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseLogFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	entries, unsubscribe := h.logger.Subscribe(256)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": streaming logs\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if !filter.Matches(entry) {
+				continue
+			}
+			data, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: log\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
+
+// parseLogFilter parses the level, since, grep and limit query parameters
+func parseLogFilter(query url.Values) (logger.LogFilter, error) {
+	var filter logger.LogFilter
+	if value := query.Get("level"); value != "" {
+		level, err := logger.ParseLevel(value)
+		if err != nil {
+			return filter, errors.New("Invalid 'level': expected debug, info, warn or error")
+		}
+		filter.MinLevel = level
+	}
+	if value := query.Get("since"); value != "" {
+		since, err := parseSince(value)
+		if err != nil {
+			return filter, errors.New("Invalid 'since': expected an RFC 3339 timestamp or Unix seconds")
+		}
+		filter.Since = since
+	}
+	if value := query.Get("grep"); value != "" {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return filter, errors.New("Invalid 'grep': " + err.Error())
+		}
+		filter.Pattern = pattern
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return filter, errors.New("Invalid 'limit': expected a non-negative integer")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// parseSince parses an RFC 3339 timestamp or Unix seconds
+func parseSince(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// invalidLabelChars matches characters not allowed in Prometheus label names
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Metrics exposes load metrics in the Prometheus text format, for the Prometheus adapter
+// backing Kubernetes custom/external metrics
+func (h *HTTPHandlers) Metrics(w http.ResponseWriter, r *http.Request) {
+	stats := h.wsServer.Stats()
+	labels := h.metricLabels()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	standby := 0
+	if stats.Standby {
+		standby = 1
+	}
+	series := []struct {
+		name  string
+		help  string
+		kind  string
+		value interface{}
+	}{
+		{"socket_server_connections", "Open WebSocket connections", "gauge", stats.Connections},
+		{"socket_server_authenticated_connections", "Open authenticated WebSocket connections", "gauge", stats.AuthenticatedConnections},
+		{"socket_server_channels", "Active channels", "gauge", stats.Channels},
+		{"socket_server_messages_received_total", "Messages received from clients", "counter", stats.MessagesReceived},
+		{"socket_server_messages_sent_total", "Messages written to clients", "counter", stats.MessagesSent},
+		{"socket_server_messages_received_per_second", "Messages received per second over the last sample interval", "gauge", stats.ReceivedPerSecond},
+		{"socket_server_messages_sent_per_second", "Messages written per second over the last sample interval", "gauge", stats.SentPerSecond},
+		{"socket_server_expired_entries_total", "History messages and channel grants removed by the expiry sweep", "counter", stats.ExpiredEntries},
+		{"socket_server_reclaimed_bytes_total", "Approximate bytes reclaimed by the expiry sweep", "counter", stats.ReclaimedBytes},
+		{"socket_server_broadcasts_active", "Broadcast fan-outs currently running", "gauge", stats.BroadcastsActive},
+		{"socket_server_broadcasts_queued", "Broadcasts waiting for a fan-out slot", "gauge", stats.BroadcastsQueued},
+		{"socket_server_broadcasts_rejected_total", "Broadcasts rejected because the queue was full", "counter", stats.BroadcastsRejected},
+		{"socket_server_broadcast_wait_seconds_total", "Time broadcasts spent waiting for a fan-out slot", "counter", stats.BroadcastWaitSeconds},
+		{"socket_server_connection_panics_total", "Panics recovered in connection goroutines, each closing its connection", "counter", stats.ConnectionPanics},
+		{"socket_server_http_panics_total", "Panics recovered in HTTP handlers", "counter", stats.HTTPPanics},
+		{"socket_server_messages_rate_limited_total", "Client messages over the per-connection message rate limit", "counter", stats.MessagesRateLimited},
+		{"socket_server_http_rate_limited_total", "API requests rejected by the per-IP rate limit", "counter", stats.HTTPRateLimited},
+		{"socket_server_duplicate_broadcasts_total", "Channel broadcasts dropped because they were already delivered, relayed again by the cluster or a bridge", "counter", stats.DuplicateBroadcasts},
+		{"socket_server_connection_limit_rejected_total", "Connections refused because the node was at MAX_CONNECTIONS", "counter", stats.ConnectionLimitRejected},
+		{"socket_server_ip_connection_limit_rejected_total", "Connections refused because their address was at MAX_CONNECTIONS_PER_IP", "counter", stats.IPConnectionLimitRejected},
+		{"socket_server_channel_limit_rejected_total", "Channel joins denied because the client was at MAX_CHANNELS_PER_CLIENT", "counter", stats.ChannelLimitRejected},
+		{"socket_server_reserved_event_rejected_total", "Client messages refused because they named a reserved server event", "counter", stats.ReservedEventRejected},
+		{"socket_server_outbox_pending", "Payloads recorded in the dispatch outbox and not delivered to Laravel yet", "gauge", stats.OutboxPending},
+		{"socket_server_standby", "1 while the node is a warm standby copying its primary's state", "gauge", standby},
+		{"socket_server_standby_sync_failures_total", "Failed copies of the primary's state on a standby", "counter", stats.StandbySyncFailures},
+	}
+
+	for _, metric := range series {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", metric.name, metric.kind)
+		fmt.Fprintf(w, "%s{%s} %v\n", metric.name, labels, metric.value)
+	}
+}
+
+// MetricsSummary returns the load metrics as JSON, usable directly by the KEDA metrics-api scaler
+func (h *HTTPHandlers) MetricsSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node_id": h.wsServer.NodeID(),
+		"metrics": h.wsServer.Stats(),
+	})
+}
+
+// metricLabels builds the Prometheus label set identifying this node and its pod
+func (h *HTTPHandlers) metricLabels() string {
+	pod := h.wsServer.PodInfo()
+	pairs := []string{fmt.Sprintf("node=%q", h.wsServer.NodeID())}
+
+	if name, _ := pod["name"].(string); name != "" {
+		pairs = append(pairs, fmt.Sprintf("pod=%q", name))
+	}
+	if namespace, _ := pod["namespace"].(string); namespace != "" {
+		pairs = append(pairs, fmt.Sprintf("namespace=%q", namespace))
+	}
+
+	podLabels, _ := pod["labels"].(map[string]string)
+	names := make([]string, 0, len(podLabels))
+	for name := range podLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("label_%s=%q", invalidLabelChars.ReplaceAllString(name, "_"), podLabels[name]))
+	}
+
+	return strings.Join(pairs, ",")
+}
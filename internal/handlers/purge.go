@@ -0,0 +1,8 @@
+package handlers
+
+import "socket-server/internal/services"
+
+// SetPurgeService purges CDN caches for API broadcasts matching the configured rules
+func (h *HTTPHandlers) SetPurgeService(purge *services.PurgeService) {
+	h.purge = purge
+}
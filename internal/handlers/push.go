@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+)
+
+// GetUserDevices lists the devices registered for a user's push fallback
+func (h *HTTPHandlers) GetUserDevices(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	devices := h.wsServer.GetPushDevices(userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id": userID,
+		"devices": devices,
+		"count":   len(devices),
+	})
+}
+
+// RegisterUserDevice registers a device token that receives push notifications for user
+// broadcasts sent while the user has no active connection
+func (h *HTTPHandlers) RegisterUserDevice(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	var device models.PushDevice
+	if err := json.NewDecoder(r.Body).Decode(&device); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	if err := h.wsServer.RegisterPushDevice(userID, device); err != nil {
+		http.Error(w, "Invalid device: provider must be fcm or apns and token is required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"user_id":  userID,
+		"provider": device.Provider,
+	})
+}
+
+// DeleteUserDevice unregisters a device token from a user
+func (h *HTTPHandlers) DeleteUserDevice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.wsServer.RemovePushDevice(vars["user_id"], vars["token"]) {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"user_id": vars["user_id"],
+	})
+}
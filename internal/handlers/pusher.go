@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+)
+
+const (
+	// pusherTimestampWindow is how far the auth_timestamp of a Pusher HTTP API request may be from
+	// the server clock
+	pusherTimestampWindow = 600 * time.Second
+
+	// pusherMaxChannels is the most channels a Pusher event can be triggered on at once
+	pusherMaxChannels = 100
+
+	// pusherMaxBatch is the most events of a Pusher batch request
+	pusherMaxBatch = 10
+)
+
+// pusherEvent is an event triggered through the Pusher HTTP API. Data is a string, usually
+// JSON-encoded by the Pusher library.
+type pusherEvent struct {
+	Name     string   `json:"name"`
+	Data     string   `json:"data"`
+	Channel  string   `json:"channel"`
+	Channels []string `json:"channels"`
+	SocketID string   `json:"socket_id"`
+}
+
+// PusherEvents triggers an event on channels, the Pusher HTTP API used by Laravel's pusher
+// broadcast driver. Requests are signed with the Pusher app secret instead of the server token.
+func (h *HTTPHandlers) PusherEvents(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readPusherRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var event pusherEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message := h.triggerPusherEvent(event); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("{}"))
+}
+
+// PusherBatchEvents triggers up to 10 events, each on a single channel
+func (h *HTTPHandlers) PusherBatchEvents(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readPusherRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Batch []pusherEvent `json:"batch"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(payload.Batch) == 0 || len(payload.Batch) > pusherMaxBatch {
+		http.Error(w, "Batch must hold 1 to "+strconv.Itoa(pusherMaxBatch)+" events", http.StatusBadRequest)
+		return
+	}
+	for _, event := range payload.Batch {
+		if event.Channel == "" || len(event.Channels) > 0 {
+			http.Error(w, "Batch events must name a single channel", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, event := range payload.Batch {
+		if message := h.triggerPusherEvent(event); message != "" {
+			http.Error(w, message, http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("{}"))
+}
+
+// readPusherRequest reads the body of a Pusher HTTP API request after checking its app ID, key,
+// timestamp, body hash and signature
+func (h *HTTPHandlers) readPusherRequest(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	app := h.wsServer.PusherApp()
+	if mux.Vars(r)["app_id"] != app.ID {
+		http.Error(w, "Unknown app", http.StatusNotFound)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	query := r.URL.Query()
+	if query.Get("auth_key") != app.Key {
+		http.Error(w, "Unknown auth_key", http.StatusUnauthorized)
+		return nil, false
+	}
+	timestamp, err := strconv.ParseInt(query.Get("auth_timestamp"), 10, 64)
+	if err != nil || math.Abs(time.Since(time.Unix(timestamp, 0)).Seconds()) > pusherTimestampWindow.Seconds() {
+		http.Error(w, "Timestamp expired", http.StatusUnauthorized)
+		return nil, false
+	}
+	sum := md5.Sum(body)
+	if len(body) > 0 && query.Get("body_md5") != hex.EncodeToString(sum[:]) {
+		http.Error(w, "Invalid body_md5", http.StatusUnauthorized)
+		return nil, false
+	}
+	if !hmac.Equal([]byte(query.Get("auth_signature")), []byte(app.RequestSignature(r.Method, r.URL.Path, query))) {
+		h.logger.Warn("Rejected Pusher API request from %s: %v", r.RemoteAddr, models.ErrInvalidPusherSignature)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return nil, false
+	}
+	return body, true
+}
+
+// triggerPusherEvent broadcasts an event to its channels, returning why it was refused if it was.
+// JSON data is decoded, so native clients get it as an object like any other broadcast.
+func (h *HTTPHandlers) triggerPusherEvent(event pusherEvent) string {
+	channels := event.Channels
+	if event.Channel != "" {
+		channels = append(channels, event.Channel)
+	}
+	switch {
+	case event.Name == "" || len(event.Name) > 200:
+		return "name must be 1 to 200 characters"
+	case len(channels) == 0 || len(channels) > pusherMaxChannels:
+		return "channels must list 1 to " + strconv.Itoa(pusherMaxChannels) + " channels"
+	}
+
+	var data interface{} = event.Data
+	var decoded interface{}
+	if json.Unmarshal([]byte(event.Data), &decoded) == nil {
+		data = decoded
+	}
+
+	for _, channelName := range channels {
+		h.wsServer.BroadcastToChannel(channelName, models.Message{
+			ID:              h.wsServer.NewID(),
+			Channel:         channelName,
+			Event:           event.Name,
+			Data:            data,
+			ExcludeClientID: event.SocketID,
+			Timestamp:       h.wsServer.Now(),
+		})
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+)
+
+// GetSchedules returns the broadcasts waiting for their send time, soonest first
+func (h *HTTPHandlers) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules := h.wsServer.GetScheduledBroadcasts()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schedules": schedules,
+		"total":     len(schedules),
+	})
+}
+
+// CreateSchedule defers a broadcast. "broadcast" is the body of a POST /api/broadcast request,
+// sent at "send_at" (RFC 3339) or after "delay" seconds.
+func (h *HTTPHandlers) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Broadcast json.RawMessage `json:"broadcast"`
+		SendAt    *time.Time      `json:"send_at"`
+		Delay     int             `json:"delay"`
+		Note      string          `json:"note"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (payload.SendAt == nil) == (payload.Delay == 0) {
+		http.Error(w, "Either send_at or a positive delay is required", http.StatusBadRequest)
+		return
+	}
+
+	// The data is checked against its event schema now rather than when the broadcast comes due
+	var target struct {
+		Event string      `json:"event"`
+		Data  interface{} `json:"data"`
+	}
+	if json.Unmarshal(payload.Broadcast, &target) == nil {
+		if target.Event == "" {
+			target.Event = "broadcast"
+		}
+		if errs := h.wsServer.ValidateEventData(target.Event, target.Data); len(errs) > 0 {
+			writeSchemaErrors(w, target.Event, errs)
+			return
+		}
+	}
+
+	broadcast := models.ScheduledBroadcast{Broadcast: payload.Broadcast, Note: payload.Note}
+	if payload.SendAt != nil {
+		broadcast.SendAt = *payload.SendAt
+	} else {
+		broadcast.SendAt = h.wsServer.Now().Add(time.Duration(payload.Delay) * time.Second)
+	}
+
+	broadcast, err := h.wsServer.ScheduleBroadcast(broadcast)
+	if err != nil {
+		if err == models.ErrInvalidSchedule {
+			http.Error(w, "The broadcast must be a broadcast request object, due in the future", http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"schedule": broadcast,
+	})
+}
+
+// CancelSchedule cancels a broadcast that hasn't been sent yet
+func (h *HTTPHandlers) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.wsServer.CancelScheduledBroadcast(id); err != nil {
+		if err == models.ErrScheduleNotFound {
+			http.Error(w, "Scheduled broadcast not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Scheduled broadcast " + id + " cancelled",
+	})
+}
+
+// ExecuteBroadcast runs a broadcast request body through the Broadcast handler, so scheduled
+// broadcasts are validated and delivered exactly like immediate ones
+func (h *HTTPHandlers) ExecuteBroadcast(payload json.RawMessage) error {
+	req := httptest.NewRequest(http.MethodPost, "/api/broadcast", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	h.Broadcast(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		return fmt.Errorf("broadcast rejected (%d): %s", recorder.Code, strings.TrimSpace(recorder.Body.String()))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/internal/models"
+)
+
+// GetSchemas returns the registered event schemas
+func (h *HTTPHandlers) GetSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas := h.wsServer.GetEventSchemas()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schemas": schemas,
+		"total":   len(schemas),
+	})
+}
+
+// PutSchema registers the JSON Schema of an event, the request body, replacing any previous one.
+// Broadcasts of the event whose data doesn't match are then refused.
+func (h *HTTPHandlers) PutSchema(w http.ResponseWriter, r *http.Request) {
+	event := mux.Vars(r)["event"]
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	schema, err := h.wsServer.RegisterEventSchema(event, body)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidSchema) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"schema": schema,
+	})
+}
+
+// DeleteSchema stops validating the broadcasts of an event
+func (h *HTTPHandlers) DeleteSchema(w http.ResponseWriter, r *http.Request) {
+	event := mux.Vars(r)["event"]
+
+	if err := h.wsServer.RemoveEventSchema(event); err != nil {
+		if err == models.ErrSchemaNotFound {
+			http.Error(w, "Event schema not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"event":  event,
+	})
+}
+
+// writeSchemaErrors refuses a broadcast whose data doesn't match the schema of its event
+func writeSchemaErrors(w http.ResponseWriter, event string, errs []models.SchemaError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "Data does not match the schema of event " + event,
+		"errors": errs,
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"socket-server/internal/websocket"
+)
+
+// GetState returns the node's state as saved in STATE_FILE, which standby nodes copy
+func (h *HTTPHandlers) GetState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.wsServer.TakeSnapshot())
+}
+
+// GetStandbyStatus reports whether the node is a standby and how its copy of the primary's
+// state is going
+func (h *HTTPHandlers) GetStandbyStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.wsServer.StandbyStatus())
+}
+
+// PromoteStandby makes a standby node the primary, e.g. once the primary's host died
+func (h *HTTPHandlers) PromoteStandby(w http.ResponseWriter, r *http.Request) {
+	status, err := h.wsServer.Promote()
+	if errors.Is(err, websocket.ErrNotStandby) {
+		http.Error(w, "Node is not a standby", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Node promoted to primary",
+		"node_id": h.wsServer.NodeID(),
+		"standby": status,
+	})
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+// ipBucketIdleTimeout is how long an address's bucket is kept after its last request
+const ipBucketIdleTimeout = 10 * time.Minute
+
+// SharedLimiter takes tokens from rate-limit buckets shared by the nodes of a cluster
+type SharedLimiter interface {
+	Take(key string, rate float64, burst int) (allowed bool, wait time.Duration, err error)
+}
+
+// IPRateLimit provides per-IP rate limiting middleware
+type IPRateLimit struct {
+	rate      float64
+	burst     int
+	logger    *logger.Logger
+	onLimited func()
+
+	shared        SharedLimiter
+	sharedFailing atomic.Bool // the last shared take failed, so the failure was already logged
+
+	buckets   map[string]*ipBucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+// ipBucket is the token bucket of one address
+type ipBucket struct {
+	bucket   *models.TokenBucket
+	lastSeen time.Time
+	limited  bool // the previous request was rejected, so the burst was already logged
+}
+
+// NewIPRateLimit creates a new rate limiting middleware allowing rate requests per second from
+// each IP address, with bursts of up to burst requests. onLimited, if set, is called for every
+// rejected request.
+func NewIPRateLimit(rate float64, burst int, logger *logger.Logger, onLimited func()) *IPRateLimit {
+	return &IPRateLimit{
+		rate:      rate,
+		burst:     burst,
+		logger:    logger,
+		onLimited: onLimited,
+		buckets:   make(map[string]*ipBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// SetShared makes each address share its bucket across the nodes of a cluster, so a client
+// can't multiply its rate by spreading requests across replicas. The local buckets are used
+// while the shared ones can't be reached.
+func (l *IPRateLimit) SetShared(shared SharedLimiter) {
+	l.shared = shared
+}
+
+// Middleware rejects requests over the rate of their address with 429 Too Many Requests and a
+// Retry-After header. A nil limiter passes every request through.
+func (l *IPRateLimit) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		allowed, wait, newBurst := l.take(ip)
+		if !allowed {
+			if newBurst {
+				l.logger.Warn("HTTP API rate limit exceeded by %s (%s %s)", ip, r.Method, r.URL.Path)
+			}
+			if l.onLimited != nil {
+				l.onLimited()
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take takes a token from an address's bucket, creating it on the address's first request
+func (l *IPRateLimit) take(ip string) (allowed bool, wait time.Duration, newBurst bool) {
+	entry := l.entry(ip)
+
+	if l.shared != nil {
+		var err error
+		if allowed, wait, err = l.shared.Take("api:"+ip, l.rate, l.burst); err != nil {
+			if !l.sharedFailing.Swap(true) {
+				l.logger.Warn("Shared API rate limit unavailable, limiting per node: %v", err)
+			}
+			allowed, wait = entry.bucket.Take()
+		} else if l.sharedFailing.Swap(false) {
+			l.logger.Info("Shared API rate limit available again")
+		}
+	} else {
+		allowed, wait = entry.bucket.Take()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	newBurst = !allowed && !entry.limited
+	entry.limited = !allowed
+	return allowed, wait, newBurst
+}
+
+// entry returns an address's bucket, forgetting those of addresses idle for too long
+func (l *IPRateLimit) entry(ip string) *ipBucket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= time.Minute {
+		for address, entry := range l.buckets {
+			if now.Sub(entry.lastSeen) >= ipBucketIdleTimeout {
+				delete(l.buckets, address)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, exists := l.buckets[ip]
+	if !exists {
+		entry = &ipBucket{bucket: models.NewTokenBucket(l.rate, l.burst)}
+		l.buckets[ip] = entry
+	}
+	entry.lastSeen = now
+	return entry
+}
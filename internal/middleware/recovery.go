@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gorilla/mux"
+
+	"socket-server/pkg/logger"
+)
+
+// Recovery provides panic recovery middleware for HTTP handlers
+type Recovery struct {
+	logger  *logger.Logger
+	onPanic func()
+}
+
+// NewRecovery creates a new panic recovery middleware. onPanic, if set, is called for every
+// recovered panic, e.g. to count it in metrics.
+func NewRecovery(logger *logger.Logger, onPanic func()) *Recovery {
+	return &Recovery{
+		logger:  logger,
+		onPanic: onPanic,
+	}
+}
+
+// Middleware recovers panics in the next handler: the panic is logged with the route and stack,
+// the request gets a 500 response and the server keeps running
+func (rc *Recovery) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// net/http aborts responses this way on purpose
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			route := r.URL.Path
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+			rc.logger.Error("Recovered panic in HTTP handler %s %s (route %s, from %s): %v\n%s", r.Method, r.URL.Path, route, r.RemoteAddr, recovered, debug.Stack())
+			if rc.onPanic != nil {
+				rc.onPanic()
+			}
+
+			// If the handler already started the response, net/http only logs a superfluous WriteHeader
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
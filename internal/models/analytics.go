@@ -0,0 +1,25 @@
+package models
+
+// AnalyticsDateFormat is the layout of analytics days, which are calendar days in UTC
+const AnalyticsDateFormat = "2006-01-02"
+
+// AnalyticsDay is the usage rollup of one day
+type AnalyticsDay struct {
+	Day string `json:"day"`
+	// Messages counts messages published to channels
+	Messages uint64 `json:"messages"`
+	// UniqueUsers counts distinct users that authenticated
+	UniqueUsers int `json:"unique_users"`
+	// PeakConnections is the highest number of simultaneous connections
+	PeakConnections int                         `json:"peak_connections"`
+	Channels        map[string]ChannelAnalytics `json:"channels"`
+}
+
+// ChannelAnalytics is the usage rollup of one channel on one day
+type ChannelAnalytics struct {
+	Messages uint64 `json:"messages"`
+	// UniqueUsers counts distinct users that joined or published to the channel
+	UniqueUsers int `json:"unique_users"`
+	// PeakSubscribers is the highest number of simultaneous subscribers
+	PeakSubscribers int `json:"peak_subscribers"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"net"
+	"time"
+)
+
+// Ban types
+const (
+	// BanTypeUser bans a user ID: its connections are closed and it can't authenticate
+	BanTypeUser = "user"
+	// BanTypeIP bans an IP address: its connections are closed and refused
+	BanTypeIP = "ip"
+)
+
+// Ban blocks a user or IP address, permanently or until ExpiresAt
+type Ban struct {
+	Type      string     `json:"type"`
+	Value     string     `json:"value"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil for a permanent ban
+}
+
+// Validate checks the ban has a known type and a value, and normalizes IP addresses so
+// equivalent spellings are one ban
+func (b *Ban) Validate() error {
+	switch b.Type {
+	case BanTypeUser:
+		if b.Value == "" {
+			return ErrInvalidBan
+		}
+	case BanTypeIP:
+		ip := net.ParseIP(b.Value)
+		if ip == nil {
+			return ErrInvalidBan
+		}
+		b.Value = ip.String()
+	default:
+		return ErrInvalidBan
+	}
+	return nil
+}
+
+// Active reports whether the ban is in force at the given time
+func (b Ban) Active(now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// DisconnectReasonBandwidthExceeded is recorded when a client is disconnected for exceeding its
+// bandwidth cap
+const DisconnectReasonBandwidthExceeded = "bandwidth_exceeded"
+
+// bandwidthMeter counts bytes in one-second windows
+type bandwidthMeter struct {
+	windowStart     time.Time
+	windowBytes     int64
+	lastWindowBytes int64
+	mutex           sync.Mutex
+}
+
+// roll starts a new window once the current one is over. The caller must hold m.mutex.
+func (m *bandwidthMeter) roll(now time.Time) {
+	elapsed := now.Sub(m.windowStart)
+	if elapsed < time.Second {
+		return
+	}
+	if elapsed < 2*time.Second {
+		m.lastWindowBytes = m.windowBytes
+	} else {
+		m.lastWindowBytes = 0
+	}
+	m.windowStart = now
+	m.windowBytes = 0
+}
+
+// add counts bytes in the current window
+func (m *bandwidthMeter) add(bytes int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.roll(time.Now())
+	m.windowBytes += bytes
+}
+
+// tryAdd counts bytes if they fit within limit for the current window. Otherwise it returns
+// how long until the window resets. A window always accepts its first write, so a message
+// larger than the limit can still go out.
+func (m *bandwidthMeter) tryAdd(bytes, limit int64) (bool, time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	m.roll(now)
+	if m.windowBytes > 0 && m.windowBytes+bytes > limit {
+		return false, m.windowStart.Add(time.Second).Sub(now)
+	}
+	m.windowBytes += bytes
+	return true, 0
+}
+
+// rate returns the bytes counted in the last complete window
+func (m *bandwidthMeter) rate() int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.roll(time.Now())
+	return m.lastWindowBytes
+}
+
+// SetBandwidthLimit caps the bytes per second written to the client (0 disables the cap).
+// Writes over the cap are delayed until the next second, or with disconnect set, the client
+// is disconnected instead.
+func (c *Client) SetBandwidthLimit(bytesPerSecond int64, disconnect bool) {
+	c.bandwidthLimit.Store(bytesPerSecond)
+	c.bandwidthDisconnect.Store(disconnect)
+}
+
+// waitForBandwidth blocks until size bytes fit within the client's bandwidth cap
+func (c *Client) waitForBandwidth(size int) error {
+	limit := c.bandwidthLimit.Load()
+	if limit <= 0 {
+		c.sentBandwidth.add(int64(size))
+		return nil
+	}
+
+	for {
+		ok, wait := c.sentBandwidth.tryAdd(int64(size), limit)
+		if ok {
+			return nil
+		}
+		if c.bandwidthDisconnect.Load() {
+			return ErrBandwidthExceeded
+		}
+		c.stats.bandwidthThrottled.Add(1)
+		time.Sleep(wait)
+	}
+}
+
+// AllowBandwidth reports whether bytes can be published to the channel within its bandwidth
+// cap, counting them if so. Rejected publishes are counted as dropped.
+func (ch *Channel) AllowBandwidth(bytes int64) bool {
+	if ch.BandwidthLimit <= 0 {
+		ch.bandwidth.add(bytes)
+		return true
+	}
+	if ok, _ := ch.bandwidth.tryAdd(bytes, ch.BandwidthLimit); !ok {
+		ch.bandwidthDropped.Add(1)
+		return false
+	}
+	return true
+}
+
+// BandwidthUsage returns the bytes published to the channel in the last second and the number
+// of messages dropped by its bandwidth cap
+func (ch *Channel) BandwidthUsage() (bytesPerSecond int64, dropped uint64) {
+	return ch.bandwidth.rate(), ch.bandwidthDropped.Load()
+}
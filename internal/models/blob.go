@@ -0,0 +1,47 @@
+package models
+
+import "github.com/gorilla/websocket"
+
+// BlobEvent is the event of the envelope announcing a binary blob relayed through a channel
+const BlobEvent = "blob"
+
+// blobFrameSize is the most bytes of a blob written in one binary frame
+const blobFrameSize = 64 * 1024
+
+// BlobInfo is the metadata envelope of a blob: the binary frames following it carry its Size
+// bytes
+type BlobInfo struct {
+	ID          string      `json:"blob_id"`
+	Event       string      `json:"event"`
+	Name        string      `json:"name,omitempty"`
+	ContentType string      `json:"content_type,omitempty"`
+	Size        int         `json:"size"`
+	Metadata    interface{} `json:"metadata,omitempty"`
+}
+
+// writeBlob writes a blob message: its envelope, then its bytes in binary frames. Nothing else
+// is written in between, so the frames following an envelope always belong to it. Clients of
+// another wire protocol can't receive blobs and are skipped.
+func (c *Client) writeBlob(conn *websocket.Conn, message Message) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if c.encoder != nil {
+		return nil
+	}
+	data, err := c.encode(message)
+	if err != nil {
+		return err
+	}
+	if err := c.writeFrame(conn, websocket.TextMessage, data); err != nil {
+		return err
+	}
+	for blob := message.Blob; len(blob) > 0; {
+		frame := blob[:min(blobFrameSize, len(blob))]
+		if err := c.writeFrame(conn, websocket.BinaryMessage, frame); err != nil {
+			return err
+		}
+		blob = blob[len(frame):]
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// ChunkEvent is the event of the frames a large message is split into
+const ChunkEvent = "chunk"
+
+const (
+	// chunkTimeout is how long the chunks of a message may take to arrive
+	chunkTimeout = time.Minute
+	// maxPendingChunkedMessages is how many chunked messages a client may be sending at once
+	maxPendingChunkedMessages = 4
+	// maxChunks is the most chunks a message may be split into
+	maxChunks = 10000
+)
+
+// Chunk is one part of a message split into chunks. Concatenating the payloads of the chunks
+// sharing an ID, in index order, gives back the message's JSON.
+type Chunk struct {
+	ID      string `json:"chunk_id"`
+	Index   int    `json:"index"`
+	Total   int    `json:"total"`
+	Payload string `json:"payload"`
+}
+
+// SplitChunks splits an encoded message into chunks of at most size bytes, sharing the given
+// ID. Chunks are only cut between characters, so each payload is valid UTF-8.
+func SplitChunks(id string, data []byte, size int) []Chunk {
+	var chunks []Chunk
+	for len(data) > 0 {
+		cut := min(size, len(data))
+		for cut < len(data) && cut > 1 && !utf8.RuneStart(data[cut]) {
+			cut--
+		}
+		chunks = append(chunks, Chunk{ID: id, Index: len(chunks), Payload: string(data[:cut])})
+		data = data[cut:]
+	}
+	for i := range chunks {
+		chunks[i].Total = len(chunks)
+	}
+	return chunks
+}
+
+// SetChunkSize splits messages encoding to more than size bytes into chunk frames
+// (0 disables chunking). Only native protocol clients are sent chunks.
+func (c *Client) SetChunkSize(size int) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.chunkSize = size
+}
+
+// writeQueued writes a message taken from the outbound queue. A blob is written with its binary
+// frames (see writeBlob). A message over the chunk size is written as chunk frames, and control
+// messages queued meanwhile are written between them, so a large document doesn't hold up
+// acknowledgements and system messages.
+func (c *Client) writeQueued(conn *websocket.Conn, message Message, control chan Message) error {
+	if message.Blob != nil {
+		return c.writeBlob(conn, message)
+	}
+
+	c.writeMutex.Lock()
+	data, err := c.encode(message)
+	if err != nil || data == nil || c.encoder != nil || c.chunkSize <= 0 || len(data) <= c.chunkSize {
+		defer c.writeMutex.Unlock()
+		if err != nil || data == nil {
+			return err
+		}
+		return c.writeFrame(conn, websocket.TextMessage, data)
+	}
+	chunks := SplitChunks(c.keeper.NewID(), data, c.chunkSize)
+	c.writeMutex.Unlock()
+
+	for i, chunk := range chunks {
+		if i > 0 {
+			if err := c.writePendingControl(conn, control); err != nil {
+				return err
+			}
+		}
+		frame, err := json.Marshal(Message{ID: c.keeper.NewID(), Channel: message.Channel, Event: ChunkEvent, Data: chunk, Timestamp: message.Timestamp})
+		if err != nil {
+			return err
+		}
+		c.writeMutex.Lock()
+		err = c.writeFrame(conn, websocket.TextMessage, frame)
+		c.writeMutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePendingControl writes the control messages waiting in the queue
+func (c *Client) writePendingControl(conn *websocket.Conn, control chan Message) error {
+	for {
+		select {
+		case message, ok := <-control:
+			if !ok {
+				return nil
+			}
+			if err := c.writeJSON(conn, message); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// SetChunkedMessageLimit accepts messages of up to maxSize bytes sent in chunks (0 refuses them)
+func (c *Client) SetChunkedMessageLimit(maxSize int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.chunks = nil
+	if maxSize > 0 {
+		c.chunks = NewChunkAssembler(maxSize)
+	}
+}
+
+// AddChunk records a chunk of a message the client is sending, returning the message's JSON
+// once it is complete (see ChunkAssembler.Add)
+func (c *Client) AddChunk(chunk Chunk) ([]byte, error) {
+	c.mutex.RLock()
+	chunks := c.chunks
+	c.mutex.RUnlock()
+	if chunks == nil {
+		return nil, ErrChunkedMessageTooLarge
+	}
+	return chunks.Add(chunk, c.keeper.Now())
+}
+
+// ChunkAssembler reassembles the messages a client sends in chunks
+type ChunkAssembler struct {
+	maxSize int
+	pending map[string]*chunkedMessage
+	mutex   sync.Mutex
+}
+
+type chunkedMessage struct {
+	payloads []string
+	received int
+	size     int
+	started  time.Time
+}
+
+// NewChunkAssembler creates an assembler accepting messages of up to maxSize bytes
+func NewChunkAssembler(maxSize int) *ChunkAssembler {
+	return &ChunkAssembler{maxSize: maxSize, pending: make(map[string]*chunkedMessage)}
+}
+
+// Add records a chunk received at now. Once every chunk of the message has arrived, it returns
+// the message's JSON; until then it returns nil. A message that is malformed, too large or too
+// slow to arrive is dropped.
+func (a *ChunkAssembler) Add(chunk Chunk, now time.Time) ([]byte, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for id, message := range a.pending {
+		if now.Sub(message.started) > chunkTimeout {
+			delete(a.pending, id)
+		}
+	}
+
+	if chunk.ID == "" || chunk.Total < 1 || chunk.Index < 0 || chunk.Index >= chunk.Total || chunk.Total > maxChunks {
+		delete(a.pending, chunk.ID)
+		return nil, ErrInvalidChunk
+	}
+	message, exists := a.pending[chunk.ID]
+	if !exists {
+		if len(a.pending) >= maxPendingChunkedMessages {
+			return nil, ErrInvalidChunk
+		}
+		message = &chunkedMessage{payloads: make([]string, chunk.Total), started: now}
+		a.pending[chunk.ID] = message
+	}
+	if chunk.Total != len(message.payloads) || message.payloads[chunk.Index] != "" || chunk.Payload == "" {
+		delete(a.pending, chunk.ID)
+		return nil, ErrInvalidChunk
+	}
+
+	message.size += len(chunk.Payload)
+	if message.size > a.maxSize {
+		delete(a.pending, chunk.ID)
+		return nil, ErrChunkedMessageTooLarge
+	}
+	message.payloads[chunk.Index] = chunk.Payload
+	message.received++
+	if message.received < chunk.Total {
+		return nil, nil
+	}
+
+	delete(a.pending, chunk.ID)
+	data := make([]byte, 0, message.size)
+	for _, payload := range message.payloads {
+		data = append(data, payload...)
+	}
+	return data, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock returns the current time
+type Clock func() time.Time
+
+// Timekeeper is the clock and ID generator of a server or service. Its zero value, like a nil
+// one, reads the system clock and takes IDs from NewID; tests set their own to assert exact
+// payloads and step through expiries. Set them before the server or service is used. Clients
+// and channels share the Timekeeper of their server. Connection deadlines, timers and
+// elapsed-time measurements always use the system clock.
+type Timekeeper struct {
+	clock       Clock
+	idGenerator IDGenerator
+}
+
+// SetClock replaces the clock timestamps and expiries are read from. A nil clock restores the
+// system clock.
+func (t *Timekeeper) SetClock(clock Clock) {
+	t.clock = clock
+}
+
+// SetIDGenerator replaces the generator of message and client IDs. A nil generator restores
+// NewID.
+func (t *Timekeeper) SetIDGenerator(generator IDGenerator) {
+	t.idGenerator = generator
+}
+
+// Now returns the current time on the clock
+func (t *Timekeeper) Now() time.Time {
+	if t != nil && t.clock != nil {
+		return t.clock()
+	}
+	return time.Now()
+}
+
+// NewID returns a new message or client ID from the generator
+func (t *Timekeeper) NewID() string {
+	if t != nil && t.idGenerator != nil {
+		return t.idGenerator()
+	}
+	return NewID()
+}
+
+// ManualClock is a clock that only moves when told to, for tests. Pass its Now method to a
+// Timekeeper's SetClock.
+type ManualClock struct {
+	now   time.Time
+	mutex sync.Mutex
+}
+
+// NewManualClock creates a manual clock set to start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's time
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t
+func (c *ManualClock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = t
+}
+
+// NewSequentialIDGenerator returns a generator of predictable IDs, prefix-1, prefix-2 and so on,
+// for tests. Pass it to a Timekeeper's SetIDGenerator.
+func NewSequentialIDGenerator(prefix string) IDGenerator {
+	var sequence atomic.Uint64
+	return func() string {
+		return fmt.Sprintf("%s-%d", prefix, sequence.Add(1))
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+// SetCompression enables or disables permessage-deflate for messages written to the client.
+// It only has an effect when compression was negotiated during the handshake.
+func (c *Client) SetCompression(enabled bool) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.compressionDisabled = !enabled
+}
+
+// SetCompressionThreshold sets the size in bytes below which messages are written uncompressed
+// (0 compresses every message), since deflating tiny frames costs more CPU than it saves
+func (c *Client) SetCompressionThreshold(minSize int) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.compressionMinSize = minSize
+}
+
+// CompressionEnabled reports whether the client accepts compressed messages
+func (c *Client) CompressionEnabled() bool {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return !c.compressionDisabled
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"net/mail"
+	"time"
+)
+
+// CriticalDelivery marks a user broadcast as critical: if the user stays offline beyond the
+// configured threshold, the message is delivered through the fallback sinks (such as email)
+// instead
+type CriticalDelivery struct {
+	Email   string `json:"email"`   // recipient address for the email fallback
+	Name    string `json:"name"`    // optional recipient name, available to the email template
+	Subject string `json:"subject"` // optional, defaults to the broadcast event
+}
+
+// Validate checks the delivery has a bare recipient address (the name goes in Name)
+func (d CriticalDelivery) Validate() error {
+	address, err := mail.ParseAddress(d.Email)
+	if err != nil || address.Address != d.Email {
+		return ErrInvalidCriticalDelivery
+	}
+	return nil
+}
+
+// CriticalResult reports how the fallback of a critical broadcast was scheduled in the
+// broadcast response
+type CriticalResult struct {
+	// Sinks are the fallback sinks the message will be delivered through
+	Sinks []string `json:"sinks,omitempty"`
+	// DeliverAt is when the fallback runs, unless the user reconnects first
+	DeliverAt time.Time `json:"deliver_at,omitempty"`
+	// Skipped explains why no fallback was scheduled
+	Skipped string `json:"skipped,omitempty"`
+}
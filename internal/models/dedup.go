@@ -0,0 +1,51 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// RecentIDs remembers the IDs seen within a time window, so a message that reaches a node twice
+// (relayed by the cluster and a bridge, or looping between servers) is only delivered once. At
+// most limit IDs are kept; the oldest are forgotten first.
+type RecentIDs struct {
+	window time.Duration
+	limit  int
+	seen   map[string]time.Time
+	order  []recentID // oldest first
+	mutex  sync.Mutex
+}
+
+type recentID struct {
+	id   string
+	seen time.Time
+}
+
+// NewRecentIDs creates an empty set remembering IDs for window, keeping at most limit of them
+func NewRecentIDs(window time.Duration, limit int) *RecentIDs {
+	return &RecentIDs{window: window, limit: max(limit, 1), seen: make(map[string]time.Time)}
+}
+
+// Add records an ID seen at now. It returns false when the ID was already seen within the window.
+func (r *RecentIDs) Add(id string, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for len(r.order) > 0 && now.Sub(r.order[0].seen) >= r.window {
+		r.forgetOldest()
+	}
+	if _, exists := r.seen[id]; exists {
+		return false
+	}
+	if len(r.order) >= r.limit {
+		r.forgetOldest()
+	}
+	r.seen[id] = now
+	r.order = append(r.order, recentID{id: id, seen: now})
+	return true
+}
+
+func (r *RecentIDs) forgetOldest() {
+	delete(r.seen, r.order[0].id)
+	r.order = r.order[1:]
+}
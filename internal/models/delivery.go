@@ -0,0 +1,17 @@
+package models
+
+// ChannelDelivery reports how a broadcast on a channel was delivered on this node
+type ChannelDelivery struct {
+	Channel string `json:"channel"`
+	// Recipients counts the channel's members and pattern subscribers
+	Recipients int `json:"recipients"`
+	// Delivered counts the recipients the message was queued for; the others had a full send
+	// queue or were disconnecting
+	Delivered int `json:"delivered"`
+	// Throttled is set when the channel's rate limit holds the message back: it is published
+	// later, possibly coalesced, and isn't counted here
+	Throttled bool `json:"throttled,omitempty"`
+	// Dropped explains why the message wasn't published: "duplicate", "no_channel" or
+	// "bandwidth_limit"
+	Dropped string `json:"dropped,omitempty"`
+}
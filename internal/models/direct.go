@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DirectChannelPrefix is the prefix used for user-to-user direct message channels
+const DirectChannelPrefix = "dm."
+
+// User IDs are escaped in direct channel names, so an ID containing the "." separator, such as
+// an email address, stays a single segment: "a.b" becomes "a%2Eb"
+var (
+	directIDEscaper   = strings.NewReplacer("%", "%25", ".", "%2E")
+	directIDUnescaper = strings.NewReplacer("%25", "%", "%2E", ".")
+)
+
+// DirectChannelName returns the normalized direct channel name for two users.
+// The lower user ID always comes first so both participants resolve the same channel.
+func DirectChannelName(userA, userB string) string {
+	if compareUserIDs(userA, userB) > 0 {
+		userA, userB = userB, userA
+	}
+	return DirectChannelPrefix + directIDEscaper.Replace(userA) + "." + directIDEscaper.Replace(userB)
+}
+
+// IsDirectChannel reports whether a channel name uses the direct channel prefix
+func IsDirectChannel(channelName string) bool {
+	return strings.HasPrefix(channelName, DirectChannelPrefix)
+}
+
+// ParseDirectChannelName extracts the two participants from a direct channel name.
+// It only accepts names in normalized order, so "dm.2.1" is rejected in favour of "dm.1.2".
+func ParseDirectChannelName(channelName string) (userA, userB string, err error) {
+	if !IsDirectChannel(channelName) {
+		return "", "", ErrInvalidDirectChannel
+	}
+
+	parts := strings.Split(strings.TrimPrefix(channelName, DirectChannelPrefix), ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || parts[0] == parts[1] {
+		return "", "", ErrInvalidDirectChannel
+	}
+
+	// Rebuilding the name also refuses other spellings of the escaped IDs
+	userA, userB = directIDUnescaper.Replace(parts[0]), directIDUnescaper.Replace(parts[1])
+	if DirectChannelName(userA, userB) != channelName {
+		return "", "", ErrInvalidDirectChannel
+	}
+
+	return userA, userB, nil
+}
+
+// IsDirectChannelParticipant reports whether a user is one of the two participants of a direct channel
+func IsDirectChannelParticipant(channelName, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	userA, userB, err := ParseDirectChannelName(channelName)
+	if err != nil {
+		return false
+	}
+	return userID == userA || userID == userB
+}
+
+// compareUserIDs orders user IDs numerically when both are integers and lexically otherwise
+func compareUserIDs(a, b string) int {
+	numA, errA := strconv.ParseInt(a, 10, 64)
+	numB, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case numA < numB:
+			return -1
+		case numA > numB:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(a, b)
+}
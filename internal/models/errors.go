@@ -20,4 +20,96 @@ var (
 
 	// ErrInvalidMessage indicates an invalid message format
 	ErrInvalidMessage = errors.New("invalid message format")
+
+	// ErrSendQueueFull indicates a client's outbound queue has no room for another message
+	ErrSendQueueFull = errors.New("client send queue is full")
+
+	// ErrGroupNotFound indicates a channel group was not found
+	ErrGroupNotFound = errors.New("channel group not found")
+
+	// ErrGroupExists indicates a channel group with the same name already exists
+	ErrGroupExists = errors.New("channel group already exists")
+
+	// ErrInvalidPattern indicates a malformed channel pattern
+	ErrInvalidPattern = errors.New("invalid channel pattern")
+
+	// ErrInvalidDirectChannel indicates a malformed direct message channel name
+	ErrInvalidDirectChannel = errors.New("invalid direct channel name")
+
+	// ErrChannelFull indicates a channel has reached its client capacity
+	ErrChannelFull = errors.New("channel is full")
+
+	// ErrChannelExists indicates a channel with the same name already exists
+	ErrChannelExists = errors.New("channel already exists")
+
+	// ErrInvalidChannelSettings indicates invalid channel settings, e.g. a negative capacity
+	ErrInvalidChannelSettings = errors.New("invalid channel settings")
+
+	// ErrBandwidthExceeded indicates a client went over its bandwidth cap
+	ErrBandwidthExceeded = errors.New("bandwidth cap exceeded")
+
+	// ErrBroadcastQueueFull indicates too many broadcasts are already waiting for a fan-out slot
+	ErrBroadcastQueueFull = errors.New("broadcast queue is full")
+
+	// ErrUnknownIngestSource indicates a webhook for a source that isn't configured
+	ErrUnknownIngestSource = errors.New("unknown ingest source")
+
+	// ErrUnknownIDFormat indicates an ID format other than uuid, ulid or ksuid
+	ErrUnknownIDFormat = errors.New("unknown ID format")
+
+	// ErrUnknownPushProvider indicates a push device for a provider other than fcm or apns
+	ErrUnknownPushProvider = errors.New("unknown push provider")
+
+	// ErrInvalidPushDevice indicates a push device registration without a token
+	ErrInvalidPushDevice = errors.New("invalid push device")
+
+	// ErrPushTokenUnregistered indicates the push provider no longer accepts a device token
+	ErrPushTokenUnregistered = errors.New("push token is no longer registered")
+
+	// ErrPushProviderDisabled indicates a push to a provider that isn't configured
+	ErrPushProviderDisabled = errors.New("push provider is not configured")
+
+	// ErrInvalidCriticalDelivery indicates a critical broadcast without a valid recipient address
+	ErrInvalidCriticalDelivery = errors.New("critical delivery requires a valid email address")
+
+	// ErrInvalidBan indicates a ban whose type isn't user or ip, or without a valid value
+	ErrInvalidBan = errors.New("ban requires a type of user or ip and a valid value")
+
+	// ErrBanNotFound indicates a ban was not found
+	ErrBanNotFound = errors.New("ban not found")
+
+	// ErrInvalidSchedule indicates a scheduled broadcast that isn't a JSON object or isn't due in
+	// the future
+	ErrInvalidSchedule = errors.New("scheduled broadcast requires a broadcast object and a future send time")
+
+	// ErrScheduleNotFound indicates a scheduled broadcast was not found
+	ErrScheduleNotFound = errors.New("scheduled broadcast not found")
+
+	// ErrInvalidSocketIOPacket indicates a Socket.IO frame that can't be decoded, including
+	// binary packets, which aren't supported
+	ErrInvalidSocketIOPacket = errors.New("invalid Socket.IO packet")
+
+	// ErrInvalidPusherSignature indicates a Pusher subscription, signin or HTTP API request whose
+	// signature doesn't match the app secret
+	ErrInvalidPusherSignature = errors.New("invalid Pusher signature")
+
+	// ErrInvalidSchema indicates an event schema that isn't valid JSON Schema or uses keywords
+	// the server doesn't enforce
+	ErrInvalidSchema = errors.New("invalid event schema")
+
+	// ErrSchemaNotFound indicates no schema is registered for an event
+	ErrSchemaNotFound = errors.New("event schema not found")
+
+	// ErrInvalidChunk indicates a chunk that doesn't fit the message it belongs to, or a chunked
+	// message started while too many others are still incomplete
+	ErrInvalidChunk = errors.New("invalid chunk")
+
+	// ErrChunkedMessageTooLarge indicates a chunked message over the size limit
+	ErrChunkedMessageTooLarge = errors.New("chunked message is too large")
+
+	// ErrUnknownExportFormat indicates a history export format other than ndjson or csv
+	ErrUnknownExportFormat = errors.New("unknown export format")
+
+	// ErrUnsupportedVersion indicates a persisted file of a version the server can't upgrade
+	ErrUnsupportedVersion = errors.New("unsupported version")
 )
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Channel history export formats
+const (
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatCSV    = "csv"
+)
+
+// exportColumns are the CSV columns of an exported message; data holds the JSON-encoded data
+var exportColumns = []string{"id", "sequence", "timestamp", "channel", "event", "user_id", "username", "data"}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	if format == ExportFormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// ExportHistory writes messages in an export format: NDJSON writes each message as it is sent
+// to clients, one per line, and CSV writes a header row and a row per message
+func ExportHistory(w io.Writer, format string, messages []Message) error {
+	switch format {
+	case ExportFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, message := range messages {
+			if err := encoder.Encode(message); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		writer.Write(exportColumns)
+		for _, message := range messages {
+			data, err := json.Marshal(message.Data)
+			if err != nil {
+				return err
+			}
+			writer.Write([]string{
+				message.ID,
+				strconv.FormatUint(message.Sequence, 10),
+				message.Timestamp.UTC().Format(time.RFC3339Nano),
+				message.Channel,
+				message.Event,
+				message.UserID,
+				message.Username,
+				string(data),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return ErrUnknownExportFormat
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ConnectionFingerprint identifies the device a user is connected from. Clients may send a
+// stable device ID (e.g. one kept in local storage); otherwise the remote IP and User-Agent
+// stand in for it, so tabs of the same browser share a fingerprint.
+func ConnectionFingerprint(userID, deviceID, remoteIP, userAgent string) string {
+	hash := sha256.New()
+	hash.Write([]byte(userID))
+	hash.Write([]byte{0})
+	if deviceID != "" {
+		hash.Write([]byte("device:" + deviceID))
+	} else {
+		hash.Write([]byte(remoteIP))
+		hash.Write([]byte{0})
+		hash.Write([]byte(userAgent))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// SetFingerprint records the fingerprint of an authenticated connection
+func (c *Client) SetFingerprint(fingerprint string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Fingerprint = fingerprint
+}
+
+// GetFingerprint returns the connection's fingerprint, empty before authentication
+func (c *Client) GetFingerprint() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.Fingerprint
+}
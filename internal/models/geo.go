@@ -0,0 +1,37 @@
+package models
+
+import "strings"
+
+// GeoInfo holds the location resolved from a client's IP address
+type GeoInfo struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code, e.g. "FR"
+	Region  string `json:"region,omitempty"`  // ISO 3166-2 subdivision code, e.g. "IDF"
+	City    string `json:"city,omitempty"`
+}
+
+// MatchesLocation reports whether the location is in one of the given countries and, when
+// region is set, in that region. Empty filters match anything; unknown locations match only
+// empty filters.
+func (g *GeoInfo) MatchesLocation(countries []string, region string) bool {
+	if len(countries) == 0 && region == "" {
+		return true
+	}
+	if g == nil {
+		return false
+	}
+
+	if len(countries) > 0 {
+		found := false
+		for _, country := range countries {
+			if strings.EqualFold(g.Country, country) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return region == "" || strings.EqualFold(g.Region, region)
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"path"
+	"sync"
+	"time"
+)
+
+// NewChannelGroup creates a new channel group
+func NewChannelGroup(name string, channels []string, pattern string) *ChannelGroup {
+	group := &ChannelGroup{
+		Name:      name,
+		Channels:  make(map[string]bool),
+		Pattern:   pattern,
+		CreatedAt: time.Now(),
+	}
+	for _, channelName := range channels {
+		if channelName != "" {
+			group.Channels[channelName] = true
+		}
+	}
+	return group
+}
+
+// ChannelGroup represents a named set of channels that can be broadcast to in one call.
+// Membership is either an explicit list of channels, a glob pattern (e.g. "stores.eu.*"), or both.
+type ChannelGroup struct {
+	Name      string          `json:"name"`
+	Channels  map[string]bool `json:"channels"`
+	Pattern   string          `json:"pattern,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	mutex     sync.RWMutex    `json:"-"`
+}
+
+// AddChannel adds a channel to the group
+func (g *ChannelGroup) AddChannel(channelName string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.Channels[channelName] = true
+}
+
+// RemoveChannel removes a channel from the group
+func (g *ChannelGroup) RemoveChannel(channelName string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.Channels, channelName)
+}
+
+// SetPattern replaces the group's channel pattern
+func (g *ChannelGroup) SetPattern(pattern string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.Pattern = pattern
+}
+
+// SetChannels replaces the group's explicit channels
+func (g *ChannelGroup) SetChannels(channels []string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.Channels = make(map[string]bool)
+	for _, channelName := range channels {
+		if channelName != "" {
+			g.Channels[channelName] = true
+		}
+	}
+}
+
+// GetChannels returns a copy of the group's explicit channels
+func (g *ChannelGroup) GetChannels() []string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	channels := make([]string, 0, len(g.Channels))
+	for channelName := range g.Channels {
+		channels = append(channels, channelName)
+	}
+	return channels
+}
+
+// GetPattern returns the group's channel pattern
+func (g *ChannelGroup) GetPattern() string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.Pattern
+}
+
+// Matches reports whether a channel belongs to the group, either explicitly or via its pattern
+func (g *ChannelGroup) Matches(channelName string) bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.Channels[channelName] {
+		return true
+	}
+	if g.Pattern == "" {
+		return false
+	}
+	matched, err := path.Match(g.Pattern, channelName)
+	return err == nil && matched
+}
+
+// ValidatePattern checks that a group pattern is well-formed
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return ErrInvalidPattern
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import "strings"
+
+// ChannelSeparator separates a parent channel name from its child room name,
+// e.g. "event.123" is the parent of "event.123.chat" and "event.123.qa"
+const ChannelSeparator = "."
+
+// IsChildChannel reports whether name is a direct child room of parent. Only one level of
+// nesting is considered, so "event.123.chat.mods" is not a child of "event.123".
+func IsChildChannel(parent, name string) bool {
+	if parent == "" || IsDirectChannel(name) {
+		return false
+	}
+	rest, found := strings.CutPrefix(name, parent+ChannelSeparator)
+	return found && rest != "" && !strings.Contains(rest, ChannelSeparator)
+}
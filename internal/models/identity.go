@@ -0,0 +1,27 @@
+package models
+
+// identityFields are the fields of message data naming the sender, which only the server may set
+var identityFields = []string{"user_id", "username"}
+
+// StampIdentity makes the identity fields of client-supplied message data name the sender: a
+// user_id or username the client put in an object is overwritten with its authenticated
+// identity, or removed when it isn't authenticated, so receivers and Laravel can trust them.
+// Other data is returned unchanged.
+func StampIdentity(data interface{}, userID, username string) interface{} {
+	fields, ok := data.(map[string]interface{})
+	if !ok {
+		return data
+	}
+	identity := map[string]string{"user_id": userID, "username": username}
+	for _, name := range identityFields {
+		if _, present := fields[name]; !present {
+			continue
+		}
+		if identity[name] == "" {
+			delete(fields, name)
+		} else {
+			fields[name] = identity[name]
+		}
+	}
+	return fields
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ID formats accepted by NewIDGenerator
+const (
+	IDFormatUUID  = "uuid"
+	IDFormatULID  = "ulid"
+	IDFormatKSUID = "ksuid"
+)
+
+// IDGenerator returns a new unique identifier for a message or client
+type IDGenerator func() string
+
+// NewIDGenerator returns the generator for an ID format: "uuid" (random UUIDv4, the default),
+// "ulid" or "ksuid". ULIDs and KSUIDs start with a timestamp, so they sort by creation time.
+func NewIDGenerator(format string) (IDGenerator, error) {
+	switch format {
+	case "", IDFormatUUID:
+		return newUUID, nil
+	case IDFormatULID:
+		return (&ulidSource{}).next, nil
+	case IDFormatKSUID:
+		return newKSUID, nil
+	default:
+		return nil, ErrUnknownIDFormat
+	}
+}
+
+// NewID returns a new random UUIDv4. Servers and services take their IDs from their
+// Timekeeper, which generates them in the configured format.
+func NewID() string {
+	return newUUID()
+}
+
+func newUUID() string {
+	return uuid.New().String()
+}
+
+// crockfordAlphabet is the base32 alphabet used by ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidSource generates monotonic ULIDs: a 48-bit millisecond timestamp followed by 80 random
+// bits. IDs created in the same millisecond increment the random part so they still sort in
+// creation order.
+type ulidSource struct {
+	lastMillis uint64
+	entropy    [10]byte
+	mutex      sync.Mutex
+}
+
+func (u *ulidSource) next() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	millis := uint64(time.Now().UnixMilli())
+	if millis > u.lastMillis {
+		u.lastMillis = millis
+		rand.Read(u.entropy[:])
+	} else {
+		// Same millisecond (or the clock went back): keep the last timestamp and increment
+		for i := len(u.entropy) - 1; i >= 0; i-- {
+			u.entropy[i]++
+			if u.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:2], uint16(u.lastMillis>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(u.lastMillis))
+	copy(id[6:], u.entropy[:])
+
+	// 128 bits encode to 26 base32 characters, the first carrying only 3 bits
+	value := new(big.Int).SetBytes(id[:])
+	encoded := make([]byte, 26)
+	mask := big.NewInt(31)
+	for i := len(encoded) - 1; i >= 0; i-- {
+		encoded[i] = crockfordAlphabet[new(big.Int).And(value, mask).Int64()]
+		value.Rsh(value, 5)
+	}
+	return string(encoded)
+}
+
+const (
+	// ksuidEpoch is the KSUID timestamp origin (2014-05-13T16:53:20Z)
+	ksuidEpoch = 1400000000
+
+	// base62Alphabet is the alphabet used by KSUIDs
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// newKSUID returns a KSUID: a 32-bit second timestamp followed by 128 random bits, encoded as
+// 27 base62 characters
+func newKSUID() string {
+	return newKSUIDAt(time.Now())
+}
+
+// newKSUIDAt returns a KSUID for the given creation time
+func newKSUIDAt(now time.Time) string {
+	var id [20]byte
+	binary.BigEndian.PutUint32(id[0:4], uint32(now.Unix()-ksuidEpoch))
+	rand.Read(id[4:])
+
+	value := new(big.Int).SetBytes(id[:])
+	base := big.NewInt(62)
+	remainder := new(big.Int)
+	encoded := make([]byte, 27)
+	for i := len(encoded) - 1; i >= 0; i-- {
+		value.DivMod(value, base, remainder)
+		encoded[i] = base62Alphabet[remainder.Int64()]
+	}
+	return string(encoded)
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the language client-facing strings are written in
+const DefaultLocale = "en"
+
+// catalog maps a locale to translations of client-facing strings, keyed by the English text
+var catalog = map[string]map[string]string{
+	"fr": {
+		"Invalid token format":                             "Format de jeton invalide",
+		"Invalid token":                                    "Jeton invalide",
+		"Already authenticated":                            "Déjà authentifié",
+		"Invalid channel name":                             "Nom de canal invalide",
+		"Channel not found":                                "Canal introuvable",
+		"Channel is full":                                  "Le canal est complet",
+		"Channel is read-only":                             "Le canal est en lecture seule",
+		"Channel requires authentication":                  "Le canal nécessite une authentification",
+		"Not a member of channel":                          "Vous n'êtes pas membre du canal",
+		"Invalid direct channel name":                      "Nom de canal direct invalide",
+		"Direct channels require authentication":           "Les canaux directs nécessitent une authentification",
+		"Direct channel access denied":                     "Accès au canal direct refusé",
+		"User channel access denied":                       "Accès au canal utilisateur refusé",
+		"Invalid user_id":                                  "user_id invalide",
+		"Read receipts require authentication":             "Les accusés de lecture nécessitent une authentification",
+		"Read receipts are not enabled for this channel":   "Les accusés de lecture ne sont pas activés pour ce canal",
+		"Invalid read_up_to sequence":                      "Séquence read_up_to invalide",
+		"Invalid compression setting":                      "Paramètre de compression invalide",
+		"Session resumption is disabled":                   "La reprise de session est désactivée",
+		"Invalid resume token":                             "Jeton de reprise invalide",
+		"Resume token already used":                        "Jeton de reprise déjà utilisé",
+		"Session expired":                                  "Session expirée",
+		"Session resume from a different address rejected": "Reprise de session depuis une autre adresse refusée",
+		"Server is shutting down, please reconnect":        "Le serveur s'arrête, veuillez vous reconnecter",
+		"Rate limit exceeded":                              "Limite de débit dépassée",
+		"Already connected from another session":           "Déjà connecté depuis une autre session",
+		"Already subscribed from another session":          "Déjà abonné depuis une autre session",
+		"Client events require authentication":             "Les événements client nécessitent une authentification",
+		"Client events are not enabled for this channel":   "Les événements client ne sont pas activés pour ce canal",
+	},
+	"es": {
+		"Invalid token format":                             "Formato de token no válido",
+		"Invalid token":                                    "Token no válido",
+		"Already authenticated":                            "Ya autenticado",
+		"Invalid channel name":                             "Nombre de canal no válido",
+		"Channel not found":                                "Canal no encontrado",
+		"Channel is full":                                  "El canal está lleno",
+		"Channel is read-only":                             "El canal es de solo lectura",
+		"Channel requires authentication":                  "El canal requiere autenticación",
+		"Not a member of channel":                          "No eres miembro del canal",
+		"Invalid direct channel name":                      "Nombre de canal directo no válido",
+		"Direct channels require authentication":           "Los canales directos requieren autenticación",
+		"Direct channel access denied":                     "Acceso al canal directo denegado",
+		"User channel access denied":                       "Acceso al canal de usuario denegado",
+		"Invalid user_id":                                  "user_id no válido",
+		"Read receipts require authentication":             "Las confirmaciones de lectura requieren autenticación",
+		"Read receipts are not enabled for this channel":   "Las confirmaciones de lectura no están activadas en este canal",
+		"Invalid read_up_to sequence":                      "Secuencia read_up_to no válida",
+		"Invalid compression setting":                      "Configuración de compresión no válida",
+		"Session resumption is disabled":                   "La reanudación de sesión está desactivada",
+		"Invalid resume token":                             "Token de reanudación no válido",
+		"Resume token already used":                        "Token de reanudación ya utilizado",
+		"Session expired":                                  "Sesión caducada",
+		"Session resume from a different address rejected": "Reanudación de sesión desde otra dirección rechazada",
+		"Server is shutting down, please reconnect":        "El servidor se está apagando, vuelve a conectarte",
+		"Rate limit exceeded":                              "Límite de velocidad excedido",
+		"Already connected from another session":           "Ya conectado desde otra sesión",
+		"Already subscribed from another session":          "Ya suscrito desde otra sesión",
+		"Client events require authentication":             "Los eventos de cliente requieren autenticación",
+		"Client events are not enabled for this channel":   "Los eventos de cliente no están activados en este canal",
+	},
+	"de": {
+		"Invalid token format":                             "Ungültiges Token-Format",
+		"Invalid token":                                    "Ungültiges Token",
+		"Already authenticated":                            "Bereits authentifiziert",
+		"Invalid channel name":                             "Ungültiger Kanalname",
+		"Channel not found":                                "Kanal nicht gefunden",
+		"Channel is full":                                  "Der Kanal ist voll",
+		"Channel is read-only":                             "Der Kanal ist schreibgeschützt",
+		"Channel requires authentication":                  "Der Kanal erfordert eine Authentifizierung",
+		"Not a member of channel":                          "Kein Mitglied des Kanals",
+		"Invalid direct channel name":                      "Ungültiger Direktkanalname",
+		"Direct channels require authentication":           "Direktkanäle erfordern eine Authentifizierung",
+		"Direct channel access denied":                     "Zugriff auf den Direktkanal verweigert",
+		"User channel access denied":                       "Zugriff auf den Benutzerkanal verweigert",
+		"Invalid user_id":                                  "Ungültige user_id",
+		"Read receipts require authentication":             "Lesebestätigungen erfordern eine Authentifizierung",
+		"Read receipts are not enabled for this channel":   "Lesebestätigungen sind für diesen Kanal nicht aktiviert",
+		"Invalid read_up_to sequence":                      "Ungültige read_up_to-Sequenz",
+		"Invalid compression setting":                      "Ungültige Komprimierungseinstellung",
+		"Session resumption is disabled":                   "Die Sitzungswiederaufnahme ist deaktiviert",
+		"Invalid resume token":                             "Ungültiges Wiederaufnahme-Token",
+		"Resume token already used":                        "Wiederaufnahme-Token bereits verwendet",
+		"Session expired":                                  "Sitzung abgelaufen",
+		"Session resume from a different address rejected": "Sitzungswiederaufnahme von einer anderen Adresse abgelehnt",
+		"Server is shutting down, please reconnect":        "Der Server wird heruntergefahren, bitte neu verbinden",
+		"Rate limit exceeded":                              "Ratenlimit überschritten",
+		"Already connected from another session":           "Bereits über eine andere Sitzung verbunden",
+		"Already subscribed from another session":          "Bereits über eine andere Sitzung abonniert",
+		"Client events require authentication":             "Client-Ereignisse erfordern eine Authentifizierung",
+		"Client events are not enabled for this channel":   "Client-Ereignisse sind für diesen Kanal nicht aktiviert",
+	},
+}
+
+var catalogMutex sync.RWMutex
+
+// Translate returns text in the given locale. Regional locales such as "fr-CA" fall back to
+// their language, and untranslated strings are returned in English.
+func Translate(locale, text string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if locale == "" || locale == DefaultLocale {
+		return text
+	}
+
+	catalogMutex.RLock()
+	defer catalogMutex.RUnlock()
+
+	if translated, ok := catalog[locale][text]; ok {
+		return translated
+	}
+	if language, _, found := strings.Cut(locale, "-"); found {
+		if translated, ok := catalog[language][text]; ok {
+			return translated
+		}
+	}
+	return text
+}
+
+// LoadCatalog merges translations into the message catalog. The JSON document maps locales to
+// English strings and their translations, e.g. {"it": {"Channel not found": "Canale non trovato"}}.
+func LoadCatalog(data []byte) error {
+	var translations map[string]map[string]string
+	if err := json.Unmarshal(data, &translations); err != nil {
+		return fmt.Errorf("error parsing message catalog: %w", err)
+	}
+
+	catalogMutex.Lock()
+	defer catalogMutex.Unlock()
+
+	for locale, messages := range translations {
+		locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+		if catalog[locale] == nil {
+			catalog[locale] = make(map[string]string, len(messages))
+		}
+		for text, translated := range messages {
+			catalog[locale][text] = translated
+		}
+	}
+	return nil
+}
+
+// SetLocale sets the language of client-facing error and system messages
+func (c *Client) SetLocale(locale string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.locale = locale
+}
+
+// Locale returns the client's language, or DefaultLocale when none was set
+func (c *Client) Locale() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.locale == "" {
+		return DefaultLocale
+	}
+	return c.locale
+}
+
+// Translate returns text in the client's language
+func (c *Client) Translate(text string) string {
+	return Translate(c.Locale(), text)
+}
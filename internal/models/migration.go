@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Migration upgrades a persisted JSON document from version From to From+1. The document is
+// decoded generically, so a migration can rename, move or drop fields the current types no
+// longer have.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(document map[string]interface{}) error
+}
+
+// MigrateDocument upgrades a JSON document with a top-level "version" field to version current,
+// applying the migrations in turn. It returns the upgraded document and the version it was
+// written with; a document already at current is returned unchanged. A document without a
+// version is taken to be version 0. Documents of a newer version, or older ones missing a
+// migration step, are refused with ErrUnsupportedVersion rather than read as if they were
+// current.
+func MigrateDocument(data []byte, current int, migrations []Migration) ([]byte, int, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, 0, err
+	}
+	version := header.Version
+	if version == current {
+		return data, version, nil
+	}
+	if version > current {
+		return nil, version, fmt.Errorf("%w %d: written by a newer server, this one reads up to version %d", ErrUnsupportedVersion, version, current)
+	}
+
+	steps := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		steps[migration.From] = migration
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, version, err
+	}
+	for from := version; from < current; from++ {
+		migration, exists := steps[from]
+		if !exists {
+			return nil, version, fmt.Errorf("%w %d: no migration to version %d", ErrUnsupportedVersion, from, from+1)
+		}
+		if err := migration.Apply(document); err != nil {
+			return nil, version, fmt.Errorf("error migrating from version %d (%s): %w", from, migration.Description, err)
+		}
+		document["version"] = from + 1
+	}
+
+	upgraded, err := json.Marshal(document)
+	if err != nil {
+		return nil, version, err
+	}
+	return upgraded, version, nil
+}
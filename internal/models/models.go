@@ -1,33 +1,67 @@
 package models
 
 import (
+	"encoding/json"
+	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-// NewClient creates a new client
+const (
+	// DefaultSendQueueSize is the number of outbound messages buffered per client
+	DefaultSendQueueSize = 256
+
+	// DefaultControlQueueSize is the number of high-priority messages buffered per client
+	DefaultControlQueueSize = 64
+
+	// DefaultWriteTimeout is the write deadline for a single frame
+	DefaultWriteTimeout = 500 * time.Millisecond
+)
+
+// NewClient creates a new client on the system clock
 func NewClient(id string, conn *websocket.Conn) *Client {
-	return &Client{
+	return NewClientWithTimekeeper(id, conn, nil)
+}
+
+// NewClientWithTimekeeper creates a new client taking its timestamps and chunk IDs from the
+// Timekeeper of its server
+func NewClientWithTimekeeper(id string, conn *websocket.Conn, keeper *Timekeeper) *Client {
+	now := keeper.Now()
+	client := &Client{
 		ID:              id,
 		Conn:            conn,
 		Channels:        make(map[string]bool),
 		ChannelMetadata: make(map[string]*ChannelMetadata),
-		LastSeen:        time.Now(),
+		LastSeen:        now,
+		ConnectedAt:     now,
 		RemoteAddr:      "",
 		UserAgent:       "",
+		keeper:          keeper,
 	}
+	client.stats.keeper = keeper
+	client.SetPingPolicy(DefaultPingPolicy)
+	return client
 }
 
-// NewChannel creates a new channel
+// NewChannel creates a new channel on the system clock
 func NewChannel(name string) *Channel {
+	return NewChannelWithTimekeeper(name, nil)
+}
+
+// NewChannelWithTimekeeper creates a new channel taking its timestamps from the Timekeeper of
+// its server
+func NewChannelWithTimekeeper(name string, keeper *Timekeeper) *Channel {
 	return &Channel{
 		Name:        name,
 		Clients:     make(map[string]*Client),
 		IsPrivate:   false,
 		RequireAuth: false,
-		CreatedAt:   time.Now(),
+		CreatedAt:   keeper.Now(),
+		keeper:      keeper,
 	}
 }
 
@@ -35,106 +69,453 @@ func NewChannel(name string) *Channel {
 type ChannelMetadata struct {
 	Data     interface{} `json:"data"`
 	JoinedAt time.Time   `json:"joined_at"`
+	Events   []string    `json:"events,omitempty"` // events delivered from the channel, empty for all
+}
+
+// WantsEvent reports whether the client asked for an event of the channel
+func (m *ChannelMetadata) WantsEvent(event string) bool {
+	if m == nil || len(m.Events) == 0 {
+		return true
+	}
+	for _, name := range m.Events {
+		if name == event {
+			return true
+		}
+	}
+	return false
 }
 
 // Client represents a connected WebSocket client
 type Client struct {
-	ID              string                      `json:"id"`
-	Conn            *websocket.Conn             `json:"-"`
-	UserID          string                      `json:"user_id,omitempty"`
-	Username        string                      `json:"username,omitempty"`
-	Email           string                      `json:"email,omitempty"`
-	Channels        map[string]bool             `json:"channels"`
-	ChannelMetadata map[string]*ChannelMetadata `json:"channel_metadata"`
-	LastSeen        time.Time                   `json:"last_seen"`
-	RemoteAddr      string                      `json:"remote_addr"`
-	UserAgent       string                      `json:"user_agent"`
-	mutex           sync.RWMutex                `json:"-"`
+	ID               string                      `json:"id"`
+	Conn             *websocket.Conn             `json:"-"`
+	UserID           string                      `json:"user_id,omitempty"`
+	Username         string                      `json:"username,omitempty"`
+	Email            string                      `json:"email,omitempty"`
+	Channels         map[string]bool             `json:"channels"`
+	ChannelMetadata  map[string]*ChannelMetadata `json:"channel_metadata"`
+	LastSeen         time.Time                   `json:"last_seen"`
+	ConnectedAt      time.Time                   `json:"connected_at"`
+	RemoteAddr       string                      `json:"remote_addr"`
+	UserAgent        string                      `json:"user_agent"`
+	Device           DeviceInfo                  `json:"device"`
+	Geo              *GeoInfo                    `json:"geo,omitempty"`
+	Fingerprint      string                      `json:"fingerprint,omitempty"`
+	Protocol         string                      `json:"protocol,omitempty"`
+	Observer         bool                        `json:"observer,omitempty"` // may join any channel, never publish
+	disconnectReason string                      `json:"-"`
+	send             chan Message                `json:"-"`
+	control          chan Message                `json:"-"`
+	coalesced        map[string]Message          `json:"-"`
+	coalesceMutex    sync.Mutex                  `json:"-"`
+	writeMutex       sync.Mutex                  `json:"-"`
+	stats            clientStats                 `json:"-"`
+	mutex            sync.RWMutex                `json:"-"`
+
+	// Clock and ID generator of the client's server (see clock.go), nil for the system clock
+	keeper *Timekeeper
+
+	// Per-connection compression settings (see compression.go), guarded by writeMutex
+	compressionDisabled bool
+	compressionMinSize  int
+
+	// Write deadline per frame in nanoseconds (0 uses DefaultWriteTimeout)
+	writeTimeout atomic.Int64
+
+	// Language of error and system messages (see locale.go), guarded by mutex
+	locale string
+
+	// Told about panics in the writer goroutine (see recovery.go), guarded by mutex
+	panicHandler PanicHandler
+
+	// Told about every frame when wire tracing is enabled (see wiretrace.go)
+	wireTap WireTap
+
+	// Serializes messages for clients of another wire protocol (see protocol.go)
+	encoder MessageEncoder
+
+	// Chunking of large messages (see chunking.go); chunkSize is guarded by writeMutex and
+	// chunks by mutex
+	chunkSize int
+	chunks    *ChunkAssembler
+
+	// Adaptive ping state (see ping.go)
+	ping pingState
+
+	// Bandwidth accounting and cap (see bandwidth.go)
+	sentBandwidth       bandwidthMeter
+	receivedBandwidth   bandwidthMeter
+	bandwidthLimit      atomic.Int64
+	bandwidthDisconnect atomic.Bool
+
+	// Incoming message rate limit (see ratelimit.go), nil when disabled
+	messageLimiter *TokenBucket
+	rateLimited    atomic.Bool
 }
 
 // Channel represents a communication channel
 type Channel struct {
-	Name        string             `json:"name"`
-	Clients     map[string]*Client `json:"-"`
-	IsPrivate   bool               `json:"is_private"`
-	RequireAuth bool               `json:"require_auth"`
-	CreatedAt   time.Time          `json:"created_at"`
-	mutex       sync.RWMutex       `json:"-"`
+	Name         string                 `json:"name"`
+	Clients      map[string]*Client     `json:"-"`
+	IsPrivate    bool                   `json:"is_private"`
+	RequireAuth  bool                   `json:"require_auth"`
+	CreatedAt    time.Time              `json:"created_at"`
+	AckMode      bool                   `json:"ack_mode"`
+	ReadOnly     bool                   `json:"read_only"`
+	MaxClients   int                    `json:"max_clients"`
+	ClientEvents bool                   `json:"client_events"`
+	sequence     uint64                 `json:"-"`
+	readCursors  map[string]*ReadCursor `json:"-"`
+	history      []Message              `json:"-"`
+	historyLimit int                    `json:"-"`
+	metadata     map[string]interface{} `json:"-"`
+	publishMutex sync.Mutex             `json:"-"`
+	mutex        sync.RWMutex           `json:"-"`
+
+	// BandwidthLimit caps the bytes per second fanned out to members (0 disables)
+	BandwidthLimit   int64 `json:"bandwidth_limit,omitempty"`
+	bandwidth        bandwidthMeter
+	bandwidthDropped atomic.Uint64
+
+	// Clock of the channel's server (see clock.go), nil for the system clock
+	keeper *Timekeeper
 }
 
-// Message represents a message to be sent
+// Message represents a message to be sent.
+// A non-empty CoalesceKey lets a newer message replace an older one with the same key (within the
+// same channel) that is still waiting in a client's queue, e.g. cursor positions or progress bars.
 type Message struct {
-	ID        string      `json:"id"`
-	Channel   string      `json:"channel"`
-	Private   *bool       `json:"private,omitempty"`
-	Event     string      `json:"event"`
-	Data      interface{} `json:"data"`
-	UserID    string      `json:"user_id,omitempty"`
-	Username  string      `json:"username,omitempty"`
-	Timestamp time.Time   `json:"timestamp"`
+	ID          string      `json:"id"`
+	Channel     string      `json:"channel"`
+	Private     *bool       `json:"private,omitempty"`
+	Event       string      `json:"event"`
+	Data        interface{} `json:"data"`
+	UserID      string      `json:"user_id,omitempty"`
+	Username    string      `json:"username,omitempty"`
+	Sequence    uint64      `json:"sequence,omitempty"`
+	CoalesceKey string      `json:"coalesce_key,omitempty"`
+	Timestamp   time.Time   `json:"timestamp"`
+	Priority    Priority    `json:"-"`
+	// Template marks Data as containing {{placeholders}} resolved per recipient (see RenderTemplate)
+	Template bool `json:"-"`
+	// BridgedFrom is the node that forwarded the message over a bridge; bridged messages are
+	// never forwarded again
+	BridgedFrom string `json:"-"`
+	// Origin is the node that first accepted the broadcast. It travels with the message ID when
+	// the cluster or a bridge relays the message, so copies reaching a node twice are dropped.
+	Origin string `json:"-"`
+	// RelayedFrom is the node that relayed the message over the cluster; relayed messages are
+	// delivered locally only
+	RelayedFrom string `json:"-"`
+	// ExcludeClientID is the connection that triggered the message, which doesn't get it back:
+	// a native client ID or a Pusher socket ID
+	ExcludeClientID string `json:"-"`
+	// Blob is the binary payload of a blob envelope, written as binary frames after it
+	Blob []byte `json:"-"`
 }
 
-// SendMessage sends a message to the client
+// Priority selects the outbound lane a message is queued in
+type Priority int
+
+const (
+	// PriorityNormal is used for regular channel traffic
+	PriorityNormal Priority = iota
+	// PriorityHigh is used for control and system messages (kick, auth, errors, confirmations),
+	// which are written ahead of any queued normal traffic
+	PriorityHigh
+)
+
+// ReadCursor records how far a user has read in an ACK-mode channel
+type ReadCursor struct {
+	UserID    string    `json:"user_id"`
+	ReadUpTo  uint64    `json:"read_up_to"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SendMessage sends a message to the client. Once the writer has been started the message is
+// appended to the client's outbound queue for its priority and written by the writer goroutine.
+// Messages of the same priority are delivered in the order they were queued; high-priority
+// messages are written ahead of any pending normal traffic.
 func (c *Client) SendMessage(message Message) error {
-	start := time.Now()
+	if message.ExcludeClientID != "" && message.ExcludeClientID == c.ID {
+		return nil
+	}
 
-	// Track mutex wait time
-	mutexStart := time.Now()
+	c.mutex.RLock()
+	conn, send := c.Conn, c.send
+	if message.Template {
+		message.Data = RenderTemplate(message.Data, c.templateVars(message))
+		message.Template = false
+	}
+	if message.Priority == PriorityHigh {
+		send = c.control
+	}
+	if conn == nil {
+		c.mutex.RUnlock()
+		return ErrNilConnection
+	}
+
+	if send != nil {
+		// Enqueue while holding the read lock so Close cannot close the queue underneath us
+		defer c.mutex.RUnlock()
+
+		var err error
+		if message.CoalesceKey != "" {
+			err = c.enqueueCoalesced(send, message)
+		} else {
+			select {
+			case send <- message:
+			default:
+				err = ErrSendQueueFull
+			}
+		}
+		c.stats.recordEnqueue(len(c.send)+len(c.control), err)
+		return err
+	}
+	c.mutex.RUnlock()
+
+	if message.Blob != nil {
+		return c.writeBlob(conn, message)
+	}
+	return c.writeJSON(conn, message)
+}
+
+// enqueueCoalesced queues a message with a coalesce key. If a message with the same key is
+// still waiting to be written, it is replaced in place instead of queueing a second one.
+func (c *Client) enqueueCoalesced(send chan Message, message Message) error {
+	c.coalesceMutex.Lock()
+	defer c.coalesceMutex.Unlock()
+
+	if c.coalesced == nil {
+		c.coalesced = make(map[string]Message)
+	}
+
+	key := coalesceMapKey(message)
+	if _, pending := c.coalesced[key]; pending {
+		c.coalesced[key] = message
+		return nil
+	}
+
+	select {
+	case send <- message:
+		c.coalesced[key] = message
+		return nil
+	default:
+		return ErrSendQueueFull
+	}
+}
+
+// takeCoalesced returns the latest message for a queued coalesce slot. It reports false when
+// the slot has already been written.
+func (c *Client) takeCoalesced(message Message) (Message, bool) {
+	c.coalesceMutex.Lock()
+	defer c.coalesceMutex.Unlock()
+
+	key := coalesceMapKey(message)
+	latest, pending := c.coalesced[key]
+	if !pending {
+		return message, false
+	}
+	delete(c.coalesced, key)
+	return latest, true
+}
+
+// coalesceMapKey scopes a coalesce key to the message's channel
+func coalesceMapKey(message Message) string {
+	return message.Channel + "\x00" + message.CoalesceKey
+}
+
+// StartWriter creates the client's outbound queues and starts the goroutine that owns all
+// message writes to the connection
+func (c *Client) StartWriter(queueSize int) {
 	c.mutex.Lock()
-	mutexTime := time.Since(mutexStart)
-	defer c.mutex.Unlock()
+	if c.send != nil || c.Conn == nil {
+		c.mutex.Unlock()
+		return
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultSendQueueSize
+	}
+	send := make(chan Message, queueSize)
+	control := make(chan Message, DefaultControlQueueSize)
+	c.send = send
+	c.control = control
+	c.mutex.Unlock()
 
-	if c.Conn == nil {
-		return ErrNilConnection
+	go c.writeLoop(send, control)
+}
+
+// SetWriteTimeout sets the deadline for writing a single frame to the connection. A client that
+// can't accept a frame in time is disconnected; zero restores DefaultWriteTimeout.
+func (c *Client) SetWriteTimeout(timeout time.Duration) {
+	c.writeTimeout.Store(int64(timeout))
+}
+
+// WriteTimeout returns the deadline for writing a single frame to the connection
+func (c *Client) WriteTimeout() time.Duration {
+	if timeout := time.Duration(c.writeTimeout.Load()); timeout > 0 {
+		return timeout
+	}
+	return DefaultWriteTimeout
+}
+
+// writeLoop writes queued messages to the connection until both queues are closed,
+// always draining the control lane before taking the next normal message
+func (c *Client) writeLoop(send, control chan Message) {
+	defer c.recoverWriter()
+
+	for send != nil || control != nil {
+		var message Message
+		var ok bool
+
+		select {
+		case message, ok = <-control:
+			if !ok {
+				control = nil
+				continue
+			}
+		default:
+			select {
+			case message, ok = <-control:
+				if !ok {
+					control = nil
+					continue
+				}
+			case message, ok = <-send:
+				if !ok {
+					send = nil
+					continue
+				}
+			}
+		}
+
+		if message.CoalesceKey != "" {
+			if message, ok = c.takeCoalesced(message); !ok {
+				continue
+			}
+		}
+
+		c.mutex.RLock()
+		conn := c.Conn
+		c.mutex.RUnlock()
+
+		if conn == nil {
+			continue // drain remaining messages after Close
+		}
+
+		if err := c.writeQueued(conn, message, control); err != nil {
+			if err == ErrBandwidthExceeded {
+				c.SetDisconnectReason(DisconnectReasonBandwidthExceeded)
+			}
+			// A failed write leaves the connection unusable; closing it makes the
+			// read loop exit and run the normal disconnect cleanup
+			c.Close()
+		}
 	}
 
-	// Set a very short write deadline for local environment (500ms)
-	deadlineStart := time.Now()
-	c.Conn.SetWriteDeadline(time.Now().Add(500 * time.Millisecond))
-	deadlineTime := time.Since(deadlineStart)
+	// The queues are only closed when the client is going away
+	c.Close()
+}
+
+// writeJSON serializes writes to the connection, which supports only one concurrent writer
+func (c *Client) writeJSON(conn *websocket.Conn, message Message) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
 
-	// Track actual write time
-	writeStart := time.Now()
-	err := c.Conn.WriteJSON(message)
-	writeTime := time.Since(writeStart)
+	data, err := c.encode(message)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	return c.writeFrame(conn, websocket.TextMessage, data)
+}
 
-	totalTime := time.Since(start)
+// writeFrame writes a text frame (an encoded message) or a binary frame to the connection; the
+// caller must hold writeMutex
+func (c *Client) writeFrame(conn *websocket.Conn, messageType int, data []byte) error {
+	if err := c.waitForBandwidth(len(data)); err != nil {
+		return err
+	}
 
-	// Log all operations that take more than 50ms in local environment
-	if totalTime > 50*time.Millisecond {
-		// Log detailed timing breakdown for slow operations
-		// This will help identify if it's mutex contention, network, or JSON serialization
-		_ = mutexTime    // Mutex wait time
-		_ = deadlineTime // Deadline setting time
-		_ = writeTime    // Actual write time
-		_ = totalTime    // Total time
+	conn.EnableWriteCompression(!c.compressionDisabled && len(data) >= c.compressionMinSize)
+	timeout := c.WriteTimeout()
+	start := time.Now()
+	conn.SetWriteDeadline(start.Add(timeout))
+	if err := conn.WriteMessage(messageType, data); err != nil {
+		c.stats.recordError(err)
+		return err
+	}
+	// A write that nearly timed out means the client's TCP window is full; a write that times
+	// out closes the connection, so slow writes are the warning sign support can act on
+	if time.Since(start) > timeout/2 {
+		c.stats.slowWrites.Add(1)
 	}
+	c.stats.recordSent(len(data))
+	if c.wireTap != nil {
+		c.wireTap(c, WireOutbound, messageType, data)
+	}
+	return nil
+}
 
-	return err
+// QueueLength returns the number of messages waiting in the outbound queues
+func (c *Client) QueueLength() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.send) + len(c.control)
 }
 
-// SafeReadJSON safely reads a JSON message from the client connection
+// SafeReadJSON safely reads a JSON message from the client connection.
+// Reads do not hold the client mutex, so writes are never blocked by a pending read.
 func (c *Client) SafeReadJSON(v interface{}) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	data, err := c.SafeReadFrame()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
 
-	if c.Conn == nil {
-		return ErrNilConnection
+// SafeReadFrame safely reads the payload of the next frame from the client connection
+func (c *Client) SafeReadFrame() ([]byte, error) {
+	_, data, err := c.SafeReadMessage()
+	return data, err
+}
+
+// SafeReadMessage safely reads the next frame from the client connection, returning its type
+// (websocket.TextMessage or websocket.BinaryMessage) and payload
+func (c *Client) SafeReadMessage() (int, []byte, error) {
+	c.mutex.RLock()
+	conn := c.Conn
+	c.mutex.RUnlock()
+
+	if conn == nil {
+		return 0, nil, ErrNilConnection
 	}
 
-	return c.Conn.ReadJSON(v)
+	opcode, data, err := conn.ReadMessage()
+	if err != nil {
+		return 0, nil, err
+	}
+	if c.wireTap != nil {
+		c.wireTap(c, WireInbound, opcode, data)
+	}
+	c.stats.recordReceived(len(data))
+	c.receivedBandwidth.add(int64(len(data)))
+	return opcode, data, nil
 }
 
 // SafeSetReadDeadline safely sets the read deadline on the client connection
 func (c *Client) SafeSetReadDeadline(t time.Time) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	c.mutex.RLock()
+	conn := c.Conn
+	c.mutex.RUnlock()
 
-	if c.Conn == nil {
+	if conn == nil {
 		return ErrNilConnection
 	}
 
-	return c.Conn.SetReadDeadline(t)
+	return conn.SetReadDeadline(t)
 }
 
 // AddToChannel adds the client to a channel
@@ -146,9 +527,15 @@ func (c *Client) AddToChannel(channelName string) {
 
 // AddToChannelWithMetadata adds the client to a channel with metadata
 func (c *Client) AddToChannelWithMetadata(channelName string, data interface{}) {
+	c.AddToChannelWithEvents(channelName, data, nil)
+}
+
+// AddToChannelWithEvents adds the client to a channel with metadata, subscribed to the given
+// events of the channel only (nil for every event)
+func (c *Client) AddToChannelWithEvents(channelName string, data interface{}, events []string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	
+
 	// Safety check - ensure maps are initialized
 	if c.Channels == nil {
 		c.Channels = make(map[string]bool)
@@ -156,14 +543,22 @@ func (c *Client) AddToChannelWithMetadata(channelName string, data interface{})
 	if c.ChannelMetadata == nil {
 		c.ChannelMetadata = make(map[string]*ChannelMetadata)
 	}
-	
+
 	c.Channels[channelName] = true
 	c.ChannelMetadata[channelName] = &ChannelMetadata{
 		Data:     data,
-		JoinedAt: time.Now(),
+		JoinedAt: c.keeper.Now(),
+		Events:   events,
 	}
 }
 
+// WantsEvent reports whether the client asked for an event of a channel it joined
+func (c *Client) WantsEvent(channelName, event string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.ChannelMetadata[channelName].WantsEvent(event)
+}
+
 // RemoveFromChannel removes the client from a channel
 func (c *Client) RemoveFromChannel(channelName string) {
 	c.mutex.Lock()
@@ -215,7 +610,14 @@ func (c *Client) SetUserInfo(userID, username, email string) {
 	c.Email = email
 }
 
-// Close safely closes the client connection
+// SetObserver makes the client a read-only observer
+func (c *Client) SetObserver() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Observer = true
+}
+
+// Close safely closes the client connection and its outbound queue
 func (c *Client) Close() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -223,6 +625,49 @@ func (c *Client) Close() {
 		c.Conn.Close()
 		c.Conn = nil
 	}
+	c.closeQueues()
+}
+
+// closeQueues closes the outbound queues; the caller must hold c.mutex
+func (c *Client) closeQueues() {
+	if c.send != nil {
+		close(c.send)
+		c.send = nil
+	}
+	if c.control != nil {
+		close(c.control)
+		c.control = nil
+	}
+}
+
+// SetDisconnectReason records why the connection is ending. The first reason recorded wins,
+// so a kick is not overwritten by the read error it causes.
+func (c *Client) SetDisconnectReason(reason string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.disconnectReason == "" {
+		c.disconnectReason = reason
+	}
+}
+
+// GetDisconnectReason returns the recorded disconnect reason
+func (c *Client) GetDisconnectReason() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.disconnectReason
+}
+
+// CloseAfterFlush closes the outbound queues and lets the writer deliver the messages already
+// queued (e.g. a "kicked" notice) before the connection is closed
+func (c *Client) CloseAfterFlush() {
+	c.mutex.Lock()
+	if c.send == nil {
+		c.mutex.Unlock()
+		c.Close()
+		return
+	}
+	c.closeQueues()
+	c.mutex.Unlock()
 }
 
 // IsConnected safely checks if the client connection is still valid
@@ -234,17 +679,28 @@ func (c *Client) IsConnected() bool {
 
 // SendPing sends a ping message to the client
 func (c *Client) SendPing() error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	c.mutex.RLock()
+	conn := c.Conn
+	c.mutex.RUnlock()
 
-	if c.Conn == nil {
+	if conn == nil {
 		return ErrNilConnection
 	}
 
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
 	// Set write deadline for ping (same as SendMessage)
-	c.Conn.SetWriteDeadline(time.Now().Add(500 * time.Millisecond))
+	conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout()))
 
-	return c.Conn.WriteMessage(websocket.PingMessage, nil)
+	if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+		return err
+	}
+	if c.wireTap != nil {
+		c.wireTap(c, WireOutbound, websocket.PingMessage, nil)
+	}
+	c.recordPing()
+	return nil
 }
 
 // AddClient adds a client to the channel
@@ -254,6 +710,27 @@ func (ch *Channel) AddClient(client *Client) {
 	ch.Clients[client.ID] = client
 }
 
+// TryAddClient adds a client to the channel unless it is at capacity
+func (ch *Channel) TryAddClient(client *Client) error {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if _, member := ch.Clients[client.ID]; !member && ch.MaxClients > 0 && len(ch.Clients) >= ch.MaxClients {
+		return ErrChannelFull
+	}
+	ch.Clients[client.ID] = client
+	return nil
+}
+
+// IsFull reports whether a client that is not yet a member would be refused for capacity
+func (ch *Channel) IsFull(clientID string) bool {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	_, member := ch.Clients[clientID]
+	return !member && ch.MaxClients > 0 && len(ch.Clients) >= ch.MaxClients
+}
+
 // RemoveClient removes a client from the channel
 func (ch *Channel) RemoveClient(clientID string) {
 	ch.mutex.Lock()
@@ -279,3 +756,258 @@ func (ch *Channel) GetClientCount() int {
 	defer ch.mutex.RUnlock()
 	return len(ch.Clients)
 }
+
+// SetHistoryLimit sets how many recent messages the channel retains (0 disables history)
+func (ch *Channel) SetHistoryLimit(limit int) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ch.historyLimit = limit
+	if limit <= 0 {
+		ch.history = nil
+	} else if len(ch.history) > limit {
+		ch.history = ch.history[len(ch.history)-limit:]
+	}
+}
+
+// AddToHistory records a message in the channel history if history is enabled
+func (ch *Channel) AddToHistory(message Message) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if ch.historyLimit <= 0 {
+		return
+	}
+
+	ch.history = append(ch.history, message)
+	if len(ch.history) > ch.historyLimit {
+		ch.history = ch.history[len(ch.history)-ch.historyLimit:]
+	}
+}
+
+// ExpireHistory drops retained messages older than cutoff and returns them
+func (ch *Channel) ExpireHistory(cutoff time.Time) []Message {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	expired := 0
+	for expired < len(ch.history) && ch.history[expired].Timestamp.Before(cutoff) {
+		expired++
+	}
+	if expired == 0 {
+		return nil
+	}
+
+	removed := ch.history[:expired]
+	ch.history = append([]Message(nil), ch.history[expired:]...)
+	return removed
+}
+
+// GetHistory returns a copy of the channel's retained messages, oldest first
+func (ch *Channel) GetHistory() []Message {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	history := make([]Message, len(ch.history))
+	copy(history, ch.history)
+	return history
+}
+
+// HistorySince returns the retained messages published after the message with the given ID, at
+// most the limit most recent ones (0 for all). With an empty ID, or one no longer retained, it
+// returns the whole history and reports false, since messages may have been missed.
+func (ch *Channel) HistorySince(messageID string, limit int) ([]Message, bool) {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	start, found := 0, false
+	if messageID != "" {
+		for i, message := range ch.history {
+			if message.ID == messageID {
+				start, found = i+1, true
+				break
+			}
+		}
+	}
+
+	if limit > 0 && len(ch.history)-start > limit {
+		start = len(ch.history) - limit
+	}
+	history := make([]Message, len(ch.history)-start)
+	copy(history, ch.history[start:])
+	return history, found
+}
+
+// WithPublishLock runs fn while holding the channel's publish lock. Broadcasts to the same
+// channel are serialized through it, so every subscriber receives them in publish order.
+func (ch *Channel) WithPublishLock(fn func()) {
+	ch.publishMutex.Lock()
+	defer ch.publishMutex.Unlock()
+	fn()
+}
+
+// NextSequence stamps and returns the next message sequence number for the channel. Sequences
+// increase by one per broadcast, so clients can order messages without trusting timestamps.
+func (ch *Channel) NextSequence() uint64 {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ch.sequence++
+	return ch.sequence
+}
+
+// RestoreSequence moves the channel's sequence up to at least sequence, e.g. after restoring
+// history from a snapshot, so new messages keep sorting after the restored ones
+func (ch *Channel) RestoreSequence(sequence uint64) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ch.sequence = max(ch.sequence, sequence)
+}
+
+// CurrentSequence returns the sequence number of the last message broadcast on the channel
+func (ch *Channel) CurrentSequence() uint64 {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	return ch.sequence
+}
+
+// UpdateReadCursor advances a user's read cursor. Cursors never move backwards and are
+// clamped to the channel's current sequence. It returns the resulting cursor and whether it moved.
+func (ch *Channel) UpdateReadCursor(userID string, readUpTo uint64) (ReadCursor, bool) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if ch.readCursors == nil {
+		ch.readCursors = make(map[string]*ReadCursor)
+	}
+
+	if readUpTo > ch.sequence {
+		readUpTo = ch.sequence
+	}
+
+	cursor, exists := ch.readCursors[userID]
+	if !exists {
+		cursor = &ReadCursor{UserID: userID}
+		ch.readCursors[userID] = cursor
+	} else if readUpTo <= cursor.ReadUpTo {
+		return *cursor, false
+	}
+
+	cursor.ReadUpTo = readUpTo
+	cursor.UpdatedAt = ch.keeper.Now()
+	return *cursor, true
+}
+
+// GetReadCursors returns a copy of all users' read cursors for the channel
+func (ch *Channel) GetReadCursors() map[string]ReadCursor {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	cursors := make(map[string]ReadCursor)
+	for userID, cursor := range ch.readCursors {
+		cursors[userID] = *cursor
+	}
+	return cursors
+}
+
+// SetMetadata merges updates into the channel's metadata; a nil value removes the key.
+// It returns the keys whose value actually changed, sorted.
+func (ch *Channel) SetMetadata(updates map[string]interface{}) []string {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if ch.metadata == nil {
+		ch.metadata = make(map[string]interface{})
+	}
+
+	changed := make([]string, 0, len(updates))
+	for key, value := range updates {
+		current, exists := ch.metadata[key]
+		if value == nil {
+			if exists {
+				delete(ch.metadata, key)
+				changed = append(changed, key)
+			}
+			continue
+		}
+		if exists && reflect.DeepEqual(current, value) {
+			continue
+		}
+		ch.metadata[key] = value
+		changed = append(changed, key)
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+// GetMetadata returns a copy of the channel's metadata
+func (ch *Channel) GetMetadata() map[string]interface{} {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	metadata := make(map[string]interface{}, len(ch.metadata))
+	for key, value := range ch.metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// ChannelSettings holds changes to a channel's access flags and capacity; nil fields are unchanged
+type ChannelSettings struct {
+	IsPrivate   *bool `json:"is_private,omitempty"`
+	RequireAuth *bool `json:"require_auth,omitempty"`
+	ReadOnly    *bool `json:"read_only,omitempty"`
+	MaxClients  *int  `json:"max_clients,omitempty"`
+	// ClientEvents lets members relay client- events to each other
+	ClientEvents *bool `json:"client_events,omitempty"`
+}
+
+// ApplySettings updates the channel's flags and capacity, returning the names of the settings
+// that actually changed
+func (ch *Channel) ApplySettings(settings ChannelSettings) []string {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	changed := make([]string, 0, 5)
+	if settings.IsPrivate != nil && *settings.IsPrivate != ch.IsPrivate {
+		ch.IsPrivate = *settings.IsPrivate
+		changed = append(changed, "is_private")
+	}
+	if settings.RequireAuth != nil && *settings.RequireAuth != ch.RequireAuth {
+		ch.RequireAuth = *settings.RequireAuth
+		changed = append(changed, "require_auth")
+	}
+	if settings.ReadOnly != nil && *settings.ReadOnly != ch.ReadOnly {
+		ch.ReadOnly = *settings.ReadOnly
+		changed = append(changed, "read_only")
+	}
+	if settings.MaxClients != nil && *settings.MaxClients != ch.MaxClients {
+		ch.MaxClients = *settings.MaxClients
+		changed = append(changed, "max_clients")
+	}
+	if settings.ClientEvents != nil && *settings.ClientEvents != ch.ClientEvents {
+		ch.ClientEvents = *settings.ClientEvents
+		changed = append(changed, "client_events")
+	}
+	return changed
+}
+
+// AccessFlags returns whether the channel is private and whether it requires authentication
+func (ch *Channel) AccessFlags() (bool, bool) {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	return ch.IsPrivate, ch.RequireAuth
+}
+
+// GetSettings returns the channel's current flags and capacity
+func (ch *Channel) GetSettings() map[string]interface{} {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"is_private":    ch.IsPrivate,
+		"require_auth":  ch.RequireAuth,
+		"read_only":     ch.ReadOnly,
+		"max_clients":   ch.MaxClients,
+		"client_events": ch.ClientEvents,
+	}
+}
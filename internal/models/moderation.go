@@ -0,0 +1,90 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// Actions recorded in a channel's moderation log
+const (
+	// ModerationMessageRejected is a message of the user refused by a channel rule
+	ModerationMessageRejected = "message_rejected"
+	// ModerationRemoved is a member taken out of the channel by a kick or a ban
+	ModerationRemoved = "removed"
+)
+
+// ModerationEntry is an action taken against a user's content or membership in a channel. Rule
+// names what decided it: read_only, client_events_disabled, reserved_event, schema, kicked or
+// banned.
+type ModerationEntry struct {
+	ID       string    `json:"id"`
+	Channel  string    `json:"channel"`
+	Action   string    `json:"action"`
+	Rule     string    `json:"rule"`
+	ClientID string    `json:"client_id,omitempty"`
+	UserID   string    `json:"user_id,omitempty"`
+	Event    string    `json:"event,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// ModerationLog keeps the latest moderation entries of each channel, at most size of them per
+// channel; the oldest are dropped first. A nil log records nothing.
+type ModerationLog struct {
+	Timekeeper // of entries recorded without an ID or time
+
+	size     int
+	channels map[string][]ModerationEntry // channel -> entries, oldest first
+	mutex    sync.Mutex
+}
+
+// NewModerationLog creates a log keeping size entries per channel, or nil when size is 0
+func NewModerationLog(size int) *ModerationLog {
+	if size <= 0 {
+		return nil
+	}
+	return &ModerationLog{size: size, channels: make(map[string][]ModerationEntry)}
+}
+
+// Record adds an entry to the log of its channel, filling in its ID and time when missing
+func (l *ModerationLog) Record(entry ModerationEntry) {
+	if l == nil {
+		return
+	}
+	if entry.ID == "" {
+		entry.ID = l.NewID()
+	}
+	if entry.At.IsZero() {
+		entry.At = l.Now()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := append(l.channels[entry.Channel], entry)
+	if len(entries) > l.size {
+		entries = append([]ModerationEntry(nil), entries[len(entries)-l.size:]...)
+	}
+	l.channels[entry.Channel] = entries
+}
+
+// Entries returns the entries of a channel, newest first. A positive limit keeps the most
+// recent ones.
+func (l *ModerationLog) Entries(channel string, limit int) []ModerationEntry {
+	if l == nil {
+		return []ModerationEntry{}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := l.channels[channel]
+	if limit <= 0 || limit > len(entries) {
+		limit = len(entries)
+	}
+	newest := make([]ModerationEntry, 0, limit)
+	for i := len(entries) - 1; len(newest) < limit; i-- {
+		newest = append(newest, entries[i])
+	}
+	return newest
+}
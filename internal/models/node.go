@@ -0,0 +1,53 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// NodeStatus is the load a node of a cluster advertises to its peers, so they can suggest the
+// least loaded nodes to clients that should reconnect elsewhere
+type NodeStatus struct {
+	NodeID         string    `json:"node_id"`
+	Endpoint       string    `json:"endpoint,omitempty"` // WebSocket URL reaching the node directly
+	Connections    int       `json:"connections"`
+	MaxConnections int       `json:"max_connections"` // 0 for unlimited
+	Pressure       bool      `json:"pressure"`        // near its connection limit
+	Draining       bool      `json:"draining"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Load returns the share of its connection limit a node uses, 0 when unlimited
+func (n NodeStatus) Load() float64 {
+	if n.MaxConnections <= 0 {
+		return 0
+	}
+	return float64(n.Connections) / float64(n.MaxConnections)
+}
+
+// SuggestEndpoints returns the endpoints of at most limit nodes that can take more clients,
+// least loaded first. Nodes that are under pressure, draining, without an endpoint or that
+// haven't advertised their status since maxAge are left out.
+func SuggestEndpoints(nodes []NodeStatus, now time.Time, maxAge time.Duration, limit int) []string {
+	candidates := make([]NodeStatus, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Endpoint != "" && !node.Pressure && !node.Draining && now.Sub(node.UpdatedAt) <= maxAge {
+			candidates = append(candidates, node)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Load() != candidates[j].Load() {
+			return candidates[i].Load() < candidates[j].Load()
+		}
+		if candidates[i].Connections != candidates[j].Connections {
+			return candidates[i].Connections < candidates[j].Connections
+		}
+		return candidates[i].NodeID < candidates[j].NodeID
+	})
+
+	endpoints := make([]string, 0, min(len(candidates), limit))
+	for _, node := range candidates[:min(len(candidates), limit)] {
+		endpoints = append(endpoints, node.Endpoint)
+	}
+	return endpoints
+}
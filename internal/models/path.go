@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pathPlaceholder matches {{dotted.path}} placeholders in ingest mapping templates
+var pathPlaceholder = regexp.MustCompile(`\{\{\s*([\w.\-]+)\s*\}\}`)
+
+// LookupPath returns the value at a dotted path such as "data.object.id" in decoded JSON.
+// Numeric segments index into arrays. An empty path returns data itself.
+func LookupPath(data interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+
+	current := data
+	for _, segment := range strings.Split(path, ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, exists := value[segment]
+			if !exists {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			current = value[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// RenderPathTemplate replaces {{path}} placeholders in template with the values returned by
+// lookup. It fails if a placeholder can't be resolved, so a mapping never produces a channel
+// name with a hole in it.
+func RenderPathTemplate(template string, lookup func(path string) (interface{}, bool)) (string, bool) {
+	resolved := true
+	rendered := pathPlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		value, ok := lookup(pathPlaceholder.FindStringSubmatch(match)[1])
+		if !ok || value == nil {
+			resolved = false
+			return ""
+		}
+		return formatPathValue(value)
+	})
+	return rendered, resolved
+}
+
+// formatPathValue formats a JSON value for use in a template; whole numbers are written without
+// a decimal point
+func formatPathValue(value interface{}) string {
+	if number, ok := value.(float64); ok && number == float64(int64(number)) {
+		return strconv.FormatInt(int64(number), 10)
+	}
+	return fmt.Sprint(value)
+}
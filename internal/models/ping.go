@@ -0,0 +1,124 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// stablePongRTT is the round trip under which a pong counts as healthy and relaxes the interval
+const stablePongRTT = time.Second
+
+// DefaultPongTimeout is added to the read timeout on top of two ping intervals
+const DefaultPongTimeout = 10 * time.Second
+
+// PingPolicy bounds a connection's adaptive ping interval. Connections start at Interval; each
+// quick pong relaxes the interval by 25% up to MaxInterval, and each missed pong halves it down
+// to MinInterval. Equal bounds keep a fixed interval.
+//
+// A connection silent for two ping intervals plus PongTimeout (0 uses DefaultPongTimeout) is
+// considered dead, or for ReadTimeout when it is set.
+type PingPolicy struct {
+	Interval    time.Duration
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	PongTimeout time.Duration
+	ReadTimeout time.Duration
+}
+
+// DefaultPingPolicy pings every 30 seconds without adapting
+var DefaultPingPolicy = PingPolicy{Interval: 30 * time.Second, MinInterval: 30 * time.Second, MaxInterval: 30 * time.Second}
+
+// pingState tracks ping round trips for a connection
+type pingState struct {
+	policy      PingPolicy
+	interval    time.Duration
+	sentAt      time.Time
+	awaiting    bool
+	rtt         time.Duration
+	missedPongs uint64
+	mutex       sync.Mutex
+}
+
+// SetPingPolicy sets the bounds of the client's ping interval and restarts it at policy.Interval
+func (c *Client) SetPingPolicy(policy PingPolicy) {
+	c.ping.mutex.Lock()
+	defer c.ping.mutex.Unlock()
+	c.ping.policy = policy
+	c.ping.interval = policy.Interval
+}
+
+// PingInterval returns how long to wait before the next ping
+func (c *Client) PingInterval() time.Duration {
+	c.ping.mutex.Lock()
+	defer c.ping.mutex.Unlock()
+	return c.ping.interval
+}
+
+// ReadTimeout returns how long the connection may stay silent (no message or pong) before it
+// is considered dead: two ping intervals plus the pong timeout, unless the policy fixes it
+func (c *Client) ReadTimeout() time.Duration {
+	c.ping.mutex.Lock()
+	defer c.ping.mutex.Unlock()
+	return c.ping.policy.readTimeout(c.ping.interval)
+}
+
+// readTimeout returns the read timeout of a connection pinged at the given interval
+func (p PingPolicy) readTimeout(interval time.Duration) time.Duration {
+	if p.ReadTimeout > 0 {
+		return p.ReadTimeout
+	}
+	pongTimeout := p.PongTimeout
+	if pongTimeout <= 0 {
+		pongTimeout = DefaultPongTimeout
+	}
+	return 2*interval + pongTimeout
+}
+
+// InitialReadTimeout returns the read timeout of a connection before its ping interval adapts
+func (p PingPolicy) InitialReadTimeout() time.Duration {
+	return p.readTimeout(p.Interval)
+}
+
+// recordPing notes a ping written to the connection. If the previous ping is still unanswered
+// the pong is counted as missed and the interval is tightened to detect a dead link sooner.
+func (c *Client) recordPing() {
+	c.ping.mutex.Lock()
+	defer c.ping.mutex.Unlock()
+
+	if c.ping.awaiting {
+		c.ping.missedPongs++
+		c.ping.interval = max(c.ping.interval/2, c.ping.policy.MinInterval)
+	}
+	c.ping.awaiting = true
+	c.ping.sentAt = time.Now()
+}
+
+// RecordPong notes a pong from the client, updating the smoothed round trip time and relaxing
+// the interval when the connection answers quickly
+func (c *Client) RecordPong() {
+	c.ping.mutex.Lock()
+	defer c.ping.mutex.Unlock()
+
+	if !c.ping.awaiting {
+		return
+	}
+	c.ping.awaiting = false
+
+	rtt := time.Since(c.ping.sentAt)
+	if c.ping.rtt == 0 {
+		c.ping.rtt = rtt
+	} else {
+		c.ping.rtt = (4*c.ping.rtt + rtt) / 5
+	}
+
+	if rtt < stablePongRTT {
+		c.ping.interval = min(c.ping.interval*5/4, c.ping.policy.MaxInterval)
+	}
+}
+
+// pingStats returns the current ping interval, smoothed round trip time and missed pong count
+func (c *Client) pingStats() (interval, rtt time.Duration, missedPongs uint64) {
+	c.ping.mutex.Lock()
+	defer c.ping.mutex.Unlock()
+	return c.ping.interval, c.ping.rtt, c.ping.missedPongs
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ProtocolSocketIO is the Protocol of clients connected through the Socket.IO adapter. Native
+// clients have an empty Protocol; see also ProtocolPusher.
+const ProtocolSocketIO = "socket.io"
+
+// MessageEncoder serializes an outbound message into the payload of a text frame, for clients
+// speaking another wire protocol than the native JSON one. A nil payload skips the message.
+type MessageEncoder func(message Message) ([]byte, error)
+
+// SetEncoder sets how the client's messages are serialized. Call it before StartWriter.
+func (c *Client) SetEncoder(encoder MessageEncoder) {
+	c.encoder = encoder
+}
+
+// encode serializes a message with the client's encoder, or as JSON
+func (c *Client) encode(message Message) ([]byte, error) {
+	if c.encoder != nil {
+		return c.encoder(message)
+	}
+	return json.Marshal(message)
+}
+
+// WriteText writes a protocol frame, e.g. a handshake packet, to the connection directly,
+// bypassing the outbound queues
+func (c *Client) WriteText(data []byte) error {
+	c.mutex.RLock()
+	conn := c.Conn
+	c.mutex.RUnlock()
+
+	if conn == nil {
+		return ErrNilConnection
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout()))
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		c.stats.recordError(err)
+		return err
+	}
+	c.stats.recordSent(len(data))
+	if c.wireTap != nil {
+		c.wireTap(c, WireOutbound, websocket.TextMessage, data)
+	}
+	return nil
+}
+
+// SendTextPing writes an application-level ping frame, for protocols with their own heartbeat
+// such as Engine.IO. The answer is recorded with RecordPong like a WebSocket pong.
+func (c *Client) SendTextPing(data []byte) error {
+	if err := c.WriteText(data); err != nil {
+		return err
+	}
+	c.recordPing()
+	return nil
+}
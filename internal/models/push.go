@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Push providers a device token can belong to
+const (
+	PushProviderFCM  = "fcm"  // Firebase Cloud Messaging (Android, web and iOS via Firebase)
+	PushProviderAPNs = "apns" // Apple Push Notification service
+)
+
+// PushDevice is a mobile device registered to receive push notifications for a user
+type PushDevice struct {
+	Provider     string    `json:"provider"`
+	Token        string    `json:"token"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// Validate checks the device has a known provider and a token
+func (d PushDevice) Validate() error {
+	if d.Provider != PushProviderFCM && d.Provider != PushProviderAPNs {
+		return ErrUnknownPushProvider
+	}
+	if d.Token == "" {
+		return ErrInvalidPushDevice
+	}
+	return nil
+}
+
+// PushNotification is the visible part of a push sent to users without an active connection.
+// The broadcast's event and data are delivered alongside it.
+type PushNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// PushResult reports the outcome of a push fallback in the broadcast response
+type PushResult struct {
+	Attempted int `json:"attempted"`
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	// Removed counts devices dropped because their provider reported the token as invalid
+	Removed int      `json:"removed,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+	// Skipped explains why no push was attempted
+	Skipped string `json:"skipped,omitempty"`
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProtocolPusher is the Protocol of clients connected through the Pusher adapter
+const ProtocolPusher = "pusher"
+
+// Pusher protocol (version 7) events
+const (
+	PusherConnectionEstablished = "pusher:connection_established"
+	PusherError                 = "pusher:error"
+	PusherPing                  = "pusher:ping"
+	PusherPong                  = "pusher:pong"
+	PusherSubscribe             = "pusher:subscribe"
+	PusherUnsubscribe           = "pusher:unsubscribe"
+	PusherSignin                = "pusher:signin"
+	PusherSigninSuccess         = "pusher:signin_success"
+	PusherSubscriptionError     = "pusher:subscription_error"
+	PusherSubscriptionSucceeded = "pusher_internal:subscription_succeeded"
+	PusherMemberAdded           = "pusher_internal:member_added"
+	PusherMemberRemoved         = "pusher_internal:member_removed"
+)
+
+// Pusher channel name prefixes; private and presence channels need a signature to subscribe
+const (
+	PusherPrivatePrefix  = "private-"
+	PusherPresencePrefix = "presence-"
+)
+
+// PusherEvent is a frame of a Pusher connection. Data is a JSON-encoded string in frames sent
+// by the server; clients may send it as an object.
+type PusherEvent struct {
+	Event   string          `json:"event"`
+	Channel string          `json:"channel,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	UserID  string          `json:"user_id,omitempty"`
+}
+
+// PusherMember is a presence channel member, the channel_data of a presence subscription
+type PusherMember struct {
+	UserID   string          `json:"user_id"`
+	UserInfo json.RawMessage `json:"user_info,omitempty"`
+}
+
+// UnmarshalJSON accepts numeric user IDs, which Laravel sends for integer keys
+func (m *PusherMember) UnmarshalJSON(data []byte) error {
+	var member struct {
+		UserID   interface{}     `json:"user_id"`
+		UserInfo json.RawMessage `json:"user_info"`
+	}
+	if err := json.Unmarshal(data, &member); err != nil {
+		return err
+	}
+	m.UserID, m.UserInfo = pusherUserID(member.UserID), member.UserInfo
+	return nil
+}
+
+// PusherUser is the user_data of a pusher:signin
+type PusherUser struct {
+	ID       string
+	UserInfo map[string]interface{}
+}
+
+// UnmarshalJSON accepts numeric user IDs, which Laravel sends for integer keys
+func (u *PusherUser) UnmarshalJSON(data []byte) error {
+	var user struct {
+		ID       interface{}            `json:"id"`
+		UserInfo map[string]interface{} `json:"user_info"`
+	}
+	if err := json.Unmarshal(data, &user); err != nil {
+		return err
+	}
+	u.ID, u.UserInfo = pusherUserID(user.ID), user.UserInfo
+	return nil
+}
+
+// pusherUserID returns a user ID sent as a string or a number
+func pusherUserID(id interface{}) string {
+	switch id := id.(type) {
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	}
+	return ""
+}
+
+// PusherApp holds the credentials of the Pusher app the adapter emulates. Laravel signs channel
+// subscriptions and HTTP API requests with the same key and secret.
+type PusherApp struct {
+	ID     string
+	Key    string
+	Secret string
+}
+
+// Sign returns the hex HMAC-SHA256 of a payload with the app secret
+func (a PusherApp) Sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(a.Secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyChannelAuth checks the auth of a private or presence channel subscription, "key:signature"
+// where the signature covers the socket ID, channel name and, for presence channels, channel data
+func (a PusherApp) VerifyChannelAuth(auth, socketID, channel, channelData string) bool {
+	payload := socketID + ":" + channel
+	if strings.HasPrefix(channel, PusherPresencePrefix) {
+		payload += ":" + channelData
+	}
+	return a.verifyAuth(auth, payload)
+}
+
+// VerifyUserAuth checks the auth of a pusher:signin, which covers the socket ID and user data
+func (a PusherApp) VerifyUserAuth(auth, socketID, userData string) bool {
+	return a.verifyAuth(auth, socketID+"::user::"+userData)
+}
+
+// verifyAuth checks an auth string of the app's key and the payload's signature
+func (a PusherApp) verifyAuth(auth, payload string) bool {
+	key, signature, ok := strings.Cut(auth, ":")
+	if !ok || key != a.Key {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(a.Sign(payload)))
+}
+
+// RequestSignature returns the auth_signature of an HTTP API request: the signed string is the
+// method, path and query parameters sorted by name, without auth_signature
+func (a PusherApp) RequestSignature(method, path string, query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key != "auth_signature" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, strings.ToLower(key)+"="+query.Get(key))
+	}
+	return a.Sign(method + "\n" + path + "\n" + strings.Join(params, "&"))
+}
+
+// NewPusherSocketID returns a socket ID in Pusher's "digits.digits" format, which Laravel
+// validates before signing a subscription
+func NewPusherSocketID() string {
+	limit := big.NewInt(1_000_000_000)
+	a, _ := rand.Int(rand.Reader, limit)
+	b, _ := rand.Int(rand.Reader, limit)
+	return a.String() + "." + b.String()
+}
+
+// EncodePusherEvent builds a Pusher frame; data is sent as a JSON-encoded string, or as is when
+// it already is a string
+func EncodePusherEvent(event, channel string, data interface{}) ([]byte, error) {
+	return encodePusherFrame(event, channel, "", data)
+}
+
+// EncodePusherMessage builds the frame of a channel message. Client events name their sender,
+// like Pusher does on presence channels.
+func EncodePusherMessage(message Message) ([]byte, error) {
+	userID := ""
+	if IsClientEvent(message.Event) {
+		userID = message.UserID
+	}
+	return encodePusherFrame(message.Event, message.Channel, userID, message.Data)
+}
+
+func encodePusherFrame(event, channel, userID string, data interface{}) ([]byte, error) {
+	encoded, ok := data.(string)
+	if !ok {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(payload)
+	}
+	quoted, _ := json.Marshal(encoded)
+	return json.Marshal(PusherEvent{Event: event, Channel: channel, Data: quoted, UserID: userID})
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// DisconnectReasonRateLimited is recorded when a client is disconnected for sending messages
+// faster than its rate limit
+const DisconnectReasonRateLimited = "rate_limited"
+
+// TokenBucket is a token-bucket rate limiter: it allows bursts of up to burst events, refilled
+// at rate events per second
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// NewTokenBucket creates a full bucket. A burst below one allows a single event at a time.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	b := float64(max(burst, 1))
+	return &TokenBucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// Take takes a token if one is available. Otherwise it returns how long until the next token
+// is refilled.
+func (b *TokenBucket) Take() (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// SetMessageRateLimit limits the messages per second the client may send, with bursts of up to
+// burst messages (a rate of 0 disables the limit). Call it before reading from the client.
+func (c *Client) SetMessageRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		c.messageLimiter = nil
+		return
+	}
+	c.messageLimiter = NewTokenBucket(rate, burst)
+}
+
+// AllowMessage takes a token for a message received from the client. Otherwise it counts the
+// message as rate limited; newBurst is set for the first limited message after allowed ones,
+// so callers can log each burst once.
+func (c *Client) AllowMessage() (allowed bool, newBurst bool) {
+	if c.messageLimiter == nil {
+		return true, false
+	}
+	if allowed, _ = c.messageLimiter.Take(); allowed {
+		c.rateLimited.Store(false)
+		return true, false
+	}
+	c.stats.rateLimited.Add(1)
+	return false, !c.rateLimited.Swap(true)
+}
+
+// WaitMessage blocks until a message the rate limit turned down fits the client's rate, and
+// takes its token
+func (c *Client) WaitMessage() {
+	if c.messageLimiter == nil {
+		return
+	}
+	for {
+		allowed, wait := c.messageLimiter.Take()
+		if allowed {
+			return
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "runtime/debug"
+
+// DisconnectReasonInternalError is recorded when a panic in one of the client's goroutines
+// ends its connection
+const DisconnectReasonInternalError = "internal_error"
+
+// PanicHandler is told about a panic recovered in one of a client's goroutines, with the
+// goroutine's stack at the time of the panic
+type PanicHandler func(client *Client, goroutine string, recovered interface{}, stack []byte)
+
+// SetPanicHandler sets the handler told about panics in the client's writer goroutine. Call it
+// before StartWriter.
+func (c *Client) SetPanicHandler(handler PanicHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.panicHandler = handler
+}
+
+// recoverWriter stops a panic in the writer from taking the process down: the panic is
+// reported and only this client's connection is closed
+func (c *Client) recoverWriter() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	c.SetDisconnectReason(DisconnectReasonInternalError)
+	c.mutex.RLock()
+	handler := c.panicHandler
+	c.mutex.RUnlock()
+	if handler != nil {
+		handler(c, "writer", recovered, debug.Stack())
+	}
+	c.Close()
+}
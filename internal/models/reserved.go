@@ -0,0 +1,48 @@
+package models
+
+import "strings"
+
+// ReservedEventsVersion is bumped whenever the reserved event names change, and announced to
+// clients with the names, so SDKs can tell which events only the server emits
+const ReservedEventsVersion = 1
+
+// reservedEventPrefixes are the namespaces of server control events
+var reservedEventPrefixes = []string{"system.", "pusher:", "pusher_internal:"}
+
+// reservedEvents are the control events the server sends connections
+var reservedEvents = []string{
+	"authenticated",
+	"banned",
+	"channel_closed",
+	"connected",
+	"error",
+	"kicked",
+	"resumed",
+	"server_draining",
+	"session_replaced",
+}
+
+// IsReservedEvent reports whether an event name is a server control event, or in the namespace
+// of one, which clients may not emit to other connections
+func IsReservedEvent(event string) bool {
+	for _, name := range reservedEvents {
+		if event == name {
+			return true
+		}
+	}
+	for _, prefix := range reservedEventPrefixes {
+		if strings.HasPrefix(event, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReservedEventsInfo describes the reserved event names for the connected event
+func ReservedEventsInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"version":  ReservedEventsVersion,
+		"events":   append([]string(nil), reservedEvents...),
+		"prefixes": append([]string(nil), reservedEventPrefixes...),
+	}
+}
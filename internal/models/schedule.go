@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ScheduledBroadcast is a broadcast deferred until SendAt. Broadcast holds the body of a
+// POST /api/broadcast request, so every broadcast type can be scheduled.
+type ScheduledBroadcast struct {
+	ID        string          `json:"id"`
+	SendAt    time.Time       `json:"send_at"`
+	Broadcast json.RawMessage `json:"broadcast"`
+	Note      string          `json:"note,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Validate checks the broadcast is a JSON object due after now
+func (b *ScheduledBroadcast) Validate(now time.Time) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b.Broadcast, &fields); err != nil || fields == nil {
+		return ErrInvalidSchedule
+	}
+	if !b.SendAt.After(now) {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
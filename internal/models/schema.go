@@ -0,0 +1,272 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// EventSchema is the JSON Schema the data of broadcasts of an event must match
+type EventSchema struct {
+	Event     string          `json:"event"`
+	Schema    json.RawMessage `json:"schema"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	compiled *Schema
+}
+
+// Compile parses the schema, so Validate can be used
+func (e *EventSchema) Compile() error {
+	schema, err := ParseSchema(e.Schema)
+	if err != nil {
+		return err
+	}
+	e.compiled = schema
+	return nil
+}
+
+// Validate checks broadcast data against the compiled schema
+func (e *EventSchema) Validate(data interface{}) []SchemaError {
+	if e.compiled == nil {
+		return nil
+	}
+	return e.compiled.Validate(data)
+}
+
+// SchemaError is a field of broadcast data that doesn't match its schema. Field is a path such
+// as data.items[2].price.
+type SchemaError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Schema is the subset of JSON Schema the server enforces: type, enum and const, the properties,
+// required and additionalProperties of objects, the items, minItems and maxItems of arrays, the
+// minLength, maxLength and pattern of strings and the minimum and maximum of numbers. Other
+// keywords are refused rather than silently ignored, except annotations such as title and
+// description.
+type Schema struct {
+	Type                 schemaTypes        `json:"type,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Const                *interface{}       `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// schemaKeywords are the keywords a Schema accepts: the enforced ones and annotations
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true,
+	"additionalProperties": true, "items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true,
+}
+
+// schemaTypeNames are the JSON Schema types
+var schemaTypeNames = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// ParseSchema decodes a JSON Schema, refusing keywords the server doesn't enforce
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return &schema, nil
+}
+
+// UnmarshalJSON decodes a schema object, checking its keywords
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil || keywords == nil {
+		return fmt.Errorf("a schema must be an object")
+	}
+	for keyword := range keywords {
+		if !schemaKeywords[keyword] {
+			return fmt.Errorf("unsupported keyword %q", keyword)
+		}
+	}
+
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q", s.Pattern)
+		}
+		s.pattern = pattern
+	}
+	return nil
+}
+
+// schemaTypes is the type keyword, a type name or a list of them
+type schemaTypes []string
+
+// UnmarshalJSON accepts a type name or a list of them
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var names []string
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &names); err != nil {
+			return err
+		}
+	} else {
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return err
+		}
+		names = []string{name}
+	}
+	for _, name := range names {
+		if !schemaTypeNames[name] {
+			return fmt.Errorf("unknown type %q", name)
+		}
+	}
+	*t = names
+	return nil
+}
+
+// Validate checks a decoded JSON value, reporting every field that doesn't match
+func (s *Schema) Validate(value interface{}) []SchemaError {
+	var errs []SchemaError
+	s.validate("data", value, &errs)
+	return errs
+}
+
+func (s *Schema) validate(field string, value interface{}, errs *[]SchemaError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, SchemaError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		fail("must be %s, got %s", strings.Join(s.Type, " or "), jsonTypeName(value))
+		return
+	}
+	if s.Const != nil && !reflect.DeepEqual(value, *s.Const) {
+		fail("must be %s", jsonText(*s.Const))
+	}
+	if len(s.Enum) > 0 {
+		allowed := false
+		for _, option := range s.Enum {
+			allowed = allowed || reflect.DeepEqual(value, option)
+		}
+		if !allowed {
+			options := make([]string, len(s.Enum))
+			for i, option := range s.Enum {
+				options[i] = jsonText(option)
+			}
+			fail("must be one of %s", strings.Join(options, ", "))
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, exists := value[name]; !exists {
+				*errs = append(*errs, SchemaError{Field: field + "." + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, exists := s.Properties[name]; exists {
+				property.validate(field+"."+name, value[name], errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, SchemaError{Field: field + "." + name, Message: "is not allowed"})
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.validate(field+"["+strconv.Itoa(i)+"]", item, errs)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(value)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			fail("must match %s", s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	}
+}
+
+// matchesType reports whether a value is of one of the schema's types
+func (s *Schema) matchesType(value interface{}) bool {
+	actual := jsonTypeName(value)
+	for _, name := range s.Type {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeName returns the JSON Schema type of a decoded JSON value; whole numbers are integers
+func jsonTypeName(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonText shows a decoded JSON value in an error message
+func jsonText(value interface{}) string {
+	text, _ := json.Marshal(value)
+	return string(text)
+}
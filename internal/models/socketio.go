@@ -0,0 +1,126 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// Engine.IO v4 packet types, the first character of every frame of a Socket.IO connection
+const (
+	EngineOpen    byte = '0'
+	EngineClose   byte = '1'
+	EnginePing    byte = '2'
+	EnginePong    byte = '3'
+	EngineMessage byte = '4'
+	EngineUpgrade byte = '5'
+	EngineNoop    byte = '6'
+)
+
+// Socket.IO v5 packet types, the character following EngineMessage
+const (
+	SocketIOConnect      byte = '0'
+	SocketIODisconnect   byte = '1'
+	SocketIOEvent        byte = '2'
+	SocketIOAck          byte = '3'
+	SocketIOConnectError byte = '4'
+	SocketIOBinaryEvent  byte = '5'
+	SocketIOBinaryAck    byte = '6'
+)
+
+// SocketIOPacket is a decoded frame of a Socket.IO connection. Socket.IO fields are only set
+// for EngineMessage frames.
+type SocketIOPacket struct {
+	EngineType byte
+	Type       byte
+	Namespace  string
+	// AckID is the acknowledgement the sender asked for, -1 when it didn't
+	AckID int64
+	// Data is the packet's JSON payload, e.g. the auth object of a connect packet
+	Data json.RawMessage
+	// Event and Args are the name and arguments of an event packet
+	Event string
+	Args  []json.RawMessage
+}
+
+// ParseSocketIOPacket decodes a text frame of a Socket.IO connection. Binary packets aren't
+// supported.
+func ParseSocketIOPacket(frame []byte) (SocketIOPacket, error) {
+	packet := SocketIOPacket{Namespace: "/", AckID: -1}
+	if len(frame) == 0 || frame[0] < EngineOpen || frame[0] > EngineNoop {
+		return packet, ErrInvalidSocketIOPacket
+	}
+	packet.EngineType = frame[0]
+	if packet.EngineType != EngineMessage {
+		packet.Data = frame[1:]
+		return packet, nil
+	}
+
+	rest := frame[1:]
+	if len(rest) == 0 || rest[0] < SocketIOConnect || rest[0] > SocketIOBinaryAck {
+		return packet, ErrInvalidSocketIOPacket
+	}
+	packet.Type, rest = rest[0], rest[1:]
+	if packet.Type == SocketIOBinaryEvent || packet.Type == SocketIOBinaryAck {
+		return packet, ErrInvalidSocketIOPacket
+	}
+
+	if len(rest) > 0 && rest[0] == '/' {
+		end := bytes.IndexByte(rest, ',')
+		if end < 0 {
+			end = len(rest)
+		}
+		packet.Namespace, rest = string(rest[:end]), rest[min(end+1, len(rest)):]
+	}
+
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 {
+		id, err := strconv.ParseInt(string(rest[:digits]), 10, 64)
+		if err != nil {
+			return packet, ErrInvalidSocketIOPacket
+		}
+		packet.AckID, rest = id, rest[digits:]
+	}
+	packet.Data = rest
+
+	if packet.Type == SocketIOEvent {
+		if err := json.Unmarshal(rest, &packet.Args); err != nil || len(packet.Args) == 0 {
+			return packet, ErrInvalidSocketIOPacket
+		}
+		if err := json.Unmarshal(packet.Args[0], &packet.Event); err != nil {
+			return packet, ErrInvalidSocketIOPacket
+		}
+		packet.Args = packet.Args[1:]
+	}
+	return packet, nil
+}
+
+// EncodeSocketIOPacket builds a Socket.IO frame. The default namespace "/" is left out, an ackID
+// of -1 adds no acknowledgement ID and data is appended as JSON unless it is nil.
+func EncodeSocketIOPacket(packetType byte, namespace string, ackID int64, data interface{}) ([]byte, error) {
+	frame := []byte{EngineMessage, packetType}
+	if namespace != "" && namespace != "/" {
+		frame = append(append(frame, namespace...), ',')
+	}
+	if ackID >= 0 {
+		frame = strconv.AppendInt(frame, ackID, 10)
+	}
+	if data == nil {
+		return frame, nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(frame, payload...), nil
+}
+
+// EncodeSocketIOEvent builds the event frame of a message: the event name, then the message
+// as the single argument, so Socket.IO clients get the same fields as native ones
+func EncodeSocketIOEvent(message Message) ([]byte, error) {
+	return EncodeSocketIOPacket(SocketIOEvent, "/", -1, []interface{}{message.Event, message})
+}
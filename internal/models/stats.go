@@ -0,0 +1,143 @@
+package models
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SlowClientDropThreshold is the number of consecutive messages dropped on a full send queue
+// after which a client is considered too slow to keep up
+const SlowClientDropThreshold = 3
+
+// ClientStats is a snapshot of a client's connection statistics
+type ClientStats struct {
+	MessagesSent     uint64     `json:"messages_sent"`
+	MessagesReceived uint64     `json:"messages_received"`
+	BytesSent        uint64     `json:"bytes_sent"`
+	BytesReceived    uint64     `json:"bytes_received"`
+	MessagesDropped  uint64     `json:"messages_dropped"`
+	SlowWrites       uint64     `json:"slow_writes"`
+	QueueLength      int        `json:"queue_length"`
+	QueueHighWater   int64      `json:"queue_high_water"`
+	LastError        string     `json:"last_error,omitempty"`
+	LastErrorAt      *time.Time `json:"last_error_at,omitempty"`
+	PingInterval     float64    `json:"ping_interval_seconds"`
+	RTT              float64    `json:"rtt_ms"`
+	MissedPongs      uint64     `json:"missed_pongs"`
+
+	// Bandwidth over the last second, and how often writes were delayed by the bandwidth cap
+	SentBytesPerSecond     int64  `json:"sent_bytes_per_second"`
+	ReceivedBytesPerSecond int64  `json:"received_bytes_per_second"`
+	BandwidthLimit         int64  `json:"bandwidth_limit,omitempty"`
+	BandwidthThrottled     uint64 `json:"bandwidth_throttled"`
+
+	// RateLimited counts incoming messages over the client's message rate limit
+	RateLimited uint64 `json:"rate_limited"`
+}
+
+// clientStats holds the live counters behind ClientStats
+type clientStats struct {
+	messagesSent     atomic.Uint64
+	messagesReceived atomic.Uint64
+	bytesSent        atomic.Uint64
+	bytesReceived    atomic.Uint64
+	messagesDropped  atomic.Uint64
+	consecutiveDrops atomic.Uint32
+	slowWrites       atomic.Uint64 // writes that took over half the write timeout
+	queueHighWater   atomic.Int64
+	lastError        string
+	lastErrorAt      time.Time
+	errorMutex       sync.Mutex
+	keeper           *Timekeeper // the client's
+
+	bandwidthThrottled atomic.Uint64
+	rateLimited        atomic.Uint64
+}
+
+// recordSent counts a message written to the connection
+func (s *clientStats) recordSent(bytes int) {
+	s.messagesSent.Add(1)
+	s.bytesSent.Add(uint64(bytes))
+}
+
+// recordReceived counts a message read from the connection
+func (s *clientStats) recordReceived(bytes int) {
+	s.messagesReceived.Add(1)
+	s.bytesReceived.Add(uint64(bytes))
+}
+
+// recordEnqueue tracks the queue depth after an enqueue attempt and counts dropped messages
+func (s *clientStats) recordEnqueue(queueLength int, err error) {
+	if err == ErrSendQueueFull {
+		s.messagesDropped.Add(1)
+		s.consecutiveDrops.Add(1)
+		s.recordError(err)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	s.consecutiveDrops.Store(0)
+	for {
+		highWater := s.queueHighWater.Load()
+		if int64(queueLength) <= highWater || s.queueHighWater.CompareAndSwap(highWater, int64(queueLength)) {
+			return
+		}
+	}
+}
+
+// recordError remembers the most recent error seen on the connection
+func (s *clientStats) recordError(err error) {
+	s.errorMutex.Lock()
+	defer s.errorMutex.Unlock()
+	s.lastError = err.Error()
+	s.lastErrorAt = s.keeper.Now()
+}
+
+// Stats returns a snapshot of the client's connection statistics
+func (c *Client) Stats() ClientStats {
+	stats := ClientStats{
+		MessagesSent:     c.stats.messagesSent.Load(),
+		MessagesReceived: c.stats.messagesReceived.Load(),
+		BytesSent:        c.stats.bytesSent.Load(),
+		BytesReceived:    c.stats.bytesReceived.Load(),
+		MessagesDropped:  c.stats.messagesDropped.Load(),
+		SlowWrites:       c.stats.slowWrites.Load(),
+		QueueLength:      c.QueueLength(),
+		QueueHighWater:   c.stats.queueHighWater.Load(),
+	}
+
+	c.stats.errorMutex.Lock()
+	if c.stats.lastError != "" {
+		lastErrorAt := c.stats.lastErrorAt
+		stats.LastError = c.stats.lastError
+		stats.LastErrorAt = &lastErrorAt
+	}
+	c.stats.errorMutex.Unlock()
+
+	interval, rtt, missedPongs := c.pingStats()
+	stats.PingInterval = interval.Seconds()
+	stats.RTT = float64(rtt) / float64(time.Millisecond)
+	stats.MissedPongs = missedPongs
+
+	stats.SentBytesPerSecond = c.sentBandwidth.rate()
+	stats.ReceivedBytesPerSecond = c.receivedBandwidth.rate()
+	stats.BandwidthThrottled = c.stats.bandwidthThrottled.Load()
+	stats.BandwidthLimit = c.bandwidthLimit.Load()
+	stats.RateLimited = c.stats.rateLimited.Load()
+
+	return stats
+}
+
+// MessagesSent returns the number of messages written to the client's connection
+func (c *Client) MessagesSent() uint64 {
+	return c.stats.messagesSent.Load()
+}
+
+// IsSlow reports whether the client has failed to keep up with its send queue for several
+// consecutive messages, as opposed to a single burst filling the queue once
+func (c *Client) IsSlow() bool {
+	return c.stats.consecutiveDrops.Load() >= SlowClientDropThreshold
+}
@@ -0,0 +1,48 @@
+package models
+
+import "regexp"
+
+// templatePlaceholder matches {{name}} placeholders, allowing spaces inside the braces
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// RenderTemplate returns a copy of data with {{name}} placeholders in every string replaced by
+// the matching value from vars. Maps and slices are walked recursively; unknown placeholders
+// are left untouched.
+func RenderTemplate(data interface{}, vars map[string]string) interface{} {
+	switch value := data.(type) {
+	case string:
+		return templatePlaceholder.ReplaceAllStringFunc(value, func(match string) string {
+			name := templatePlaceholder.FindStringSubmatch(match)[1]
+			if replacement, ok := vars[name]; ok {
+				return replacement
+			}
+			return match
+		})
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			rendered[key] = RenderTemplate(item, vars)
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, item := range value {
+			rendered[i] = RenderTemplate(item, vars)
+		}
+		return rendered
+	default:
+		return data
+	}
+}
+
+// templateVars returns the per-recipient values available to templated messages.
+// The caller must hold c.mutex.
+func (c *Client) templateVars(message Message) map[string]string {
+	return map[string]string{
+		"client_id": c.ID,
+		"user_id":   c.UserID,
+		"username":  c.Username,
+		"email":     c.Email,
+		"channel":   message.Channel,
+	}
+}
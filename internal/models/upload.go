@@ -0,0 +1,13 @@
+package models
+
+// UploadedFile is the reference to a file a client uploaded to storage, broadcast to the
+// channel instead of the file itself
+type UploadedFile struct {
+	ID          string      `json:"upload_id"`
+	Key         string      `json:"key"`
+	URL         string      `json:"url"`
+	Name        string      `json:"name,omitempty"`
+	ContentType string      `json:"content_type,omitempty"`
+	Size        int64       `json:"size"`
+	Metadata    interface{} `json:"metadata,omitempty"`
+}
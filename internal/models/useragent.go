@@ -0,0 +1,125 @@
+package models
+
+import "strings"
+
+// Device types reported for clients
+const (
+	DeviceTypeDesktop = "desktop"
+	DeviceTypeMobile  = "mobile"
+	DeviceTypeTablet  = "tablet"
+	DeviceTypeBot     = "bot"
+	DeviceTypeUnknown = "unknown"
+)
+
+// DeviceInfo describes the browser, operating system and device class parsed from a User-Agent
+type DeviceInfo struct {
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os"`
+	DeviceType     string `json:"device_type"`
+}
+
+// browserTokens maps User-Agent product tokens to browser names, most specific first
+// (Edge and Opera also advertise Chrome, and Chrome also advertises Safari)
+var browserTokens = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "edge"},
+	{"EdgiOS/", "edge"},
+	{"OPR/", "opera"},
+	{"SamsungBrowser/", "samsung"},
+	{"FxiOS/", "firefox"},
+	{"Firefox/", "firefox"},
+	{"CriOS/", "chrome"},
+	{"Chrome/", "chrome"},
+	{"Version/", "safari"},
+}
+
+// botTokens identify crawlers and non-browser HTTP clients
+var botTokens = []string{"bot", "crawler", "spider", "curl/", "wget/", "python-requests", "go-http-client", "okhttp"}
+
+// ParseUserAgent classifies a User-Agent string. Unrecognized values yield "unknown" fields.
+func ParseUserAgent(userAgent string) DeviceInfo {
+	info := DeviceInfo{
+		Browser:    "unknown",
+		OS:         "unknown",
+		DeviceType: DeviceTypeUnknown,
+	}
+	if userAgent == "" {
+		return info
+	}
+
+	lower := strings.ToLower(userAgent)
+	for _, token := range botTokens {
+		if strings.Contains(lower, token) {
+			info.DeviceType = DeviceTypeBot
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		info.OS = "ios"
+	case strings.Contains(userAgent, "Android"):
+		info.OS = "android"
+	case strings.Contains(userAgent, "Windows"):
+		info.OS = "windows"
+	case strings.Contains(userAgent, "CrOS"):
+		info.OS = "chromeos"
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		info.OS = "macos"
+	case strings.Contains(userAgent, "Linux"):
+		info.OS = "linux"
+	}
+
+	if info.DeviceType != DeviceTypeBot {
+		switch {
+		case strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "Tablet"),
+			info.OS == "android" && !strings.Contains(userAgent, "Mobile"):
+			info.DeviceType = DeviceTypeTablet
+		case strings.Contains(userAgent, "Mobi"), strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPod"):
+			info.DeviceType = DeviceTypeMobile
+		case info.OS == "windows", info.OS == "macos", info.OS == "linux", info.OS == "chromeos":
+			info.DeviceType = DeviceTypeDesktop
+		}
+	}
+
+	for _, browser := range browserTokens {
+		index := strings.Index(userAgent, browser.token)
+		if index < 0 {
+			continue
+		}
+		// Safari is only identified by "Version/" alongside the Safari token
+		if browser.name == "safari" && !strings.Contains(userAgent, "Safari/") {
+			continue
+		}
+
+		info.Browser = browser.name
+		info.BrowserVersion = productVersion(userAgent[index+len(browser.token):])
+		break
+	}
+
+	return info
+}
+
+// productVersion returns the major version from the text following a product token
+func productVersion(rest string) string {
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	return rest[:end]
+}
+
+// MatchesPlatform reports whether the device matches the given device type and OS filters.
+// Empty filters match anything.
+func (d DeviceInfo) MatchesPlatform(deviceType, os string) bool {
+	if deviceType != "" && !strings.EqualFold(d.DeviceType, deviceType) {
+		return false
+	}
+	if os != "" && !strings.EqualFold(d.OS, os) {
+		return false
+	}
+	return true
+}
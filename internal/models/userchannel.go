@@ -0,0 +1,24 @@
+package models
+
+import "strings"
+
+// UserIDPlaceholder is replaced with the user's ID in personal channel templates
+const UserIDPlaceholder = "{user_id}"
+
+// UserChannelName returns the personal channel name of a user for a template such as "user.{user_id}"
+func UserChannelName(template, userID string) string {
+	return strings.Replace(template, UserIDPlaceholder, userID, 1)
+}
+
+// UserChannelOwner extracts the user ID from a personal channel name built with the template.
+// It reports false for channels that don't match the template.
+func UserChannelOwner(template, channelName string) (string, bool) {
+	prefix, suffix, found := strings.Cut(template, UserIDPlaceholder)
+	if !found || len(channelName) <= len(prefix)+len(suffix) {
+		return "", false
+	}
+	if !strings.HasPrefix(channelName, prefix) || !strings.HasSuffix(channelName, suffix) {
+		return "", false
+	}
+	return channelName[len(prefix) : len(channelName)-len(suffix)], true
+}
@@ -0,0 +1,12 @@
+package models
+
+import "strings"
+
+// ClientEventPrefix marks events that clients relay to the other members of a channel without
+// going through Laravel, like Pusher client events and Laravel Echo whispers
+const ClientEventPrefix = "client-"
+
+// IsClientEvent reports whether an event name uses the client event prefix
+func IsClientEvent(event string) bool {
+	return strings.HasPrefix(event, ClientEventPrefix) && len(event) > len(ClientEventPrefix)
+}
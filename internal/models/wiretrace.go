@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Directions of traced frames
+const (
+	WireInbound  = "in"
+	WireOutbound = "out"
+)
+
+// RedactedValue replaces redacted values in traced frames
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactKeys are the JSON keys whose values are always redacted from traced frames
+var DefaultRedactKeys = []string{"token", "resume_token", "access_token", "refresh_token", "password", "secret", "authorization", "api_key"}
+
+// jwtPattern matches JWTs appearing under keys that aren't redacted
+var jwtPattern = regexp.MustCompile(`^eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`)
+
+// WireTap is told about every frame read from or written to a client's connection, with the
+// frame's payload as it is on the wire
+type WireTap func(client *Client, direction string, opcode int, payload []byte)
+
+// WireFrame is one traced frame, written as a line of NDJSON
+type WireFrame struct {
+	Time      time.Time       `json:"time"`
+	ClientID  string          `json:"client_id"`
+	UserID    string          `json:"user_id,omitempty"`
+	Direction string          `json:"direction"`
+	Opcode    string          `json:"opcode"`
+	Size      int             `json:"size"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	// Raw holds payloads that aren't JSON, which can't be redacted
+	Raw string `json:"raw,omitempty"`
+}
+
+// SetWireTap sets the tap told about the client's frames. Call it before StartWriter.
+func (c *Client) SetWireTap(tap WireTap) {
+	c.wireTap = tap
+}
+
+// RedactPayload returns a JSON payload with the values of the given keys (matched
+// case-insensitively, at any depth) and any JWT-looking strings replaced. It returns false for
+// payloads that aren't JSON.
+func RedactPayload(payload []byte, keys []string) ([]byte, bool) {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return nil, false
+	}
+
+	redact := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		redact[strings.ToLower(key)] = true
+	}
+
+	redacted, err := json.Marshal(redactValue(value, redact))
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+// redactValue walks a decoded JSON value, redacting in place
+func redactValue(value interface{}, keys map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if keys[strings.ToLower(key)] {
+				v[key] = RedactedValue
+			} else {
+				v[key] = redactValue(field, keys)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, keys)
+		}
+	case string:
+		if jwtPattern.MatchString(v) {
+			return RedactedValue
+		}
+	}
+	return value
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+// analyticsSaveInterval is how often rollups are written to the analytics file
+const analyticsSaveInterval = time.Minute
+
+// analyticsFileVersion is bumped whenever the analytics file layout changes incompatibly
+const analyticsFileVersion = 1
+
+// analyticsMigrations upgrade analytics files of earlier layout versions. Bumping
+// analyticsFileVersion requires adding the migration from the previous version.
+var analyticsMigrations []models.Migration
+
+// AnalyticsStore aggregates daily usage rollups, for the whole server and per channel. Rollups
+// are kept in memory and saved to a JSON file periodically and on Close, so they survive
+// restarts. Days older than the retention period are dropped. A nil store records nothing.
+type AnalyticsStore struct {
+	file      string
+	retention int // days
+	logger    *logger.Logger
+
+	mutex sync.Mutex
+	days  map[string]*analyticsDay
+	dirty bool
+
+	stop chan struct{}
+	done chan struct{}
+
+	// Clock and ID generator of timestamps and IDs; the system clock and models.NewID unless
+	// set otherwise
+	models.Timekeeper
+}
+
+// analyticsDay is the rollup of one day. User IDs are kept only until the day is over; then
+// just their count remains.
+type analyticsDay struct {
+	Messages        uint64                       `json:"messages"`
+	UniqueUsers     int                          `json:"unique_users"`
+	PeakConnections int                          `json:"peak_connections"`
+	Users           map[string]bool              `json:"users,omitempty"`
+	Channels        map[string]*analyticsChannel `json:"channels"`
+}
+
+type analyticsChannel struct {
+	Messages        uint64          `json:"messages"`
+	UniqueUsers     int             `json:"unique_users"`
+	PeakSubscribers int             `json:"peak_subscribers"`
+	Users           map[string]bool `json:"users,omitempty"`
+}
+
+// analyticsFile is the layout of the analytics file
+type analyticsFile struct {
+	Version int                      `json:"version"`
+	Days    map[string]*analyticsDay `json:"days"`
+}
+
+// NewAnalyticsStore creates a store saved to file, keeping retentionDays days of rollups. It
+// loads the rollups already in the file; a missing file starts empty.
+func NewAnalyticsStore(file string, retentionDays int, logger *logger.Logger) (*AnalyticsStore, error) {
+	store := &AnalyticsStore{
+		file:      file,
+		retention: retentionDays,
+		logger:    logger,
+		days:      make(map[string]*analyticsDay),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading analytics file: %w", err)
+	}
+
+	upgraded, version, err := models.MigrateDocument(data, analyticsFileVersion, analyticsMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing analytics file: %w", err)
+	}
+	if version < analyticsFileVersion {
+		// The next save writes the new layout over the file
+		backupFile := fmt.Sprintf("%s.v%d.bak", file, version)
+		if err := os.WriteFile(backupFile, data, 0600); err != nil {
+			return nil, fmt.Errorf("error keeping the analytics file before its upgrade: %w", err)
+		}
+		logger.Info("Upgraded analytics file from version %d to %d, the original is kept as %s", version, analyticsFileVersion, backupFile)
+	}
+
+	var saved analyticsFile
+	if err := json.Unmarshal(upgraded, &saved); err != nil {
+		return nil, fmt.Errorf("error parsing analytics file: %w", err)
+	}
+	for day, rollup := range saved.Days {
+		if rollup.Channels == nil {
+			rollup.Channels = make(map[string]*analyticsChannel)
+		}
+		store.days[day] = rollup
+	}
+	store.compact(store.Now())
+	return store, nil
+}
+
+// Start saves the rollups periodically until Close
+func (a *AnalyticsStore) Start() {
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(analyticsSaveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				a.mutex.Lock()
+				a.compact(a.Now())
+				a.mutex.Unlock()
+				if err := a.save(); err != nil {
+					a.logger.Error("Failed to save analytics: %v", err)
+				}
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the periodic save and saves the rollups one last time
+func (a *AnalyticsStore) Close() error {
+	if a == nil {
+		return nil
+	}
+	close(a.stop)
+	<-a.done
+	return a.save()
+}
+
+// RecordMessage counts a message published to a channel, and its sender as a channel user
+func (a *AnalyticsStore) RecordMessage(channelName, userID string) {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	day, channel := a.channel(channelName)
+	day.Messages++
+	channel.Messages++
+	channel.addUser(userID)
+	a.dirty = true
+}
+
+// RecordSubscribers records a user joining a channel that now has subscribers subscribers
+func (a *AnalyticsStore) RecordSubscribers(channelName, userID string, subscribers int) {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	_, channel := a.channel(channelName)
+	channel.PeakSubscribers = max(channel.PeakSubscribers, subscribers)
+	channel.addUser(userID)
+	a.dirty = true
+}
+
+// RecordConnections records the number of connections after a client connected
+func (a *AnalyticsStore) RecordConnections(connections int) {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	day := a.today()
+	day.PeakConnections = max(day.PeakConnections, connections)
+	a.dirty = true
+}
+
+// RecordUser records an authenticated user
+func (a *AnalyticsStore) RecordUser(userID string) {
+	if a == nil || userID == "" {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	day := a.today()
+	if !day.Users[userID] {
+		day.Users[userID] = true
+		day.UniqueUsers++
+		a.dirty = true
+	}
+}
+
+// Query returns the rollups of the days from from to to (inclusive, "2006-01-02" in UTC),
+// oldest first. Only channels matching one of the patterns are included; no patterns include
+// every channel.
+func (a *AnalyticsStore) Query(from, to string, patterns []string) []models.AnalyticsDay {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	result := make([]models.AnalyticsDay, 0)
+	for key, day := range a.days {
+		if key < from || key > to {
+			continue
+		}
+		rollup := models.AnalyticsDay{
+			Day:             key,
+			Messages:        day.Messages,
+			UniqueUsers:     day.UniqueUsers,
+			PeakConnections: day.PeakConnections,
+			Channels:        make(map[string]models.ChannelAnalytics),
+		}
+		for name, channel := range day.Channels {
+			if len(patterns) > 0 && !matchesAny(patterns, name) {
+				continue
+			}
+			rollup.Channels[name] = models.ChannelAnalytics{
+				Messages:        channel.Messages,
+				UniqueUsers:     channel.UniqueUsers,
+				PeakSubscribers: channel.PeakSubscribers,
+			}
+		}
+		result = append(result, rollup)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Day < result[j].Day })
+	return result
+}
+
+// today returns the current day's rollup, creating it on the first event of the day. The
+// caller must hold the mutex.
+func (a *AnalyticsStore) today() *analyticsDay {
+	key := a.Now().UTC().Format(models.AnalyticsDateFormat)
+	day, ok := a.days[key]
+	if !ok {
+		day = &analyticsDay{Channels: make(map[string]*analyticsChannel)}
+		a.days[key] = day
+	}
+	if day.Users == nil {
+		day.Users = make(map[string]bool)
+	}
+	return day
+}
+
+// channel returns the current day's rollup of a channel. The caller must hold the mutex.
+func (a *AnalyticsStore) channel(channelName string) (*analyticsDay, *analyticsChannel) {
+	day := a.today()
+	channel, ok := day.Channels[channelName]
+	if !ok {
+		channel = &analyticsChannel{}
+		day.Channels[channelName] = channel
+	}
+	if channel.Users == nil {
+		channel.Users = make(map[string]bool)
+	}
+	return day, channel
+}
+
+func (c *analyticsChannel) addUser(userID string) {
+	if userID != "" && !c.Users[userID] {
+		c.Users[userID] = true
+		c.UniqueUsers++
+	}
+}
+
+// compact drops days past the retention period and the user IDs of days that are over. The
+// caller must hold the mutex.
+func (a *AnalyticsStore) compact(now time.Time) {
+	today := now.UTC().Format(models.AnalyticsDateFormat)
+	oldest := now.UTC().AddDate(0, 0, -a.retention+1).Format(models.AnalyticsDateFormat)
+
+	for key, day := range a.days {
+		if key < oldest {
+			delete(a.days, key)
+			a.dirty = true
+			continue
+		}
+		if key < today && day.Users != nil {
+			day.Users = nil
+			for _, channel := range day.Channels {
+				channel.Users = nil
+			}
+			a.dirty = true
+		}
+	}
+}
+
+// save writes the rollups to the analytics file, replacing it atomically, if they changed
+func (a *AnalyticsStore) save() error {
+	a.mutex.Lock()
+	if !a.dirty {
+		a.mutex.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(analyticsFile{Version: analyticsFileVersion, Days: a.days})
+	a.dirty = false
+	a.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding analytics: %w", err)
+	}
+
+	tmpFile := a.file + ".tmp"
+	err = os.WriteFile(tmpFile, data, 0600)
+	if err == nil {
+		err = os.Rename(tmpFile, a.file)
+	}
+	if err != nil {
+		// Retry on the next save
+		a.mutex.Lock()
+		a.dirty = true
+		a.mutex.Unlock()
+		return fmt.Errorf("error writing analytics file: %w", err)
+	}
+	return nil
+}
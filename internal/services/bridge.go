@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+// BridgeHeader marks broadcasts forwarded by a bridge with the ID of the node that forwarded
+// them. Servers never forward bridged broadcasts again, so two servers bridging the same
+// channels to each other don't loop.
+const BridgeHeader = "X-GoSocket-Bridge"
+
+const (
+	bridgeRequestTimeout = 10 * time.Second
+	bridgeInitialBackoff = 250 * time.Millisecond
+	bridgeMaxBackoff     = 10 * time.Second
+	bridgeStopTimeout    = 5 * time.Second
+)
+
+// BridgeStats counts the outcome of forwarded broadcasts
+type BridgeStats struct {
+	Forwarded uint64 `json:"forwarded"`
+	Failed    uint64 `json:"failed"`  // gave up after the retry limit
+	Dropped   uint64 `json:"dropped"` // the queue was full
+	Queued    int    `json:"queued"`
+}
+
+// BridgeService forwards broadcasts on selected channels to a remote server's broadcast API.
+// Messages are delivered in order by a single worker; failed requests are retried with
+// exponential backoff before the message is given up on.
+type BridgeService struct {
+	endpoint   string
+	token      string
+	nodeID     string
+	channels   []string
+	maxRetries int
+	client     *http.Client
+	guard      *SinkGuard // every request runs under it
+	logger     *logger.Logger
+
+	queue    chan models.Message
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	forwarded atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// NewBridgeService creates a bridge to the server at baseURL, forwarding channels matching
+// any of the patterns. Call Start to begin delivering.
+func NewBridgeService(baseURL, token, nodeID string, channels []string, queueSize, maxRetries int, logger *logger.Logger) *BridgeService {
+	return &BridgeService{
+		endpoint:   strings.TrimRight(baseURL, "/") + "/api/broadcast",
+		token:      token,
+		nodeID:     nodeID,
+		channels:   channels,
+		maxRetries: maxRetries,
+		client:     &http.Client{Timeout: bridgeRequestTimeout},
+		guard:      NewSinkGuard("bridge", bridgeRequestTimeout),
+		logger:     logger,
+		queue:      make(chan models.Message, queueSize),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start runs the delivery worker
+func (b *BridgeService) Start() {
+	go b.run()
+}
+
+// Stop delivers the queued messages, giving up once the stop timeout elapses
+func (b *BridgeService) Stop() {
+	b.stopOnce.Do(func() { close(b.stop) })
+	select {
+	case <-b.done:
+	case <-time.After(bridgeStopTimeout):
+		b.logger.Warn("Bridge: stopped with %d messages undelivered", len(b.queue))
+	}
+}
+
+// Matches reports whether broadcasts on a channel are forwarded
+func (b *BridgeService) Matches(channelName string) bool {
+	return b != nil && matchesAny(b.channels, channelName)
+}
+
+// Forward queues a message for delivery without blocking. It returns false when the queue
+// is full and the message was dropped.
+func (b *BridgeService) Forward(message models.Message) bool {
+	select {
+	case b.queue <- message:
+		return true
+	default:
+		b.dropped.Add(1)
+		b.logger.Warn("Bridge: queue full, dropping message %s on channel %s", message.ID, message.Channel)
+		return false
+	}
+}
+
+// Health returns the record of the requests to the remote server
+func (b *BridgeService) Health() SinkHealth {
+	return b.guard.Health()
+}
+
+// Stats returns the delivery counters
+func (b *BridgeService) Stats() BridgeStats {
+	return BridgeStats{
+		Forwarded: b.forwarded.Load(),
+		Failed:    b.failed.Load(),
+		Dropped:   b.dropped.Load(),
+		Queued:    len(b.queue),
+	}
+}
+
+// run delivers queued messages until stopped, then flushes what is left
+func (b *BridgeService) run() {
+	defer close(b.done)
+	for {
+		select {
+		case message := <-b.queue:
+			b.deliver(message)
+		case <-b.stop:
+			for {
+				select {
+				case message := <-b.queue:
+					b.deliver(message)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver posts a message, retrying with exponential backoff
+func (b *BridgeService) deliver(message models.Message) {
+	body, err := json.Marshal(bridgePayload(message))
+	if err != nil {
+		b.failed.Add(1)
+		b.logger.Error("Bridge: failed to encode message %s: %v", message.ID, err)
+		return
+	}
+
+	backoff := bridgeInitialBackoff
+	for attempt := 0; ; attempt++ {
+		err = b.guard.Run(func(ctx context.Context) error {
+			return b.post(ctx, body)
+		})
+		if err == nil {
+			b.forwarded.Add(1)
+			return
+		}
+		if attempt >= b.maxRetries {
+			break
+		}
+
+		b.logger.Warn("Bridge: forwarding message %s failed (attempt %d): %v", message.ID, attempt+1, err)
+		select {
+		case <-time.After(backoff):
+		case <-b.stop:
+			// Shutting down: keep retrying, but without waiting out long backoffs
+			time.Sleep(bridgeInitialBackoff)
+		}
+		backoff = min(backoff*2, bridgeMaxBackoff)
+	}
+
+	b.failed.Add(1)
+	b.logger.Error("Bridge: giving up on message %s on channel %s: %v", message.ID, message.Channel, err)
+}
+
+// post sends one broadcast request to the remote server
+func (b *BridgeService) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	req.Header.Set(BridgeHeader, b.nodeID)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("remote server returned %s", resp.Status)
+	}
+	return nil
+}
+
+// bridgePayload converts a message into a channel broadcast request. The message ID and origin
+// are kept, so the remote server drops a copy that reaches it twice.
+func bridgePayload(message models.Message) map[string]interface{} {
+	payload := map[string]interface{}{
+		"broadcast_type": "channel",
+		"channel":        message.Channel,
+		"event":          message.Event,
+		"data":           message.Data,
+		"message_id":     message.ID,
+		"origin_node":    message.Origin,
+	}
+	if message.CoalesceKey != "" {
+		payload["coalesce_key"] = message.CoalesceKey
+	}
+	if message.Priority == models.PriorityHigh {
+		payload["priority"] = "high"
+	}
+	if message.Template {
+		payload["template"] = true
+	}
+	if message.ExcludeClientID != "" {
+		payload["exclude_client_id"] = message.ExcludeClientID
+	}
+	return payload
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+// Cluster event types
+const (
+	ClusterEventBan       = "ban"
+	ClusterEventUnban     = "unban"
+	ClusterEventBroadcast = "broadcast"
+	ClusterEventStatus    = "status"
+)
+
+const (
+	clusterInitialBackoff = 500 * time.Millisecond
+	clusterMaxBackoff     = 30 * time.Second
+)
+
+// ClusterEvent is a change published to every node of the cluster. Node is the ID of the node
+// that published it; nodes ignore their own events.
+type ClusterEvent struct {
+	Type string          `json:"type"`
+	Node string          `json:"node"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ClusterService shares state between the server nodes of a cluster through Redis: rate-limit
+// buckets every node takes from, the ban list, and events published to the other nodes, which
+// include the broadcasts on the relayed channels. Keys and the pub/sub channel are namespaced
+// by prefix, so several clusters can share a Redis.
+type ClusterService struct {
+	redis    *RedisClient
+	prefix   string
+	nodeID   string
+	channels []string
+	logger   *logger.Logger
+
+	subscription *RedisSubscription
+	stopped      bool
+	mutex        sync.Mutex
+
+	// Clock and ID generator of timestamps and IDs; the system clock and models.NewID unless
+	// set otherwise
+	models.Timekeeper
+}
+
+// NewClusterService creates the cluster membership of this node, relaying broadcasts on
+// channels matching any of the patterns. Call Listen to receive the other nodes' events.
+func NewClusterService(redisURL, prefix, nodeID string, channels []string, logger *logger.Logger) (*ClusterService, error) {
+	redis, err := NewRedisClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterService{redis: redis, prefix: prefix, nodeID: nodeID, channels: channels, logger: logger}, nil
+}
+
+// Matches reports whether broadcasts on a channel are relayed to the other nodes
+func (c *ClusterService) Matches(channelName string) bool {
+	return c != nil && matchesAny(c.channels, channelName)
+}
+
+// Address returns the host and port of the cluster's Redis
+func (c *ClusterService) Address() string {
+	return c.redis.Address()
+}
+
+// Ping checks that Redis can be reached
+func (c *ClusterService) Ping() error {
+	_, err := c.redis.Do("PING")
+	return err
+}
+
+// Take takes a token from a rate-limit bucket shared by every node. Shared buckets are fixed
+// windows of burst requests per burst/rate seconds, so the average rate and the burst size
+// match those of the local token buckets. When the bucket is empty, it returns how long until
+// its window ends.
+func (c *ClusterService) Take(key string, rate float64, burst int) (bool, time.Duration, error) {
+	burst = max(burst, 1)
+	window := int64(math.Ceil(float64(burst) / rate * 1000))
+	key = c.key("ratelimit", key)
+
+	replies, err := c.redis.Pipeline([][]interface{}{
+		{"SET", key, 0, "PX", window, "NX"},
+		{"INCR", key},
+		{"PTTL", key},
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	count, ok := replies[1].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected INCR reply %v", replies[1])
+	}
+
+	ttl, _ := replies[2].(int64)
+	if ttl < 0 {
+		// The window expired between SET and INCR, which recreated the key without expiry
+		c.redis.Do("PEXPIRE", key, window)
+		ttl = window
+	}
+	if count > int64(burst) {
+		return false, time.Duration(ttl) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
+
+// Publish sends an event to the other nodes
+func (c *ClusterService) Publish(eventType string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	event, err := json.Marshal(ClusterEvent{Type: eventType, Node: c.nodeID, Data: encoded})
+	if err != nil {
+		return err
+	}
+	_, err = c.redis.Do("PUBLISH", c.key("events"), event)
+	return err
+}
+
+// Listen calls handle for every event the other nodes publish, until Stop. synced is called
+// each time the subscription is established, including after Redis was unreachable, so state
+// changed in the meantime can be reloaded.
+func (c *ClusterService) Listen(handle func(ClusterEvent), synced func()) {
+	backoff := clusterInitialBackoff
+	for {
+		subscription, err := c.redis.Subscribe(c.key("events"))
+		if err != nil {
+			c.logger.Warn("Cluster: failed to subscribe to events, retrying in %s: %v", backoff, err)
+		} else {
+			c.mutex.Lock()
+			if c.stopped {
+				c.mutex.Unlock()
+				subscription.Close()
+				return
+			}
+			c.subscription = subscription
+			c.mutex.Unlock()
+
+			backoff = clusterInitialBackoff
+			synced()
+			for {
+				payload, err := subscription.Receive()
+				if err != nil {
+					break
+				}
+				var event ClusterEvent
+				if err := json.Unmarshal(payload, &event); err != nil {
+					c.logger.Warn("Cluster: ignoring malformed event: %v", err)
+					continue
+				}
+				if event.Node != c.nodeID {
+					handle(event)
+				}
+			}
+			subscription.Close()
+		}
+
+		c.mutex.Lock()
+		stopped := c.stopped
+		c.mutex.Unlock()
+		if stopped {
+			return
+		}
+		if err == nil {
+			c.logger.Warn("Cluster: lost the event subscription, reconnecting")
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, clusterMaxBackoff)
+	}
+}
+
+// Stop ends Listen and closes the connections to Redis
+func (c *ClusterService) Stop() {
+	c.mutex.Lock()
+	c.stopped = true
+	if c.subscription != nil {
+		c.subscription.Close()
+	}
+	c.mutex.Unlock()
+	c.redis.Close()
+}
+
+// SaveBan records a ban in the cluster's ban list
+func (c *ClusterService) SaveBan(ban models.Ban) error {
+	encoded, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+	_, err = c.redis.Do("HSET", c.key("bans"), ban.Type+":"+ban.Value, encoded)
+	return err
+}
+
+// DeleteBan removes a ban from the cluster's ban list
+func (c *ClusterService) DeleteBan(banType, value string) error {
+	_, err := c.redis.Do("HDEL", c.key("bans"), banType+":"+value)
+	return err
+}
+
+// LoadBans returns the bans of the cluster's ban list that are in force, forgetting the
+// expired ones
+func (c *ClusterService) LoadBans() ([]models.Ban, error) {
+	reply, err := c.redis.Do("HGETALL", c.key("bans"))
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := reply.([]interface{})
+
+	now := c.Now()
+	bans := make([]models.Ban, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		field, _ := fields[i].(string)
+		value, _ := fields[i+1].(string)
+
+		var ban models.Ban
+		if err := json.Unmarshal([]byte(value), &ban); err != nil || !ban.Active(now) {
+			c.redis.Do("HDEL", c.key("bans"), field)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+// key namespaces a Redis key or channel name with the cluster prefix
+func (c *ClusterService) key(parts ...string) string {
+	key := c.prefix
+	for _, part := range parts {
+		key += ":" + part
+	}
+	return key
+}
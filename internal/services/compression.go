@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// PayloadCompressionZstd compresses Laravel payload files with Zstandard
+const PayloadCompressionZstd = "zstd"
+
+// SetPayloadCompression compresses payload files of at least minSize bytes with the given
+// algorithm ("" disables compression). Compressed files are written with a ".json.zst" suffix,
+// which tells the Laravel command to decompress them before decoding.
+func (s *LaravelService) SetPayloadCompression(algorithm string, minSize int) error {
+	switch algorithm {
+	case "":
+		s.zstdEncoder = nil
+		return nil
+	case PayloadCompressionZstd:
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			return fmt.Errorf("error creating zstd encoder: %w", err)
+		}
+		s.zstdEncoder = encoder
+		s.compressMinSize = minSize
+		return nil
+	default:
+		return fmt.Errorf("unsupported payload compression %q", algorithm)
+	}
+}
+
+// compressPayload compresses a JSON payload when compression is enabled and the payload is large
+// enough, returning the bytes to write and the file extension to use
+func (s *LaravelService) compressPayload(data []byte) ([]byte, string) {
+	if s.zstdEncoder == nil || len(data) < s.compressMinSize {
+		return data, ".json"
+	}
+	return s.zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2)), ".json.zst"
+}
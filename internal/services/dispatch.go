@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"socket-server/internal/auth"
+)
+
+// Dispatch strategies selecting how payloads reach Laravel
+const (
+	// DispatchStrategyExec runs `php artisan <command> --payload <file>` for every payload
+	DispatchStrategyExec = "exec"
+	// DispatchStrategyWorkerPool streams payloads to long-running `php artisan <command> --worker`
+	// processes over stdin, one JSON document per line
+	DispatchStrategyWorkerPool = "worker-pool"
+	// DispatchStrategyHTTP posts payloads to an HTTP endpoint: a Laravel route, or any backend
+	// consuming socket events as webhooks
+	DispatchStrategyHTTP = "http-callback"
+)
+
+// Headers of http-callback requests
+const (
+	// DispatchSignatureHeader carries the "sha256=<hex>" HMAC of the request body
+	DispatchSignatureHeader = "X-Socket-Signature"
+	// DispatchEventHeader carries the payload's action, e.g. "client_connected"
+	DispatchEventHeader = "X-Socket-Event"
+	// DispatchDeliveryHeader carries an ID that stays the same when a payload is retried, so
+	// receivers can discard duplicates
+	DispatchDeliveryHeader = "X-Socket-Delivery"
+)
+
+// callbackInitialBackoff is the delay before the first retry of a failed callback; it doubles
+// with each further retry
+const callbackInitialBackoff = 200 * time.Millisecond
+
+// defaultDispatchTimeout bounds a dispatch when DispatchOptions.Timeout is 0
+const defaultDispatchTimeout = 30 * time.Second
+
+// DispatchOptions configures the worker-pool and http-callback strategies
+type DispatchOptions struct {
+	// Workers is the number of PHP worker processes
+	Workers int
+	// WorkerMaxJobs recycles a worker after this many payloads, bounding PHP memory growth
+	// (0 never recycles)
+	WorkerMaxJobs int
+	// Timeout bounds how long a command, a worker or a callback request may take to handle a
+	// payload (0 uses 30 seconds)
+	Timeout time.Duration
+	// CallbackURL is the Laravel route receiving http-callback payloads
+	CallbackURL string
+	// CallbackSecret signs http-callback requests (empty sends them unsigned)
+	CallbackSecret string
+	// CallbackRetries is how many times a callback failing with a network error, 429 or 5xx
+	// response is retried
+	CallbackRetries int
+}
+
+// SetDispatchStrategy selects how payloads are delivered to Laravel. The worker pool is
+// started immediately; call Close on shutdown to stop it.
+func (s *LaravelService) SetDispatchStrategy(strategy string, options DispatchOptions) error {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultDispatchTimeout
+	}
+	s.guard = NewSinkGuard("laravel", options.Timeout)
+
+	switch strategy {
+	case "", DispatchStrategyExec:
+		return nil
+	case DispatchStrategyWorkerPool:
+		if options.Workers <= 0 {
+			return fmt.Errorf("worker pool needs at least one worker")
+		}
+		s.workers = newWorkerPool(s, options)
+		s.logger.Info("Dispatching to Laravel through %d PHP workers", options.Workers)
+		return nil
+	case DispatchStrategyHTTP:
+		if options.CallbackURL == "" {
+			return fmt.Errorf("http-callback dispatch needs a callback URL")
+		}
+		s.callback = &httpCallback{
+			url:     options.CallbackURL,
+			secret:  options.CallbackSecret,
+			retries: options.CallbackRetries,
+			client:  &http.Client{Timeout: options.Timeout},
+		}
+		s.logger.Info("Dispatching through the HTTP callback %s", options.CallbackURL)
+		return nil
+	default:
+		return fmt.Errorf("unknown dispatch strategy %q", strategy)
+	}
+}
+
+// Close stops the worker pool, letting workers finish the payload they are handling
+func (s *LaravelService) Close() {
+	if s.workers != nil {
+		s.workers.close()
+	}
+}
+
+// Health returns the record of the dispatches to Laravel
+func (s *LaravelService) Health() SinkHealth {
+	return s.guard.Health()
+}
+
+// OutboxPending returns the number of payloads in the outbox not delivered yet
+func (s *LaravelService) OutboxPending() int64 {
+	return s.outbox.Pending()
+}
+
+// SetOutbox records every payload in an outbox before dispatching it, so payloads in flight
+// when the server stops aren't lost. Call ReplayOutbox afterwards to deliver those left by the
+// previous run.
+func (s *LaravelService) SetOutbox(outbox *Outbox) {
+	s.outbox = outbox
+}
+
+// ReplayOutbox delivers the payloads left in the outbox by the previous run, oldest first, in
+// the background. Each is delivered with its original message ID and delivery ID, so Laravel
+// can discard those it handled before the server stopped. Payloads that fail again stay in the
+// outbox for the next start.
+func (s *LaravelService) ReplayOutbox() error {
+	if s.outbox == nil {
+		return nil
+	}
+	names, err := s.outbox.entries()
+	if err != nil || len(names) == 0 {
+		return err
+	}
+
+	s.logger.Info("Replaying %d undelivered payloads from the outbox", len(names))
+	go func() {
+		delivered := 0
+		for _, name := range names {
+			entry, err := s.outbox.load(name)
+			if err != nil {
+				s.logger.Error("Skipping outbox entry %s: %v", name, err)
+				continue
+			}
+			var payload map[string]interface{}
+			if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+				s.logger.Error("Skipping outbox entry %s: %v", name, err)
+				continue
+			}
+			if err := s.deliver(entry.ID, payload); err != nil {
+				s.logger.Error("Failed to replay payload %s from the outbox: %v", entry.ID, err)
+				continue
+			}
+			if err := s.outbox.complete(name); err != nil {
+				s.logger.Warn("%v", err)
+			}
+			delivered++
+		}
+		s.logger.Info("Replayed %d of %d payloads from the outbox", delivered, len(names))
+	}()
+	return nil
+}
+
+// dispatch records a payload in the outbox, delivers it, then removes it from the outbox. A
+// payload that fails to deliver stays in the outbox and is replayed on the next start. With
+// batching enabled, the messages of the payload's client batched before it are delivered
+// first.
+func (s *LaravelService) dispatch(payload interface{}) error {
+	if s.batchInterval > 0 {
+		s.awaitBatchedMessages(payloadClientID(payload))
+	}
+
+	id := s.payloadID(payload)
+	entry, err := s.outbox.record(id, payload)
+	if err != nil {
+		return err
+	}
+	if err := s.deliver(id, payload); err != nil {
+		return err
+	}
+	if err := s.outbox.complete(entry); err != nil {
+		s.logger.Warn("%v", err)
+	}
+	return nil
+}
+
+// deliver sends a payload to Laravel with the configured strategy. Each command, worker
+// round trip or callback request runs under the dispatch timeout, and a panic fails only its
+// payload. The http-callback strategy sends id as the delivery ID.
+func (s *LaravelService) deliver(id string, payload interface{}) error {
+	switch {
+	case s.workers != nil:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("error marshaling payload data: %w", err)
+		}
+		return s.guard.Run(func(context.Context) error {
+			return s.workers.dispatch(data)
+		})
+	case s.callback != nil:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("error marshaling payload data: %w", err)
+		}
+		return s.callback.post(s.guard, payloadAction(payload), id, data)
+	default:
+		payloadFile, err := s.createTempPayloadFileFromData(payload)
+		if err != nil {
+			return fmt.Errorf("error creating temp payload file: %w", err)
+		}
+		return s.guard.Run(func(ctx context.Context) error {
+			return s.executeLaravelCommand(ctx, payloadFile)
+		})
+	}
+}
+
+// payloadID returns the message ID of a dispatch payload, or a new ID for payloads without one
+func (s *LaravelService) payloadID(payload interface{}) string {
+	if fields, ok := payload.(map[string]interface{}); ok {
+		if id, ok := fields["message_id"].(string); ok && id != "" {
+			return id
+		}
+	}
+	return s.NewID()
+}
+
+// payloadClientID returns the ID of the client a dispatch payload is about
+func payloadClientID(payload interface{}) string {
+	if fields, ok := payload.(map[string]interface{}); ok {
+		if auth, ok := fields["auth"].(map[string]interface{}); ok {
+			if id, ok := auth["id"].(string); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// payloadAction returns the action of a dispatch payload, such as "client_connected"
+func payloadAction(payload interface{}) string {
+	if fields, ok := payload.(map[string]interface{}); ok {
+		if action, ok := fields["action"].(string); ok {
+			return action
+		}
+	}
+	return ""
+}
+
+// httpCallback posts payloads to an HTTP endpoint
+type httpCallback struct {
+	url     string
+	secret  string
+	retries int
+	client  *http.Client
+}
+
+// post delivers a payload, retrying transient failures with exponential backoff. Each request
+// runs under the guard.
+func (c *httpCallback) post(guard *SinkGuard, action, delivery string, data []byte) error {
+	backoff := callbackInitialBackoff
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		err := guard.Run(func(ctx context.Context) (err error) {
+			retryable, err = c.send(ctx, action, delivery, data)
+			return err
+		})
+		if errors.Is(err, ErrSinkTimeout) {
+			retryable = true
+		}
+		if err == nil || !retryable || attempt >= c.retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send makes one callback request, reporting whether a failure is worth retrying
+func (c *httpCallback) send(ctx context.Context, action, delivery string, data []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DispatchEventHeader, action)
+	req.Header.Set(DispatchDeliveryHeader, delivery)
+	if c.secret != "" {
+		req.Header.Set(DispatchSignatureHeader, auth.SignHMACSignature(c.secret, data))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("error calling callback: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, fmt.Errorf("callback returned %s", resp.Status)
+	}
+	return false, nil
+}
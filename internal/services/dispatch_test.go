@@ -0,0 +1,145 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"socket-server/internal/auth"
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+func TestHTTPCallbackDelivery(t *testing.T) {
+	type request struct {
+		event, delivery string
+		signed          bool
+	}
+	var requests []request
+	var statuses []int
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		signed := auth.VerifyHMACSignature("secret", r.Header.Get(DispatchSignatureHeader), body) == nil
+		requests = append(requests, request{r.Header.Get(DispatchEventHeader), r.Header.Get(DispatchDeliveryHeader), signed})
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	defer server.Close()
+
+	service := NewLaravelService("", "", "", t.TempDir(), logger.New(false))
+	service.SetIDGenerator(models.NewSequentialIDGenerator("msg"))
+	options := DispatchOptions{CallbackURL: server.URL, CallbackSecret: "secret", CallbackRetries: 2}
+	if err := service.SetDispatchStrategy(DispatchStrategyHTTP, options); err != nil {
+		t.Fatalf("Failed to set the dispatch strategy: %v", err)
+	}
+	client := models.NewClient("client-1", nil)
+	sent := func(code ...int) []request {
+		mutex.Lock()
+		requests, statuses = nil, code
+		mutex.Unlock()
+		service.DispatchConnection(client)
+		mutex.Lock()
+		defer mutex.Unlock()
+		return requests
+	}
+
+	if got := sent(); len(got) != 1 || got[0] != (request{"client_connected", "msg-1", true}) {
+		t.Errorf("Expected one signed request with the event and delivery ID, got %v", got)
+	}
+
+	got := sent(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	if len(got) != 3 || got[1].delivery != got[0].delivery || got[2].delivery != got[0].delivery {
+		t.Errorf("Expected 2 retries with the same delivery ID, got %v", got)
+	}
+
+	if got := sent(http.StatusUnprocessableEntity); len(got) != 1 {
+		t.Errorf("Expected a 4xx response not retried, got %v", got)
+	}
+	if err := service.DispatchConnection(client); err != nil {
+		t.Errorf("Expected a dispatch to succeed, got %v", err)
+	}
+	mutex.Lock()
+	statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+	mutex.Unlock()
+	if err := service.DispatchConnection(client); err == nil {
+		t.Error("Expected a dispatch failing after its retries to return an error")
+	}
+}
+
+// workerScript is a PHP worker stand-in: it answers each payload line by its contents, and
+// appends its process ID to a file for each payload it handles
+const workerScript = `#!/bin/sh
+while read -r line; do
+	echo $$ >> "$0.jobs"
+	case "$line" in
+	*rejected*) echo '{"ok": false, "error": "rejected"}' ;;
+	*crash*) exit 1 ;;
+	*slow*) exec sleep 5 ;;
+	*) echo "handling payload"; echo '{"ok": true}' ;;
+	esac
+done
+`
+
+func TestWorkerPoolDispatch(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "worker")
+	if err := os.WriteFile(script, []byte(workerScript), 0700); err != nil {
+		t.Fatalf("Failed to write the worker script: %v", err)
+	}
+	service := NewLaravelService(dir, script, "socket:dispatch", t.TempDir(), logger.New(false))
+	options := DispatchOptions{Workers: 1, WorkerMaxJobs: 3, Timeout: 200 * time.Millisecond}
+	if err := service.SetDispatchStrategy(DispatchStrategyWorkerPool, options); err != nil {
+		t.Fatalf("Failed to set the dispatch strategy: %v", err)
+	}
+	defer service.Close()
+
+	client := models.NewClient("client-1", nil)
+	dispatch := func(event string) error {
+		return service.DispatchMessage(models.Message{Channel: "chat", Event: event}, client)
+	}
+	workers := func() []string {
+		data, _ := os.ReadFile(script + ".jobs")
+		return strings.Fields(string(data))
+	}
+
+	if err := dispatch("sent"); err != nil {
+		t.Fatalf("Expected the payload handled, got %v", err)
+	}
+	if err := dispatch("rejected"); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("Expected the worker's error, got %v", err)
+	}
+	if err := dispatch("sent"); err != nil {
+		t.Errorf("Expected the payload handled, got %v", err)
+	}
+	// The worker reached its job limit and is replaced
+	if err := dispatch("sent"); err != nil {
+		t.Errorf("Expected the payload handled by a new worker, got %v", err)
+	}
+	if jobs := workers(); len(jobs) != 4 || jobs[0] != jobs[2] || jobs[2] == jobs[3] {
+		t.Errorf("Expected a worker kept after a rejected payload and recycled after 3 jobs, got %v", jobs)
+	}
+
+	if err := dispatch("crash"); err == nil {
+		t.Error("Expected an error for a worker that exited")
+	}
+	if err := dispatch("slow"); err == nil {
+		t.Error("Expected an error for a worker missing the timeout")
+	}
+	if err := dispatch("sent"); err != nil {
+		t.Errorf("Expected the payload handled by a replacement worker, got %v", err)
+	}
+	if jobs := workers(); len(jobs) != 7 || jobs[4] == jobs[5] || jobs[5] == jobs[6] {
+		t.Errorf("Expected crashed and timed out workers replaced, got %v", jobs)
+	}
+}
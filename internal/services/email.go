@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// defaultEmailTemplate is the plain-text body used when no template file is configured
+const defaultEmailTemplate = `{{if .Name}}Hi {{.Name}},{{else}}Hello,{{end}}
+
+While you were offline, you missed "{{.Event}}"{{if .Channel}} on {{.Channel}}{{end}} ({{.Timestamp.Format "2006-01-02 15:04 MST"}}):
+
+{{.DataJSON}}
+`
+
+// EmailSettings configures the SMTP server the email sink sends through
+type EmailSettings struct {
+	Host     string
+	Port     int
+	Username string // empty sends without authentication
+	Password string
+	From     string
+}
+
+// emailTemplateData is what email templates are rendered with
+type emailTemplateData struct {
+	UserID    string
+	Name      string
+	Email     string
+	Subject   string
+	Event     string
+	Channel   string
+	Data      interface{}
+	DataJSON  string // Data as indented JSON
+	Timestamp time.Time
+}
+
+// emailTemplate is implemented by both text and HTML templates
+type emailTemplate interface {
+	Execute(w io.Writer, data any) error
+}
+
+// EmailSink is a fallback sink sending critical broadcasts as email over SMTP. The server
+// upgrades to TLS with STARTTLS when it offers it.
+type EmailSink struct {
+	settings EmailSettings
+	from     *mail.Address
+	template emailTemplate
+	html     bool
+
+	// Clock and ID generator of timestamps and IDs; the system clock and models.NewID unless
+	// set otherwise
+	models.Timekeeper
+}
+
+// NewEmailSink creates an email sink rendering messages with templateFile, a Go template. Files
+// ending in .html are rendered as HTML email; an empty path uses a built-in plain-text template.
+func NewEmailSink(settings EmailSettings, templateFile string) (*EmailSink, error) {
+	from, err := mail.ParseAddress(settings.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", settings.From, err)
+	}
+
+	sink := &EmailSink{settings: settings, from: from}
+	if templateFile == "" {
+		sink.template = template.Must(template.New("email").Parse(defaultEmailTemplate))
+		return sink, nil
+	}
+
+	data, err := os.ReadFile(templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading email template: %w", err)
+	}
+	if strings.HasSuffix(strings.ToLower(templateFile), ".html") {
+		sink.template, err = htmltemplate.New("email").Parse(string(data))
+		sink.html = true
+	} else {
+		sink.template, err = template.New("email").Parse(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing email template: %w", err)
+	}
+	return sink, nil
+}
+
+// Name identifies the sink
+func (e *EmailSink) Name() string {
+	return "email"
+}
+
+// Deliver emails the message to the delivery's recipient
+func (e *EmailSink) Deliver(userID string, delivery models.CriticalDelivery, message models.Message) error {
+	subject := delivery.Subject
+	if subject == "" {
+		subject = message.Event
+	}
+
+	dataJSON, err := json.MarshalIndent(message.Data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding email data: %w", err)
+	}
+
+	var body bytes.Buffer
+	err = e.template.Execute(&body, emailTemplateData{
+		UserID:    userID,
+		Name:      delivery.Name,
+		Email:     delivery.Email,
+		Subject:   subject,
+		Event:     message.Event,
+		Channel:   message.Channel,
+		Data:      message.Data,
+		DataJSON:  string(dataJSON),
+		Timestamp: message.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("error rendering email template: %w", err)
+	}
+
+	to := &mail.Address{Name: delivery.Name, Address: delivery.Email}
+	contentType := "text/plain"
+	if e.html {
+		contentType = "text/html"
+	}
+
+	var email bytes.Buffer
+	fmt.Fprintf(&email, "From: %s\r\n", e.from)
+	fmt.Fprintf(&email, "To: %s\r\n", to)
+	fmt.Fprintf(&email, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&email, "Date: %s\r\n", e.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&email, "Message-ID: <%s@%s>\r\n", message.ID, e.settings.Host)
+	fmt.Fprintf(&email, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&email, "Content-Type: %s; charset=UTF-8\r\n", contentType)
+	fmt.Fprintf(&email, "Content-Transfer-Encoding: 8bit\r\n\r\n")
+	email.WriteString(strings.ReplaceAll(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if e.settings.Username != "" {
+		auth = smtp.PlainAuth("", e.settings.Username, e.settings.Password, e.settings.Host)
+	}
+	addr := net.JoinHostPort(e.settings.Host, strconv.Itoa(e.settings.Port))
+	if err := smtp.SendMail(addr, auth, e.from.Address, []string{delivery.Email}, email.Bytes()); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"socket-server/internal/models"
+)
+
+// GeoIPService resolves client IP addresses against a local MaxMind database
+// (GeoLite2/GeoIP2 City or Country). A nil service resolves nothing.
+type GeoIPService struct {
+	reader *geoip2.Reader
+}
+
+// NewGeoIPService opens a MaxMind database file
+func NewGeoIPService(databasePath string) (*GeoIPService, error) {
+	reader, err := geoip2.Open(databasePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening GeoIP database %s: %w", databasePath, err)
+	}
+	return &GeoIPService{reader: reader}, nil
+}
+
+// Lookup returns the location of an IP address, or nil when it cannot be resolved
+func (g *GeoIPService) Lookup(ip string) *models.GeoInfo {
+	if g == nil {
+		return nil
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+
+	record, err := g.reader.City(parsed)
+	if err != nil {
+		// Country databases don't support city lookups
+		country, err := g.reader.Country(parsed)
+		if err != nil || country.Country.IsoCode == "" {
+			return nil
+		}
+		return &models.GeoInfo{Country: country.Country.IsoCode}
+	}
+	if record.Country.IsoCode == "" {
+		return nil
+	}
+
+	geo := &models.GeoInfo{
+		Country: record.Country.IsoCode,
+		City:    record.City.Names["en"],
+	}
+	if len(record.Subdivisions) > 0 {
+		geo.Region = record.Subdivisions[0].IsoCode
+	}
+	return geo
+}
+
+// Close releases the database
+func (g *GeoIPService) Close() error {
+	if g == nil {
+		return nil
+	}
+	return g.reader.Close()
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// unhealthyAfterFailures is how many invocations in a row must fail before a sink is reported
+// unhealthy
+const unhealthyAfterFailures = 3
+
+// ErrSinkTimeout is returned for an invocation that didn't finish within its sink's timeout
+var ErrSinkTimeout = errors.New("sink timed out")
+
+// SinkHealth is the record of a sink's invocations
+type SinkHealth struct {
+	Name                string     `json:"name"`
+	Healthy             bool       `json:"healthy"`
+	Calls               uint64     `json:"calls_total"`
+	Failures            uint64     `json:"failures_total"`
+	Timeouts            uint64     `json:"timeouts_total"`
+	Panics              uint64     `json:"panics_total"`
+	InFlight            int        `json:"in_flight"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastDurationMS      float64    `json:"last_duration_ms"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// SinkGuard runs the invocations of a sink (Laravel dispatch, a webhook, an email server, ...)
+// each with its own timeout, recovering their panics, so a stuck or crashing sink fails its own
+// deliveries without holding up the caller. A nil guard runs invocations unguarded.
+type SinkGuard struct {
+	name    string
+	timeout time.Duration
+	health  SinkHealth
+	mutex   sync.Mutex
+}
+
+// NewSinkGuard creates the guard of a sink. Invocations running longer than timeout are
+// abandoned with ErrSinkTimeout (0 never abandons them).
+func NewSinkGuard(name string, timeout time.Duration) *SinkGuard {
+	return &SinkGuard{name: name, timeout: timeout, health: SinkHealth{Name: name, Healthy: true}}
+}
+
+// Run invokes the sink. The context is cancelled once the timeout passes, so invocations
+// that honor it stop; those that don't are left to finish in the background.
+func (g *SinkGuard) Run(invoke func(ctx context.Context) error) error {
+	if g == nil {
+		return invoke(context.Background())
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if g.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+	}
+	defer cancel()
+
+	g.mutex.Lock()
+	g.health.Calls++
+	g.health.InFlight++
+	g.mutex.Unlock()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	panicked := make(chan bool, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				panicked <- true
+				result <- fmt.Errorf("sink %s panicked: %v", g.name, recovered)
+			}
+		}()
+		result <- invoke(ctx)
+	}()
+
+	var err error
+	timedOut := false
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		// An invocation that returned right at the deadline still counts
+		select {
+		case err = <-result:
+		default:
+			timedOut = true
+			err = fmt.Errorf("%s: %w after %v", g.name, ErrSinkTimeout, g.timeout)
+		}
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.health.InFlight--
+	g.health.LastDurationMS = float64(time.Since(start).Microseconds()) / 1000
+	if err == nil {
+		g.health.ConsecutiveFailures = 0
+		g.health.Healthy = true
+		return nil
+	}
+	now := time.Now()
+	g.health.Failures++
+	g.health.ConsecutiveFailures++
+	g.health.Healthy = g.health.ConsecutiveFailures < unhealthyAfterFailures
+	g.health.LastError = err.Error()
+	g.health.LastFailureAt = &now
+	if timedOut {
+		g.health.Timeouts++
+	}
+	select {
+	case <-panicked:
+		g.health.Panics++
+	default:
+	}
+	return err
+}
+
+// Health returns the sink's record
+func (g *SinkGuard) Health() SinkHealth {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.health
+}
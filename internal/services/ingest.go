@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"socket-server/internal/auth"
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+// IngestService turns signed third-party webhooks into channel broadcasts, following the
+// mapping rules of each configured source. A nil service knows no sources.
+type IngestService struct {
+	sources map[string]config.IngestSource
+
+	// Clock and ID generator of timestamps and IDs; the system clock and models.NewID unless
+	// set otherwise
+	models.Timekeeper
+}
+
+// NewIngestService creates an ingest service for the given sources
+func NewIngestService(sources map[string]config.IngestSource) *IngestService {
+	return &IngestService{sources: sources}
+}
+
+// Transform verifies a webhook from source and returns the channel messages its rules produce.
+// Every matching rule produces a message; a webhook that matches no rule produces none.
+func (s *IngestService) Transform(source string, header http.Header, body []byte) ([]models.Message, error) {
+	if s == nil {
+		return nil, models.ErrUnknownIngestSource
+	}
+	definition, exists := s.sources[source]
+	if !exists {
+		return nil, models.ErrUnknownIngestSource
+	}
+
+	if err := verifyIngestSignature(definition, header, body); err != nil {
+		return nil, err
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("error parsing webhook body: %w", err)
+	}
+
+	lookup := func(path string) (interface{}, bool) {
+		if name, isHeader := strings.CutPrefix(path, "header."); isHeader {
+			value := header.Get(name)
+			return value, value != ""
+		}
+		return models.LookupPath(payload, path)
+	}
+
+	var messages []models.Message
+	for _, rule := range definition.Rules {
+		if !ingestRuleMatches(rule, lookup) {
+			continue
+		}
+
+		channel, channelOK := models.RenderPathTemplate(rule.Channel, lookup)
+		event, eventOK := models.RenderPathTemplate(rule.Event, lookup)
+		data, dataOK := models.LookupPath(payload, rule.Data)
+		if !channelOK || !eventOK || !dataOK || channel == "" || event == "" {
+			continue
+		}
+
+		messages = append(messages, models.Message{
+			ID:        s.NewID(),
+			Channel:   channel,
+			Event:     event,
+			Data:      data,
+			Timestamp: s.Now(),
+		})
+	}
+	return messages, nil
+}
+
+// verifyIngestSignature checks the webhook signature header of the source's type
+func verifyIngestSignature(source config.IngestSource, header http.Header, body []byte) error {
+	switch source.Type {
+	case config.IngestTypeStripe:
+		return auth.VerifyStripeSignature(source.Secret, header.Get("Stripe-Signature"), body, time.Now(), auth.StripeSignatureTolerance)
+	case config.IngestTypeGitHub:
+		return auth.VerifyHMACSignature(source.Secret, header.Get("X-Hub-Signature-256"), body)
+	default:
+		return auth.VerifyHMACSignature(source.Secret, header.Get("X-Signature-256"), body)
+	}
+}
+
+// ingestRuleMatches reports whether every match condition of a rule holds
+func ingestRuleMatches(rule config.IngestRule, lookup func(path string) (interface{}, bool)) bool {
+	for path, expected := range rule.Match {
+		value, ok := lookup(path)
+		if !ok || fmt.Sprint(value) != expected {
+			return false
+		}
+	}
+	return true
+}
@@ -1,15 +1,17 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
 
 	"socket-server/internal/models"
 	"socket-server/pkg/logger"
@@ -22,6 +24,36 @@ type LaravelService struct {
 	laravelCmd string
 	tempDir    string
 	logger     *logger.Logger
+
+	// Deferred dispatch batching (disabled when batchInterval is zero). batchClients are the
+	// clients with messages in the pending batch, clientFlights the batch being delivered with
+	// the latest messages of each client, and lastFlight the batch taken last.
+	batchInterval time.Duration
+	batchSize     int
+	batch         []map[string]interface{}
+	batchClients  map[string]bool
+	clientFlights map[string]*batchFlight
+	lastFlight    *batchFlight
+	batchMutex    sync.Mutex
+
+	// Payload file compression (see compression.go; disabled when zstdEncoder is nil)
+	zstdEncoder     *zstd.Encoder
+	compressMinSize int
+
+	// Dispatch strategy (see dispatch.go); payloads go through payload files and artisan
+	// when both are nil. Every dispatch runs under guard.
+	workers  *workerPool
+	callback *httpCallback
+	guard    *SinkGuard
+
+	// Payloads are recorded in the outbox before they are dispatched (see outbox.go; disabled
+	// when nil). batchEntries are the outbox entries of the batched messages.
+	outbox       *Outbox
+	batchEntries []string
+
+	// Clock and ID generator of timestamps and IDs; the system clock and models.NewID unless
+	// set otherwise
+	models.Timekeeper
 }
 
 // NewLaravelService creates a new Laravel service
@@ -32,6 +64,7 @@ func NewLaravelService(workingDir, phpBinary, laravelCmd, tempDir string, logger
 		laravelCmd: laravelCmd,
 		tempDir:    tempDir,
 		logger:     logger,
+		guard:      NewSinkGuard("laravel", defaultDispatchTimeout),
 	}
 }
 
@@ -52,65 +85,255 @@ func (s *LaravelService) InitializeTempDirectory() error {
 
 // DispatchMessage sends a client message to Laravel for processing
 func (s *LaravelService) DispatchMessage(message models.Message, client *models.Client) error {
-	payloadFile, err := s.createTempPayloadFile(message, client)
+	return s.dispatch(s.buildMessagePayload(message, client))
+}
+
+// QueueMessage dispatches a client message to Laravel, deferring it into the next batch when
+// batching is enabled. Messages are flushed in arrival order, and a client's other payloads are
+// dispatched after its batched messages were delivered, so Laravel receives its join, leave and
+// disconnection events after the messages it sent before them.
+func (s *LaravelService) QueueMessage(message models.Message, client *models.Client) error {
+	if s.batchInterval <= 0 {
+		return s.DispatchMessage(message, client)
+	}
+
+	payload := s.buildMessagePayload(message, client)
+	entry, err := s.outbox.record(s.payloadID(payload), payload)
 	if err != nil {
-		return fmt.Errorf("error creating temp payload file: %w", err)
+		return err
+	}
+
+	s.batchMutex.Lock()
+	s.batch = append(s.batch, payload)
+	if s.batchClients == nil {
+		s.batchClients = make(map[string]bool)
+	}
+	s.batchClients[client.ID] = true
+	if entry != "" {
+		s.batchEntries = append(s.batchEntries, entry)
+	}
+	full := len(s.batch) >= s.batchSize
+	s.batchMutex.Unlock()
+
+	if full {
+		go s.FlushBatch()
+	}
+	return nil
+}
+
+// StartBatching enables deferred dispatch: queued messages are delivered to Laravel as a single
+// payload every interval, or as soon as maxSize messages are pending
+func (s *LaravelService) StartBatching(interval time.Duration, maxSize int) {
+	if interval <= 0 {
+		return
+	}
+	if maxSize <= 0 {
+		maxSize = 1
+	}
+
+	s.batchInterval = interval
+	s.batchSize = maxSize
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.FlushBatch()
+		}
+	}()
+
+	s.logger.Info("Started Laravel dispatch batching (every %v or %d messages)", interval, maxSize)
+}
+
+// FlushBatch delivers all pending batched messages to Laravel in a single command invocation.
+// The messages were recorded in the outbox as they were queued, so a batch that fails to
+// deliver is replayed message by message on the next start.
+func (s *LaravelService) FlushBatch() error {
+	s.batchMutex.Lock()
+	flight := s.takeBatchLocked()
+	s.batchMutex.Unlock()
+
+	if flight == nil {
+		return nil
+	}
+	return s.deliverBatch(flight)
+}
+
+// batchFlight is a batch taken off the pending messages to be delivered
+type batchFlight struct {
+	messages []map[string]interface{}
+	entries  []string
+	clients  map[string]bool
+	previous *batchFlight // the batch taken before, delivered first
+	done     chan struct{}
+}
+
+// takeBatchLocked takes the pending messages as a batch to deliver, nil when there are none.
+// The caller must hold s.batchMutex.
+func (s *LaravelService) takeBatchLocked() *batchFlight {
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	flight := &batchFlight{
+		messages: s.batch,
+		entries:  s.batchEntries,
+		clients:  s.batchClients,
+		previous: s.lastFlight,
+		done:     make(chan struct{}),
+	}
+	s.batch, s.batchEntries, s.batchClients = nil, nil, nil
+	s.lastFlight = flight
+	if s.clientFlights == nil {
+		s.clientFlights = make(map[string]*batchFlight)
 	}
+	for clientID := range flight.clients {
+		s.clientFlights[clientID] = flight
+	}
+	return flight
+}
 
-	return s.executeLaravelCommand(payloadFile)
+// awaitBatchedMessages returns once the messages the client queued so far were delivered: the
+// pending batch is delivered when it holds some, or the batch being delivered with them is
+// waited for. Other clients' dispatches don't wait.
+func (s *LaravelService) awaitBatchedMessages(clientID string) {
+	s.batchMutex.Lock()
+	var taken, inFlight *batchFlight
+	if s.batchClients[clientID] {
+		taken = s.takeBatchLocked()
+	} else {
+		inFlight = s.clientFlights[clientID]
+	}
+	s.batchMutex.Unlock()
+
+	if taken != nil {
+		// A failed batch stays in the outbox for the next start, like a failed payload
+		s.deliverBatch(taken)
+	} else if inFlight != nil {
+		<-inFlight.done
+	}
+}
+
+// deliverBatch delivers a batch once the batch taken before it was, so batches reach Laravel in
+// the order they were filled
+func (s *LaravelService) deliverBatch(flight *batchFlight) error {
+	if flight.previous != nil {
+		<-flight.previous.done
+		flight.previous = nil
+	}
+	defer func() {
+		s.batchMutex.Lock()
+		for clientID := range flight.clients {
+			if s.clientFlights[clientID] == flight {
+				delete(s.clientFlights, clientID)
+			}
+		}
+		s.batchMutex.Unlock()
+		close(flight.done)
+	}()
+
+	pending, entries := flight.messages, flight.entries
+	batchPayload := map[string]interface{}{
+		"message_id": s.NewID(),
+		"timestamp":  s.Now().Format(time.RFC3339),
+		"action":     "batch",
+		"count":      len(pending),
+		"messages":   pending,
+	}
+
+	s.logger.Debug("Flushing %d batched messages to Laravel", len(pending))
+	if err := s.deliver(batchPayload["message_id"].(string), batchPayload); err != nil {
+		s.logger.Error("Failed to dispatch batch of %d messages: %v", len(pending), err)
+		return err
+	}
+	if err := s.outbox.complete(entries...); err != nil {
+		s.logger.Warn("%v", err)
+	}
+	return nil
 }
 
 // DispatchAuthentication sends authentication events to Laravel
 func (s *LaravelService) DispatchAuthentication(client *models.Client, status string, token string) error {
 	// Create standardized authentication payload
 	standardizedPayload := map[string]interface{}{
-		"message_id": uuid.New().String(),
-		"timestamp":  time.Now().Format(time.RFC3339),
+		"message_id": s.NewID(),
+		"timestamp":  s.Now().Format(time.RFC3339),
 		"action":     "client_authentication",
-		"auth": map[string]interface{}{
-			"user_id":     client.UserID,
-			"user_email":  client.Email,
-			"logged_at":   time.Now().Format(time.RFC3339),
-			"id":          client.ID,
-			"username":    client.Username,
-			"remote_addr": client.RemoteAddr,
-		},
+		"auth":       s.clientAuthPayload(client),
 		"data": map[string]interface{}{
 			"authentication_status": status,
 			"token_provided":        token != "",
 		},
 	}
 
-	payloadFile, err := s.createTempPayloadFileFromData(standardizedPayload)
-	if err != nil {
-		return fmt.Errorf("error creating temp authentication payload file: %w", err)
+	return s.dispatch(standardizedPayload)
+}
+
+// DispatchConnection notifies Laravel that a client connected
+func (s *LaravelService) DispatchConnection(client *models.Client) error {
+	standardizedPayload := map[string]interface{}{
+		"message_id": s.NewID(),
+		"timestamp":  s.Now().Format(time.RFC3339),
+		"action":     "client_connected",
+		"auth":       s.clientAuthPayload(client),
+		"data": map[string]interface{}{
+			"connected_at": client.ConnectedAt.Format(time.RFC3339),
+			"remote_addr":  client.RemoteAddr,
+			"user_agent":   client.UserAgent,
+			"device":       client.Device,
+		},
 	}
 
-	return s.executeLaravelCommand(payloadFile)
+	return s.dispatch(standardizedPayload)
 }
 
-// createTempPayloadFile creates a temporary file with message data
-func (s *LaravelService) createTempPayloadFile(message models.Message, client *models.Client) (string, error) {
-	// Create standardized message payload
+// DispatchDisconnection notifies Laravel that a client disconnected, including how long it was
+// connected, the channels it had joined and why the connection ended
+func (s *LaravelService) DispatchDisconnection(client *models.Client, channels []string, reason string) error {
+	disconnectedAt := s.Now()
+
 	standardizedPayload := map[string]interface{}{
-		"message_id": uuid.New().String(),
-		"timestamp":  time.Now().Format(time.RFC3339),
+		"message_id": s.NewID(),
+		"timestamp":  disconnectedAt.Format(time.RFC3339),
+		"action":     "client_disconnected",
+		"auth":       s.clientAuthPayload(client),
+		"data": map[string]interface{}{
+			"connected_at":     client.ConnectedAt.Format(time.RFC3339),
+			"disconnected_at":  disconnectedAt.Format(time.RFC3339),
+			"duration_seconds": int64(disconnectedAt.Sub(client.ConnectedAt).Seconds()),
+			"channels":         channels,
+			"reason":           reason,
+		},
+	}
+
+	return s.dispatch(standardizedPayload)
+}
+
+// clientAuthPayload builds the "auth" section shared by all payloads sent to Laravel
+func (s *LaravelService) clientAuthPayload(client *models.Client) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":     client.UserID,
+		"user_email":  client.Email,
+		"logged_at":   s.Now().Format(time.RFC3339),
+		"id":          client.ID,
+		"username":    client.Username,
+		"remote_addr": client.RemoteAddr,
+	}
+}
+
+// buildMessagePayload creates the standardized payload for a client message
+func (s *LaravelService) buildMessagePayload(message models.Message, client *models.Client) map[string]interface{} {
+	return map[string]interface{}{
+		"message_id": s.NewID(),
+		"timestamp":  s.Now().Format(time.RFC3339),
 		"action":     message.Event,
 		"id":         message.ID,
 		"channel":    message.Channel,
 		"private":    message.Private,
-		"auth": map[string]interface{}{
-			"user_id":     client.UserID,
-			"user_email":  client.Email,
-			"logged_at":   time.Now().Format(time.RFC3339),
-			"id":          client.ID,
-			"username":    client.Username,
-			"remote_addr": client.RemoteAddr,
-		},
-		"data": message.Data,
+		"auth":       s.clientAuthPayload(client),
+		"data":       message.Data,
 	}
-
-	return s.createTempPayloadFileFromData(standardizedPayload)
 }
 
 // createTempPayloadFileFromData creates a temporary file with the given data
@@ -121,13 +344,16 @@ func (s *LaravelService) createTempPayloadFileFromData(data interface{}) (string
 		return "", fmt.Errorf("error marshaling payload data: %w", err)
 	}
 
+	// Large payloads are compressed when enabled; the extension tells Laravel how to read them
+	fileData, extension := s.compressPayload(jsonData)
+
 	// Create filename with timestamp for expiration tracking
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("payload_%d_%s.json", timestamp, uuid.New().String()[:8])
+	timestamp := s.Now().Unix()
+	filename := fmt.Sprintf("payload_%d_%s%s", timestamp, s.NewID(), extension)
 	filepath := filepath.Join(s.tempDir, filename)
 
 	// Write file with permissions readable by Laravel (0644)
-	if err := os.WriteFile(filepath, jsonData, 0644); err != nil {
+	if err := os.WriteFile(filepath, fileData, 0644); err != nil {
 		return "", fmt.Errorf("error writing payload file: %w", err)
 	}
 
@@ -136,12 +362,14 @@ func (s *LaravelService) createTempPayloadFileFromData(data interface{}) (string
 }
 
 // executeLaravelCommand executes the Laravel artisan command with payload file
-func (s *LaravelService) executeLaravelCommand(payloadFile string) error {
+func (s *LaravelService) executeLaravelCommand(ctx context.Context, payloadFile string) error {
 	cmdString := fmt.Sprintf("%s artisan %s --payload %s", s.phpBinary, s.laravelCmd, payloadFile)
 	s.logger.LaravelCommand(cmdString)
 
-	cmd := exec.Command(s.phpBinary, "artisan", s.laravelCmd, "--payload", payloadFile)
+	// The command is killed once the dispatch times out
+	cmd := exec.CommandContext(ctx, s.phpBinary, "artisan", s.laravelCmd, "--payload", payloadFile)
 	cmd.Dir = s.workingDir
+	cmd.WaitDelay = time.Second
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -188,7 +416,7 @@ func (s *LaravelService) cleanupExpiredFiles() {
 		}
 
 		// Check if file follows our naming pattern
-		if !strings.HasPrefix(file.Name(), "payload_") || !strings.HasSuffix(file.Name(), ".json") {
+		if !strings.HasPrefix(file.Name(), "payload_") || (!strings.HasSuffix(file.Name(), ".json") && !strings.HasSuffix(file.Name(), ".json.zst")) {
 			continue
 		}
 
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+// laravelScript is a PHP stand-in: it appends each payload to a file. A batch first creates a
+// file telling it arrived, then waits while a hold file exists.
+const laravelScript = `#!/bin/sh
+if grep -q '^{"action":"batch"' "$4"; then
+	touch "$0.batch"
+	while [ -e "$0.hold" ]; do sleep 0.01; done
+fi
+cat "$4" >> "$0.payloads"
+echo >> "$0.payloads"
+`
+
+// newScriptService returns a Laravel service running laravelScript, the script's path, and the
+// payloads the script received, in order
+func newScriptService(t *testing.T) (*LaravelService, string, func() []map[string]interface{}) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "php")
+	if err := os.WriteFile(script, []byte(laravelScript), 0700); err != nil {
+		t.Fatalf("Failed to write the Laravel script: %v", err)
+	}
+
+	service := NewLaravelService(dir, script, "socket:handle", t.TempDir(), logger.New(false))
+	return service, script, func() []map[string]interface{} {
+		data, _ := os.ReadFile(script + ".payloads")
+		var payloads []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var payload map[string]interface{}
+			if json.Unmarshal([]byte(line), &payload) == nil {
+				payloads = append(payloads, payload)
+			}
+		}
+		return payloads
+	}
+}
+
+func TestBatchedMessagesPrecedeLaterDispatches(t *testing.T) {
+	service, _, received := newScriptService(t)
+	service.StartBatching(time.Hour, 100)
+	client := models.NewClient("client-1", nil)
+
+	service.QueueMessage(models.Message{Channel: "chat", Event: "typed"}, client)
+	service.QueueMessage(models.Message{Channel: "chat", Event: "sent"}, client)
+	if payloads := received(); len(payloads) != 0 {
+		t.Fatalf("Expected queued messages to wait for the batch, got %v", payloads)
+	}
+
+	if err := service.DispatchMessage(models.Message{Channel: "chat", Event: "leave"}, client); err != nil {
+		t.Fatalf("Failed to dispatch the leave: %v", err)
+	}
+	payloads := received()
+	if len(payloads) != 2 || payloads[0]["action"] != "batch" || payloads[1]["action"] != "leave" {
+		t.Fatalf("Expected the batch, then the leave, got %v", payloads)
+	}
+	messages, _ := payloads[0]["messages"].([]interface{})
+	if len(messages) != 2 || messages[0].(map[string]interface{})["action"] != "typed" || messages[1].(map[string]interface{})["action"] != "sent" {
+		t.Errorf("Expected the batch to hold the messages in the order they were sent, got %v", messages)
+	}
+
+	if err := service.FlushBatch(); err != nil || len(received()) != 2 {
+		t.Errorf("Expected nothing left to flush, got %v and %d payloads", err, len(received()))
+	}
+}
+
+func TestUnbatchedMessagesDispatchImmediately(t *testing.T) {
+	service, _, received := newScriptService(t)
+	client := models.NewClient("client-1", nil)
+
+	service.QueueMessage(models.Message{Channel: "chat", Event: "sent"}, client)
+	service.DispatchDisconnection(client, []string{"chat"}, "closed")
+	payloads := received()
+	if len(payloads) != 2 || payloads[0]["action"] != "sent" || payloads[1]["action"] != "client_disconnected" {
+		t.Errorf("Expected the message, then the disconnection, got %v", payloads)
+	}
+}
+
+func TestDispatchesOnlyWaitForTheirClientsBatchedMessages(t *testing.T) {
+	service, script, received := newScriptService(t)
+	service.StartBatching(time.Hour, 100)
+	alice, bob := models.NewClient("alice", nil), models.NewClient("bob", nil)
+
+	// The batch holding Alice's message is held by Laravel until the hold file is removed
+	os.WriteFile(script+".hold", nil, 0600)
+	release := func() { os.Remove(script + ".hold") }
+	defer release()
+	service.QueueMessage(models.Message{Channel: "chat", Event: "typed"}, alice)
+	flushed := make(chan error, 1)
+	go func() { flushed <- service.FlushBatch() }()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(script + ".batch"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the batch to reach Laravel")
+		}
+	}
+
+	// Bob has no batched message, so the batch being delivered doesn't hold him up
+	bobDone := make(chan error, 1)
+	go func() {
+		bobDone <- service.DispatchMessage(models.Message{Channel: "chat", Event: "join_channel"}, bob)
+	}()
+	select {
+	case err := <-bobDone:
+		if err != nil {
+			t.Fatalf("Failed to dispatch Bob's join: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected another client's dispatch not to wait for the batch")
+	}
+
+	// Alice's leave waits for the batch holding her message
+	aliceDone := make(chan error, 1)
+	go func() {
+		aliceDone <- service.DispatchMessage(models.Message{Channel: "chat", Event: "leave_channel"}, alice)
+	}()
+	release()
+	if err := <-flushed; err != nil {
+		t.Fatalf("Failed to flush the batch: %v", err)
+	}
+	if err := <-aliceDone; err != nil {
+		t.Fatalf("Failed to dispatch Alice's leave: %v", err)
+	}
+
+	var actions []string
+	for _, payload := range received() {
+		actions = append(actions, payload["action"].(string))
+	}
+	if expected := []string{"join_channel", "batch", "leave_channel"}; !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Expected %v, got %v", expected, actions)
+	}
+}
+
+func TestPayloadsUseTheServiceClockAndIDs(t *testing.T) {
+	service, _, received := newScriptService(t)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.SetClock(models.NewManualClock(start).Now)
+	service.SetIDGenerator(models.NewSequentialIDGenerator("msg"))
+
+	if err := service.DispatchConnection(models.NewClient("client-1", nil)); err != nil {
+		t.Fatalf("Failed to dispatch the connection: %v", err)
+	}
+	payloads := received()
+	if len(payloads) != 1 {
+		t.Fatalf("Expected one payload, got %v", payloads)
+	}
+	if payloads[0]["message_id"] != "msg-1" || payloads[0]["timestamp"] != "2026-01-02T03:04:05Z" {
+		t.Errorf("Expected the injected ID and time, got %v and %v", payloads[0]["message_id"], payloads[0]["timestamp"])
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// outboxEntry is a payload recorded in the outbox, kept until it reached Laravel
+type outboxEntry struct {
+	ID         string          `json:"id"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Outbox records dispatch payloads on disk before they are delivered, and removes them once
+// delivered, so payloads in flight when the server stops are delivered on the next start. Each
+// payload is a file named after the time it was recorded, so entries replay in order. A nil
+// outbox records nothing.
+type Outbox struct {
+	dir      string
+	sequence atomic.Uint64
+	pending  atomic.Int64
+
+	// Clock and ID generator of timestamps and IDs; the system clock and models.NewID unless
+	// set otherwise
+	models.Timekeeper
+}
+
+// NewOutbox opens the outbox in dir, creating the directory when needed
+func NewOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating outbox directory %s: %w", dir, err)
+	}
+	outbox := &Outbox{dir: dir}
+	names, err := outbox.entries()
+	if err != nil {
+		return nil, err
+	}
+	outbox.pending.Store(int64(len(names)))
+	return outbox, nil
+}
+
+// Pending returns the number of payloads recorded and not delivered yet
+func (o *Outbox) Pending() int64 {
+	if o == nil {
+		return 0
+	}
+	return o.pending.Load()
+}
+
+// record persists a payload before its delivery, returning the name of its entry. The entry
+// is synced to disk, so it survives a crash of the machine too.
+func (o *Outbox) record(id string, payload interface{}) (string, error) {
+	if o == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling payload data: %w", err)
+	}
+	data, err = json.Marshal(outboxEntry{ID: id, RecordedAt: o.Now(), Payload: data})
+	if err != nil {
+		return "", fmt.Errorf("error marshaling outbox entry: %w", err)
+	}
+
+	// Entries are named after the time they were recorded and a sequence, so they replay in order
+	name := fmt.Sprintf("%019d-%06d.json", o.Now().UnixNano(), o.sequence.Add(1)%1000000)
+	tmpFile := filepath.Join(o.dir, name+".tmp")
+	file, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("error creating outbox entry: %w", err)
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile, filepath.Join(o.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		return "", fmt.Errorf("error writing outbox entry: %w", err)
+	}
+	o.pending.Add(1)
+	return name, nil
+}
+
+// complete removes the entries of delivered payloads
+func (o *Outbox) complete(names ...string) error {
+	if o == nil {
+		return nil
+	}
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(o.dir, name)); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("error removing outbox entry %s: %w", name, err)
+		}
+		o.pending.Add(-1)
+	}
+	return nil
+}
+
+// entries returns the names of the recorded entries, oldest first. Entries left half-written
+// by a crash are removed: their payload was never dispatched.
+func (o *Outbox) entries() ([]string, error) {
+	files, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading outbox directory %s: %w", o.dir, err)
+	}
+	var names []string
+	for _, file := range files {
+		switch name := file.Name(); {
+		case file.IsDir():
+		case strings.HasSuffix(name, ".json.tmp"):
+			os.Remove(filepath.Join(o.dir, name))
+		case strings.HasSuffix(name, ".json"):
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load reads a recorded entry
+func (o *Outbox) load(name string) (*outboxEntry, error) {
+	data, err := os.ReadFile(filepath.Join(o.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var entry outboxEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("error decoding outbox entry %s: %w", name, err)
+	}
+	return &entry, nil
+}
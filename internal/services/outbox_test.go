@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+func TestOutboxRecordAndComplete(t *testing.T) {
+	dir := t.TempDir()
+	outbox, err := NewOutbox(dir)
+	if err != nil {
+		t.Fatalf("Failed to open the outbox: %v", err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	outbox.SetClock(models.NewManualClock(start).Now)
+
+	first, err := outbox.record("msg-1", map[string]interface{}{"action": "first"})
+	if err != nil {
+		t.Fatalf("Failed to record a payload: %v", err)
+	}
+	second, _ := outbox.record("msg-2", map[string]interface{}{"action": "second"})
+	if outbox.Pending() != 2 {
+		t.Errorf("Expected 2 pending payloads, got %d", outbox.Pending())
+	}
+
+	entry, err := outbox.load(first)
+	if err != nil {
+		t.Fatalf("Failed to load an entry: %v", err)
+	}
+	if entry.ID != "msg-1" || !entry.RecordedAt.Equal(start) || string(entry.Payload) != `{"action":"first"}` {
+		t.Errorf("Expected the recorded ID, time and payload, got %s, %v and %s", entry.ID, entry.RecordedAt, entry.Payload)
+	}
+
+	if err := outbox.complete(first); err != nil {
+		t.Fatalf("Failed to complete an entry: %v", err)
+	}
+	if err := outbox.complete(first); err != nil || outbox.Pending() != 1 {
+		t.Errorf("Expected completing an entry twice to be harmless, got %v and %d pending", err, outbox.Pending())
+	}
+
+	// A crash while recording leaves a temporary file, which isn't an entry
+	os.WriteFile(filepath.Join(dir, "0000000000000000001-000001.json.tmp"), []byte("{"), 0600)
+	reopened, err := NewOutbox(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen the outbox: %v", err)
+	}
+	if names, _ := reopened.entries(); !reflect.DeepEqual(names, []string{second}) || reopened.Pending() != 1 {
+		t.Errorf("Expected only the undelivered entry after reopening, got %v and %d pending", names, reopened.Pending())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0000000000000000001-000001.json.tmp")); !os.IsNotExist(err) {
+		t.Error("Expected the half-written entry removed")
+	}
+
+	var disabled *Outbox
+	if name, err := disabled.record("msg-3", nil); name != "" || err != nil || disabled.Pending() != 0 {
+		t.Errorf("Expected a nil outbox to record nothing, got %q and %v", name, err)
+	}
+}
+
+func TestOutboxReplaysInOrderAfterRestart(t *testing.T) {
+	type delivery struct{ action, id string }
+	var received []delivery
+	var failing = true
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		action, _ := payload["action"].(string)
+		mutex.Lock()
+		defer mutex.Unlock()
+		received = append(received, delivery{action, r.Header.Get(DispatchDeliveryHeader)})
+		if failing {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	newService := func() *LaravelService {
+		service := NewLaravelService("", "", "", t.TempDir(), logger.New(false))
+		if err := service.SetDispatchStrategy(DispatchStrategyHTTP, DispatchOptions{CallbackURL: server.URL}); err != nil {
+			t.Fatalf("Failed to set the dispatch strategy: %v", err)
+		}
+		outbox, err := NewOutbox(dir)
+		if err != nil {
+			t.Fatalf("Failed to open the outbox: %v", err)
+		}
+		service.SetOutbox(outbox)
+		return service
+	}
+
+	service := newService()
+	client := models.NewClient("client-1", nil)
+	service.DispatchConnection(client)
+	service.DispatchMessage(models.Message{Channel: "chat", Event: "sent"}, client)
+	service.DispatchDisconnection(client, []string{"chat"}, "client_closed")
+	if service.OutboxPending() != 3 {
+		t.Fatalf("Expected the 3 failed payloads kept in the outbox, got %d", service.OutboxPending())
+	}
+
+	mutex.Lock()
+	failed := append([]delivery{}, received...)
+	received = nil
+	failing = false
+	mutex.Unlock()
+
+	restarted := newService()
+	if restarted.OutboxPending() != 3 {
+		t.Fatalf("Expected the outbox to hold 3 payloads after the restart, got %d", restarted.OutboxPending())
+	}
+	if err := restarted.ReplayOutbox(); err != nil {
+		t.Fatalf("Failed to replay the outbox: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for restarted.OutboxPending() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the outbox replayed, %d payloads pending", restarted.OutboxPending())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mutex.Lock()
+	replayed := append([]delivery{}, received...)
+	mutex.Unlock()
+	if !reflect.DeepEqual(replayed, failed) {
+		t.Errorf("Expected the payloads replayed in order with their delivery IDs %v, got %v", failed, replayed)
+	}
+
+	// A delivered payload leaves nothing behind
+	if err := restarted.DispatchConnection(client); err != nil || restarted.OutboxPending() != 0 {
+		t.Errorf("Expected a delivered payload removed from the outbox, got %v and %d pending", err, restarted.OutboxPending())
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+	"socket-server/pkg/logger"
+)
+
+// Default provider API base URLs, overridable per rule
+const (
+	cloudflareAPI = "https://api.cloudflare.com/client/v4"
+	fastlyAPI     = "https://api.fastly.com"
+)
+
+const purgeRequestTimeout = 10 * time.Second
+
+// PurgeService purges CDN caches for broadcasts matching the configured rules, so a single
+// broadcast call refreshes both connected clients and cached pages. A nil service purges
+// nothing.
+type PurgeService struct {
+	rules  []config.PurgeRule
+	client *http.Client
+	logger *logger.Logger
+}
+
+// NewPurgeService creates a purge service for the given rules
+func NewPurgeService(rules []config.PurgeRule, logger *logger.Logger) *PurgeService {
+	return &PurgeService{
+		rules:  rules,
+		client: &http.Client{Timeout: purgeRequestTimeout},
+		logger: logger,
+	}
+}
+
+// Trigger purges the URLs and tags of every rule matching the message. Purges run in the
+// background and failures are logged, so they never delay or fail the broadcast itself.
+func (p *PurgeService) Trigger(message models.Message) {
+	if p == nil {
+		return
+	}
+
+	document := map[string]interface{}{
+		"channel": message.Channel,
+		"event":   message.Event,
+		"data":    message.Data,
+	}
+	lookup := func(path string) (interface{}, bool) {
+		return models.LookupPath(document, path)
+	}
+
+	for _, rule := range p.rules {
+		if !matchesAny(rule.Events, message.Event) || (len(rule.Channels) > 0 && !matchesAny(rule.Channels, message.Channel)) {
+			continue
+		}
+
+		urls := renderPurgeTemplates(rule.URLs, lookup)
+		tags := renderPurgeTemplates(rule.Tags, lookup)
+		if len(urls) == 0 && len(tags) == 0 {
+			p.logger.Warn("CDN purge: no URL or tag of the %s rule for event %s could be resolved", rule.Provider, message.Event)
+			continue
+		}
+
+		go func(rule config.PurgeRule) {
+			if err := p.purge(rule, urls, tags); err != nil {
+				p.logger.Error("CDN purge via %s for event %s failed: %v", rule.Provider, message.Event, err)
+				return
+			}
+			p.logger.Info("CDN purge via %s for event %s: %d URLs, %d tags", rule.Provider, message.Event, len(urls), len(tags))
+		}(rule)
+	}
+}
+
+// purge sends the provider's purge requests
+func (p *PurgeService) purge(rule config.PurgeRule, urls, tags []string) error {
+	switch rule.Provider {
+	case config.PurgeProviderCloudflare:
+		return p.purgeCloudflare(rule, urls, tags)
+	case config.PurgeProviderFastly:
+		return p.purgeFastly(rule, urls, tags)
+	default:
+		return fmt.Errorf("unknown provider %q", rule.Provider)
+	}
+}
+
+// purgeCloudflare purges files and cache tags; Cloudflare takes them in separate requests
+func (p *PurgeService) purgeCloudflare(rule config.PurgeRule, urls, tags []string) error {
+	endpoint := apiBase(rule, cloudflareAPI) + "/zones/" + url.PathEscape(rule.ZoneID) + "/purge_cache"
+	header := http.Header{"Authorization": {"Bearer " + rule.Token}, "Content-Type": {"application/json"}}
+
+	if len(urls) > 0 {
+		body, _ := json.Marshal(map[string][]string{"files": urls})
+		if err := p.send(endpoint, header, body); err != nil {
+			return err
+		}
+	}
+	if len(tags) > 0 {
+		body, _ := json.Marshal(map[string][]string{"tags": tags})
+		if err := p.send(endpoint, header, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeFastly purges each URL individually and all surrogate keys in one request
+func (p *PurgeService) purgeFastly(rule config.PurgeRule, urls, tags []string) error {
+	base := apiBase(rule, fastlyAPI)
+	header := http.Header{"Fastly-Key": {rule.Token}}
+
+	for _, rawURL := range urls {
+		target := strings.TrimPrefix(strings.TrimPrefix(rawURL, "https://"), "http://")
+		if err := p.send(base+"/purge/"+target, header, nil); err != nil {
+			return err
+		}
+	}
+	if len(tags) > 0 {
+		keyHeader := header.Clone()
+		keyHeader.Set("Surrogate-Key", strings.Join(tags, " "))
+		if err := p.send(base+"/service/"+url.PathEscape(rule.ServiceID)+"/purge", keyHeader, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send posts one purge request and checks the response status
+func (p *PurgeService) send(endpoint string, header http.Header, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// apiBase returns the rule's endpoint override or the provider default
+func apiBase(rule config.PurgeRule, defaultBase string) string {
+	if rule.Endpoint != "" {
+		return strings.TrimRight(rule.Endpoint, "/")
+	}
+	return defaultBase
+}
+
+// renderPurgeTemplates renders URL or tag templates, skipping those that can't be resolved
+func renderPurgeTemplates(templates []string, lookup func(path string) (interface{}, bool)) []string {
+	rendered := make([]string, 0, len(templates))
+	for _, template := range templates {
+		if value, ok := models.RenderPathTemplate(template, lookup); ok && value != "" {
+			rendered = append(rendered, value)
+		}
+	}
+	return rendered
+}
+
+// matchesAny reports whether name matches one of the glob patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"socket-server/internal/models"
+)
+
+const (
+	pushRequestTimeout = 10 * time.Second
+	// pushSendTimeout bounds a send, which may also fetch a new FCM access token
+	pushSendTimeout = 2 * pushRequestTimeout
+
+	fcmScope          = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmEndpoint       = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	apnsEndpoint      = "https://api.push.apple.com"
+	apnsSandbox       = "https://api.sandbox.push.apple.com"
+	apnsTokenLifetime = 50 * time.Minute // Apple rejects provider tokens older than an hour
+)
+
+// PushService sends push notifications through FCM and APNs, as a fallback for users without
+// an active connection. Providers are enabled individually; a nil service sends nothing.
+type PushService struct {
+	client *http.Client
+	fcm    *fcmSender
+	apns   *apnsSender
+
+	// Sends of each provider run under its guard
+	fcmGuard  *SinkGuard
+	apnsGuard *SinkGuard
+}
+
+// NewPushService creates a push service with no providers enabled
+func NewPushService() *PushService {
+	return &PushService{client: &http.Client{Timeout: pushRequestTimeout}}
+}
+
+// EnableFCM enables Firebase Cloud Messaging with a Google service account key file
+func (p *PushService) EnableFCM(credentialsFile string) error {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return fmt.Errorf("error reading FCM credentials: %w", err)
+	}
+
+	var account struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return fmt.Errorf("error parsing FCM credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.TokenURI == "" {
+		return fmt.Errorf("FCM credentials must be a service account key with project_id, client_email and token_uri")
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("error parsing FCM private key: %w", err)
+	}
+
+	p.fcm = &fcmSender{
+		endpoint:    fmt.Sprintf(fcmEndpoint, url.PathEscape(account.ProjectID)),
+		tokenURI:    account.TokenURI,
+		clientEmail: account.ClientEmail,
+		key:         key,
+	}
+	p.fcmGuard = NewSinkGuard("push_fcm", pushSendTimeout)
+	return nil
+}
+
+// EnableAPNs enables the Apple Push Notification service with a .p8 token signing key
+func (p *PushService) EnableAPNs(keyFile, keyID, teamID, topic string, sandbox bool) error {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("error reading APNs key: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return fmt.Errorf("error parsing APNs key: %w", err)
+	}
+
+	endpoint := apnsEndpoint
+	if sandbox {
+		endpoint = apnsSandbox
+	}
+	p.apns = &apnsSender{
+		endpoint: endpoint,
+		keyID:    keyID,
+		teamID:   teamID,
+		topic:    topic,
+		key:      key,
+	}
+	p.apnsGuard = NewSinkGuard("push_apns", pushSendTimeout)
+	return nil
+}
+
+// Enabled reports whether any provider is configured
+func (p *PushService) Enabled() bool {
+	return p != nil && (p.fcm != nil || p.apns != nil)
+}
+
+// Send pushes a notification for a broadcast message to one device. It returns
+// models.ErrPushTokenUnregistered when the provider no longer accepts the device token.
+func (p *PushService) Send(device models.PushDevice, notification models.PushNotification, message models.Message) error {
+	switch {
+	case device.Provider == models.PushProviderFCM && p.fcm != nil:
+		return p.fcmGuard.Run(func(context.Context) error {
+			return p.fcm.send(p.client, device.Token, notification, message)
+		})
+	case device.Provider == models.PushProviderAPNs && p.apns != nil:
+		return p.apnsGuard.Run(func(context.Context) error {
+			return p.apns.send(p.client, device.Token, notification, message)
+		})
+	default:
+		return fmt.Errorf("%w: %s", models.ErrPushProviderDisabled, device.Provider)
+	}
+}
+
+// Health returns the records of the enabled providers
+func (p *PushService) Health() []SinkHealth {
+	var health []SinkHealth
+	if p == nil {
+		return health
+	}
+	for _, guard := range []*SinkGuard{p.fcmGuard, p.apnsGuard} {
+		if guard != nil {
+			health = append(health, guard.Health())
+		}
+	}
+	return health
+}
+
+// fcmSender sends through the FCM HTTP v1 API, authenticating with OAuth access tokens
+// obtained from a service account
+type fcmSender struct {
+	endpoint    string
+	tokenURI    string
+	clientEmail string
+	key         *rsa.PrivateKey
+
+	mutex       sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func (f *fcmSender) send(client *http.Client, token string, notification models.PushNotification, message models.Message) error {
+	accessToken, err := f.token(client)
+	if err != nil {
+		return err
+	}
+
+	// FCM data values must be strings, so the broadcast data travels JSON-encoded
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		return fmt.Errorf("error encoding push data: %w", err)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": notification,
+			"data": map[string]string{
+				"id":      message.ID,
+				"event":   message.Event,
+				"payload": string(data),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding push: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, f.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound || strings.Contains(string(detail), "UNREGISTERED") {
+		return models.ErrPushTokenUnregistered
+	}
+	return fmt.Errorf("FCM returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// token returns a cached OAuth access token, exchanging a signed assertion for a new one
+// shortly before it expires
+func (f *fcmSender) token(client *http.Client) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.accessToken != "" && time.Now().Before(f.expiresAt) {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.clientEmail,
+		"scope": fcmScope,
+		"aud":   f.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", fmt.Errorf("error signing FCM token request: %w", err)
+	}
+
+	resp, err := client.PostForm(f.tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error requesting FCM access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("FCM access token request returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.AccessToken == "" {
+		return "", fmt.Errorf("invalid FCM access token response")
+	}
+
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return f.accessToken, nil
+}
+
+// apnsSender sends through the APNs HTTP/2 API with token-based authentication
+type apnsSender struct {
+	endpoint string
+	keyID    string
+	teamID   string
+	topic    string
+	key      *ecdsa.PrivateKey
+
+	mutex    sync.Mutex
+	signed   string
+	issuedAt time.Time
+}
+
+func (a *apnsSender) send(client *http.Client, token string, notification models.PushNotification, message models.Message) error {
+	providerToken, err := a.token()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": notification,
+			"sound": "default",
+		},
+		"id":    message.ID,
+		"event": message.Event,
+		"data":  message.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding push: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("Apns-Topic", a.topic)
+	req.Header.Set("Apns-Push-Type", "alert")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&reason)
+	if resp.StatusCode == http.StatusGone || reason.Reason == "BadDeviceToken" || reason.Reason == "Unregistered" {
+		return models.ErrPushTokenUnregistered
+	}
+	return fmt.Errorf("APNs returned %s: %s", resp.Status, reason.Reason)
+}
+
+// token returns the provider authentication token, re-signing it before Apple considers it
+// expired
+func (a *apnsSender) token() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.signed != "" && time.Since(a.issuedAt) < apnsTokenLifetime {
+		return a.signed, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.keyID
+	signed, err := token.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("error signing APNs token: %w", err)
+	}
+
+	a.signed, a.issuedAt = signed, now
+	return a.signed, nil
+}
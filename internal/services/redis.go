@@ -0,0 +1,361 @@
+package services
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisDialTimeout = 3 * time.Second
+	redisIOTimeout   = 2 * time.Second
+	// redisRetryDelay is how long commands fail fast after Redis couldn't be reached, so
+	// callers on the request path don't each wait for the dial timeout
+	redisRetryDelay = 5 * time.Second
+	redisMaxIdle    = 8
+)
+
+// errRedisUnavailable is returned while Redis is considered down after a failed dial
+var errRedisUnavailable = errors.New("redis unavailable")
+
+// redisError is an error reply from Redis
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// RedisClient is a minimal Redis client speaking RESP2: commands, pipelines and pub/sub, which
+// is all the cluster needs. Commands run on pooled connections; a connection that fails is
+// discarded and the next command dials a new one.
+type RedisClient struct {
+	address   string
+	username  string
+	password  string
+	db        int
+	tlsConfig *tls.Config // nil for plain TCP
+
+	idle      chan *redisConn
+	downUntil time.Time
+	mutex     sync.Mutex
+}
+
+// NewRedisClient creates a client for a redis:// or rediss:// (TLS) URL of the form
+// redis://[[user]:password@]host[:port][/db]. No connection is made until the first command.
+func NewRedisClient(rawURL string) (*RedisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q", rawURL)
+	}
+
+	client := &RedisClient{address: u.Host, idle: make(chan *redisConn, redisMaxIdle)}
+	if u.Port() == "" {
+		client.address = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		client.username = u.User.Username()
+		client.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if client.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+	if u.Scheme == "rediss" {
+		client.tlsConfig = &tls.Config{ServerName: u.Hostname()}
+	}
+	return client, nil
+}
+
+// Address returns the host and port of the Redis server
+func (c *RedisClient) Address() string {
+	return c.address
+}
+
+// Do runs a command and returns its reply: a string, an int64, nil or a []interface{} of those
+func (c *RedisClient) Do(args ...interface{}) (interface{}, error) {
+	replies, err := c.Pipeline([][]interface{}{args})
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := replies[0].(redisError); ok {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// Pipeline sends several commands at once and returns their replies in order. Error replies
+// of single commands are returned in place as errors rather than failing the pipeline.
+func (c *RedisClient) Pipeline(commands [][]interface{}) ([]interface{}, error) {
+	conn, pooled, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	replies, unrun, err := c.pipeline(conn, commands)
+	if err != nil && pooled && unrun {
+		// The idle connection may have been closed by a Redis restart since it was last used.
+		// Commands that may have run aren't sent again, so an INCR is never counted twice.
+		if conn, err = c.dial(); err != nil {
+			return nil, err
+		}
+		replies, _, err = c.pipeline(conn, commands)
+	}
+	return replies, err
+}
+
+// pipeline runs commands on a connection, closing it if it fails. unrun is set when the
+// commands can't have run: writing them failed, or the connection was closed before any reply
+// arrived. After a timeout or a partial reply they may have run, so unrun is not set.
+func (c *RedisClient) pipeline(conn *redisConn, commands [][]interface{}) (replies []interface{}, unrun bool, err error) {
+	conn.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	for _, args := range commands {
+		conn.writeCommand(args)
+	}
+	if err := conn.writer.Flush(); err != nil {
+		conn.conn.Close()
+		return nil, true, err
+	}
+	if _, err := conn.reader.Peek(1); err != nil {
+		conn.conn.Close()
+		return nil, !isTimeout(err), err
+	}
+
+	replies = make([]interface{}, len(commands))
+	for i := range commands {
+		reply, err := conn.readReply()
+		if err != nil {
+			conn.conn.Close()
+			return nil, false, err
+		}
+		replies[i] = reply
+	}
+	c.put(conn)
+	return replies, false, nil
+}
+
+// Subscribe opens a dedicated connection subscribed to a pub/sub channel
+func (c *RedisClient) Subscribe(channel string) (*RedisSubscription, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	conn.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	conn.writeCommand([]interface{}{"SUBSCRIBE", channel})
+	if err := conn.writer.Flush(); err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+	if _, err := conn.readReply(); err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+	// Messages can be a long time apart; TCP keep-alives detect a dead connection
+	conn.conn.SetDeadline(time.Time{})
+	return &RedisSubscription{conn: conn}, nil
+}
+
+// Close closes the idle connections
+func (c *RedisClient) Close() {
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// get takes an idle connection or dials a new one; pooled is set for an idle connection
+func (c *RedisClient) get() (conn *redisConn, pooled bool, err error) {
+	select {
+	case conn := <-c.idle:
+		return conn, true, nil
+	default:
+	}
+
+	c.mutex.Lock()
+	down := time.Now().Before(c.downUntil)
+	c.mutex.Unlock()
+	if down {
+		return nil, false, errRedisUnavailable
+	}
+	conn, err = c.dial()
+	return conn, false, err
+}
+
+// put returns a healthy connection to the pool
+func (c *RedisClient) put(conn *redisConn) {
+	select {
+	case c.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// dial connects, authenticates and selects the database. A failed dial makes commands fail
+// fast for the retry delay.
+func (c *RedisClient) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisDialTimeout, KeepAlive: 15 * time.Second}
+	var netConn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", c.address, c.tlsConfig)
+	} else {
+		netConn, err = dialer.Dial("tcp", c.address)
+	}
+	c.mutex.Lock()
+	if err != nil {
+		c.downUntil = time.Now().Add(redisRetryDelay)
+	} else {
+		c.downUntil = time.Time{}
+	}
+	c.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}
+	var setup [][]interface{}
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []interface{}{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []interface{}{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		setup = append(setup, []interface{}{"SELECT", c.db})
+	}
+	netConn.SetDeadline(time.Now().Add(redisIOTimeout))
+	for _, args := range setup {
+		conn.writeCommand(args)
+		if err := conn.writer.Flush(); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		reply, err := conn.readReply()
+		if replyErr, ok := reply.(redisError); ok {
+			err = replyErr
+		}
+		if err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("%s failed: %w", args[0], err)
+		}
+	}
+	return conn, nil
+}
+
+// RedisSubscription receives the messages of a pub/sub channel
+type RedisSubscription struct {
+	conn *redisConn
+}
+
+// Receive blocks until a message is published and returns its payload
+func (s *RedisSubscription) Receive() ([]byte, error) {
+	for {
+		reply, err := s.conn.readReply()
+		if err != nil {
+			return nil, err
+		}
+		if parts, ok := reply.([]interface{}); ok && len(parts) == 3 && parts[0] == "message" {
+			payload, _ := parts[2].(string)
+			return []byte(payload), nil
+		}
+	}
+}
+
+// Close closes the subscription's connection, making Receive return
+func (s *RedisSubscription) Close() {
+	s.conn.conn.Close()
+}
+
+// redisConn is a connection to Redis
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// writeCommand buffers a command as an array of bulk strings
+func (c *redisConn) writeCommand(args []interface{}) {
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var value string
+		switch arg := arg.(type) {
+		case string:
+			value = arg
+		case []byte:
+			value = string(arg)
+		default:
+			value = fmt.Sprint(arg)
+		}
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(value), value)
+	}
+}
+
+// readReply reads a reply. Error replies are returned as a redisError value, not an error,
+// so the connection stays usable.
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed Redis reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return redisError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("malformed Redis bulk length %q", body)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("malformed Redis array length %q", body)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown Redis reply type %q", kind)
+}
+
+// isTimeout reports whether a network error is a timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
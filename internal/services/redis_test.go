@@ -0,0 +1,312 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process Redis speaking RESP2. handle returns the raw reply to a command;
+// an empty reply closes the connection without answering.
+type fakeRedis struct {
+	listener net.Listener
+	handle   func(conn net.Conn, args []string) string
+	commands [][]string
+	conns    []net.Conn
+	mutex    sync.Mutex
+}
+
+func newFakeRedis(t *testing.T, handle func(conn net.Conn, args []string) string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	fake := &fakeRedis{listener: listener, handle: handle}
+	t.Cleanup(fake.close)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			fake.mutex.Lock()
+			fake.conns = append(fake.conns, conn)
+			fake.mutex.Unlock()
+			go fake.serve(conn)
+		}
+	}()
+	return fake
+}
+
+func (f *fakeRedis) url() string {
+	return "redis://" + f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readFakeCommand(reader)
+		if err != nil {
+			return
+		}
+		f.mutex.Lock()
+		f.commands = append(f.commands, args)
+		f.mutex.Unlock()
+
+		reply := f.handle(conn, args)
+		if reply == "" {
+			return
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// dropConnections closes the open connections, as a Redis restart does
+func (f *fakeRedis) dropConnections() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeRedis) close() {
+	f.listener.Close()
+	f.dropConnections()
+}
+
+// received returns the names of the commands received, in order
+func (f *fakeRedis) received() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var names []string
+	for _, args := range f.commands {
+		names = append(names, strings.Join(args, " "))
+	}
+	return names
+}
+
+// readFakeCommand reads a command sent as an array of bulk strings
+func readFakeCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisReplies(t *testing.T) {
+	fake := newFakeRedis(t, func(conn net.Conn, args []string) string {
+		switch args[0] {
+		case "PING":
+			return "+PONG\r\n"
+		case "INCR":
+			return ":42\r\n"
+		case "GET":
+			if args[1] == "missing" {
+				return "$-1\r\n"
+			}
+			return "$12\r\nhello\r\nworld\r\n"
+		case "HGETALL":
+			return "*4\r\n$1\r\na\r\n$0\r\n\r\n$1\r\nb\r\n*-1\r\n"
+		default:
+			return "-ERR unknown command '" + args[0] + "'\r\n"
+		}
+	})
+	client, err := NewRedisClient(fake.url())
+	if err != nil {
+		t.Fatalf("Failed to create the client: %v", err)
+	}
+	defer client.Close()
+
+	expected := map[string]interface{}{"PING": "PONG", "INCR": int64(42), "GET": "hello\r\nworld"}
+	for command, want := range expected {
+		if reply, err := client.Do(command, "key"); err != nil || reply != want {
+			t.Errorf("Expected %q for %s, got %#v, %v", want, command, reply, err)
+		}
+	}
+	if reply, err := client.Do("GET", "missing"); err != nil || reply != nil {
+		t.Errorf("Expected nil for a missing key, got %#v, %v", reply, err)
+	}
+	reply, err := client.Do("HGETALL", "hash")
+	items, _ := reply.([]interface{})
+	if err != nil || len(items) != 4 || items[0] != "a" || items[1] != "" || items[3] != nil {
+		t.Errorf("Expected an array with an empty string and a nil array, got %#v, %v", reply, err)
+	}
+	if _, err := client.Do("NOPE"); err == nil || err.Error() != "ERR unknown command 'NOPE'" {
+		t.Errorf("Expected the error reply as an error, got %v", err)
+	}
+
+	replies, err := client.Pipeline([][]interface{}{{"NOPE"}, {"INCR", "counter"}})
+	if err != nil || len(replies) != 2 || replies[1] != int64(42) {
+		t.Fatalf("Expected the pipeline to succeed around an error reply, got %#v, %v", replies, err)
+	}
+	if _, ok := replies[0].(redisError); !ok {
+		t.Errorf("Expected the error reply in place, got %#v", replies[0])
+	}
+}
+
+func TestRedisAuthAndSelect(t *testing.T) {
+	fake := newFakeRedis(t, func(conn net.Conn, args []string) string {
+		if args[0] == "AUTH" && args[len(args)-1] != "secret" {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+		return "+OK\r\n"
+	})
+	address := fake.listener.Addr().String()
+
+	client, _ := NewRedisClient("redis://app:secret@" + address + "/2")
+	if _, err := client.Do("PING"); err != nil {
+		t.Fatalf("Failed to run a command: %v", err)
+	}
+	if received := fake.received(); fmt.Sprint(received) != "[AUTH app secret SELECT 2 PING]" {
+		t.Errorf("Expected AUTH and SELECT before the command, got %v", received)
+	}
+
+	wrong, _ := NewRedisClient("redis://:nope@" + address)
+	if _, err := wrong.Do("PING"); err == nil || !strings.Contains(err.Error(), "AUTH failed: WRONGPASS") {
+		t.Errorf("Expected the AUTH failure, got %v", err)
+	}
+}
+
+func TestRedisRedialsClosedIdleConnection(t *testing.T) {
+	fake := newFakeRedis(t, func(conn net.Conn, args []string) string {
+		return ":1\r\n"
+	})
+	client, _ := NewRedisClient(fake.url())
+	defer client.Close()
+
+	if _, err := client.Do("INCR", "a"); err != nil {
+		t.Fatalf("Failed to run a command: %v", err)
+	}
+	fake.dropConnections()
+	time.Sleep(10 * time.Millisecond)
+
+	if reply, err := client.Do("INCR", "b"); err != nil || reply != int64(1) {
+		t.Fatalf("Expected the command to run on a new connection, got %v, %v", reply, err)
+	}
+	if received := fake.received(); fmt.Sprint(received) != "[INCR a INCR b]" {
+		t.Errorf("Expected each command to run once, got %v", received)
+	}
+}
+
+func TestRedisPipelineNotRetriedAfterPartialReply(t *testing.T) {
+	var incrs int
+	fake := newFakeRedis(t, func(conn net.Conn, args []string) string {
+		if args[1] == "warmup" {
+			return ":1\r\n"
+		}
+		incrs++
+		if incrs == 2 {
+			// Redis ran the command but the connection broke before the reply
+			return ""
+		}
+		return ":" + strconv.Itoa(incrs) + "\r\n"
+	})
+	client, _ := NewRedisClient(fake.url())
+	defer client.Close()
+
+	if _, err := client.Do("INCR", "warmup"); err != nil {
+		t.Fatalf("Failed to run a command: %v", err)
+	}
+
+	_, err := client.Pipeline([][]interface{}{{"INCR", "rate"}, {"INCR", "rate"}})
+	if err == nil {
+		t.Fatal("Expected the pipeline to fail")
+	}
+	if received := fake.received(); len(received) != 3 {
+		t.Errorf("Expected the commands not to be sent again once a reply arrived, got %v", received)
+	}
+}
+
+func TestRedisFailsFastWhileDown(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	listener.Close()
+
+	client, _ := NewRedisClient("redis://" + address)
+	if _, err := client.Do("PING"); err == nil || errors.Is(err, errRedisUnavailable) {
+		t.Fatalf("Expected the dial error first, got %v", err)
+	}
+	if _, err := client.Do("PING"); !errors.Is(err, errRedisUnavailable) {
+		t.Errorf("Expected commands to fail fast after a failed dial, got %v", err)
+	}
+}
+
+func TestRedisSubscription(t *testing.T) {
+	fake := newFakeRedis(t, func(conn net.Conn, args []string) string {
+		if args[0] != "SUBSCRIBE" {
+			return "-ERR only SUBSCRIBE is allowed\r\n"
+		}
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			io.WriteString(conn, "*3\r\n$7\r\nmessage\r\n$6\r\nevents\r\n$5\r\nhello\r\n")
+		}()
+		return "*3\r\n$9\r\nsubscribe\r\n$6\r\nevents\r\n:1\r\n"
+	})
+	client, _ := NewRedisClient(fake.url())
+
+	subscription, err := client.Subscribe("events")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	payload, err := subscription.Receive()
+	if err != nil || string(payload) != "hello" {
+		t.Fatalf("Expected the published payload, got %q, %v", payload, err)
+	}
+
+	received := make(chan error, 1)
+	go func() {
+		_, err := subscription.Receive()
+		received <- err
+	}()
+	subscription.Close()
+	select {
+	case err := <-received:
+		if err == nil {
+			t.Error("Expected Receive to fail once the subscription is closed")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected Close to make Receive return")
+	}
+}
+
+func TestNewRedisClient(t *testing.T) {
+	client, err := NewRedisClient("rediss://:pw@cache.internal/3")
+	if err != nil || client.Address() != "cache.internal:6379" || client.db != 3 || client.password != "pw" || client.tlsConfig == nil {
+		t.Errorf("Expected the default port, database 3, the password and TLS, got %+v, %v", client, err)
+	}
+	for _, invalid := range []string{"http://cache:6379", "redis://", "redis://cache/db"} {
+		if _, err := NewRedisClient(invalid); err == nil {
+			t.Errorf("Expected %q to be refused", invalid)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// FallbackSink delivers a critical broadcast to a user through something other than a socket
+// connection, once the user has been offline beyond the critical threshold
+type FallbackSink interface {
+	// Name identifies the sink in logs and broadcast responses
+	Name() string
+	// Deliver sends the message to the user, returning an error if it could not be delivered
+	Deliver(userID string, delivery models.CriticalDelivery, message models.Message) error
+}
+
+// GuardedSink is a fallback sink whose deliveries each run under a SinkGuard
+type GuardedSink struct {
+	FallbackSink
+	guard *SinkGuard
+}
+
+// GuardSink wraps a fallback sink so a delivery taking longer than timeout, or panicking,
+// fails without holding up the others
+func GuardSink(sink FallbackSink, timeout time.Duration) *GuardedSink {
+	return &GuardedSink{FallbackSink: sink, guard: NewSinkGuard(sink.Name(), timeout)}
+}
+
+// Deliver sends the message through the wrapped sink under the guard
+func (g *GuardedSink) Deliver(userID string, delivery models.CriticalDelivery, message models.Message) error {
+	return g.guard.Run(func(context.Context) error {
+		return g.FallbackSink.Deliver(userID, delivery, message)
+	})
+}
+
+// Health returns the record of the sink's deliveries
+func (g *GuardedSink) Health() SinkHealth {
+	return g.guard.Health()
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+const (
+	uploadRequestTimeout = 10 * time.Second
+	// maxPresignTTL is the longest validity of a signature version 4 pre-signed URL
+	maxPresignTTL = 7 * 24 * time.Hour
+)
+
+// UploadStorage signs URLs of an S3-compatible bucket (AWS S3, MinIO, Cloudflare R2, ...), so
+// clients upload large files straight to storage instead of through their connection
+type UploadStorage struct {
+	models.Timekeeper // signing time of the URLs
+
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	publicURL string
+	client    *http.Client
+}
+
+// NewUploadStorage creates the storage of a bucket. Objects are addressed as
+// endpoint/bucket/key with pathStyle, or bucket.endpoint-host/key otherwise. publicURL, when
+// set, is the base URL objects are read from; otherwise they are read with pre-signed URLs.
+func NewUploadStorage(endpoint, bucket, region, accessKey, secretKey string, pathStyle bool, publicURL string) (*UploadStorage, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid storage endpoint %q", endpoint)
+	}
+	return &UploadStorage{
+		endpoint:  parsed,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		client:    &http.Client{Timeout: uploadRequestTimeout},
+	}, nil
+}
+
+// PresignPut returns a URL uploading an object with a PUT request until ttl has passed, and
+// the headers the request must carry: the signature covers the content type and length, so
+// the client can't upload another kind or size of file than the one it announced
+func (u *UploadStorage) PresignPut(key, contentType string, size int64, ttl time.Duration) (string, map[string]string) {
+	headers := map[string]string{"Content-Length": strconv.FormatInt(size, 10)}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	return u.presign(http.MethodPut, key, headers, ttl, u.Now()), headers
+}
+
+// ObjectURL returns the URL clients download an object from: under the public URL, or
+// pre-signed for the longest time allowed
+func (u *UploadStorage) ObjectURL(key string) string {
+	if u.publicURL != "" {
+		return u.publicURL + "/" + encodeObjectKey(key)
+	}
+	return u.presign(http.MethodGet, key, nil, maxPresignTTL, u.Now())
+}
+
+// Stat returns the size of an uploaded object, or an error when it wasn't uploaded
+func (u *UploadStorage) Stat(key string) (int64, error) {
+	req, err := http.NewRequest(http.MethodHead, u.presign(http.MethodHead, key, nil, time.Minute, u.Now()), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("storage returned %s", resp.Status)
+	}
+	return resp.ContentLength, nil
+}
+
+// presign signs a request for an object with AWS signature version 4 in the query string.
+// The payload isn't signed, and headers must be sent as given.
+func (u *UploadStorage) presign(method, key string, headers map[string]string, ttl time.Duration, now time.Time) string {
+	host, path := u.endpoint.Host, "/"+encodeObjectKey(key)
+	if u.pathStyle {
+		path = "/" + u.bucket + path
+	} else {
+		host = u.bucket + "." + host
+	}
+	path = strings.TrimSuffix(u.endpoint.Path, "/") + path
+
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + u.region + "/s3/aws4_request"
+
+	signed := map[string]string{"host": host}
+	for name, value := range headers {
+		signed[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := canonicalQuery(map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    u.accessKey + "/" + scope,
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       strconv.Itoa(int(min(ttl, maxPresignTTL).Seconds())),
+		"X-Amz-SignedHeaders": signedHeaders,
+	})
+	canonicalRequest := strings.Join([]string{method, path, query, canonicalHeaders.String(), signedHeaders, "UNSIGNED-PAYLOAD"}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+u.secretKey), date)
+	for _, part := range []string{u.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return u.endpoint.Scheme + "://" + host + path + "?" + query + "&X-Amz-Signature=" + signature
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as signature version 4 expects
+func canonicalQuery(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = uriEncode(name, true) + "=" + uriEncode(params[name], true)
+	}
+	return strings.Join(pairs, "&")
+}
+
+// encodeObjectKey encodes an object key for a URL path, keeping its slashes
+func encodeObjectKey(key string) string {
+	return uriEncode(key, false)
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and slashes unless asked to
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
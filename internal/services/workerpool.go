@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// workerPool keeps long-running `php artisan <command> --worker` processes, so the framework
+// is booted once per worker instead of once per payload.
+//
+// Protocol: each payload is written to the worker's stdin as one line of JSON. After handling
+// it, the worker writes a line {"ok": true} (or {"ok": false, "error": "..."}) to stdout.
+// Other output lines are logged and ignored. A worker that crashes, misses the timeout or
+// reaches its job limit is replaced.
+type workerPool struct {
+	service *LaravelService
+	options DispatchOptions
+
+	// idle holds one slot per worker; a nil slot is (re)spawned on its next use
+	idle      chan *phpWorker
+	nextID    int
+	idMutex   sync.Mutex
+	closeOnce sync.Once
+}
+
+// phpWorker is one PHP process and its pipes
+type phpWorker struct {
+	id     int
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	jobs   int
+}
+
+// errPayloadFailed marks payloads the worker handled but reported as failed; the worker
+// itself is healthy and stays in the pool
+var errPayloadFailed = errors.New("PHP worker failed to handle payload")
+
+// workerResponse is the line a worker writes after handling a payload
+type workerResponse struct {
+	OK    *bool  `json:"ok"`
+	Error string `json:"error"`
+}
+
+func newWorkerPool(service *LaravelService, options DispatchOptions) *workerPool {
+	pool := &workerPool{
+		service: service,
+		options: options,
+		idle:    make(chan *phpWorker, options.Workers),
+	}
+	for i := 0; i < options.Workers; i++ {
+		worker, err := pool.spawn()
+		if err != nil {
+			service.logger.Error("Failed to start PHP worker: %v", err)
+		}
+		pool.idle <- worker
+	}
+	return pool
+}
+
+// dispatch hands a payload to the next idle worker, waiting for one to become free
+func (p *workerPool) dispatch(data []byte) error {
+	worker := <-p.idle
+	if worker == nil {
+		var err error
+		if worker, err = p.spawn(); err != nil {
+			p.idle <- nil
+			return fmt.Errorf("error starting PHP worker: %w", err)
+		}
+	}
+
+	err := p.handle(worker, data)
+	if err != nil && !errors.Is(err, errPayloadFailed) {
+		p.service.logger.LaravelCommandError(p.service.laravelCmd+" --worker", err, "")
+		worker.stop()
+		p.idle <- nil
+		return err
+	}
+
+	worker.jobs++
+	if p.options.WorkerMaxJobs > 0 && worker.jobs >= p.options.WorkerMaxJobs {
+		p.service.logger.Debug("Recycling PHP worker %d after %d jobs", worker.id, worker.jobs)
+		worker.stop()
+		worker = nil
+	}
+	p.idle <- worker
+	return err
+}
+
+// handle sends one payload and waits for the worker's response line
+func (p *workerPool) handle(worker *phpWorker, data []byte) error {
+	if _, err := worker.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing to PHP worker %d: %w", worker.id, err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- p.readResponse(worker)
+	}()
+
+	timeout := p.options.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		// Killing the process unblocks the reader
+		worker.kill()
+		return fmt.Errorf("PHP worker %d did not respond within %v", worker.id, timeout)
+	}
+}
+
+// readResponse reads output lines until the worker reports the outcome of the payload
+func (p *workerPool) readResponse(worker *phpWorker) error {
+	for {
+		line, err := worker.stdout.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("PHP worker %d exited: %w", worker.id, err)
+		}
+		line = strings.TrimSpace(line)
+
+		var response workerResponse
+		if json.Unmarshal([]byte(line), &response) != nil || response.OK == nil {
+			if line != "" {
+				p.service.logger.Debug("PHP worker %d: %s", worker.id, line)
+			}
+			continue
+		}
+		if !*response.OK {
+			return fmt.Errorf("%w (worker %d): %s", errPayloadFailed, worker.id, response.Error)
+		}
+		return nil
+	}
+}
+
+// spawn starts a new worker process
+func (p *workerPool) spawn() (*phpWorker, error) {
+	p.idMutex.Lock()
+	p.nextID++
+	id := p.nextID
+	p.idMutex.Unlock()
+
+	cmd := exec.Command(p.service.phpBinary, "artisan", p.service.laravelCmd, "--worker")
+	cmd.Dir = p.service.workingDir
+	cmd.Stderr = &workerLogWriter{service: p.service, id: id}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p.service.logger.Debug("Started PHP worker %d (pid %d)", id, cmd.Process.Pid)
+	return &phpWorker{id: id, cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// close stops every worker once it is idle
+func (p *workerPool) close() {
+	p.closeOnce.Do(func() {
+		for i := 0; i < p.options.Workers; i++ {
+			if worker := <-p.idle; worker != nil {
+				worker.stop()
+			}
+		}
+	})
+}
+
+// stop closes the worker's stdin so it exits after its current payload, killing it if it
+// doesn't exit promptly
+func (w *phpWorker) stop() {
+	w.stdin.Close()
+
+	exited := make(chan struct{})
+	go func() {
+		w.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		w.kill()
+		<-exited
+	}
+}
+
+func (w *phpWorker) kill() {
+	if w.cmd.Process != nil {
+		w.cmd.Process.Kill()
+	}
+}
+
+// workerLogWriter logs what workers write to stderr
+type workerLogWriter struct {
+	service *LaravelService
+	id      int
+}
+
+func (w *workerLogWriter) Write(data []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line != "" {
+			w.service.logger.Warn("PHP worker %d: %s", w.id, line)
+		}
+	}
+	return len(data), nil
+}
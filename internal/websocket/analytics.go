@@ -0,0 +1,8 @@
+package websocket
+
+import "socket-server/internal/services"
+
+// SetAnalytics enables the daily usage rollups
+func (s *Server) SetAnalytics(analytics *services.AnalyticsStore) {
+	s.analytics = analytics
+}
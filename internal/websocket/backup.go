@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// backupVersion is bumped whenever the backup archive layout changes incompatibly
+const backupVersion = 1
+
+// Files of a backup archive
+const (
+	backupManifestFile = "manifest.json"
+	backupStateFile    = "state.json"
+)
+
+// BackupManifest describes a backup archive: a gzipped tar of this manifest and the server
+// state snapshot
+type BackupManifest struct {
+	Version         int       `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	Source          string    `json:"source"`
+	NodeID          string    `json:"node_id"`
+	SnapshotVersion int       `json:"snapshot_version"`
+	TakenAt         time.Time `json:"taken_at"`
+	// Contents counts the state's entries by kind, e.g. "channels" or "bans"
+	Contents map[string]int `json:"contents"`
+}
+
+// BackupContents counts the entries of a snapshot by kind
+func BackupContents(snapshot *Snapshot) map[string]int {
+	grants := 0
+	for _, channels := range snapshot.UserGrants {
+		grants += len(channels)
+	}
+	devices := 0
+	for _, userDevices := range snapshot.PushDevices {
+		devices += len(userDevices)
+	}
+	return map[string]int{
+		"channels":             len(snapshot.Channels),
+		"groups":               len(snapshot.Groups),
+		"banned_ips":           len(snapshot.BannedIPs),
+		"bans":                 len(snapshot.Bans),
+		"scheduled_broadcasts": len(snapshot.ScheduledBroadcasts),
+		"event_schemas":        len(snapshot.EventSchemas),
+		"channel_grants":       grants,
+		"push_devices":         devices,
+	}
+}
+
+// WriteBackup writes a backup archive of a snapshot, created at createdAt. Source names where
+// the state came from, such as the server URL or the state file.
+func WriteBackup(w io.Writer, snapshot *Snapshot, source string, createdAt time.Time) (BackupManifest, error) {
+	manifest := BackupManifest{
+		Version:         backupVersion,
+		CreatedAt:       createdAt.UTC(),
+		Source:          source,
+		NodeID:          snapshot.NodeID,
+		SnapshotVersion: snapshot.Version,
+		TakenAt:         snapshot.TakenAt,
+		Contents:        BackupContents(snapshot),
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("error encoding backup manifest: %w", err)
+	}
+	stateData, err := json.Marshal(snapshot)
+	if err != nil {
+		return manifest, fmt.Errorf("error encoding state snapshot: %w", err)
+	}
+
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{backupManifestFile, manifestData}, {backupStateFile, stateData}} {
+		header := &tar.Header{Name: file.name, Mode: 0600, Size: int64(len(file.data)), ModTime: manifest.CreatedAt}
+		if err := archive.WriteHeader(header); err != nil {
+			return manifest, fmt.Errorf("error writing backup archive: %w", err)
+		}
+		if _, err := archive.Write(file.data); err != nil {
+			return manifest, fmt.Errorf("error writing backup archive: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return manifest, fmt.Errorf("error writing backup archive: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return manifest, fmt.Errorf("error writing backup archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// ReadBackup reads a backup archive written by WriteBackup, refusing archives and snapshots of
+// an unsupported version
+func ReadBackup(r io.Reader) (*BackupManifest, *Snapshot, error) {
+	compressed, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer compressed.Close()
+
+	var manifest *BackupManifest
+	var snapshot *Snapshot
+	archive := tar.NewReader(compressed)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading backup archive: %w", err)
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading backup archive: %w", err)
+		}
+
+		switch header.Name {
+		case backupManifestFile:
+			manifest = &BackupManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("error decoding backup manifest: %w", err)
+			}
+			if manifest.Version != backupVersion {
+				return nil, nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+			}
+		case backupStateFile:
+			if snapshot, err = DecodeSnapshot(data); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if manifest == nil || snapshot == nil {
+		return nil, nil, fmt.Errorf("incomplete backup archive: %s and %s are required", backupManifestFile, backupStateFile)
+	}
+	return manifest, snapshot, nil
+}
@@ -0,0 +1,28 @@
+package websocket
+
+import (
+	"encoding/json"
+	"path"
+
+	"socket-server/internal/models"
+)
+
+// channelBandwidthLimit returns the bandwidth cap in bytes per second of the first matching
+// CHANNEL_BANDWIDTH_LIMITS rule, or 0. The caller must hold s.mutex.
+func (s *Server) channelBandwidthLimit(channelName string) int64 {
+	for _, rule := range s.bandwidthRules {
+		if matched, err := path.Match(rule.Pattern, channelName); err == nil && matched {
+			return int64(rule.PerSecond)
+		}
+	}
+	return 0
+}
+
+// estimateFanoutBytes approximates the bytes written when a message goes out to recipients
+func estimateFanoutBytes(message models.Message, recipients int) int64 {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return 0
+	}
+	return int64((len(data) + len(message.Blob)) * recipients)
+}
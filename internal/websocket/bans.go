@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// banKey identifies a ban in the server's ban map
+func banKey(banType, value string) string {
+	return banType + ":" + value
+}
+
+// AddBan bans a user or IP address, replacing any existing ban of it, and disconnects the
+// matching connections. It returns the stored ban and the number of connections closed.
+func (s *Server) AddBan(ban models.Ban) (models.Ban, int, error) {
+	if err := ban.Validate(); err != nil {
+		return ban, 0, err
+	}
+	ban.CreatedAt = s.Now()
+
+	disconnected := s.storeBan(ban)
+	s.logger.Warn("Banned %s %s (reason: %q, expires: %s), %d connections closed", ban.Type, ban.Value, ban.Reason, banExpiry(ban), disconnected)
+	s.shareBan(ban)
+	return ban, disconnected, nil
+}
+
+// storeBan records a ban and disconnects the matching connections, returning how many
+func (s *Server) storeBan(ban models.Ban) int {
+	s.mutex.Lock()
+	s.bans[banKey(ban.Type, ban.Value)] = ban
+	s.mutex.Unlock()
+
+	var banned []*models.Client
+	for _, client := range s.GetClients() {
+		if ban.Type == models.BanTypeUser && client.UserID == ban.Value ||
+			ban.Type == models.BanTypeIP && normalizeIP(remoteIP(client.RemoteAddr)) == ban.Value {
+			banned = append(banned, client)
+		}
+	}
+	for _, client := range banned {
+		s.disconnectBanned(client, ban)
+	}
+	return len(banned)
+}
+
+// RemoveBan lifts the ban of a user or IP address
+func (s *Server) RemoveBan(banType, value string) error {
+	if banType == models.BanTypeIP {
+		value = normalizeIP(value)
+	}
+	if err := s.liftBan(banType, value); err != nil {
+		return err
+	}
+	s.logger.Info("Lifted the ban of %s %s", banType, value)
+	s.shareUnban(banType, value)
+	return nil
+}
+
+// liftBan forgets a ban
+func (s *Server) liftBan(banType, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := banKey(banType, value)
+	if _, exists := s.bans[key]; !exists {
+		return models.ErrBanNotFound
+	}
+	delete(s.bans, key)
+	return nil
+}
+
+// GetBans returns the bans in force, oldest first
+func (s *Server) GetBans() []models.Ban {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	now := s.Now()
+	bans := make([]models.Ban, 0, len(s.bans))
+	for _, ban := range s.bans {
+		if ban.Active(now) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt.Before(bans[j].CreatedAt)
+	})
+	return bans
+}
+
+// activeBan returns the ban in force of a user or IP address
+func (s *Server) activeBan(banType, value string) (models.Ban, bool) {
+	if value == "" {
+		return models.Ban{}, false
+	}
+	if banType == models.BanTypeIP {
+		value = normalizeIP(value)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ban, exists := s.bans[banKey(banType, value)]
+	return ban, exists && ban.Active(s.Now())
+}
+
+// disconnectBanned tells a client it is banned and closes its connection
+func (s *Server) disconnectBanned(client *models.Client, ban models.Ban) {
+	data := map[string]interface{}{"reason": ban.Reason}
+	if ban.ExpiresAt != nil {
+		data["expires_at"] = ban.ExpiresAt
+	}
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "banned",
+		Priority:  models.PriorityHigh,
+		Data:      data,
+		Timestamp: s.Now(),
+	})
+	client.SetDisconnectReason(DisconnectReasonBanned)
+	s.recordRemoval(client, moderationRuleBanned, ban.Reason)
+	client.CloseAfterFlush()
+}
+
+// sweepBans forgets expired bans. The caller must hold the mutex.
+func (s *Server) sweepBans(now time.Time) int {
+	removed := 0
+	for key, ban := range s.bans {
+		if !ban.Active(now) {
+			delete(s.bans, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// banExpiry describes when a ban expires, for logs
+func banExpiry(ban models.Ban) string {
+	if ban.ExpiresAt == nil {
+		return "never"
+	}
+	return ban.ExpiresAt.Format(time.RFC3339)
+}
+
+// normalizeIP returns the canonical form of an IP address, so equivalent spellings match
+func normalizeIP(value string) string {
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return value
+}
@@ -0,0 +1,136 @@
+package websocket
+
+import (
+	"path"
+
+	"socket-server/internal/models"
+)
+
+// blobUpload is a blob a client announced with send_blob, collected from the binary frames it
+// sends next
+type blobUpload struct {
+	channel string
+	info    models.BlobInfo
+	data    []byte
+}
+
+// blobLimit returns the largest blob accepted on a channel: that of the first matching
+// CHANNEL_BLOB_LIMITS rule, or BLOB_MAX_BYTES
+func (s *Server) blobLimit(channelName string) int {
+	for _, rule := range s.blobRules {
+		if matched, err := path.Match(rule.Pattern, channelName); err == nil && matched {
+			return rule.Bytes
+		}
+	}
+	return s.config.BlobMaxSize
+}
+
+// handleSendBlob starts a blob upload: the client announces the blob's channel, event, size
+// and metadata, then streams its bytes in binary frames. The checks of send_message apply.
+func (s *Server) handleSendBlob(client *models.Client, msg map[string]interface{}) {
+	channelName, ok := msg["channel"].(string)
+	if !ok {
+		s.sendError(client, "Invalid channel name")
+		return
+	}
+	size, _ := msg["size"].(float64)
+	limit := s.blobLimit(channelName)
+	if limit == 0 {
+		s.recordRejection(client, channelName, "", moderationRuleBlobLimit)
+		s.sendError(client, "Blobs are not allowed on this channel")
+		return
+	}
+	if size < 1 || size != float64(int(size)) {
+		s.sendError(client, "Invalid blob size")
+		return
+	}
+	if int(size) > limit {
+		s.logger.Warn("Client %s announced a %d-byte blob on channel '%s', over the %d-byte limit", client.ID, int(size), channelName, limit)
+		s.recordRejection(client, channelName, "", moderationRuleBlobLimit)
+		s.sendError(client, "Blob too large")
+		return
+	}
+
+	if models.IsDirectChannel(channelName) && !models.IsDirectChannelParticipant(channelName, client.UserID) {
+		s.sendError(client, "Direct channel access denied")
+		return
+	}
+	if owner, isUserChannel := s.userChannelOwner(channelName); isUserChannel && owner != client.UserID {
+		s.sendError(client, "User channel access denied")
+		return
+	}
+	if channel, exists := s.GetChannel(channelName); exists && channel.ReadOnly {
+		s.recordRejection(client, channelName, "", moderationRuleReadOnly)
+		s.sendError(client, "Channel is read-only")
+		return
+	}
+
+	upload := &blobUpload{
+		channel: channelName,
+		info: models.BlobInfo{
+			ID:          s.NewID(),
+			Event:       getStringFromMap(msg, "event", "blob"),
+			Name:        getStringFromMap(msg, "name", ""),
+			ContentType: getStringFromMap(msg, "content_type", ""),
+			Size:        int(size),
+			Metadata:    msg["metadata"],
+		},
+		data: make([]byte, 0, int(size)),
+	}
+
+	s.mutex.Lock()
+	s.blobUploads[client.ID] = upload
+	s.mutex.Unlock()
+	s.logger.Debug("Client %s started blob %s of %d bytes on channel '%s'", client.ID, upload.info.ID, upload.info.Size, channelName)
+}
+
+// handleBlobData adds a binary frame to the client's blob upload. Once the announced size is
+// reached, the blob is broadcast to the channel and the sender gets a blob_sent confirmation.
+func (s *Server) handleBlobData(client *models.Client, data []byte) {
+	s.mutex.Lock()
+	upload, exists := s.blobUploads[client.ID]
+	if exists && len(upload.data)+len(data) >= upload.info.Size {
+		delete(s.blobUploads, client.ID)
+	}
+	s.mutex.Unlock()
+
+	if !exists {
+		s.sendError(client, "Unexpected binary frame, announce blobs with send_blob")
+		return
+	}
+	if len(upload.data)+len(data) > upload.info.Size {
+		s.logger.Warn("Client %s sent more bytes than announced for blob %s", client.ID, upload.info.ID)
+		s.sendError(client, "Blob larger than announced")
+		return
+	}
+	upload.data = append(upload.data, data...)
+	if len(upload.data) < upload.info.Size {
+		return
+	}
+
+	message := models.Message{
+		ID:        upload.info.ID,
+		Channel:   upload.channel,
+		Event:     models.BlobEvent,
+		Data:      upload.info,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		Timestamp: s.Now(),
+		Blob:      upload.data,
+	}
+	s.logger.MessageSent(client.ID, client.Username, upload.channel, models.BlobEvent, upload.info)
+
+	// Laravel is told about the blob; its bytes aren't part of the dispatched message
+	if err := s.laravelSvc.QueueMessage(message, client); err != nil {
+		s.logger.Error("Failed to dispatch message to Laravel: %v", err)
+	}
+	s.BroadcastToChannel(upload.channel, message)
+
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "blob_sent",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]interface{}{"blob_id": upload.info.ID, "channel": upload.channel, "size": upload.info.Size},
+		Timestamp: s.Now(),
+	})
+}
@@ -0,0 +1,22 @@
+package websocket
+
+import (
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// SetBridge forwards broadcasts on the bridge's channels to a remote server
+func (s *Server) SetBridge(bridge *services.BridgeService) {
+	s.bridge = bridge
+}
+
+// forwardToBridge queues a channel broadcast for the remote server. Broadcasts that arrived
+// over a bridge are not forwarded again, those relayed by another node of the cluster were
+// already forwarded by that node, and blobs stay on the node they were sent to.
+func (s *Server) forwardToBridge(channelName string, message models.Message) {
+	if message.BridgedFrom != "" || message.RelayedFrom != "" || message.Blob != nil || !s.bridge.Matches(channelName) {
+		return
+	}
+	message.Channel = channelName
+	s.bridge.Forward(message)
+}
@@ -0,0 +1,213 @@
+package websocket
+
+import (
+	"sort"
+
+	"socket-server/internal/models"
+)
+
+// UpdateChannelMetadata merges updates into a channel's metadata (a nil value removes a key),
+// creating the channel if it doesn't exist yet so metadata can be set before anyone joins.
+// Members are notified with a channel_updated event when anything changed.
+func (s *Server) UpdateChannelMetadata(channelName string, updates map[string]interface{}) (map[string]interface{}, []string) {
+	channel := s.getOrCreateChannel(channelName, false)
+
+	changed := channel.SetMetadata(updates)
+	metadata := channel.GetMetadata()
+	if len(changed) == 0 {
+		return metadata, changed
+	}
+
+	s.logger.Info("Channel '%s' metadata updated: %v", channelName, changed)
+	s.notifyChannelUpdated(channel, map[string]interface{}{
+		"changes":      []string{"metadata"},
+		"changed_keys": changed,
+		"metadata":     metadata,
+	})
+	return metadata, changed
+}
+
+// CreateChannel creates a channel ahead of its first subscriber, with settings applied over the
+// defaults the configuration gives its name
+func (s *Server) CreateChannel(channelName string, settings models.ChannelSettings) (*models.Channel, error) {
+	if settings.MaxClients != nil && *settings.MaxClients < 0 {
+		return nil, models.ErrInvalidChannelSettings
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.channels[channelName]; exists {
+		return nil, models.ErrChannelExists
+	}
+	channel := s.newChannel(channelName, false)
+	channel.ApplySettings(settings)
+	s.channels[channelName] = channel
+
+	s.logger.Info("Channel '%s' created with settings %v", channelName, channel.GetSettings())
+	return channel, nil
+}
+
+// CloseChannel deletes a channel, unsubscribing its members. They stay connected and receive a
+// channel_closed event with the reason, and their leaves are dispatched to Laravel. It returns
+// the number of members removed.
+func (s *Server) CloseChannel(channelName, reason string) (int, error) {
+	s.mutex.Lock()
+	channel, exists := s.channels[channelName]
+	delete(s.channels, channelName)
+	s.mutex.Unlock()
+	s.dropThrottle(channelName)
+	if !exists {
+		return 0, models.ErrChannelNotFound
+	}
+
+	var members map[string]*models.Client
+	storedMetadata := make(map[string]*models.ChannelMetadata)
+	channel.WithPublishLock(func() {
+		members = channel.GetClients()
+		s.sendToChannelMembers(channel, models.Message{
+			ID:        s.NewID(),
+			Channel:   channelName,
+			Event:     "channel_closed",
+			Priority:  models.PriorityHigh,
+			Data:      map[string]string{"channel": channelName, "reason": reason},
+			Timestamp: s.Now(),
+		})
+		for _, member := range members {
+			storedMetadata[member.ID] = member.GetChannelMetadata(channelName)
+			channel.RemoveClient(member.ID)
+			member.RemoveFromChannel(channelName)
+		}
+	})
+
+	for _, member := range members {
+		s.logger.ChannelLeft(member.ID, member.Username, channelName)
+
+		var dataToForward interface{}
+		if metadata := storedMetadata[member.ID]; metadata != nil {
+			dataToForward = metadata.Data
+		} else {
+			dataToForward = map[string]interface{}{
+				"channel":   channelName,
+				"client_id": member.ID,
+				"user_id":   member.UserID,
+				"username":  member.Username,
+				"reason":    reason,
+			}
+		}
+		leaveMessage := models.Message{
+			ID:        s.NewID(),
+			Channel:   channelName,
+			Event:     "leave_channel",
+			Data:      dataToForward,
+			UserID:    member.UserID,
+			Username:  member.Username,
+			Timestamp: s.Now(),
+		}
+		member := member
+		s.queueDispatch(member, func() {
+			if err := s.laravelSvc.DispatchMessage(leaveMessage, member); err != nil {
+				s.logger.Error("Failed to dispatch leave_channel message to Laravel: %v", err)
+			}
+		})
+	}
+
+	s.logger.Info("Channel '%s' closed (reason: %q), %d members removed", channelName, reason, len(members))
+	return len(members), nil
+}
+
+// UpdateChannelSettings changes a channel's access flags and capacity and notifies members with
+// a channel_updated event when anything changed
+func (s *Server) UpdateChannelSettings(channelName string, settings models.ChannelSettings) ([]string, error) {
+	channel, exists := s.GetChannel(channelName)
+	if !exists {
+		return nil, models.ErrChannelNotFound
+	}
+	if settings.MaxClients != nil && *settings.MaxClients < 0 {
+		return nil, models.ErrInvalidChannelSettings
+	}
+
+	changed := channel.ApplySettings(settings)
+	if len(changed) == 0 {
+		return changed, nil
+	}
+
+	s.logger.Info("Channel '%s' settings updated: %v", channelName, changed)
+	s.notifyChannelUpdated(channel, map[string]interface{}{
+		"changes":  changed,
+		"settings": channel.GetSettings(),
+	})
+	return changed, nil
+}
+
+// notifyChannelUpdated broadcasts a channel_updated system event to the channel's members.
+// It is sent under the publish lock so members see it in order with channel messages.
+func (s *Server) notifyChannelUpdated(channel *models.Channel, data map[string]interface{}) {
+	data["channel"] = channel.Name
+
+	channel.WithPublishLock(func() {
+		s.sendToChannelMembers(channel, models.Message{
+			ID:        s.NewID(),
+			Channel:   channel.Name,
+			Event:     "channel_updated",
+			Data:      data,
+			Timestamp: s.Now(),
+		})
+	})
+}
+
+// GetChildChannels returns the existing child rooms of a parent channel, sorted by name
+func (s *Server) GetChildChannels(parent string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	children := make([]string, 0)
+	for channelName := range s.channels {
+		if models.IsChildChannel(parent, channelName) {
+			children = append(children, channelName)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// BroadcastToChannelTree broadcasts a message to a parent channel and each of its child rooms,
+// returning its delivery on each channel, the parent's first
+func (s *Server) BroadcastToChannelTree(parent string, message models.Message) []models.ChannelDelivery {
+	channelNames := append([]string{parent}, s.GetChildChannels(parent)...)
+
+	deliveries := make([]models.ChannelDelivery, 0, len(channelNames))
+	for _, channelName := range channelNames {
+		channelMessage := message
+		channelMessage.Channel = channelName
+		deliveries = append(deliveries, s.BroadcastToChannel(channelName, channelMessage))
+	}
+
+	s.logger.Info("Broadcasted message to channel '%s' and %d child channels", parent, len(channelNames)-1)
+	return deliveries
+}
+
+// cascadeToChildren repeats a join or leave request for every child room of the channel when
+// the client sent "include_children": true. Each child goes through the regular checks; with
+// joinedOnly, children the client isn't a member of are skipped (for leaves).
+func (s *Server) cascadeToChildren(client *models.Client, msg map[string]interface{}, parent string, joinedOnly bool, handle func(*models.Client, map[string]interface{})) {
+	if includeChildren, _ := msg["include_children"].(bool); !includeChildren {
+		return
+	}
+
+	joined := client.GetChannels()
+	for _, child := range s.GetChildChannels(parent) {
+		if joinedOnly && !joined[child] {
+			continue
+		}
+
+		childMsg := make(map[string]interface{}, len(msg))
+		for key, value := range msg {
+			childMsg[key] = value
+		}
+		childMsg["channel"] = child
+		delete(childMsg, "include_children")
+
+		handle(client, childMsg)
+	}
+}
@@ -0,0 +1,44 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"socket-server/internal/models"
+)
+
+// handleChunk collects a chunk of a message too large for one frame. Once every chunk has
+// arrived, the reassembled message is handled like any other client message.
+func (s *Server) handleChunk(client *models.Client, msg map[string]interface{}) {
+	var chunk models.Chunk
+	chunk.ID, _ = msg["chunk_id"].(string)
+	chunk.Payload, _ = msg["payload"].(string)
+	index, indexOK := msg["index"].(float64)
+	total, totalOK := msg["total"].(float64)
+	if !indexOK || !totalOK {
+		s.sendError(client, "Invalid chunk")
+		return
+	}
+	chunk.Index, chunk.Total = int(index), int(total)
+
+	data, err := client.AddChunk(chunk)
+	switch {
+	case err == models.ErrChunkedMessageTooLarge:
+		s.logger.Warn("Client %s sent a chunked message over the size limit", client.ID)
+		s.sendError(client, "Chunked message too large")
+		return
+	case err != nil:
+		s.logger.Debug("Client %s sent an invalid chunk %d/%d of message %s", client.ID, chunk.Index+1, chunk.Total, chunk.ID)
+		s.sendError(client, "Invalid chunk")
+		return
+	case data == nil:
+		return
+	}
+
+	var message map[string]interface{}
+	if err := json.Unmarshal(data, &message); err != nil || message["action"] == "chunk" {
+		s.sendError(client, "Invalid message format")
+		return
+	}
+	s.logger.Debug("Client %s sent a %d-byte message in %d chunks", client.ID, len(data), chunk.Total)
+	s.handleAction(client, message)
+}
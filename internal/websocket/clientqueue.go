@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"sync"
+
+	"socket-server/internal/models"
+)
+
+// clientQueueSize is the number of a client's actions waiting to be handled past which the
+// server stops reading from the client until Laravel catches up
+const clientQueueSize = 64
+
+// clientQueue runs a client's actions and Laravel dispatches one at a time, in the order they
+// were queued, on a goroutine that only exists while there is work. The connection keeps being
+// read and pinged while Laravel handles a dispatch, and Laravel still receives each client's
+// events in the order they happened.
+type clientQueue struct {
+	mutex   sync.Mutex
+	space   *sync.Cond // signaled when jobs are taken off the queue
+	jobs    []queuedJob
+	running bool
+	closed  bool
+}
+
+// queuedJob is an action read from the client, or a Laravel dispatch of the server
+type queuedJob struct {
+	run    func()
+	action bool // actions are dropped once the client disconnected, like unread messages
+}
+
+func newClientQueue() *clientQueue {
+	q := &clientQueue{}
+	q.space = sync.NewCond(&q.mutex)
+	return q
+}
+
+// push queues a job. Actions wait for room in the queue, and are dropped once it is closed.
+func (q *clientQueue) push(job queuedJob) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for job.action && len(q.jobs) >= clientQueueSize && !q.closed {
+		q.space.Wait()
+	}
+	if job.action && q.closed {
+		return
+	}
+	q.jobs = append(q.jobs, job)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+// pushDispatch queues a Laravel dispatch, which never waits for room. Without a queue, the
+// dispatch runs on a goroutine of its own.
+func (q *clientQueue) pushDispatch(dispatch func()) {
+	if q == nil {
+		go dispatch()
+		return
+	}
+	q.push(queuedJob{run: dispatch})
+}
+
+// close drops the actions still queued and refuses further ones; dispatches still run
+func (q *clientQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	kept := q.jobs[:0]
+	for _, job := range q.jobs {
+		if !job.action {
+			kept = append(kept, job)
+		}
+	}
+	q.jobs = kept
+	q.space.Broadcast()
+}
+
+// run handles the queued jobs until none is left
+func (q *clientQueue) run() {
+	for {
+		q.mutex.Lock()
+		if len(q.jobs) == 0 {
+			q.running = false
+			q.mutex.Unlock()
+			return
+		}
+		job := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		q.space.Broadcast()
+		q.mutex.Unlock()
+
+		job.run()
+	}
+}
+
+// openClientQueue creates the queue of a client being registered
+func (s *Server) openClientQueue(client *models.Client) {
+	s.queuesMutex.Lock()
+	s.clientQueues[client.ID] = newClientQueue()
+	s.queuesMutex.Unlock()
+}
+
+// clientQueueOf returns the queue of a registered client, nil for other clients
+func (s *Server) clientQueueOf(client *models.Client) *clientQueue {
+	s.queuesMutex.RLock()
+	defer s.queuesMutex.RUnlock()
+	return s.clientQueues[client.ID]
+}
+
+// queueAction handles an action of the client after its earlier actions and dispatches. The
+// caller waits while the client has clientQueueSize actions queued already. Actions of clients
+// without a queue are handled right away.
+func (s *Server) queueAction(client *models.Client, action func()) {
+	queue := s.clientQueueOf(client)
+	if queue == nil {
+		action()
+		return
+	}
+	queue.push(queuedJob{run: func() {
+		defer s.recoverClient(client, "queue")
+		action()
+	}, action: true})
+}
+
+// queueDispatch runs a Laravel dispatch of the client after its earlier actions and dispatches,
+// without waiting for it
+func (s *Server) queueDispatch(client *models.Client, dispatch func()) {
+	s.clientQueueOf(client).pushDispatch(dispatch)
+}
+
+// closeClientQueue drops the actions a disconnecting client still has queued and refuses
+// further ones. Its dispatches keep being queued in order until removeClientQueue.
+func (s *Server) closeClientQueue(client *models.Client) {
+	if queue := s.clientQueueOf(client); queue != nil {
+		queue.close()
+	}
+}
+
+// removeClientQueue forgets the queue of a disconnected client once its last dispatch was
+// queued; the queued dispatches still run
+func (s *Server) removeClientQueue(client *models.Client) {
+	s.queuesMutex.Lock()
+	delete(s.clientQueues, client.ID)
+	s.queuesMutex.Unlock()
+}
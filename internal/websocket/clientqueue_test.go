@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"socket-server/internal/auth"
+	"socket-server/internal/config"
+	"socket-server/internal/services"
+	"socket-server/pkg/logger"
+)
+
+// laravelScript is a PHP stand-in: it appends the action of each payload to a file, and holds
+// connection and join payloads until a release file exists
+const laravelScript = `#!/bin/sh
+action=$(grep -o '"action":"[a-z_]*"' "$4" | head -1 | cut -d'"' -f4)
+case "$action" in
+client_connected|join_channel) while [ ! -e "$0.release" ]; do sleep 0.01; done ;;
+esac
+echo "$action" >> "$0.actions"
+`
+
+func TestClientDispatchesRunInOrderWithoutHoldingUpTheClient(t *testing.T) {
+	for _, connectionEvents := range []bool{true, false} {
+		dir := t.TempDir()
+		script := filepath.Join(dir, "php")
+		if err := os.WriteFile(script, []byte(laravelScript), 0700); err != nil {
+			t.Fatalf("Failed to write the Laravel script: %v", err)
+		}
+
+		cfg := config.New()
+		cfg.JWTSecret = "secret"
+		cfg.DispatchConnectionEvents = connectionEvents
+		log := logger.New(false)
+		laravelSvc := services.NewLaravelService(dir, script, "socket:handle", t.TempDir(), log)
+		server := New(cfg, auth.New(cfg.JWTSecret), laravelSvc, log)
+
+		// The client is greeted while Laravel holds the connection or join dispatch
+		conn := dialTestServer(t, server, "")
+		conn.send(map[string]interface{}{"action": "join_channel", "channel": "news"})
+		conn.send(map[string]interface{}{"action": "send_message", "channel": "news", "event": "sent"})
+		os.WriteFile(script+".release", nil, 0600)
+		conn.expect("joined_channel")
+		conn.conn.Close()
+
+		expected := []string{"client_connected", "join_channel", "sent", "leave_channel", "client_disconnected"}
+		if !connectionEvents {
+			expected = expected[1:4]
+		}
+		var got []string
+		for deadline := time.Now().Add(2 * time.Second); len(got) < len(expected); time.Sleep(10 * time.Millisecond) {
+			data, _ := os.ReadFile(script + ".actions")
+			got = strings.Fields(string(data))
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the dispatches %v, got %v", expected, got)
+			}
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected the dispatches in the order of the client's events %v, got %v", expected, got)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+func TestServersKeepTheirOwnClocks(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	manual := models.NewManualClock(start)
+	server := newTestServer(t, &config.Config{})
+	server.SetClock(manual.Now)
+	server.SetIDGenerator(models.NewSequentialIDGenerator("msg"))
+	other := newTestServer(t, &config.Config{})
+
+	expiresAt := start.Add(time.Minute)
+	ban, _, err := server.AddBan(models.Ban{Type: models.BanTypeUser, Value: "42", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("Failed to add the ban: %v", err)
+	}
+	if !ban.CreatedAt.Equal(start) {
+		t.Errorf("Expected the ban created at the injected time %v, got %v", start, ban.CreatedAt)
+	}
+	if _, active := server.activeBan(models.BanTypeUser, "42"); !active {
+		t.Error("Expected the ban active before its expiry")
+	}
+	manual.Advance(2 * time.Minute)
+	if _, active := server.activeBan(models.BanTypeUser, "42"); active {
+		t.Error("Expected the ban expired once the server's clock passed its expiry")
+	}
+	manual.Set(start)
+	if group, _ := server.CreateGroup("eu", []string{"news"}, ""); !group.CreatedAt.Equal(start) {
+		t.Errorf("Expected the group created at the injected time %v, got %v", start, group.CreatedAt)
+	}
+	if id := server.NewID(); id != "msg-1" {
+		t.Errorf("Expected the injected ID generator, got %s", id)
+	}
+
+	// Clients and channels stamp their events on the server's clock
+	client := models.NewClientWithTimekeeper(server.NewID(), nil, &server.Timekeeper)
+	channel := server.getOrCreateChannel("chat", false)
+	manual.Advance(time.Minute)
+	client.AddToChannelWithEvents("chat", nil, nil)
+	cursor, _ := channel.UpdateReadCursor("42", 0)
+	if !client.ConnectedAt.Equal(start) || !channel.CreatedAt.Equal(start) {
+		t.Errorf("Expected the client and channel created at the injected time %v, got %v and %v", start, client.ConnectedAt, channel.CreatedAt)
+	}
+	if joinedAt := client.GetChannelMetadata("chat").JoinedAt; !joinedAt.Equal(start.Add(time.Minute)) || !cursor.UpdatedAt.Equal(joinedAt) {
+		t.Errorf("Expected the join and read cursor at the injected time, got %v and %v", joinedAt, cursor.UpdatedAt)
+	}
+
+	if now := other.Now(); now.Before(time.Now().Add(-time.Minute)) {
+		t.Errorf("Expected another server on the system clock, got %v", now)
+	}
+	if id := other.NewID(); id == "msg-2" {
+		t.Error("Expected another server unaffected by the injected ID generator")
+	}
+}
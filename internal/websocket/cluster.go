@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// clusterMessage is a channel broadcast relayed to the other nodes. It keeps the ID and origin
+// of the message, so nodes drop copies that reach them again over another route.
+type clusterMessage struct {
+	ID              string          `json:"id"`
+	Origin          string          `json:"origin"`
+	Channel         string          `json:"channel"`
+	Event           string          `json:"event"`
+	Data            interface{}     `json:"data"`
+	UserID          string          `json:"user_id,omitempty"`
+	Username        string          `json:"username,omitempty"`
+	CoalesceKey     string          `json:"coalesce_key,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
+	Priority        models.Priority `json:"priority,omitempty"`
+	Template        bool            `json:"template,omitempty"`
+	BridgedFrom     string          `json:"bridged_from,omitempty"`
+	ExcludeClientID string          `json:"exclude_client_id,omitempty"` // may be a connection of any node
+}
+
+// SetCluster shares this node's bans, the broadcasts on the relayed channels and its load with
+// the other nodes of a cluster and applies theirs. It starts listening for the other nodes'
+// events and advertising the node's load.
+func (s *Server) SetCluster(cluster *services.ClusterService) {
+	s.cluster = cluster
+	go cluster.Listen(s.handleClusterEvent, s.syncClusterBans)
+	go s.advertiseStatus()
+}
+
+// relayToCluster publishes a channel broadcast to the other nodes. Broadcasts relayed by
+// another node, and blobs, are only delivered locally.
+func (s *Server) relayToCluster(channelName string, message models.Message) {
+	if message.RelayedFrom != "" || message.Blob != nil || !s.cluster.Matches(channelName) {
+		return
+	}
+	err := s.cluster.Publish(services.ClusterEventBroadcast, clusterMessage{
+		ID:              message.ID,
+		Origin:          message.Origin,
+		Channel:         channelName,
+		Event:           message.Event,
+		Data:            message.Data,
+		UserID:          message.UserID,
+		Username:        message.Username,
+		CoalesceKey:     message.CoalesceKey,
+		Timestamp:       message.Timestamp,
+		Priority:        message.Priority,
+		Template:        message.Template,
+		BridgedFrom:     message.BridgedFrom,
+		ExcludeClientID: message.ExcludeClientID,
+	})
+	if err != nil {
+		s.logger.Warn("Cluster: failed to relay message %s on channel %s: %v", message.ID, channelName, err)
+	}
+}
+
+// handleClusterEvent applies a ban change made on another node, delivers a broadcast relayed by
+// another node to the local subscribers, or records another node's load
+func (s *Server) handleClusterEvent(event services.ClusterEvent) {
+	if event.Type == services.ClusterEventStatus {
+		s.handleClusterStatus(event)
+		return
+	}
+	if event.Type == services.ClusterEventBroadcast {
+		var relayed clusterMessage
+		if err := json.Unmarshal(event.Data, &relayed); err != nil || relayed.ID == "" || relayed.Channel == "" {
+			s.logger.Warn("Cluster: ignoring malformed %s event from node %s: %v", event.Type, event.Node, err)
+			return
+		}
+		s.BroadcastToChannel(relayed.Channel, models.Message{
+			ID:              relayed.ID,
+			Origin:          relayed.Origin,
+			Channel:         relayed.Channel,
+			Event:           relayed.Event,
+			Data:            relayed.Data,
+			UserID:          relayed.UserID,
+			Username:        relayed.Username,
+			CoalesceKey:     relayed.CoalesceKey,
+			Timestamp:       relayed.Timestamp,
+			Priority:        relayed.Priority,
+			Template:        relayed.Template,
+			BridgedFrom:     relayed.BridgedFrom,
+			RelayedFrom:     event.Node,
+			ExcludeClientID: relayed.ExcludeClientID,
+		})
+		return
+	}
+
+	var ban models.Ban
+	if err := json.Unmarshal(event.Data, &ban); err != nil {
+		s.logger.Warn("Cluster: ignoring malformed %s event from node %s: %v", event.Type, event.Node, err)
+		return
+	}
+
+	switch event.Type {
+	case services.ClusterEventBan:
+		disconnected := s.storeBan(ban)
+		s.logger.Warn("Banned %s %s on node %s (reason: %q, expires: %s), %d connections closed", ban.Type, ban.Value, event.Node, ban.Reason, banExpiry(ban), disconnected)
+	case services.ClusterEventUnban:
+		if s.liftBan(ban.Type, ban.Value) == nil {
+			s.logger.Info("Lifted the ban of %s %s on node %s", ban.Type, ban.Value, event.Node)
+		}
+	}
+}
+
+// syncClusterBans merges this node's bans with the cluster's ban list, once subscribed to the
+// cluster's events: bans made elsewhere while this node was cut off are applied, and bans of
+// this node missing from the list (restored from its state file, or lost by a Redis restart)
+// are added to it. Bans are never lifted by a sync, so a lost list can't unban anyone.
+func (s *Server) syncClusterBans() {
+	bans, err := s.cluster.LoadBans()
+	if err != nil {
+		s.logger.Warn("Cluster: failed to load the ban list: %v", err)
+		return
+	}
+
+	shared := make(map[string]bool, len(bans))
+	for _, ban := range bans {
+		shared[banKey(ban.Type, ban.Value)] = true
+		if existing, exists := s.activeBan(ban.Type, ban.Value); !exists || !existing.CreatedAt.Equal(ban.CreatedAt) {
+			s.storeBan(ban)
+		}
+	}
+
+	for _, ban := range s.GetBans() {
+		if !shared[banKey(ban.Type, ban.Value)] {
+			s.shareBan(ban)
+		}
+	}
+	s.logger.Info("Cluster: merged %d bans of the ban list", len(bans))
+}
+
+// shareBan adds a ban made on this node to the cluster's ban list and tells the other nodes
+func (s *Server) shareBan(ban models.Ban) {
+	if s.cluster == nil {
+		return
+	}
+	if err := s.cluster.SaveBan(ban); err != nil {
+		s.logger.Warn("Cluster: failed to save the ban of %s %s: %v", ban.Type, ban.Value, err)
+	}
+	if err := s.cluster.Publish(services.ClusterEventBan, ban); err != nil {
+		s.logger.Warn("Cluster: failed to publish the ban of %s %s: %v", ban.Type, ban.Value, err)
+	}
+}
+
+// shareUnban removes a ban lifted on this node from the cluster's ban list and tells the other
+// nodes
+func (s *Server) shareUnban(banType, value string) {
+	if s.cluster == nil {
+		return
+	}
+	if err := s.cluster.DeleteBan(banType, value); err != nil {
+		s.logger.Warn("Cluster: failed to delete the ban of %s %s: %v", banType, value, err)
+	}
+	if err := s.cluster.Publish(services.ClusterEventUnban, models.Ban{Type: banType, Value: value}); err != nil {
+		s.logger.Warn("Cluster: failed to publish the lifted ban of %s %s: %v", banType, value, err)
+	}
+}
@@ -0,0 +1,158 @@
+package websocket
+
+import (
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// fallbackSinkTimeout bounds a delivery through a fallback sink
+const fallbackSinkTimeout = 30 * time.Second
+
+// AddFallbackSink registers a sink that critical user broadcasts are delivered through when
+// the user stays offline. Each delivery runs with its own timeout and panic isolation.
+func (s *Server) AddFallbackSink(sink services.FallbackSink) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.fallbackSinks = append(s.fallbackSinks, services.GuardSink(sink, fallbackSinkTimeout))
+}
+
+// sinkHealth returns the records of the sinks messages are delivered to: Laravel, the bridge,
+// push providers and fallback sinks
+func (s *Server) sinkHealth() []services.SinkHealth {
+	health := []services.SinkHealth{s.laravelSvc.Health()}
+	if s.bridge != nil {
+		health = append(health, s.bridge.Health())
+	}
+	health = append(health, s.push.Health()...)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, sink := range s.fallbackSinks {
+		if guarded, ok := sink.(*services.GuardedSink); ok {
+			health = append(health, guarded.Health())
+		}
+	}
+	return health
+}
+
+// markUserOffline records when a user's connection ended. Users with other connections are
+// still online; userOfflineSince only consults this once they have none left.
+func (s *Server) markUserOffline(userID string, at time.Time) {
+	if userID == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	s.userLastSeen[userID] = at
+	s.mutex.Unlock()
+}
+
+// UserLastSeen returns when a user's last connection ended, if this server remembers it.
+// Disconnections are forgotten once older than the critical offline threshold.
+func (s *Server) UserLastSeen(userID string) (time.Time, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	lastSeen, ok := s.userLastSeen[userID]
+	return lastSeen, ok
+}
+
+// userOfflineSince returns when a user went offline, and false while the user has an active
+// connection. Users this server hasn't seen count as offline since it started serving clients.
+func (s *Server) userOfflineSince(userID string) (time.Time, bool) {
+	if len(s.GetUserClients(userID)) > 0 {
+		return time.Time{}, false
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if lastSeen, ok := s.userLastSeen[userID]; ok {
+		return lastSeen, true
+	}
+	if s.servingSince.After(s.startedAt) {
+		return s.servingSince, true
+	}
+	return s.startedAt, true
+}
+
+// DeliverCritical schedules the fallback delivery of a critical broadcast that found the user
+// offline. Sinks run once the user has been offline for the critical threshold, immediately
+// if that has already passed, and are skipped if the user reconnects in the meantime.
+func (s *Server) DeliverCritical(userID string, delivery models.CriticalDelivery, message models.Message) models.CriticalResult {
+	var result models.CriticalResult
+
+	s.mutex.RLock()
+	sinks := append([]services.FallbackSink{}, s.fallbackSinks...)
+	s.mutex.RUnlock()
+	if len(sinks) == 0 {
+		result.Skipped = "no fallback sinks are configured"
+		return result
+	}
+
+	offlineSince, offline := s.userOfflineSince(userID)
+	if !offline {
+		result.Skipped = "user is online"
+		return result
+	}
+
+	for _, sink := range sinks {
+		result.Sinks = append(result.Sinks, sink.Name())
+	}
+	result.DeliverAt = offlineSince.Add(s.config.CriticalOfflineThreshold)
+	if now := s.Now(); result.DeliverAt.Before(now) {
+		result.DeliverAt = now
+	}
+
+	time.AfterFunc(result.DeliverAt.Sub(s.Now()), func() {
+		s.runFallbackSinks(userID, delivery, message, sinks)
+	})
+	s.logger.Info("Critical message %s for offline user %s: fallback through %v at %s", message.ID, userID, result.Sinks, result.DeliverAt.Format(time.RFC3339))
+	return result
+}
+
+// runFallbackSinks delivers a critical message through every sink, unless the user came back
+// online. A user who reconnected and left again is given the full threshold from their new
+// disconnection.
+func (s *Server) runFallbackSinks(userID string, delivery models.CriticalDelivery, message models.Message, sinks []services.FallbackSink) {
+	offlineSince, offline := s.userOfflineSince(userID)
+	if !offline {
+		s.logger.Info("Critical message %s: user %s is back online, skipping the fallback", message.ID, userID)
+		return
+	}
+	if wait := offlineSince.Add(s.config.CriticalOfflineThreshold).Sub(s.Now()); wait > 0 {
+		time.AfterFunc(wait, func() {
+			s.runFallbackSinks(userID, delivery, message, sinks)
+		})
+		return
+	}
+
+	for _, sink := range sinks {
+		if err := sink.Deliver(userID, delivery, message); err != nil {
+			s.logger.Error("Critical message %s: %s fallback for user %s failed: %v", message.ID, sink.Name(), userID, err)
+			continue
+		}
+		s.logger.Info("Critical message %s delivered to user %s by %s", message.ID, userID, sink.Name())
+	}
+}
+
+// sweepUserLastSeen forgets disconnections older than the critical threshold, since those
+// users count as offline long enough either way. The caller must hold the mutex.
+func (s *Server) sweepUserLastSeen(now time.Time) int {
+	threshold := s.config.CriticalOfflineThreshold
+	if now.Sub(s.startedAt) < threshold {
+		return 0
+	}
+
+	removed := 0
+	for userID, lastSeen := range s.userLastSeen {
+		if now.Sub(lastSeen) >= threshold {
+			delete(s.userLastSeen, userID)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,31 @@
+package websocket
+
+import (
+	"time"
+
+	"socket-server/internal/models"
+)
+
+const (
+	// dedupWindow is how long delivered broadcasts are remembered, longer than a bridge spends
+	// retrying a forward
+	dedupWindow = 5 * time.Minute
+	// dedupLimit caps the broadcasts remembered, forgetting the oldest first
+	dedupLimit = 100000
+)
+
+// firstDelivery reports whether a channel broadcast is delivered on this node for the first
+// time. Only broadcasts that can reach the node again are remembered: those the cluster or a
+// bridge relays, and those that arrived over a bridge. IDs are remembered per channel, since a
+// broadcast to a channel tree sends the same message to each room.
+func (s *Server) firstDelivery(channelName string, message models.Message) bool {
+	if s.cluster == nil && s.bridge == nil && message.BridgedFrom == "" {
+		return true
+	}
+	if s.recentBroadcasts.Add(channelName+"\x00"+message.ID, s.Now()) {
+		return true
+	}
+	s.duplicateBroadcasts.Add(1)
+	s.logger.Debug("Dropping duplicate message %s on channel %s from node %s", message.ID, channelName, message.Origin)
+	return false
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"sort"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// Reasons a client is reported as slow
+const (
+	SlowReasonQueueDepth      = "queue_depth"
+	SlowReasonSlowWrites      = "slow_writes"
+	SlowReasonHighRTT         = "high_rtt"
+	SlowReasonDroppedMessages = "dropped_messages"
+)
+
+// defaultSlowClientRTT is the round-trip time above which a client is reported by default
+const defaultSlowClientRTT = 500 * time.Millisecond
+
+// defaultSlowClientWrites is the number of slow writes above which a client is reported by default
+const defaultSlowClientWrites = 3
+
+// SlowClientCriteria are the thresholds at which a client is reported as slow. A client
+// meeting any of them is reported.
+type SlowClientCriteria struct {
+	QueueDepth int           // queued outbound messages
+	RTT        time.Duration // latest ping round trip
+	SlowWrites uint64        // writes that took over half the write timeout
+}
+
+// SlowClient is a client with bad connectivity, with the reasons it was reported
+type SlowClient struct {
+	ClientID   string             `json:"client_id"`
+	UserID     string             `json:"user_id,omitempty"`
+	Username   string             `json:"username,omitempty"`
+	RemoteAddr string             `json:"remote_addr"`
+	UserAgent  string             `json:"user_agent,omitempty"`
+	Channels   []string           `json:"channels"`
+	Reasons    []string           `json:"reasons"`
+	Stats      models.ClientStats `json:"stats"`
+}
+
+// DefaultSlowClientCriteria reports clients whose queue is half full, whose round trip is over
+// 500ms, or that had several slow writes
+func (s *Server) DefaultSlowClientCriteria() SlowClientCriteria {
+	queueSize := s.config.SendQueueSize
+	if queueSize <= 0 {
+		queueSize = models.DefaultSendQueueSize
+	}
+	return SlowClientCriteria{
+		QueueDepth: max(queueSize/2, 1),
+		RTT:        defaultSlowClientRTT,
+		SlowWrites: defaultSlowClientWrites,
+	}
+}
+
+// SlowClients returns the clients meeting any of the criteria, or that dropped messages
+// because their queue was full. The clients matching the most reasons come first.
+func (s *Server) SlowClients(criteria SlowClientCriteria) []SlowClient {
+	report := make([]SlowClient, 0)
+	for _, client := range s.GetClients() {
+		stats := client.Stats()
+
+		var reasons []string
+		if stats.QueueLength >= criteria.QueueDepth {
+			reasons = append(reasons, SlowReasonQueueDepth)
+		}
+		if stats.SlowWrites >= criteria.SlowWrites {
+			reasons = append(reasons, SlowReasonSlowWrites)
+		}
+		// The RTT is 0 until the first pong arrives
+		if stats.RTT > 0 && time.Duration(stats.RTT*float64(time.Millisecond)) >= criteria.RTT {
+			reasons = append(reasons, SlowReasonHighRTT)
+		}
+		if stats.MessagesDropped > 0 {
+			reasons = append(reasons, SlowReasonDroppedMessages)
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		channels := make([]string, 0)
+		for channelName := range client.GetChannels() {
+			channels = append(channels, channelName)
+		}
+		sort.Strings(channels)
+
+		report = append(report, SlowClient{
+			ClientID:   client.ID,
+			UserID:     client.UserID,
+			Username:   client.Username,
+			RemoteAddr: client.RemoteAddr,
+			UserAgent:  client.UserAgent,
+			Channels:   channels,
+			Reasons:    reasons,
+			Stats:      stats,
+		})
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if len(a.Reasons) != len(b.Reasons) {
+			return len(a.Reasons) > len(b.Reasons)
+		}
+		if a.Stats.QueueLength != b.Stats.QueueLength {
+			return a.Stats.QueueLength > b.Stats.QueueLength
+		}
+		return a.Stats.RTT > b.Stats.RTT
+	})
+	return report
+}
@@ -0,0 +1,65 @@
+package websocket
+
+import (
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// IsDraining reports whether the server has stopped accepting new connections
+func (s *Server) IsDraining() bool {
+	return s.draining.Load()
+}
+
+// Drain stops accepting new connections, tells every client to reconnect elsewhere and closes
+// their connections spread evenly over the configured shutdown grace period, so a rolling update doesn't send every client
+// to the remaining pods at once. It blocks until all connections are closed; concurrent calls
+// (e.g. a preStop hook followed by SIGTERM) wait for the same drain.
+func (s *Server) Drain() {
+	s.drainOnce.Do(func() {
+		go s.drain(s.config.ShutdownGrace)
+	})
+	<-s.drained
+}
+
+// drain performs the actual connection draining
+func (s *Server) drain(window time.Duration) {
+	defer close(s.drained)
+
+	s.draining.Store(true)
+	s.flushPendingLeaves()
+	if s.cluster != nil {
+		// Peers stop suggesting this node to their clients
+		s.publishStatus()
+	}
+	endpoints := s.SuggestedEndpoints()
+
+	clients := s.GetClients()
+	s.logger.Info("Draining %d connections over %v", len(clients), window)
+	if len(clients) == 0 {
+		return
+	}
+
+	interval := window / time.Duration(len(clients))
+	for _, client := range clients {
+		data := map[string]interface{}{"node_id": s.NodeID(), "reason": client.Translate("Server is shutting down, please reconnect")}
+		if len(endpoints) > 0 {
+			data["suggested_endpoints"] = endpoints
+		}
+		client.SendMessage(models.Message{
+			ID:        s.NewID(),
+			Event:     "server_draining",
+			Priority:  models.PriorityHigh,
+			Data:      data,
+			Timestamp: s.Now(),
+		})
+		client.SetDisconnectReason(DisconnectReasonServerDraining)
+		client.CloseAfterFlush()
+
+		if interval > 0 {
+			time.Sleep(interval)
+		}
+	}
+
+	s.logger.Info("Drain complete")
+}
@@ -0,0 +1,160 @@
+package websocket
+
+import (
+	"path"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+// fingerprintClient computes the fingerprint of a client authenticating as userID, from the
+// device_id it sent or else its address and User-Agent
+func fingerprintClient(client *models.Client, userID string, msg map[string]interface{}) string {
+	deviceID, _ := msg["device_id"].(string)
+	return models.ConnectionFingerprint(userID, deviceID, remoteIP(client.RemoteAddr), client.UserAgent)
+}
+
+// isDuplicate reports whether other duplicates the session of a client authenticated as userID
+// with the given fingerprint. With the "user" scope, any other connection of the user does.
+func (s *Server) isDuplicate(client, other *models.Client, userID, fingerprint string) bool {
+	if other.ID == client.ID || userID == "" || other.UserID != userID {
+		return false
+	}
+	return s.config.DuplicateConnectionScope == "user" || other.GetFingerprint() == fingerprint
+}
+
+// admitConnection applies user bans and the duplicate connection policy to a client
+// authenticating as userID. It returns false when the client must stay unauthenticated; banned
+// users are also disconnected. With newest-wins, the user's older duplicate connections are
+// closed instead.
+func (s *Server) admitConnection(client *models.Client, userID, fingerprint string) bool {
+	if ban, banned := s.activeBan(models.BanTypeUser, userID); banned {
+		s.logger.Warn("Client %s denied authentication: user %s is banned", client.ID, userID)
+		s.disconnectBanned(client, ban)
+		return false
+	}
+
+	policy := s.config.DuplicateConnectionPolicy
+	if policy == "" || policy == config.DuplicatePolicyAllow {
+		return true
+	}
+
+	var duplicates []*models.Client
+	for _, other := range s.GetUserClients(userID) {
+		if s.isDuplicate(client, other, userID, fingerprint) {
+			duplicates = append(duplicates, other)
+		}
+	}
+	if len(duplicates) == 0 {
+		return true
+	}
+
+	if policy == config.DuplicatePolicyDeny {
+		s.logger.Warn("Client %s denied authentication: user %s is already connected as %s", client.ID, userID, duplicates[0].ID)
+		s.sendError(client, "Already connected from another session")
+		return false
+	}
+
+	for _, other := range duplicates {
+		s.logger.Info("Closing client %s: user %s connected again as %s", other.ID, userID, client.ID)
+		other.SendMessage(models.Message{
+			ID:        s.NewID(),
+			Event:     "session_replaced",
+			Priority:  models.PriorityHigh,
+			Data:      map[string]string{"reason": DisconnectReasonDuplicateSession},
+			Timestamp: s.Now(),
+		})
+		other.SetDisconnectReason(DisconnectReasonDuplicateSession)
+		other.CloseAfterFlush()
+	}
+	return true
+}
+
+// channelDuplicatePolicy returns the duplicate policy of the first rule matching a channel
+func (s *Server) channelDuplicatePolicy(channelName string) string {
+	for _, rule := range s.duplicateRules {
+		if matched, err := path.Match(rule.Pattern, channelName); err == nil && matched {
+			return rule.Policy
+		}
+	}
+	return config.DuplicatePolicyAllow
+}
+
+// channelDuplicates returns the subscribers of a channel duplicating the client's session
+func (s *Server) channelDuplicates(client *models.Client, channel *models.Channel) []*models.Client {
+	fingerprint := client.GetFingerprint()
+
+	var duplicates []*models.Client
+	for _, other := range channel.GetClients() {
+		if s.isDuplicate(client, other, client.UserID, fingerprint) {
+			duplicates = append(duplicates, other)
+		}
+	}
+	return duplicates
+}
+
+// admitChannelJoin refuses joins to "deny" channels the client's session is already
+// subscribed to from another connection
+func (s *Server) admitChannelJoin(client *models.Client, channel *models.Channel) bool {
+	if s.channelDuplicatePolicy(channel.Name) != config.DuplicatePolicyDeny {
+		return true
+	}
+	if duplicates := s.channelDuplicates(client, channel); len(duplicates) > 0 {
+		s.logger.Warn("Client %s denied access to channel '%s': user %s is already subscribed as %s", client.ID, channel.Name, client.UserID, duplicates[0].ID)
+		s.sendError(client, "Already subscribed from another session")
+		return false
+	}
+	return true
+}
+
+// replaceChannelDuplicates unsubscribes the older duplicates of a client that just joined a
+// "newest-wins" channel. They stay connected, and are told why they left.
+func (s *Server) replaceChannelDuplicates(client *models.Client, channel *models.Channel) {
+	if s.channelDuplicatePolicy(channel.Name) != config.DuplicatePolicyNewestWins {
+		return
+	}
+
+	for _, other := range s.channelDuplicates(client, channel) {
+		storedMetadata := other.GetChannelMetadata(channel.Name)
+		channel.RemoveClient(other.ID)
+		other.RemoveFromChannel(channel.Name)
+		s.logger.ChannelLeft(other.ID, other.Username, channel.Name)
+		s.logger.Info("Client %s left channel '%s': user %s subscribed again as %s", other.ID, channel.Name, other.UserID, client.ID)
+
+		var dataToForward interface{}
+		if storedMetadata != nil {
+			dataToForward = storedMetadata.Data
+		} else {
+			dataToForward = map[string]interface{}{
+				"channel":   channel.Name,
+				"client_id": other.ID,
+				"user_id":   other.UserID,
+				"username":  other.Username,
+				"reason":    DisconnectReasonDuplicateSession,
+			}
+		}
+		leaveMessage := models.Message{
+			ID:        s.NewID(),
+			Channel:   channel.Name,
+			Event:     "leave_channel",
+			Data:      dataToForward,
+			UserID:    other.UserID,
+			Username:  other.Username,
+			Timestamp: s.Now(),
+		}
+		other := other
+		s.queueDispatch(other, func() {
+			if err := s.laravelSvc.DispatchMessage(leaveMessage, other); err != nil {
+				s.logger.Error("Failed to dispatch leave_channel message to Laravel: %v", err)
+			}
+		})
+
+		other.SendMessage(models.Message{
+			ID:        s.NewID(),
+			Event:     "left_channel",
+			Priority:  models.PriorityHigh,
+			Data:      map[string]string{"channel": channel.Name, "reason": DisconnectReasonDuplicateSession},
+			Timestamp: s.Now(),
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"net"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+// ApplyDynamicSettings applies settings read from the dynamic configuration backend.
+// Omitted settings are left unchanged. Reliable channel patterns only affect channels
+// created after the update. Connections from newly banned IPs are closed, and channel
+// settings apply to existing channels and to channels created later. The groups a document
+// defines replace those of the previous document, so a group removed from the backend is
+// deleted; groups created through the API are left alone.
+func (s *Server) ApplyDynamicSettings(settings *config.DynamicSettings) {
+	s.applyDynamicSettings(settings)
+
+	// Connections and channels are updated once the settings are in place, without the lock
+	if settings.BannedIPs != nil {
+		s.disconnectBannedIPs()
+	}
+	for name, channelSettings := range settings.ChannelSettings {
+		if _, err := s.UpdateChannelSettings(name, channelSettings); err != nil && err != models.ErrChannelNotFound {
+			s.logger.Warn("Dynamic config: failed to update channel %s: %v", name, err)
+		}
+	}
+}
+
+// applyDynamicSettings stores the settings of a dynamic configuration document
+func (s *Server) applyDynamicSettings(settings *config.DynamicSettings) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if settings.ChannelRateLimits != nil {
+		// Rules are validated when the document is parsed
+		rateLimits, _ := config.ParseRateLimitRules(*settings.ChannelRateLimits)
+		s.setRateLimits(rateLimits)
+		s.config.ChannelRateLimits = *settings.ChannelRateLimits
+		s.logger.Info("Dynamic config: %d channel rate limit rules loaded", len(rateLimits))
+	}
+
+	if settings.ReliableChannels != nil {
+		s.config.ReliableChannels = settings.ReliableChannels
+		s.logger.Info("Dynamic config: reliable channels set to %v", settings.ReliableChannels)
+	}
+
+	if settings.AllowedOrigins != nil {
+		s.config.AllowedOrigins = settings.AllowedOrigins
+		s.logger.Info("Dynamic config: allowed origins set to %v", settings.AllowedOrigins)
+	}
+
+	if settings.BannedIPs != nil {
+		s.bannedIPs = make(map[string]bool, len(settings.BannedIPs))
+		for _, ip := range settings.BannedIPs {
+			s.bannedIPs[normalizeIP(ip)] = true
+		}
+		s.logger.Info("Dynamic config: %d banned IP addresses loaded", len(s.bannedIPs))
+	}
+
+	if settings.ChannelSettings != nil {
+		s.channelSettings = settings.ChannelSettings
+		s.logger.Info("Dynamic config: settings of %d channels loaded", len(settings.ChannelSettings))
+	}
+
+	if settings.ChannelGroups != nil {
+		for name := range s.dynamicGroups {
+			if _, defined := settings.ChannelGroups[name]; !defined {
+				delete(s.groups, name)
+				delete(s.dynamicGroups, name)
+				s.logger.Info("Dynamic config: removed channel group %s", name)
+			}
+		}
+	}
+	for name, definition := range settings.ChannelGroups {
+		if err := models.ValidatePattern(definition.Pattern); err != nil {
+			s.logger.Warn("Dynamic config: skipping group %s with invalid pattern %q", name, definition.Pattern)
+			continue
+		}
+
+		if group, exists := s.groups[name]; exists {
+			if !s.dynamicGroups[name] {
+				s.logger.Warn("Dynamic config: skipping group %s, a group of that name was created through the API", name)
+				continue
+			}
+			group.SetChannels(definition.Channels)
+			group.SetPattern(definition.Pattern)
+		} else {
+			s.groups[name] = s.newGroup(name, definition.Channels, definition.Pattern)
+		}
+		s.dynamicGroups[name] = true
+	}
+	if len(settings.ChannelGroups) > 0 {
+		s.logger.Info("Dynamic config: %d channel groups defined", len(settings.ChannelGroups))
+	}
+}
+
+// disconnectBannedIPs closes the connections from IPs banned by the dynamic configuration
+func (s *Server) disconnectBannedIPs() {
+	for _, client := range s.GetClients() {
+		ip := normalizeIP(remoteIP(client.RemoteAddr))
+		s.mutex.RLock()
+		banned := s.bannedIPs[ip]
+		s.mutex.RUnlock()
+		if banned {
+			s.logger.Warn("Dynamic config: closing connection %s from banned IP %s", client.ID, ip)
+			s.disconnectBanned(client, models.Ban{Type: models.BanTypeIP, Value: ip})
+		}
+	}
+}
+
+// isBannedIP reports whether a remote address belongs to an IP banned by the dynamic
+// configuration or through the ban API
+func (s *Server) isBannedIP(remoteAddr string) bool {
+	ip := remoteIP(remoteAddr)
+	if _, banned := s.activeBan(models.BanTypeIP, ip); banned {
+		return true
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.bannedIPs[normalizeIP(ip)]
+}
+
+// remoteIP strips the port from a remote address
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
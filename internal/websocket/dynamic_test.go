@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"socket-server/internal/config"
+)
+
+func TestApplyDynamicSettingsConvergesGroups(t *testing.T) {
+	server := newTestServer(t, &config.Config{})
+	if _, err := server.CreateGroup("api", []string{"orders"}, ""); err != nil {
+		t.Fatalf("Failed to create the API group: %v", err)
+	}
+
+	apply := func(document string) {
+		settings, err := config.ParseDynamicSettings([]byte(document))
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", document, err)
+		}
+		server.ApplyDynamicSettings(settings)
+	}
+	groups := func() map[string]bool {
+		names := make(map[string]bool)
+		for name := range server.GetGroups() {
+			names[name] = true
+		}
+		return names
+	}
+
+	apply(`{"channel_groups": {"eu": {"channels": ["paris"]}, "us": {"pattern": "us.*"}, "api": {"channels": ["refunds"]}}}`)
+	if names := groups(); len(names) != 3 || !names["eu"] || !names["us"] {
+		t.Fatalf("Expected the API group and both dynamic groups, got %v", names)
+	}
+	if group, _ := server.GetGroup("api"); len(group.GetChannels()) != 1 || group.GetChannels()[0] != "orders" {
+		t.Errorf("Expected the API group kept over the dynamic group of the same name, got %v", group.GetChannels())
+	}
+
+	apply(`{"channel_groups": {"eu": {"channels": ["paris", "berlin"]}}}`)
+	if names := groups(); len(names) != 2 || !names["api"] || !names["eu"] {
+		t.Fatalf("Expected the group removed from the document to be deleted, got %v", names)
+	}
+	if group, _ := server.GetGroup("eu"); len(group.GetChannels()) != 2 {
+		t.Errorf("Expected the remaining group to be updated, got %v", group.GetChannels())
+	}
+
+	apply(`{"banned_ips": []}`)
+	if names := groups(); len(names) != 2 {
+		t.Errorf("Expected a document without channel_groups to leave the groups unchanged, got %v", names)
+	}
+
+	// A restart restores the group from the snapshot, still owned by the dynamic configuration
+	restarted := newTestServer(t, &config.Config{})
+	restarted.RestoreSnapshot(server.TakeSnapshot())
+	server = restarted
+	apply(`{"channel_groups": {}}`)
+	if names := groups(); len(names) != 1 || !names["api"] {
+		t.Errorf("Expected only the API group left, got %v", names)
+	}
+}
+
+func TestApplyDynamicSettingsReachesConnectionsAndChannels(t *testing.T) {
+	server := newTestServer(t, &config.Config{})
+	apply := func(document string) {
+		settings, err := config.ParseDynamicSettings([]byte(document))
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", document, err)
+		}
+		server.ApplyDynamicSettings(settings)
+	}
+
+	request := httptest.NewRequest("GET", "http://socket.example.com/ws", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	if server.checkOrigin(request) {
+		t.Fatal("Expected another origin refused by default")
+	}
+	apply(`{"allowed_origins": ["https://*.example.com"]}`)
+	if !server.checkOrigin(request) {
+		t.Error("Expected the origin allowed by the dynamic configuration")
+	}
+
+	conn := dialTestServer(t, server, "")
+	conn.send(map[string]interface{}{"action": "join_channel", "channel": "announcements"})
+	conn.expect("joined_channel")
+	apply(`{"channel_settings": {"announcements": {"read_only": true}, "alerts": {"max_clients": 5}}}`)
+	conn.expect("channel_updated")
+	if channel, _ := server.GetChannel("announcements"); !channel.ReadOnly {
+		t.Error("Expected the existing channel made read-only")
+	}
+	if channel := server.getOrCreateChannel("alerts", false); channel.MaxClients != 5 {
+		t.Errorf("Expected a channel created later to get its settings, got a capacity of %d", channel.MaxClients)
+	}
+
+	// The connection comes from 127.0.0.1
+	apply(`{"banned_ips": ["10.0.0.1"]}`)
+	if len(server.GetClients()) != 1 {
+		t.Fatal("Expected connections from other IPs kept")
+	}
+	apply(`{"banned_ips": ["127.0.0.1"]}`)
+	conn.expect("banned")
+	if _, open := <-conn.events; open {
+		t.Error("Expected the connection from the banned IP closed")
+	}
+}
@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"sort"
+
+	"socket-server/internal/models"
+)
+
+// maxSubscribedEvents is the most event names a connection may subscribe to in one channel
+const maxSubscribedEvents = 50
+
+// parseSubscribedEvents reads the events of a join_channel message: a list of event names, sorted
+// and without duplicates. A missing list subscribes to every event.
+func parseSubscribedEvents(value interface{}) ([]string, bool) {
+	var names []string
+	switch list := value.(type) {
+	case nil:
+		return nil, true
+	case []string:
+		names = list
+	case []interface{}:
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			names = append(names, name)
+		}
+	default:
+		return nil, false
+	}
+
+	seen := make(map[string]bool, len(names))
+	events := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, false
+		}
+		if !seen[name] {
+			seen[name] = true
+			events = append(events, name)
+		}
+	}
+	if len(events) == 0 || len(events) > maxSubscribedEvents {
+		return nil, false
+	}
+	sort.Strings(events)
+	return events, true
+}
+
+// removeUnsubscribed drops the members that didn't subscribe to an event of the channel
+func removeUnsubscribed(members map[string]*models.Client, channelName, event string) {
+	for id, client := range members {
+		if !client.WantsEvent(channelName, event) {
+			delete(members, id)
+		}
+	}
+}
+
+// subscribedHistory returns the messages of a channel's history a member subscribed to
+func subscribedHistory(client *models.Client, channelName string, history []models.Message) []models.Message {
+	metadata := client.GetChannelMetadata(channelName)
+	if metadata == nil || len(metadata.Events) == 0 {
+		return history
+	}
+	wanted := make([]models.Message, 0, len(history))
+	for _, message := range history {
+		if metadata.WantsEvent(message.Event) {
+			wanted = append(wanted, message)
+		}
+	}
+	return wanted
+}
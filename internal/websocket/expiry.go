@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StartExpirySweeper periodically reclaims expired channel history, channel grants and resumable
+// sessions so long-running servers don't grow without bound. It does nothing when the sweep
+// interval is 0.
+func (s *Server) StartExpirySweeper() {
+	interval := s.config.ExpirySweepInterval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			entries, bytes := s.sweepExpired(s.Now())
+			if entries > 0 {
+				s.logger.Debug("Expiry sweep reclaimed %d entries (~%d bytes)", entries, bytes)
+			}
+		}
+	}()
+}
+
+// sweepExpired removes history older than the history TTL, channel grants, resumable sessions
+// and bans past their expiry, and disconnection times no longer needed for critical broadcasts.
+// It returns the number of entries removed and an estimate of the bytes reclaimed.
+func (s *Server) sweepExpired(now time.Time) (entries, bytes int) {
+	if ttl := s.config.HistoryTTL; ttl > 0 {
+		for _, channel := range s.GetChannels() {
+			for _, message := range channel.ExpireHistory(now.Add(-ttl)) {
+				entries++
+				if data, err := json.Marshal(message); err == nil {
+					bytes += len(data)
+				}
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	for userID, grants := range s.userGrants {
+		for channelName, expiresAt := range grants {
+			if now.After(expiresAt) {
+				delete(grants, channelName)
+				entries++
+				bytes += len(channelName)
+			}
+		}
+		if len(grants) == 0 {
+			delete(s.userGrants, userID)
+		}
+	}
+	entries += s.sweepResumableSessions(now)
+	entries += s.sweepUserLastSeen(now)
+	entries += s.sweepBans(now)
+	s.mutex.Unlock()
+
+	s.expiredEntries.Add(uint64(entries))
+	s.reclaimedBytes.Add(uint64(bytes))
+	return entries, bytes
+}
@@ -0,0 +1,38 @@
+package websocket
+
+import (
+	"strings"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// SetGeoIPService enables Geo-IP enrichment of new connections
+func (s *Server) SetGeoIPService(geoIP *services.GeoIPService) {
+	s.geoIP = geoIP
+}
+
+// isCountryAllowed applies the configured country allow and block lists. Connections whose
+// country cannot be resolved are only rejected when an allow list is configured.
+func (s *Server) isCountryAllowed(geo *models.GeoInfo) bool {
+	country := ""
+	if geo != nil {
+		country = geo.Country
+	}
+
+	for _, blocked := range s.config.GeoBlockedCountries {
+		if strings.EqualFold(blocked, country) {
+			return false
+		}
+	}
+
+	if len(s.config.GeoAllowedCountries) == 0 {
+		return true
+	}
+	for _, allowed := range s.config.GeoAllowedCountries {
+		if strings.EqualFold(allowed, country) {
+			return true
+		}
+	}
+	return false
+}
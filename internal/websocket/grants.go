@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// DefaultUserGrantTTL is how long a server-side channel grant keeps applying to new connections
+// of the user when no TTL is given
+const DefaultUserGrantTTL = time.Hour
+
+// GrantUserChannel subscribes every current connection of a user to a channel and remembers the
+// grant for ttl, so connections the user authenticates later are subscribed as well. Access was
+// approved by the caller (usually Laravel), so the join isn't dispatched back for approval.
+// It returns the number of connections that were joined.
+func (s *Server) GrantUserChannel(userID, channelName string, ttl time.Duration) int {
+	if ttl <= 0 {
+		ttl = DefaultUserGrantTTL
+	}
+
+	s.mutex.Lock()
+	grants, exists := s.userGrants[userID]
+	if !exists {
+		grants = make(map[string]time.Time)
+		s.userGrants[userID] = grants
+	}
+	grants[channelName] = s.Now().Add(ttl)
+	s.mutex.Unlock()
+
+	channel := s.getOrCreateChannel(channelName, true)
+
+	joined := 0
+	for _, client := range s.GetUserClients(userID) {
+		if s.joinGrantedChannel(client, channel) {
+			joined++
+		}
+	}
+
+	s.logger.Info("User %s granted channel '%s' for %v (%d connections joined)", userID, channelName, ttl, joined)
+	return joined
+}
+
+// applyUserGrants subscribes a freshly authenticated client to the channels its user was granted,
+// dropping grants that have expired
+func (s *Server) applyUserGrants(client *models.Client) {
+	now := s.Now()
+	var channelNames []string
+
+	s.mutex.Lock()
+	for channelName, expiresAt := range s.userGrants[client.UserID] {
+		if now.After(expiresAt) {
+			delete(s.userGrants[client.UserID], channelName)
+			continue
+		}
+		channelNames = append(channelNames, channelName)
+	}
+	if len(s.userGrants[client.UserID]) == 0 {
+		delete(s.userGrants, client.UserID)
+	}
+	s.mutex.Unlock()
+
+	for _, channelName := range channelNames {
+		s.joinGrantedChannel(client, s.getOrCreateChannel(channelName, true))
+	}
+}
+
+// joinGrantedChannel adds a client to a granted channel unless it is already a member.
+// It reports whether the client was added.
+func (s *Server) joinGrantedChannel(client *models.Client, channel *models.Channel) bool {
+	if client.GetChannels()[channel.Name] {
+		return false
+	}
+
+	if err := s.addClientToChannel(client, channel, nil, nil); err != nil {
+		s.logger.Warn("Client %s could not be joined to granted channel '%s': %v", client.ID, channel.Name, err)
+		s.sendError(client, "Channel is full")
+		return false
+	}
+	return true
+}
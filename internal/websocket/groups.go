@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"sort"
+
+	"socket-server/internal/models"
+)
+
+// CreateGroup registers a new named channel group
+func (s *Server) CreateGroup(name string, channels []string, pattern string) (*models.ChannelGroup, error) {
+	if err := models.ValidatePattern(pattern); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.groups[name]; exists {
+		return nil, models.ErrGroupExists
+	}
+
+	group := s.newGroup(name, channels, pattern)
+	s.groups[name] = group
+	s.logger.Info("Created channel group '%s' (%d channels, pattern: %q)", name, len(group.Channels), pattern)
+	return group, nil
+}
+
+// newGroup creates a channel group created at the server's time, without adding it to the
+// server
+func (s *Server) newGroup(name string, channels []string, pattern string) *models.ChannelGroup {
+	group := models.NewChannelGroup(name, channels, pattern)
+	group.CreatedAt = s.Now()
+	return group
+}
+
+// DeleteGroup removes a channel group
+func (s *Server) DeleteGroup(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.groups[name]; !exists {
+		return models.ErrGroupNotFound
+	}
+
+	delete(s.groups, name)
+	delete(s.dynamicGroups, name)
+	s.logger.Info("Deleted channel group '%s'", name)
+	return nil
+}
+
+// GetGroups returns all channel groups
+func (s *Server) GetGroups() map[string]*models.ChannelGroup {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	groups := make(map[string]*models.ChannelGroup)
+	for k, v := range s.groups {
+		groups[k] = v
+	}
+	return groups
+}
+
+// GetGroup returns a specific channel group
+func (s *Server) GetGroup(name string) (*models.ChannelGroup, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	group, exists := s.groups[name]
+	return group, exists
+}
+
+// ResolveGroupChannels returns the names of all existing channels that belong to a group
+func (s *Server) ResolveGroupChannels(name string) ([]string, error) {
+	group, exists := s.GetGroup(name)
+	if !exists {
+		return nil, models.ErrGroupNotFound
+	}
+
+	channelNames := make([]string, 0)
+	for channelName := range s.GetChannels() {
+		if group.Matches(channelName) {
+			channelNames = append(channelNames, channelName)
+		}
+	}
+	sort.Strings(channelNames)
+	return channelNames, nil
+}
+
+// BroadcastToGroup sends a message to every channel in a group and returns its delivery on each
+func (s *Server) BroadcastToGroup(name string, message models.Message) ([]models.ChannelDelivery, error) {
+	channelNames, err := s.ResolveGroupChannels(name)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.ChannelDelivery, 0, len(channelNames))
+	for _, channelName := range channelNames {
+		channelMessage := message
+		channelMessage.Channel = channelName
+		deliveries = append(deliveries, s.BroadcastToChannel(channelName, channelMessage))
+	}
+
+	s.logger.Info("Broadcasted message to %d channels in group '%s'", len(channelNames), name)
+	return deliveries, nil
+}
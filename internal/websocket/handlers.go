@@ -1,9 +1,13 @@
 package websocket
 
 import (
+	"encoding/json"
+	"path"
+	"sort"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
 
 	"socket-server/internal/models"
 )
@@ -14,49 +18,105 @@ func (s *Server) handleClientMessages(client *models.Client, done chan bool) {
 		s.logger.Debug("Client %s message handler exiting", client.ID)
 		done <- true
 	}()
+	defer s.recoverClient(client, "reader")
 
 	for {
+		messageType, data, err := client.SafeReadMessage()
 		var msg map[string]interface{}
-		err := client.SafeReadJSON(&msg)
+		if err == nil && messageType != websocket.BinaryMessage {
+			err = json.Unmarshal(data, &msg)
+		}
 		if err != nil {
-			if err == models.ErrNilConnection {
-				s.logger.Debug("Client %s connection became nil during message read", client.ID)
-			} else {
-				s.logger.WebSocketError(client.ID, err)
-			}
+			s.recordReadError(client, err)
 			break
 		}
 
 		// Reset read deadline on successful message
-		if err := client.SafeSetReadDeadline(time.Now().Add(60 * time.Second)); err != nil {
+		if err := client.SafeSetReadDeadline(time.Now().Add(client.ReadTimeout())); err != nil {
 			s.logger.Debug("Client %s failed to set read deadline: %v", client.ID, err)
 			break
 		}
-		client.LastSeen = time.Now()
+		s.handleClientMessage(client, messageType, data, msg)
+	}
+}
 
-		// Log incoming message
-		actionStr := "unknown"
-		if action, ok := msg["action"].(string); ok {
-			actionStr = action
-		}
+// handleClientMessage handles a message read from a client: the bytes of a blob, or an action.
+// It is queued behind the client's earlier messages and Laravel dispatches.
+func (s *Server) handleClientMessage(client *models.Client, messageType int, data []byte, msg map[string]interface{}) {
+	client.LastSeen = s.Now()
+	s.messagesReceived.Add(1)
 
-		s.logger.MessageReceived(client.ID, client.Username, actionStr, msg)
-
-		// Handle different message types
-		switch msg["action"] {
-		case "authenticate":
-			s.handleAuthentication(client, msg)
-		case "join_channel":
-			s.handleJoinChannel(client, msg)
-		case "leave_channel":
-			s.handleLeaveChannel(client, msg)
-		case "send_message":
-			s.handleSendMessage(client, msg)
-		case "ping":
-			s.handlePing(client)
-		default:
-			s.handleMessage(client, msg)
+	s.queueAction(client, func() {
+		if messageType == websocket.BinaryMessage {
+			s.handleBlobData(client, data)
+			return
 		}
+		s.handleAction(client, msg)
+	})
+}
+
+// recordReadError logs why reading from a client failed and records the disconnect reason
+func (s *Server) recordReadError(client *models.Client, err error) {
+	if err == models.ErrNilConnection {
+		s.logger.Debug("Client %s connection became nil during message read", client.ID)
+	} else {
+		s.logger.WebSocketError(client.ID, err)
+	}
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		client.SetDisconnectReason(DisconnectReasonClientClosed)
+	} else {
+		client.SetDisconnectReason(DisconnectReasonConnectionLost)
+	}
+}
+
+// handleAction runs a client message through the rate limit and dispatches it by its action
+func (s *Server) handleAction(client *models.Client, msg map[string]interface{}) {
+	// Log incoming message
+	actionStr := "unknown"
+	if action, ok := msg["action"].(string); ok {
+		actionStr = action
+	}
+
+	s.logger.MessageReceived(client.ID, client.Username, actionStr, msg)
+
+	if !s.admitMessage(client) || !s.admitObserverAction(client, actionStr) {
+		return
+	}
+
+	// Handle different message types
+	switch msg["action"] {
+	case "authenticate":
+		s.handleAuthentication(client, msg)
+	case "resume":
+		s.handleResume(client, msg)
+	case "join_channel":
+		s.handleJoinChannel(client, msg)
+	case "leave_channel":
+		s.handleLeaveChannel(client, msg)
+	case "subscribe_pattern":
+		s.handleSubscribePattern(client, msg)
+	case "unsubscribe_pattern":
+		s.handleUnsubscribePattern(client, msg)
+	case "send_message":
+		s.handleSendMessage(client, msg)
+	case "send_blob":
+		s.handleSendBlob(client, msg)
+	case "request_upload":
+		s.handleRequestUpload(client, msg)
+	case "complete_upload":
+		s.handleCompleteUpload(client, msg)
+	case "open_direct_channel":
+		s.handleOpenDirectChannel(client, msg)
+	case "mark_read":
+		s.handleMarkRead(client, msg)
+	case "set_compression":
+		s.handleSetCompression(client, msg)
+	case "ping":
+		s.handlePing(client)
+	case "chunk":
+		s.handleChunk(client, msg)
+	default:
+		s.handleMessage(client, msg)
 	}
 }
 
@@ -69,13 +129,13 @@ func (s *Server) handleMessage(client *models.Client, msg map[string]interface{}
 
 	// Convert raw message to models.Message
 	message := models.Message{
-		ID:        uuid.New().String(),
+		ID:        s.NewID(),
 		Event:     getStringFromMap(msg, "action", "unknown"),
 		Channel:   getStringFromMap(msg, "channel", ""),
-		Data:      msg["data"],
+		Data:      models.StampIdentity(msg["data"], client.UserID, client.Username),
 		UserID:    client.UserID,
 		Username:  client.Username,
-		Timestamp: time.Now(),
+		Timestamp: s.Now(),
 	}
 
 	// Log specifically for ping messages
@@ -87,17 +147,12 @@ func (s *Server) handleMessage(client *models.Client, msg map[string]interface{}
 	if message.Event == "ping" && message.Channel == "" && message.Data == nil {
 		s.logger.Info("🏓 Handling ping internally, not sending to Laravel")
 		// Just send pong back to client
-		pong := models.Message{
-			ID:        uuid.New().String(),
-			Event:     "pong",
-			Timestamp: time.Now(),
-		}
-		client.SendMessage(pong)
+		s.sendPong(client)
 		return
 	}
 
 	start := time.Now()
-	s.laravelSvc.DispatchMessage(message, client)
+	s.laravelSvc.QueueMessage(message, client)
 	duration := time.Since(start)
 
 	if message.Event == "ping" {
@@ -114,13 +169,18 @@ func getStringFromMap(m map[string]interface{}, key string, defaultValue string)
 }
 
 // handleClientPing manages ping/pong for connection health
-func (s *Server) handleClientPing(client *models.Client, pingTicker *time.Ticker, done chan bool) {
+func (s *Server) handleClientPing(client *models.Client, done chan bool) {
 	defer func() {
 		s.logger.Debug("Client %s ping handler exiting", client.ID)
 		done <- true
 	}()
+	defer s.recoverClient(client, "ping")
+
+	// The interval adapts to the connection's health, so the timer is re-armed after each ping
+	pingTimer := time.NewTimer(client.PingInterval())
+	defer pingTimer.Stop()
 
-	for range pingTicker.C {
+	for range pingTimer.C {
 		// Check if client connection is still valid before sending ping
 		if !client.IsConnected() {
 			s.logger.Debug("Client %s connection is no longer valid, stopping ping handler", client.ID)
@@ -146,9 +206,11 @@ func (s *Server) handleClientPing(client *models.Client, pingTicker *time.Ticker
 			} else {
 				s.logger.Error("Failed to send ping to client %s: %v", client.ID, err)
 			}
+			client.SetDisconnectReason(DisconnectReasonPingFailed)
 			return
 		}
 		s.logger.PingSent(client.ID)
+		pingTimer.Reset(client.PingInterval())
 	}
 }
 
@@ -160,6 +222,10 @@ func (s *Server) handleAuthentication(client *models.Client, msg map[string]inte
 		s.sendError(client, "Invalid token format")
 		return
 	}
+	if s.isAPIToken(tokenStr) {
+		s.signInObserver(client)
+		return
+	}
 
 	s.logger.Debug("Client %s attempting JWT authentication", client.ID)
 
@@ -170,13 +236,44 @@ func (s *Server) handleAuthentication(client *models.Client, msg map[string]inte
 		s.laravelSvc.DispatchAuthentication(client, "failed", tokenStr)
 		return
 	}
+	s.completeAuthentication(client, tokenStr, claims, msg)
+}
 
+// completeAuthentication signs a client in with the claims of its validated token, then joins
+// its personal channel and granted channels and issues its resume token
+func (s *Server) completeAuthentication(client *models.Client, tokenStr string, claims jwt.MapClaims, msg map[string]interface{}) {
 	// Extract user info from claims
 	userID, username, email := s.authService.ExtractUserInfo(claims)
+	if !s.signIn(client, userID, username, email, msg) {
+		return
+	}
+	if s.authService.IsObserver(claims) {
+		client.SetObserver()
+		s.logger.Info("Client %s (user %s) connected as an observer", client.ID, userID)
+	}
+	if locale, ok := claims["locale"].(string); ok && locale != "" {
+		client.SetLocale(locale)
+	}
+	s.laravelSvc.DispatchAuthentication(client, "success", tokenStr)
+
+	s.joinUserChannel(client)
+	s.applyUserGrants(client)
+	s.issueResumeToken(client)
+}
+
+// signIn sets the identity of a client whose credentials were verified, unless the user is
+// banned or the duplicate connection policy refuses the connection
+func (s *Server) signIn(client *models.Client, userID, username, email string, msg map[string]interface{}) bool {
+	fingerprint := fingerprintClient(client, userID, msg)
+	if !s.admitConnection(client, userID, fingerprint) {
+		return false
+	}
 	client.SetUserInfo(userID, username, email)
+	client.SetFingerprint(fingerprint)
 
 	s.logger.ClientAuthenticated(client.ID, client.Username, client.UserID)
-	s.laravelSvc.DispatchAuthentication(client, "success", tokenStr)
+	s.analytics.RecordUser(client.UserID)
+	return true
 }
 
 // handleJoinChannel adds client to a channel
@@ -194,6 +291,52 @@ func (s *Server) handleJoinChannel(client *models.Client, msg map[string]interfa
 		privateStatus = false // Default to public channel if not specified
 	}
 
+	// A join may subscribe to some events of the channel only
+	events, ok := parseSubscribedEvents(msg["events"])
+	if !ok {
+		s.sendError(client, "Invalid events")
+		return
+	}
+
+	// A join may also select the language of error and system messages
+	if locale, ok := msg["locale"].(string); ok && locale != "" {
+		client.SetLocale(locale)
+	}
+
+	if client.Observer {
+		s.observeChannel(client, msg, channelName, privateStatus)
+		return
+	}
+
+	// Direct message channels are restricted to their two participants
+	if models.IsDirectChannel(channelName) {
+		if _, _, err := models.ParseDirectChannelName(channelName); err != nil {
+			s.logger.Warn("Client %s sent invalid direct channel name '%s'", client.ID, channelName)
+			s.sendError(client, "Invalid direct channel name")
+			return
+		}
+		if !models.IsDirectChannelParticipant(channelName, client.UserID) {
+			s.logger.Warn("Client %s (user %s) denied access to direct channel '%s'", client.ID, client.UserID, channelName)
+			s.sendError(client, "Direct channel access denied")
+			return
+		}
+		privateStatus = true
+	}
+
+	// Personal user channels are restricted to their owner
+	if owner, isUserChannel := s.userChannelOwner(channelName); isUserChannel {
+		if owner != client.UserID {
+			s.logger.Warn("Client %s (user %s) denied access to user channel '%s'", client.ID, client.UserID, channelName)
+			s.sendError(client, "User channel access denied")
+			return
+		}
+		privateStatus = true
+	}
+
+	if !s.admitChannelCount(client, channelName) {
+		return
+	}
+
 	s.logger.Debug("Client %s (%s) attempting to join channel '%s'", client.ID, client.Username, channelName)
 
 	// Get or create channel
@@ -206,6 +349,16 @@ func (s *Server) handleJoinChannel(client *models.Client, msg map[string]interfa
 		return
 	}
 
+	if channel.IsFull(client.ID) {
+		s.logger.Warn("Client %s denied access to channel '%s': channel is full", client.ID, channelName)
+		s.sendError(client, "Channel is full")
+		return
+	}
+
+	if !s.admitChannelJoin(client, channel) {
+		return
+	}
+
 	// Create message for Laravel dispatch
 	// Forward optional data from client, or nil if not provided
 	var dataToForward interface{}
@@ -216,40 +369,210 @@ func (s *Server) handleJoinChannel(client *models.Client, msg map[string]interfa
 	}
 
 	joinMessage := models.Message{
-		ID:        uuid.New().String(),
+		ID:        s.NewID(),
 		Channel:   channelName,
 		Event:     "join_channel",
 		Data:      dataToForward,
 		Private:   &privateStatus,
 		UserID:    client.UserID,
 		Username:  client.Username,
-		Timestamp: time.Now(),
+		Timestamp: s.Now(),
 	}
 
+	// A user rejoining within the presence grace period was already approved: the held-back
+	// leave and this join cancel out, so Laravel sees no membership churn
+	rejoin := s.takePendingLeave(client.UserID, channelName)
+
 	// Dispatch to Laravel
 	// if the command works with no errors, we'll assume the joinning is approved
 	// and proceed to add the client to the channel
-	if err := s.laravelSvc.DispatchMessage(joinMessage, client); err != nil {
+	var err error
+	if rejoin != nil {
+		s.logger.Debug("User %s rejoined channel '%s' within the presence grace period", client.UserID, channelName)
+	} else {
+		err = s.laravelSvc.DispatchMessage(joinMessage, client)
+	}
+	if err != nil {
 		s.logger.Error("Failed to dispatch join_channel message to Laravel: %v", err)
 	} else {
 
-		// Add client to channel with metadata
-		channel.AddClient(client)
-		client.AddToChannelWithMetadata(channelName, dataToForward)
+		// Add client to channel with metadata; capacity is re-checked in case the channel
+		// filled up while Laravel was approving the join
+		if err := s.addClientToChannel(client, channel, dataToForward, events); err != nil {
+			s.logger.Warn("Client %s denied access to channel '%s': %v", client.ID, channelName, err)
+			s.sendError(client, "Channel is full")
+			if rejoin != nil {
+				s.dispatchLeave(rejoin.client, rejoin.message)
+			}
+			return
+		}
+		s.replaceChannelDuplicates(client, channel)
+
+		s.cascadeToChildren(client, msg, channelName, false, s.handleJoinChannel)
+	}
+}
+
+// addClientToChannel subscribes an approved client to a channel, or only to the given events of
+// it, then sends the joined_channel confirmation and the channel history. It fails with
+// models.ErrChannelFull at capacity.
+func (s *Server) addClientToChannel(client *models.Client, channel *models.Channel, metadata interface{}, events []string) error {
+	if err := channel.TryAddClient(client); err != nil {
+		return err
+	}
+	client.AddToChannelWithEvents(channel.Name, metadata, events)
+	s.analytics.RecordSubscribers(channel.Name, client.UserID, channel.GetClientCount())
+
+	// Server-initiated joins (personal channel, grants) also cancel a held-back leave
+	s.takePendingLeave(client.UserID, channel.Name)
+
+	s.logger.ChannelJoined(client.ID, client.Username, channel.Name)
+
+	// Send confirmation
+	data := map[string]interface{}{"channel": channel.Name, "metadata": channel.GetMetadata(), "settings": channel.GetSettings()}
+	if len(events) > 0 {
+		data["events"] = events
+	}
+	confirmation := models.Message{
+		ID:        s.NewID(),
+		Event:     "joined_channel",
+		Priority:  models.PriorityHigh,
+		Data:      data,
+		Timestamp: s.Now(),
+	}
+	client.SendMessage(confirmation)
+
+	s.sendChannelHistory(client, channel)
+	return nil
+}
+
+// handleOpenDirectChannel creates (on demand) and joins the direct channel between the client and another user
+func (s *Server) handleOpenDirectChannel(client *models.Client, msg map[string]interface{}) {
+	if client.UserID == "" {
+		s.sendError(client, "Direct channels require authentication")
+		return
+	}
+
+	targetUserID := getStringFromMap(msg, "user_id", "")
+	if targetUserID == "" || targetUserID == client.UserID {
+		s.logger.Error("Client %s sent invalid user_id for direct channel", client.ID)
+		s.sendError(client, "Invalid user_id")
+		return
+	}
+
+	channelName := models.DirectChannelName(client.UserID, targetUserID)
+	joinMsg := map[string]interface{}{
+		"action":  "join_channel",
+		"channel": channelName,
+		"private": true,
+	}
+	if data, exists := msg["data"]; exists {
+		joinMsg["data"] = data
+	}
+	s.handleJoinChannel(client, joinMsg)
+
+	// Let the other participant's connections know the channel is available
+	if channel, exists := s.GetChannel(channelName); exists && channel.GetClients()[client.ID] != nil {
+		s.BroadcastToUser(targetUserID, models.Message{
+			ID:       s.NewID(),
+			Event:    "direct_channel_opened",
+			Priority: models.PriorityHigh,
+			Data: map[string]string{
+				"channel":  channelName,
+				"user_id":  client.UserID,
+				"username": client.Username,
+			},
+			Timestamp: s.Now(),
+		})
+	}
+}
+
+// sendChannelHistory delivers a channel's retained messages to a client that just joined
+func (s *Server) sendChannelHistory(client *models.Client, channel *models.Channel) {
+	history := subscribedHistory(client, channel.Name, channel.GetHistory())
+	if len(history) == 0 {
+		return
+	}
+
+	client.SendMessage(models.Message{
+		ID:    s.NewID(),
+		Event: "channel_history",
+		Data: map[string]interface{}{
+			"channel":  channel.Name,
+			"messages": history,
+		},
+		Timestamp: s.Now(),
+	})
+}
+
+// handleMarkRead records how far a user has read in an ACK-mode channel and notifies other members
+func (s *Server) handleMarkRead(client *models.Client, msg map[string]interface{}) {
+	channelName, ok := msg["channel"].(string)
+	if !ok {
+		s.sendError(client, "Invalid channel name")
+		return
+	}
+
+	readUpTo, ok := msg["read_up_to"].(float64)
+	if !ok || readUpTo < 0 {
+		s.sendError(client, "Invalid read_up_to sequence")
+		return
+	}
 
-		s.logger.ChannelJoined(client.ID, client.Username, channelName)
+	if client.UserID == "" {
+		s.sendError(client, "Read receipts require authentication")
+		return
+	}
 
-		// Send confirmation
-		confirmation := models.Message{
-			ID:        uuid.New().String(),
-			Event:     "joined_channel",
-			Data:      map[string]string{"channel": channelName},
-			Timestamp: time.Now(),
+	channel, exists := s.GetChannel(channelName)
+	if !exists || !client.GetChannels()[channelName] {
+		s.sendError(client, "Not a member of channel")
+		return
+	}
+
+	if !channel.AckMode {
+		s.sendError(client, "Read receipts are not enabled for this channel")
+		return
+	}
+
+	cursor, moved := channel.UpdateReadCursor(client.UserID, uint64(readUpTo))
+	if !moved {
+		return
+	}
+
+	s.logger.Debug("User %s read channel '%s' up to %d", client.UserID, channelName, cursor.ReadUpTo)
+
+	s.sendToChannelMembers(channel, models.Message{
+		ID:      s.NewID(),
+		Channel: channelName,
+		Event:   "read_receipt",
+		Data: map[string]interface{}{
+			"user_id":    cursor.UserID,
+			"username":   client.Username,
+			"read_up_to": cursor.ReadUpTo,
+		},
+		Timestamp: s.Now(),
+	})
+}
+
+// sendToChannelMembers delivers a system message to channel members without sequencing or history
+func (s *Server) sendToChannelMembers(channel *models.Channel, message models.Message) {
+	for _, member := range channel.GetClients() {
+		if err := member.SendMessage(message); err != nil {
+			s.logger.Debug("Failed to send %s to client %s: %v", message.Event, member.ID, err)
 		}
-		client.SendMessage(confirmation)
 	}
 }
 
+// isReliableChannel reports whether a channel name matches one of the configured ACK-mode patterns
+func (s *Server) isReliableChannel(channelName string) bool {
+	for _, pattern := range s.config.ReliableChannels {
+		if matched, err := path.Match(pattern, channelName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
 // handleLeaveChannel removes client from a channel
 func (s *Server) handleLeaveChannel(client *models.Client, msg map[string]interface{}) {
 	channelName, ok := msg["channel"].(string)
@@ -261,6 +584,12 @@ func (s *Server) handleLeaveChannel(client *models.Client, msg map[string]interf
 
 	s.logger.Debug("Client %s (%s) attempting to leave channel '%s'", client.ID, client.Username, channelName)
 
+	// User-targeted messages are routed through the personal channel, so it can't be left
+	if owner, isUserChannel := s.userChannelOwner(channelName); isUserChannel && owner == client.UserID {
+		s.sendError(client, "User channel can't be left")
+		return
+	}
+
 	channel, exists := s.GetChannel(channelName)
 	if !exists {
 		s.logger.Error("Client %s tried to leave non-existent channel '%s'", client.ID, channelName)
@@ -295,13 +624,13 @@ func (s *Server) handleLeaveChannel(client *models.Client, msg map[string]interf
 	}
 
 	leaveMessage := models.Message{
-		ID:        uuid.New().String(),
+		ID:        s.NewID(),
 		Channel:   channelName,
 		Event:     "leave_channel",
 		Data:      dataToForward,
 		UserID:    client.UserID,
 		Username:  client.Username,
-		Timestamp: time.Now(),
+		Timestamp: s.Now(),
 	}
 
 	// Dispatch to Laravel
@@ -311,12 +640,15 @@ func (s *Server) handleLeaveChannel(client *models.Client, msg map[string]interf
 
 	// Send confirmation
 	confirmation := models.Message{
-		ID:        uuid.New().String(),
+		ID:        s.NewID(),
 		Event:     "left_channel",
+		Priority:  models.PriorityHigh,
 		Data:      map[string]string{"channel": channelName},
-		Timestamp: time.Now(),
+		Timestamp: s.Now(),
 	}
 	client.SendMessage(confirmation)
+
+	s.cascadeToChildren(client, msg, channelName, true, s.handleLeaveChannel)
 }
 
 // handleSendMessage processes messages sent by clients
@@ -333,22 +665,52 @@ func (s *Server) handleSendMessage(client *models.Client, msg map[string]interfa
 		event = "message"
 	}
 
-	data := msg["data"]
+	// Identity fields in the data can't claim to be another user
+	data := models.StampIdentity(msg["data"], client.UserID, client.Username)
+
+	if !s.admitEventName(client, channelName, event) {
+		return
+	}
+	if models.IsClientEvent(event) {
+		s.handleClientEvent(client, channelName, event, data)
+		return
+	}
+
+	if models.IsDirectChannel(channelName) && !models.IsDirectChannelParticipant(channelName, client.UserID) {
+		s.logger.Warn("Client %s (user %s) denied sending to direct channel '%s'", client.ID, client.UserID, channelName)
+		s.sendError(client, "Direct channel access denied")
+		return
+	}
+
+	// Only the owner of a personal channel can publish into it, others could fake deliveries
+	if owner, isUserChannel := s.userChannelOwner(channelName); isUserChannel && owner != client.UserID {
+		s.logger.Warn("Client %s (user %s) denied sending to user channel '%s'", client.ID, client.UserID, channelName)
+		s.sendError(client, "User channel access denied")
+		return
+	}
+
+	if channel, exists := s.GetChannel(channelName); exists && channel.ReadOnly {
+		s.logger.Warn("Client %s denied sending to read-only channel '%s'", client.ID, channelName)
+		s.recordRejection(client, channelName, event, moderationRuleReadOnly)
+		s.sendError(client, "Channel is read-only")
+		return
+	}
 
 	s.logger.MessageSent(client.ID, client.Username, channelName, event, data)
 
 	message := models.Message{
-		ID:        uuid.New().String(),
-		Channel:   channelName,
-		Event:     event,
-		Data:      data,
-		UserID:    client.UserID,
-		Username:  client.Username,
-		Timestamp: time.Now(),
+		ID:          s.NewID(),
+		Channel:     channelName,
+		Event:       event,
+		Data:        data,
+		UserID:      client.UserID,
+		Username:    client.Username,
+		CoalesceKey: getStringFromMap(msg, "coalesce_key", ""),
+		Timestamp:   s.Now(),
 	}
 
 	// Dispatch to Laravel if configured
-	if err := s.laravelSvc.DispatchMessage(message, client); err != nil {
+	if err := s.laravelSvc.QueueMessage(message, client); err != nil {
 		s.logger.Error("Failed to dispatch message to Laravel: %v", err)
 	}
 
@@ -356,15 +718,44 @@ func (s *Server) handleSendMessage(client *models.Client, msg map[string]interfa
 	s.BroadcastToChannel(channelName, message)
 }
 
+// handleSetCompression lets a client turn compression of its messages on or off after the
+// handshake, e.g. mobile clients that mostly receive tiny frames
+func (s *Server) handleSetCompression(client *models.Client, msg map[string]interface{}) {
+	enabled, ok := msg["enabled"].(bool)
+	if !ok {
+		s.sendError(client, "Invalid compression setting")
+		return
+	}
+
+	client.SetCompression(enabled)
+	s.logger.Debug("Client %s set compression to %v", client.ID, enabled)
+
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "compression_updated",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]interface{}{"enabled": enabled},
+		Timestamp: s.Now(),
+	})
+}
+
 // handlePing processes ping messages
 func (s *Server) handlePing(client *models.Client) {
 	s.logger.PongReceived(client.ID)
-	pong := models.Message{
-		ID:        uuid.New().String(),
+	s.sendPong(client)
+}
+
+// sendPong answers a client ping with the server clock, which clients can use to re-estimate
+// their clock skew
+func (s *Server) sendPong(client *models.Client) {
+	now := s.Now()
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
 		Event:     "pong",
-		Timestamp: time.Now(),
-	}
-	client.SendMessage(pong)
+		Priority:  models.PriorityHigh,
+		Data:      map[string]interface{}{"server_time": now.UnixMilli()},
+		Timestamp: now,
+	})
 }
 
 // disconnectClient removes a client from the server
@@ -373,13 +764,29 @@ func (s *Server) disconnectClient(client *models.Client) {
 
 	// Remove client from server's client list
 	s.mutex.Lock()
-	delete(s.clients, client.ID)
+	if _, exists := s.clients[client.ID]; exists {
+		delete(s.clients, client.ID)
+		s.retiredMessagesSent += client.MessagesSent()
+		s.releaseConnectionLocked(client.RemoteAddr)
+	}
+	delete(s.blobUploads, client.ID)
+	connections := len(s.clients)
 	s.mutex.Unlock()
+	s.closeClientQueue(client)
+	s.checkPressure(connections)
+	s.markUserOffline(client.UserID, s.Now())
+	s.patterns.RemoveClient(client.ID)
 
 	// Remove client from all channels and notify Laravel
 	channels := client.GetChannels()
 	allMetadata := client.GetAllChannelMetadata()
 
+	joinedChannels := make([]string, 0, len(channels))
+	for channelName := range channels {
+		joinedChannels = append(joinedChannels, channelName)
+	}
+	sort.Strings(joinedChannels)
+
 	for channelName := range channels {
 		if channel, exists := s.GetChannel(channelName); exists {
 			// Remove client from channel
@@ -403,24 +810,34 @@ func (s *Server) disconnectClient(client *models.Client) {
 
 			// Create leave_channel message for Laravel dispatch
 			leaveMessage := models.Message{
-				ID:        uuid.New().String(),
+				ID:        s.NewID(),
 				Channel:   channelName,
 				Event:     "leave_channel",
 				Data:      dataToForward,
 				UserID:    client.UserID,
 				Username:  client.Username,
-				Timestamp: time.Now(),
+				Timestamp: s.Now(),
 			}
 
-			// Dispatch to Laravel (don't block disconnection if Laravel fails)
-			if err := s.laravelSvc.DispatchMessage(leaveMessage, client); err != nil {
-				s.logger.Error("Failed to dispatch disconnect leave_channel message to Laravel for channel %s: %v", channelName, err)
-			} else {
-				s.logger.Debug("Notified Laravel about client %s leaving channel %s due to disconnection", client.ID, channelName)
-			}
+			// Dispatch to Laravel, after the presence grace period for authenticated users
+			s.scheduleLeave(client, leaveMessage)
 		}
 	}
 
+	reason := client.GetDisconnectReason()
+	if reason == "" {
+		reason = DisconnectReasonConnectionLost
+	}
+	s.saveResumableSession(client, channels, allMetadata, reason)
+	if s.config.DispatchConnectionEvents {
+		s.queueDispatch(client, func() {
+			if err := s.laravelSvc.DispatchDisconnection(client, joinedChannels, reason); err != nil {
+				s.logger.Error("Failed to dispatch client_disconnected to Laravel: %v", err)
+			}
+		})
+	}
+	s.removeClientQueue(client)
+
 	// Safely close the client connection
 	client.Close()
 }
@@ -433,26 +850,69 @@ func (s *Server) getOrCreateChannel(channelName string, private bool) *models.Ch
 	channel, exists := s.channels[channelName]
 	if !exists {
 		s.logger.Debug("Creating new channel '%s'", channelName)
-		channel = &models.Channel{
-			Name:        channelName,
-			Clients:     make(map[string]*models.Client),
-			IsPrivate:   private,
-			RequireAuth: false,
-			CreatedAt:   time.Now(),
-		}
+		channel = s.newChannel(channelName, private)
 		s.channels[channelName] = channel
 	}
 
 	return channel
 }
 
+// newChannel creates a channel with the settings the configuration gives its name, without
+// adding it to the server. The caller must hold the mutex.
+func (s *Server) newChannel(channelName string, private bool) *models.Channel {
+	channel := models.NewChannelWithTimekeeper(channelName, &s.Timekeeper)
+	channel.IsPrivate = private
+	channel.SetHistoryLimit(s.config.ChannelHistorySize)
+	if models.IsDirectChannel(channelName) {
+		channel.IsPrivate = true
+		channel.RequireAuth = true
+		channel.SetHistoryLimit(s.config.DirectHistorySize)
+	}
+	if _, isUserChannel := s.userChannelOwner(channelName); isUserChannel {
+		channel.IsPrivate = true
+		channel.RequireAuth = true
+	}
+	channel.AckMode = s.isReliableChannel(channelName)
+	channel.ClientEvents = s.allowsClientEvents(channelName)
+	channel.BandwidthLimit = s.channelBandwidthLimit(channelName)
+	if settings, defined := s.channelSettings[channelName]; defined {
+		channel.ApplySettings(settings)
+	}
+	return channel
+}
+
+// admitEventName reports whether a client may emit an event, telling it otherwise. Reserved
+// names belong to the server's control events, which only the server and the API may send.
+func (s *Server) admitEventName(client *models.Client, channelName, event string) bool {
+	if !models.IsReservedEvent(event) {
+		return true
+	}
+	s.reservedEventRejections.Add(1)
+	s.logger.Warn("Client %s denied emitting reserved event '%s' on channel '%s'", client.ID, event, channelName)
+	s.recordRejection(client, channelName, event, moderationRuleReservedEvent)
+	s.sendError(client, "Reserved event name")
+	return false
+}
+
 // sendError sends an error message to a client
 func (s *Server) sendError(client *models.Client, errorMsg string) {
+	// errorMsg is the English text; it is translated to the client's locale when a translation exists
 	message := models.Message{
-		ID:        uuid.New().String(),
+		ID:        s.NewID(),
 		Event:     "error",
-		Data:      map[string]string{"error": errorMsg},
-		Timestamp: time.Now(),
+		Priority:  models.PriorityHigh,
+		Data:      s.errorData(client, errorMsg),
+		Timestamp: s.Now(),
 	}
 	client.SendMessage(message)
 }
+
+// errorData builds the payload of an error event. Translated errors also carry the English text
+// as "key", which stays stable across locales for clients that match on it.
+func (s *Server) errorData(client *models.Client, errorMsg string) map[string]string {
+	translated := client.Translate(errorMsg)
+	if translated == errorMsg {
+		return map[string]string{"error": errorMsg}
+	}
+	return map[string]string{"error": translated, "key": errorMsg}
+}
@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// broadcastLimiter caps the number of fan-outs running at once. Callers over the limit wait in
+// a bounded queue; once the queue is full, new broadcasts are rejected.
+type broadcastLimiter struct {
+	slots     chan struct{} // nil when unlimited
+	maxQueued int64
+	active    atomic.Int64
+	queued    atomic.Int64
+	rejected  atomic.Uint64
+	waitTotal atomic.Int64 // nanoseconds spent queued
+}
+
+// newBroadcastLimiter creates a limiter allowing concurrency fan-outs (0 is unlimited) with up
+// to queueSize waiting
+func newBroadcastLimiter(concurrency, queueSize int) *broadcastLimiter {
+	limiter := &broadcastLimiter{maxQueued: int64(queueSize)}
+	if concurrency > 0 {
+		limiter.slots = make(chan struct{}, concurrency)
+	}
+	return limiter
+}
+
+// acquire waits for a free slot and returns the function releasing it
+func (l *broadcastLimiter) acquire(ctx context.Context) (func(), error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			if l.queued.Add(1) > l.maxQueued {
+				l.queued.Add(-1)
+				l.rejected.Add(1)
+				return nil, models.ErrBroadcastQueueFull
+			}
+
+			start := time.Now()
+			select {
+			case l.slots <- struct{}{}:
+				l.queued.Add(-1)
+				l.waitTotal.Add(int64(time.Since(start)))
+			case <-ctx.Done():
+				l.queued.Add(-1)
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	l.active.Add(1)
+	return func() {
+		l.active.Add(-1)
+		if l.slots != nil {
+			<-l.slots
+		}
+	}, nil
+}
+
+// AcquireBroadcastSlot reserves one of the server-wide fan-out slots, waiting in the queue if
+// all are busy. The returned function must be called once the broadcast is done.
+func (s *Server) AcquireBroadcastSlot(ctx context.Context) (func(), error) {
+	return s.broadcastLimiter.acquire(ctx)
+}
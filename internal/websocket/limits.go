@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"net/http"
+
+	"socket-server/internal/models"
+)
+
+// reserveConnection takes a connection slot for an upgrade request, or refuses it with 503 when
+// the node or the request's address is at its connection limit. The slot is held until
+// releaseConnection, so upgrades in progress count against the limits too.
+func (s *Server) reserveConnection(w http.ResponseWriter, r *http.Request) bool {
+	ip := remoteIP(r.RemoteAddr)
+
+	s.mutex.Lock()
+	total, fromIP := s.connectionSlots, s.connectionsByIP[ip]
+	nodeFull := s.config.MaxConnections > 0 && total >= s.config.MaxConnections
+	ipFull := s.config.MaxConnectionsPerIP > 0 && fromIP >= s.config.MaxConnectionsPerIP
+	if !nodeFull && !ipFull {
+		s.connectionSlots++
+		s.connectionsByIP[ip]++
+	}
+	s.mutex.Unlock()
+
+	switch {
+	case nodeFull:
+		s.connectionLimitRejections.Add(1)
+		s.logger.Warn("Rejected connection from %s: node at its limit of %d connections", r.RemoteAddr, s.config.MaxConnections)
+		http.Error(w, "Too many connections", http.StatusServiceUnavailable)
+		return false
+	case ipFull:
+		s.ipConnectionLimitRejections.Add(1)
+		s.logger.Warn("Rejected connection from %s: address at its limit of %d connections", r.RemoteAddr, s.config.MaxConnectionsPerIP)
+		http.Error(w, "Too many connections from this address", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// releaseConnection frees the connection slot of an address
+func (s *Server) releaseConnection(addr string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.releaseConnectionLocked(addr)
+}
+
+// releaseConnectionLocked frees the connection slot of an address; the caller holds mutex
+func (s *Server) releaseConnectionLocked(addr string) {
+	ip := remoteIP(addr)
+	s.connectionSlots--
+	if s.connectionsByIP[ip]--; s.connectionsByIP[ip] <= 0 {
+		delete(s.connectionsByIP, ip)
+	}
+}
+
+// admitChannelCount reports whether a client may join another channel, telling it otherwise.
+// Channels the client is already in don't count as another.
+func (s *Server) admitChannelCount(client *models.Client, channelName string) bool {
+	limit := s.config.MaxChannelsPerClient
+	if limit <= 0 {
+		return true
+	}
+	channels := client.GetChannels()
+	if channels[channelName] || len(channels) < limit {
+		return true
+	}
+	s.channelLimitRejections.Add(1)
+	s.logger.Warn("Client %s denied access to channel '%s': already in %d channels", client.ID, channelName, len(channels))
+	s.sendError(client, "Too many channels")
+	return false
+}
@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// Stats is a point-in-time snapshot of the server load, used for autoscaling metrics
+type Stats struct {
+	Connections               int     `json:"connections"`
+	AuthenticatedConnections  int     `json:"authenticated_connections"`
+	Channels                  int     `json:"channels"`
+	MessagesReceived          uint64  `json:"messages_received_total"`
+	MessagesSent              uint64  `json:"messages_sent_total"`
+	ReceivedPerSecond         float64 `json:"messages_received_per_second"`
+	SentPerSecond             float64 `json:"messages_sent_per_second"`
+	ExpiredEntries            uint64  `json:"expired_entries_total"`
+	ReclaimedBytes            uint64  `json:"reclaimed_bytes_total"`
+	BroadcastsActive          int64   `json:"broadcasts_active"`
+	BroadcastsQueued          int64   `json:"broadcasts_queued"`
+	BroadcastsRejected        uint64  `json:"broadcasts_rejected_total"`
+	BroadcastWaitSeconds      float64 `json:"broadcast_wait_seconds_total"`
+	ConnectionPanics          uint64  `json:"connection_panics_total"`
+	HTTPPanics                uint64  `json:"http_panics_total"`
+	MessagesRateLimited       uint64  `json:"messages_rate_limited_total"`
+	HTTPRateLimited           uint64  `json:"http_rate_limited_total"`
+	DuplicateBroadcasts       uint64  `json:"duplicate_broadcasts_total"`
+	ConnectionLimitRejected   uint64  `json:"connection_limit_rejected_total"`
+	IPConnectionLimitRejected uint64  `json:"ip_connection_limit_rejected_total"`
+	ChannelLimitRejected      uint64  `json:"channel_limit_rejected_total"`
+	ReservedEventRejected     uint64  `json:"reserved_event_rejected_total"`
+	OutboxPending             int64   `json:"outbox_pending"`
+	StandbySyncFailures       uint64  `json:"standby_sync_failures_total"`
+	UnderPressure             bool    `json:"under_pressure"`
+	Standby                   bool    `json:"standby"`
+
+	// Countries counts connections per country when Geo-IP is enabled
+	Countries map[string]int `json:"countries,omitempty"`
+	// Bridge counts broadcasts forwarded to the remote server when bridging is enabled
+	Bridge *services.BridgeStats `json:"bridge,omitempty"`
+	// Sinks is the health of each sink messages are delivered to, such as Laravel or the bridge
+	Sinks []services.SinkHealth `json:"sinks"`
+	// Peers is the load the other nodes advertised, in cluster mode
+	Peers []models.NodeStatus `json:"peers,omitempty"`
+}
+
+// messageRates holds the message rates computed by the metrics sampler
+type messageRates struct {
+	received float64
+	sent     float64
+}
+
+// StartMetricsSampler periodically computes messages/sec from the message counters
+func (s *Server) StartMetricsSampler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastReceived, lastSent := s.messagesReceived.Load(), s.totalMessagesSent()
+		lastSample := time.Now()
+
+		for range ticker.C {
+			received, sent := s.messagesReceived.Load(), s.totalMessagesSent()
+			elapsed := time.Since(lastSample).Seconds()
+
+			s.ratesMutex.Lock()
+			s.rates = messageRates{
+				received: float64(received-lastReceived) / elapsed,
+				sent:     float64(sent-lastSent) / elapsed,
+			}
+			s.ratesMutex.Unlock()
+
+			lastReceived, lastSent, lastSample = received, sent, time.Now()
+		}
+	}()
+}
+
+// Stats returns the current load snapshot
+func (s *Server) Stats() Stats {
+	s.mutex.RLock()
+	stats := Stats{
+		Connections: len(s.clients),
+		Channels:    len(s.channels),
+	}
+	for _, client := range s.clients {
+		if client.UserID != "" {
+			stats.AuthenticatedConnections++
+		}
+		if client.Geo != nil {
+			if stats.Countries == nil {
+				stats.Countries = make(map[string]int)
+			}
+			stats.Countries[client.Geo.Country]++
+		}
+	}
+	s.mutex.RUnlock()
+
+	stats.MessagesReceived = s.messagesReceived.Load()
+	stats.MessagesSent = s.totalMessagesSent()
+	stats.ExpiredEntries = s.expiredEntries.Load()
+	stats.ReclaimedBytes = s.reclaimedBytes.Load()
+	stats.BroadcastsActive = s.broadcastLimiter.active.Load()
+	stats.BroadcastsQueued = s.broadcastLimiter.queued.Load()
+	stats.BroadcastsRejected = s.broadcastLimiter.rejected.Load()
+	stats.BroadcastWaitSeconds = time.Duration(s.broadcastLimiter.waitTotal.Load()).Seconds()
+	stats.ConnectionPanics = s.connectionPanics.Load()
+	stats.HTTPPanics = s.httpPanics.Load()
+	stats.MessagesRateLimited = s.messagesRateLimited.Load()
+	stats.HTTPRateLimited = s.httpRateLimited.Load()
+	stats.DuplicateBroadcasts = s.duplicateBroadcasts.Load()
+	stats.ConnectionLimitRejected = s.connectionLimitRejections.Load()
+	stats.IPConnectionLimitRejected = s.ipConnectionLimitRejections.Load()
+	stats.ChannelLimitRejected = s.channelLimitRejections.Load()
+	stats.ReservedEventRejected = s.reservedEventRejections.Load()
+	stats.OutboxPending = s.laravelSvc.OutboxPending()
+	stats.UnderPressure = s.pressure.Load()
+	standby := s.StandbyStatus()
+	stats.Standby = standby.Standby
+	stats.StandbySyncFailures = standby.Failures
+
+	s.ratesMutex.RLock()
+	stats.ReceivedPerSecond = s.rates.received
+	stats.SentPerSecond = s.rates.sent
+	s.ratesMutex.RUnlock()
+
+	if s.bridge != nil {
+		bridgeStats := s.bridge.Stats()
+		stats.Bridge = &bridgeStats
+	}
+	if s.cluster != nil {
+		stats.Peers = s.Peers()
+	}
+	stats.Sinks = s.sinkHealth()
+
+	return stats
+}
+
+// totalMessagesSent sums the messages written to live connections and to closed ones
+func (s *Server) totalMessagesSent() uint64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	total := s.retiredMessagesSent
+	for _, client := range s.clients {
+		total += client.MessagesSent()
+	}
+	return total
+}
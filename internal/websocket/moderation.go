@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"socket-server/internal/models"
+)
+
+// Rules recorded in the moderation log
+const (
+	moderationRuleReadOnly             = "read_only"
+	moderationRuleClientEventsDisabled = "client_events_disabled"
+	moderationRuleReservedEvent        = "reserved_event"
+	moderationRuleBlobLimit            = "blob_limit"
+	moderationRuleKicked               = "kicked"
+	moderationRuleBanned               = "banned"
+)
+
+// recordRejection logs a client message a channel rule refused in the channel's moderation log
+func (s *Server) recordRejection(client *models.Client, channelName, event, rule string) {
+	s.moderationLog.Record(models.ModerationEntry{
+		ID:       s.NewID(),
+		At:       s.Now(),
+		Channel:  channelName,
+		Action:   models.ModerationMessageRejected,
+		Rule:     rule,
+		ClientID: client.ID,
+		UserID:   client.UserID,
+		Event:    event,
+	})
+}
+
+// recordRemoval logs a client taken out of its channels, in the moderation log of each of them
+func (s *Server) recordRemoval(client *models.Client, rule, reason string) {
+	for channelName := range client.GetChannels() {
+		s.moderationLog.Record(models.ModerationEntry{
+			ID:       s.NewID(),
+			At:       s.Now(),
+			Channel:  channelName,
+			Action:   models.ModerationRemoved,
+			Rule:     rule,
+			ClientID: client.ID,
+			UserID:   client.UserID,
+			Reason:   reason,
+		})
+	}
+}
+
+// GetModerationLog returns the moderation actions recorded in a channel on this node, newest
+// first. A positive limit keeps the most recent ones.
+func (s *Server) GetModerationLog(channelName string, limit int) []models.ModerationEntry {
+	return s.moderationLog.Entries(channelName, limit)
+}
@@ -0,0 +1,334 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"socket-server/internal/models"
+)
+
+const (
+	// netpollReadTimeout is how long a netpoll connection may take to send the rest of a frame
+	// once it started sending it
+	netpollReadTimeout = 10 * time.Second
+	// netpollSweepInterval is how often netpoll connections are checked for due pings and
+	// silence
+	netpollSweepInterval = time.Second
+	// maxControlFrameSize is the size of the largest masked control frame
+	maxControlFrameSize = 2 + 4 + 125
+)
+
+var errNetpollUnsupported = errors.New("netpoll is only supported on Linux")
+
+// netpollConn is the network connection of a client watched by the netpoller instead of a
+// reader goroutine. It passes the WebSocket library one frame at a time, never reading ahead,
+// so once a message has been read nothing is left buffered and the poller knows whether more
+// is coming. Ping and pong frames between messages are answered without waking the library,
+// which would otherwise wait for the next message.
+type netpollConn struct {
+	net.Conn
+	raw    syscall.RawConn
+	fd     int
+	client *models.Client
+
+	pending     []byte   // header bytes read ahead to look at the next frame's opcode
+	header      [14]byte // the current frame's header, as far as it has been passed on
+	headerLen   int
+	payloadLeft uint64 // bytes of the current frame's payload not passed on yet
+
+	handling sync.Mutex   // held while a worker reads from the connection
+	lastRead atomic.Int64 // Unix nanoseconds of the last frame received
+	nextPing atomic.Int64 // Unix nanoseconds the next ping is due
+
+	closed   func()
+	released sync.Once
+}
+
+// Read passes on the bytes of the current frame, up to its end
+func (c *netpollConn) Read(p []byte) (int, error) {
+	var limit uint64
+	if c.payloadLeft > 0 {
+		limit = c.payloadLeft
+	} else {
+		limit = uint64(frameHeaderSize(c.header[:c.headerLen]) - c.headerLen)
+	}
+	if uint64(len(p)) > limit {
+		p = p[:limit]
+	}
+
+	var n int
+	var err error
+	if len(c.pending) > 0 {
+		n = copy(p, c.pending)
+		c.pending = c.pending[n:]
+	} else {
+		n, err = c.Conn.Read(p)
+	}
+
+	if c.payloadLeft > 0 {
+		c.payloadLeft -= uint64(n)
+		return n, err
+	}
+	c.headerLen += copy(c.header[c.headerLen:], p[:n])
+	if c.headerLen >= 2 && c.headerLen == frameHeaderSize(c.header[:c.headerLen]) {
+		c.payloadLeft = framePayloadSize(c.header[:c.headerLen])
+		c.headerLen = 0
+	}
+	return n, err
+}
+
+// Close stops watching the connection before closing it, so its descriptor isn't reused while
+// still registered
+func (c *netpollConn) Close() error {
+	if c.closed != nil {
+		c.closed()
+	}
+	return c.Conn.Close()
+}
+
+// frameHeaderSize returns the size of a frame header given at least its first two bytes, or 2
+// with fewer
+func frameHeaderSize(header []byte) int {
+	if len(header) < 2 {
+		return 2
+	}
+	size := 2
+	switch header[1] & 0x7f {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	if header[1]&0x80 != 0 {
+		size += 4 // masking key
+	}
+	return size
+}
+
+// framePayloadSize returns the payload length of a complete frame header
+func framePayloadSize(header []byte) uint64 {
+	switch length := header[1] & 0x7f; length {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(header[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(header[2:10])
+	default:
+		return uint64(length)
+	}
+}
+
+// netpollResponseWriter hands the upgrader a netpollConn when hijacking the connection
+type netpollResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w netpollResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// TLS connections can't be read from the socket; they keep their reader goroutine
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return conn, rw, nil
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return conn, rw, nil
+	}
+	pc := &netpollConn{Conn: conn, raw: raw, fd: -1}
+	raw.Control(func(fd uintptr) { pc.fd = int(fd) })
+	return pc, rw, nil
+}
+
+// EnableNetpoll watches idle native connections with epoll: their messages are read by a
+// bounded pool of workers when they arrive, and their pings are sent by a single sweeper, so a
+// connection only keeps its writer goroutine. It is only supported on Linux.
+func (s *Server) EnableNetpoll(workers int) error {
+	poller, err := newNetpoller(workers)
+	if err != nil {
+		return err
+	}
+	s.netpoll = poller
+	go poller.wait(s.handleNetpollReadable, s.logger)
+	go s.sweepNetpoll()
+	return nil
+}
+
+// watchNetpoll hands a new client over to the netpoller, reporting whether it did. Clients
+// whose connection can't be watched are served by goroutines.
+func (s *Server) watchNetpoll(client *models.Client) bool {
+	if s.netpoll == nil {
+		return false
+	}
+	conn, ok := client.Conn.UnderlyingConn().(*netpollConn)
+	if !ok || conn.fd < 0 {
+		return false
+	}
+
+	conn.client = client
+	now := time.Now()
+	conn.lastRead.Store(now.UnixNano())
+	conn.nextPing.Store(now.Add(client.PingInterval()).UnixNano())
+	// Silence is detected by the sweeper; a deadline would fail reads started later
+	client.SafeSetReadDeadline(time.Time{})
+	conn.closed = func() {
+		s.netpoll.remove(conn)
+		go s.releaseNetpoll(conn)
+	}
+
+	if err := s.netpoll.add(conn); err != nil {
+		s.logger.Warn("Failed to watch client %s with netpoll, serving it with goroutines: %v", client.ID, err)
+		conn.closed = nil
+		client.SafeSetReadDeadline(time.Now().Add(client.ReadTimeout()))
+		return false
+	}
+	return true
+}
+
+// releaseNetpoll stops watching a netpoll connection and disconnects its client, once
+func (s *Server) releaseNetpoll(conn *netpollConn) {
+	conn.released.Do(func() {
+		s.netpoll.remove(conn)
+		s.disconnectClient(conn.client)
+	})
+}
+
+// handleNetpollReadable reads the frames waiting on a netpoll connection, then watches it
+// again. A worker that finds another one already reading leaves the connection to it, as the
+// connection is only re-armed once that one is done.
+func (s *Server) handleNetpollReadable(conn *netpollConn) {
+	if !conn.handling.TryLock() || !s.readNetpollFrames(conn) {
+		return
+	}
+	if err := s.netpoll.rearm(conn); err != nil && conn.client.IsConnected() {
+		s.logger.Warn("Failed to watch client %s with netpoll: %v", conn.client.ID, err)
+		conn.client.SetDisconnectReason(DisconnectReasonConnectionLost)
+		s.releaseNetpoll(conn)
+	}
+}
+
+// readNetpollFrames reads the frames waiting on a netpoll connection and releases its handling
+// lock, reporting whether the connection should be watched again
+func (s *Server) readNetpollFrames(conn *netpollConn) (open bool) {
+	defer conn.handling.Unlock()
+	defer s.recoverClient(conn.client, "netpoll")
+
+	for {
+		readable, err := conn.readable()
+		if err == nil && !readable {
+			return true
+		}
+		if err == nil {
+			err = s.readNetpollFrame(conn)
+		}
+		if err == io.EOF {
+			// Reported like the WebSocket library reports connections closed without a close frame
+			err = &websocket.CloseError{Code: websocket.CloseAbnormalClosure, Text: io.ErrUnexpectedEOF.Error()}
+		}
+		if err != nil {
+			s.recordReadError(conn.client, err)
+			s.releaseNetpoll(conn)
+			return false
+		}
+	}
+}
+
+// readNetpollFrame reads the next frame of a netpoll connection: a ping or pong is answered
+// here, anything else is read as a message by the WebSocket library
+func (s *Server) readNetpollFrame(conn *netpollConn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(netpollReadTimeout)); err != nil {
+		return err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	var frame [maxControlFrameSize]byte
+	if _, err := io.ReadFull(conn.Conn, frame[:2]); err != nil {
+		return err
+	}
+	conn.lastRead.Store(time.Now().UnixNano())
+
+	opcode := int(frame[0] & 0x0f)
+	if (opcode == websocket.PingMessage || opcode == websocket.PongMessage) && frame[1]&0x7f <= 125 {
+		size := frameHeaderSize(frame[:2]) + int(frame[1]&0x7f)
+		if _, err := io.ReadFull(conn.Conn, frame[2:size]); err != nil {
+			return err
+		}
+		payload := frame[frameHeaderSize(frame[:2]):size]
+		if frame[1]&0x80 != 0 {
+			mask := frame[2:6]
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+
+		wsConn := conn.client.Conn
+		if opcode == websocket.PingMessage {
+			return wsConn.PingHandler()(string(payload))
+		}
+		return wsConn.PongHandler()(string(payload))
+	}
+
+	conn.pending = append(conn.pending[:0], frame[:2]...)
+	messageType, data, err := conn.client.SafeReadMessage()
+	var msg map[string]interface{}
+	if err == nil && messageType != websocket.BinaryMessage {
+		err = json.Unmarshal(data, &msg)
+	}
+	if err != nil {
+		return err
+	}
+	conn.lastRead.Store(time.Now().UnixNano())
+	s.handleClientMessage(conn.client, messageType, data, msg)
+	return nil
+}
+
+// sweepNetpoll sends the pings due on netpoll connections and drops those silent for longer
+// than their read timeout, as the ping and reader goroutines do for other connections
+func (s *Server) sweepNetpoll() {
+	ticker := time.NewTicker(netpollSweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, conn := range s.netpoll.conns() {
+			client := conn.client
+			if !client.IsConnected() {
+				continue
+			}
+			if now.Sub(time.Unix(0, conn.lastRead.Load())) > client.ReadTimeout() {
+				s.logger.Debug("Client %s went silent, closing its netpoll connection", client.ID)
+				client.SetDisconnectReason(DisconnectReasonConnectionLost)
+				go s.releaseNetpoll(conn)
+				continue
+			}
+			if now.UnixNano() < conn.nextPing.Load() {
+				continue
+			}
+			conn.nextPing.Store(now.Add(client.PingInterval()).UnixNano())
+			s.netpoll.run(func() {
+				if err := client.SendPing(); err != nil {
+					s.logger.Debug("Failed to send ping to client %s: %v", client.ID, err)
+					client.SetDisconnectReason(DisconnectReasonPingFailed)
+					s.releaseNetpoll(conn)
+					return
+				}
+				s.logger.PingSent(client.ID)
+			})
+		}
+	}
+}
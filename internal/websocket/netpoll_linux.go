@@ -0,0 +1,126 @@
+//go:build linux
+
+package websocket
+
+import (
+	"io"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"socket-server/pkg/logger"
+)
+
+// netpoller watches netpoll connections with epoll. Connections are registered one-shot: once
+// a connection is readable it isn't reported again until the worker reading it re-arms it.
+type netpoller struct {
+	epfd    int
+	watched map[int]*netpollConn // by file descriptor
+	workers chan struct{}
+	mutex   sync.Mutex
+}
+
+func newNetpoller(workers int) (*netpoller, error) {
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	return &netpoller{epfd: epfd, watched: make(map[int]*netpollConn), workers: make(chan struct{}, workers)}, nil
+}
+
+// add starts watching a connection
+func (p *netpoller) add(conn *netpollConn) error {
+	p.mutex.Lock()
+	p.watched[conn.fd] = conn
+	p.mutex.Unlock()
+
+	event := unix.EpollEvent{Events: unix.EPOLLIN | unix.EPOLLRDHUP | unix.EPOLLONESHOT, Fd: int32(conn.fd)}
+	if err := unix.EpollCtl(p.epfd, unix.EPOLL_CTL_ADD, conn.fd, &event); err != nil {
+		p.remove(conn)
+		return err
+	}
+	return nil
+}
+
+// rearm watches a connection again after its readable frames were read
+func (p *netpoller) rearm(conn *netpollConn) error {
+	event := unix.EpollEvent{Events: unix.EPOLLIN | unix.EPOLLRDHUP | unix.EPOLLONESHOT, Fd: int32(conn.fd)}
+	return unix.EpollCtl(p.epfd, unix.EPOLL_CTL_MOD, conn.fd, &event)
+}
+
+// remove stops watching a connection
+func (p *netpoller) remove(conn *netpollConn) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.watched[conn.fd] != conn {
+		return
+	}
+	delete(p.watched, conn.fd)
+	unix.EpollCtl(p.epfd, unix.EPOLL_CTL_DEL, conn.fd, nil)
+}
+
+// conns returns the watched connections
+func (p *netpoller) conns() []*netpollConn {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	conns := make([]*netpollConn, 0, len(p.watched))
+	for _, conn := range p.watched {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// run runs a task on a worker, waiting for one to be free
+func (p *netpoller) run(task func()) {
+	p.workers <- struct{}{}
+	go func() {
+		defer func() { <-p.workers }()
+		task()
+	}()
+}
+
+// wait hands each connection that becomes readable to a worker
+func (p *netpoller) wait(handle func(*netpollConn), logger *logger.Logger) {
+	events := make([]unix.EpollEvent, 256)
+	for {
+		n, err := unix.EpollWait(p.epfd, events, -1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			logger.Error("Netpoll: waiting for readable connections failed: %v", err)
+			return
+		}
+		for _, event := range events[:n] {
+			p.mutex.Lock()
+			conn := p.watched[int(event.Fd)]
+			p.mutex.Unlock()
+			if conn != nil {
+				p.run(func() { handle(conn) })
+			}
+		}
+	}
+}
+
+// readable reports, without blocking, whether data is waiting on the connection, returning
+// io.EOF once the peer closed it
+func (c *netpollConn) readable() (bool, error) {
+	var buf [1]byte
+	var n int
+	var peekErr error
+	err := c.raw.Read(func(fd uintptr) bool {
+		n, _, peekErr = unix.Recvfrom(int(fd), buf[:], unix.MSG_PEEK|unix.MSG_DONTWAIT)
+		return true
+	})
+	switch {
+	case err != nil:
+		return false, err
+	case peekErr == unix.EAGAIN || peekErr == unix.EINTR:
+		return false, nil
+	case peekErr != nil:
+		return false, peekErr
+	case n == 0:
+		return false, io.EOF
+	}
+	return true, nil
+}
@@ -0,0 +1,131 @@
+//go:build linux
+
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"socket-server/internal/config"
+)
+
+func TestNetpollRoundTrip(t *testing.T) {
+	server := newTestServer(t, config.New())
+	if err := server.EnableNetpoll(2); err != nil {
+		t.Fatalf("Failed to enable netpoll: %v", err)
+	}
+	httpServer := httptest.NewServer(http.HandlerFunc(server.HandleConnection))
+	defer httpServer.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	pongs := make(chan string, 10)
+	conn.SetPongHandler(func(data string) error {
+		pongs <- data
+		return nil
+	})
+	events := make(chan map[string]interface{}, 10)
+	go func() {
+		for {
+			var event map[string]interface{}
+			if err := conn.ReadJSON(&event); err != nil {
+				close(events)
+				return
+			}
+			events <- event
+		}
+	}()
+	expectEvent := func(name, channel string) {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("Expected a %s event, the connection closed", name)
+			}
+			if event["event"] != name {
+				t.Fatalf("Expected a %s event, got %v", name, event)
+			}
+			if channel == "" {
+				return
+			}
+			if data, _ := event["data"].(map[string]interface{}); data["channel"] != channel {
+				t.Fatalf("Expected a %s event for %s, got %v", name, channel, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for a %s event", name)
+		}
+	}
+	expectPong := func(data string) {
+		t.Helper()
+		select {
+		case got := <-pongs:
+			if got != data {
+				t.Fatalf("Expected a pong of %q, got %q", data, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for the pong of %q", data)
+		}
+	}
+	join := func(channel, padding string) []byte {
+		message, _ := json.Marshal(map[string]string{"action": "join_channel", "channel": channel, "padding": padding})
+		return message
+	}
+	send := func(frames ...[]byte) {
+		t.Helper()
+		for _, frame := range frames {
+			// Frames are written raw, so they reach the server one at a time
+			if _, err := conn.UnderlyingConn().Write(frame); err != nil {
+				t.Fatalf("Failed to send a frame: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	expectEvent("connected", "")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.netpoll.conns()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the connection to be watched by the netpoller")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	send(clientFrame(true, websocket.PingMessage, []byte("idle")))
+	expectPong("idle")
+
+	send(clientFrame(true, websocket.TextMessage, join("short", "")))
+	expectEvent("joined_channel", "short")
+
+	send(clientFrame(true, websocket.PingMessage, nil))
+	expectPong("")
+
+	message := join("fragmented", "")
+	send(
+		clientFrame(false, websocket.TextMessage, message[:10]),
+		clientFrame(true, websocket.PingMessage, []byte("between")),
+		clientFrame(false, 0, message[10:]),
+		clientFrame(true, 0, nil),
+	)
+	expectPong("between")
+	expectEvent("joined_channel", "fragmented")
+
+	send(clientFrame(true, websocket.TextMessage, join("medium", strings.Repeat("x", 300))))
+	expectEvent("joined_channel", "medium")
+
+	send(clientFrame(true, websocket.TextMessage, join("large", strings.Repeat("x", 70000))))
+	expectEvent("joined_channel", "large")
+
+	if clients := len(server.netpoll.conns()); clients != 1 {
+		t.Errorf("Expected the connection to still be watched, got %d watched connections", clients)
+	}
+}
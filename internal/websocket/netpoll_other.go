@@ -0,0 +1,21 @@
+//go:build !linux
+
+package websocket
+
+import "socket-server/pkg/logger"
+
+// netpoller is only implemented on Linux
+type netpoller struct{}
+
+func newNetpoller(workers int) (*netpoller, error) {
+	return nil, errNetpollUnsupported
+}
+
+func (p *netpoller) add(conn *netpollConn) error             { return errNetpollUnsupported }
+func (p *netpoller) rearm(conn *netpollConn) error           { return errNetpollUnsupported }
+func (p *netpoller) remove(conn *netpollConn)                {}
+func (p *netpoller) conns() []*netpollConn                   { return nil }
+func (p *netpoller) run(task func())                         {}
+func (p *netpoller) wait(func(*netpollConn), *logger.Logger) {}
+
+func (c *netpollConn) readable() (bool, error) { return false, errNetpollUnsupported }
@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// clientFrame encodes a masked frame, as a client sends it
+func clientFrame(fin bool, opcode int, payload []byte) []byte {
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func TestFrameHeaderSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      []byte
+		size        int
+		payloadSize uint64
+	}{
+		{"partial", []byte{0x81}, 2, 0},
+		{"unmasked", []byte{0x81, 5}, 2, 5},
+		{"masked", []byte{0x81, 0x80 | 5, 1, 2, 3, 4}, 6, 5},
+		{"masked empty", []byte{0x81, 0x80, 1, 2, 3, 4}, 6, 0},
+		{"largest short length", []byte{0x82, 125}, 2, 125},
+		{"16-bit length", []byte{0x82, 126, 0x01, 0x2c}, 4, 300},
+		{"masked 16-bit length", []byte{0x82, 0x80 | 126, 0xff, 0xff, 1, 2, 3, 4}, 8, 65535},
+		{"64-bit length", []byte{0x82, 127, 0, 0, 0, 0, 0, 0x01, 0x11, 0x70}, 10, 70000},
+		{"masked 64-bit length", []byte{0x82, 0x80 | 127, 0, 0, 0, 1, 0, 0, 0, 0, 1, 2, 3, 4}, 14, 1 << 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frameHeaderSize(tt.header); got != tt.size {
+				t.Errorf("Expected a header size of %d, got %d", tt.size, got)
+			}
+			if len(tt.header) < 2 {
+				return
+			}
+			if got := framePayloadSize(tt.header); got != tt.payloadSize {
+				t.Errorf("Expected a payload size of %d, got %d", tt.payloadSize, got)
+			}
+		})
+	}
+}
+
+// streamConn is a connection reading from a byte stream
+type streamConn struct {
+	net.Conn
+	stream io.Reader
+}
+
+func (c *streamConn) Read(p []byte) (int, error) {
+	return c.stream.Read(p)
+}
+
+func TestNetpollConnReadStopsAtFrameBoundaries(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), 70000)
+	tests := []struct {
+		name   string
+		frames [][]byte
+		reads  []int
+	}{
+		{
+			name:   "short",
+			frames: [][]byte{clientFrame(true, websocket.TextMessage, []byte("hello"))},
+			reads:  []int{2, 4, 5},
+		},
+		{
+			name:   "16-bit length",
+			frames: [][]byte{clientFrame(true, websocket.TextMessage, large[:300])},
+			reads:  []int{2, 6, 300},
+		},
+		{
+			name:   "64-bit length",
+			frames: [][]byte{clientFrame(true, websocket.BinaryMessage, large)},
+			reads:  []int{2, 12, 70000},
+		},
+		{
+			name: "masked empty frames",
+			frames: [][]byte{
+				clientFrame(true, websocket.TextMessage, nil),
+				clientFrame(true, websocket.PingMessage, nil),
+				clientFrame(true, websocket.TextMessage, []byte("x")),
+			},
+			reads: []int{2, 4, 2, 4, 2, 4, 1},
+		},
+		{
+			name: "ping between fragments",
+			frames: [][]byte{
+				clientFrame(false, websocket.TextMessage, []byte("hel")),
+				clientFrame(true, websocket.PingMessage, []byte("p")),
+				clientFrame(true, websocket.PongMessage, nil),
+				clientFrame(true, 0, []byte("lo")),
+			},
+			reads: []int{2, 4, 3, 2, 4, 1, 2, 4, 2, 4, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		stream := bytes.Join(tt.frames, nil)
+		for _, pending := range []int{0, 1, 2} {
+			conn := &netpollConn{
+				Conn:    &streamConn{stream: bytes.NewReader(stream[pending:])},
+				pending: append([]byte(nil), stream[:pending]...),
+			}
+
+			var reads []int
+			var read []byte
+			buffer := make([]byte, 1<<20)
+			for {
+				n, err := conn.Read(buffer)
+				if n > 0 {
+					reads = append(reads, n)
+					read = append(read, buffer[:n]...)
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s: unexpected read error: %v", tt.name, err)
+				}
+			}
+
+			if pending == 1 {
+				// The pending byte is passed on by itself, then the rest of the first header
+				reads = append([]int{reads[0] + reads[1]}, reads[2:]...)
+			}
+			if !reflect.DeepEqual(reads, tt.reads) {
+				t.Errorf("%s with %d pending bytes: expected reads of %v, got %v", tt.name, pending, tt.reads, reads)
+			}
+			if !bytes.Equal(read, stream) {
+				t.Errorf("%s with %d pending bytes: expected the stream passed on unchanged", tt.name, pending)
+			}
+		}
+	}
+}
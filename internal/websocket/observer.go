@@ -0,0 +1,65 @@
+package websocket
+
+import (
+	"crypto/subtle"
+
+	"socket-server/internal/models"
+)
+
+// observerActions are the actions observers may send; everything else would publish, or reach
+// Laravel, and is refused
+var observerActions = map[string]bool{
+	"authenticate":        true,
+	"resume":              true,
+	"join_channel":        true,
+	"leave_channel":       true,
+	"subscribe_pattern":   true,
+	"unsubscribe_pattern": true,
+	"set_compression":     true,
+	"ping":                true,
+	"chunk":               true,
+}
+
+// isAPIToken reports whether a token presented by a WebSocket client is the HTTP API token,
+// which connects it as an observer
+func (s *Server) isAPIToken(token string) bool {
+	return s.config.HTTPToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.config.HTTPToken)) == 1
+}
+
+// signInObserver makes a client presenting the API token an observer. It has no user identity.
+func (s *Server) signInObserver(client *models.Client) {
+	client.SetObserver()
+	s.logger.Info("Client %s from %s connected as an observer with the API token", client.ID, client.RemoteAddr)
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "authenticated",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]interface{}{"observer": true},
+		Timestamp: s.Now(),
+	})
+}
+
+// admitObserverAction refuses the actions observers can't send
+func (s *Server) admitObserverAction(client *models.Client, action string) bool {
+	if !client.Observer || observerActions[action] {
+		return true
+	}
+	s.logger.Warn("Observer %s tried to send %q", client.ID, action)
+	s.sendError(client, "Observers can't publish")
+	return false
+}
+
+// observeChannel joins an observer to any channel, private, direct and personal channels
+// included, without asking Laravel. Observers still count towards the channel's capacity.
+func (s *Server) observeChannel(client *models.Client, msg map[string]interface{}, channelName string, private bool) {
+	if _, isUserChannel := s.userChannelOwner(channelName); isUserChannel || models.IsDirectChannel(channelName) {
+		private = true
+	}
+	channel := s.getOrCreateChannel(channelName, private)
+	if err := s.addClientToChannel(client, channel, nil, nil); err != nil {
+		s.logger.Warn("Observer %s denied access to channel '%s': %v", client.ID, channelName, err)
+		s.sendError(client, "Channel is full")
+		return
+	}
+	s.cascadeToChildren(client, msg, channelName, false, s.handleJoinChannel)
+}
@@ -0,0 +1,50 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// checkOrigin decides whether a browser may open a connection from the request's Origin.
+// Requests without an Origin header come from non-browser clients and are always allowed. With
+// no allowed origins configured, only the server's own origin is accepted. Rejected upgrades
+// are answered with 403 Forbidden.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.config.AllowAllOrigins {
+		return true
+	}
+
+	// The dynamic configuration can replace the allowed origins
+	s.mutex.RLock()
+	allowedOrigins := s.config.AllowedOrigins
+	s.mutex.RUnlock()
+
+	if len(allowedOrigins) == 0 {
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+	} else if originAllowed(allowedOrigins, origin) {
+		return true
+	}
+
+	s.logger.Warn("Rejected connection from %s: origin %s not allowed", r.RemoteAddr, origin)
+	return false
+}
+
+// originAllowed reports whether an origin matches one of the patterns, ignoring case. Patterns
+// are full origins that may contain wildcards, e.g. https://*.example.com; "*" matches any origin.
+func originAllowed(patterns []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+		if matched, _ := path.Match(strings.ToLower(pattern), origin); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package websocket
+
+import (
+	"strings"
+	"sync"
+
+	"socket-server/internal/models"
+)
+
+// Pattern subscription wildcards: "*" matches exactly one channel name segment and a final "**"
+// matches one or more, so "orders.*" matches "orders.42" and "orders.**" also "orders.42.items"
+const (
+	segmentWildcard = "*"
+	tailWildcard    = "**"
+)
+
+// maxPatternSubscriptions is the most channel patterns a connection may subscribe to
+const maxPatternSubscriptions = 50
+
+// patternNode is a node of the pattern trie, reached by the segments of a pattern prefix
+type patternNode struct {
+	children map[string]*patternNode
+	wildcard *patternNode              // the "*" segment
+	clients  map[string]*models.Client // patterns ending at this node
+	tail     map[string]*models.Client // patterns ending with "**" after this node
+}
+
+func (n *patternNode) empty() bool {
+	return len(n.children) == 0 && n.wildcard == nil && len(n.clients) == 0 && len(n.tail) == 0
+}
+
+// patternSubscriptions indexes the clients subscribed to channel patterns in a trie keyed by
+// name segments, so finding the subscribers of a channel walks its segments rather than every
+// pattern
+type patternSubscriptions struct {
+	root     *patternNode
+	byClient map[string]map[string]bool // client ID -> its patterns
+	mutex    sync.RWMutex
+}
+
+func newPatternSubscriptions() *patternSubscriptions {
+	return &patternSubscriptions{root: &patternNode{}, byClient: make(map[string]map[string]bool)}
+}
+
+// validSubscriptionPattern reports whether a pattern has non-empty segments, wildcards only as
+// whole segments, "**" only last, and at least one wildcard
+func validSubscriptionPattern(pattern string) bool {
+	segments := strings.Split(pattern, models.ChannelSeparator)
+	wildcards := 0
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+			return false
+		case segment == tailWildcard:
+			if i != len(segments)-1 {
+				return false
+			}
+			wildcards++
+		case segment == segmentWildcard:
+			wildcards++
+		case strings.Contains(segment, segmentWildcard):
+			return false
+		}
+	}
+	return wildcards > 0
+}
+
+// Add subscribes a client to a pattern. It returns false when the client already has the most
+// patterns allowed.
+func (p *patternSubscriptions) Add(pattern string, client *models.Client) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	patterns := p.byClient[client.ID]
+	if patterns[pattern] {
+		return true
+	}
+	if len(patterns) >= maxPatternSubscriptions {
+		return false
+	}
+	if patterns == nil {
+		patterns = make(map[string]bool)
+		p.byClient[client.ID] = patterns
+	}
+	patterns[pattern] = true
+
+	node := p.root
+	segments := strings.Split(pattern, models.ChannelSeparator)
+	for _, segment := range segments {
+		if segment == tailWildcard {
+			if node.tail == nil {
+				node.tail = make(map[string]*models.Client)
+			}
+			node.tail[client.ID] = client
+			return true
+		}
+		node = node.child(segment, true)
+	}
+	if node.clients == nil {
+		node.clients = make(map[string]*models.Client)
+	}
+	node.clients[client.ID] = client
+	return true
+}
+
+// Remove unsubscribes a client from a pattern, reporting whether it was subscribed
+func (p *patternSubscriptions) Remove(pattern, clientID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.byClient[clientID][pattern] {
+		return false
+	}
+	delete(p.byClient[clientID], pattern)
+	if len(p.byClient[clientID]) == 0 {
+		delete(p.byClient, clientID)
+	}
+	p.root.remove(strings.Split(pattern, models.ChannelSeparator), clientID)
+	return true
+}
+
+// RemoveClient unsubscribes a client from all its patterns
+func (p *patternSubscriptions) RemoveClient(clientID string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for pattern := range p.byClient[clientID] {
+		p.root.remove(strings.Split(pattern, models.ChannelSeparator), clientID)
+	}
+	delete(p.byClient, clientID)
+}
+
+// Match returns the clients subscribed to a pattern matching the channel name, by client ID
+func (p *patternSubscriptions) Match(channelName string) map[string]*models.Client {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	matched := make(map[string]*models.Client)
+	if len(p.byClient) > 0 {
+		p.root.match(strings.Split(channelName, models.ChannelSeparator), matched)
+	}
+	return matched
+}
+
+// child returns the node of a segment below n, creating it if asked to
+func (n *patternNode) child(segment string, create bool) *patternNode {
+	if segment == segmentWildcard {
+		if n.wildcard == nil && create {
+			n.wildcard = &patternNode{}
+		}
+		return n.wildcard
+	}
+	child := n.children[segment]
+	if child == nil && create {
+		if n.children == nil {
+			n.children = make(map[string]*patternNode)
+		}
+		child = &patternNode{}
+		n.children[segment] = child
+	}
+	return child
+}
+
+// remove removes a client's pattern below n, pruning the nodes left empty
+func (n *patternNode) remove(segments []string, clientID string) {
+	if len(segments) == 0 {
+		delete(n.clients, clientID)
+		return
+	}
+	if segments[0] == tailWildcard {
+		delete(n.tail, clientID)
+		return
+	}
+	child := n.child(segments[0], false)
+	if child == nil {
+		return
+	}
+	child.remove(segments[1:], clientID)
+	if !child.empty() {
+		return
+	}
+	if segments[0] == segmentWildcard {
+		n.wildcard = nil
+	} else {
+		delete(n.children, segments[0])
+	}
+}
+
+// match adds the clients of the patterns below n matching the remaining segments of a name
+func (n *patternNode) match(segments []string, matched map[string]*models.Client) {
+	if len(segments) == 0 {
+		for id, client := range n.clients {
+			matched[id] = client
+		}
+		return
+	}
+	for id, client := range n.tail {
+		matched[id] = client
+	}
+	if child := n.children[segments[0]]; child != nil {
+		child.match(segments[1:], matched)
+	}
+	if n.wildcard != nil {
+		n.wildcard.match(segments[1:], matched)
+	}
+}
+
+// handleSubscribePattern subscribes a client to every channel matching a pattern, such as
+// "orders.*". The subscription is approved by Laravel like a join, and receives the broadcasts
+// of public channels (and of channels requiring authentication, once authenticated) without
+// joining them; observers receive those of every channel.
+func (s *Server) handleSubscribePattern(client *models.Client, msg map[string]interface{}) {
+	pattern, _ := msg["pattern"].(string)
+	if !validSubscriptionPattern(pattern) {
+		s.sendError(client, "Invalid channel pattern")
+		return
+	}
+
+	if !client.Observer {
+		subscribeMessage := models.Message{
+			ID:        s.NewID(),
+			Event:     "subscribe_pattern",
+			Data:      map[string]interface{}{"pattern": pattern},
+			UserID:    client.UserID,
+			Username:  client.Username,
+			Timestamp: s.Now(),
+		}
+		if err := s.laravelSvc.DispatchMessage(subscribeMessage, client); err != nil {
+			s.logger.Error("Failed to dispatch subscribe_pattern message to Laravel: %v", err)
+			s.sendError(client, "Pattern subscription denied")
+			return
+		}
+	}
+
+	if !s.patterns.Add(pattern, client) {
+		s.sendError(client, "Too many pattern subscriptions")
+		return
+	}
+	s.logger.Info("Client %s (%s) subscribed to channel pattern '%s'", client.ID, client.Username, pattern)
+
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "subscribed_pattern",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]string{"pattern": pattern},
+		Timestamp: s.Now(),
+	})
+}
+
+// handleUnsubscribePattern removes one of a client's pattern subscriptions
+func (s *Server) handleUnsubscribePattern(client *models.Client, msg map[string]interface{}) {
+	pattern, _ := msg["pattern"].(string)
+	if !s.patterns.Remove(pattern, client.ID) {
+		s.sendError(client, "Not subscribed to this pattern")
+		return
+	}
+	s.logger.Info("Client %s (%s) unsubscribed from channel pattern '%s'", client.ID, client.Username, pattern)
+
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "unsubscribed_pattern",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]string{"pattern": pattern},
+		Timestamp: s.Now(),
+	})
+}
+
+// patternSubscribers returns the pattern subscribers a channel's broadcasts are delivered to:
+// those matching its name that aren't members and may read it
+func (s *Server) patternSubscribers(channel *models.Channel, members map[string]*models.Client) []*models.Client {
+	matched := s.patterns.Match(channel.Name)
+	if len(matched) == 0 {
+		return nil
+	}
+
+	private, requireAuth := channel.AccessFlags()
+	subscribers := make([]*models.Client, 0, len(matched))
+	for id, client := range matched {
+		if _, member := members[id]; member {
+			continue
+		}
+		if !client.Observer && (private || (requireAuth && client.UserID == "")) {
+			continue
+		}
+		subscribers = append(subscribers, client)
+	}
+	return subscribers
+}
@@ -0,0 +1,96 @@
+package websocket
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+func TestSubscriptionPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+		matches []string
+		misses  []string
+	}{
+		{"orders.*", true, []string{"orders.42", "orders.x"}, []string{"orders", "orders.42.items", "invoices.42"}},
+		{"orders.**", true, []string{"orders.42", "orders.42.items.7"}, []string{"orders", "invoices.42"}},
+		{"*.created", true, []string{"orders.created", "users.created"}, []string{"created", "orders.42.created"}},
+		{"tenant.*.orders.**", true, []string{"tenant.7.orders.1", "tenant.7.orders.1.items"}, []string{"tenant.7.orders", "tenant.orders.1"}},
+		{"orders.42", false, nil, nil},
+		{"orders.**.items", false, nil, nil},
+		{"orders.4*", false, nil, nil},
+		{"orders..*", false, nil, nil},
+		{"", false, nil, nil},
+	}
+	for _, tt := range tests {
+		if valid := validSubscriptionPattern(tt.pattern); valid != tt.valid {
+			t.Errorf("Expected pattern %q valid to be %v", tt.pattern, tt.valid)
+			continue
+		}
+		if !tt.valid {
+			continue
+		}
+		patterns := newPatternSubscriptions()
+		patterns.Add(tt.pattern, models.NewClient("client-1", nil))
+		for _, name := range tt.matches {
+			if len(patterns.Match(name)) != 1 {
+				t.Errorf("Expected %q to match %q", tt.pattern, name)
+			}
+		}
+		for _, name := range tt.misses {
+			if len(patterns.Match(name)) != 0 {
+				t.Errorf("Expected %q not to match %q", tt.pattern, name)
+			}
+		}
+		if !patterns.Remove(tt.pattern, "client-1") || !patterns.root.empty() {
+			t.Errorf("Expected removing %q to prune the trie", tt.pattern)
+		}
+	}
+}
+
+func TestPatternSubscribersFanOut(t *testing.T) {
+	server := newTestServer(t, &config.Config{})
+	subscriber := dialTestServer(t, server, "")
+	subscriber.send(map[string]interface{}{"action": "subscribe_pattern", "pattern": "orders.*"})
+	subscriber.expect("subscribed_pattern")
+	member := dialTestServer(t, server, "")
+	member.send(map[string]interface{}{"action": "join_channel", "channel": "orders.7"})
+	member.expect("joined_channel")
+
+	tests := []struct {
+		channel    string
+		recipients int
+		dropped    string
+	}{
+		// Nobody joined orders.42: only the subscriber receives it
+		{"orders.42", 1, ""},
+		// The member and the subscriber each receive it once
+		{"orders.7", 2, ""},
+		{"orders.42.items", 0, "no_channel"},
+		{"invoices.42", 0, "no_channel"},
+	}
+	for _, tt := range tests {
+		delivery := server.BroadcastToChannel(tt.channel, models.Message{ID: server.NewID(), Channel: tt.channel, Event: "order"})
+		if delivery.Recipients != tt.recipients || delivery.Dropped != tt.dropped {
+			t.Errorf("Expected %s delivered to %d recipients (dropped %q), got %+v", tt.channel, tt.recipients, tt.dropped, delivery)
+		}
+	}
+	for _, conn := range []*testConn{subscriber, subscriber, member} {
+		if event := conn.expect("order"); event["channel"] != "orders.42" && event["channel"] != "orders.7" {
+			t.Errorf("Expected broadcasts of matching channels only, got %v", event)
+		}
+	}
+
+	var channels []string
+	for name := range server.GetChannels() {
+		channels = append(channels, name)
+	}
+	sort.Strings(channels)
+	if !reflect.DeepEqual(channels, []string{"orders.7"}) {
+		t.Errorf("Expected broadcasting to pattern subscribers to create no channel, got %v", channels)
+	}
+}
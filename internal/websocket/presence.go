@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// pendingLeave is a leave_channel dispatch held back for the presence grace period
+type pendingLeave struct {
+	timer   *time.Timer
+	client  *models.Client
+	message models.Message
+}
+
+// presenceKey identifies a user's membership of a channel across connections
+func presenceKey(userID, channelName string) string {
+	return userID + "\x00" + channelName
+}
+
+// scheduleLeave dispatches the leave_channel message of a disconnected client after the
+// presence grace period. Anonymous clients, and every client when the grace period is
+// disabled or the server is draining, are dispatched right away.
+func (s *Server) scheduleLeave(client *models.Client, message models.Message) {
+	if s.config.PresenceGrace <= 0 || client.UserID == "" || s.IsDraining() {
+		s.queueDispatch(client, func() { s.dispatchLeave(client, message) })
+		return
+	}
+
+	key := presenceKey(client.UserID, message.Channel)
+	pending := &pendingLeave{client: client, message: message}
+
+	s.mutex.Lock()
+	if previous, exists := s.pendingLeaves[key]; exists {
+		// Another connection of the same user already left; its leave is superseded
+		previous.timer.Stop()
+	}
+	pending.timer = time.AfterFunc(s.config.PresenceGrace, func() {
+		s.mutex.Lock()
+		if s.pendingLeaves[key] != pending {
+			s.mutex.Unlock()
+			return
+		}
+		delete(s.pendingLeaves, key)
+		s.mutex.Unlock()
+
+		s.dispatchLeave(pending.client, pending.message)
+	})
+	s.pendingLeaves[key] = pending
+	s.mutex.Unlock()
+}
+
+// takePendingLeave cancels the held-back leave of a user rejoining a channel within the grace
+// period, returning it so the caller can still dispatch it if the rejoin fails
+func (s *Server) takePendingLeave(userID, channelName string) *pendingLeave {
+	if userID == "" {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := presenceKey(userID, channelName)
+	pending, exists := s.pendingLeaves[key]
+	if !exists || !pending.timer.Stop() {
+		return nil
+	}
+	delete(s.pendingLeaves, key)
+	return pending
+}
+
+// dispatchLeave notifies Laravel that a disconnected client left a channel
+func (s *Server) dispatchLeave(client *models.Client, message models.Message) {
+	// Don't block disconnection if Laravel fails
+	if err := s.laravelSvc.DispatchMessage(message, client); err != nil {
+		s.logger.Error("Failed to dispatch disconnect leave_channel message to Laravel for channel %s: %v", message.Channel, err)
+	} else {
+		s.logger.Debug("Notified Laravel about client %s leaving channel %s due to disconnection", client.ID, message.Channel)
+	}
+}
+
+// flushPendingLeaves dispatches every held-back leave immediately. Draining servers call it
+// since their clients reconnect to another node.
+func (s *Server) flushPendingLeaves() {
+	s.mutex.Lock()
+	pending := make([]*pendingLeave, 0, len(s.pendingLeaves))
+	for key, leave := range s.pendingLeaves {
+		if leave.timer.Stop() {
+			pending = append(pending, leave)
+		}
+		delete(s.pendingLeaves, key)
+	}
+	s.mutex.Unlock()
+
+	for _, leave := range pending {
+		s.dispatchLeave(leave.client, leave.message)
+	}
+}
@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// maxPushDevicesPerUser caps the devices kept per user; registering another one replaces the
+// oldest
+const maxPushDevicesPerUser = 10
+
+// SetPushService enables the push fallback for user broadcasts
+func (s *Server) SetPushService(push *services.PushService) {
+	s.push = push
+}
+
+// RegisterPushDevice adds a device to a user's push fallback. Registering a token again only
+// refreshes its registration time.
+func (s *Server) RegisterPushDevice(userID string, device models.PushDevice) error {
+	if err := device.Validate(); err != nil {
+		return err
+	}
+	device.RegisteredAt = s.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	devices := s.pushDevices[userID]
+	for i, existing := range devices {
+		if existing.Token == device.Token {
+			devices = append(devices[:i], devices[i+1:]...)
+			break
+		}
+	}
+	if len(devices) >= maxPushDevicesPerUser {
+		devices = devices[1:]
+	}
+	s.pushDevices[userID] = append(devices, device)
+
+	s.logger.Info("Registered %s push device for user %s", device.Provider, userID)
+	return nil
+}
+
+// RemovePushDevice removes a device token from a user, returning whether it was registered
+func (s *Server) RemovePushDevice(userID, token string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	devices := s.pushDevices[userID]
+	for i, device := range devices {
+		if device.Token == token {
+			devices = append(devices[:i:i], devices[i+1:]...)
+			if len(devices) == 0 {
+				delete(s.pushDevices, userID)
+			} else {
+				s.pushDevices[userID] = devices
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// GetPushDevices returns the devices registered for a user
+func (s *Server) GetPushDevices(userID string) []models.PushDevice {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]models.PushDevice{}, s.pushDevices[userID]...)
+}
+
+// PushToUser sends a push notification to every device of a user, for user broadcasts that
+// found no active connection. Devices whose token the provider rejects as unregistered are
+// removed.
+func (s *Server) PushToUser(userID string, notification models.PushNotification, message models.Message) models.PushResult {
+	var result models.PushResult
+	if !s.push.Enabled() {
+		result.Skipped = "push notifications are not configured"
+		return result
+	}
+
+	devices := s.GetPushDevices(userID)
+	if len(devices) == 0 {
+		result.Skipped = "user has no registered devices"
+		return result
+	}
+
+	for _, device := range devices {
+		result.Attempted++
+		err := s.push.Send(device, notification, message)
+		if err == nil {
+			result.Delivered++
+			continue
+		}
+
+		result.Failed++
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", device.Provider, err))
+		if errors.Is(err, models.ErrPushTokenUnregistered) && s.RemovePushDevice(userID, device.Token) {
+			result.Removed++
+		}
+	}
+
+	s.logger.Info("Push fallback for user %s: %d of %d devices notified", userID, result.Delivered, result.Attempted)
+	return result
+}
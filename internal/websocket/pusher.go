@@ -0,0 +1,366 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"socket-server/internal/models"
+)
+
+// pusherActivityTimeout is how long Pusher clients stay silent before sending pusher:ping,
+// announced when the connection is established
+const pusherActivityTimeout = 120 * time.Second
+
+// PusherApp returns the credentials of the emulated Pusher app
+func (s *Server) PusherApp() models.PusherApp {
+	return models.PusherApp{ID: s.config.PusherAppID, Key: s.config.PusherAppKey, Secret: s.config.PusherAppSecret}
+}
+
+// HandlePusher serves Pusher protocol clients at /app/{key}, so Laravel Echo with the pusher-js
+// connector works unmodified. Private and presence subscriptions are checked against the
+// signature Laravel's /broadcasting/auth endpoint issued with the app secret; the subscription
+// is then handled like a native join_channel, Laravel dispatch included.
+func (s *Server) HandlePusher(w http.ResponseWriter, r *http.Request) {
+	client := s.upgradeClient(w, r)
+	if client == nil {
+		return
+	}
+	client.Protocol = models.ProtocolPusher
+
+	key := strings.TrimPrefix(r.URL.Path, "/app/")
+	protocol, _ := strconv.Atoi(r.URL.Query().Get("protocol"))
+	switch {
+	case key != s.config.PusherAppKey:
+		s.refusePusherClient(client, 4001, "App key "+key+" not in this cluster")
+		return
+	case protocol < 5 || protocol > 7:
+		s.refusePusherClient(client, 4007, "Unsupported protocol version")
+		return
+	}
+
+	// Only channel messages and errors mean something to Pusher clients; confirmations and other
+	// system messages of the native protocol are skipped
+	socketID := models.NewPusherSocketID()
+	client.SetEncoder(func(message models.Message) ([]byte, error) {
+		switch {
+		case message.ExcludeClientID == socketID:
+			return nil, nil
+		case message.Channel != "":
+			return models.EncodePusherMessage(message)
+		case message.Event == "error":
+			data, _ := message.Data.(map[string]string)
+			return models.EncodePusherEvent(models.PusherError, "", map[string]interface{}{"message": data["error"], "code": nil})
+		}
+		return nil, nil
+	})
+	s.registerClient(client)
+
+	established, _ := models.EncodePusherEvent(models.PusherConnectionEstablished, "", map[string]interface{}{
+		"socket_id":        socketID,
+		"activity_timeout": int(pusherActivityTimeout.Seconds()),
+	})
+	if err := client.WriteText(established); err != nil {
+		s.logger.Debug("Failed to send Pusher handshake to client %s: %v", client.ID, err)
+		client.SetDisconnectReason(DisconnectReasonConnectionLost)
+		s.disconnectClient(client)
+		return
+	}
+
+	done := make(chan bool, 2)
+	go s.handlePusherMessages(client, socketID, done)
+	go s.handleClientPing(client, done)
+
+	<-done
+
+	// Members of presence channels are announced as gone once their last connection left
+	members := s.pusherMemberships(client)
+	s.disconnectClient(client)
+	for channelName, member := range members {
+		s.announcePusherMemberRemoved(channelName, member)
+	}
+}
+
+// refusePusherClient sends a Pusher error to a client that can't be served and closes it with
+// the error code; pusher-js doesn't reconnect after codes 4000-4099
+func (s *Server) refusePusherClient(client *models.Client, code int, message string) {
+	s.logger.Warn("Refused Pusher client %s: %s", client.ID, message)
+	frame, _ := models.EncodePusherEvent(models.PusherError, "", map[string]interface{}{"message": message, "code": code})
+	client.WriteText(frame)
+	client.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, message), time.Now().Add(time.Second))
+	client.Close()
+	// The client was never registered, so disconnectClient won't free its slot
+	s.releaseConnection(client.RemoteAddr)
+}
+
+// handlePusherMessages reads a Pusher client's events until the connection closes
+func (s *Server) handlePusherMessages(client *models.Client, socketID string, done chan bool) {
+	defer func() {
+		s.logger.Debug("Client %s message handler exiting", client.ID)
+		done <- true
+	}()
+	defer s.recoverClient(client, "reader")
+
+	for {
+		frame, err := client.SafeReadFrame()
+		if err != nil {
+			s.recordReadError(client, err)
+			return
+		}
+
+		if err := client.SafeSetReadDeadline(time.Now().Add(client.ReadTimeout())); err != nil {
+			s.logger.Debug("Client %s failed to set read deadline: %v", client.ID, err)
+			return
+		}
+		client.LastSeen = s.Now()
+
+		var event models.PusherEvent
+		if err := json.Unmarshal(frame, &event); err != nil || event.Event == "" {
+			s.logger.Debug("Client %s sent an invalid Pusher event: %s", client.ID, frame)
+			continue
+		}
+		s.messagesReceived.Add(1)
+
+		if event.Event == models.PusherPing {
+			pong, _ := models.EncodePusherEvent(models.PusherPong, "", map[string]interface{}{})
+			client.WriteText(pong)
+			continue
+		}
+		s.queueAction(client, func() { s.handlePusherEvent(client, socketID, event) })
+	}
+}
+
+// handlePusherEvent handles an event of a Pusher client other than a ping
+func (s *Server) handlePusherEvent(client *models.Client, socketID string, event models.PusherEvent) {
+	switch {
+	case event.Event == models.PusherSubscribe:
+		s.handlePusherSubscribe(client, socketID, event.Data)
+	case event.Event == models.PusherUnsubscribe:
+		s.handlePusherUnsubscribe(client, event.Data)
+	case event.Event == models.PusherSignin:
+		s.handlePusherSignin(client, socketID, event.Data)
+	case models.IsClientEvent(event.Event):
+		s.handlePusherClientEvent(client, event)
+	default:
+		s.logger.Debug("Client %s sent unsupported Pusher event '%s'", client.ID, event.Event)
+	}
+}
+
+// sendPusherError sends a pusher:error event, which doesn't close the connection
+func (s *Server) sendPusherError(client *models.Client, message string) {
+	frame, _ := models.EncodePusherEvent(models.PusherError, "", map[string]interface{}{"message": message, "code": nil})
+	client.WriteText(frame)
+}
+
+// handlePusherSubscribe checks the signature of a private or presence subscription, then joins
+// the channel and confirms with pusher_internal:subscription_succeeded
+func (s *Server) handlePusherSubscribe(client *models.Client, socketID string, data json.RawMessage) {
+	var subscription struct {
+		Channel     string `json:"channel"`
+		Auth        string `json:"auth"`
+		ChannelData string `json:"channel_data"`
+	}
+	if err := json.Unmarshal(data, &subscription); err != nil || subscription.Channel == "" {
+		s.sendPusherError(client, "Invalid subscription")
+		return
+	}
+	channelName := subscription.Channel
+
+	presence := strings.HasPrefix(channelName, models.PusherPresencePrefix)
+	private := presence || strings.HasPrefix(channelName, models.PusherPrivatePrefix)
+	if private && !s.PusherApp().VerifyChannelAuth(subscription.Auth, socketID, channelName, subscription.ChannelData) {
+		s.logger.Warn("Client %s sent an invalid signature for channel '%s'", client.ID, channelName)
+		s.sendPusherError(client, "Invalid signature for subscription to "+channelName)
+		return
+	}
+
+	join := map[string]interface{}{"action": "join_channel", "channel": channelName, "private": private}
+	var member models.PusherMember
+	if presence {
+		if err := json.Unmarshal([]byte(subscription.ChannelData), &member); err != nil || member.UserID == "" {
+			s.sendPusherError(client, "Presence subscriptions require channel_data with a user_id")
+			return
+		}
+		join["data"] = member
+	}
+
+	wasPresent := presence && s.isPusherMemberPresent(channelName, member.UserID)
+	if _, isUserChannel := s.userChannelOwner(channelName); isUserChannel && private {
+		// join_channel keeps personal channels to their signed-in owner; here the app's signature
+		// grants it, as for Echo.private("user.42") without user authentication
+		s.joinGrantedChannel(client, s.getOrCreateChannel(channelName, true))
+	} else {
+		s.handleAction(client, join)
+	}
+	if !client.GetChannels()[channelName] {
+		return
+	}
+
+	var confirmation interface{} = map[string]interface{}{}
+	if presence {
+		confirmation = map[string]interface{}{"presence": s.pusherPresence(channelName)}
+	}
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Channel:   channelName,
+		Event:     models.PusherSubscriptionSucceeded,
+		Priority:  models.PriorityHigh,
+		Data:      confirmation,
+		Timestamp: s.Now(),
+	})
+
+	if presence && !wasPresent {
+		s.sendToPusherMembers(channelName, client.ID, models.PusherMemberAdded, member)
+	}
+}
+
+// handlePusherUnsubscribe leaves a channel
+func (s *Server) handlePusherUnsubscribe(client *models.Client, data json.RawMessage) {
+	var subscription struct {
+		Channel string `json:"channel"`
+	}
+	if err := json.Unmarshal(data, &subscription); err != nil || !client.GetChannels()[subscription.Channel] {
+		return
+	}
+
+	members := s.pusherMemberships(client)
+	s.handleAction(client, map[string]interface{}{"action": "leave_channel", "channel": subscription.Channel})
+	if member, ok := members[subscription.Channel]; ok {
+		s.announcePusherMemberRemoved(subscription.Channel, member)
+	}
+}
+
+// handlePusherSignin authenticates a client with user data signed by the app, the user
+// authentication of pusher-js
+func (s *Server) handlePusherSignin(client *models.Client, socketID string, data json.RawMessage) {
+	var signin struct {
+		Auth     string `json:"auth"`
+		UserData string `json:"user_data"`
+	}
+	var user models.PusherUser
+	if err := json.Unmarshal(data, &signin); err != nil || json.Unmarshal([]byte(signin.UserData), &user) != nil || user.ID == "" {
+		s.sendPusherError(client, "Invalid signin")
+		return
+	}
+	if !s.PusherApp().VerifyUserAuth(signin.Auth, socketID, signin.UserData) {
+		s.logger.ClientAuthenticationFailed(client.ID, models.ErrInvalidPusherSignature)
+		s.sendPusherError(client, "Invalid signature for signin")
+		return
+	}
+	if !s.admitMessage(client) {
+		return
+	}
+
+	username, _ := user.UserInfo["name"].(string)
+	email, _ := user.UserInfo["email"].(string)
+	if !s.signIn(client, user.ID, username, email, nil) {
+		return
+	}
+	s.laravelSvc.DispatchAuthentication(client, "success", signin.Auth)
+	s.joinUserChannel(client)
+	s.applyUserGrants(client)
+
+	frame, _ := models.EncodePusherEvent(models.PusherSigninSuccess, "", map[string]interface{}{"user_data": signin.UserData})
+	client.WriteText(frame)
+}
+
+// handlePusherClientEvent relays a client- event. Like on Pusher, client events are limited to
+// private and presence channels; CLIENT_EVENTS_CHANNELS must allow them as well.
+func (s *Server) handlePusherClientEvent(client *models.Client, event models.PusherEvent) {
+	if !strings.HasPrefix(event.Channel, models.PusherPrivatePrefix) && !strings.HasPrefix(event.Channel, models.PusherPresencePrefix) {
+		s.sendPusherError(client, "Client event rejected - only supported on private and presence channels")
+		return
+	}
+
+	var data interface{}
+	json.Unmarshal(event.Data, &data)
+	s.handleAction(client, map[string]interface{}{"action": "send_message", "channel": event.Channel, "event": event.Event, "data": data})
+}
+
+// pusherMemberships returns the presence member a client joined each presence channel as
+func (s *Server) pusherMemberships(client *models.Client) map[string]models.PusherMember {
+	members := make(map[string]models.PusherMember)
+	for channelName, metadata := range client.GetAllChannelMetadata() {
+		if member, ok := metadata.Data.(models.PusherMember); ok {
+			members[channelName] = member
+		}
+	}
+	return members
+}
+
+// isPusherMemberPresent reports whether a user is a member of a presence channel through any
+// of their connections
+func (s *Server) isPusherMemberPresent(channelName, userID string) bool {
+	channel, exists := s.GetChannel(channelName)
+	if !exists {
+		return false
+	}
+	for _, client := range channel.GetClients() {
+		if metadata := client.GetChannelMetadata(channelName); metadata != nil {
+			if member, ok := metadata.Data.(models.PusherMember); ok && member.UserID == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pusherPresence builds the presence payload of a subscription: the members' IDs, their user
+// info by ID and their count
+func (s *Server) pusherPresence(channelName string) map[string]interface{} {
+	ids := make([]string, 0)
+	hash := make(map[string]json.RawMessage)
+	if channel, exists := s.GetChannel(channelName); exists {
+		for _, client := range channel.GetClients() {
+			metadata := client.GetChannelMetadata(channelName)
+			if metadata == nil {
+				continue
+			}
+			member, ok := metadata.Data.(models.PusherMember)
+			if _, seen := hash[member.UserID]; !ok || seen {
+				continue
+			}
+			ids = append(ids, member.UserID)
+			hash[member.UserID] = member.UserInfo
+			if hash[member.UserID] == nil {
+				hash[member.UserID] = json.RawMessage("null")
+			}
+		}
+	}
+	return map[string]interface{}{"ids": ids, "hash": hash, "count": len(ids)}
+}
+
+// announcePusherMemberRemoved tells a presence channel's members that a user left, unless
+// another of the user's connections is still a member
+func (s *Server) announcePusherMemberRemoved(channelName string, member models.PusherMember) {
+	if s.isPusherMemberPresent(channelName, member.UserID) {
+		return
+	}
+	s.sendToPusherMembers(channelName, "", models.PusherMemberRemoved, map[string]string{"user_id": member.UserID})
+}
+
+// sendToPusherMembers sends a presence event to the Pusher clients of a channel, except one
+func (s *Server) sendToPusherMembers(channelName, exceptClientID, event string, data interface{}) {
+	channel, exists := s.GetChannel(channelName)
+	if !exists {
+		return
+	}
+
+	message := models.Message{
+		ID:        s.NewID(),
+		Channel:   channelName,
+		Event:     event,
+		Priority:  models.PriorityHigh,
+		Data:      data,
+		Timestamp: s.Now(),
+	}
+	for _, member := range channel.GetClients() {
+		if member.ID != exceptClientID && member.Protocol == models.ProtocolPusher {
+			member.SendMessage(message)
+		}
+	}
+}
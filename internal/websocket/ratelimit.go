@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// admitMessage applies the client's message rate limit to a message it sent. With the throttle
+// action, reading from the client pauses until the message fits the rate, and it is then
+// handled. With the disconnect action, the message is discarded and the client disconnected.
+func (s *Server) admitMessage(client *models.Client) bool {
+	allowed, newBurst := client.AllowMessage()
+	if allowed {
+		return true
+	}
+	s.messagesRateLimited.Add(1)
+
+	if s.config.ClientRateLimitAction == "disconnect" {
+		// Messages read while the connection is closing are discarded silently
+		if !newBurst {
+			return false
+		}
+		s.logger.Warn("Disconnecting client %s (%s) from %s: message rate limit exceeded", client.ID, client.UserID, client.RemoteAddr)
+		s.sendError(client, "Rate limit exceeded")
+		client.SetDisconnectReason(DisconnectReasonRateLimited)
+		client.CloseAfterFlush()
+		return false
+	}
+
+	if newBurst {
+		s.logger.Warn("Throttling client %s (%s) from %s: message rate limit exceeded", client.ID, client.UserID, client.RemoteAddr)
+	}
+	client.WaitMessage()
+	// The wait counts against the read timeout that was set when the message arrived
+	if err := client.SafeSetReadDeadline(time.Now().Add(client.ReadTimeout())); err != nil {
+		return false
+	}
+	return true
+}
+
+// RecordHTTPRateLimited counts an API request rejected by the per-IP rate limit
+func (s *Server) RecordHTTPRateLimited() {
+	s.httpRateLimited.Add(1)
+}
@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+const (
+	// statusInterval is how often a node advertises its load to the other nodes of the cluster
+	statusInterval = 5 * time.Second
+	// statusMaxAge is how long a peer's status is trusted; peers silent for longer aren't suggested
+	statusMaxAge = 3 * statusInterval
+	// maxSuggestedEndpoints is the length of the suggested_endpoints lists sent to clients
+	maxSuggestedEndpoints = 3
+)
+
+// advertiseStatus publishes this node's load to the other nodes every status interval, and
+// forgets peers that stopped advertising theirs
+func (s *Server) advertiseStatus() {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	s.publishStatus()
+	for range ticker.C {
+		s.publishStatus()
+
+		now := s.Now()
+		s.mutex.Lock()
+		for nodeID, peer := range s.peers {
+			if now.Sub(peer.UpdatedAt) > statusMaxAge {
+				delete(s.peers, nodeID)
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// publishStatus advertises this node's load to the other nodes
+func (s *Server) publishStatus() {
+	if err := s.cluster.Publish(services.ClusterEventStatus, s.nodeStatus()); err != nil {
+		s.logger.Debug("Cluster: failed to advertise the node status: %v", err)
+	}
+}
+
+// nodeStatus returns the load this node advertises
+func (s *Server) nodeStatus() models.NodeStatus {
+	s.mutex.RLock()
+	connections := len(s.clients)
+	s.mutex.RUnlock()
+
+	return models.NodeStatus{
+		NodeID:         s.NodeID(),
+		Endpoint:       s.config.PublicURL,
+		Connections:    connections,
+		MaxConnections: s.config.MaxConnections,
+		Pressure:       s.underPressure(connections),
+		Draining:       s.IsDraining(),
+		UpdatedAt:      s.Now(),
+	}
+}
+
+// handleClusterStatus records the load a peer advertised. Its age is measured from receipt, so
+// clock differences between nodes don't matter. A node that just joined is answered with this
+// node's status, so it doesn't wait for the next status interval to know its peers.
+func (s *Server) handleClusterStatus(event services.ClusterEvent) {
+	var status models.NodeStatus
+	if err := json.Unmarshal(event.Data, &status); err != nil {
+		s.logger.Warn("Cluster: ignoring malformed %s event from node %s: %v", event.Type, event.Node, err)
+		return
+	}
+	status.NodeID, status.UpdatedAt = event.Node, s.Now()
+
+	s.mutex.Lock()
+	_, known := s.peers[event.Node]
+	s.peers[event.Node] = status
+	s.mutex.Unlock()
+	if !known {
+		go s.publishStatus()
+	}
+}
+
+// underPressure reports whether a number of connections reaches the rebalance threshold
+func (s *Server) underPressure(connections int) bool {
+	return s.config.MaxConnections > 0 && s.config.RebalanceThreshold > 0 &&
+		connections*100 >= s.config.MaxConnections*s.config.RebalanceThreshold
+}
+
+// checkPressure is called as connections come and go. When the node comes under pressure, or
+// no longer is, it tells the other nodes right away rather than at the next status interval.
+func (s *Server) checkPressure(connections int) {
+	pressure := s.underPressure(connections)
+	if s.pressure.Swap(pressure) == pressure {
+		return
+	}
+	if pressure {
+		s.logger.Warn("Node under pressure with %d of %d connections, suggesting other nodes to new clients", connections, s.config.MaxConnections)
+	} else {
+		s.logger.Info("Node no longer under pressure with %d of %d connections", connections, s.config.MaxConnections)
+	}
+	if s.cluster != nil {
+		go s.publishStatus()
+	}
+}
+
+// SuggestedEndpoints returns the endpoints of the least loaded peers that can take more clients
+func (s *Server) SuggestedEndpoints() []string {
+	return models.SuggestEndpoints(s.Peers(), s.Now(), statusMaxAge, maxSuggestedEndpoints)
+}
+
+// Peers returns the last status advertised by each of the other nodes of the cluster, by node ID
+func (s *Server) Peers() []models.NodeStatus {
+	s.mutex.RLock()
+	peers := make([]models.NodeStatus, 0, len(s.peers))
+	for _, peer := range s.peers {
+		peers = append(peers, peer)
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].NodeID < peers[j].NodeID })
+	return peers
+}
@@ -0,0 +1,31 @@
+package websocket
+
+import (
+	"runtime/debug"
+
+	"socket-server/internal/models"
+)
+
+// recoverClient is deferred at the top of a client's goroutines. A panic is logged and closes
+// only that client's connection; the normal disconnect cleanup then runs as for a lost
+// connection.
+func (s *Server) recoverClient(client *models.Client, goroutine string) {
+	if recovered := recover(); recovered != nil {
+		s.handleClientPanic(client, goroutine, recovered, debug.Stack())
+	}
+}
+
+// handleClientPanic reports a panic recovered in one of a client's goroutines and closes the
+// client's connection
+func (s *Server) handleClientPanic(client *models.Client, goroutine string, recovered interface{}, stack []byte) {
+	s.connectionPanics.Add(1)
+	s.logger.Error("Recovered panic in %s goroutine of client %s (user %q, %s): %v\n%s", goroutine, client.ID, client.UserID, client.RemoteAddr, recovered, stack)
+
+	client.SetDisconnectReason(DisconnectReasonInternalError)
+	client.Close()
+}
+
+// RecordHTTPPanic counts a panic recovered in an HTTP handler
+func (s *Server) RecordHTTPPanic() {
+	s.httpPanics.Add(1)
+}
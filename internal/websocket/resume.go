@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"sort"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// resumableSession is the state of a disconnected authenticated client that can be resumed
+type resumableSession struct {
+	nonce     string
+	userID    string
+	username  string
+	email     string
+	ip        string
+	channels  map[string]interface{} // channel -> data sent when joining
+	events    map[string][]string    // channel -> events subscribed to, for channels filtered by event
+	expiresAt time.Time
+}
+
+// issueResumeToken sends the client a new single-use resume token. Only the nonce of the latest
+// token is kept, so issuing a new one invalidates the previous token.
+func (s *Server) issueResumeToken(client *models.Client) {
+	// A resumed session would lose the observer mode, so observers reconnect and authenticate
+	if s.resumeSigner == nil || client.Observer {
+		return
+	}
+
+	token, claims, err := s.resumeSigner.Issue(client.ID, client.UserID)
+	if err != nil {
+		s.logger.Error("Failed to issue resume token for client %s: %v", client.ID, err)
+		return
+	}
+
+	s.mutex.Lock()
+	s.resumeNonces[client.ID] = claims.Nonce
+	s.mutex.Unlock()
+
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "session",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]interface{}{"resume_token": token, "resume_ttl": int(s.config.ResumeTTL.Seconds())},
+		Timestamp: s.Now(),
+	})
+}
+
+// saveResumableSession keeps a disconnected client's identity and channels for the resume
+// window. Kicked or replaced clients and clients that never received a resume token can't be
+// resumed.
+func (s *Server) saveResumableSession(client *models.Client, channels map[string]bool, metadata map[string]*models.ChannelMetadata, reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	nonce, issued := s.resumeNonces[client.ID]
+	delete(s.resumeNonces, client.ID)
+	if !issued || reason == DisconnectReasonKicked || reason == DisconnectReasonDuplicateSession {
+		return
+	}
+
+	session := &resumableSession{
+		nonce:     nonce,
+		userID:    client.UserID,
+		username:  client.Username,
+		email:     client.Email,
+		ip:        remoteIP(client.RemoteAddr),
+		channels:  make(map[string]interface{}, len(channels)),
+		events:    make(map[string][]string),
+		expiresAt: s.Now().Add(s.config.ResumeTTL),
+	}
+	for channelName := range channels {
+		var data interface{}
+		if channelMetadata, exists := metadata[channelName]; exists && channelMetadata != nil {
+			data = channelMetadata.Data
+			if len(channelMetadata.Events) > 0 {
+				session.events[channelName] = channelMetadata.Events
+			}
+		}
+		session.channels[channelName] = data
+	}
+	s.resumableSessions[client.ID] = session
+}
+
+// handleResume restores a disconnected session on a new connection: the user identity is
+// restored and the previous channels are joined again through the regular join checks.
+// Tokens are single-use; reuse and address changes are logged as anomalies.
+func (s *Server) handleResume(client *models.Client, msg map[string]interface{}) {
+	if s.resumeSigner == nil {
+		s.sendError(client, "Session resumption is disabled")
+		return
+	}
+	if client.UserID != "" {
+		s.sendError(client, "Already authenticated")
+		return
+	}
+
+	token, ok := msg["token"].(string)
+	if !ok {
+		s.sendError(client, "Invalid token format")
+		return
+	}
+
+	claims, err := s.resumeSigner.Verify(token)
+	if err != nil {
+		s.logger.Warn("Client %s sent an invalid resume token from %s", client.ID, client.RemoteAddr)
+		s.sendError(client, "Invalid resume token")
+		return
+	}
+
+	ip := remoteIP(client.RemoteAddr)
+	policy := s.config.ResumeIPPolicy
+
+	s.mutex.Lock()
+	if _, used := s.usedResumeNonces[claims.Nonce]; used {
+		s.mutex.Unlock()
+		s.logger.Warn("Resume anomaly: token for session %s (user %s) reused from %s", claims.SessionID, claims.UserID, client.RemoteAddr)
+		s.sendError(client, "Resume token already used")
+		return
+	}
+	session, exists := s.resumableSessions[claims.SessionID]
+	if !exists || session.nonce != claims.Nonce || s.Now().After(session.expiresAt) {
+		s.mutex.Unlock()
+		s.sendError(client, "Session expired")
+		return
+	}
+	if session.ip != ip && policy == "reject" {
+		s.mutex.Unlock()
+		s.logger.Warn("Resume anomaly: session %s (user %s) rejected from %s, previously %s", claims.SessionID, session.userID, ip, session.ip)
+		s.sendError(client, "Session resume from a different address rejected")
+		return
+	}
+	delete(s.resumableSessions, claims.SessionID)
+	s.usedResumeNonces[claims.Nonce] = session.expiresAt
+	s.mutex.Unlock()
+
+	if session.ip != ip && policy != "allow" {
+		s.logger.Warn("Resume anomaly: session %s (user %s) resumed from %s, previously %s", claims.SessionID, session.userID, ip, session.ip)
+	}
+
+	// The token is spent even if the policy turns the resume down
+	fingerprint := fingerprintClient(client, session.userID, msg)
+	if !s.admitConnection(client, session.userID, fingerprint) {
+		return
+	}
+	client.SetUserInfo(session.userID, session.username, session.email)
+	client.SetFingerprint(fingerprint)
+	s.logger.Info("Client %s resumed session %s (user %s)", client.ID, claims.SessionID, session.userID)
+	s.analytics.RecordUser(session.userID)
+	s.laravelSvc.DispatchAuthentication(client, "resumed", "")
+
+	channelNames := make([]string, 0, len(session.channels))
+	for channelName := range session.channels {
+		channelNames = append(channelNames, channelName)
+	}
+	sort.Strings(channelNames)
+	for _, channelName := range channelNames {
+		join := map[string]interface{}{"channel": channelName, "data": session.channels[channelName]}
+		if events, filtered := session.events[channelName]; filtered {
+			join["events"] = events
+		}
+		s.handleJoinChannel(client, join)
+	}
+	s.joinUserChannel(client)
+	s.applyUserGrants(client)
+
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "resumed",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]interface{}{"session_id": claims.SessionID, "channels": channelNames},
+		Timestamp: s.Now(),
+	})
+	s.issueResumeToken(client)
+}
+
+// sweepResumableSessions drops expired sessions and used nonces. The caller must hold s.mutex.
+func (s *Server) sweepResumableSessions(now time.Time) (entries int) {
+	for sessionID, session := range s.resumableSessions {
+		if now.After(session.expiresAt) {
+			delete(s.resumableSessions, sessionID)
+			entries++
+		}
+	}
+	for nonce, forgetAt := range s.usedResumeNonces {
+		if now.After(forgetAt) {
+			delete(s.usedResumeNonces, nonce)
+			entries++
+		}
+	}
+	return entries
+}
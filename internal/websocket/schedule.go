@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// BroadcastExecutor runs a broadcast given as the body of a POST /api/broadcast request
+type BroadcastExecutor func(payload json.RawMessage) error
+
+// scheduledBroadcast is a stored broadcast with the timer that runs it
+type scheduledBroadcast struct {
+	broadcast models.ScheduledBroadcast
+	timer     *time.Timer
+}
+
+// SetBroadcastExecutor sets how scheduled broadcasts are run and starts the timers of those
+// restored from a snapshot. Broadcasts that came due while the server was down run right away.
+// A standby starts them once promoted.
+func (s *Server) SetBroadcastExecutor(executor BroadcastExecutor) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.broadcaster = executor
+	if s.standby != nil {
+		return
+	}
+	for _, scheduled := range s.schedules {
+		if scheduled.timer == nil {
+			s.armSchedule(scheduled)
+		}
+	}
+}
+
+// ScheduleBroadcast stores a broadcast to run at its send time and returns it with its ID
+func (s *Server) ScheduleBroadcast(broadcast models.ScheduledBroadcast) (models.ScheduledBroadcast, error) {
+	now := s.Now()
+	if err := broadcast.Validate(now); err != nil {
+		return broadcast, err
+	}
+	broadcast.ID = s.NewID()
+	broadcast.CreatedAt = now
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scheduled := &scheduledBroadcast{broadcast: broadcast}
+	s.schedules[broadcast.ID] = scheduled
+	if s.broadcaster != nil && s.standby == nil {
+		s.armSchedule(scheduled)
+	}
+
+	s.logger.Info("Scheduled broadcast %s for %s", broadcast.ID, broadcast.SendAt.Format(time.RFC3339))
+	return broadcast, nil
+}
+
+// CancelScheduledBroadcast removes a broadcast that hasn't run yet
+func (s *Server) CancelScheduledBroadcast(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scheduled, exists := s.schedules[id]
+	if !exists {
+		return models.ErrScheduleNotFound
+	}
+	if scheduled.timer != nil {
+		scheduled.timer.Stop()
+	}
+	delete(s.schedules, id)
+
+	s.logger.Info("Cancelled scheduled broadcast %s", id)
+	return nil
+}
+
+// GetScheduledBroadcasts returns the broadcasts waiting for their send time, soonest first
+func (s *Server) GetScheduledBroadcasts() []models.ScheduledBroadcast {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	broadcasts := make([]models.ScheduledBroadcast, 0, len(s.schedules))
+	for _, scheduled := range s.schedules {
+		broadcasts = append(broadcasts, scheduled.broadcast)
+	}
+	sort.Slice(broadcasts, func(i, j int) bool {
+		return broadcasts[i].SendAt.Before(broadcasts[j].SendAt)
+	})
+	return broadcasts
+}
+
+// armSchedule starts the timer of a scheduled broadcast; the caller must hold s.mutex
+func (s *Server) armSchedule(scheduled *scheduledBroadcast) {
+	id := scheduled.broadcast.ID
+	scheduled.timer = time.AfterFunc(scheduled.broadcast.SendAt.Sub(s.Now()), func() {
+		s.runScheduledBroadcast(id)
+	})
+}
+
+// runScheduledBroadcast runs a broadcast that came due, unless it was cancelled meanwhile
+func (s *Server) runScheduledBroadcast(id string) {
+	s.mutex.Lock()
+	scheduled, exists := s.schedules[id]
+	delete(s.schedules, id)
+	executor := s.broadcaster
+	s.mutex.Unlock()
+
+	if !exists {
+		return
+	}
+
+	if err := executor(scheduled.broadcast.Broadcast); err != nil {
+		s.logger.Error("Scheduled broadcast %s failed: %v", id, err)
+		return
+	}
+	s.logger.Info("Sent scheduled broadcast %s, due %s", id, scheduled.broadcast.SendAt.Format(time.RFC3339))
+}
@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+
+	"socket-server/internal/models"
+)
+
+// RegisterEventSchema sets the JSON Schema the data of an event's broadcasts must match,
+// replacing any previous schema of the event
+func (s *Server) RegisterEventSchema(event string, schema json.RawMessage) (models.EventSchema, error) {
+	registered := models.EventSchema{Event: event, Schema: schema, CreatedAt: s.Now()}
+	if event == "" {
+		return registered, models.ErrInvalidSchema
+	}
+	if err := registered.Compile(); err != nil {
+		return registered, err
+	}
+
+	s.mutex.Lock()
+	s.eventSchemas[event] = &registered
+	s.mutex.Unlock()
+
+	s.logger.Info("Registered the schema of event %s", event)
+	return registered, nil
+}
+
+// RemoveEventSchema stops validating the data of an event's broadcasts
+func (s *Server) RemoveEventSchema(event string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.eventSchemas[event]; !exists {
+		return models.ErrSchemaNotFound
+	}
+	delete(s.eventSchemas, event)
+	s.logger.Info("Removed the schema of event %s", event)
+	return nil
+}
+
+// GetEventSchemas returns the registered event schemas, by event name
+func (s *Server) GetEventSchemas() []models.EventSchema {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	schemas := make([]models.EventSchema, 0, len(s.eventSchemas))
+	for _, schema := range s.eventSchemas {
+		schemas = append(schemas, *schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Event < schemas[j].Event
+	})
+	return schemas
+}
+
+// ValidateEventData checks broadcast data against the schema of its event. Events without a
+// schema are not validated.
+func (s *Server) ValidateEventData(event string, data interface{}) []models.SchemaError {
+	s.mutex.RLock()
+	schema, exists := s.eventSchemas[event]
+	s.mutex.RUnlock()
+
+	if !exists {
+		return nil
+	}
+	return schema.Validate(data)
+}
@@ -2,97 +2,487 @@ package websocket
 
 import (
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 
 	"socket-server/internal/auth"
+	"socket-server/internal/config"
 	"socket-server/internal/models"
 	"socket-server/internal/services"
 	"socket-server/pkg/logger"
 )
 
+// Disconnect reasons reported to Laravel when a client connection ends
+const (
+	DisconnectReasonClientClosed      = "client_closed"
+	DisconnectReasonConnectionLost    = "connection_lost"
+	DisconnectReasonPingFailed        = "ping_failed"
+	DisconnectReasonKicked            = "kicked"
+	DisconnectReasonBanned            = "banned"
+	DisconnectReasonServerDraining    = "server_draining"
+	DisconnectReasonSlowConsumer      = "slow_consumer"
+	DisconnectReasonBandwidthExceeded = models.DisconnectReasonBandwidthExceeded
+	DisconnectReasonDuplicateSession  = "duplicate_session"
+	DisconnectReasonInternalError     = models.DisconnectReasonInternalError
+	DisconnectReasonRateLimited       = models.DisconnectReasonRateLimited
+)
+
+// defaultMaxMessageSize is the largest message accepted from a client when MAX_MESSAGE_BYTES is 0
+const defaultMaxMessageSize = 512 * 1024
+
 // Server manages WebSocket connections and channels
 type Server struct {
-	clients     map[string]*models.Client
-	channels    map[string]*models.Channel
+	clients        map[string]*models.Client
+	channels       map[string]*models.Channel
+	groups         map[string]*models.ChannelGroup
+	dynamicGroups  map[string]bool // groups defined by the dynamic configuration (see dynamic.go)
+	throttles      map[string]*channelThrottle
+	unthrottled    map[string]bool
+	rateLimits     []config.RateLimitRule
+	bandwidthRules []config.RateLimitRule // per-channel bandwidth caps in bytes per second
+	duplicateRules []config.DuplicatePolicyRule
+	bannedIPs      map[string]bool                 // IPs banned by the dynamic configuration
+	bans           map[string]models.Ban           // "type:value" -> ban made through the API (see bans.go)
+	schedules      map[string]*scheduledBroadcast  // ID -> broadcast waiting for its send time (see schedule.go)
+	eventSchemas   map[string]*models.EventSchema  // event -> schema its broadcast data must match (see schemas.go)
+	userGrants     map[string]map[string]time.Time // user ID -> channel -> grant expiry
+	pushDevices    map[string][]models.PushDevice  // user ID -> devices for the push fallback
+	userLastSeen   map[string]time.Time            // user ID -> when a connection of the user last ended
+	fallbackSinks  []services.FallbackSink         // critical broadcast delivery (see critical.go)
+	broadcaster    BroadcastExecutor               // runs scheduled broadcasts, nil until set
+	startedAt      time.Time
+
+	// Session resumption (see resume.go); resumeSigner is nil when disabled
+	resumeSigner      *auth.ResumeSigner
+	resumeNonces      map[string]string            // live client ID -> nonce of its latest resume token
+	resumableSessions map[string]*resumableSession // disconnected client ID -> session
+	usedResumeNonces  map[string]time.Time         // consumed nonce -> when it can be forgotten
+
+	// Leaves held back for the presence grace period (see presence.go), guarded by mutex
+	pendingLeaves map[string]*pendingLeave
+
+	draining    atomic.Bool
+	drainOnce   sync.Once
+	drained     chan struct{}
 	upgrader    websocket.Upgrader
+	config      *config.Config
 	authService *auth.Service
 	laravelSvc  *services.LaravelService
+	geoIP       *services.GeoIPService
+	bridge      *services.BridgeService
+	cluster     *services.ClusterService
+	push        *services.PushService
+	analytics   *services.AnalyticsStore
 	logger      *logger.Logger
 	mutex       sync.RWMutex
+
+	// Load metrics (see metrics.go); retiredMessagesSent is guarded by mutex
+	messagesReceived    atomic.Uint64
+	retiredMessagesSent uint64
+	rates               messageRates
+	ratesMutex          sync.RWMutex
+
+	// Guards throttles, unthrottled and rateLimits (see throttle.go), so broadcasts don't wait on mutex
+	throttleMutex sync.RWMutex
+
+	// Settings of the channels named by the dynamic configuration (see dynamic.go), guarded by mutex
+	channelSettings map[string]models.ChannelSettings
+
+	// Server-wide cap on concurrent fan-outs (see limiter.go)
+	broadcastLimiter *broadcastLimiter
+
+	// Expiry sweep totals (see expiry.go)
+	expiredEntries atomic.Uint64
+	reclaimedBytes atomic.Uint64
+
+	// Recovered panic totals (see recovery.go)
+	connectionPanics atomic.Uint64
+	httpPanics       atomic.Uint64
+
+	// Frame tracing for debugging (see wiretrace.go), nil when disabled
+	wireTracer *wireTracer
+
+	// Rate limit totals (see ratelimit.go)
+	messagesRateLimited atomic.Uint64
+	httpRateLimited     atomic.Uint64
+
+	// Channel broadcasts delivered recently, to drop copies relayed again (see dedup.go)
+	recentBroadcasts    *models.RecentIDs
+	duplicateBroadcasts atomic.Uint64
+
+	// Rebalancing hints (see rebalance.go); peers is guarded by mutex
+	peers    map[string]models.NodeStatus // node ID -> last status the node advertised
+	pressure atomic.Bool
+
+	// Queues of each client's actions and Laravel dispatches (see clientqueue.go)
+	clientQueues map[string]*clientQueue
+	queuesMutex  sync.RWMutex
+
+	// Channel pattern subscriptions (see patterns.go)
+	patterns *patternSubscriptions
+
+	// Binary blob relay (see blob.go); blobUploads is guarded by mutex
+	blobRules   []config.SizeRule
+	blobUploads map[string]*blobUpload // client ID -> blob it is sending
+
+	// Epoll watching of connections (see netpoll.go); nil unless NETPOLL_ENABLED
+	netpoll *netpoller
+
+	// Uploads to storage (see uploads.go); pendingUploads is guarded by mutex
+	uploadStorage  *services.UploadStorage
+	pendingUploads map[string]*pendingUpload // upload ID -> upload
+
+	// Connection limits (see limits.go); connectionSlots and connectionsByIP are guarded by mutex
+	connectionSlots             int
+	connectionsByIP             map[string]int // IP -> connections holding a slot
+	connectionLimitRejections   atomic.Uint64
+	ipConnectionLimitRejections atomic.Uint64
+	channelLimitRejections      atomic.Uint64
+
+	// Client messages refused for naming a reserved event (see admitEventName)
+	reservedEventRejections atomic.Uint64
+
+	// Moderation actions per channel (see moderation.go); nil when MODERATION_LOG_SIZE is 0
+	moderationLog *models.ModerationLog
+
+	// Warm standby (see standby.go), guarded by mutex; standby is nil unless the node is an
+	// unpromoted standby, promoted is the standby it was promoted from
+	standby      *standbySync
+	promoted     *standbySync
+	servingSince time.Time // when a promoted standby started serving clients
+
+	// Clock and ID generator of timestamps, expiries and IDs; the system clock and
+	// models.NewID unless set otherwise
+	models.Timekeeper
 }
 
 // New creates a new WebSocket server
-func New(authService *auth.Service, laravelSvc *services.LaravelService, logger *logger.Logger) *Server {
-	return &Server{
-		clients:     make(map[string]*models.Client),
-		channels:    make(map[string]*models.Channel),
-		authService: authService,
-		laravelSvc:  laravelSvc,
-		logger:      logger,
+func New(cfg *config.Config, authService *auth.Service, laravelSvc *services.LaravelService, logger *logger.Logger) *Server {
+	// Rules are checked by cfg.Validate at startup
+	rateLimits, _ := config.ParseRateLimitRules(cfg.ChannelRateLimits)
+	bandwidthRules, _ := config.ParseRateLimitRules(cfg.ChannelBandwidthLimits)
+	duplicateRules, _ := config.ParseDuplicatePolicyRules(cfg.ChannelDuplicatePolicies)
+	blobRules, _ := config.ParseSizeRules(cfg.ChannelBlobLimits)
+
+	var resumeSigner *auth.ResumeSigner
+	if len(cfg.ResumeSecrets) > 0 {
+		resumeSigner = auth.NewResumeSigner(cfg.ResumeSecrets)
+	}
+
+	s := &Server{
+		clients:        make(map[string]*models.Client),
+		channels:       make(map[string]*models.Channel),
+		groups:         make(map[string]*models.ChannelGroup),
+		dynamicGroups:  make(map[string]bool),
+		throttles:      make(map[string]*channelThrottle),
+		unthrottled:    make(map[string]bool),
+		rateLimits:     rateLimits,
+		bandwidthRules: bandwidthRules,
+		duplicateRules: duplicateRules,
+		bannedIPs:      make(map[string]bool),
+		bans:           make(map[string]models.Ban),
+		schedules:      make(map[string]*scheduledBroadcast),
+		eventSchemas:   make(map[string]*models.EventSchema),
+		userGrants:     make(map[string]map[string]time.Time),
+		pushDevices:    make(map[string][]models.PushDevice),
+		userLastSeen:   make(map[string]time.Time),
+		startedAt:      time.Now(),
+		drained:        make(chan struct{}),
+		config:         cfg,
+		authService:    authService,
+		laravelSvc:     laravelSvc,
+		logger:         logger,
+
+		broadcastLimiter: newBroadcastLimiter(cfg.BroadcastConcurrency, cfg.BroadcastQueueSize),
+		recentBroadcasts: models.NewRecentIDs(dedupWindow, dedupLimit),
+
+		resumeSigner:      resumeSigner,
+		resumeNonces:      make(map[string]string),
+		resumableSessions: make(map[string]*resumableSession),
+		usedResumeNonces:  make(map[string]time.Time),
+		pendingLeaves:     make(map[string]*pendingLeave),
+		peers:             make(map[string]models.NodeStatus),
+		patterns:          newPatternSubscriptions(),
+		blobRules:         blobRules,
+		blobUploads:       make(map[string]*blobUpload),
+		pendingUploads:    make(map[string]*pendingUpload),
+		clientQueues:      make(map[string]*clientQueue),
+		connectionsByIP:   make(map[string]int),
+		moderationLog:     models.NewModerationLog(cfg.ModerationLogSize),
+
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for now
-			},
 			ReadBufferSize:    4096, // Increased from 1024
 			WriteBufferSize:   4096, // Increased from 1024
 			EnableCompression: true, // Enable compression for better performance
 		},
 	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+	return s
 }
 
 // HandleConnection handles a new WebSocket connection
 func (s *Server) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	// A token in the upgrade request authenticates the connection before its first message; an
+	// invalid one refuses the upgrade
+	token := handshakeToken(r)
+	observer := token != "" && s.isAPIToken(token)
+	var claims jwt.MapClaims
+	if token != "" && !observer {
+		var err error
+		if claims, err = s.authService.ValidateToken(token); err != nil {
+			s.logger.Warn("Rejected connection from %s: invalid handshake token: %v", r.RemoteAddr, err)
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	client := s.upgradeClient(w, r)
+	if client == nil {
+		return
+	}
+	s.registerClient(client)
+
+	// Send welcome message, including the server clock so clients can estimate their skew
+	client.SendMessage(s.welcomeMessage(client))
+
+	if observer {
+		s.queueAction(client, func() { s.signInObserver(client) })
+	} else if claims != nil {
+		deviceID := r.URL.Query().Get("device_id")
+		s.queueAction(client, func() {
+			s.completeAuthentication(client, token, claims, map[string]interface{}{"device_id": deviceID})
+		})
+	}
+
+	// Netpoll connections are read and pinged when due, without goroutines of their own
+	if s.watchNetpoll(client) {
+		return
+	}
+
+	// Handle client messages and ping in separate goroutines
+	done := make(chan bool, 2)
+	go s.handleClientMessages(client, done)
+	go s.handleClientPing(client, done)
+
+	// Wait for either handler to finish
+	<-done
+
+	// Handle client disconnection - this happens after goroutines finish
+	s.disconnectClient(client)
+}
+
+// handshakeToken returns the JWT of an upgrade request, from the token query parameter (browsers
+// can't set headers on WebSocket requests) or a bearer Authorization header
+func handshakeToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// upgradeClient admits a connection request and upgrades it to a WebSocket, returning the
+// configured client, or nil when the request was refused
+func (s *Server) upgradeClient(w http.ResponseWriter, r *http.Request) *models.Client {
+	if s.isBannedIP(r.RemoteAddr) {
+		s.logger.Warn("Rejected connection from banned address %s", r.RemoteAddr)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil
+	}
+	geo := s.geoIP.Lookup(remoteIP(r.RemoteAddr))
+	if !s.isCountryAllowed(geo) {
+		s.logger.Warn("Rejected connection from %s: country not allowed", r.RemoteAddr)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil
+	}
+	if s.IsDraining() {
+		http.Error(w, "Server is draining", http.StatusServiceUnavailable)
+		return nil
+	}
+	if s.IsStandby() {
+		http.Error(w, "Server is a standby", http.StatusServiceUnavailable)
+		return nil
+	}
+	if !s.reserveConnection(w, r) {
+		return nil
+	}
+
+	if s.netpoll != nil {
+		w = netpollResponseWriter{w}
+	}
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		s.releaseConnection(r.RemoteAddr)
 		s.logger.Error("WebSocket upgrade error: %v", err)
-		return
+		return nil
 	}
 
-	// Set connection timeouts and limits
-	conn.SetReadLimit(512 * 1024) // 512KB max message size
-	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	conn.SetPongHandler(func(string) error {
-		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	client := models.NewClientWithTimekeeper(s.NewID(), conn, &s.Timekeeper)
+	client.ConnectedAt = s.Now()
+	client.LastSeen = client.ConnectedAt
+	client.SetPingPolicy(s.pingPolicy())
+
+	// Set connection timeouts and limits; the read timeout follows the adaptive ping interval
+	conn.SetReadLimit(int64(s.maxMessageSize()))
+	conn.SetReadDeadline(time.Now().Add(client.ReadTimeout()))
+	conn.SetPongHandler(func(appData string) error {
+		if s.wireTracer != nil {
+			s.wireTracer.tap(client, models.WireInbound, websocket.PongMessage, []byte(appData))
+		}
+		client.RecordPong()
+		conn.SetReadDeadline(time.Now().Add(client.ReadTimeout()))
 		return nil
 	})
 
-	client := models.NewClient(uuid.New().String(), conn)
 	client.RemoteAddr = r.RemoteAddr
 	client.UserAgent = r.UserAgent()
+	client.Device = models.ParseUserAgent(client.UserAgent)
+	client.Geo = geo
+	client.SetCompressionThreshold(s.config.CompressionMinSize)
+	client.SetChunkSize(s.config.ChunkSize)
+	client.SetChunkedMessageLimit(s.config.ChunkedMessageMaxSize)
+	client.SetLocale(s.config.DefaultLocale)
+	client.SetBandwidthLimit(s.config.ClientBandwidthLimit, s.config.BandwidthCapAction == "disconnect")
+	client.SetMessageRateLimit(float64(s.config.ClientMessageRate), s.config.ClientMessageBurst)
+	client.SetWriteTimeout(s.config.WriteTimeout)
+	client.SetPanicHandler(s.handleClientPanic)
+	if s.wireTracer != nil {
+		client.SetWireTap(s.wireTracer.tap)
+	}
+	return client
+}
+
+// registerClient starts the client's writer and adds it to the server's clients
+func (s *Server) registerClient(client *models.Client) {
+	client.StartWriter(s.config.SendQueueSize)
 
 	s.mutex.Lock()
 	s.clients[client.ID] = client
+	connections := len(s.clients)
 	s.mutex.Unlock()
+	s.analytics.RecordConnections(connections)
+	s.checkPressure(connections)
 
 	s.logger.ClientConnected(client.ID, client.RemoteAddr, client.UserAgent)
 
-	// Send welcome message
-	welcome := models.Message{
-		ID:        uuid.New().String(),
+	// The client is greeted without waiting for Laravel
+	s.openClientQueue(client)
+	if s.config.DispatchConnectionEvents {
+		s.queueDispatch(client, func() {
+			if err := s.laravelSvc.DispatchConnection(client); err != nil {
+				s.logger.Error("Failed to dispatch client_connected to Laravel: %v", err)
+			}
+		})
+	}
+}
+
+// welcomeMessage is the connected message greeting a new client. While the node is under
+// pressure, it suggests less loaded nodes the client can reconnect to.
+func (s *Server) welcomeMessage(client *models.Client) models.Message {
+	now := s.Now()
+	data := map[string]interface{}{
+		"client_id":       client.ID,
+		"node_id":         s.config.NodeID,
+		"server_time":     now.UnixMilli(),
+		"reserved_events": models.ReservedEventsInfo(),
+	}
+	if s.pressure.Load() {
+		if endpoints := s.SuggestedEndpoints(); len(endpoints) > 0 {
+			data["suggested_endpoints"] = endpoints
+		}
+	}
+	return models.Message{
+		ID:        s.NewID(),
 		Event:     "connected",
-		Data:      map[string]string{"client_id": client.ID},
-		Timestamp: time.Now(),
+		Priority:  models.PriorityHigh,
+		Data:      data,
+		Timestamp: now,
 	}
-	client.SendMessage(welcome)
+}
 
-	// Start ping ticker for connection health
-	pingTicker := time.NewTicker(30 * time.Second)
-	defer pingTicker.Stop()
+// pingPolicy returns the configured bounds of the adaptive ping interval and read timeouts
+func (s *Server) pingPolicy() models.PingPolicy {
+	policy := models.DefaultPingPolicy
+	if s.config.PingInterval > 0 {
+		policy.Interval = s.config.PingInterval
+		policy.MinInterval = s.config.PingMinInterval
+		policy.MaxInterval = s.config.PingMaxInterval
+	}
+	policy.PongTimeout = s.config.PongTimeout
+	policy.ReadTimeout = s.config.ReadTimeout
+	return policy
+}
 
-	// Handle client messages and ping in separate goroutines
-	done := make(chan bool, 2)
-	go s.handleClientMessages(client, done)
-	go s.handleClientPing(client, pingTicker, done)
+// maxMessageSize returns the largest message accepted from a client in bytes
+func (s *Server) maxMessageSize() int {
+	if s.config.MaxMessageSize > 0 {
+		return s.config.MaxMessageSize
+	}
+	return defaultMaxMessageSize
+}
 
-	// Wait for either handler to finish
-	<-done
+// HeartbeatSettings returns the effective keepalive timings and message size limit of new
+// connections
+func (s *Server) HeartbeatSettings() map[string]interface{} {
+	policy := s.pingPolicy()
+	pongTimeout := policy.PongTimeout
+	if pongTimeout <= 0 {
+		pongTimeout = models.DefaultPongTimeout
+	}
+	writeTimeout := s.config.WriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = models.DefaultWriteTimeout
+	}
+	return map[string]interface{}{
+		"ping_interval_seconds":     policy.Interval.Seconds(),
+		"ping_min_interval_seconds": policy.MinInterval.Seconds(),
+		"ping_max_interval_seconds": policy.MaxInterval.Seconds(),
+		"pong_timeout_seconds":      pongTimeout.Seconds(),
+		"read_timeout_seconds":      policy.InitialReadTimeout().Seconds(),
+		"write_timeout_ms":          writeTimeout.Milliseconds(),
+		"max_message_bytes":         s.maxMessageSize(),
+	}
+}
 
-	// Handle client disconnection - this happens after goroutines finish
-	s.disconnectClient(client)
+// NodeID returns the identifier of this server instance
+func (s *Server) NodeID() string {
+	return s.config.NodeID
+}
+
+// PodInfo returns the Kubernetes pod identity of this node, empty outside Kubernetes
+func (s *Server) PodInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"name":      s.config.PodName,
+		"namespace": s.config.PodNamespace,
+		"labels":    s.config.PodLabels,
+	}
+}
+
+// GetUserClients returns the connections of a specific user on this node. With user channels
+// enabled they are the members of the user's personal channel, so no scan of every client is
+// needed.
+func (s *Server) GetUserClients(userID string) []*models.Client {
+	if s.config.UserChannels {
+		return s.userChannelClients(userID)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	clients := make([]*models.Client, 0)
+	for _, client := range s.clients {
+		if client.UserID == userID {
+			clients = append(clients, client)
+		}
+	}
+	return clients
 }
 
 // GetClients returns all connected clients
@@ -146,299 +536,254 @@ func (s *Server) KickClient(clientID string) error {
 
 	// Send kick message
 	kickMessage := models.Message{
-		ID:        uuid.New().String(),
+		ID:        s.NewID(),
 		Event:     "kicked",
+		Priority:  models.PriorityHigh,
 		Data:      map[string]string{"reason": "Kicked by admin"},
-		Timestamp: time.Now(),
+		Timestamp: s.Now(),
 	}
 	client.SendMessage(kickMessage)
+	client.SetDisconnectReason(DisconnectReasonKicked)
+	s.recordRemoval(client, moderationRuleKicked, "Kicked by admin")
 
-	// Close connection
-	client.Close()
+	// Close connection once the kick notice has been written
+	client.CloseAfterFlush()
 
 	return nil
 }
 
-// BroadcastToChannel sends a message to all clients in a channel
-func (s *Server) BroadcastToChannel(channelName string, message models.Message) {
+// BroadcastToChannel sends a message to all clients in a channel.
+// Broadcasts to the same channel are serialized and each client's messages are written in queue
+// order, so every subscriber receives channel messages in publish order. Channels with a rate
+// limit are throttled, coalescing bursts of the same event into the latest message. A message
+// already delivered on the channel, relayed again by the cluster or a bridge, is dropped.
+// It reports how many local subscribers the message was queued for.
+func (s *Server) BroadcastToChannel(channelName string, message models.Message) models.ChannelDelivery {
+	if message.Origin == "" {
+		message.Origin = s.NodeID()
+	}
+	if !s.firstDelivery(channelName, message) {
+		return models.ChannelDelivery{Channel: channelName, Dropped: "duplicate"}
+	}
+	s.forwardToBridge(channelName, message)
+	s.relayToCluster(channelName, message)
+	if throttle := s.getThrottle(channelName); throttle != nil {
+		throttle.submit(message)
+		return models.ChannelDelivery{Channel: channelName, Throttled: true}
+	}
+	return s.publishToChannel(channelName, message)
+}
+
+// publishToChannel fans a message out to the current subscribers of a channel by queueing it
+// for each of them. Nothing waits on the network: a client whose queue is full misses the
+// message, and is disconnected if it keeps overflowing (see handleSendFailure).
+func (s *Server) publishToChannel(channelName string, message models.Message) models.ChannelDelivery {
 	start := time.Now()
-	s.logger.Info("📺 BroadcastToChannel started for channel: %s", channelName)
+	delivery := models.ChannelDelivery{Channel: channelName}
 
-	lookupStart := time.Now()
 	channel, exists := s.GetChannel(channelName)
 	if !exists {
-		s.logger.Warn("Channel %s not found for broadcast", channelName)
-		return
+		if len(s.patterns.Match(channelName)) == 0 {
+			s.logger.Warn("Channel %s not found for broadcast", channelName)
+			delivery.Dropped = "no_channel"
+			return delivery
+		}
+		// Pattern subscribers listen to channels nobody joined yet. The message reaches them
+		// through a channel that isn't added to the server, so broadcasting to any name doesn't
+		// leave channels behind; with no channel there is no sequence or history either.
+		s.mutex.RLock()
+		channel = s.newChannel(channelName, false)
+		s.mutex.RUnlock()
 	}
-	lookupTime := time.Since(lookupStart)
-	s.logger.Info("⏱️ Channel lookup took: %v", lookupTime)
-
-	clientsStart := time.Now()
-	clients := channel.GetClients()
-	clientsTime := time.Since(clientsStart)
-	s.logger.Info("⏱️ Getting clients took: %v", clientsTime)
-
-	sendStart := time.Now()
+	lookupTime := time.Since(start)
 
-	// Use goroutines for non-blocking sends with timeout
-	type clientResult struct {
-		clientID string
-		err      error
-		duration time.Duration
-	}
+	successCount := 0
+	clientCount := 0
+	var clientsTime, sendTime time.Duration
+
+	channel.WithPublishLock(func() {
+		clientsStart := time.Now()
+		clients := channel.GetClients()
+		subscribers := s.patternSubscribers(channel, clients)
+		removeUnsubscribed(clients, channelName, message.Event)
+		clientCount = len(clients) + len(subscribers)
+		clientsTime = time.Since(clientsStart)
+
+		if !channel.AllowBandwidth(estimateFanoutBytes(message, clientCount)) {
+			s.logger.Warn("Channel %s is over its bandwidth cap, dropping message %s", channelName, message.ID)
+			delivery.Dropped = "bandwidth_limit"
+			return
+		}
 
-	results := make(chan clientResult, len(clients))
+		// Every channel message carries a monotonic sequence; wall-clock timestamps can't be
+		// trusted for ordering once client and server clocks disagree
+		if exists {
+			message.Sequence = channel.NextSequence()
+		}
+		if exists && message.Blob == nil {
+			// Blobs aren't retained: history would replay their envelopes without the bytes
+			channel.AddToHistory(message)
+		}
+		s.analytics.RecordMessage(channelName, message.UserID)
 
-	// Send to all clients concurrently
-	for _, client := range clients {
-		go func(c *models.Client) {
-			clientStart := time.Now()
-			err := c.SendMessage(message)
-			results <- clientResult{
-				clientID: c.ID,
-				err:      err,
-				duration: time.Since(clientStart),
-			}
-		}(client)
-	}
+		sendStart := time.Now()
 
-	// Collect results with timeout
-	successCount := 0
-	timeout := time.After(1 * time.Second) // Max 1 second for all sends in local env
-
-collectLoop:
-	for i := 0; i < len(clients); i++ {
-		select {
-		case result := <-results:
-			if result.err != nil {
-				s.logger.Error("Failed to send message to client %s: %v", result.clientID, result.err)
+		// Enqueue to every subscriber while holding the publish lock; the per-client writer
+		// goroutines perform the actual network writes
+		for _, client := range clients {
+			if err := client.SendMessage(message); err != nil {
+				s.logger.Error("Failed to send message to client %s: %v", client.ID, err)
+				s.handleSendFailure(client, err)
 			} else {
 				successCount++
 			}
-			if result.duration > 10*time.Millisecond {
-				s.logger.Warn("⚠️ Slow client send to %s took: %v", result.clientID, result.duration)
-			}
-		case <-timeout:
-			s.logger.Warn("⏰ Broadcast timeout - %d/%d clients completed", i, len(clients))
-			break collectLoop
 		}
-	}
-
-	sendTime := time.Since(sendStart)
-	s.logger.Info("⏱️ Concurrent sending to %d clients took: %v (success: %d)", len(clients), sendTime, successCount)
-
-	// After collecting results, remove clients that consistently failed
-	go func() {
-		for i := 0; i < len(clients); i++ {
-			select {
-			case result := <-results:
-				// If a client took too long, it's likely dead - remove it
-				if result.duration > 500*time.Millisecond && result.err != nil {
-					s.logger.Info("🗑️ Removing slow/dead client: %s (took %v)", result.clientID, result.duration)
-					s.mutex.Lock()
-					delete(s.clients, result.clientID)
-					s.mutex.Unlock()
-				}
-			case <-time.After(100 * time.Millisecond):
-				// Any remaining clients are likely dead
-				return
+		for _, client := range subscribers {
+			if err := client.SendMessage(message); err != nil {
+				s.logger.Error("Failed to send message to client %s: %v", client.ID, err)
+				s.handleSendFailure(client, err)
+			} else {
+				successCount++
 			}
 		}
-	}()
 
-	totalTime := time.Since(start)
-	s.logger.Info("🏁 BroadcastToChannel total time: %v", totalTime)
-	s.logger.Info("Broadcasted message to %d clients in channel %s", len(clients), channelName)
+		sendTime = time.Since(sendStart)
+	})
+
+	s.logger.With(logger.Fields{
+		"channel":    channelName,
+		"message_id": message.ID,
+		"clients":    clientCount,
+		"delivered":  successCount,
+		"lookup_ms":  logger.Millis(lookupTime),
+		"clients_ms": logger.Millis(clientsTime),
+		"queue_ms":   logger.Millis(sendTime),
+		"total_ms":   logger.Millis(time.Since(start)),
+	}).Debug("Channel broadcast timing")
+	s.logger.Info("Broadcasted message to %d/%d clients in channel %s", successCount, clientCount, channelName)
+
+	delivery.Recipients, delivery.Delivered = clientCount, successCount
+	return delivery
+}
+
+// BroadcastToAll sends a message to all connected clients and returns how many it was queued for
+func (s *Server) BroadcastToAll(message models.Message) int {
+	return s.broadcastToClients("global", message, func(*models.Client) bool { return true })
 }
 
-// BroadcastToAll sends a message to all connected clients
-func (s *Server) BroadcastToAll(message models.Message) {
+// BroadcastToAuthenticated sends a message to all authenticated clients and returns how many it
+// was queued for
+func (s *Server) BroadcastToAuthenticated(message models.Message) int {
+	return s.broadcastToClients("authenticated", message, func(client *models.Client) bool {
+		return client.UserID != ""
+	})
+}
+
+// broadcastToClients queues a message for every connected client matching a filter. Queueing
+// never blocks, so every client is reached however many there are; those whose queue is full
+// miss the message and are disconnected if they keep overflowing (see handleSendFailure).
+func (s *Server) broadcastToClients(scope string, message models.Message, matches func(*models.Client) bool) int {
 	start := time.Now()
-	s.logger.Info("🌍 BroadcastToAll started")
 
-	lockStart := time.Now()
 	s.mutex.RLock()
 	clients := make([]*models.Client, 0, len(s.clients))
 	for _, client := range s.clients {
-		clients = append(clients, client)
+		if matches(client) {
+			clients = append(clients, client)
+		}
 	}
 	s.mutex.RUnlock()
-	lockTime := time.Since(lockStart)
-	s.logger.Info("⏱️ Client collection took: %v", lockTime)
+	lockTime := time.Since(start)
 
 	sendStart := time.Now()
-
-	// Use goroutines for non-blocking sends with timeout
-	type clientResult struct {
-		clientID string
-		err      error
-		duration time.Duration
+	successCount := 0
+	for _, client := range clients {
+		if err := client.SendMessage(message); err != nil {
+			s.logger.Error("Failed to send message to client %s: %v", client.ID, err)
+			s.handleSendFailure(client, err)
+		} else {
+			successCount++
+		}
 	}
 
-	results := make(chan clientResult, len(clients))
+	s.logger.With(logger.Fields{
+		"broadcast":  scope,
+		"message_id": message.ID,
+		"clients":    len(clients),
+		"delivered":  successCount,
+		"collect_ms": logger.Millis(lockTime),
+		"send_ms":    logger.Millis(time.Since(sendStart)),
+		"total_ms":   logger.Millis(time.Since(start)),
+	}).Debug("Broadcast timing")
+	s.logger.Info("Broadcasted message to %d/%d clients (%s)", successCount, len(clients), scope)
+	return successCount
+}
 
-	// Send to all clients concurrently
-	for _, client := range clients {
-		s.logger.Info("🎇 Starting goroutine for client %s", client.ID)
-		go func(c *models.Client, s *Server) {
-			s.logger.Info("🏤 Sending message to client %s", c.ID)
-			clientStart := time.Now()
-			err := c.SendMessage(message)
-			results <- clientResult{
-				clientID: c.ID,
-				err:      err,
-				duration: time.Since(clientStart),
-			}
-			s.logger.Info("💌 Sent message to client %s", c.ID)
-		}(client, s)
-		s.logger.Info("🎆 Ended goroutine for client %s", client.ID)
+// handleSendFailure disconnects a client whose send queue has stayed full across several
+// consecutive messages. A single full queue (e.g. a burst) only drops that message, and with
+// the "drop" slow client policy slow clients are never disconnected.
+func (s *Server) handleSendFailure(client *models.Client, err error) {
+	if err != models.ErrSendQueueFull || !client.IsSlow() || s.config.SlowClientPolicy == "drop" {
+		return
 	}
 
-	// Collect results with timeout
+	stats := client.Stats()
+	s.logger.Warn("Disconnecting slow client %s: %d messages dropped, queue high-water %d",
+		client.ID, stats.MessagesDropped, stats.QueueHighWater)
+
+	client.SetDisconnectReason(DisconnectReasonSlowConsumer)
+	client.Close()
+}
+
+// BroadcastToUser sends a message to all connections of a specific user
+func (s *Server) BroadcastToUser(userID string, message models.Message) int {
 	successCount := 0
-	timeout := time.After(1 * time.Second) // Max 1 second for all sends in local env
-
-collectLoop:
-	for i := 0; i < len(clients); i++ {
-		select {
-		case result := <-results:
-			if result.err != nil {
-				s.logger.Error("Failed to send message to client %s: %v", result.clientID, result.err)
-			} else {
-				successCount++
-			}
-			if result.duration > 10*time.Millisecond {
-				s.logger.Warn("⚠️ Slow global client send to %s took: %v", result.clientID, result.duration)
-			}
-		case <-timeout:
-			s.logger.Warn("⏰ Global broadcast timeout - %d/%d clients completed", i, len(clients))
-			break collectLoop
+	for _, client := range s.GetUserClients(userID) {
+		if err := client.SendMessage(message); err != nil {
+			s.logger.Error("Failed to send message to user %s client %s: %v", userID, client.ID, err)
+			s.handleSendFailure(client, err)
+		} else {
+			successCount++
 		}
 	}
 
-	sendTime := time.Since(sendStart)
-	s.logger.Info("⏱️ Concurrent global sending to %d clients took: %v (success: %d)", len(clients), sendTime, successCount)
-
-	// After collecting results, remove clients that consistently failed
-	go func() {
-		for i := 0; i < len(clients); i++ {
-			select {
-			case result := <-results:
-				// If a client took too long, it's likely dead - remove it
-				if result.duration > 500*time.Millisecond && result.err != nil {
-					s.logger.Info("🗑️ Removing slow/dead client: %s (took %v)", result.clientID, result.duration)
-					s.mutex.Lock()
-					delete(s.clients, result.clientID)
-					s.mutex.Unlock()
-				}
-			case <-time.After(100 * time.Millisecond):
-				// Any remaining clients are likely dead
-				return
-			}
-		}
-	}()
-
-	totalTime := time.Since(start)
-	s.logger.Info("🏁 BroadcastToAll total time: %v", totalTime)
-	s.logger.Info("Broadcasted message to %d/%d clients globally", successCount, len(clients))
+	s.logger.Info("Broadcasted message to %d connections of user %s", successCount, userID)
+	return successCount
 }
 
-// BroadcastToAuthenticated sends a message to all authenticated clients
-func (s *Server) BroadcastToAuthenticated(message models.Message) {
-	start := time.Now()
-	s.logger.Info("🔐 BroadcastToAuthenticated started")
-
-	lockStart := time.Now()
+// BroadcastToPlatform sends a message to all clients on a given device type and/or operating
+// system (e.g. only mobile clients, or only iOS clients), returning the number of recipients
+func (s *Server) BroadcastToPlatform(deviceType, os string, message models.Message) int {
 	s.mutex.RLock()
 	clients := make([]*models.Client, 0)
 	for _, client := range s.clients {
-		if client.UserID != "" {
+		if client.Device.MatchesPlatform(deviceType, os) {
 			clients = append(clients, client)
 		}
 	}
 	s.mutex.RUnlock()
-	lockTime := time.Since(lockStart)
-	s.logger.Info("⏱️ Authenticated client collection took: %v", lockTime)
-
-	sendStart := time.Now()
-
-	// Use goroutines for non-blocking sends with timeout
-	type clientResult struct {
-		clientID string
-		err      error
-		duration time.Duration
-	}
-
-	results := make(chan clientResult, len(clients))
 
-	// Send to all clients concurrently
-	for _, client := range clients {
-		go func(c *models.Client) {
-			clientStart := time.Now()
-			err := c.SendMessage(message)
-			results <- clientResult{
-				clientID: c.ID,
-				err:      err,
-				duration: time.Since(clientStart),
-			}
-		}(client)
-	}
-
-	// Collect results with timeout
 	successCount := 0
-	timeout := time.After(1 * time.Second) // Max 1 second for all sends in local env
-
-collectLoop:
-	for i := 0; i < len(clients); i++ {
-		select {
-		case result := <-results:
-			if result.err != nil {
-				s.logger.Error("Failed to send message to authenticated client %s: %v", result.clientID, result.err)
-			} else {
-				successCount++
-			}
-			if result.duration > 10*time.Millisecond {
-				s.logger.Warn("⚠️ Slow authenticated client send to %s took: %v", result.clientID, result.duration)
-			}
-		case <-timeout:
-			s.logger.Warn("⏰ Authenticated broadcast timeout - %d/%d clients completed", i, len(clients))
-			break collectLoop
+	for _, client := range clients {
+		if err := client.SendMessage(message); err != nil {
+			s.logger.Error("Failed to send message to client %s: %v", client.ID, err)
+			s.handleSendFailure(client, err)
+		} else {
+			successCount++
 		}
 	}
 
-	sendTime := time.Since(sendStart)
-	s.logger.Info("⏱️ Concurrent authenticated sending to %d clients took: %v (success: %d)", len(clients), sendTime, successCount)
-
-	// After collecting results, remove clients that consistently failed
-	go func() {
-		for i := 0; i < len(clients); i++ {
-			select {
-			case result := <-results:
-				// If a client took too long, it's likely dead - remove it
-				if result.duration > 500*time.Millisecond && result.err != nil {
-					s.logger.Info("🗑️ Removing slow/dead client: %s (took %v)", result.clientID, result.duration)
-					s.mutex.Lock()
-					delete(s.clients, result.clientID)
-					s.mutex.Unlock()
-				}
-			case <-time.After(100 * time.Millisecond):
-				// Any remaining clients are likely dead
-				return
-			}
-		}
-	}()
-
-	totalTime := time.Since(start)
-	s.logger.Info("🏁 BroadcastToAuthenticated total time: %v", totalTime)
-	s.logger.Info("Broadcasted message to %d authenticated clients", successCount)
+	s.logger.Info("Broadcasted message to %d clients on platform (device: %q, os: %q)", successCount, deviceType, os)
+	return successCount
 }
 
-// BroadcastToUser sends a message to all connections of a specific user
-func (s *Server) BroadcastToUser(userID string, message models.Message) {
+// BroadcastToGeo sends a message to all clients located in one of the given countries and,
+// optionally, a region. Clients whose location is unknown are skipped.
+func (s *Server) BroadcastToGeo(countries []string, region string, message models.Message) int {
 	s.mutex.RLock()
 	clients := make([]*models.Client, 0)
 	for _, client := range s.clients {
-		if client.UserID == userID {
+		if client.Geo.MatchesLocation(countries, region) {
 			clients = append(clients, client)
 		}
 	}
@@ -447,17 +792,20 @@ func (s *Server) BroadcastToUser(userID string, message models.Message) {
 	successCount := 0
 	for _, client := range clients {
 		if err := client.SendMessage(message); err != nil {
-			s.logger.Error("Failed to send message to user %s client %s: %v", userID, client.ID, err)
+			s.logger.Error("Failed to send message to client %s: %v", client.ID, err)
+			s.handleSendFailure(client, err)
 		} else {
 			successCount++
 		}
 	}
 
-	s.logger.Info("Broadcasted message to %d connections of user %s", successCount, userID)
+	s.logger.Info("Broadcasted message to %d clients in %v (region: %q)", successCount, countries, region)
+	return successCount
 }
 
 // BroadcastToUsersExcept sends a message to all authenticated clients except the specified user
-func (s *Server) BroadcastToUsersExcept(excludeUserID string, message models.Message) {
+// and returns how many it was queued for
+func (s *Server) BroadcastToUsersExcept(excludeUserID string, message models.Message) int {
 	s.mutex.RLock()
 	clients := make([]*models.Client, 0)
 	for _, client := range s.clients {
@@ -471,12 +819,14 @@ func (s *Server) BroadcastToUsersExcept(excludeUserID string, message models.Mes
 	for _, client := range clients {
 		if err := client.SendMessage(message); err != nil {
 			s.logger.Error("Failed to send message to client %s: %v", client.ID, err)
+			s.handleSendFailure(client, err)
 		} else {
 			successCount++
 		}
 	}
 
 	s.logger.Info("Broadcasted message to %d authenticated clients (excluding user %s)", successCount, excludeUserID)
+	return successCount
 }
 
 // BroadcastToClient sends a message to a specific client connection
@@ -491,6 +841,7 @@ func (s *Server) BroadcastToClient(clientID string, message models.Message) erro
 
 	if err := client.SendMessage(message); err != nil {
 		s.logger.Error("Failed to send message to client %s: %v", clientID, err)
+		s.handleSendFailure(client, err)
 		return err
 	}
 
@@ -0,0 +1,262 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"socket-server/internal/auth"
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+	"socket-server/pkg/logger"
+)
+
+// newTestServer creates a server with the given configuration and no Laravel command, for
+// tests that don't dispatch to Laravel
+func newTestServer(t *testing.T, cfg *config.Config) *Server {
+	t.Helper()
+	if cfg.JWTSecret == "" {
+		cfg.JWTSecret = "secret"
+	}
+
+	// Disconnections can still be writing payload files when the test ends, so the directory
+	// is removed without failing the test
+	payloadDir, err := os.MkdirTemp("", "socket-server-test")
+	if err != nil {
+		t.Fatalf("Failed to create the payload directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(payloadDir) })
+
+	log := logger.New(false)
+	laravelSvc := services.NewLaravelService(t.TempDir(), "/bin/true", "", payloadDir, log)
+	return New(cfg, auth.New(cfg.JWTSecret), laravelSvc, log)
+}
+
+// testConn is a websocket connection to a test server
+type testConn struct {
+	t      *testing.T
+	conn   *websocket.Conn
+	events chan map[string]interface{}
+}
+
+// dialTestServer connects to the server, authenticated by a handshake token when userID is set
+func dialTestServer(t *testing.T, server *Server, userID string) *testConn {
+	t.Helper()
+	httpServer := httptest.NewServer(http.HandlerFunc(server.HandleConnection))
+	t.Cleanup(httpServer.Close)
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	if userID != "" {
+		token, err := auth.New(server.config.JWTSecret).GenerateToken(userID, "")
+		if err != nil {
+			t.Fatalf("Failed to generate a token: %v", err)
+		}
+		url += "?token=" + token
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &testConn{t: t, conn: conn, events: make(chan map[string]interface{}, 100)}
+	go func() {
+		defer close(c.events)
+		for {
+			var event map[string]interface{}
+			if err := conn.ReadJSON(&event); err != nil {
+				return
+			}
+			c.events <- event
+		}
+	}()
+	c.expect("connected")
+	return c
+}
+
+// send sends an action to the server
+func (c *testConn) send(action map[string]interface{}) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(action); err != nil {
+		c.t.Fatalf("Failed to send %v: %v", action, err)
+	}
+}
+
+// expect skips events until one of the given name arrives, and returns it. Skipping an event of
+// one of the refused names fails the test.
+func (c *testConn) expect(name string, refused ...string) map[string]interface{} {
+	c.t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-c.events:
+			if !ok {
+				c.t.Fatalf("Expected a %s event, the connection closed", name)
+			}
+			if event["event"] == name {
+				return event
+			}
+			for _, refusedName := range refused {
+				if event["event"] == refusedName {
+					c.t.Fatalf("Expected a %s event, got %v", name, event)
+				}
+			}
+		case <-timeout:
+			c.t.Fatalf("Timed out waiting for a %s event", name)
+		}
+	}
+}
+
+func TestBackupRestoresTheServerState(t *testing.T) {
+	server := newTestServer(t, &config.Config{})
+	readOnly := true
+	if _, err := server.CreateChannel("announcements", models.ChannelSettings{ReadOnly: &readOnly}); err != nil {
+		t.Fatalf("Failed to create the channel: %v", err)
+	}
+	if _, _, err := server.AddBan(models.Ban{Type: models.BanTypeUser, Value: "42", Reason: "spam"}); err != nil {
+		t.Fatalf("Failed to add the ban: %v", err)
+	}
+	if _, err := server.CreateGroup("eu", []string{"paris"}, ""); err != nil {
+		t.Fatalf("Failed to create the group: %v", err)
+	}
+
+	var archive bytes.Buffer
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	written, err := WriteBackup(&archive, server.TakeSnapshot(), "test", createdAt)
+	if err != nil {
+		t.Fatalf("Failed to write the backup: %v", err)
+	}
+	data := archive.Bytes()
+
+	manifest, snapshot, err := ReadBackup(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to read the backup: %v", err)
+	}
+	if !manifest.CreatedAt.Equal(createdAt) || manifest.Source != "test" || !reflect.DeepEqual(manifest.Contents, written.Contents) {
+		t.Errorf("Expected the manifest written, got %+v", manifest)
+	}
+	restored := newTestServer(t, &config.Config{})
+	restored.RestoreSnapshot(snapshot)
+	if channel, exists := restored.GetChannel("announcements"); !exists || !channel.ReadOnly {
+		t.Error("Expected the channel restored with its settings")
+	}
+	if _, banned := restored.activeBan(models.BanTypeUser, "42"); !banned {
+		t.Error("Expected the ban restored")
+	}
+	if _, exists := restored.GetGroup("eu"); !exists {
+		t.Error("Expected the group restored")
+	}
+
+	for name, corrupt := range map[string][]byte{"not an archive": []byte("state"), "truncated": data[:len(data)/2]} {
+		if _, _, err := ReadBackup(bytes.NewReader(corrupt)); err == nil {
+			t.Errorf("Expected a %s backup refused", name)
+		}
+	}
+}
+
+func TestSlowClientsReportsStalledConnections(t *testing.T) {
+	server := newTestServer(t, &config.Config{})
+	httpServer := httptest.NewServer(http.HandlerFunc(server.HandleConnection))
+	t.Cleanup(httpServer.Close)
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	// The stalled connection never reads, the healthy one is read by its test connection
+	stalled, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { stalled.Close() })
+	var stalledClient *models.Client
+	for deadline := time.Now().Add(2 * time.Second); stalledClient == nil; time.Sleep(5 * time.Millisecond) {
+		for _, client := range server.GetClients() {
+			stalledClient = client
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the stalled connection registered")
+		}
+	}
+	dialTestServer(t, server, "")
+
+	// A message larger than the socket buffers holds the writer, the next ones wait in the queue
+	stalledClient.SetWriteTimeout(time.Minute)
+	stalledClient.SendMessage(models.Message{Event: "large", Data: strings.Repeat("x", 32<<20)})
+	for deadline := time.Now().Add(2 * time.Second); stalledClient.QueueLength() > 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the writer to take the large message")
+		}
+	}
+	for i := 0; i < 10; i++ {
+		stalledClient.SendMessage(models.Message{Event: "small"})
+	}
+
+	criteria := SlowClientCriteria{QueueDepth: 5, RTT: time.Hour, SlowWrites: 1000}
+	report := server.SlowClients(criteria)
+	if len(report) != 1 || report[0].ClientID != stalledClient.ID || !reflect.DeepEqual(report[0].Reasons, []string{SlowReasonQueueDepth}) {
+		t.Fatalf("Expected only the stalled client reported for its queue depth, got %+v", report)
+	}
+	if report[0].Stats.QueueLength != 10 {
+		t.Errorf("Expected the 10 queued messages in the report, got %d", report[0].Stats.QueueLength)
+	}
+
+	criteria.QueueDepth = 11
+	if report := server.SlowClients(criteria); len(report) != 0 {
+		t.Errorf("Expected no client under the thresholds reported, got %+v", report)
+	}
+}
+
+func TestBroadcastLimiterQueuesThenRejects(t *testing.T) {
+	limiter := newBroadcastLimiter(1, 1)
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected a free slot, got %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		queuedRelease, err := limiter.acquire(context.Background())
+		if err != nil {
+			t.Errorf("Expected the queued broadcast to run, got %v", err)
+		}
+		acquired <- queuedRelease
+	}()
+	for deadline := time.Now().Add(2 * time.Second); limiter.queued.Load() != 1; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the second broadcast queued")
+		}
+	}
+
+	if _, err := limiter.acquire(context.Background()); !errors.Is(err, models.ErrBroadcastQueueFull) {
+		t.Errorf("Expected a broadcast over the queue rejected, got %v", err)
+	}
+	if limiter.rejected.Load() != 1 || limiter.active.Load() != 1 {
+		t.Errorf("Expected a rejection counted and one active broadcast, got %d and %d", limiter.rejected.Load(), limiter.active.Load())
+	}
+
+	release()
+	select {
+	case queuedRelease := <-acquired:
+		queuedRelease()
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the queued broadcast to take the released slot")
+	}
+	if limiter.active.Load() != 0 || limiter.queued.Load() != 0 {
+		t.Errorf("Expected the limiter idle, got %d active and %d queued", limiter.active.Load(), limiter.queued.Load())
+	}
+
+	unlimited := newBroadcastLimiter(0, 0)
+	for i := 0; i < 3; i++ {
+		if _, err := unlimited.acquire(context.Background()); err != nil {
+			t.Errorf("Expected an unlimited limiter to never wait, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+// snapshotVersion is bumped whenever the snapshot layout changes incompatibly
+const snapshotVersion = 1
+
+// snapshotMigrations upgrade snapshots of earlier layout versions. Bumping snapshotVersion
+// requires adding the migration from the previous version, so existing state files still load.
+var snapshotMigrations []models.Migration
+
+// Snapshot is the operator-configured server state persisted across restarts
+type Snapshot struct {
+	Version   int                               `json:"version"`
+	TakenAt   time.Time                         `json:"taken_at"`
+	NodeID    string                            `json:"node_id"`
+	Channels  []ChannelSnapshot                 `json:"channels"`
+	Groups    map[string]config.GroupDefinition `json:"groups,omitempty"`
+	BannedIPs []string                          `json:"banned_ips,omitempty"`
+	// DynamicGroups are the groups defined by the dynamic configuration, removed with it
+	DynamicGroups []string `json:"dynamic_groups,omitempty"`
+	// Bans are the user and IP bans made through the ban API
+	Bans []models.Ban `json:"bans,omitempty"`
+	// ScheduledBroadcasts are the broadcasts waiting for their send time
+	ScheduledBroadcasts []models.ScheduledBroadcast `json:"scheduled_broadcasts,omitempty"`
+	// EventSchemas are the schemas broadcast data is validated against
+	EventSchemas []models.EventSchema            `json:"event_schemas,omitempty"`
+	UserGrants   map[string]map[string]time.Time `json:"user_grants,omitempty"`
+	// PushDevices are the devices registered for the push fallback, keyed by user ID
+	PushDevices map[string][]models.PushDevice `json:"push_devices,omitempty"`
+	// UserLastSeen is when recently disconnected users went offline, for critical fallbacks
+	UserLastSeen map[string]time.Time `json:"user_last_seen,omitempty"`
+}
+
+// ChannelSnapshot holds a channel's settings, metadata and retained history
+type ChannelSnapshot struct {
+	Name         string                 `json:"name"`
+	IsPrivate    bool                   `json:"is_private"`
+	RequireAuth  bool                   `json:"require_auth"`
+	ReadOnly     bool                   `json:"read_only"`
+	MaxClients   int                    `json:"max_clients"`
+	ClientEvents bool                   `json:"client_events,omitempty"`
+	Sequence     uint64                 `json:"sequence,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	History      []models.Message       `json:"history,omitempty"`
+}
+
+// TakeSnapshot captures the current channels, groups, bans, scheduled broadcasts, event schemas,
+// channel grants, push devices and when users went offline
+func (s *Server) TakeSnapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Version: snapshotVersion,
+		TakenAt: s.Now(),
+		NodeID:  s.config.NodeID,
+		Groups:  make(map[string]config.GroupDefinition),
+	}
+
+	for name, channel := range s.GetChannels() {
+		settings := channel.GetSettings()
+		snapshot.Channels = append(snapshot.Channels, ChannelSnapshot{
+			Name:         name,
+			IsPrivate:    settings["is_private"].(bool),
+			RequireAuth:  settings["require_auth"].(bool),
+			ReadOnly:     settings["read_only"].(bool),
+			MaxClients:   settings["max_clients"].(int),
+			ClientEvents: settings["client_events"].(bool),
+			Sequence:     channel.CurrentSequence(),
+			Metadata:     channel.GetMetadata(),
+			History:      channel.GetHistory(),
+		})
+	}
+
+	for name, group := range s.GetGroups() {
+		snapshot.Groups[name] = config.GroupDefinition{Channels: group.GetChannels(), Pattern: group.GetPattern()}
+	}
+
+	s.mutex.RLock()
+	for name := range s.dynamicGroups {
+		snapshot.DynamicGroups = append(snapshot.DynamicGroups, name)
+	}
+	for ip := range s.bannedIPs {
+		snapshot.BannedIPs = append(snapshot.BannedIPs, ip)
+	}
+	for _, ban := range s.bans {
+		snapshot.Bans = append(snapshot.Bans, ban)
+	}
+	for _, scheduled := range s.schedules {
+		snapshot.ScheduledBroadcasts = append(snapshot.ScheduledBroadcasts, scheduled.broadcast)
+	}
+	for _, schema := range s.eventSchemas {
+		snapshot.EventSchemas = append(snapshot.EventSchemas, *schema)
+	}
+	if len(s.userGrants) > 0 {
+		snapshot.UserGrants = make(map[string]map[string]time.Time, len(s.userGrants))
+		for userID, grants := range s.userGrants {
+			snapshot.UserGrants[userID] = make(map[string]time.Time, len(grants))
+			for channelName, expiresAt := range grants {
+				snapshot.UserGrants[userID][channelName] = expiresAt
+			}
+		}
+	}
+	if len(s.pushDevices) > 0 {
+		snapshot.PushDevices = make(map[string][]models.PushDevice, len(s.pushDevices))
+		for userID, devices := range s.pushDevices {
+			snapshot.PushDevices[userID] = append([]models.PushDevice{}, devices...)
+		}
+	}
+	if len(s.userLastSeen) > 0 {
+		snapshot.UserLastSeen = make(map[string]time.Time, len(s.userLastSeen))
+		for userID, lastSeen := range s.userLastSeen {
+			snapshot.UserLastSeen[userID] = lastSeen
+		}
+	}
+	s.mutex.RUnlock()
+
+	return snapshot
+}
+
+// RestoreSnapshot recreates the state captured by TakeSnapshot. It is meant to run at startup,
+// before clients connect, so no channel_updated events are sent. Expired grants are skipped.
+func (s *Server) RestoreSnapshot(snapshot *Snapshot) {
+	s.restoreState(snapshot)
+	s.logger.Info("Restored state snapshot from %s: %d channels, %d groups, %d banned IPs, %d bans, %d scheduled broadcasts",
+		snapshot.TakenAt.Format(time.RFC3339), len(snapshot.Channels), len(snapshot.Groups), len(snapshot.BannedIPs), len(snapshot.Bans), len(snapshot.ScheduledBroadcasts))
+}
+
+// restoreState adds the state of a snapshot to the server's
+func (s *Server) restoreState(snapshot *Snapshot) {
+	for _, saved := range snapshot.Channels {
+		channel := s.getOrCreateChannel(saved.Name, saved.IsPrivate)
+		channel.ApplySettings(models.ChannelSettings{
+			IsPrivate:    &saved.IsPrivate,
+			RequireAuth:  &saved.RequireAuth,
+			ReadOnly:     &saved.ReadOnly,
+			MaxClients:   &saved.MaxClients,
+			ClientEvents: &saved.ClientEvents,
+		})
+		if len(saved.Metadata) > 0 {
+			channel.SetMetadata(saved.Metadata)
+		}
+		for _, message := range saved.History {
+			channel.AddToHistory(message)
+			channel.RestoreSequence(message.Sequence)
+		}
+		channel.RestoreSequence(saved.Sequence)
+	}
+
+	now := s.Now()
+	s.mutex.Lock()
+	for name, definition := range snapshot.Groups {
+		s.groups[name] = s.newGroup(name, definition.Channels, definition.Pattern)
+	}
+	for _, name := range snapshot.DynamicGroups {
+		if _, exists := s.groups[name]; exists {
+			s.dynamicGroups[name] = true
+		}
+	}
+	for _, ip := range snapshot.BannedIPs {
+		s.bannedIPs[ip] = true
+	}
+	for _, ban := range snapshot.Bans {
+		if ban.Active(now) {
+			s.bans[banKey(ban.Type, ban.Value)] = ban
+		}
+	}
+	// Their timers start once the broadcast executor is set
+	for _, broadcast := range snapshot.ScheduledBroadcasts {
+		s.schedules[broadcast.ID] = &scheduledBroadcast{broadcast: broadcast}
+	}
+	for i := range snapshot.EventSchemas {
+		schema := &snapshot.EventSchemas[i]
+		if err := schema.Compile(); err != nil {
+			s.logger.Warn("Skipped the saved schema of event %s: %v", schema.Event, err)
+			continue
+		}
+		s.eventSchemas[schema.Event] = schema
+	}
+	for userID, grants := range snapshot.UserGrants {
+		for channelName, expiresAt := range grants {
+			if now.After(expiresAt) {
+				continue
+			}
+			if s.userGrants[userID] == nil {
+				s.userGrants[userID] = make(map[string]time.Time)
+			}
+			s.userGrants[userID][channelName] = expiresAt
+		}
+	}
+	for userID, devices := range snapshot.PushDevices {
+		s.pushDevices[userID] = devices
+	}
+	for userID, lastSeen := range snapshot.UserLastSeen {
+		if lastSeen.After(s.userLastSeen[userID]) {
+			s.userLastSeen[userID] = lastSeen
+		}
+	}
+	s.mutex.Unlock()
+}
+
+// SaveSnapshot writes the current state to filename, replacing it atomically
+func (s *Server) SaveSnapshot(filename string) error {
+	return WriteSnapshotFile(filename, s.TakeSnapshot())
+}
+
+// WriteSnapshotFile writes a snapshot to filename, replacing it atomically
+func WriteSnapshotFile(filename string, snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error encoding state snapshot: %w", err)
+	}
+
+	tmpFile := filename + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return fmt.Errorf("error writing state snapshot: %w", err)
+	}
+	if err := os.Rename(tmpFile, filename); err != nil {
+		return fmt.Errorf("error writing state snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot restores the state saved in filename. A missing file is not an error, so the
+// first start with a new state file begins empty. A file of an earlier layout version is
+// upgraded in place, keeping the original next to it as <filename>.v<version>.bak.
+func (s *Server) LoadSnapshot(filename string) error {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading state snapshot: %w", err)
+	}
+	snapshot, version, err := decodeSnapshot(data)
+	if err != nil {
+		return err
+	}
+
+	if version < snapshotVersion {
+		backupFile := fmt.Sprintf("%s.v%d.bak", filename, version)
+		if err := os.WriteFile(backupFile, data, 0600); err != nil {
+			return fmt.Errorf("error keeping the state snapshot before its upgrade: %w", err)
+		}
+		if err := WriteSnapshotFile(filename, snapshot); err != nil {
+			return err
+		}
+		s.logger.Info("Upgraded state snapshot from version %d to %d, the original is kept as %s", version, snapshotVersion, backupFile)
+	}
+
+	s.RestoreSnapshot(snapshot)
+	return nil
+}
+
+// ReadSnapshotFile reads the snapshot saved in filename
+func ReadSnapshotFile(filename string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading state snapshot: %w", err)
+	}
+	return DecodeSnapshot(data)
+}
+
+// DecodeSnapshot decodes a snapshot, upgrading those of an earlier layout version and refusing
+// those of a newer one
+func DecodeSnapshot(data []byte) (*Snapshot, error) {
+	snapshot, _, err := decodeSnapshot(data)
+	return snapshot, err
+}
+
+// decodeSnapshot decodes a snapshot, also returning the layout version it was written with
+func decodeSnapshot(data []byte) (*Snapshot, int, error) {
+	data, version, err := models.MigrateDocument(data, snapshotVersion, snapshotMigrations)
+	if err != nil {
+		return nil, version, fmt.Errorf("error decoding state snapshot: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, version, fmt.Errorf("error decoding state snapshot: %w", err)
+	}
+	return &snapshot, version, nil
+}
+
+// StartSnapshotter saves the state to the configured state file every snapshot interval
+func (s *Server) StartSnapshotter() {
+	if s.config.StateFile == "" || s.config.StateSnapshotInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.config.StateSnapshotInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.SaveSnapshot(s.config.StateFile); err != nil {
+				s.logger.Error("Failed to save state snapshot: %v", err)
+			}
+		}
+	}()
+}
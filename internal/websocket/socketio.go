@@ -0,0 +1,236 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// socketIOPingTimeout is how long Socket.IO clients wait past the ping interval for a ping
+// before closing the connection, announced in the handshake
+const socketIOPingTimeout = 20 * time.Second
+
+// socketIOActionAliases maps Socket.IO room idioms to client actions
+var socketIOActionAliases = map[string]string{
+	"join":  "join_channel",
+	"leave": "leave_channel",
+}
+
+// HandleSocketIO serves Socket.IO clients (Engine.IO v4) over the WebSocket transport, so
+// existing frontends can connect without being rewritten. Emitted events are handled as client
+// actions: emit("join_channel", {channel: "chat.1"}) is the native join_channel message. Channel
+// messages are emitted to the client under their event name, with the message as the argument.
+// HTTP long-polling isn't supported; clients must be configured with transports: ["websocket"].
+func (s *Server) HandleSocketIO(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch {
+	case query.Get("EIO") != "4":
+		writeSocketIOError(w, 5, "Unsupported protocol version")
+		return
+	case query.Get("transport") != "websocket":
+		writeSocketIOError(w, 0, "Transport unknown")
+		return
+	case query.Get("sid") != "":
+		writeSocketIOError(w, 1, "Session ID unknown")
+		return
+	}
+
+	client := s.upgradeClient(w, r)
+	if client == nil {
+		return
+	}
+	client.Protocol = models.ProtocolSocketIO
+
+	// Engine.IO clients expect pings at the interval announced in the handshake, so it doesn't adapt
+	policy := s.pingPolicy()
+	interval := policy.Interval
+	policy.MinInterval, policy.MaxInterval = interval, interval
+	client.SetPingPolicy(policy)
+
+	// Events can only be emitted once the client has connected to the namespace
+	var connected atomic.Bool
+	client.SetEncoder(func(message models.Message) ([]byte, error) {
+		if !connected.Load() {
+			return nil, nil
+		}
+		return models.EncodeSocketIOEvent(message)
+	})
+	s.registerClient(client)
+
+	handshake, _ := json.Marshal(map[string]interface{}{
+		"sid":          client.ID,
+		"upgrades":     []string{},
+		"pingInterval": interval.Milliseconds(),
+		"pingTimeout":  socketIOPingTimeout.Milliseconds(),
+		"maxPayload":   s.maxMessageSize(),
+	})
+	if err := client.WriteText(append([]byte{models.EngineOpen}, handshake...)); err != nil {
+		s.logger.Debug("Failed to send Socket.IO handshake to client %s: %v", client.ID, err)
+		client.SetDisconnectReason(DisconnectReasonConnectionLost)
+		s.disconnectClient(client)
+		return
+	}
+
+	done := make(chan bool, 2)
+	go s.handleSocketIOMessages(client, &connected, done)
+	go s.handleSocketIOPing(client, done)
+
+	<-done
+	s.disconnectClient(client)
+}
+
+// writeSocketIOError refuses a Socket.IO request with an Engine.IO error code
+func writeSocketIOError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "message": message})
+}
+
+// handleSocketIOMessages reads a Socket.IO client's packets until the connection closes
+func (s *Server) handleSocketIOMessages(client *models.Client, connected *atomic.Bool, done chan bool) {
+	defer func() {
+		s.logger.Debug("Client %s message handler exiting", client.ID)
+		done <- true
+	}()
+	defer s.recoverClient(client, "reader")
+
+	for {
+		frame, err := client.SafeReadFrame()
+		if err != nil {
+			s.recordReadError(client, err)
+			return
+		}
+
+		if err := client.SafeSetReadDeadline(time.Now().Add(client.ReadTimeout())); err != nil {
+			s.logger.Debug("Client %s failed to set read deadline: %v", client.ID, err)
+			return
+		}
+		client.LastSeen = s.Now()
+
+		packet, err := models.ParseSocketIOPacket(frame)
+		if err != nil {
+			s.logger.Debug("Client %s sent an invalid Socket.IO packet: %v", client.ID, err)
+			continue
+		}
+
+		switch packet.EngineType {
+		case models.EnginePong:
+			client.RecordPong()
+		case models.EnginePing:
+			client.WriteText(append([]byte{models.EnginePong}, packet.Data...))
+		case models.EngineClose:
+			client.SetDisconnectReason(DisconnectReasonClientClosed)
+			return
+		case models.EngineMessage:
+			s.queueAction(client, func() {
+				// The reader stops once the connection is closed
+				if !s.handleSocketIOPacket(client, packet, connected) {
+					client.SetDisconnectReason(DisconnectReasonClientClosed)
+					client.Close()
+				}
+			})
+		}
+	}
+}
+
+// handleSocketIOPacket handles a Socket.IO packet, returning false when the client disconnected
+// from the namespace
+func (s *Server) handleSocketIOPacket(client *models.Client, packet models.SocketIOPacket, connected *atomic.Bool) bool {
+	if packet.Namespace != "/" {
+		reply, _ := models.EncodeSocketIOPacket(models.SocketIOConnectError, packet.Namespace, -1, map[string]string{"message": "Invalid namespace"})
+		client.WriteText(reply)
+		return true
+	}
+
+	switch packet.Type {
+	case models.SocketIOConnect:
+		if connected.Load() {
+			return true
+		}
+		reply, _ := models.EncodeSocketIOPacket(models.SocketIOConnect, "/", -1, map[string]string{"sid": client.ID})
+		if err := client.WriteText(reply); err != nil {
+			return false
+		}
+		connected.Store(true)
+		client.SendMessage(s.welcomeMessage(client))
+
+		// The auth payload of io({auth: {token}}) authenticates the connection
+		var auth map[string]interface{}
+		if json.Unmarshal(packet.Data, &auth) == nil && auth["token"] != nil {
+			auth["action"] = "authenticate"
+			s.handleAction(client, auth)
+		}
+	case models.SocketIODisconnect:
+		return false
+	case models.SocketIOEvent:
+		if !connected.Load() {
+			return true
+		}
+		s.messagesReceived.Add(1)
+		s.handleAction(client, socketIOAction(packet))
+
+		if packet.AckID >= 0 {
+			reply, _ := models.EncodeSocketIOPacket(models.SocketIOAck, "/", packet.AckID, []interface{}{map[string]string{"status": "received"}})
+			client.WriteText(reply)
+		}
+	}
+	return true
+}
+
+// socketIOAction turns an event packet into a client message: the event names the action and
+// an object argument holds its fields. A string argument of join and leave names the channel;
+// any other argument becomes the message data.
+func socketIOAction(packet models.SocketIOPacket) map[string]interface{} {
+	action := packet.Event
+	if alias, ok := socketIOActionAliases[action]; ok {
+		action = alias
+	}
+
+	msg := make(map[string]interface{})
+	var arg interface{}
+	if len(packet.Args) > 0 {
+		json.Unmarshal(packet.Args[0], &arg)
+	}
+	switch value := arg.(type) {
+	case map[string]interface{}:
+		msg = value
+	case string:
+		if action == "join_channel" || action == "leave_channel" {
+			msg["channel"] = value
+		} else {
+			msg["data"] = value
+		}
+	case nil:
+	default:
+		msg["data"] = value
+	}
+	msg["action"] = action
+	return msg
+}
+
+// handleSocketIOPing sends Engine.IO pings at the interval announced in the handshake
+func (s *Server) handleSocketIOPing(client *models.Client, done chan bool) {
+	defer func() {
+		s.logger.Debug("Client %s ping handler exiting", client.ID)
+		done <- true
+	}()
+	defer s.recoverClient(client, "ping")
+
+	ticker := time.NewTicker(client.PingInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !client.IsConnected() {
+			return
+		}
+		if err := client.SendTextPing([]byte{models.EnginePing}); err != nil {
+			s.logger.Debug("Failed to send Socket.IO ping to client %s: %v", client.ID, err)
+			client.SetDisconnectReason(DisconnectReasonPingFailed)
+			return
+		}
+		s.logger.PingSent(client.ID)
+	}
+}
@@ -0,0 +1,221 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"socket-server/internal/models"
+)
+
+// standbyFetchTimeout bounds a request for the primary's state
+const standbyFetchTimeout = 30 * time.Second
+
+// ErrNotStandby is returned when promoting a node that isn't a standby
+var ErrNotStandby = errors.New("node is not a standby")
+
+// StandbyStatus describes a standby node's copy of its primary's state
+type StandbyStatus struct {
+	Standby    bool       `json:"standby"`
+	Primary    string     `json:"primary,omitempty"`
+	Syncs      uint64     `json:"syncs_total"`
+	Failures   uint64     `json:"failures_total"`
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+}
+
+// standbySync copies the primary's state until the node is promoted. Its mutex is held while
+// a copy is applied, so a promotion never races a sync.
+type standbySync struct {
+	primary string
+	token   string
+	client  *http.Client
+	stop    chan struct{}
+	status  StandbyStatus
+	mutex   sync.Mutex
+}
+
+// IsStandby reports whether the node is a standby that hasn't been promoted
+func (s *Server) IsStandby() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.standby != nil
+}
+
+// StartStandby turns the node into a warm standby of the primary configured by STANDBY_OF: it
+// refuses clients, holds back scheduled broadcasts, and replaces its state with the primary's
+// every sync interval until promoted.
+func (s *Server) StartStandby() {
+	token := s.config.StandbyToken
+	if token == "" {
+		token = s.config.HTTPToken
+	}
+	standby := &standbySync{
+		primary: strings.TrimSuffix(s.config.StandbyOf, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: standbyFetchTimeout},
+		stop:    make(chan struct{}),
+		status:  StandbyStatus{Standby: true, Primary: s.config.StandbyOf},
+	}
+
+	s.mutex.Lock()
+	s.standby = standby
+	s.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(s.config.StandbySyncInterval)
+		defer ticker.Stop()
+
+		for {
+			s.syncFromPrimary(standby)
+			select {
+			case <-standby.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// syncFromPrimary copies the primary's state once
+func (s *Server) syncFromPrimary(standby *standbySync) {
+	snapshot, err := standby.fetch()
+
+	standby.mutex.Lock()
+	defer standby.mutex.Unlock()
+
+	if !standby.status.Standby {
+		return
+	}
+	if err != nil {
+		standby.status.Failures++
+		if standby.status.LastError == "" {
+			s.logger.Error("Standby: failed to copy the state of %s: %v", standby.primary, err)
+		}
+		standby.status.LastError = err.Error()
+		return
+	}
+
+	s.replaceState(snapshot)
+	now := s.Now()
+	standby.status.Syncs++
+	standby.status.LastSyncAt = &now
+	if standby.status.Syncs == 1 || standby.status.LastError != "" {
+		s.logger.Info("Standby: copied the state of %s: %d channels, %d bans, %d scheduled broadcasts",
+			standby.primary, len(snapshot.Channels), len(snapshot.Bans), len(snapshot.ScheduledBroadcasts))
+	}
+	standby.status.LastError = ""
+}
+
+// fetch requests the primary's state from its /api/state endpoint
+func (standby *standbySync) fetch() (*Snapshot, error) {
+	req, err := http.NewRequest("GET", standby.primary+"/api/state", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+standby.token)
+
+	resp, err := standby.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("primary answered %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the primary's state: %w", err)
+	}
+	return DecodeSnapshot(data)
+}
+
+// replaceState swaps the node's state for a snapshot of the primary's. A standby serves no
+// clients, so its channels are simply recreated.
+func (s *Server) replaceState(snapshot *Snapshot) {
+	s.mutex.Lock()
+	s.channels = make(map[string]*models.Channel)
+	s.groups = make(map[string]*models.ChannelGroup)
+	s.dynamicGroups = make(map[string]bool)
+	s.bannedIPs = make(map[string]bool)
+	s.bans = make(map[string]models.Ban)
+	s.schedules = make(map[string]*scheduledBroadcast)
+	s.eventSchemas = make(map[string]*models.EventSchema)
+	s.userGrants = make(map[string]map[string]time.Time)
+	s.pushDevices = make(map[string][]models.PushDevice)
+	s.userLastSeen = make(map[string]time.Time)
+	s.mutex.Unlock()
+
+	s.restoreState(snapshot)
+}
+
+// Promote makes a standby the primary: it stops copying state, accepts clients and starts the
+// timers of the scheduled broadcasts it copied. Broadcasts that came due meanwhile run right away.
+func (s *Server) Promote() (StandbyStatus, error) {
+	s.mutex.RLock()
+	standby := s.standby
+	s.mutex.RUnlock()
+	if standby == nil {
+		return s.StandbyStatus(), ErrNotStandby
+	}
+
+	standby.mutex.Lock()
+	defer standby.mutex.Unlock()
+	if !standby.status.Standby {
+		return standby.status, ErrNotStandby
+	}
+	now := s.Now()
+	standby.status.Standby = false
+	standby.status.PromotedAt = &now
+	close(standby.stop)
+
+	s.mutex.Lock()
+	s.standby = nil
+	s.promoted = standby
+	// Users the primary saw online count as offline from the promotion on
+	s.servingSince = now
+	if s.broadcaster != nil {
+		for _, scheduled := range s.schedules {
+			if scheduled.timer == nil {
+				s.armSchedule(scheduled)
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	s.logger.Warn("Promoted from standby of %s to primary (state copied %d times, last at %s)",
+		standby.primary, standby.status.Syncs, formatSyncTime(standby.status.LastSyncAt))
+	return standby.status, nil
+}
+
+// StandbyStatus returns the state of the standby, or of the standby the node was promoted from
+func (s *Server) StandbyStatus() StandbyStatus {
+	s.mutex.RLock()
+	standby := s.standby
+	if standby == nil {
+		standby = s.promoted
+	}
+	s.mutex.RUnlock()
+
+	if standby == nil {
+		return StandbyStatus{}
+	}
+	standby.mutex.Lock()
+	defer standby.mutex.Unlock()
+	return standby.status
+}
+
+// formatSyncTime shows when the state was last copied
+func formatSyncTime(at *time.Time) string {
+	if at == nil {
+		return "never"
+	}
+	return at.Format(time.RFC3339)
+}
@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+// channelThrottle limits how often messages are published to a channel. Messages arriving
+// faster than the limit are held back and coalesced: only the latest message per event key is
+// kept, and pending keys are released one per interval in the order they first arrived.
+// Intervals are measured on the system clock, like the timers that release pending keys, not
+// on the server's clock.
+type channelThrottle struct {
+	interval  time.Duration
+	lastSent  time.Time
+	pending   map[string]models.Message
+	order     []string
+	scheduled bool // a flush is scheduled
+	publish   func(models.Message)
+	coalesced int
+	mutex     sync.Mutex
+
+	// Clock and timer of the throttle, replaced in tests
+	now       func() time.Time
+	afterFunc func(time.Duration, func())
+}
+
+// newChannelThrottle creates a throttle allowing perSecond messages per second
+func newChannelThrottle(perSecond float64, publish func(models.Message)) *channelThrottle {
+	return &channelThrottle{
+		interval:  time.Duration(float64(time.Second) / perSecond),
+		pending:   make(map[string]models.Message),
+		publish:   publish,
+		now:       time.Now,
+		afterFunc: func(delay time.Duration, f func()) { time.AfterFunc(delay, f) },
+	}
+}
+
+// submit publishes the message now if the rate allows, otherwise queues it latest-wins
+func (t *channelThrottle) submit(message models.Message) {
+	t.mutex.Lock()
+
+	now := t.now()
+	if len(t.order) == 0 && now.Sub(t.lastSent) >= t.interval {
+		t.lastSent = now
+		t.mutex.Unlock()
+		t.publish(message)
+		return
+	}
+
+	key := coalesceKey(message)
+	if _, exists := t.pending[key]; exists {
+		t.coalesced++
+	} else {
+		t.order = append(t.order, key)
+	}
+	t.pending[key] = message
+
+	if !t.scheduled {
+		t.scheduled = true
+		t.afterFunc(t.lastSent.Add(t.interval).Sub(now), t.flush)
+	}
+	t.mutex.Unlock()
+}
+
+// flush releases the oldest pending key and reschedules itself while messages remain
+func (t *channelThrottle) flush() {
+	t.mutex.Lock()
+
+	t.scheduled = false
+	if len(t.order) == 0 {
+		t.mutex.Unlock()
+		return
+	}
+
+	key := t.order[0]
+	t.order = t.order[1:]
+	message := t.pending[key]
+	delete(t.pending, key)
+	t.lastSent = t.now()
+
+	if len(t.order) > 0 {
+		t.scheduled = true
+		t.afterFunc(t.interval, t.flush)
+	}
+	t.mutex.Unlock()
+
+	t.publish(message)
+}
+
+// coalesceKey returns the key under which throttled messages replace each other: the
+// message's coalesce key when it has one, otherwise its event name
+func coalesceKey(message models.Message) string {
+	if message.CoalesceKey != "" {
+		return message.CoalesceKey
+	}
+	return message.Event
+}
+
+// unthrottledCacheSize bounds the names of channels remembered as having no rate limit; the
+// cache starts over once full
+const unthrottledCacheSize = 10000
+
+// getThrottle returns the throttle for a channel, creating it when a rate limit rule matches.
+// It returns nil for channels without a rate limit. Channels are matched against the rules
+// once: throttles are cached until CloseChannel drops them, and up to unthrottledCacheSize
+// unthrottled channel names are remembered.
+func (s *Server) getThrottle(channelName string) *channelThrottle {
+	s.throttleMutex.RLock()
+	throttle, exists := s.throttles[channelName]
+	unthrottled := len(s.rateLimits) == 0 || s.unthrottled[channelName]
+	s.throttleMutex.RUnlock()
+	if exists || unthrottled {
+		return throttle
+	}
+
+	s.throttleMutex.Lock()
+	defer s.throttleMutex.Unlock()
+
+	if throttle, exists := s.throttles[channelName]; exists {
+		return throttle
+	}
+
+	// Rules can be replaced at runtime by dynamic configuration, so match them under the lock
+	var rule *config.RateLimitRule
+	for i := range s.rateLimits {
+		if matched, err := path.Match(s.rateLimits[i].Pattern, channelName); err == nil && matched {
+			rule = &s.rateLimits[i]
+			break
+		}
+	}
+	if rule == nil {
+		if len(s.unthrottled) >= unthrottledCacheSize {
+			s.unthrottled = make(map[string]bool)
+		}
+		s.unthrottled[channelName] = true
+		return nil
+	}
+	throttle = newChannelThrottle(rule.PerSecond, func(message models.Message) {
+		s.publishToChannel(channelName, message)
+	})
+	s.throttles[channelName] = throttle
+	return throttle
+}
+
+// setRateLimits replaces the channel rate limit rules; every channel is matched again
+func (s *Server) setRateLimits(rules []config.RateLimitRule) {
+	s.throttleMutex.Lock()
+	defer s.throttleMutex.Unlock()
+	s.rateLimits = rules
+	s.throttles = make(map[string]*channelThrottle)
+	s.unthrottled = make(map[string]bool)
+}
+
+// dropThrottle forgets the throttle of a closed channel
+func (s *Server) dropThrottle(channelName string) {
+	s.throttleMutex.Lock()
+	defer s.throttleMutex.Unlock()
+	delete(s.throttles, channelName)
+	delete(s.unthrottled, channelName)
+}
@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+func TestChannelThrottleLatestWins(t *testing.T) {
+	var published []string
+	throttle := newChannelThrottle(10, func(message models.Message) {
+		published = append(published, message.Event+":"+message.ID)
+	})
+	// The throttle runs on a manual clock, its scheduled flushes are fired by the test
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var flushes []func()
+	var delays []time.Duration
+	throttle.now = func() time.Time { return now }
+	throttle.afterFunc = func(delay time.Duration, f func()) {
+		delays = append(delays, delay)
+		flushes = append(flushes, f)
+	}
+	fire := func() {
+		t.Helper()
+		if len(flushes) != 1 {
+			t.Fatalf("Expected one scheduled flush, got %d", len(flushes))
+		}
+		flush, delay := flushes[0], delays[0]
+		flushes, delays = nil, nil
+		now = now.Add(delay)
+		flush()
+	}
+
+	throttle.submit(models.Message{ID: "1", Event: "price"})
+	throttle.submit(models.Message{ID: "2", Event: "price"})
+	throttle.submit(models.Message{ID: "3", Event: "volume"})
+	throttle.submit(models.Message{ID: "4", Event: "price"})
+	if !reflect.DeepEqual(published, []string{"price:1"}) {
+		t.Fatalf("Expected only the first message published right away, got %v", published)
+	}
+	if delays[0] != 100*time.Millisecond {
+		t.Errorf("Expected a flush scheduled one 100ms interval later, got %v", delays[0])
+	}
+
+	// The latest price is released first, then the volume
+	fire()
+	if !reflect.DeepEqual(published, []string{"price:1", "price:4"}) {
+		t.Fatalf("Expected the latest price after one interval, got %v", published)
+	}
+	fire()
+	if !reflect.DeepEqual(published, []string{"price:1", "price:4", "volume:3"}) {
+		t.Fatalf("Expected the volume after two intervals, got %v", published)
+	}
+	if len(flushes) != 0 {
+		t.Errorf("Expected no flush scheduled once nothing is pending, got %d", len(flushes))
+	}
+	if throttle.coalesced != 1 {
+		t.Errorf("Expected 1 coalesced message, got %d", throttle.coalesced)
+	}
+}
+
+func TestGetThrottleCachesOnlyRateLimitedChannels(t *testing.T) {
+	server := newTestServer(t, &config.Config{ChannelRateLimits: "ticker.*=10"})
+
+	if throttle := server.getThrottle("chat"); throttle != nil {
+		t.Fatal("Expected no throttle for a channel without a rate limit")
+	}
+	throttle := server.getThrottle("ticker.btc")
+	if throttle == nil || server.getThrottle("ticker.btc") != throttle {
+		t.Fatal("Expected the rate-limited channel to keep its throttle")
+	}
+	if len(server.throttles) != 1 || !server.unthrottled["chat"] {
+		t.Errorf("Expected the throttled channel cached and the other one remembered as unthrottled, got %d throttles and %v", len(server.throttles), server.unthrottled)
+	}
+
+	server.getOrCreateChannel("ticker.btc", false)
+	if _, err := server.CloseChannel("ticker.btc", "test"); err != nil {
+		t.Fatalf("Failed to close the channel: %v", err)
+	}
+	if len(server.throttles) != 0 {
+		t.Errorf("Expected closing the channel to drop its throttle, got %d entries", len(server.throttles))
+	}
+
+	// Replacing the rules matches every channel again
+	server.setRateLimits([]config.RateLimitRule{{Pattern: "chat", PerSecond: 5}})
+	if server.getThrottle("chat") == nil || server.getThrottle("ticker.btc") != nil {
+		t.Fatal("Expected the channels matched against the new rules")
+	}
+}
@@ -0,0 +1,212 @@
+package websocket
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"socket-server/internal/models"
+	"socket-server/internal/services"
+)
+
+// maxPendingUploads is the most uploads a connection may have requested and not completed
+const maxPendingUploads = 10
+
+// pendingUpload is a file a client was given an upload URL for, broadcast once it completes
+// the upload
+type pendingUpload struct {
+	clientID string
+	userID   string
+	channel  string
+	event    string
+	file     models.UploadedFile
+	expires  time.Time
+}
+
+// SetUploadStorage lets clients upload large files straight to a bucket: they request a
+// pre-signed upload URL, and once the file is uploaded the channel receives its reference
+func (s *Server) SetUploadStorage(storage *services.UploadStorage) {
+	s.uploadStorage = storage
+}
+
+// uploadObjectKey returns the key a file is stored under: the channel and upload ID, then its
+// base name reduced to safe characters
+func uploadObjectKey(channelName, uploadID, name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if strings.Trim(name, ".") == "" {
+		name = "file"
+	}
+	return "uploads/" + channelName + "/" + uploadID + "/" + name
+}
+
+// handleRequestUpload gives a client a pre-signed URL to upload a file of the announced name,
+// type and size to. The checks of send_message apply to the channel.
+func (s *Server) handleRequestUpload(client *models.Client, msg map[string]interface{}) {
+	if s.uploadStorage == nil {
+		s.sendError(client, "Uploads are not enabled")
+		return
+	}
+	channelName, ok := msg["channel"].(string)
+	if !ok || channelName == "" {
+		s.sendError(client, "Invalid channel name")
+		return
+	}
+	size, _ := msg["size"].(float64)
+	if size < 1 || size != float64(int64(size)) {
+		s.sendError(client, "Invalid upload size")
+		return
+	}
+	if int64(size) > s.config.UploadMaxSize {
+		s.sendError(client, "Upload too large")
+		return
+	}
+
+	if models.IsDirectChannel(channelName) && !models.IsDirectChannelParticipant(channelName, client.UserID) {
+		s.sendError(client, "Direct channel access denied")
+		return
+	}
+	if owner, isUserChannel := s.userChannelOwner(channelName); isUserChannel && owner != client.UserID {
+		s.sendError(client, "User channel access denied")
+		return
+	}
+	if channel, exists := s.GetChannel(channelName); exists && channel.ReadOnly {
+		s.recordRejection(client, channelName, "", moderationRuleReadOnly)
+		s.sendError(client, "Channel is read-only")
+		return
+	}
+
+	event := getStringFromMap(msg, "event", "file_uploaded")
+	if !s.admitEventName(client, channelName, event) {
+		return
+	}
+
+	name := getStringFromMap(msg, "name", "")
+	if name != "" {
+		name = path.Base("/" + name)
+	}
+	now := s.Now()
+	id := s.NewID()
+	upload := &pendingUpload{
+		clientID: client.ID,
+		userID:   client.UserID,
+		channel:  channelName,
+		event:    event,
+		file: models.UploadedFile{
+			ID:          id,
+			Key:         uploadObjectKey(channelName, id, name),
+			Name:        name,
+			ContentType: getStringFromMap(msg, "content_type", ""),
+			Size:        int64(size),
+			Metadata:    msg["metadata"],
+		},
+		expires: now.Add(s.config.UploadURLTTL),
+	}
+
+	s.mutex.Lock()
+	pending := 0
+	for uploadID, other := range s.pendingUploads {
+		if now.After(other.expires) {
+			delete(s.pendingUploads, uploadID)
+		} else if other.clientID == client.ID {
+			pending++
+		}
+	}
+	if pending >= maxPendingUploads {
+		s.mutex.Unlock()
+		s.sendError(client, "Too many pending uploads")
+		return
+	}
+	s.pendingUploads[id] = upload
+	s.mutex.Unlock()
+
+	url, headers := s.uploadStorage.PresignPut(upload.file.Key, upload.file.ContentType, upload.file.Size, s.config.UploadURLTTL)
+	s.logger.Info("Client %s (%s) requested upload %s of %d bytes to channel '%s'", client.ID, client.Username, id, upload.file.Size, channelName)
+
+	client.SendMessage(models.Message{
+		ID:       s.NewID(),
+		Event:    "upload_url",
+		Priority: models.PriorityHigh,
+		Data: map[string]interface{}{
+			"upload_id":  id,
+			"url":        url,
+			"method":     "PUT",
+			"headers":    headers,
+			"expires_at": upload.expires,
+		},
+		Timestamp: now,
+	})
+}
+
+// handleCompleteUpload checks that a requested upload reached storage, then broadcasts the
+// file's reference to the channel. The upload may be completed by the connection that
+// requested it, or another connection of the same user.
+func (s *Server) handleCompleteUpload(client *models.Client, msg map[string]interface{}) {
+	uploadID, _ := msg["upload_id"].(string)
+
+	s.mutex.Lock()
+	upload, exists := s.pendingUploads[uploadID]
+	if exists && upload.clientID != client.ID && (upload.userID == "" || upload.userID != client.UserID) {
+		exists = false
+	}
+	if exists && s.Now().After(upload.expires) {
+		delete(s.pendingUploads, uploadID)
+		exists = false
+	}
+	s.mutex.Unlock()
+	if !exists {
+		s.sendError(client, "Unknown or expired upload")
+		return
+	}
+
+	// The upload can be completed again once the file is in storage
+	size, err := s.uploadStorage.Stat(upload.file.Key)
+	if err != nil {
+		s.logger.Warn("Upload %s of client %s isn't in storage: %v", uploadID, client.ID, err)
+		s.sendError(client, "Upload not found in storage")
+		return
+	}
+	if size != upload.file.Size {
+		s.sendError(client, "Uploaded file size doesn't match the announced size")
+		return
+	}
+
+	s.mutex.Lock()
+	_, exists = s.pendingUploads[uploadID]
+	delete(s.pendingUploads, uploadID)
+	s.mutex.Unlock()
+	if !exists {
+		// Completed meanwhile by another connection of the user
+		return
+	}
+
+	file := upload.file
+	file.URL = s.uploadStorage.ObjectURL(file.Key)
+	message := models.Message{
+		ID:        s.NewID(),
+		Channel:   upload.channel,
+		Event:     upload.event,
+		Data:      file,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		Timestamp: s.Now(),
+	}
+	s.logger.MessageSent(client.ID, client.Username, upload.channel, upload.event, file)
+
+	if err := s.laravelSvc.QueueMessage(message, client); err != nil {
+		s.logger.Error("Failed to dispatch message to Laravel: %v", err)
+	}
+	s.BroadcastToChannel(upload.channel, message)
+
+	client.SendMessage(models.Message{
+		ID:        s.NewID(),
+		Event:     "upload_completed",
+		Priority:  models.PriorityHigh,
+		Data:      map[string]interface{}{"upload_id": uploadID, "channel": upload.channel, "url": file.URL},
+		Timestamp: s.Now(),
+	})
+}
@@ -0,0 +1,38 @@
+package websocket
+
+import "socket-server/internal/models"
+
+// userChannelClients returns the members of a user's personal channel. Every authenticated
+// connection joins it and can't leave it, so they are all the user's connections.
+func (s *Server) userChannelClients(userID string) []*models.Client {
+	channel, exists := s.GetChannel(models.UserChannelName(s.config.UserChannelTemplate, userID))
+	if !exists {
+		return []*models.Client{}
+	}
+
+	members := channel.GetClients()
+	clients := make([]*models.Client, 0, len(members))
+	for _, client := range members {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// userChannelOwner returns the user a personal channel belongs to, when user channels are enabled
+func (s *Server) userChannelOwner(channelName string) (string, bool) {
+	if !s.config.UserChannels {
+		return "", false
+	}
+	return models.UserChannelOwner(s.config.UserChannelTemplate, channelName)
+}
+
+// joinUserChannel subscribes an authenticated client to its user's personal channel, so Laravel
+// can reach every connection of the user through a regular channel broadcast
+func (s *Server) joinUserChannel(client *models.Client) {
+	if !s.config.UserChannels || client.UserID == "" {
+		return
+	}
+
+	channelName := models.UserChannelName(s.config.UserChannelTemplate, client.UserID)
+	s.joinGrantedChannel(client, s.getOrCreateChannel(channelName, true))
+}
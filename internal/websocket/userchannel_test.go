@@ -0,0 +1,36 @@
+package websocket
+
+import (
+	"testing"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+func TestUserChannelOnlyAcceptsMessagesFromItsOwner(t *testing.T) {
+	cfg := config.New()
+	cfg.UserChannels = true
+	cfg.UserChannelTemplate = "private-user.{user_id}"
+	server := newTestServer(t, cfg)
+
+	owner := dialTestServer(t, server, "1")
+	owner.expect("joined_channel")
+	intruder := dialTestServer(t, server, "2")
+	intruder.expect("joined_channel")
+
+	intruder.send(map[string]interface{}{"action": "send_message", "channel": "private-user.1", "event": "invoice.paid", "data": "forged"})
+	if data, _ := intruder.expect("error")["data"].(map[string]interface{}); data["error"] != "User channel access denied" {
+		t.Errorf("Expected the message into another user's channel refused, got %v", data)
+	}
+	intruder.send(map[string]interface{}{"action": "join_channel", "channel": "private-user.1"})
+	intruder.expect("error")
+
+	// The owner receives its own message, and the forged one never came first
+	owner.send(map[string]interface{}{"action": "send_message", "channel": "private-user.1", "event": "note", "data": "mine"})
+	if event := owner.expect("note", "invoice.paid"); event["data"] != "mine" {
+		t.Errorf("Expected the owner's own message, got %v", event)
+	}
+	if channel, _ := server.GetChannel(models.UserChannelName(cfg.UserChannelTemplate, "1")); channel.GetClientCount() != 1 {
+		t.Errorf("Expected only the owner in the personal channel, got %d members", channel.GetClientCount())
+	}
+}
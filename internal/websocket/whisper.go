@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"path"
+
+	"socket-server/internal/models"
+)
+
+// handleClientEvent relays a client- event to the other members of a channel. Client events
+// are ephemeral: they aren't sequenced, kept in history or dispatched to Laravel, and the
+// sending connection doesn't get its own event back.
+func (s *Server) handleClientEvent(client *models.Client, channelName, event string, data interface{}) {
+	// Pusher clients were authorized for their private and presence channels by the app's signature
+	if client.UserID == "" && client.Protocol != models.ProtocolPusher {
+		s.sendError(client, "Client events require authentication")
+		return
+	}
+
+	channel, exists := s.GetChannel(channelName)
+	if !exists || !client.GetChannels()[channelName] {
+		s.sendError(client, "Not a member of channel")
+		return
+	}
+
+	if !channel.ClientEvents {
+		s.logger.Warn("Client %s denied client event '%s' on channel '%s'", client.ID, event, channelName)
+		s.recordRejection(client, channelName, event, moderationRuleClientEventsDisabled)
+		s.sendError(client, "Client events are not enabled for this channel")
+		return
+	}
+
+	if channel.ReadOnly {
+		s.recordRejection(client, channelName, event, moderationRuleReadOnly)
+		s.sendError(client, "Channel is read-only")
+		return
+	}
+
+	message := models.Message{
+		ID:        s.NewID(),
+		Channel:   channelName,
+		Event:     event,
+		Data:      data,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		Timestamp: s.Now(),
+	}
+
+	delivered := 0
+	channel.WithPublishLock(func() {
+		for _, member := range channel.GetClients() {
+			if member.ID == client.ID {
+				continue
+			}
+			if err := member.SendMessage(message); err != nil {
+				s.logger.Debug("Failed to send %s to client %s: %v", event, member.ID, err)
+				continue
+			}
+			delivered++
+		}
+	})
+
+	s.logger.Debug("Client %s relayed '%s' to %d members of channel '%s'", client.ID, event, delivered, channelName)
+}
+
+// allowsClientEvents reports whether a channel name matches one of the configured client event
+// patterns
+func (s *Server) allowsClientEvents(channelName string) bool {
+	for _, pattern := range s.config.ClientEventChannels {
+		if matched, err := path.Match(pattern, channelName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
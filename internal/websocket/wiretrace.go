@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"socket-server/internal/config"
+	"socket-server/internal/models"
+)
+
+// wireTracer records the frames of selected clients, for diagnosing protocol bugs. Frames go
+// to an NDJSON file, one models.WireFrame per line, or to the log.
+type wireTracer struct {
+	server     *Server
+	selectors  []config.TraceSelector
+	redactKeys []string
+
+	file  *os.File // nil logs frames instead
+	mutex sync.Mutex
+}
+
+// opcodeNames names WebSocket opcodes in traced frames
+var opcodeNames = map[int]string{
+	websocket.TextMessage:   "text",
+	websocket.BinaryMessage: "binary",
+	websocket.CloseMessage:  "close",
+	websocket.PingMessage:   "ping",
+	websocket.PongMessage:   "pong",
+}
+
+// EnableWireTrace starts tracing the frames of clients matching the selectors, redacting the
+// values of redactKeys in addition to models.DefaultRedactKeys. Frames are appended to file,
+// or logged when it is empty. It applies to clients connecting afterwards.
+func (s *Server) EnableWireTrace(selectors []string, redactKeys []string, file string) error {
+	parsed, err := config.ParseTraceSelectors(selectors)
+	if err != nil {
+		return err
+	}
+
+	tracer := &wireTracer{
+		server:     s,
+		selectors:  parsed,
+		redactKeys: append(append([]string{}, models.DefaultRedactKeys...), redactKeys...),
+	}
+	if file != "" {
+		if tracer.file, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+			return fmt.Errorf("error opening wire trace file: %w", err)
+		}
+	}
+
+	s.wireTracer = tracer
+	s.logger.Warn("Wire tracing enabled for %v; traces contain message contents", selectors)
+	return nil
+}
+
+// CloseWireTrace closes the wire trace file
+func (s *Server) CloseWireTrace() error {
+	if s.wireTracer == nil || s.wireTracer.file == nil {
+		return nil
+	}
+
+	s.wireTracer.mutex.Lock()
+	defer s.wireTracer.mutex.Unlock()
+
+	err := s.wireTracer.file.Close()
+	s.wireTracer.file = nil
+	return err
+}
+
+// tap is the models.WireTap of traced servers
+func (t *wireTracer) tap(client *models.Client, direction string, opcode int, payload []byte) {
+	if !t.selects(client, payload) {
+		return
+	}
+
+	frame := models.WireFrame{
+		Time:      t.server.Now(),
+		ClientID:  client.ID,
+		UserID:    client.UserID,
+		Direction: direction,
+		Opcode:    opcodeNames[opcode],
+		Size:      len(payload),
+	}
+	if len(payload) > 0 {
+		if redacted, ok := models.RedactPayload(payload, t.redactKeys); ok {
+			frame.Payload = redacted
+		} else {
+			frame.Raw = fmt.Sprintf("%q", payload)
+		}
+	}
+
+	line, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.file == nil {
+		t.server.logger.Info("Wire %s", line)
+		return
+	}
+	if _, err := t.file.Write(append(line, '\n')); err != nil {
+		t.server.logger.Error("Failed to write wire trace: %v", err)
+	}
+}
+
+// selects reports whether a frame is traced: its client matches a client or user selector, or
+// the frame's channel or one of the client's channels matches a channel selector
+func (t *wireTracer) selects(client *models.Client, payload []byte) bool {
+	var channels map[string]bool
+	frameChannel, decoded := "", false
+
+	for _, selector := range t.selectors {
+		switch selector.Kind {
+		case "client":
+			if matched, _ := path.Match(selector.Pattern, client.ID); matched {
+				return true
+			}
+		case "user":
+			if matched, _ := path.Match(selector.Pattern, client.UserID); matched && client.UserID != "" {
+				return true
+			}
+		case "channel":
+			if !decoded {
+				var fields struct {
+					Channel string `json:"channel"`
+				}
+				json.Unmarshal(payload, &fields)
+				frameChannel, decoded = fields.Channel, true
+				channels = client.GetChannels()
+			}
+			if matched, _ := path.Match(selector.Pattern, frameChannel); matched && frameChannel != "" {
+				return true
+			}
+			for channelName := range channels {
+				if matched, _ := path.Match(selector.Pattern, channelName); matched {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
 
 	"socket-server/internal/auth"
 	"socket-server/internal/config"
+	"socket-server/internal/dynconfig"
 	"socket-server/internal/handlers"
 	"socket-server/internal/middleware"
+	"socket-server/internal/models"
 	"socket-server/internal/services"
 	"socket-server/internal/websocket"
 	"socket-server/pkg/logger"
@@ -27,6 +34,47 @@ var (
 	laravelCmd string
 	tempDir    string
 	webDir     string
+
+	dmHistory        int
+	channelHistory   int
+	reliableChannels string
+	clientEvents     string
+	batchInterval    int
+	batchSize        int
+	rateLimits       string
+	nodeID           string
+	shutdownGrace    int
+	statusFD         int
+	statusFile       string
+	geoIPDatabase    string
+	configBackend    string
+	configBackendURL string
+	configKey        string
+	userChannel      string
+	stateFile        string
+	payloadCompress  string
+	idFormat         string
+	bridgeURL        string
+	clusterRedisURL  string
+	dispatchStrategy string
+	tlsCert          string
+	tlsKey           string
+	autocertHosts    string
+	allowAllOrigins  bool
+	socketIO         bool
+	netpoll          bool
+	traceWire        string
+	traceWireFile    string
+	logLevel         string
+	logFormat        string
+	logOutput        string
+	pingInterval     int
+	pongTimeout      int
+	readTimeout      int
+	writeTimeout     int
+	maxMessageSize   int
+	adminPort        string
+	standbyOf        string
 )
 
 var rootCmd = &cobra.Command{
@@ -46,24 +94,109 @@ func init() {
 	rootCmd.Flags().StringVar(&phpBinary, "php", "", "PHP binary path (default: 'php' or PHP_BINARY env var)")
 	rootCmd.Flags().StringVar(&laravelCmd, "command", "", "Laravel artisan command to execute (default: 'socket:handle' or LARAVEL_COMMAND env var)")
 	rootCmd.Flags().StringVar(&tempDir, "temp", "", "Temporary directory for payload files (default: system temp/socket-server-payloads or SOCKET_TEMP_DIR env var)")
+	rootCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "PEM certificate file for serving HTTPS and WSS directly (default: TLS_CERT_FILE env var)")
+	rootCmd.Flags().StringVar(&tlsKey, "tls-key", "", "PEM private key file for --tls-cert (default: TLS_KEY_FILE env var)")
+	rootCmd.Flags().StringVar(&autocertHosts, "tls-autocert-hosts", "", "Comma-separated hostnames to obtain Let's Encrypt certificates for (default: TLS_AUTOCERT_HOSTS env var)")
+	rootCmd.Flags().BoolVar(&socketIO, "socketio", false, "Serve Socket.IO clients at /socket.io/ (default: SOCKETIO_ENABLED env var)")
+	rootCmd.Flags().BoolVar(&netpoll, "netpoll", false, "Watch idle connections with epoll instead of goroutines, for very many connections (Linux only; default: NETPOLL_ENABLED env var)")
+	rootCmd.Flags().BoolVar(&allowAllOrigins, "allow-all-origins", false, "Accept WebSocket connections from any origin, for development (default: ALLOW_ALL_ORIGINS env var)")
+	rootCmd.Flags().StringVar(&traceWire, "trace-wire", "", "Trace the frames of matching clients for debugging: client:<id>, user:<id>, channel:<pattern> or * (default: TRACE_WIRE env var)")
+	rootCmd.Flags().StringVar(&traceWireFile, "trace-wire-file", "", "Write traced frames to this NDJSON file instead of the log (default: TRACE_WIRE_FILE env var)")
+	rootCmd.Flags().StringVar(&logLevel, "log-level", "", "Least severe level logged: debug, info, warn or error (default: info or LOG_LEVEL env var)")
+	rootCmd.Flags().StringVar(&logFormat, "log-format", "", "Log format: text or json (default: text or LOG_FORMAT env var)")
+	rootCmd.Flags().StringVar(&logOutput, "log-output", "", "Log destination: stdout, stderr, syslog or a file path (default: stdout or LOG_OUTPUT env var)")
 	rootCmd.Flags().StringVar(&webDir, "web", "", "Web directory for static files (default: ./web or WEB_DIR env var)")
+	rootCmd.Flags().StringVar(&adminPort, "admin-port", "", "Serve the REST API, metrics and admin interface on this port or host:port instead of the public port (default: ADMIN_PORT env var)")
+	rootCmd.Flags().StringVar(&standbyOf, "standby-of", "", "Run as a warm standby copying the state of the primary at this admin API URL (default: STANDBY_OF env var)")
+	rootCmd.Flags().StringVar(&reliableChannels, "reliable-channels", "", "Comma-separated channel patterns with ACK mode and read receipts (default: RELIABLE_CHANNELS env var)")
+	rootCmd.Flags().StringVar(&clientEvents, "client-events-channels", "", "Comma-separated channel patterns whose members may relay client- events (default: CLIENT_EVENTS_CHANNELS env var)")
+	rootCmd.Flags().IntVar(&batchInterval, "batch-interval", -1, "Batch client messages sent to Laravel every N milliseconds, 0 disables (default: 0 or DISPATCH_BATCH_INTERVAL_MS env var)")
+	rootCmd.Flags().StringVar(&dispatchStrategy, "dispatch-strategy", "", "How payloads reach Laravel: exec, worker-pool or http-callback (default: exec or DISPATCH_STRATEGY env var)")
+	rootCmd.Flags().IntVar(&batchSize, "batch-size", 0, "Flush a Laravel dispatch batch once N messages are pending (default: 50 or DISPATCH_BATCH_SIZE env var)")
+	rootCmd.Flags().StringVar(&configBackend, "config-backend", "", "Dynamic configuration backend: consul or etcd (or CONFIG_BACKEND env var)")
+	rootCmd.Flags().StringVar(&configBackendURL, "config-backend-addr", "", "Dynamic configuration backend address (or CONFIG_BACKEND_ADDR env var)")
+	rootCmd.Flags().StringVar(&configKey, "config-key", "", "Key holding the dynamic configuration document (or CONFIG_KEY env var)")
+	rootCmd.Flags().IntVar(&shutdownGrace, "shutdown-grace", -1, "Seconds to spread connection draining over on shutdown (default: 25 or SHUTDOWN_GRACE_SECONDS env var)")
+	rootCmd.Flags().IntVar(&statusFD, "status-fd", -1, "Write a JSON startup report to this file descriptor once the server is ready")
+	rootCmd.Flags().StringVar(&statusFile, "status-file", "", "Write a JSON startup report to this file once the server is ready")
+	rootCmd.Flags().StringVar(&geoIPDatabase, "geoip-db", "", "MaxMind GeoIP2/GeoLite2 database for Geo-IP enrichment (default: GEOIP_DATABASE env var)")
+	rootCmd.Flags().StringVar(&nodeID, "node-id", "", "Identifier of this server instance used in routing hints (default: hostname or NODE_ID env var)")
+	rootCmd.Flags().StringVar(&idFormat, "id-format", "", "Format of message and client IDs: uuid, ulid or ksuid (default: uuid or ID_FORMAT env var)")
+	rootCmd.Flags().StringVar(&bridgeURL, "bridge-url", "", "Forward broadcasts on BRIDGE_CHANNELS to the server at this URL (default: BRIDGE_URL env var)")
+	rootCmd.Flags().StringVar(&clusterRedisURL, "cluster-redis-url", "", "Share bans and rate limits with the other nodes through this Redis, e.g. redis://redis:6379 (default: CLUSTER_REDIS_URL env var)")
+	rootCmd.Flags().StringVar(&rateLimits, "channel-rate-limits", "", "Per-channel outbound limits as pattern=msgs_per_sec pairs, e.g. telemetry.*=10 (default: CHANNEL_RATE_LIMITS env var)")
+	rootCmd.Flags().StringVar(&userChannel, "user-channel", "", "Personal channel each authenticated connection joins, e.g. user.{user_id} (default: private-user.{user_id} or USER_CHANNEL_TEMPLATE env var)")
+	rootCmd.Flags().StringVar(&payloadCompress, "payload-compression", "", "Compress large Laravel payload files: zstd (default: PAYLOAD_COMPRESSION env var)")
+	rootCmd.Flags().StringVar(&stateFile, "state-file", "", "Persist channels, groups, bans and channel grants to this file across restarts (default: STATE_FILE env var)")
+	rootCmd.Flags().IntVar(&channelHistory, "channel-history", -1, "Messages retained per channel, 0 disables (default: 0 or CHANNEL_HISTORY_SIZE env var)")
+	rootCmd.Flags().IntVar(&dmHistory, "dm-history", -1, "Messages retained per direct message channel, 0 disables (default: 0 or DM_HISTORY_SIZE env var)")
+	rootCmd.Flags().IntVar(&pingInterval, "ping-interval", -1, "Seconds between pings of a new connection, 0 for a fixed 30s (default: 30 or PING_INTERVAL_SECONDS env var)")
+	rootCmd.Flags().IntVar(&pongTimeout, "pong-timeout", -1, "Seconds a connection may stay silent past two ping intervals (default: 10 or PONG_TIMEOUT_SECONDS env var)")
+	rootCmd.Flags().IntVar(&readTimeout, "read-timeout", -1, "Seconds a connection may stay silent whatever its ping interval, 0 derives it from the ping interval (default: 0 or READ_TIMEOUT_SECONDS env var)")
+	rootCmd.Flags().IntVar(&writeTimeout, "write-timeout", -1, "Milliseconds allowed for writing a frame before the connection is closed (default: 500 or WRITE_TIMEOUT_MS env var)")
+	rootCmd.Flags().IntVar(&maxMessageSize, "max-message-size", -1, "Largest message accepted from a client in bytes (default: 524288 or MAX_MESSAGE_BYTES env var)")
+
+	// check-config checks the configuration the server would run with, flags included
+	checkConfigCmd.Flags().AddFlagSet(rootCmd.Flags())
 }
 
 func runServer(cmd *cobra.Command, args []string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	serve(signals, nil)
+}
+
+// serve runs the server until a signal is received on stop, then drains connections and returns.
+// logHook, when set, receives every log entry (used for the Windows event log).
+func serve(stop <-chan os.Signal, logHook func(level, message string)) {
 	// Load configuration
 	cfg := config.New()
 	cfg.LoadFromFlags(port, jwtSecret, httpToken, workingDir, phpBinary, laravelCmd, tempDir, webDir)
+	applyFlagOverrides(cfg)
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+	// Validate configuration, reporting every problem at once
+	if problems := cfg.Check(); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("Configuration error: %v", problem)
+		}
+		os.Exit(1)
 	}
 
 	// Initialize logger
-	logger := logger.New(cfg.Debug)
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	logger, err := logger.NewWithOptions(logger.Options{Level: level, Format: cfg.LogFormat, Output: cfg.LogOutput})
+	if err != nil {
+		log.Fatalf("Logger error: %v", err)
+	}
+	defer logger.Close()
+	if logHook != nil {
+		logger.SetHook(logHook)
+	}
+
+	podLabels, err := config.LoadPodLabels(cfg.PodLabelsFile)
+	if err != nil {
+		logger.Warn("Failed to load pod labels: %v", err)
+	}
+	cfg.PodLabels = podLabels
+
+	if cfg.IngestSourcesFile != "" {
+		if cfg.IngestSources, err = config.LoadIngestSources(cfg.IngestSourcesFile); err != nil {
+			logger.Fatal("Failed to load ingest sources: %v", err)
+		}
+	}
+	if cfg.PurgeRulesFile != "" {
+		if cfg.PurgeRules, err = config.LoadPurgeRules(cfg.PurgeRulesFile); err != nil {
+			logger.Fatal("Failed to load CDN purge rules: %v", err)
+		}
+	}
+	if cfg.EventSchemasFile != "" {
+		if cfg.EventSchemas, err = config.LoadEventSchemas(cfg.EventSchemasFile); err != nil {
+			logger.Fatal("Failed to load event schemas: %v", err)
+		}
+	}
 
 	// Display configuration
-	logger.Info("Starting Socket Server on port %s", cfg.Port)
+	logger.Info("Starting Socket Server on port %s (node: %s)", cfg.Port, cfg.NodeID)
 
 	// Safely display JWT secret (first few characters)
 	secretDisplay := cfg.JWTSecret
@@ -83,53 +216,568 @@ func runServer(cmd *cobra.Command, args []string) {
 
 	logger.Info("JWT Secret: %s", secretDisplay)
 	logger.Info("HTTP API Token: %s", tokenDisplay)
-	logger.Info("Working Directory: %s", cfg.WorkingDir)
-	logger.Info("PHP Binary: %s", cfg.PHPBinary)
-	logger.Info("Laravel Command: %s", cfg.LaravelCmd)
-	logger.Info("Temp Directory: %s", cfg.TempDir)
+	if cfg.DispatchStrategy != services.DispatchStrategyHTTP {
+		logger.Info("Working Directory: %s", cfg.WorkingDir)
+		logger.Info("PHP Binary: %s", cfg.PHPBinary)
+		logger.Info("Laravel Command: %s", cfg.LaravelCmd)
+		logger.Info("Temp Directory: %s", cfg.TempDir)
+	}
+
+	if cfg.AllowAllOrigins {
+		logger.Warn("Origin check disabled: accepting WebSocket connections from any origin")
+	}
+
+	// Generate message and client IDs in the configured format; the server and the services
+	// creating IDs are given the generator
+	idGenerator, err := models.NewIDGenerator(cfg.IDFormat)
+	if err != nil {
+		logger.Fatal("Failed to configure ID generation: %v", err)
+	}
+
+	// Merge custom translations of client-facing messages
+	if cfg.LocaleCatalog != "" {
+		data, err := os.ReadFile(cfg.LocaleCatalog)
+		if err != nil {
+			logger.Fatal("Failed to read locale catalog: %v", err)
+		}
+		if err := models.LoadCatalog(data); err != nil {
+			logger.Fatal("Failed to load locale catalog: %v", err)
+		}
+	}
 
 	// Initialize services
 	authService := auth.New(cfg.JWTSecret)
 	laravelSvc := services.NewLaravelService(cfg.WorkingDir, cfg.PHPBinary, cfg.LaravelCmd, cfg.TempDir, logger)
+	laravelSvc.SetIDGenerator(idGenerator)
 
-	// Initialize temp directory and start cleanup routine
-	if err := laravelSvc.InitializeTempDirectory(); err != nil {
-		logger.Fatal("Failed to initialize temp directory: %v", err)
+	// Initialize temp directory and start cleanup routine; the http-callback strategy writes
+	// no payload files, so backends without PHP can run on a read-only filesystem
+	if cfg.DispatchStrategy != services.DispatchStrategyHTTP {
+		if err := laravelSvc.InitializeTempDirectory(); err != nil {
+			logger.Fatal("Failed to initialize temp directory: %v", err)
+		}
+		if err := laravelSvc.SetPayloadCompression(cfg.PayloadCompression, cfg.PayloadCompressionMinSize); err != nil {
+			logger.Fatal("Failed to configure payload compression: %v", err)
+		}
+		laravelSvc.StartCleanupRoutine()
+	}
+	if err := laravelSvc.SetDispatchStrategy(cfg.DispatchStrategy, services.DispatchOptions{
+		Workers:         cfg.DispatchWorkers,
+		WorkerMaxJobs:   cfg.DispatchWorkerMaxJobs,
+		Timeout:         cfg.DispatchTimeout,
+		CallbackURL:     cfg.DispatchCallbackURL,
+		CallbackSecret:  cfg.DispatchCallbackSecret,
+		CallbackRetries: cfg.DispatchCallbackRetries,
+	}); err != nil {
+		logger.Fatal("Failed to configure Laravel dispatch: %v", err)
 	}
-	laravelSvc.StartCleanupRoutine()
+	if cfg.DispatchOutboxDir != "" {
+		outbox, err := services.NewOutbox(cfg.DispatchOutboxDir)
+		if err != nil {
+			logger.Fatal("Failed to open dispatch outbox: %v", err)
+		}
+		laravelSvc.SetOutbox(outbox)
+		if err := laravelSvc.ReplayOutbox(); err != nil {
+			logger.Fatal("Failed to replay dispatch outbox: %v", err)
+		}
+	}
+	laravelSvc.StartBatching(cfg.DispatchBatchInterval, cfg.DispatchBatchSize)
 
 	// Initialize WebSocket server
-	wsServer := websocket.New(authService, laravelSvc, logger)
+	wsServer := websocket.New(cfg, authService, laravelSvc, logger)
+	wsServer.SetIDGenerator(idGenerator)
+	wsServer.StartMetricsSampler(10 * time.Second)
+	wsServer.StartExpirySweeper()
+
+	// Let clients upload large files straight to a bucket
+	if cfg.UploadBucket != "" {
+		endpoint := cfg.UploadEndpoint
+		if endpoint == "" {
+			endpoint = "https://s3." + cfg.UploadRegion + ".amazonaws.com"
+		}
+		storage, err := services.NewUploadStorage(endpoint, cfg.UploadBucket, cfg.UploadRegion, cfg.UploadAccessKey, cfg.UploadSecretKey, cfg.UploadPathStyle, cfg.UploadPublicURL)
+		if err != nil {
+			logger.Fatal("Failed to configure upload storage: %v", err)
+		}
+		wsServer.SetUploadStorage(storage)
+		logger.Info("Uploads: bucket %s at %s", cfg.UploadBucket, endpoint)
+	}
+
+	// Watch idle connections with epoll rather than goroutines
+	if cfg.Netpoll {
+		if err := wsServer.EnableNetpoll(cfg.NetpollWorkers); err != nil {
+			logger.Warn("Netpoll unavailable, serving connections with goroutines: %v", err)
+		} else if cfg.TLSEnabled() {
+			logger.Warn("Netpoll doesn't apply to TLS connections, terminate TLS at a proxy to use it")
+		} else {
+			logger.Info("Netpoll: enabled with %d workers", cfg.NetpollWorkers)
+		}
+	}
+
+	// Restore operator-configured state from the previous run
+	if cfg.StateFile != "" {
+		if err := wsServer.LoadSnapshot(cfg.StateFile); err != nil {
+			logger.Fatal("Failed to restore state: %v", err)
+		}
+		wsServer.StartSnapshotter()
+	}
+
+	// Copy the primary's state and refuse clients until promoted
+	if cfg.StandbyOf != "" {
+		wsServer.StartStandby()
+		logger.Info("Standby of %s: copying its state every %v until promoted through POST /api/standby/promote", cfg.StandbyOf, cfg.StandbySyncInterval)
+	}
+
+	// Schemas of the schema file replace those saved in the snapshot
+	for event, schema := range cfg.EventSchemas {
+		if _, err := wsServer.RegisterEventSchema(event, schema); err != nil {
+			logger.Fatal("Invalid schema of event %s: %v", event, err)
+		}
+	}
+
+	// Resolve client locations when a GeoIP database is configured
+	if cfg.GeoIPDatabase != "" {
+		geoIP, err := services.NewGeoIPService(cfg.GeoIPDatabase)
+		if err != nil {
+			logger.Fatal("Failed to load GeoIP database: %v", err)
+		}
+		defer geoIP.Close()
+		wsServer.SetGeoIPService(geoIP)
+		logger.Info("GeoIP Database: %s", cfg.GeoIPDatabase)
+	}
+
+	// Forward selected channels to a remote server
+	if cfg.BridgeURL != "" {
+		bridge := services.NewBridgeService(cfg.BridgeURL, cfg.BridgeToken, cfg.NodeID, cfg.BridgeChannels, cfg.BridgeQueueSize, cfg.BridgeMaxRetries, logger)
+		bridge.Start()
+		defer bridge.Stop()
+		wsServer.SetBridge(bridge)
+		logger.Info("Bridging channels %v to %s", cfg.BridgeChannels, cfg.BridgeURL)
+	}
+
+	// Share bans, rate limits and broadcasts with the other nodes of the cluster
+	var cluster *services.ClusterService
+	if cfg.ClusterRedisURL != "" {
+		if cluster, err = services.NewClusterService(cfg.ClusterRedisURL, cfg.ClusterPrefix, cfg.NodeID, cfg.ClusterChannels, logger); err != nil {
+			logger.Fatal("Invalid cluster settings: %v", err)
+		}
+		if err := cluster.Ping(); err != nil {
+			logger.Fatal("Failed to reach the cluster Redis at %s: %v", cluster.Address(), err)
+		}
+		defer cluster.Stop()
+		wsServer.SetCluster(cluster)
+		logger.Info("Cluster mode: sharing bans and rate limits through Redis at %s (prefix %s)", cluster.Address(), cfg.ClusterPrefix)
+		if len(cfg.ClusterChannels) > 0 {
+			logger.Info("Cluster mode: relaying broadcasts on channels %v to the other nodes", cfg.ClusterChannels)
+		}
+	}
+
+	// Notify offline users through their mobile devices
+	if cfg.PushFCMCredentials != "" || cfg.PushAPNsKeyFile != "" {
+		push := services.NewPushService()
+		if cfg.PushFCMCredentials != "" {
+			if err := push.EnableFCM(cfg.PushFCMCredentials); err != nil {
+				logger.Fatal("Failed to enable FCM push: %v", err)
+			}
+			logger.Info("Push fallback: FCM enabled")
+		}
+		if cfg.PushAPNsKeyFile != "" {
+			if err := push.EnableAPNs(cfg.PushAPNsKeyFile, cfg.PushAPNsKeyID, cfg.PushAPNsTeamID, cfg.PushAPNsTopic, cfg.PushAPNsSandbox); err != nil {
+				logger.Fatal("Failed to enable APNs push: %v", err)
+			}
+			logger.Info("Push fallback: APNs enabled for %s", cfg.PushAPNsTopic)
+		}
+		wsServer.SetPushService(push)
+	}
+
+	// Email critical broadcasts to users who stay offline
+	if cfg.SMTPHost != "" {
+		email, err := services.NewEmailSink(services.EmailSettings{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}, cfg.EmailTemplateFile)
+		if err != nil {
+			logger.Fatal("Failed to enable the email fallback: %v", err)
+		}
+		wsServer.AddFallbackSink(email)
+		logger.Info("Email fallback for critical broadcasts through %s after %v offline", cfg.SMTPHost, cfg.CriticalOfflineThreshold)
+	}
+
+	// Keep runtime settings in sync with the dynamic configuration backend
+	if cfg.ConfigBackend != "" {
+		source, err := dynconfig.NewSource(cfg.ConfigBackend, cfg.ConfigBackendAddr, cfg.ConfigKey, cfg.ConfigPollInterval)
+		if err != nil {
+			logger.Fatal("Failed to initialize dynamic config: %v", err)
+		}
+
+		watcher := dynconfig.NewWatcher(source, logger)
+		settings, version, err := watcher.Load()
+		if err != nil {
+			logger.Warn("Dynamic config: initial load from %s failed, using local settings: %v", source.Name(), err)
+		} else {
+			wsServer.ApplyDynamicSettings(settings)
+		}
+		go watcher.Watch(version, wsServer.ApplyDynamicSettings)
+	}
+
+	// Aggregate daily usage rollups
+	var analytics *services.AnalyticsStore
+	if cfg.AnalyticsFile != "" {
+		analytics, err = services.NewAnalyticsStore(cfg.AnalyticsFile, cfg.AnalyticsRetentionDays, logger)
+		if err != nil {
+			logger.Fatal("Failed to load analytics: %v", err)
+		}
+		analytics.Start()
+		wsServer.SetAnalytics(analytics)
+		logger.Info("Analytics rollups enabled in %s (%d days)", cfg.AnalyticsFile, cfg.AnalyticsRetentionDays)
+	}
+
+	// Trace the frames of selected clients for debugging
+	if len(cfg.TraceWire) > 0 {
+		if err := wsServer.EnableWireTrace(cfg.TraceWire, cfg.TraceWireRedact, cfg.TraceWireFile); err != nil {
+			logger.Fatal("Failed to enable wire tracing: %v", err)
+		}
+	}
 
 	// Initialize HTTP handlers
 	httpHandlers := handlers.New(wsServer, logger)
+	httpHandlers.SetAnalytics(analytics)
+	if cfg.ExportDir != "" {
+		if err := os.MkdirAll(cfg.ExportDir, 0700); err != nil {
+			logger.Fatal("Failed to create the export directory: %v", err)
+		}
+		httpHandlers.SetExportDir(cfg.ExportDir)
+	}
+	wsServer.SetBroadcastExecutor(httpHandlers.ExecuteBroadcast)
+	if len(cfg.IngestSources) > 0 {
+		ingest := services.NewIngestService(cfg.IngestSources)
+		ingest.SetIDGenerator(idGenerator)
+		httpHandlers.SetIngestService(ingest)
+		logger.Info("Webhook ingestion enabled for %d sources", len(cfg.IngestSources))
+	}
+	if len(cfg.PurgeRules) > 0 {
+		httpHandlers.SetPurgeService(services.NewPurgeService(cfg.PurgeRules, logger))
+		logger.Info("CDN purging enabled with %d rules", len(cfg.PurgeRules))
+	}
 
 	// Initialize HTTP authentication middleware
 	httpAuth := middleware.NewHTTPAuth(cfg.HTTPToken, logger)
 
-	// Setup routes
+	// Setup routes; a panic in any handler fails only its own request
+	recovery := middleware.NewRecovery(logger, wsServer.RecordHTTPPanic)
 	r := mux.NewRouter()
+	r.Use(recovery.Middleware)
+
+	// The REST API, Prometheus metrics and admin interface get their own listener with ADMIN_PORT
+	admin := r
+	if cfg.AdminPort != "" {
+		admin = mux.NewRouter()
+		admin.Use(recovery.Middleware)
+	}
 
 	// WebSocket endpoint (no authentication required for WebSocket - handled internally)
 	r.HandleFunc("/ws", wsServer.HandleConnection)
+	if cfg.SocketIO {
+		r.PathPrefix("/socket.io/").HandlerFunc(wsServer.HandleSocketIO)
+		logger.Info("Socket.IO adapter enabled at /socket.io/")
+	}
+	if cfg.PusherAppKey != "" {
+		// Pusher clients and HTTP API requests are authenticated with the Pusher app credentials
+		r.HandleFunc("/app/{key}", wsServer.HandlePusher)
+		r.HandleFunc("/apps/{app_id}/events", httpHandlers.PusherEvents).Methods("POST")
+		r.HandleFunc("/apps/{app_id}/batch_events", httpHandlers.PusherBatchEvents).Methods("POST")
+		logger.Info("Pusher adapter enabled at /app/%s for app %s", cfg.PusherAppKey, cfg.PusherAppID)
+	}
+
+	// Per-IP rate limit of the /api endpoints (nil when disabled)
+	var apiRateLimit *middleware.IPRateLimit
+	if cfg.APIRateLimit > 0 {
+		apiRateLimit = middleware.NewIPRateLimit(float64(cfg.APIRateLimit), cfg.APIRateBurst, logger, wsServer.RecordHTTPRateLimited)
+		if cluster != nil {
+			apiRateLimit.SetShared(cluster)
+		}
+		logger.Info("API rate limit: %d requests/s per IP (burst %d)", cfg.APIRateLimit, cfg.APIRateBurst)
+	}
+
+	// Webhook ingestion (authenticated by each source's webhook signature)
+	r.Handle("/api/ingest/{source}", apiRateLimit.Middleware(http.HandlerFunc(httpHandlers.Ingest))).Methods("POST")
 
 	// REST API endpoints (all require authentication)
-	api := r.PathPrefix("/api").Subrouter()
+	api := admin.PathPrefix("/api").Subrouter()
+	api.Use(apiRateLimit.Middleware)
 	api.HandleFunc("/health", httpAuth.AuthenticateFunc(httpHandlers.Health)).Methods("GET")
 	api.HandleFunc("/clients", httpAuth.AuthenticateFunc(httpHandlers.GetClients)).Methods("GET")
+	api.HandleFunc("/clients/{client}", httpAuth.AuthenticateFunc(httpHandlers.GetClient)).Methods("GET")
 	api.HandleFunc("/channels", httpAuth.AuthenticateFunc(httpHandlers.GetChannels)).Methods("GET")
 	api.HandleFunc("/channels/{channel}/clients", httpAuth.AuthenticateFunc(httpHandlers.GetChannelClients)).Methods("GET")
+	api.HandleFunc("/channels", httpAuth.AuthenticateFunc(httpHandlers.CreateChannel)).Methods("POST")
+	api.HandleFunc("/channels/{channel}", httpAuth.AuthenticateFunc(httpHandlers.UpdateChannel)).Methods("PATCH")
+	api.HandleFunc("/channels/{channel}", httpAuth.AuthenticateFunc(httpHandlers.DeleteChannel)).Methods("DELETE")
+	api.HandleFunc("/channels/{channel}/metadata", httpAuth.AuthenticateFunc(httpHandlers.GetChannelMetadata)).Methods("GET")
+	api.HandleFunc("/channels/{channel}/metadata", httpAuth.AuthenticateFunc(httpHandlers.UpdateChannelMetadata)).Methods("PATCH")
+	api.HandleFunc("/channels/{channel}/metadata/{key}", httpAuth.AuthenticateFunc(httpHandlers.DeleteChannelMetadataKey)).Methods("DELETE")
+	api.HandleFunc("/channels/{channel}/users/{user_id}", httpAuth.AuthenticateFunc(httpHandlers.JoinUserToChannel)).Methods("POST")
+	api.HandleFunc("/channels/{channel}/history", httpAuth.AuthenticateFunc(httpHandlers.GetChannelHistory)).Methods("GET")
+	api.HandleFunc("/channels/{channel}/moderation", httpAuth.AuthenticateFunc(httpHandlers.GetChannelModeration)).Methods("GET")
+	api.HandleFunc("/channels/{channel}/history/export", httpAuth.AuthenticateFunc(httpHandlers.ExportChannelHistory)).Methods("GET")
+	api.HandleFunc("/channels/{channel}/exports", httpAuth.AuthenticateFunc(httpHandlers.CreateChannelExport)).Methods("POST")
+	api.HandleFunc("/channels/{channel}/receipts", httpAuth.AuthenticateFunc(httpHandlers.GetChannelReceipts)).Methods("GET")
+	api.HandleFunc("/users/{user_id}", httpAuth.AuthenticateFunc(httpHandlers.GetUser)).Methods("GET")
+	api.HandleFunc("/users/{user_id}/devices", httpAuth.AuthenticateFunc(httpHandlers.GetUserDevices)).Methods("GET")
+	api.HandleFunc("/users/{user_id}/devices", httpAuth.AuthenticateFunc(httpHandlers.RegisterUserDevice)).Methods("POST")
+	api.HandleFunc("/users/{user_id}/devices/{token}", httpAuth.AuthenticateFunc(httpHandlers.DeleteUserDevice)).Methods("DELETE")
 	api.HandleFunc("/clients/{client}/kick", httpAuth.AuthenticateFunc(httpHandlers.KickClient)).Methods("POST")
+	api.HandleFunc("/bans", httpAuth.AuthenticateFunc(httpHandlers.GetBans)).Methods("GET")
+	api.HandleFunc("/bans", httpAuth.AuthenticateFunc(httpHandlers.CreateBan)).Methods("POST")
+	api.HandleFunc("/bans/{type}/{value}", httpAuth.AuthenticateFunc(httpHandlers.DeleteBan)).Methods("DELETE")
 	api.HandleFunc("/broadcast", httpAuth.AuthenticateFunc(httpHandlers.Broadcast)).Methods("POST")
+	api.HandleFunc("/schedules", httpAuth.AuthenticateFunc(httpHandlers.GetSchedules)).Methods("GET")
+	api.HandleFunc("/schedules", httpAuth.AuthenticateFunc(httpHandlers.CreateSchedule)).Methods("POST")
+	api.HandleFunc("/schedules/{id}", httpAuth.AuthenticateFunc(httpHandlers.CancelSchedule)).Methods("DELETE")
+	api.HandleFunc("/schemas", httpAuth.AuthenticateFunc(httpHandlers.GetSchemas)).Methods("GET")
+	api.HandleFunc("/schemas/{event}", httpAuth.AuthenticateFunc(httpHandlers.PutSchema)).Methods("PUT")
+	api.HandleFunc("/schemas/{event}", httpAuth.AuthenticateFunc(httpHandlers.DeleteSchema)).Methods("DELETE")
+	api.HandleFunc("/metrics", httpAuth.AuthenticateFunc(httpHandlers.MetricsSummary)).Methods("GET")
+	api.HandleFunc("/analytics", httpAuth.AuthenticateFunc(httpHandlers.GetAnalytics)).Methods("GET")
+	api.HandleFunc("/diagnostics/slow-clients", httpAuth.AuthenticateFunc(httpHandlers.GetSlowClients)).Methods("GET")
+	api.HandleFunc("/drain", httpAuth.AuthenticateFunc(httpHandlers.Drain)).Methods("POST")
+	api.HandleFunc("/state", httpAuth.AuthenticateFunc(httpHandlers.GetState)).Methods("GET")
+	api.HandleFunc("/standby", httpAuth.AuthenticateFunc(httpHandlers.GetStandbyStatus)).Methods("GET")
+	api.HandleFunc("/standby/promote", httpAuth.AuthenticateFunc(httpHandlers.PromoteStandby)).Methods("POST")
+	api.HandleFunc("/route", httpAuth.AuthenticateFunc(httpHandlers.Route)).Methods("GET")
 	api.HandleFunc("/logs", httpAuth.AuthenticateFunc(httpHandlers.GetLogs)).Methods("GET")
+	api.HandleFunc("/logs/stream", httpAuth.AuthenticateFunc(httpHandlers.StreamLogs)).Methods("GET")
+	api.HandleFunc("/groups", httpAuth.AuthenticateFunc(httpHandlers.GetGroups)).Methods("GET")
+	api.HandleFunc("/groups", httpAuth.AuthenticateFunc(httpHandlers.CreateGroup)).Methods("POST")
+	api.HandleFunc("/groups/{group}", httpAuth.AuthenticateFunc(httpHandlers.GetGroup)).Methods("GET")
+	api.HandleFunc("/groups/{group}", httpAuth.AuthenticateFunc(httpHandlers.UpdateGroupPattern)).Methods("PATCH")
+	api.HandleFunc("/groups/{group}", httpAuth.AuthenticateFunc(httpHandlers.DeleteGroup)).Methods("DELETE")
+	api.HandleFunc("/groups/{group}/channels/{channel}", httpAuth.AuthenticateFunc(httpHandlers.AddGroupChannel)).Methods("POST")
+	api.HandleFunc("/groups/{group}/channels/{channel}", httpAuth.AuthenticateFunc(httpHandlers.RemoveGroupChannel)).Methods("DELETE")
+
+	// Prometheus metrics (same bearer token as the REST API)
+	admin.HandleFunc("/metrics", httpAuth.AuthenticateFunc(httpHandlers.Metrics)).Methods("GET")
+
+	// Kubernetes probes (no authentication required), answered on both listeners
+	r.HandleFunc("/livez", httpHandlers.Live).Methods("GET")
+	r.HandleFunc("/readyz", httpHandlers.Ready).Methods("GET")
+	if admin != r {
+		admin.HandleFunc("/livez", httpHandlers.Live).Methods("GET")
+		admin.HandleFunc("/readyz", httpHandlers.Ready).Methods("GET")
+	}
 
 	// Static file serving for admin interface (no authentication required)
 	logger.Info("Serving static files from: %s", cfg.WebDir)
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir(cfg.WebDir)))
+	admin.PathPrefix("/").Handler(http.FileServer(http.Dir(cfg.WebDir)))
 
 	// Start server
-	logger.Info("Socket server starting on port %s", cfg.Port)
-	logger.Fatal("Server error: %v", http.ListenAndServe(":"+cfg.Port, r))
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	var adminServer *http.Server
+	if admin != r {
+		adminServer = &http.Server{Addr: cfg.AdminAddr(), Handler: admin}
+	}
+	// Log streams never go idle, so end them for the shutdown to complete
+	if adminServer != nil {
+		adminServer.RegisterOnShutdown(logger.EndSubscriptions)
+	} else {
+		server.RegisterOnShutdown(logger.EndSubscriptions)
+	}
+	var certFile, keyFile string
+	if cfg.TLSEnabled() {
+		certFile, keyFile = configureTLS(server, cfg, logger)
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Fatal("Server error: %v", err)
+	}
+	go func() {
+		logger.Info("Socket server starting on port %s", cfg.Port)
+		serve := server.Serve
+		if cfg.TLSEnabled() {
+			serve = func(listener net.Listener) error { return server.ServeTLS(listener, certFile, keyFile) }
+		}
+		if err := serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Server error: %v", err)
+		}
+	}()
+
+	// The admin listener serves plain HTTP, so bind it to a private interface
+	var adminListener net.Listener
+	if adminServer != nil {
+		if adminListener, err = net.Listen("tcp", adminServer.Addr); err != nil {
+			logger.Fatal("Admin server error: %v", err)
+		}
+		go func() {
+			logger.Info("Admin API and interface listening on %s", adminListener.Addr())
+			if err := adminServer.Serve(adminListener); err != nil && err != http.ErrServerClosed {
+				logger.Fatal("Admin server error: %v", err)
+			}
+		}()
+	}
+
+	// The listener is bound, so report readiness to supervisors
+	if statusFD >= 0 || statusFile != "" {
+		adminAddr := ""
+		if adminListener != nil {
+			adminAddr = adminListener.Addr().String()
+		}
+		report := buildStartupReport(cfg, listener.Addr().String(), adminAddr)
+		if err := writeStartupReport(report, statusFD, statusFile); err != nil {
+			logger.Error("Failed to write startup report: %v", err)
+		}
+	}
+
+	// On SIGTERM, drain connections within the grace period before exiting
+	sig := <-stop
+
+	logger.Info("Received %v, draining connections", sig)
+	wsServer.Drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("HTTP server shutdown error: %v", err)
+	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			logger.Error("Admin server shutdown error: %v", err)
+		}
+	}
+	laravelSvc.FlushBatch()
+	laravelSvc.Close()
+	if err := wsServer.CloseWireTrace(); err != nil {
+		logger.Error("Failed to close wire trace file: %v", err)
+	}
+	if err := analytics.Close(); err != nil {
+		logger.Error("Failed to save analytics: %v", err)
+	}
+	if cfg.StateFile != "" {
+		if err := wsServer.SaveSnapshot(cfg.StateFile); err != nil {
+			logger.Error("Failed to save state snapshot: %v", err)
+		}
+	}
+	logger.Info("Socket server stopped")
+}
+
+// applyFlagOverrides applies optional feature flags on top of the environment configuration
+func applyFlagOverrides(cfg *config.Config) {
+	if dmHistory >= 0 {
+		cfg.DirectHistorySize = dmHistory
+	}
+	if channelHistory >= 0 {
+		cfg.ChannelHistorySize = channelHistory
+	}
+	if reliableChannels != "" {
+		cfg.ReliableChannels = config.ParseList(reliableChannels)
+	}
+	if clientEvents != "" {
+		cfg.ClientEventChannels = config.ParseList(clientEvents)
+	}
+	if batchInterval >= 0 {
+		cfg.DispatchBatchInterval = time.Duration(batchInterval) * time.Millisecond
+	}
+	if batchSize > 0 {
+		cfg.DispatchBatchSize = batchSize
+	}
+	if rateLimits != "" {
+		cfg.ChannelRateLimits = rateLimits
+	}
+	if nodeID != "" {
+		cfg.NodeID = nodeID
+	}
+	if geoIPDatabase != "" {
+		cfg.GeoIPDatabase = geoIPDatabase
+	}
+	if pingInterval >= 0 {
+		cfg.PingInterval = time.Duration(pingInterval) * time.Second
+	}
+	if pongTimeout >= 0 {
+		cfg.PongTimeout = time.Duration(pongTimeout) * time.Second
+	}
+	if readTimeout >= 0 {
+		cfg.ReadTimeout = time.Duration(readTimeout) * time.Second
+	}
+	if writeTimeout >= 0 {
+		cfg.WriteTimeout = time.Duration(writeTimeout) * time.Millisecond
+	}
+	if maxMessageSize >= 0 {
+		cfg.MaxMessageSize = maxMessageSize
+	}
+	if shutdownGrace >= 0 {
+		cfg.ShutdownGrace = time.Duration(shutdownGrace) * time.Second
+	}
+	if configBackend != "" {
+		cfg.ConfigBackend = configBackend
+	}
+	if configBackendURL != "" {
+		cfg.ConfigBackendAddr = configBackendURL
+	}
+	if configKey != "" {
+		cfg.ConfigKey = configKey
+	}
+	if userChannel != "" {
+		cfg.UserChannelTemplate = userChannel
+	}
+	if adminPort != "" {
+		cfg.AdminPort = adminPort
+	}
+	if standbyOf != "" {
+		cfg.StandbyOf = standbyOf
+	}
+	if stateFile != "" {
+		cfg.StateFile = stateFile
+	}
+	if payloadCompress != "" {
+		cfg.PayloadCompression = payloadCompress
+	}
+	if idFormat != "" {
+		cfg.IDFormat = idFormat
+	}
+	if bridgeURL != "" {
+		cfg.BridgeURL = bridgeURL
+	}
+	if clusterRedisURL != "" {
+		cfg.ClusterRedisURL = clusterRedisURL
+	}
+	if allowAllOrigins {
+		cfg.AllowAllOrigins = true
+	}
+	if socketIO {
+		cfg.SocketIO = true
+	}
+	if netpoll {
+		cfg.Netpoll = true
+	}
+	if dispatchStrategy != "" {
+		cfg.DispatchStrategy = dispatchStrategy
+	}
+	if tlsCert != "" {
+		cfg.TLSCertFile = tlsCert
+	}
+	if tlsKey != "" {
+		cfg.TLSKeyFile = tlsKey
+	}
+	if autocertHosts != "" {
+		cfg.TLSAutocertHosts = config.ParseList(autocertHosts)
+	}
+	if logLevel != "" {
+		cfg.LogLevel = logLevel
+	}
+	if logFormat != "" {
+		cfg.LogFormat = logFormat
+	}
+	if logOutput != "" {
+		cfg.LogOutput = logOutput
+	}
+	if traceWire != "" {
+		cfg.TraceWire = config.ParseList(traceWire)
+	}
+	if traceWireFile != "" {
+		cfg.TraceWireFile = traceWireFile
+	}
 }
 
 func main() {
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// chunkTimeout is how long the chunks of a message may take to arrive, as on the server
+const chunkTimeout = time.Minute
+
+// chunkedMessage is a message the server is sending as chunk events (see CHUNK_SIZE)
+type chunkedMessage struct {
+	parts    []string
+	filled   []bool
+	received int
+	started  time.Time
+}
+
+// assemble collects a chunk event, returning the whole message once its last chunk arrived
+func (c *Client) assemble(message Message) (Message, bool) {
+	var chunk struct {
+		ChunkID string `json:"chunk_id"`
+		Index   int    `json:"index"`
+		Total   int    `json:"total"`
+		Payload string `json:"payload"`
+	}
+	if err := message.Decode(&chunk); err != nil || chunk.Total < 1 || chunk.Index < 0 || chunk.Index >= chunk.Total {
+		return Message{}, false
+	}
+
+	now := time.Now()
+	for id, pending := range c.chunks {
+		if now.Sub(pending.started) > chunkTimeout {
+			delete(c.chunks, id)
+		}
+	}
+
+	pending, exists := c.chunks[chunk.ChunkID]
+	if !exists || len(pending.parts) != chunk.Total {
+		pending = &chunkedMessage{parts: make([]string, chunk.Total), filled: make([]bool, chunk.Total), started: now}
+		c.chunks[chunk.ChunkID] = pending
+	}
+	if !pending.filled[chunk.Index] {
+		pending.filled[chunk.Index] = true
+		pending.received++
+	}
+	pending.parts[chunk.Index] = chunk.Payload
+	if pending.received < chunk.Total {
+		return Message{}, false
+	}
+
+	delete(c.chunks, chunk.ChunkID)
+	var whole Message
+	if err := json.Unmarshal([]byte(strings.Join(pending.parts, "")), &whole); err != nil {
+		return Message{}, false
+	}
+	return whole, true
+}
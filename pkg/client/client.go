@@ -0,0 +1,556 @@
+// Package client is a Go client of the socket server's WebSocket protocol. It authenticates
+// with a JWT, joins and leaves channels, sends messages, and calls the handlers registered for
+// the events it receives. A lost connection is re-established with exponential backoff, and
+// the channels the client was in are joined again.
+//
+//	c, err := client.New(client.Options{URL: "ws://localhost:8080/ws", Token: jwt})
+//	if err != nil {
+//		return err
+//	}
+//	c.On("order.created", func(m client.Message) { ... })
+//	if err := c.Connect(ctx); err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//	err = c.Join(ctx, "orders")
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Defaults of the zero Options
+const (
+	defaultMinBackoff     = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// clientEventPrefix marks events relayed straight to the other members of a channel
+const clientEventPrefix = "client-"
+
+var (
+	// ErrClosed is returned by the methods of a closed client
+	ErrClosed = errors.New("client closed")
+	// ErrNotConnected is returned when sending while the client is reconnecting
+	ErrNotConnected = errors.New("not connected")
+	// ErrUnauthorized is returned when the server rejects the token; the client doesn't
+	// reconnect with a rejected token
+	ErrUnauthorized = errors.New("token rejected by the server")
+)
+
+// ServerError is an error event the server answered an action with
+type ServerError struct {
+	// Message is the error, translated to the connection's locale
+	Message string
+	// Key is the English text of a translated error
+	Key string
+}
+
+func (e *ServerError) Error() string {
+	return "server error: " + e.Message
+}
+
+// Message is an event received from the server
+type Message struct {
+	ID        string          `json:"id"`
+	Channel   string          `json:"channel,omitempty"`
+	Event     string          `json:"event"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	UserID    string          `json:"user_id,omitempty"`
+	Username  string          `json:"username,omitempty"`
+	Sequence  uint64          `json:"sequence,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Decode unmarshals the message data into v
+func (m Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Data, v)
+}
+
+// Err returns the *ServerError of an error event, and nil for other events
+func (m Message) Err() error {
+	if m.Event != "error" {
+		return nil
+	}
+	var data struct {
+		Error string `json:"error"`
+		Key   string `json:"key"`
+	}
+	m.Decode(&data)
+	return &ServerError{Message: data.Error, Key: data.Key}
+}
+
+// Handler is called with the events a client receives, on the goroutine reading the
+// connection; handlers that block hold up the events after theirs
+type Handler func(Message)
+
+// Options configures a client
+type Options struct {
+	// URL is the server's WebSocket endpoint, e.g. ws://localhost:8080/ws. http and https URLs
+	// are accepted, and /ws is used when the URL has no path.
+	URL string
+	// Token is the JWT the connection authenticates with (empty connects anonymously)
+	Token string
+	// Header holds additional headers of the upgrade request, such as Origin
+	Header http.Header
+	// Dialer opens connections (nil uses websocket.DefaultDialer)
+	Dialer *websocket.Dialer
+
+	// MinBackoff and MaxBackoff bound the delay between reconnection attempts, which doubles
+	// after each failed attempt (defaults: 500ms and 30s)
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// RequestTimeout bounds how long Join and Leave wait for the server's confirmation when
+	// their context has no deadline (default: 10s)
+	RequestTimeout time.Duration
+	// ReadTimeout closes and re-establishes a connection the server has been silent on for
+	// this long. Set it above the server's longest ping interval (0 disables it).
+	ReadTimeout time.Duration
+
+	// OnConnect is called with the connection's client ID each time the client connects
+	OnConnect func(clientID string)
+	// OnDisconnect is called when the connection is lost, before reconnecting. It is called
+	// with ErrUnauthorized when the client gives up because the server rejected the token.
+	OnDisconnect func(err error)
+}
+
+// Client is a connection to the socket server that reconnects until it is closed. Its
+// methods are safe for concurrent use.
+type Client struct {
+	options Options
+	url     string
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mutex    sync.Mutex
+	conn     *websocket.Conn
+	clientID string
+	channels map[string][]string // joined channel -> subscribed events, joined again on reconnect
+	handlers map[string][]Handler
+	waiter   chan Message // confirmations and errors for the request being awaited
+	closed   bool
+
+	// writeMutex serializes writes to the connection; requestMutex lets one request at a
+	// time wait for its confirmation
+	writeMutex   sync.Mutex
+	requestMutex sync.Mutex
+
+	// chunks holds the chunks of messages being received; only the reading goroutine uses it
+	chunks map[string]*chunkedMessage
+}
+
+// New creates a client. It doesn't connect until Connect is called.
+func New(options Options) (*Client, error) {
+	endpoint, err := websocketURL(options.URL)
+	if err != nil {
+		return nil, err
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = defaultMinBackoff
+	}
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = max(defaultMaxBackoff, options.MinBackoff)
+	}
+	if options.RequestTimeout <= 0 {
+		options.RequestTimeout = defaultRequestTimeout
+	}
+	if options.Dialer == nil {
+		options.Dialer = websocket.DefaultDialer
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		options:  options,
+		url:      endpoint,
+		ctx:      ctx,
+		cancel:   cancel,
+		channels: make(map[string][]string),
+		handlers: make(map[string][]Handler),
+		chunks:   make(map[string]*chunkedMessage),
+	}, nil
+}
+
+// websocketURL returns the WebSocket URL of a server URL
+func websocketURL(raw string) (string, error) {
+	endpoint, err := url.Parse(raw)
+	if err != nil || endpoint.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q", raw)
+	}
+	switch endpoint.Scheme {
+	case "ws", "wss":
+	case "http":
+		endpoint.Scheme = "ws"
+	case "https":
+		endpoint.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("invalid server URL %q", raw)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = "/ws"
+	}
+	return endpoint.String(), nil
+}
+
+// Connect opens the connection. Once connected, the client reconnects whenever the
+// connection is lost, until Close is called.
+func (c *Client) Connect(ctx context.Context) error {
+	c.mutex.Lock()
+	closed, connected := c.closed, c.conn != nil
+	c.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if connected {
+		return nil
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	go c.run(conn)
+	return nil
+}
+
+// dial opens a connection, waits for the server's welcome and makes it the current connection
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	header := http.Header{}
+	for name, values := range c.options.Header {
+		header[name] = values
+	}
+	if c.options.Token != "" {
+		header.Set("Authorization", "Bearer "+c.options.Token)
+	}
+
+	conn, resp, err := c.options.Dialer.DialContext(ctx, c.url, header)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return nil, ErrUnauthorized
+		}
+		return nil, fmt.Errorf("error connecting to %s: %w", c.url, err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(c.options.RequestTimeout))
+	var welcome struct {
+		Event string `json:"event"`
+		Data  struct {
+			ClientID string `json:"client_id"`
+		} `json:"data"`
+	}
+	if err := conn.ReadJSON(&welcome); err != nil || welcome.Event != "connected" {
+		conn.Close()
+		if err == nil {
+			err = fmt.Errorf("unexpected %q event", welcome.Event)
+		}
+		return nil, fmt.Errorf("error waiting for the server's welcome: %w", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+	c.watchReads(conn)
+
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		conn.Close()
+		return nil, ErrClosed
+	}
+	c.conn, c.clientID = conn, welcome.Data.ClientID
+	c.mutex.Unlock()
+
+	if c.options.OnConnect != nil {
+		c.options.OnConnect(welcome.Data.ClientID)
+	}
+	return conn, nil
+}
+
+// watchReads extends the read deadline of a connection whenever the server pings it
+func (c *Client) watchReads(conn *websocket.Conn) {
+	if c.options.ReadTimeout <= 0 {
+		return
+	}
+	conn.SetReadDeadline(time.Now().Add(c.options.ReadTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(c.options.ReadTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+}
+
+// run reads connections until the client is closed, reconnecting when one is lost
+func (c *Client) run(conn *websocket.Conn) {
+	for conn != nil {
+		err := c.read(conn)
+		conn.Close()
+
+		c.mutex.Lock()
+		c.conn = nil
+		closed := c.closed
+		c.mutex.Unlock()
+		if closed {
+			return
+		}
+		if c.options.OnDisconnect != nil {
+			c.options.OnDisconnect(err)
+		}
+		conn = c.reconnect()
+	}
+}
+
+// reconnect dials until a connection is established, waiting longer after each failed
+// attempt, then joins the channels the client was in. It returns nil once the client is closed
+// or its token was rejected.
+func (c *Client) reconnect() *websocket.Conn {
+	backoff := c.options.MinBackoff
+	for {
+		// Jitter spreads the reconnections of clients that lost their server at the same time
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		conn, err := c.dial(c.ctx)
+		if errors.Is(err, ErrUnauthorized) {
+			if c.options.OnDisconnect != nil {
+				c.options.OnDisconnect(err)
+			}
+			return nil
+		}
+		if err != nil {
+			backoff = min(backoff*2, c.options.MaxBackoff)
+			continue
+		}
+
+		c.mutex.Lock()
+		channels := make(map[string][]string, len(c.channels))
+		for channel, events := range c.channels {
+			channels[channel] = events
+		}
+		c.mutex.Unlock()
+		for channel, events := range channels {
+			c.write(joinAction(channel, events))
+		}
+		return conn
+	}
+}
+
+// read handles the messages of a connection until reading fails
+func (c *Client) read(conn *websocket.Conn) error {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if c.options.ReadTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(c.options.ReadTimeout))
+		}
+		var message Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			continue
+		}
+		c.handle(message)
+	}
+}
+
+// handle delivers a received message to the awaited request and the registered handlers
+func (c *Client) handle(message Message) {
+	if message.Event == "chunk" {
+		if whole, ok := c.assemble(message); ok {
+			c.handle(whole)
+		}
+		return
+	}
+
+	c.mutex.Lock()
+	if c.waiter != nil && (message.Event == "joined_channel" || message.Event == "left_channel" || message.Event == "error") {
+		select {
+		case c.waiter <- message:
+		default:
+		}
+	}
+	handlers := append(append([]Handler(nil), c.handlers[message.Event]...), c.handlers["*"]...)
+	c.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+}
+
+// On registers a handler for an event, such as "order.created" or "error". Handlers of "*"
+// receive every event.
+func (c *Client) On(event string, handler Handler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.handlers[event] = append(c.handlers[event], handler)
+}
+
+// Handle registers a handler receiving the data of an event decoded into T. Events whose data
+// doesn't decode into T are skipped.
+func Handle[T any](c *Client, event string, handler func(Message, T)) {
+	c.On(event, func(message Message) {
+		var data T
+		if err := message.Decode(&data); err == nil {
+			handler(message, data)
+		}
+	})
+}
+
+// ID returns the server's ID of the current connection, which changes on reconnection. It
+// can be passed as exclude_client_id to broadcasts that shouldn't echo to this client.
+func (c *Client) ID() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.clientID
+}
+
+// Join joins a channel and waits for the server to confirm it. With events, only those
+// events of the channel are delivered. The channel is joined again after a reconnection.
+func (c *Client) Join(ctx context.Context, channel string, events ...string) error {
+	err := c.request(ctx, joinAction(channel, events), "joined_channel", channel)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.channels[channel] = events
+	c.mutex.Unlock()
+	return nil
+}
+
+// Leave leaves a channel and waits for the server to confirm it
+func (c *Client) Leave(ctx context.Context, channel string) error {
+	c.mutex.Lock()
+	delete(c.channels, channel)
+	c.mutex.Unlock()
+	return c.request(ctx, map[string]interface{}{"action": "leave_channel", "channel": channel}, "left_channel", channel)
+}
+
+// joinAction builds the join_channel action of a channel
+func joinAction(channel string, events []string) map[string]interface{} {
+	action := map[string]interface{}{"action": "join_channel", "channel": channel}
+	if len(events) > 0 {
+		action["events"] = events
+	}
+	return action
+}
+
+// request sends an action and waits for its confirmation event for the channel. The server
+// doesn't say which action an error answers, so an error arriving meanwhile fails the request.
+func (c *Client) request(ctx context.Context, action map[string]interface{}, confirmation, channel string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.RequestTimeout)
+		defer cancel()
+	}
+
+	c.requestMutex.Lock()
+	defer c.requestMutex.Unlock()
+
+	waiter := make(chan Message, 16)
+	c.mutex.Lock()
+	c.waiter = waiter
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		c.waiter = nil
+		c.mutex.Unlock()
+	}()
+
+	if err := c.write(action); err != nil {
+		return err
+	}
+	for {
+		select {
+		case message := <-waiter:
+			if err := message.Err(); err != nil {
+				return err
+			}
+			var data struct {
+				Channel string `json:"channel"`
+			}
+			if message.Event == confirmation && message.Decode(&data) == nil && data.Channel == channel {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return ErrClosed
+		}
+	}
+}
+
+// Send publishes an event to a channel. data is encoded as JSON, so it may be a struct.
+func (c *Client) Send(channel, event string, data interface{}) error {
+	return c.write(map[string]interface{}{"action": "send_message", "channel": channel, "event": event, "data": data})
+}
+
+// Whisper relays a client event, such as a typing indicator, to the other members of a
+// channel without going through Laravel. The client- prefix is added when event lacks it.
+func (c *Client) Whisper(channel, event string, data interface{}) error {
+	if !strings.HasPrefix(event, clientEventPrefix) {
+		event = clientEventPrefix + event
+	}
+	return c.Send(channel, event, data)
+}
+
+// SendAction sends any other action, such as mark_read, or a custom action the server
+// forwards to Laravel
+func (c *Client) SendAction(action string, fields map[string]interface{}) error {
+	message := map[string]interface{}{"action": action}
+	for key, value := range fields {
+		message[key] = value
+	}
+	return c.write(message)
+}
+
+// write sends an action on the current connection
+func (c *Client) write(action interface{}) error {
+	c.mutex.Lock()
+	conn, closed := c.conn, c.closed
+	c.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return conn.WriteJSON(action)
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	c.mutex.Unlock()
+	c.cancel()
+
+	if conn == nil {
+		return nil
+	}
+	c.writeMutex.Lock()
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.writeMutex.Unlock()
+	return conn.Close()
+}
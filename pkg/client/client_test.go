@@ -0,0 +1,337 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeServer speaks enough of the server's protocol for the client: it welcomes connections,
+// confirms joins and leaves, and records the actions it received
+type fakeServer struct {
+	*httptest.Server
+	t *testing.T
+
+	mutex       sync.Mutex
+	status      int // answers upgrade requests with this status when set
+	dials       int
+	conns       []*websocket.Conn
+	actions     [][]map[string]interface{} // by connection
+	writeMutex  sync.Mutex
+	connections chan int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{t: t, connections: make(chan int, 10)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.dials++
+	status := s.status
+	s.mutex.Unlock()
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	index := len(s.conns)
+	s.conns = append(s.conns, conn)
+	s.actions = append(s.actions, nil)
+	s.mutex.Unlock()
+
+	s.send(conn, map[string]interface{}{"event": "connected", "data": map[string]interface{}{"client_id": fmt.Sprintf("client-%d", index+1)}})
+	s.connections <- index
+	for {
+		var action map[string]interface{}
+		if err := conn.ReadJSON(&action); err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.actions[index] = append(s.actions[index], action)
+		s.mutex.Unlock()
+
+		switch action["action"] {
+		case "join_channel":
+			s.send(conn, map[string]interface{}{"event": "joined_channel", "data": map[string]interface{}{"channel": action["channel"]}})
+		case "leave_channel":
+			s.send(conn, map[string]interface{}{"event": "left_channel", "data": map[string]interface{}{"channel": action["channel"]}})
+		}
+	}
+}
+
+func (s *fakeServer) send(conn *websocket.Conn, event map[string]interface{}) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	conn.WriteJSON(event)
+}
+
+// broadcast sends an event on the latest connection
+func (s *fakeServer) broadcast(event map[string]interface{}) {
+	s.mutex.Lock()
+	conn := s.conns[len(s.conns)-1]
+	s.mutex.Unlock()
+	s.send(conn, event)
+}
+
+// drop closes the latest connection without a close frame
+func (s *fakeServer) drop() {
+	s.mutex.Lock()
+	conn := s.conns[len(s.conns)-1]
+	s.mutex.Unlock()
+	conn.UnderlyingConn().Close()
+}
+
+func (s *fakeServer) setStatus(status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status = status
+}
+
+func (s *fakeServer) dialCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.dials
+}
+
+// joins returns the channels joined on a connection, with their events, once count arrived
+func (s *fakeServer) joins(index, count int) map[string]interface{} {
+	s.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mutex.Lock()
+		joined := make(map[string]interface{})
+		for _, action := range s.actions[index] {
+			if action["action"] == "join_channel" {
+				joined[action["channel"].(string)] = action["events"]
+			}
+		}
+		s.mutex.Unlock()
+		if len(joined) >= count || time.Now().After(deadline) {
+			return joined
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitConnection waits for the server to accept the connection of the given index
+func (s *fakeServer) waitConnection(index int) {
+	s.t.Helper()
+	for {
+		select {
+		case accepted := <-s.connections:
+			if accepted == index {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			s.t.Fatalf("Timed out waiting for connection %d", index+1)
+		}
+	}
+}
+
+func newTestClient(t *testing.T, server *fakeServer, options Options) *Client {
+	t.Helper()
+	options.URL = server.URL
+	options.MinBackoff = 10 * time.Millisecond
+	options.MaxBackoff = 40 * time.Millisecond
+	c, err := New(options)
+	if err != nil {
+		t.Fatalf("Failed to create the client: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientRejoinsChannelsAfterReconnecting(t *testing.T) {
+	server := newFakeServer(t)
+	connected := make(chan string, 10)
+	disconnected := make(chan error, 10)
+	c := newTestClient(t, server, Options{
+		OnConnect:    func(clientID string) { connected <- clientID },
+		OnDisconnect: func(err error) { disconnected <- err },
+	})
+	received := make(chan Message, 10)
+	c.On("order.created", func(m Message) { received <- m })
+
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if id := <-connected; id != "client-1" || c.ID() != "client-1" {
+		t.Fatalf("Expected the first connection's client ID, got %s and %s", id, c.ID())
+	}
+	for _, channel := range []string{"orders", "chat", "news"} {
+		var err error
+		if channel == "orders" {
+			err = c.Join(ctx, channel, "order.created")
+		} else {
+			err = c.Join(ctx, channel)
+		}
+		if err != nil {
+			t.Fatalf("Failed to join %s: %v", channel, err)
+		}
+	}
+	if err := c.Leave(ctx, "chat"); err != nil {
+		t.Fatalf("Failed to leave chat: %v", err)
+	}
+
+	server.drop()
+	select {
+	case err := <-disconnected:
+		if err == nil {
+			t.Error("Expected the disconnection reported with the read error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the disconnection reported")
+	}
+	server.waitConnection(1)
+	if id := <-connected; id != "client-2" {
+		t.Errorf("Expected the new connection's client ID, got %s", id)
+	}
+
+	joined := server.joins(1, 2)
+	expected := map[string]interface{}{"orders": []interface{}{"order.created"}, "news": nil}
+	if !reflect.DeepEqual(joined, expected) {
+		t.Errorf("Expected the channels still joined to be joined again, got %v", joined)
+	}
+	if c.ID() != "client-2" {
+		t.Errorf("Expected the ID of the new connection, got %s", c.ID())
+	}
+
+	server.broadcast(map[string]interface{}{"event": "order.created", "channel": "orders", "data": map[string]interface{}{"id": 7}})
+	select {
+	case m := <-received:
+		var order struct{ ID int }
+		if m.Decode(&order); order.ID != 7 {
+			t.Errorf("Expected the order sent after reconnecting, got %s", m.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected events delivered after reconnecting")
+	}
+}
+
+func TestClientRetriesWhileServerIsDown(t *testing.T) {
+	server := newFakeServer(t)
+	c := newTestClient(t, server, Options{})
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	server.waitConnection(0)
+
+	server.setStatus(http.StatusServiceUnavailable)
+	server.drop()
+	deadline := time.Now().Add(2 * time.Second)
+	for server.dialCount() < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected repeated reconnection attempts, got %d dials", server.dialCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := c.Send("chat", "sent", nil); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected sending while reconnecting to fail with ErrNotConnected, got %v", err)
+	}
+
+	server.setStatus(0)
+	server.waitConnection(1)
+	deadline = time.Now().Add(2 * time.Second)
+	for c.Send("chat", "sent", nil) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected sending to work again once reconnected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClientStopsReconnectingWithRejectedToken(t *testing.T) {
+	server := newFakeServer(t)
+	disconnected := make(chan error, 10)
+	c := newTestClient(t, server, Options{Token: "expired", OnDisconnect: func(err error) { disconnected <- err }})
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	server.waitConnection(0)
+
+	server.setStatus(http.StatusUnauthorized)
+	server.drop()
+	// The lost connection is reported first, then the rejected token
+	for _, expected := range []error{nil, ErrUnauthorized} {
+		select {
+		case err := <-disconnected:
+			if expected != nil && !errors.Is(err, expected) {
+				t.Fatalf("Expected the client to give up with ErrUnauthorized, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the rejected token reported")
+		}
+	}
+
+	dials := server.dialCount()
+	time.Sleep(100 * time.Millisecond)
+	if server.dialCount() != dials {
+		t.Errorf("Expected no reconnection attempt after the token was rejected, got %d more", server.dialCount()-dials)
+	}
+}
+
+func TestClientCloseStopsReconnecting(t *testing.T) {
+	server := newFakeServer(t)
+	c := newTestClient(t, server, Options{})
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	server.waitConnection(0)
+
+	server.setStatus(http.StatusServiceUnavailable)
+	server.drop()
+	time.Sleep(30 * time.Millisecond)
+	c.Close()
+	dials := server.dialCount()
+	time.Sleep(100 * time.Millisecond)
+	if server.dialCount() != dials {
+		t.Errorf("Expected no reconnection attempt after Close, got %d more", server.dialCount()-dials)
+	}
+	if err := c.Connect(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected a closed client to refuse connecting, got %v", err)
+	}
+}
+
+func TestWebsocketURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"ws://localhost:8080/ws", "ws://localhost:8080/ws"},
+		{"http://localhost:8080", "ws://localhost:8080/ws"},
+		{"https://example.com/", "wss://example.com/ws"},
+		{"wss://example.com/socket", "wss://example.com/socket"},
+		{"ftp://example.com", ""},
+		{"localhost:8080", ""},
+	}
+	for _, tt := range tests {
+		got, err := websocketURL(tt.raw)
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("Expected %q to be refused, got %s", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("Expected %q to give %s, got %s and %v", tt.raw, tt.expected, got, err)
+		}
+	}
+}
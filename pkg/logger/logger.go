@@ -1,51 +1,298 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Log formats
+const (
+	// FormatText writes "2006/01/02 15:04:05 [INFO] message key=value" lines
+	FormatText = "text"
+	// FormatJSON writes one JSON object per line, with the fields as top-level keys
+	FormatJSON = "json"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels, from most to least verbose
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// levelNames are the names levels are written with
+var levelNames = map[Level]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+	LevelFatal: "FATAL",
+}
+
+// String returns the level's name, e.g. "INFO"
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error"
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// Fields are structured context attached to a log entry
+type Fields map[string]interface{}
+
 // LogEntry represents a single log entry
 type LogEntry struct {
 	Timestamp string `json:"timestamp"`
-	Level     string `json:"level"`
-	Message   string `json:"message"`
+	// Time is Timestamp with nanoseconds and the zone, for polling with LogFilter.Since
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+	Fields  Fields    `json:"fields,omitempty"`
+
+	level Level
 }
 
-// Logger wraps the standard logger with additional functionality
+// LogFilter selects recent log entries
+type LogFilter struct {
+	// MinLevel excludes entries less severe than it
+	MinLevel Level
+	// Since excludes entries logged at or before it, so polling with the last entry's Time
+	// returns only newer ones (zero keeps every entry)
+	Since time.Time
+	// Limit keeps only the most recent entries (0 keeps every entry)
+	Limit int
+	// Pattern keeps only entries whose message or fields match it (nil keeps every entry)
+	Pattern *regexp.Regexp
+}
+
+// Matches reports whether an entry passes the filter's level, time and pattern
+func (f LogFilter) Matches(entry LogEntry) bool {
+	if entry.level < f.MinLevel {
+		return false
+	}
+	if !f.Since.IsZero() && !entry.Time.After(f.Since) {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(entry.Message+formatFields(entry.Fields))
+}
+
+// Options configures a logger
+type Options struct {
+	// Level is the least severe level written
+	Level Level
+	// Format is FormatText (the default) or FormatJSON
+	Format string
+	// Output is "stdout" (the default), "stderr", "syslog" or a file path logs are appended to
+	Output string
+}
+
+// Logger writes leveled log entries as text or JSON, and keeps the most recent ones for the
+// logs API
 type Logger struct {
-	*log.Logger
-	debug      bool
+	out    io.Writer
+	closer io.Closer
+	level  Level
+	format string
+	// writeMutex serializes writes, so concurrent entries never interleave
+	writeMutex sync.Mutex
+
 	recentLogs []LogEntry
 	logMutex   sync.RWMutex
 	maxLogs    int
+	hook       func(level, message string)
+	// subscribers receive every logged entry, see Subscribe
+	subscribers map[chan LogEntry]struct{}
 }
 
-// New creates a new logger instance
+// New creates a new text logger writing to stdout, at debug level if debug is set and info
+// level otherwise
 func New(debug bool) *Logger {
+	level := LevelInfo
+	if debug {
+		level = LevelDebug
+	}
+	return newLogger(os.Stdout, nil, level, FormatText)
+}
+
+// NewWithOptions creates a logger with the given level, format and output. Close it on shutdown
+// to release a log file or syslog connection.
+func NewWithOptions(options Options) (*Logger, error) {
+	if options.Format == "" {
+		options.Format = FormatText
+	}
+	if options.Format != FormatText && options.Format != FormatJSON {
+		return nil, fmt.Errorf("unknown log format %q", options.Format)
+	}
+
+	out, closer, err := openOutput(options.Output)
+	if err != nil {
+		return nil, err
+	}
+	return newLogger(out, closer, options.Level, options.Format), nil
+}
+
+func newLogger(out io.Writer, closer io.Closer, level Level, format string) *Logger {
 	return &Logger{
-		Logger:     log.New(os.Stdout, "", log.LstdFlags),
-		debug:      debug,
+		out:        out,
+		closer:     closer,
+		level:      level,
+		format:     format,
 		recentLogs: make([]LogEntry, 0, 100),
 		maxLogs:    100, // Keep last 100 log entries
 	}
 }
 
+// Close releases the log file or syslog connection, if any
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// SetHook registers a function that receives every logged entry, e.g. to forward logs to the
+// Windows event log
+func (l *Logger) SetHook(hook func(level, message string)) {
+	l.logMutex.Lock()
+	defer l.logMutex.Unlock()
+	l.hook = hook
+}
+
+// Enabled reports whether entries of the given level are written, so callers can skip
+// building expensive fields
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// log writes an entry and records it in the recent logs
+func (l *Logger) log(level Level, message string, fields Fields) {
+	if !l.Enabled(level) {
+		return
+	}
+	now := time.Now()
+
+	var line bytes.Buffer
+	if l.format == FormatJSON {
+		writeJSON(&line, now, level, message, fields)
+	} else {
+		fmt.Fprintf(&line, "%s [%s] %s%s", now.Format("2006/01/02 15:04:05"), level, message, formatFields(fields))
+	}
+	line.WriteByte('\n')
+
+	l.writeMutex.Lock()
+	l.out.Write(line.Bytes())
+	l.writeMutex.Unlock()
+
+	l.addLog(now, level, message, fields)
+}
+
+// writeJSON writes an entry as a JSON object: time, level and msg first, then the fields in key
+// order. Errors are written as their message, and fields that can't be encoded as a string
+// describing them.
+func writeJSON(b *bytes.Buffer, now time.Time, level Level, message string, fields Fields) {
+	encode := func(value interface{}) []byte {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprintf("%v", value))
+		}
+		return data
+	}
+
+	b.WriteString(`{"time":`)
+	b.Write(encode(now.Format(time.RFC3339Nano)))
+	b.WriteString(`,"level":`)
+	b.Write(encode(strings.ToLower(level.String())))
+	b.WriteString(`,"msg":`)
+	b.Write(encode(message))
+	for _, key := range sortedKeys(fields) {
+		b.WriteByte(',')
+		b.Write(encode(key))
+		b.WriteByte(':')
+		b.Write(encode(fields[key]))
+	}
+	b.WriteByte('}')
+}
+
+// sortedKeys returns the keys of fields in order
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFields renders fields as " key=value" pairs in key order
+func formatFields(fields Fields) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, key := range sortedKeys(fields) {
+		value := fmt.Sprint(fields[key])
+		if strings.ContainsAny(value, " \t\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", key, value)
+	}
+	return b.String()
+}
+
 // addLog adds a log entry to recent logs
-func (l *Logger) addLog(level, message string) {
+func (l *Logger) addLog(now time.Time, level Level, message string, fields Fields) {
 	l.logMutex.Lock()
 	defer l.logMutex.Unlock()
 
+	if l.hook != nil {
+		l.hook(level.String(), message+formatFields(fields))
+	}
+
 	entry := LogEntry{
-		Timestamp: time.Now().Format("2006-01-02 15:04:05"),
-		Level:     level,
+		Timestamp: now.Format("2006-01-02 15:04:05"),
+		Level:     level.String(),
 		Message:   message,
+		Fields:    fields,
+		Time:      now,
+		level:     level,
 	}
 
 	l.recentLogs = append(l.recentLogs, entry)
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- entry:
+		default: // a subscriber that can't keep up misses entries rather than blocking logging
+		}
+	}
 
 	// Keep only the most recent logs
 	if len(l.recentLogs) > l.maxLogs {
@@ -64,67 +311,148 @@ func (l *Logger) GetRecentLogs() []LogEntry {
 	return logsCopy
 }
 
+// Subscribe returns a channel receiving every entry logged from now on, buffering up to buffer
+// entries, and a function ending the subscription. Entries are dropped for subscribers whose
+// buffer is full. The channel is closed when the subscription ends.
+func (l *Logger) Subscribe(buffer int) (<-chan LogEntry, func()) {
+	l.logMutex.Lock()
+	defer l.logMutex.Unlock()
+
+	subscriber := make(chan LogEntry, buffer)
+	if l.subscribers == nil {
+		l.subscribers = make(map[chan LogEntry]struct{})
+	}
+	l.subscribers[subscriber] = struct{}{}
+
+	return subscriber, func() {
+		l.logMutex.Lock()
+		defer l.logMutex.Unlock()
+		if _, ok := l.subscribers[subscriber]; ok {
+			delete(l.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// EndSubscriptions ends every subscription, e.g. so log streams don't hold up a shutdown
+func (l *Logger) EndSubscriptions() {
+	l.logMutex.Lock()
+	defer l.logMutex.Unlock()
+
+	for subscriber := range l.subscribers {
+		close(subscriber)
+	}
+	l.subscribers = nil
+}
+
+// QueryLogs returns the recent log entries matching a filter, oldest first
+func (l *Logger) QueryLogs(filter LogFilter) []LogEntry {
+	l.logMutex.RLock()
+	defer l.logMutex.RUnlock()
+
+	logs := make([]LogEntry, 0, len(l.recentLogs))
+	for _, entry := range l.recentLogs {
+		if filter.Matches(entry) {
+			logs = append(logs, entry)
+		}
+	}
+	if filter.Limit > 0 && len(logs) > filter.Limit {
+		logs = logs[len(logs)-filter.Limit:]
+	}
+	return logs
+}
+
+// sprintf formats a message, leaving messages without arguments untouched
+func sprintf(format string, args []interface{}) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
 // Debug logs a debug message if debug mode is enabled
 func (l *Logger) Debug(format string, args ...interface{}) {
-	if l.debug {
-		msg := format
-		if len(args) > 0 {
-			msg = fmt.Sprintf(format, args...)
-		}
-		l.Printf("[DEBUG] "+format, args...)
-		l.addLog("DEBUG", msg)
+	if l.Enabled(LevelDebug) {
+		l.log(LevelDebug, sprintf(format, args), nil)
 	}
 }
 
 // Info logs an info message
 func (l *Logger) Info(format string, args ...interface{}) {
-	msg := format
-	if len(args) > 0 {
-		msg = fmt.Sprintf(format, args...)
-	}
-	l.Printf("[INFO] "+format, args...)
-	l.addLog("INFO", msg)
+	l.log(LevelInfo, sprintf(format, args), nil)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, args ...interface{}) {
-	msg := format
-	if len(args) > 0 {
-		msg = fmt.Sprintf(format, args...)
-	}
-	l.Printf("[WARN] "+format, args...)
-	l.addLog("WARN", msg)
+	l.log(LevelWarn, sprintf(format, args), nil)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, args ...interface{}) {
-	msg := format
-	if len(args) > 0 {
-		msg = fmt.Sprintf(format, args...)
-	}
-	l.Printf("[ERROR] "+format, args...)
-	l.addLog("ERROR", msg)
+	l.log(LevelError, sprintf(format, args), nil)
 }
 
 // Fatal logs a fatal message and exits
 func (l *Logger) Fatal(format string, args ...interface{}) {
-	l.Printf("[FATAL] "+format, args...)
+	l.log(LevelFatal, sprintf(format, args), nil)
+	l.Close()
 	os.Exit(1)
 }
 
-// ClientConnected logs a client connection
+// Millis converts a duration to fractional milliseconds, the unit of timing fields
+func Millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// With returns an entry logging with the given fields, e.g.
+// logger.With(logger.Fields{"channel": name, "clients": n}).Debug("Broadcast queued")
+func (l *Logger) With(fields Fields) *Entry {
+	return &Entry{logger: l, fields: fields}
+}
+
+// Entry logs messages with structured fields
+type Entry struct {
+	logger *Logger
+	fields Fields
+}
+
+// Debug logs a debug message with the entry's fields
+func (e *Entry) Debug(message string) {
+	e.logger.log(LevelDebug, message, e.fields)
+}
+
+// Info logs an info message with the entry's fields
+func (e *Entry) Info(message string) {
+	e.logger.log(LevelInfo, message, e.fields)
+}
+
+// Warn logs a warning message with the entry's fields
+func (e *Entry) Warn(message string) {
+	e.logger.log(LevelWarn, message, e.fields)
+}
+
+// Error logs an error message with the entry's fields
+func (e *Entry) Error(message string) {
+	e.logger.log(LevelError, message, e.fields)
+}
+
+// ClientConnected logs a client connection, with the "client_connected" event field
 func (l *Logger) ClientConnected(clientID, remoteAddr, userAgent string) {
-	l.Info("Client connected: %s from %s (User-Agent: %s)", clientID, remoteAddr, userAgent)
+	l.With(Fields{"event": "client_connected", "client_id": clientID, "remote_addr": remoteAddr}).
+		Info(fmt.Sprintf("Client connected: %s from %s (User-Agent: %s)", clientID, remoteAddr, userAgent))
 }
 
-// ClientDisconnected logs a client disconnection
+// ClientDisconnected logs a client disconnection, with the "client_disconnected" event field
 func (l *Logger) ClientDisconnected(clientID, username, remoteAddr string) {
-	l.Info("Client %s (%s) disconnected from %s", clientID, username, remoteAddr)
+	l.With(Fields{"event": "client_disconnected", "client_id": clientID, "remote_addr": remoteAddr}).
+		Info(fmt.Sprintf("Client %s (%s) disconnected from %s", clientID, username, remoteAddr))
 }
 
-// ClientAuthenticated logs successful authentication
+// ClientAuthenticated logs successful authentication, with the "client_authenticated" event
+// field
 func (l *Logger) ClientAuthenticated(clientID, username, userID string) {
-	l.Info("✅ Client %s authenticated successfully as user %s (%s)", clientID, username, userID)
+	l.With(Fields{"event": "client_authenticated", "client_id": clientID, "user_id": userID}).
+		Info(fmt.Sprintf("✅ Client %s authenticated successfully as user %s (%s)", clientID, username, userID))
 }
 
 // ClientAuthenticationFailed logs failed authentication
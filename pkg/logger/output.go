@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// openOutput opens a log destination: "stdout" or empty, "stderr", "syslog" or a file path
+// logs are appended to. The closer is nil for the standard streams.
+func openOutput(output string) (io.Writer, io.Closer, error) {
+	switch output {
+	case "", "stdout":
+		return os.Stdout, nil, nil
+	case "stderr":
+		return os.Stderr, nil, nil
+	case "syslog":
+		writer, err := openSyslog()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error connecting to syslog: %w", err)
+		}
+		return writer, writer, nil
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening log file: %w", err)
+	}
+	return file, file, nil
+}
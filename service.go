@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// serviceName is the name the server is registered under as an operating system service
+const serviceName = "socket-server"
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Manage the Windows service",
+	Long: `Install, remove or run socket-server as a Windows service.
+Flags given after "install --" are stored and passed to the server when the service starts.`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install [-- server flags]",
+	Short: "Install socket-server as an automatically started Windows service",
+	RunE:  installService,
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the socket-server Windows service",
+	RunE:  uninstallService,
+}
+
+var serviceRunCmd = &cobra.Command{
+	Use:                "run [server flags]",
+	Short:              "Run the server under the Windows service manager (used by the service itself)",
+	DisableFlagParsing: true,
+	RunE:               runService,
+}
+
+func init() {
+	serviceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd, serviceRunCmd)
+	rootCmd.AddCommand(serviceCmd)
+}
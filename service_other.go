@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// errServiceUnsupported is returned by the service commands on non-Windows platforms
+var errServiceUnsupported = errors.New("the service command is only supported on Windows; use systemd or a container supervisor instead")
+
+func installService(cmd *cobra.Command, args []string) error {
+	return errServiceUnsupported
+}
+
+func uninstallService(cmd *cobra.Command, args []string) error {
+	return errServiceUnsupported
+}
+
+func runService(cmd *cobra.Command, args []string) error {
+	return errServiceUnsupported
+}
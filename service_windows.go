@@ -0,0 +1,157 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Event IDs written to the Windows event log
+const (
+	eventIDInfo    = 1
+	eventIDWarning = 2
+	eventIDError   = 3
+)
+
+// installService registers the service with the service control manager and its event source
+func installService(cmd *cobra.Command, args []string) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error resolving executable path: %w", err)
+	}
+	exePath, err = filepath.Abs(exePath)
+	if err != nil {
+		return fmt.Errorf("error resolving executable path: %w", err)
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", serviceName)
+	}
+
+	s, err := m.CreateService(serviceName, exePath, mgr.Config{
+		DisplayName: "Socket Server",
+		Description: "WebSocket server for Laravel integration",
+		StartType:   mgr.StartAutomatic,
+	}, append([]string{"service", "run"}, args...)...)
+	if err != nil {
+		return fmt.Errorf("error creating service: %w", err)
+	}
+	defer s.Close()
+
+	if err := eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete()
+		return fmt.Errorf("error registering event log source: %w", err)
+	}
+
+	fmt.Printf("Service %s installed\n", serviceName)
+	return nil
+}
+
+// uninstallService removes the service and its event source
+func uninstallService(cmd *cobra.Command, args []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", serviceName)
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("error deleting service: %w", err)
+	}
+	if err := eventlog.Remove(serviceName); err != nil {
+		return fmt.Errorf("error removing event log source: %w", err)
+	}
+
+	fmt.Printf("Service %s uninstalled\n", serviceName)
+	return nil
+}
+
+// runService runs the server under the service control manager
+func runService(cmd *cobra.Command, args []string) error {
+	if err := rootCmd.ParseFlags(args); err != nil {
+		return err
+	}
+
+	// Services start in the system directory; resolve relative paths next to the executable
+	if exePath, err := os.Executable(); err == nil {
+		os.Chdir(filepath.Dir(exePath))
+	}
+
+	elog, err := eventlog.Open(serviceName)
+	if err != nil {
+		return fmt.Errorf("error opening event log: %w", err)
+	}
+	defer elog.Close()
+
+	return svc.Run(serviceName, &windowsService{elog: elog})
+}
+
+// windowsService adapts the server to the service control manager
+type windowsService struct {
+	elog *eventlog.Log
+}
+
+// Execute implements svc.Handler
+func (ws *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	stop := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	go func() {
+		serve(stop, ws.logEvent)
+		close(stopped)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case <-stopped:
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32((30 * time.Second).Milliseconds())}
+				stop <- syscall.SIGTERM
+				<-stopped
+				return false, 0
+			}
+		}
+	}
+}
+
+// logEvent forwards warnings and errors to the Windows event log, along with lifecycle messages
+func (ws *windowsService) logEvent(level, message string) {
+	switch level {
+	case "ERROR", "FATAL":
+		ws.elog.Error(eventIDError, message)
+	case "WARN":
+		ws.elog.Warning(eventIDWarning, message)
+	case "INFO":
+		ws.elog.Info(eventIDInfo, message)
+	}
+}
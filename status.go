@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"socket-server/internal/config"
+	"socket-server/internal/services"
+)
+
+// startupReport is the machine-readable status written once the server is ready, for
+// supervisors and provisioning tools
+type startupReport struct {
+	Status     string                 `json:"status"`
+	PID        int                    `json:"pid"`
+	NodeID     string                 `json:"node_id"`
+	StartedAt  string                 `json:"started_at"`
+	Listeners  []map[string]string    `json:"listeners"`
+	Dispatcher map[string]interface{} `json:"dispatcher"`
+	Cluster    map[string]interface{} `json:"cluster"`
+	Config     map[string]interface{} `json:"config"`
+}
+
+// buildStartupReport describes the resolved configuration and listeners. adminAddr is the
+// address of the admin listener, empty when the API is served on the main listener. Secrets
+// are never included, only whether they are set.
+func buildStartupReport(cfg *config.Config, listenAddr, adminAddr string) startupReport {
+	dispatchMode := "immediate"
+	if cfg.DispatchBatchInterval > 0 {
+		dispatchMode = "batched"
+	}
+
+	dispatcher := map[string]interface{}{
+		"type":              "artisan",
+		"mode":              dispatchMode,
+		"php_binary":        cfg.PHPBinary,
+		"command":           cfg.LaravelCmd,
+		"working_dir":       cfg.WorkingDir,
+		"batch_interval_ms": cfg.DispatchBatchInterval.Milliseconds(),
+		"batch_size":        cfg.DispatchBatchSize,
+	}
+	switch cfg.DispatchStrategy {
+	case services.DispatchStrategyWorkerPool:
+		dispatcher["type"] = services.DispatchStrategyWorkerPool
+		dispatcher["workers"] = cfg.DispatchWorkers
+	case services.DispatchStrategyHTTP:
+		dispatcher["type"] = services.DispatchStrategyHTTP
+		dispatcher["url"] = cfg.DispatchCallbackURL
+		dispatcher["signed"] = cfg.DispatchCallbackSecret != ""
+		delete(dispatcher, "php_binary")
+		delete(dispatcher, "command")
+		delete(dispatcher, "working_dir")
+	}
+
+	dynamicConfig := "disabled"
+	if cfg.ConfigBackend != "" {
+		dynamicConfig = cfg.ConfigBackend
+	}
+
+	clusterMode := "standalone"
+	if cfg.ClusterRedisURL != "" {
+		clusterMode = "redis"
+	}
+
+	listeners := []map[string]string{
+		{"name": listenerName(cfg), "address": listenAddr, "websocket_path": "/ws", "api_prefix": "/api"},
+	}
+	if adminAddr != "" {
+		delete(listeners[0], "api_prefix")
+		listeners = append(listeners, map[string]string{"name": "admin", "address": adminAddr, "api_prefix": "/api"})
+	}
+
+	return startupReport{
+		Status:     "ready",
+		PID:        os.Getpid(),
+		NodeID:     cfg.NodeID,
+		StartedAt:  time.Now().Format(time.RFC3339),
+		Listeners:  listeners,
+		Dispatcher: dispatcher,
+		Cluster: map[string]interface{}{
+			"mode":           clusterMode,
+			"dynamic_config": dynamicConfig,
+			"pod":            cfg.PodName,
+			"namespace":      cfg.PodNamespace,
+		},
+		Config: map[string]interface{}{
+			"port":                   cfg.Port,
+			"jwt_secret_set":         cfg.JWTSecret != "",
+			"http_token_set":         cfg.HTTPToken != "",
+			"temp_dir":               cfg.TempDir,
+			"web_dir":                cfg.WebDir,
+			"debug":                  cfg.Debug,
+			"dm_history_size":        cfg.DirectHistorySize,
+			"channel_history_size":   cfg.ChannelHistorySize,
+			"reliable_channels":      cfg.ReliableChannels,
+			"channel_rate_limits":    cfg.ChannelRateLimits,
+			"shutdown_grace_seconds": int(cfg.ShutdownGrace.Seconds()),
+		},
+	}
+}
+
+// listenerName reports whether the main listener serves plain HTTP or TLS
+func listenerName(cfg *config.Config) string {
+	if cfg.TLSEnabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// writeStartupReport writes the report as a single JSON line to the given file descriptor
+// and/or file. The file is written atomically so readers never see a partial report.
+func writeStartupReport(report startupReport, fd int, filename string) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("error encoding status report: %w", err)
+	}
+	data = append(data, '\n')
+
+	if fd >= 0 {
+		file := os.NewFile(uintptr(fd), "status-fd")
+		if file == nil {
+			return fmt.Errorf("invalid status file descriptor %d", fd)
+		}
+		if _, err := file.Write(data); err != nil {
+			return fmt.Errorf("error writing status to fd %d: %w", fd, err)
+		}
+	}
+
+	if filename != "" {
+		tmpFile := filename + ".tmp"
+		if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+			return fmt.Errorf("error writing status file: %w", err)
+		}
+		if err := os.Rename(tmpFile, filename); err != nil {
+			return fmt.Errorf("error writing status file: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+// Package conformance is an executable check of the protocol guarantees documented in
+// docs/DELIVERY_GUARANTEES.md. It runs against a live server build:
+//
+//	make conformance
+//
+// or, against a server that is already running:
+//
+//	CONFORMANCE_URL=http://127.0.0.1:8080 CONFORMANCE_API_TOKEN=... CONFORMANCE_JWT_SECRET=... \
+//		go test -tags conformance -v ./tests/conformance/
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// Event is a server message as seen by a client
+type Event struct {
+	ID        string                 `json:"id"`
+	Channel   string                 `json:"channel"`
+	Event     string                 `json:"event"`
+	Data      map[string]interface{} `json:"data"`
+	UserID    string                 `json:"user_id"`
+	Sequence  uint64                 `json:"sequence"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Client is a minimal protocol client that speaks the WebSocket protocol the way a browser SDK does
+type Client struct {
+	ID     string
+	conn   *websocket.Conn
+	events chan Event
+	done   chan struct{}
+}
+
+// Dial connects to the server's /ws endpoint and waits for the welcome message
+func Dial(baseURL string) (*Client, error) {
+	wsURL := strings.Replace(strings.TrimSuffix(baseURL, "/"), "http", "ws", 1) + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", wsURL, err)
+	}
+
+	client := &Client{conn: conn, events: make(chan Event, 1024), done: make(chan struct{})}
+	go client.readLoop()
+
+	welcome, err := client.Expect("connected", 5*time.Second)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client.ID, _ = welcome.Data["client_id"].(string)
+	return client, nil
+}
+
+// readLoop decodes server messages until the connection closes. Pings are answered by the
+// websocket library's default handler.
+func (c *Client) readLoop() {
+	defer close(c.done)
+	for {
+		var event Event
+		if err := c.conn.ReadJSON(&event); err != nil {
+			return
+		}
+		c.events <- event
+	}
+}
+
+// Send writes a client action such as join_channel or mark_read
+func (c *Client) Send(action string, fields map[string]interface{}) error {
+	message := map[string]interface{}{"action": action}
+	for key, value := range fields {
+		message[key] = value
+	}
+	return c.conn.WriteJSON(message)
+}
+
+// Expect waits for the next event with the given name, skipping any other events. An error
+// event fails the wait, so rejected actions surface immediately.
+func (c *Client) Expect(name string, timeout time.Duration) (Event, error) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case event := <-c.events:
+			if event.Event == name {
+				return event, nil
+			}
+			if event.Event == "error" && name != "error" {
+				return event, fmt.Errorf("server error while waiting for %s: %v", name, event.Data["error"])
+			}
+		case <-c.done:
+			return Event{}, fmt.Errorf("connection closed while waiting for %s", name)
+		case <-deadline:
+			return Event{}, fmt.Errorf("timed out waiting for %s", name)
+		}
+	}
+}
+
+// Authenticate sends a JWT. The server doesn't acknowledge successful authentication, so
+// callers follow up with an action that requires it.
+func (c *Client) Authenticate(token string) error {
+	return c.Send("authenticate", map[string]interface{}{"token": token})
+}
+
+// Join joins a channel and waits for the confirmation
+func (c *Client) Join(channel string) error {
+	if err := c.Send("join_channel", map[string]interface{}{"channel": channel}); err != nil {
+		return err
+	}
+	for {
+		event, err := c.Expect("joined_channel", 5*time.Second)
+		if err != nil {
+			return err
+		}
+		if event.Data["channel"] == channel {
+			return nil
+		}
+	}
+}
+
+// Leave leaves a channel and waits for the confirmation
+func (c *Client) Leave(channel string) error {
+	if err := c.Send("leave_channel", map[string]interface{}{"channel": channel}); err != nil {
+		return err
+	}
+	_, err := c.Expect("left_channel", 5*time.Second)
+	return err
+}
+
+// Close closes the connection the way a browser tab does
+func (c *Client) Close() {
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.conn.Close()
+}
+
+// API calls the server's authenticated REST API
+type API struct {
+	BaseURL string
+	Token   string
+	client  http.Client
+}
+
+// NewAPI creates an API client for the server at baseURL
+func NewAPI(baseURL, token string) *API {
+	return &API{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token, client: http.Client{Timeout: 10 * time.Second}}
+}
+
+// Broadcast sends a broadcast request, e.g. {"channel": "news", "event": "update", "data": {...}}
+func (a *API) Broadcast(payload map[string]interface{}) error {
+	_, err := a.do(http.MethodPost, "/api/broadcast", payload, nil)
+	return err
+}
+
+// Get decodes the JSON response of a GET request into v and returns the status code
+func (a *API) Get(path string, v interface{}) (int, error) {
+	return a.do(http.MethodGet, path, nil, v)
+}
+
+func (a *API) do(method, path string, body, v interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, a.BaseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error calling %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s %s returned %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if v != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+	}
+	return resp.StatusCode, nil
+}
+
+// Token signs a JWT for userID the way the Laravel integration does
+func Token(secret, userID string) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":  userID,
+		"username": userID,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(secret))
+}
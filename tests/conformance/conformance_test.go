@@ -0,0 +1,374 @@
+//go:build conformance
+
+package conformance
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// server describes the server build under test
+type server struct {
+	url       string
+	api       *API
+	jwtSecret string
+}
+
+func setup(t *testing.T) *server {
+	t.Helper()
+	url := os.Getenv("CONFORMANCE_URL")
+	if url == "" {
+		url = "http://127.0.0.1:8080"
+	}
+	return &server{
+		url:       url,
+		api:       NewAPI(url, os.Getenv("CONFORMANCE_API_TOKEN")),
+		jwtSecret: os.Getenv("CONFORMANCE_JWT_SECRET"),
+	}
+}
+
+// uniqueName keeps tests independent when the suite runs repeatedly against one server
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s.%d", prefix, time.Now().UnixNano())
+}
+
+// uniqueUser returns a user ID unique to this run that direct channel names spell unescaped
+func uniqueUser(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+func (s *server) dial(t *testing.T) *Client {
+	t.Helper()
+	client, err := Dial(s.url)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func (s *server) dialAs(t *testing.T, userID string) *Client {
+	t.Helper()
+	token, err := Token(s.jwtSecret, userID)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	client := s.dial(t)
+	if err := client.Authenticate(token); err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	return client
+}
+
+// waitForDisconnect waits until the server has cleaned up a closed connection
+func (s *server) waitForDisconnect(t *testing.T, clientID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ := s.api.Get("/api/clients/"+clientID, nil)
+		if status == 404 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Client %s is still connected", clientID)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func (s *server) channelMembers(t *testing.T, channel string) int {
+	t.Helper()
+	var response struct {
+		Total int `json:"total"`
+	}
+	status, err := s.api.Get("/api/channels/"+channel+"/clients", &response)
+	if status == 404 {
+		return 0 // empty channels may be removed
+	}
+	if err != nil {
+		t.Fatalf("Failed to list channel members: %v", err)
+	}
+	return response.Total
+}
+
+// Guarantee: messages broadcast to a channel reach each subscriber in publish order, and every
+// channel message carries a per-channel sequence increasing by one
+func TestOrderingSequentialBroadcasts(t *testing.T) {
+	s := setup(t)
+	channel := uniqueName("conformance.order")
+	client := s.dial(t)
+	if err := client.Join(channel); err != nil {
+		t.Fatal(err)
+	}
+
+	const count = 50
+	for i := 0; i < count; i++ {
+		if err := s.api.Broadcast(map[string]interface{}{"channel": channel, "event": "tick", "data": map[string]interface{}{"n": i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var lastSequence uint64
+	for i := 0; i < count; i++ {
+		event, err := client.Expect("tick", 5*time.Second)
+		if err != nil {
+			t.Fatalf("Message %d: %v", i, err)
+		}
+		if n := int(event.Data["n"].(float64)); n != i {
+			t.Fatalf("Expected message %d, got %d", i, n)
+		}
+		if i > 0 && event.Sequence != lastSequence+1 {
+			t.Fatalf("Expected sequence %d, got %d", lastSequence+1, event.Sequence)
+		}
+		lastSequence = event.Sequence
+	}
+}
+
+// Guarantee: concurrent broadcasts are serialized per channel, so every subscriber sees the same
+// order, with no gaps in the sequence
+func TestOrderingConcurrentBroadcasts(t *testing.T) {
+	s := setup(t)
+	channel := uniqueName("conformance.concurrent")
+	first, second := s.dial(t), s.dial(t)
+	for _, client := range []*Client{first, second} {
+		if err := client.Join(channel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const count = 20
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if err := s.api.Broadcast(map[string]interface{}{"channel": channel, "event": "tick", "data": map[string]interface{}{"n": n}}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	received := func(client *Client) []uint64 {
+		sequences := make([]uint64, 0, count)
+		for i := 0; i < count; i++ {
+			event, err := client.Expect("tick", 5*time.Second)
+			if err != nil {
+				t.Fatalf("Message %d: %v", i, err)
+			}
+			sequences = append(sequences, event.Sequence)
+		}
+		return sequences
+	}
+	firstOrder, secondOrder := received(first), received(second)
+
+	if !sort.SliceIsSorted(firstOrder, func(i, j int) bool { return firstOrder[i] < firstOrder[j] }) {
+		t.Errorf("Expected sequences in delivery order, got %v", firstOrder)
+	}
+	if firstOrder[count-1]-firstOrder[0] != count-1 {
+		t.Errorf("Expected %d consecutive sequences, got %v", count, firstOrder)
+	}
+	for i := range firstOrder {
+		if firstOrder[i] != secondOrder[i] {
+			t.Fatalf("Subscribers disagree on order: %v vs %v", firstOrder, secondOrder)
+		}
+	}
+}
+
+// Guarantee: in ACK-mode channels, read cursors are broadcast to members as read_receipt
+// events and never move backwards. Requires RELIABLE_CHANNELS to include conformance.reliable.*
+func TestAckReadReceipts(t *testing.T) {
+	s := setup(t)
+	channel := uniqueName("conformance.reliable")
+	reader, member := s.dialAs(t, uniqueUser("reader")), s.dialAs(t, uniqueUser("member"))
+	for _, client := range []*Client{reader, member} {
+		if err := client.Join(channel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := s.api.Broadcast(map[string]interface{}{"channel": channel, "event": "note", "data": map[string]interface{}{"n": i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var last Event
+	for i := 0; i < 3; i++ {
+		event, err := reader.Expect("note", 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = event
+	}
+
+	reader.Send("mark_read", map[string]interface{}{"channel": channel, "read_up_to": last.Sequence})
+	receipt, err := member.Expect("read_receipt", 5*time.Second)
+	if err != nil {
+		if err.Error() == "server error while waiting for read_receipt: Read receipts are not enabled for this channel" {
+			t.Skip("RELIABLE_CHANNELS doesn't include conformance.reliable.*")
+		}
+		t.Fatal(err)
+	}
+	if uint64(receipt.Data["read_up_to"].(float64)) != last.Sequence {
+		t.Errorf("Expected receipt up to %d, got %v", last.Sequence, receipt.Data["read_up_to"])
+	}
+
+	// Moving the cursor backwards is ignored: no receipt is sent
+	reader.Send("mark_read", map[string]interface{}{"channel": channel, "read_up_to": last.Sequence - 1})
+	if event, err := member.Expect("read_receipt", time.Second); err == nil {
+		t.Errorf("Expected no receipt for an older cursor, got %v", event.Data)
+	}
+}
+
+// Guarantee: a dropped session can be resumed with its single-use resume token, restoring the
+// user and its channels. Requires RESUME_SECRETS.
+func TestReconnectResume(t *testing.T) {
+	s := setup(t)
+	channel := uniqueName("conformance.resume")
+	client := s.dialAs(t, uniqueUser("resumer"))
+	session, err := client.Expect("session", 2*time.Second)
+	if err != nil {
+		t.Skip("Session resumption is disabled (RESUME_SECRETS not set)")
+	}
+	if err := client.Join(channel); err != nil {
+		t.Fatal(err)
+	}
+	token := session.Data["resume_token"].(string)
+	client.Close()
+	s.waitForDisconnect(t, client.ID)
+
+	resumed := s.dial(t)
+	resumed.Send("resume", map[string]interface{}{"token": token})
+	event, err := resumed.Expect("resumed", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels, _ := event.Data["channels"].([]interface{})
+	if len(channels) == 0 || !containsChannel(channels, channel) {
+		t.Errorf("Expected %s to be rejoined, got %v", channel, channels)
+	}
+
+	if err := s.api.Broadcast(map[string]interface{}{"channel": channel, "event": "after_resume"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resumed.Expect("after_resume", 5*time.Second); err != nil {
+		t.Error(err)
+	}
+
+	// Tokens are single-use
+	replay := s.dial(t)
+	replay.Send("resume", map[string]interface{}{"token": token})
+	if _, err := replay.Expect("resumed", 2*time.Second); err == nil {
+		t.Error("Expected a reused resume token to be rejected")
+	}
+}
+
+// expectJoined waits for the confirmation of a channel starting with prefix, skipping other
+// joins such as the personal user channel
+func expectJoined(t *testing.T, client *Client, prefix string) string {
+	t.Helper()
+	for {
+		joined, err := client.Expect("joined_channel", 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if channel, _ := joined.Data["channel"].(string); strings.HasPrefix(channel, prefix) {
+			return channel
+		}
+	}
+}
+
+func containsChannel(channels []interface{}, channel string) bool {
+	for _, name := range channels {
+		if name == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Guarantee: on reconnect, direct channel history is replayed in publish order. Requires
+// DM_HISTORY_SIZE > 0.
+func TestReconnectReplay(t *testing.T) {
+	s := setup(t)
+	alice, bob := uniqueUser("alice"), uniqueUser("bob")
+
+	client := s.dialAs(t, alice)
+	client.Send("open_direct_channel", map[string]interface{}{"user_id": bob})
+	channel := expectJoined(t, client, "dm.")
+
+	for i := 0; i < 3; i++ {
+		if err := s.api.Broadcast(map[string]interface{}{"channel": channel, "event": "dm", "data": map[string]interface{}{"n": i}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Expect("dm", 5*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Close()
+	s.waitForDisconnect(t, client.ID)
+
+	reconnected := s.dialAs(t, alice)
+	reconnected.Send("open_direct_channel", map[string]interface{}{"user_id": bob})
+	history, err := reconnected.Expect("channel_history", 2*time.Second)
+	if err != nil {
+		t.Skip("Direct channel history is disabled (DM_HISTORY_SIZE not set)")
+	}
+
+	messages := history.Data["messages"].([]interface{})
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 replayed messages, got %d", len(messages))
+	}
+	for i, message := range messages {
+		data := message.(map[string]interface{})["data"].(map[string]interface{})
+		if int(data["n"].(float64)) != i {
+			t.Errorf("Expected replayed message %d at position %d, got %v", i, i, data["n"])
+		}
+	}
+}
+
+// Guarantee: channel membership follows joins, leaves and disconnects, and each join and
+// leave is confirmed to the client
+func TestPresenceMembership(t *testing.T) {
+	s := setup(t)
+	channel := uniqueName("conformance.presence")
+
+	first, second := s.dial(t), s.dial(t)
+	if err := first.Join(channel); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Join(channel); err != nil {
+		t.Fatal(err)
+	}
+	if members := s.channelMembers(t, channel); members != 2 {
+		t.Errorf("Expected 2 members after joining, got %d", members)
+	}
+
+	if err := first.Leave(channel); err != nil {
+		t.Fatal(err)
+	}
+	if members := s.channelMembers(t, channel); members != 1 {
+		t.Errorf("Expected 1 member after leaving, got %d", members)
+	}
+
+	second.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.channelMembers(t, channel) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a disconnected client to leave its channels")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Members that left no longer receive broadcasts (the broadcast may fail once the empty
+	// channel is removed)
+	s.api.Broadcast(map[string]interface{}{"channel": channel, "event": "after_leave"})
+	if _, err := first.Expect("after_leave", 500*time.Millisecond); err == nil {
+		t.Error("Expected no delivery after leaving the channel")
+	}
+}
@@ -63,7 +63,7 @@ func testComponents() {
 
 	// Test 6: WebSocket Server
 	fmt.Println("\n6. Testing WebSocket Server...")
-	wsServer := websocket.New(authService, laravelSvc, logger)
+	wsServer := websocket.New(cfg, authService, laravelSvc, logger)
 	if wsServer == nil {
 		log.Fatalf("WebSocket server creation failed")
 	}
@@ -72,3 +72,7 @@ func testComponents() {
 	fmt.Println("\n✅ All components tested successfully!")
 	fmt.Println("The refactored socket server is working correctly.")
 }
+
+func main() {
+	testComponents()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"socket-server/internal/config"
+	"socket-server/pkg/logger"
+)
+
+// configureTLS prepares the server to serve HTTPS and WSS directly. It returns the certificate
+// and key files to pass to ServeTLS, which are empty when certificates come from autocert.
+func configureTLS(server *http.Server, cfg *config.Config, logger *logger.Logger) (certFile, keyFile string) {
+	if len(cfg.TLSAutocertHosts) == 0 {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		logger.Info("TLS enabled with certificate %s", cfg.TLSCertFile)
+		return cfg.TLSCertFile, cfg.TLSKeyFile
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertHosts...),
+		Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+		Email:      cfg.TLSAutocertEmail,
+	}
+	server.TLSConfig = manager.TLSConfig()
+	server.TLSConfig.MinVersion = tls.VersionTLS12
+	logger.Info("TLS enabled with Let's Encrypt certificates for %v (cache: %s)", cfg.TLSAutocertHosts, cfg.TLSAutocertCacheDir)
+
+	// TLS-ALPN challenges are answered on the TLS port itself, which only works when it is
+	// 443; the HTTP-01 listener covers every other setup and redirects plain HTTP to HTTPS
+	if cfg.TLSAutocertHTTPAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(cfg.TLSAutocertHTTPAddr, manager.HTTPHandler(nil)); err != nil {
+				logger.Warn("ACME HTTP challenge listener on %s stopped: %v", cfg.TLSAutocertHTTPAddr, err)
+			}
+		}()
+	}
+	return "", ""
+}